@@ -0,0 +1,114 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"backend-go-model-gateway/pkg/ragfilter"
+
+	"github.com/google/uuid"
+)
+
+// Agent-to-agent message types. Peers send tasks; the planner answers each
+// with a result or an error carrying the task's correlation ID.
+const (
+	AgentMessageTask   = "task"
+	AgentMessageResult = "result"
+	AgentMessageError  = "error"
+)
+
+// ErrAgentMessageInvalid is returned by HandleAgentMessage for a message it
+// cannot act on (wrong type, no prompt, a session outside the peer's
+// namespace).
+var ErrAgentMessageInvalid = errors.New("invalid agent message")
+
+// AgentMessage is the envelope of POST /agents/message, in both directions.
+type AgentMessage struct {
+	// ID identifies this message; the planner assigns one when it is empty.
+	ID string `json:"id"`
+	// CorrelationID ties a result to its task and a task to earlier tasks of
+	// the same conversation. It defaults to the task's ID.
+	CorrelationID string `json:"correlation_id"`
+	// From is the sender. On received messages it is the authenticated peer,
+	// whatever the body says.
+	From      string       `json:"from"`
+	To        string       `json:"to,omitempty"`
+	Type      string       `json:"type"`
+	Task      *AgentTask   `json:"task,omitempty"`
+	Result    *AgentResult `json:"result,omitempty"`
+	Error     string       `json:"error,omitempty"`
+	Timestamp time.Time    `json:"timestamp"`
+}
+
+// AgentTask asks the receiving planner to run a prompt.
+type AgentTask struct {
+	Prompt string `json:"prompt"`
+	// SessionID defaults to "a2a:<from>:<correlation_id>", so every task of
+	// one conversation shares history and each peer's sessions stay apart.
+	// One that is set must start with "a2a:<from>:" too.
+	SessionID string            `json:"session_id,omitempty"`
+	Persona   string            `json:"persona,omitempty"`
+	RAGFilter *ragfilter.Filter `json:"rag_filter,omitempty"`
+}
+
+// AgentResult is the answer to a task.
+type AgentResult struct {
+	SessionID string `json:"session_id"`
+	Output    string `json:"output"`
+}
+
+// HandleAgentMessage runs a task from peer agent from and returns the reply:
+// a result, or an error message when the run failed (err is set too, so the
+// caller can pick a status). Both directions are recorded in the audit trail
+// of the task's session with the correlation ID.
+func (p *Planner) HandleAgentMessage(ctx context.Context, from string, msg AgentMessage) (*AgentMessage, error) {
+	if msg.ID == "" {
+		msg.ID = uuid.NewString()
+	}
+	if msg.CorrelationID == "" {
+		msg.CorrelationID = msg.ID
+	}
+	msg.From = from
+	reply := &AgentMessage{ID: uuid.NewString(), CorrelationID: msg.CorrelationID, From: p.cfg.AgentID, To: from}
+
+	if msg.Type != AgentMessageTask || msg.Task == nil || strings.TrimSpace(msg.Task.Prompt) == "" {
+		err := fmt.Errorf("%w: want type %q with a task prompt", ErrAgentMessageInvalid, AgentMessageTask)
+		return agentError(reply, err), err
+	}
+	task := msg.Task
+	// A peer only reaches sessions in its own namespace, never a user's.
+	namespace := "a2a:" + from + ":"
+	sessionID := task.SessionID
+	if sessionID == "" {
+		sessionID = namespace + msg.CorrelationID
+	}
+	if !strings.HasPrefix(sessionID, namespace) || sessionID == namespace {
+		err := fmt.Errorf("%w: session_id must start with %q", ErrAgentMessageInvalid, namespace)
+		return agentError(reply, err), err
+	}
+	_ = p.RecordStep(ctx, sessionID, "AGENT_MESSAGE_RECEIVED", map[string]any{"id": msg.ID, "correlation_id": msg.CorrelationID, "from": from, "prompt": task.Prompt})
+
+	if task.Persona != "" {
+		ctx = ContextWithPersona(ctx, task.Persona)
+	}
+	output, err := p.AgentLoop(ctx, task.Prompt, sessionID, nil, task.RAGFilter)
+	if err != nil {
+		agentError(reply, err)
+	} else {
+		reply.Type = AgentMessageResult
+		reply.Result = &AgentResult{SessionID: sessionID, Output: output}
+		reply.Timestamp = time.Now().UTC()
+	}
+	_ = p.RecordStep(ctx, sessionID, "AGENT_MESSAGE_SENT", map[string]any{"id": reply.ID, "correlation_id": reply.CorrelationID, "to": from, "type": reply.Type})
+	return reply, err
+}
+
+func agentError(reply *AgentMessage, err error) *AgentMessage {
+	reply.Type = AgentMessageError
+	reply.Error = err.Error()
+	reply.Timestamp = time.Now().UTC()
+	return reply
+}
@@ -0,0 +1,109 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"go.yaml.in/yaml/v2"
+)
+
+// instrument is an OpenTelemetry instrument the planner exports on /metrics.
+// initMetrics, registerLoadMetrics and registerProbeMetrics create their
+// instruments from these, so the alert rules below use the same names.
+type instrument struct {
+	name    string
+	counter bool
+	unit    string
+}
+
+var (
+	planTotalMetric           = instrument{name: "agent_plan_total", counter: true, unit: "1"}
+	breakerTripsMetric        = instrument{name: "agent_circuit_breaker_trips", counter: true, unit: "1"}
+	breakerOpenMetric         = instrument{name: "agent_circuit_breaker_open", unit: "1"}
+	toolBudgetCallsMetric     = instrument{name: "agent_tool_budget_calls_total", counter: true, unit: "1"}
+	toolBudgetRemainingMetric = instrument{name: "agent_tool_budget_remaining", unit: "1"}
+	probeTotalMetric          = instrument{name: "agent_probe_total", counter: true, unit: "1"}
+	probeLastSuccessMetric    = instrument{name: "agent_probe_last_success_timestamp_seconds", unit: "s"}
+)
+
+// prom is the instrument's name on /metrics. The OpenTelemetry Prometheus
+// exporter suffixes counters with _total, dimensionless gauges with _ratio
+// and seconds with _seconds.
+func (i instrument) prom() string {
+	name := i.name
+	switch i.unit {
+	case "1":
+		if !i.counter {
+			name += "_ratio"
+		}
+	case "s":
+		if !strings.HasSuffix(name, "_seconds") {
+			name += "_seconds"
+		}
+	}
+	if i.counter && !strings.HasSuffix(name, "_total") {
+		name += "_total"
+	}
+	return name
+}
+
+type alertRuleFile struct {
+	Groups []alertRuleGroup `yaml:"groups"`
+}
+
+type alertRuleGroup struct {
+	Name  string      `yaml:"name"`
+	Rules []alertRule `yaml:"rules"`
+}
+
+type alertRule struct {
+	Alert       string            `yaml:"alert"`
+	Expr        string            `yaml:"expr"`
+	For         string            `yaml:"for,omitempty"`
+	Labels      map[string]string `yaml:"labels"`
+	Annotations map[string]string `yaml:"annotations"`
+}
+
+// AlertRules renders recommended Prometheus alerting rules for the planner's
+// metrics as a rule file: open circuit breakers, the AgentLoop error rate,
+// tool budget exhaustion and canary failures. probeInterval is the canary's
+// AGENT_PROBE_INTERVAL; with 0 there is no rule for a stale canary.
+func AlertRules(probeInterval time.Duration) ([]byte, error) {
+	rule := func(name, severity, expr, wait, summary string) alertRule {
+		return alertRule{
+			Alert:       name,
+			Expr:        expr,
+			For:         wait,
+			Labels:      map[string]string{"severity": severity, "service": "agent-planner"},
+			Annotations: map[string]string{"summary": summary},
+		}
+	}
+	rules := []alertRule{
+		rule("AgentCircuitBreakerOpen", "critical",
+			fmt.Sprintf("max by (dependency) (%s) == 1", breakerOpenMetric.prom()), "2m",
+			"The {{ $labels.dependency }} circuit breaker has been open for 2 minutes; planner calls to it fail fast."),
+		rule("AgentCircuitBreakerFlapping", "warning",
+			fmt.Sprintf("sum by (dependency) (increase(%s[15m])) > 3", breakerTripsMetric.prom()), "",
+			"The {{ $labels.dependency }} circuit breaker opened more than 3 times in 15 minutes."),
+		rule("AgentLoopErrorRate", "warning",
+			fmt.Sprintf(`sum(rate(%[1]s{outcome="error"}[5m])) / sum(rate(%[1]s[5m])) > 0.1`, planTotalMetric.prom()), "10m",
+			"More than 10% of AgentLoop runs have failed for 10 minutes."),
+		rule("AgentToolBudgetExhausted", "warning",
+			fmt.Sprintf(`sum by (tool) (increase(%s{outcome="global_exceeded"}[15m])) > 0`, toolBudgetCallsMetric.prom()), "",
+			"Calls to {{ $labels.tool }} were refused: the hourly tool budget (AGENT_TOOL_BUDGET_PER_HOUR) is used up."),
+		rule("AgentToolBudgetEmpty", "info",
+			fmt.Sprintf("min(%s) == 0", toolBudgetRemainingMetric.prom()), "5m",
+			"A planner replica has no calls left in its hourly tool budget."),
+		rule("AgentCanaryFailing", "critical",
+			fmt.Sprintf(`sum by (stage) (increase(%s{outcome="failure"}[15m])) > 0`, probeTotalMetric.prom()), "",
+			"The canary probe failed at stage {{ $labels.stage }}."),
+	}
+	if probeInterval > 0 {
+		stale := 3 * probeInterval
+		rules = append(rules, rule("AgentCanaryStale", "critical",
+			fmt.Sprintf("%[1]s > 0 and time() - %[1]s > %d", probeLastSuccessMetric.prom(), int(stale.Seconds())), "",
+			fmt.Sprintf("No canary probe has succeeded in %s (3 probe intervals).", stale)))
+	}
+	return yaml.Marshal(alertRuleFile{Groups: []alertRuleGroup{{Name: "agent-planner", Rules: rules}}})
+}
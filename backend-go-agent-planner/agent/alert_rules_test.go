@@ -0,0 +1,96 @@
+package agent
+
+import (
+	"context"
+	"regexp"
+	"slices"
+	"testing"
+	"time"
+
+	promclient "github.com/prometheus/client_golang/prometheus"
+	otelprom "go.opentelemetry.io/otel/exporters/prometheus"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.yaml.in/yaml/v2"
+)
+
+var alertInstruments = []instrument{planTotalMetric, breakerTripsMetric, breakerOpenMetric, toolBudgetCallsMetric, toolBudgetRemainingMetric, probeTotalMetric, probeLastSuccessMetric}
+
+// exportedNames records one point per instrument through the Prometheus
+// exporter /metrics uses and returns the metric names it exposes.
+func exportedNames(t *testing.T) []string {
+	t.Helper()
+	reg := promclient.NewRegistry()
+	exp, err := otelprom.New(otelprom.WithRegisterer(reg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := sdkmetric.NewMeterProvider(sdkmetric.WithReader(exp)).Meter("alert-rules-test")
+	ctx := context.Background()
+	for _, inst := range alertInstruments {
+		if inst.counter {
+			c, err := m.Int64Counter(inst.name, metric.WithUnit(inst.unit))
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.Add(ctx, 1)
+			continue
+		}
+		g, err := m.Int64Gauge(inst.name, metric.WithUnit(inst.unit))
+		if err != nil {
+			t.Fatal(err)
+		}
+		g.Record(ctx, 1)
+	}
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, f := range families {
+		names = append(names, f.GetName())
+	}
+	return names
+}
+
+func TestAlertRules_UseExportedNames(t *testing.T) {
+	exported := exportedNames(t)
+	for _, inst := range alertInstruments {
+		if !slices.Contains(exported, inst.prom()) {
+			t.Errorf("%s: rules use %s, /metrics has %q", inst.name, inst.prom(), exported)
+		}
+	}
+
+	out, err := AlertRules(5 * time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var file alertRuleFile
+	if err := yaml.Unmarshal(out, &file); err != nil {
+		t.Fatalf("%v\n%s", err, out)
+	}
+	metricName := regexp.MustCompile(`agent_[a-z_]+`)
+	var alerts []string
+	for _, r := range file.Groups[0].Rules {
+		alerts = append(alerts, r.Alert)
+		for _, name := range metricName.FindAllString(r.Expr, -1) {
+			if !slices.Contains(exported, name) {
+				t.Errorf("%s: %s is not exported", r.Alert, name)
+			}
+		}
+	}
+	for _, want := range []string{"AgentCircuitBreakerOpen", "AgentLoopErrorRate", "AgentToolBudgetExhausted", "AgentCanaryFailing", "AgentCanaryStale"} {
+		if !slices.Contains(alerts, want) {
+			t.Errorf("missing %s in %q", want, alerts)
+		}
+	}
+	if stale := file.Groups[0].Rules[len(alerts)-1]; stale.Expr != "agent_probe_last_success_timestamp_seconds > 0 and time() - agent_probe_last_success_timestamp_seconds > 900" {
+		t.Errorf("stale canary expr = %q", stale.Expr)
+	}
+
+	out, _ = AlertRules(0)
+	file = alertRuleFile{}
+	if err := yaml.Unmarshal(out, &file); err != nil || slices.ContainsFunc(file.Groups[0].Rules, func(r alertRule) bool { return r.Alert == "AgentCanaryStale" }) {
+		t.Fatalf("without probes: %v\n%s", err, out)
+	}
+}
@@ -0,0 +1,274 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"backend-go-agent-planner/internal/logger"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Anomaly kinds.
+const (
+	AnomalyToolCalls    = "tool_call_spike"
+	AnomalyRepeatedCall = "repeated_tool_call"
+	AnomalyLatency      = "latency"
+	AnomalyCost         = "cost_jump"
+)
+
+// AnomalyConfig tunes anomaly detection (AGENT_ANOMALY_*). A finished run is
+// compared with the session's last Window runs: once MinRuns are known, a
+// tool call count, duration or token count more than Sigma deviations above
+// the session's mean is flagged. The deviation is at least a quarter of the
+// mean, so sessions whose runs look alike are not flagged for small changes.
+// A run calling the same tool with the same arguments RepeatLimit times is
+// flagged whatever the baseline.
+type AnomalyConfig struct {
+	Enabled     bool
+	Window      int
+	MinRuns     int
+	Sigma       float64
+	RepeatLimit int
+}
+
+// AnomalyConfigFromEnv reads AGENT_ANOMALY_DETECTION (default off),
+// AGENT_ANOMALY_WINDOW, AGENT_ANOMALY_MIN_RUNS, AGENT_ANOMALY_SIGMA and
+// AGENT_ANOMALY_REPEAT_LIMIT.
+func AnomalyConfigFromEnv() AnomalyConfig {
+	cfg := AnomalyConfig{
+		Enabled:     strings.EqualFold(getenv("AGENT_ANOMALY_DETECTION", "off"), "on"),
+		Window:      20,
+		MinRuns:     5,
+		Sigma:       3,
+		RepeatLimit: 3,
+	}
+	if v := os.Getenv("AGENT_ANOMALY_WINDOW"); v != "" {
+		fmt.Sscanf(v, "%d", &cfg.Window)
+	}
+	if v := os.Getenv("AGENT_ANOMALY_MIN_RUNS"); v != "" {
+		fmt.Sscanf(v, "%d", &cfg.MinRuns)
+	}
+	if v := os.Getenv("AGENT_ANOMALY_SIGMA"); v != "" {
+		fmt.Sscanf(v, "%g", &cfg.Sigma)
+	}
+	if v := os.Getenv("AGENT_ANOMALY_REPEAT_LIMIT"); v != "" {
+		fmt.Sscanf(v, "%d", &cfg.RepeatLimit)
+	}
+	cfg.Window = max(cfg.Window, 2)
+	cfg.MinRuns = min(max(cfg.MinRuns, 2), cfg.Window)
+	return cfg
+}
+
+// Anomaly is one unusual aspect of a run. Value is the run's, Mean and
+// Threshold the session baseline's (zero for repeated calls).
+type Anomaly struct {
+	Kind      string  `json:"kind"`
+	Value     float64 `json:"value"`
+	Mean      float64 `json:"mean,omitempty"`
+	Threshold float64 `json:"threshold,omitempty"`
+	// Runs is how many earlier runs the baseline covers.
+	Runs int    `json:"runs,omitempty"`
+	Tool string `json:"tool,omitempty"`
+}
+
+// runStats is what anomaly detection looks at in one AgentLoop run.
+type runStats struct {
+	toolCalls int
+	// calls counts identical tool calls by name and arguments.
+	calls  map[string]int
+	tokens int64
+}
+
+func (r *runStats) toolCall(call *ToolCall) {
+	r.toolCalls++
+	args, _ := json.Marshal(call.Args) // map keys marshal sorted
+	if r.calls == nil {
+		r.calls = map[string]int{}
+	}
+	r.calls[call.Name+" "+string(args)]++
+}
+
+// maxAnomalySessions bounds the baselines kept; the least recently seen
+// session is dropped first.
+const maxAnomalySessions = 10000
+
+// anomalyDetector keeps each session's recent runs, in memory and per
+// replica. It detects nothing while disabled, or when nil.
+type anomalyDetector struct {
+	cfg AnomalyConfig
+
+	mu       sync.Mutex
+	sessions map[string]*sessionBaseline
+}
+
+// sessionBaseline is a ring of a session's last runs.
+type sessionBaseline struct {
+	runs     []runSample
+	next     int
+	lastSeen time.Time
+}
+
+type runSample struct {
+	toolCalls, latencyMS, tokens float64
+}
+
+func newAnomalyDetector(cfg AnomalyConfig) *anomalyDetector {
+	return &anomalyDetector{cfg: cfg, sessions: map[string]*sessionBaseline{}}
+}
+
+// configure applies reloaded settings. Baselines are kept unless detection
+// is switched off or the window changes.
+func (d *anomalyDetector) configure(cfg AnomalyConfig) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !cfg.Enabled || cfg.Window != d.cfg.Window {
+		d.sessions = map[string]*sessionBaseline{}
+	}
+	d.cfg = cfg
+}
+
+// observe returns what is unusual about a finished run and adds it to the
+// session's baseline.
+func (d *anomalyDetector) observe(sessionID string, run *runStats, latency time.Duration, now time.Time) []Anomaly {
+	if d == nil {
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.cfg.Enabled {
+		return nil
+	}
+	var out []Anomaly
+	keys := make([]string, 0, len(run.calls))
+	for key := range run.calls {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if n := run.calls[key]; n >= d.cfg.RepeatLimit {
+			out = append(out, Anomaly{Kind: AnomalyRepeatedCall, Value: float64(n), Tool: strings.SplitN(key, " ", 2)[0]})
+		}
+	}
+
+	sample := runSample{toolCalls: float64(run.toolCalls), latencyMS: float64(latency.Milliseconds()), tokens: float64(run.tokens)}
+	b := d.sessions[sessionID]
+	if b == nil {
+		if len(d.sessions) >= maxAnomalySessions {
+			d.evictOldest()
+		}
+		b = &sessionBaseline{}
+		d.sessions[sessionID] = b
+	}
+	if len(b.runs) >= d.cfg.MinRuns {
+		for _, check := range []struct {
+			kind  string
+			value func(runSample) float64
+			// floor is the smallest deviation that counts, in the metric's unit.
+			floor float64
+		}{
+			{AnomalyToolCalls, func(s runSample) float64 { return s.toolCalls }, 1},
+			{AnomalyLatency, func(s runSample) float64 { return s.latencyMS }, 250},
+			{AnomalyCost, func(s runSample) float64 { return s.tokens }, 100},
+		} {
+			if a, ok := b.check(check.kind, check.value, sample, d.cfg.Sigma, check.floor); ok {
+				out = append(out, a)
+			}
+		}
+	}
+	if len(b.runs) < d.cfg.Window {
+		b.runs = append(b.runs, sample)
+	} else {
+		b.runs[b.next] = sample
+	}
+	b.next = (b.next + 1) % d.cfg.Window
+	b.lastSeen = now
+	return out
+}
+
+// check compares one metric of sample with the baseline's runs.
+func (b *sessionBaseline) check(kind string, value func(runSample) float64, sample runSample, sigma, floor float64) (Anomaly, bool) {
+	var sum, sumSq float64
+	for _, r := range b.runs {
+		v := value(r)
+		sum += v
+		sumSq += v * v
+	}
+	n := float64(len(b.runs))
+	mean := sum / n
+	if kind == AnomalyCost && mean == 0 {
+		return Anomaly{}, false // the gateway does not report tokens
+	}
+	stddev := math.Sqrt(max(sumSq/n-mean*mean, 0))
+	threshold := mean + sigma*max(stddev, mean/4, floor)
+	if v := value(sample); v > threshold {
+		return Anomaly{Kind: kind, Value: v, Mean: mean, Threshold: threshold, Runs: len(b.runs)}, true
+	}
+	return Anomaly{}, false
+}
+
+// evictOldest drops the least recently seen session; d.mu must be held.
+func (d *anomalyDetector) evictOldest() {
+	var oldest string
+	var seen time.Time
+	for id, b := range d.sessions {
+		if oldest == "" || b.lastSeen.Before(seen) {
+			oldest, seen = id, b.lastSeen
+		}
+	}
+	delete(d.sessions, oldest)
+}
+
+// forget drops a session's baseline (ForgetSession).
+func (d *anomalyDetector) forget(sessionID string) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.sessions, sessionID)
+}
+
+// status reports the settings for GET /admin/status (nil when off).
+func (d *anomalyDetector) status() map[string]any {
+	if d == nil {
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.cfg.Enabled {
+		return nil
+	}
+	sessions := len(d.sessions)
+	return map[string]any{"window": d.cfg.Window, "min_runs": d.cfg.MinRuns, "sigma": d.cfg.Sigma, "repeat_limit": d.cfg.RepeatLimit, "sessions": sessions}
+}
+
+// checkRun flags what is unusual about a finished run: each anomaly is
+// logged and counted, and the run gets one ANOMALY audit step and one WARN
+// notification listing them. Canary runs are skipped.
+func (p *Planner) checkRun(ctx context.Context, sessionID string, run *runStats, latency time.Duration) {
+	if probing(ctx) {
+		return
+	}
+	found := p.anomalies.observe(sessionID, run, latency, time.Now())
+	if len(found) == 0 {
+		return
+	}
+	lg := logger.NewContextLogger(ctx)
+	for _, a := range found {
+		lg.Warn("run_anomaly", "session_id", sessionID, "kind", a.Kind, "value", a.Value, "mean", a.Mean, "threshold", a.Threshold, "tool", a.Tool)
+		if anomaliesTotal != nil {
+			anomaliesTotal.Add(ctx, 1, metric.WithAttributes(attribute.String("kind", a.Kind)))
+		}
+	}
+	_ = p.RecordStep(ctx, sessionID, "ANOMALY", map[string]any{"anomalies": found})
+	_ = p.publish(ctx, sessionID, map[string]any{"status": "ANOMALY", "level": "WARN", "anomalies": found})
+}
@@ -0,0 +1,67 @@
+package agent
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAnomalyDetector_SpikesAgainstSessionBaseline(t *testing.T) {
+	d := newAnomalyDetector(AnomalyConfig{Enabled: true, Window: 10, MinRuns: 5, Sigma: 3, RepeatLimit: 3})
+	now := time.Now()
+	usual := func() *runStats {
+		r := &runStats{tokens: 1000}
+		r.toolCall(&ToolCall{Name: "web_search", Args: map[string]any{"query": "weather"}})
+		return r
+	}
+	for i := 0; i < 5; i++ {
+		if got := d.observe("s1", usual(), 2*time.Second, now); len(got) != 0 {
+			t.Fatalf("run %d flagged before a baseline: %+v", i, got)
+		}
+	}
+	// Within the floor of a quarter of the mean: not flagged.
+	if got := d.observe("s1", usual(), 3*time.Second, now); len(got) != 0 {
+		t.Fatalf("ordinary run flagged: %+v", got)
+	}
+
+	spike := &runStats{tokens: 5000}
+	for _, q := range []string{"a", "b", "c", "d", "e"} {
+		spike.toolCall(&ToolCall{Name: "web_search", Args: map[string]any{"query": q}})
+	}
+	got := d.observe("s1", spike, 10*time.Second, now)
+	kinds := map[string]bool{}
+	for _, a := range got {
+		kinds[a.Kind] = true
+	}
+	if len(got) != 3 || !kinds[AnomalyToolCalls] || !kinds[AnomalyLatency] || !kinds[AnomalyCost] {
+		t.Fatalf("anomalies = %+v", got)
+	}
+
+	// Another session has no baseline yet.
+	if got := d.observe("s2", spike, 10*time.Second, now); len(got) != 0 {
+		t.Fatalf("new session flagged: %+v", got)
+	}
+}
+
+func TestAnomalyDetector_RepeatedCalls(t *testing.T) {
+	d := newAnomalyDetector(AnomalyConfig{Enabled: true, Window: 10, MinRuns: 5, Sigma: 3, RepeatLimit: 3})
+	r := &runStats{}
+	for i := 0; i < 3; i++ {
+		// Argument order does not make calls different.
+		r.toolCall(&ToolCall{Name: "web_search", Args: map[string]any{"query": "x", "page": 1.0}})
+	}
+	r.toolCall(&ToolCall{Name: "web_search", Args: map[string]any{"query": "y"}})
+	got := d.observe("s1", r, time.Second, time.Now())
+	if len(got) != 1 || got[0].Kind != AnomalyRepeatedCall || got[0].Value != 3 || got[0].Tool != "web_search" {
+		t.Fatalf("anomalies = %+v", got)
+	}
+
+	d.forget("s1")
+	if d.status()["sessions"] != 0 {
+		t.Fatal("forgotten session kept")
+	}
+	d.configure(AnomalyConfig{})
+	var unset *anomalyDetector
+	if d.observe("s1", r, time.Second, time.Now()) != nil || unset.observe("s1", r, time.Second, time.Now()) != nil || d.status() != nil {
+		t.Fatal("disabled detector flagged a run")
+	}
+}
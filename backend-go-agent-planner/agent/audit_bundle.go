@@ -0,0 +1,110 @@
+package agent
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"backend-go-agent-planner/audit"
+)
+
+// ErrBundleKeyUnset is returned by ExportAuditBundle and ForgetSession when
+// PAGI_AUDIT_SIGNING_KEY is not configured.
+var ErrBundleKeyUnset = errors.New("audit bundles and deletion receipts need PAGI_AUDIT_SIGNING_KEY")
+
+// ErrBundleMemory is returned by ExportAuditBundle when a session's memory
+// snapshot cannot be fetched; a bundle is never exported without it.
+var ErrBundleMemory = errors.New("memory snapshot failed")
+
+// bundlePageSize is the audit DB page size while collecting a bundle.
+const bundlePageSize = 1000
+
+// ExportAuditBundle writes a signed compliance bundle (see audit.WriteBundle)
+// for the session and/or time range in f: every audit row and notification,
+// and the Memory Service history of each session they mention (or of
+// f.SessionID).
+func (p *Planner) ExportAuditBundle(ctx context.Context, w io.Writer, f audit.QueryFilter) error {
+	if p == nil || p.auditDB == nil {
+		return ErrAuditUnavailable
+	}
+	key, err := p.signingKey(ctx)
+	if err != nil {
+		return err
+	}
+	if f.SessionID != "" {
+		if err := p.authorizeSession(ctx, f.SessionID, false); err != nil {
+			return err
+		}
+	}
+	f.Tenant = TenantFromContext(ctx)
+
+	f.EventType, f.TraceID, f.Limit = "", "", bundlePageSize
+	b := audit.Bundle{SessionID: f.SessionID, Since: f.Since, Until: f.Until, Memory: map[string]json.RawMessage{}}
+	for page := f; ; {
+		entries, err := p.auditDB.Query(ctx, page)
+		if err != nil {
+			return err
+		}
+		b.Entries = append(b.Entries, entries...)
+		if len(entries) < bundlePageSize {
+			break
+		}
+		page.AfterID = entries[len(entries)-1].ID
+	}
+	for page := f; ; {
+		notifications, err := p.auditDB.QueryNotifications(ctx, page)
+		if err != nil {
+			return err
+		}
+		b.Notifications = append(b.Notifications, notifications...)
+		if len(notifications) < bundlePageSize {
+			break
+		}
+		page.AfterID = notifications[len(notifications)-1].ID
+	}
+
+	sessions := map[string]bool{}
+	if f.SessionID != "" {
+		sessions[f.SessionID] = true
+	}
+	for _, e := range b.Entries {
+		if e.SessionID != "" {
+			sessions[e.SessionID] = true
+		}
+	}
+	for sessionID := range sessions {
+		messages, err := p.fetchSessionHistory(ctx, sessionID)
+		if err != nil {
+			return fmt.Errorf("%w: session %s: %v", ErrBundleMemory, sessionID, err)
+		}
+		snapshot, _ := json.Marshal(map[string]any{
+			"session_id": sessionID,
+			"fetched_at": time.Now().UTC(),
+			"messages":   messages,
+		})
+		b.Memory[sessionID] = snapshot
+	}
+
+	return audit.WriteBundle(w, b, key, time.Now())
+}
+
+// signingKey returns PAGI_AUDIT_SIGNING_KEY (via pkg/secrets), which signs
+// audit bundles and deletion receipts.
+func (p *Planner) signingKey(ctx context.Context) (ed25519.PrivateKey, error) {
+	rawKey, err := p.cfg.Secrets.Lookup(ctx, "PAGI_AUDIT_SIGNING_KEY")
+	if err != nil {
+		return nil, err
+	}
+	if rawKey == "" {
+		return nil, ErrBundleKeyUnset
+	}
+	key, err := audit.ParseSigningKey(rawKey)
+	if err != nil {
+		return nil, fmt.Errorf("PAGI_AUDIT_SIGNING_KEY: %w", err)
+	}
+	return key, nil
+}
@@ -0,0 +1,69 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"backend-go-agent-planner/audit"
+	"backend-go-model-gateway/pkg/secrets"
+)
+
+func TestExportAuditBundle(t *testing.T) {
+	memory := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("session_id") == "broken" {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"messages":[{"role":"user","content":"hi"}]}`))
+	}))
+	defer memory.Close()
+
+	db, err := audit.NewAuditDB(filepath.Join(t.TempDir(), "audit.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	_ = db.RecordStep(ctx, "t1", "s1", "PLAN_START", map[string]any{"prompt": "hi"})
+	_ = db.RecordStep(ctx, "t2", "broken", "PLAN_START", nil)
+
+	seed := bytes.Repeat([]byte{1}, ed25519.SeedSize)
+	env := map[string]string{}
+	p := &Planner{
+		cfg:        Config{MemoryServiceHTTP: memory.URL, Secrets: secrets.New(secrets.Options{Env: func(k string) string { return env[k] }})},
+		httpClient: memory.Client(),
+		auditDB:    db,
+	}
+
+	var buf bytes.Buffer
+	if err := p.ExportAuditBundle(ctx, &buf, audit.QueryFilter{SessionID: "s1"}); !errors.Is(err, ErrBundleKeyUnset) {
+		t.Fatalf("no key: %v, want ErrBundleKeyUnset", err)
+	}
+	env["PAGI_AUDIT_SIGNING_KEY"] = base64.StdEncoding.EncodeToString(seed)
+
+	// A time-range export covers every session in it, so one failing memory
+	// snapshot fails the export.
+	if err := p.ExportAuditBundle(ctx, &buf, audit.QueryFilter{}); !errors.Is(err, ErrBundleMemory) {
+		t.Fatalf("broken session: %v, want ErrBundleMemory", err)
+	}
+
+	buf.Reset()
+	if err := p.ExportAuditBundle(ctx, &buf, audit.QueryFilter{SessionID: "s1", EventType: "ignored"}); err != nil {
+		t.Fatal(err)
+	}
+	pub := ed25519.NewKeyFromSeed(seed).Public().(ed25519.PublicKey)
+	m, err := audit.VerifyBundle(bytes.NewReader(buf.Bytes()), int64(buf.Len()), pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Files) != 3 || m.Files[2].Name != "memory/s1.json" || m.Files[0].Size == 0 {
+		t.Fatalf("files = %+v", m.Files)
+	}
+}
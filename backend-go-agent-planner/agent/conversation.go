@@ -0,0 +1,54 @@
+package agent
+
+import pb "backend-go-model-gateway/proto/proto"
+
+// Conversation is a run's state between planner turns: the user's prompt and
+// what each tool-calling turn added. AgentLoop appends to it instead of
+// editing a prompt string, so a run can be replayed or accounted for turn by
+// turn; messages produces the turns the next GetPlan sends after the prompt.
+type Conversation struct {
+	Prompt string       `json:"prompt"`
+	Turns  []TurnResult `json:"turns,omitempty"`
+}
+
+// TurnResult is one turn that called a tool: the model's plan and the tool's
+// output, or the error fed back in its place (refused, over budget or failed).
+type TurnResult struct {
+	Turn   int    `json:"turn"`
+	Plan   string `json:"plan"`
+	Tool   string `json:"tool"`
+	Output string `json:"output,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// addOutput records a turn whose tool ran (or was reused from the scratchpad).
+func (c *Conversation) addOutput(turn int, plan, tool, output string) {
+	c.Turns = append(c.Turns, TurnResult{Turn: turn, Plan: plan, Tool: tool, Output: output})
+}
+
+// addError records a turn whose tool call failed with err.
+func (c *Conversation) addError(turn int, plan, tool string, err error) {
+	c.Turns = append(c.Turns, TurnResult{Turn: turn, Plan: plan, Tool: tool, Error: err.Error()})
+}
+
+// messages returns the turns as GetPlan sends them after the planner prompt:
+// each plan as the assistant message it was, followed by a user message with
+// the tool result (rendered with v's followup template) or "Tool error: ...".
+// The same turns always give the same messages.
+func (c *Conversation) messages(v *promptVersion) ([]*pb.ChatMessage, error) {
+	out := make([]*pb.ChatMessage, 0, 2*len(c.Turns))
+	for _, t := range c.Turns {
+		answer := "Tool error: " + t.Error
+		if t.Error == "" {
+			var err error
+			if answer, err = v.followupPrompt(c.Prompt, t.Plan, t.Output); err != nil {
+				return nil, err
+			}
+		}
+		out = append(out,
+			&pb.ChatMessage{Role: "assistant", Content: t.Plan},
+			&pb.ChatMessage{Role: "user", Content: answer},
+		)
+	}
+	return out, nil
+}
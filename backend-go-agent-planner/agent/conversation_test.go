@@ -0,0 +1,49 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestConversation_Messages(t *testing.T) {
+	prompts, err := loadPromptSet(Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v1 := prompts.versions[DefaultPromptVersion]
+
+	conv := &Conversation{Prompt: "weather?"}
+	if got, _ := conv.messages(v1); len(got) != 0 {
+		t.Fatalf("no turns: %v", got)
+	}
+	conv.addError(1, `{"tool":{"name":"x"}}`, "x", errors.New(`tool "x" is not allowed`))
+	conv.addOutput(2, `{"tool":{"name":"web_search"}}`, "web_search", "sunny")
+	want := [][2]string{
+		{"assistant", `{"tool":{"name":"x"}}`},
+		{"user", `Tool error: tool "x" is not allowed`},
+		{"assistant", `{"tool":{"name":"web_search"}}`},
+		{"user", "<tool_result>\nsunny\n</tool_result>\n"},
+	}
+	check := func(name string, c *Conversation) {
+		t.Helper()
+		got, err := c.messages(v1)
+		if err != nil || len(got) != len(want) {
+			t.Fatalf("%s: messages = %v (%v)", name, got, err)
+		}
+		for i, m := range got {
+			if m.GetRole() != want[i][0] || m.GetContent() != want[i][1] {
+				t.Errorf("%s: message %d = %s %q, want %s %q", name, i, m.GetRole(), m.GetContent(), want[i][0], want[i][1])
+			}
+		}
+	}
+	check("messages", conv)
+
+	// A conversation restored from JSON gives the same messages.
+	b, _ := json.Marshal(conv)
+	var replayed Conversation
+	if err := json.Unmarshal(b, &replayed); err != nil {
+		t.Fatal(err)
+	}
+	check("replayed", &replayed)
+}
@@ -0,0 +1,23 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"backend-go-agent-planner/internal/logger"
+)
+
+func TestDeltaID(t *testing.T) {
+	run1 := context.WithValue(context.Background(), logger.TraceIDKey, "trace-1")
+	run2 := context.WithValue(context.Background(), logger.TraceIDKey, "trace-2")
+
+	if deltaID(run1, "s1", 0, "hi", "hello") != deltaID(run1, "s1", 0, "hi", "hello") {
+		t.Fatal("a retried write got a new delta ID")
+	}
+	if deltaID(run1, "s1", 0, "hi", "hello") == deltaID(run2, "s1", 0, "hi", "hello") {
+		t.Fatal("a later run repeating an exchange got the same delta ID")
+	}
+	if deltaID(run1, "s1", 0, "hi", "hello") == deltaID(run1, "s1", 0, "hihello") {
+		t.Fatal("content boundaries are not part of the delta ID")
+	}
+}
@@ -0,0 +1,117 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"backend-go-agent-planner/internal/logger"
+
+	"backend-go-model-gateway/pkg/drift"
+	pb "backend-go-model-gateway/proto/proto"
+)
+
+// Settings are the planner's side of the configuration it shares with the
+// gateway, the notification service and the BFF (GET /capabilities).
+func (p *Planner) Settings() drift.Settings {
+	s := drift.Settings{
+		Service:              "agent-planner",
+		NotificationsChannel: notificationsChannel,
+		TraceHeader:          string(logger.TraceIDKey),
+	}
+	if p != nil {
+		s.KnowledgeBases = p.cfg.KBs
+	}
+	return s
+}
+
+// CheckConfigDrift compares the planner's settings with the gateway's and,
+// with NOTIFICATION_ADMIN_URL set, the notification service's, and logs a
+// config_drift warning per mismatch. The services start together, so an
+// unreachable peer is retried every retry until ctx ends.
+func (p *Planner) CheckConfigDrift(ctx context.Context, retry time.Duration) {
+	lg := logger.NewContextLogger(ctx)
+	local := p.Settings()
+	check := func(peer string, callTimeout time.Duration, fetch func(context.Context) (drift.Settings, error)) {
+		for {
+			fetchCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+			remote, err := fetch(fetchCtx)
+			cancel()
+			if err == nil {
+				mismatches := drift.Check(local, callTimeout, remote)
+				for _, m := range mismatches {
+					lg.Warn("config_drift", "peer", peer, "setting", m.Setting, "local", m.Local, "remote", m.Remote, "problem", m.Problem)
+				}
+				lg.Info("config_drift_checked", "peer", peer, "mismatches", len(mismatches))
+				return
+			}
+			select {
+			case <-ctx.Done():
+				lg.Warn("config_drift_unchecked", "peer", peer, "error", err.Error())
+				return
+			case <-time.After(retry):
+			}
+		}
+	}
+	check("model_gateway", gatewayPlanTimeout, p.gatewaySettings)
+	if p.cfg.NotificationAdminURL != "" {
+		check("notification_service", 0, p.notificationSettings)
+	}
+}
+
+// gatewaySettings reads the gateway's settings from GetCapabilities.
+func (p *Planner) gatewaySettings(ctx context.Context) (drift.Settings, error) {
+	if p.modelClient == nil {
+		return drift.Settings{}, fmt.Errorf("model client is nil")
+	}
+	caps, err := p.modelClient.GetCapabilities(ctx, &pb.CapabilitiesRequest{})
+	if err != nil {
+		return drift.Settings{}, err
+	}
+	return drift.Settings{
+		Service:        "model-gateway",
+		KnowledgeBases: caps.GetKnowledgeBases(),
+		TimeoutSeconds: int(caps.GetTimeoutSeconds()),
+		TraceHeader:    caps.GetTraceHeader(),
+	}, nil
+}
+
+// notificationSettings reads the notification service's channel from its
+// GET /admin/status, with NOTIFICATION_ADMIN_API_KEY.
+func (p *Planner) notificationSettings(ctx context.Context) (drift.Settings, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(p.cfg.NotificationAdminURL, "/")+"/admin/status", nil)
+	if err != nil {
+		return drift.Settings{}, err
+	}
+	key, err := p.cfg.Secrets.Lookup(ctx, "NOTIFICATION_ADMIN_API_KEY")
+	if err != nil {
+		return drift.Settings{}, err
+	}
+	req.Header.Set("X-API-Key", key)
+	client := p.httpClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return drift.Settings{}, err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode != http.StatusOK {
+		return drift.Settings{}, fmt.Errorf("notification service status: %d", resp.StatusCode)
+	}
+	var status struct {
+		Details struct {
+			Channel string `json:"channel"`
+		} `json:"details"`
+	}
+	if err := json.Unmarshal(body, &status); err != nil {
+		return drift.Settings{}, fmt.Errorf("notification service status: %w", err)
+	}
+	return drift.Settings{Service: "notification-service", NotificationsChannel: status.Details.Channel}, nil
+}
@@ -0,0 +1,209 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+
+	"backend-go-agent-planner/internal/logger"
+	"backend-go-model-gateway/pkg/tools"
+	pb "backend-go-model-gateway/proto/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// streamPlan requests a plan with StreamPlan, passing its deltas to onDelta.
+// A gateway without StreamPlan is asked with GetPlan, now and from then on.
+func (p *Planner) streamPlan(ctx context.Context, req *pb.PlanRequest, onDelta func(string)) (*pb.PlanResponse, error) {
+	stream, err := p.modelClient.StreamPlan(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("StreamPlan ended without a plan")
+		}
+		if status.Code(err) == codes.Unimplemented {
+			logger.NewContextLogger(ctx).Warn("stream_plan_unsupported", "error", err.Error())
+			p.noStreamPlan.Store(true)
+			return p.modelClient.GetPlan(ctx, req)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if final := chunk.GetFinal(); final != nil {
+			return final, nil
+		}
+		if delta := chunk.GetDelta(); delta != "" {
+			onDelta(delta)
+		}
+	}
+}
+
+// toolCallScanner finds a tool call in a plan while it is still streaming:
+// once the top-level "tool" object of {"tool":{"name":...,"args":{...}}} has
+// closed, the call cannot change, whatever follows it.
+type toolCallScanner struct {
+	buf      []byte
+	depth    int
+	inString bool
+	escaped  bool
+	strStart int
+	// lastString is the last string closed at the top level; afterTool is
+	// set between `"tool":` and the value's first character.
+	lastString string
+	afterTool  bool
+	start      int
+	started    bool
+	done       bool
+}
+
+// feed scans the next delta and returns the tool call the first time the
+// "tool" object is complete; nil before that and after it.
+func (s *toolCallScanner) feed(delta string) *ToolCall {
+	if s.done {
+		return nil
+	}
+	from := len(s.buf)
+	s.buf = append(s.buf, delta...)
+	for i := from; i < len(s.buf); i++ {
+		c := s.buf[i]
+		if s.inString {
+			switch {
+			case s.escaped:
+				s.escaped = false
+			case c == '\\':
+				s.escaped = true
+			case c == '"':
+				s.inString = false
+				if s.depth == 1 {
+					s.lastString = string(s.buf[s.strStart+1 : i])
+				}
+			}
+			continue
+		}
+		if s.depth == 0 && c != '{' && !isJSONSpace(c) {
+			// Not a JSON object: no tool call to find.
+			s.done = true
+			return nil
+		}
+		switch c {
+		case '"':
+			s.inString, s.strStart = true, i
+			continue
+		case ':':
+			if s.depth == 1 {
+				s.afterTool = s.lastString == "tool"
+				s.lastString = ""
+				continue
+			}
+		case '{':
+			s.depth++
+			if s.depth == 2 && s.afterTool {
+				s.start, s.started = i, true
+			}
+		case '}':
+			s.depth--
+			if s.depth == 1 && s.started {
+				s.done = true
+				return parseToolObject(s.buf[s.start : i+1])
+			}
+			if s.depth == 0 {
+				s.done = true
+				return nil
+			}
+		}
+		if !isJSONSpace(c) {
+			s.afterTool = false
+		}
+	}
+	return nil
+}
+
+func isJSONSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// parseToolObject reads a "tool" object as tryParseToolCall would.
+func parseToolObject(obj []byte) *ToolCall {
+	var tool map[string]any
+	if err := json.Unmarshal(obj, &tool); err != nil {
+		return nil
+	}
+	name, _ := tool["name"].(string)
+	args, _ := tool["args"].(map[string]any)
+	if strings.TrimSpace(name) == "" {
+		return nil
+	}
+	return &ToolCall{Name: name, Args: args, Raw: map[string]any{"tool": tool}}
+}
+
+// earlyTool is a tool call dispatched while its plan was still streaming.
+type earlyTool struct {
+	call    *ToolCall
+	started time.Time
+	done    chan struct{}
+
+	out       string
+	budgetErr error
+	err       error
+}
+
+// dispatchEarly runs a streamed tool call before its plan is final, if the
+// final plan would run it too: the persona allows it, it passes validation
+// and the scratchpad holds no output to reuse. It returns nil when it does
+// not run the call.
+func (p *Planner) dispatchEarly(ctx context.Context, sessionID string, persona *Persona, notes []scratchpadEntry, call *ToolCall) *earlyTool {
+	if call == nil || !persona.allowsTool(call.Name) || tools.Validate(p.Tools(), call.Name, call.Args) != nil {
+		return nil
+	}
+	if _, ok := p.scratchpad.reuse(notes, call); ok {
+		return nil
+	}
+	logger.NewContextLogger(ctx).Info("tool_dispatched_early", "session_id", sessionID, "tool", call.Name)
+	e := &earlyTool{call: call, started: time.Now(), done: make(chan struct{})}
+	go func() {
+		defer close(e.done)
+		e.out, e.budgetErr, e.err = p.runTool(ctx, sessionID, call)
+	}()
+	return e
+}
+
+// matches reports whether the final plan's call is the one dispatched
+// (nil-safe).
+func (e *earlyTool) matches(call *ToolCall) bool {
+	return e != nil && call != nil && e.call.Name == call.Name && reflect.DeepEqual(normalizeArgs(e.call.Args), normalizeArgs(call.Args))
+}
+
+// normalizeArgs treats absent and empty args alike.
+func normalizeArgs(args map[string]any) map[string]any {
+	if len(args) == 0 {
+		return nil
+	}
+	return args
+}
+
+// wait returns the call's result.
+func (e *earlyTool) wait(ctx context.Context) (out string, budgetErr, err error) {
+	select {
+	case <-e.done:
+		return e.out, e.budgetErr, e.err
+	case <-ctx.Done():
+		return "", nil, ctx.Err()
+	}
+}
+
+// discard gives up on a call the final plan did not make (nil-safe). It
+// still runs to completion; its output is not used.
+func (e *earlyTool) discard(ctx context.Context, sessionID string) {
+	if e == nil {
+		return
+	}
+	logger.NewContextLogger(ctx).Warn("tool_early_discarded", "session_id", sessionID, "tool", e.call.Name)
+}
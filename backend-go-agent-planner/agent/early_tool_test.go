@@ -0,0 +1,73 @@
+package agent
+
+import (
+	"testing"
+)
+
+// scan feeds plan to a scanner in pieces of n bytes and returns the call it
+// found and after how many bytes.
+func scan(plan string, n int) (*ToolCall, int) {
+	s := &toolCallScanner{}
+	for i := 0; i < len(plan); i += n {
+		end := min(i+n, len(plan))
+		if call := s.feed(plan[i:end]); call != nil {
+			return call, end
+		}
+	}
+	return nil, len(plan)
+}
+
+func TestToolCallScanner(t *testing.T) {
+	for _, tc := range []struct {
+		name, plan, tool string
+		// at is how much of the plan must have arrived before the call is
+		// returned (0: no call).
+		at int
+	}{
+		{"tool call", `{"tool":{"name":"web_search","args":{"query":"lisbon"}}}`, "web_search", 55},
+		{"call before the rest of the plan", `{"tool":{"name":"web_search","args":{"query":"a}b\"{"}},"notes":["x"]}`, "web_search", 58},
+		{"whitespace", "{\n  \"tool\" : {\"name\": \"read_file\", \"args\": {\"path\": \"/tmp/a\"}}\n}", "read_file", 63},
+		{"key named tool inside another object", `{"meta":{"tool":{"name":"web_search"}},"steps":["a"]}`, "", 0},
+		{"string value tool", `{"kind":"tool","steps":["a"]}`, "", 0},
+		{"final answer", `{"steps":["Pack an umbrella"]}`, "", 0},
+		{"not JSON", `Sure! {"tool":{"name":"web_search"}}`, "", 0},
+		{"no name", `{"tool":{"args":{}}}`, "", 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, n := range []int{1, 3, len(tc.plan)} {
+				call, at := scan(tc.plan, n)
+				if tc.tool == "" {
+					if call != nil {
+						t.Fatalf("pieces of %d: found %+v", n, call)
+					}
+					continue
+				}
+				if call == nil || call.Name != tc.tool {
+					t.Fatalf("pieces of %d: call = %+v, want %s", n, call, tc.tool)
+				}
+				if at > max(tc.at, n) && at != len(tc.plan) {
+					t.Fatalf("pieces of %d: found after %d bytes, want %d", n, at, tc.at)
+				}
+				if final := tryParseToolCall(tc.plan); final != nil && !(&earlyTool{call: call}).matches(final) {
+					t.Fatalf("pieces of %d: %+v does not match the final plan's %+v", n, call, final)
+				}
+			}
+		})
+	}
+}
+
+func TestEarlyToolMatches(t *testing.T) {
+	e := &earlyTool{call: &ToolCall{Name: "web_search", Args: map[string]any{"query": "lisbon"}}}
+	if !e.matches(&ToolCall{Name: "web_search", Args: map[string]any{"query": "lisbon"}}) {
+		t.Fatal("same call does not match")
+	}
+	if e.matches(&ToolCall{Name: "web_search", Args: map[string]any{"query": "porto"}}) {
+		t.Fatal("different args match")
+	}
+	if e.matches(nil) || (*earlyTool)(nil).matches(e.call) {
+		t.Fatal("nil matches")
+	}
+	if !(&earlyTool{call: &ToolCall{Name: "list"}}).matches(&ToolCall{Name: "list", Args: map[string]any{}}) {
+		t.Fatal("absent and empty args differ")
+	}
+}
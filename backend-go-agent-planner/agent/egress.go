@@ -0,0 +1,54 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ErrResourceNotAllowed is returned by AgentLoop for resource URIs outside
+// the egress policy (PAGI_EGRESS_ALLOW).
+var ErrResourceNotAllowed = errors.New("resource URI not allowed")
+
+// checkResources rejects resource URIs the egress policy does not allow, so
+// a caller cannot point downstream fetchers at internal endpoints.
+func (p *Planner) checkResources(ctx context.Context, resources []Resource) error {
+	for i, r := range resources {
+		if err := p.egress.CheckURL(ctx, r.URI); err != nil {
+			return fmt.Errorf("%w: resources[%d]: %v", ErrResourceNotAllowed, i, err)
+		}
+	}
+	return nil
+}
+
+// checkToolURLs rejects tool calls whose arguments carry a URL the egress
+// policy does not allow. The arguments come from the model and may be
+// prompt-injected, so every string that parses as an absolute URL is checked,
+// however deeply nested.
+func (p *Planner) checkToolURLs(ctx context.Context, args any) error {
+	if p.egress == nil {
+		return nil
+	}
+	switch v := args.(type) {
+	case map[string]any:
+		for _, item := range v {
+			if err := p.checkToolURLs(ctx, item); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for _, item := range v {
+			if err := p.checkToolURLs(ctx, item); err != nil {
+				return err
+			}
+		}
+	case string:
+		s := strings.TrimSpace(v)
+		if u, err := url.Parse(s); err == nil && u.Scheme != "" && strings.Contains(s, ":/") {
+			return p.egress.CheckURL(ctx, s)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"backend-go-model-gateway/pkg/egress"
+)
+
+func TestPlannerEgressChecks(t *testing.T) {
+	policy, err := egress.New([]string{"api.search.example", "*.cdn.example"}, []string{"https"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &Planner{egress: policy}
+	ctx := context.Background()
+
+	if err := p.checkResources(ctx, []Resource{{Type: "image", URI: "https://img.cdn.example/a.png"}}); err != nil {
+		t.Fatalf("allowed resource: %v", err)
+	}
+	for _, uri := range []string{"http://img.cdn.example/a.png", "file:///etc/passwd", "https://169.254.169.254/latest/meta-data"} {
+		if err := p.checkResources(ctx, []Resource{{Type: "doc", URI: uri}}); !errors.Is(err, ErrResourceNotAllowed) {
+			t.Errorf("resource %q: %v, want ErrResourceNotAllowed", uri, err)
+		}
+	}
+
+	// URLs anywhere in the model's tool arguments are checked; other strings are not.
+	if err := p.checkToolURLs(ctx, map[string]any{"query": "weather: sunny", "url": "https://api.search.example/q"}); err != nil {
+		t.Fatalf("allowed tool args: %v", err)
+	}
+	injected := map[string]any{"requests": []any{map[string]any{"target": "https://127.0.0.1:8200/v1/secret"}}}
+	if err := p.checkToolURLs(ctx, injected); !errors.Is(err, egress.ErrDenied) {
+		t.Fatalf("nested internal URL: %v, want egress.ErrDenied", err)
+	}
+	if err := (&Planner{}).checkToolURLs(ctx, injected); err != nil {
+		t.Fatalf("no policy: %v", err)
+	}
+}
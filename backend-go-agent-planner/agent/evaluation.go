@@ -0,0 +1,128 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"backend-go-agent-planner/internal/logger"
+	"backend-go-model-gateway/pkg/answereval"
+	pb "backend-go-model-gateway/proto/proto"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Evaluation modes (AGENT_EVALUATION).
+const (
+	EvaluationOff       = "off"
+	EvaluationHeuristic = "heuristic"
+	EvaluationLLM       = "llm"
+)
+
+// evaluationWindow is how many recent scores GET /admin/status averages.
+const evaluationWindow = 100
+
+// evaluation is the result of scoring one final answer.
+type evaluation struct {
+	answereval.Scores
+	Rationale string `json:"rationale,omitempty"`
+	Model     string `json:"model,omitempty"`
+}
+
+// evaluationStats keeps the recent scores for GET /admin/status.
+type evaluationStats struct {
+	mu     sync.Mutex
+	total  int
+	recent [evaluationWindow]float64
+	next   int
+}
+
+func (s *evaluationStats) add(score float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.recent[s.next] = score
+	s.next = (s.next + 1) % evaluationWindow
+	s.total++
+}
+
+func (s *evaluationStats) snapshot() map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := min(s.total, evaluationWindow)
+	out := map[string]any{"evaluated": s.total}
+	if n > 0 {
+		sum := 0.0
+		for _, v := range s.recent[:n] {
+			sum += v
+		}
+		out["recent_mean_score"] = sum / float64(n)
+	}
+	return out
+}
+
+// evaluateInBackground scores a successful run's answer after AgentLoop has
+// returned, so evaluation (an extra model call in llm mode) adds no latency.
+// A sample of runs is scored, as set by AGENT_EVALUATION_SAMPLE_RATE.
+func (p *Planner) evaluateInBackground(ctx context.Context, t *loopTuning, sessionID, prompt, answer string, matches []*pb.RAGMatch) {
+	if t.evaluation == "" || t.evaluation == EvaluationOff || rand.Float64() >= t.evalSampleRate {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+	p.evaluations.Add(1)
+	go func() {
+		defer p.evaluations.Done()
+		ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
+		defer cancel()
+
+		e, err := p.evaluate(ctx, t.evaluation, prompt, answer, matches)
+		outcome := "scored"
+		if err != nil {
+			outcome = "error"
+			logger.NewContextLogger(ctx).Warn("answer_evaluation_failed", "mode", t.evaluation, "error", err)
+		}
+		if evaluationsTotal != nil {
+			evaluationsTotal.Add(ctx, 1, metric.WithAttributes(attribute.String("mode", t.evaluation), attribute.String("outcome", outcome)))
+		}
+		if err != nil {
+			return
+		}
+		if answerScore != nil {
+			answerScore.Record(ctx, e.Score, metric.WithAttributes(attribute.String("mode", t.evaluation), attribute.String("prompt_version", promptVersionFromContext(ctx))))
+		}
+		p.evalStats.add(e.Score)
+		_ = p.RecordStep(ctx, sessionID, "EVALUATION", map[string]any{
+			"mode": t.evaluation, "score": e.Score, "relevance": e.Relevance, "groundedness": e.Groundedness,
+			"rationale": e.Rationale, "model": e.Model,
+		})
+	}()
+}
+
+// evaluate scores answer against the prompt and the run's retrieved matches.
+func (p *Planner) evaluate(ctx context.Context, mode, prompt, answer string, matches []*pb.RAGMatch) (*evaluation, error) {
+	passages := make([]string, 0, len(matches))
+	for _, m := range matches {
+		passages = append(passages, m.GetText())
+	}
+	switch mode {
+	case EvaluationHeuristic:
+		return &evaluation{Scores: answereval.Heuristic(prompt, answer, passages)}, nil
+	case EvaluationLLM:
+		if p.modelClient == nil {
+			return nil, fmt.Errorf("model client is nil")
+		}
+		resp, err := p.modelClient.EvaluateAnswer(ctx, &pb.EvaluateRequest{Prompt: prompt, Answer: answer, Context: passages})
+		if err != nil {
+			return nil, fmt.Errorf("EvaluateAnswer: %w", err)
+		}
+		return &evaluation{
+			Scores:    answereval.Scores{Relevance: resp.GetRelevance(), Groundedness: resp.GetGroundedness(), Score: resp.GetScore()},
+			Rationale: resp.GetRationale(),
+			Model:     resp.GetModelName(),
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown AGENT_EVALUATION mode %q", mode)
+	}
+}
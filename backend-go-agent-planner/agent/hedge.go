@@ -0,0 +1,140 @@
+package agent
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const (
+	// hedgeWindow is how many recent successful latencies the p95 is taken over.
+	hedgeWindow = 128
+	// hedgeMinSamples is the history needed before the p95 is trusted; until
+	// then calls are not hedged (unless a fixed delay is configured).
+	hedgeMinSamples = 20
+)
+
+// hedger sends a second attempt when the first has not answered within the
+// recent p95 latency (or a fixed delay) and keeps whichever answers first. A
+// nil *hedger never hedges.
+type hedger struct {
+	fixed time.Duration // 0: use the observed p95
+
+	mu      sync.Mutex
+	samples [hedgeWindow]time.Duration
+	n       int // samples recorded, up to hedgeWindow
+	next    int
+}
+
+func newHedger(enabled bool, fixed time.Duration) *hedger {
+	if !enabled {
+		return nil
+	}
+	return &hedger{fixed: fixed}
+}
+
+func (h *hedger) observe(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.samples[h.next] = d
+	h.next = (h.next + 1) % hedgeWindow
+	if h.n < hedgeWindow {
+		h.n++
+	}
+}
+
+// delay is how long to wait before hedging; false while there is not enough
+// history to know the p95.
+func (h *hedger) delay() (time.Duration, bool) {
+	if h.fixed > 0 {
+		return h.fixed, true
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.n < hedgeMinSamples {
+		return 0, false
+	}
+	sorted := make([]time.Duration, h.n)
+	copy(sorted, h.samples[:h.n])
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[(h.n*95+99)/100-1], true
+}
+
+// hedged runs call and, if it is still running after h.delay(), a second
+// identical call. The first success wins and the other attempt is cancelled.
+// An error before the hedge fires is returned as is (retrying is the
+// breaker's job); once both are running, one failing waits for the other.
+func hedged[T any](ctx context.Context, h *hedger, call func(context.Context) (T, error)) (T, error) {
+	if h == nil {
+		return call(ctx)
+	}
+	delay, ok := h.delay()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	type result struct {
+		v     T
+		err   error
+		hedge bool
+	}
+	results := make(chan result, 2)
+	attempt := func(hedge bool) {
+		start := time.Now()
+		v, err := call(ctx)
+		if err == nil {
+			h.observe(time.Since(start))
+		}
+		results <- result{v: v, err: err, hedge: hedge}
+	}
+	go attempt(false)
+
+	var fire <-chan time.Time
+	if ok {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		fire = timer.C
+	}
+	inflight, fired := 1, false
+	var firstErr error
+	for {
+		select {
+		case <-fire:
+			fire, fired = nil, true
+			inflight++
+			go attempt(true)
+		case r := <-results:
+			inflight--
+			if r.err == nil {
+				if fired && r.hedge {
+					recordHedge(ctx, "hedge")
+				} else if fired {
+					recordHedge(ctx, "primary")
+				}
+				return r.v, nil
+			}
+			if firstErr == nil {
+				firstErr = r.err
+			}
+			if inflight == 0 || !fired {
+				if fired {
+					recordHedge(ctx, "none")
+				}
+				var zero T
+				return zero, firstErr
+			}
+		}
+	}
+}
+
+// recordHedge counts a fired hedge by which attempt answered: "primary",
+// "hedge" or "none" when both failed.
+func recordHedge(ctx context.Context, winner string) {
+	if ragHedges == nil {
+		return
+	}
+	ragHedges.Add(context.WithoutCancel(ctx), 1, metric.WithAttributes(attribute.String("winner", winner)))
+}
@@ -0,0 +1,83 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHedger_DelayIsP95(t *testing.T) {
+	h := newHedger(true, 0)
+	for i := 1; i < hedgeMinSamples; i++ {
+		h.observe(time.Duration(i) * time.Millisecond)
+	}
+	if _, ok := h.delay(); ok {
+		t.Fatal("hedged before enough samples")
+	}
+	for i := hedgeMinSamples; i <= 100; i++ {
+		h.observe(time.Duration(i) * time.Millisecond)
+	}
+	if d, ok := h.delay(); !ok || d != 95*time.Millisecond {
+		t.Fatalf("delay = %v, %v; want 95ms", d, ok)
+	}
+	if d, _ := newHedger(true, time.Second).delay(); d != time.Second {
+		t.Fatalf("fixed delay = %v", d)
+	}
+	if newHedger(false, time.Second) != nil {
+		t.Fatal("disabled hedger should be nil")
+	}
+}
+
+func TestHedged_SecondAttemptWinsWhenFirstStalls(t *testing.T) {
+	h := newHedger(true, 10*time.Millisecond)
+	var calls atomic.Int32
+	primaryCancelled := make(chan struct{})
+	got, err := hedged(context.Background(), h, func(ctx context.Context) (string, error) {
+		if calls.Add(1) == 1 {
+			<-ctx.Done() // the stalled first attempt is cancelled once the hedge wins
+			close(primaryCancelled)
+			return "", ctx.Err()
+		}
+		return "hedge", nil
+	})
+	if err != nil || got != "hedge" {
+		t.Fatalf("hedged = %q, %v", got, err)
+	}
+	select {
+	case <-primaryCancelled:
+	case <-time.After(time.Second):
+		t.Fatal("stalled attempt was not cancelled")
+	}
+	if calls.Load() != 2 {
+		t.Fatalf("calls = %d, want 2", calls.Load())
+	}
+}
+
+func TestHedged_NoHedgeForFastOrFailedCalls(t *testing.T) {
+	h := newHedger(true, 50*time.Millisecond)
+	var calls atomic.Int32
+	if _, err := hedged(context.Background(), h, func(context.Context) (int, error) {
+		calls.Add(1)
+		return 1, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	boom := errors.New("unavailable")
+	if _, err := hedged(context.Background(), h, func(context.Context) (int, error) {
+		calls.Add(1)
+		return 0, boom
+	}); !errors.Is(err, boom) {
+		t.Fatalf("err = %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if calls.Load() != 2 {
+		t.Fatalf("calls = %d, want 2 (no hedges)", calls.Load())
+	}
+
+	// A nil hedger (AGENT_RAG_HEDGE off) just calls through.
+	if v, err := hedged(context.Background(), nil, func(context.Context) (int, error) { return 7, nil }); v != 7 || err != nil {
+		t.Fatalf("nil hedger = %d, %v", v, err)
+	}
+}
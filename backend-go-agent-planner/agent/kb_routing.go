@@ -0,0 +1,139 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// KBRoutingRule routes prompts mentioning any of Keywords (words or phrases,
+// case-insensitive) to KB at depth TopK (0: the configured AGENT_RAG_TOP_K).
+type KBRoutingRule struct {
+	KB       string   `json:"kb"`
+	Keywords []string `json:"keywords"`
+	TopK     int      `json:"top_k"`
+}
+
+// defaultKBRoutingRules route by the KBs' conventional contents: Body-KB holds
+// the user's schedule, routines and tool specs, Soul-KB the assistant's and
+// user's values and identity, Domain-KB general knowledge.
+var defaultKBRoutingRules = []KBRoutingRule{
+	{KB: "Body-KB", Keywords: []string{
+		"schedule", "calendar", "meeting", "meetings", "appointment", "appointments", "today", "tomorrow", "tonight",
+		"routine", "routines", "habit", "habits", "sleep", "exercise", "workout", "run", "running", "health", "diet", "meal",
+		"reminder", "remind", "tool", "tools", "api",
+	}},
+	{KB: "Soul-KB", Keywords: []string{
+		"value", "values", "believe", "belief", "beliefs", "principle", "principles", "ethics", "ethical", "moral",
+		"purpose", "meaning", "mission", "identity", "who are you", "personality", "tone", "priorities",
+	}},
+	{KB: "Domain-KB", Keywords: []string{
+		"explain", "define", "definition", "fact", "facts", "research", "compare", "history", "science",
+		"news", "latest", "current", "what is", "how does",
+	}},
+}
+
+// kbQuery is one KB to retrieve from and how many matches to take.
+type kbQuery struct {
+	KB   string `json:"kb"`
+	TopK int    `json:"top_k"`
+}
+
+// kbRouter chooses the KBs and depth for a prompt with a keyword heuristic, so
+// a schedule question does not spend context on value statements.
+type kbRouter struct {
+	rules []KBRoutingRule
+	// otherTopK is the depth for KBs no rule picked; 0 skips them.
+	otherTopK int
+}
+
+// newKBRouter builds the router for cfg.KBRouting; it returns nil (query every
+// KB at the same depth) when routing is off.
+func newKBRouter(cfg Config) (*kbRouter, error) {
+	switch cfg.KBRouting {
+	case "off":
+		return nil, nil
+	case "keywords", "":
+	default:
+		return nil, fmt.Errorf("unsupported AGENT_KB_ROUTING=%q (supported: keywords, off)", cfg.KBRouting)
+	}
+	r := &kbRouter{rules: defaultKBRoutingRules, otherTopK: cfg.KBRoutingOtherTopK}
+	if cfg.KBRoutesPath != "" {
+		b, err := os.ReadFile(cfg.KBRoutesPath)
+		if err != nil {
+			return nil, fmt.Errorf("AGENT_KB_ROUTES_PATH: %w", err)
+		}
+		var rules []KBRoutingRule
+		if err := json.Unmarshal(b, &rules); err != nil {
+			return nil, fmt.Errorf("AGENT_KB_ROUTES_PATH %s: %w", cfg.KBRoutesPath, err)
+		}
+		for i, rule := range rules {
+			if rule.KB == "" || len(rule.Keywords) == 0 || rule.TopK < 0 {
+				return nil, fmt.Errorf("AGENT_KB_ROUTES_PATH %s: rule %d: want a kb, keywords and a non-negative top_k", cfg.KBRoutesPath, i)
+			}
+		}
+		r.rules = rules
+	}
+	return r, nil
+}
+
+// Route returns the queries for prompt over kbs, in kbs order. KBs picked by a
+// rule get the rule's depth, the others otherTopK. A prompt no rule matches,
+// like a nil router, queries every KB at topK. Mind-KB is never routed:
+// playbooks match the shape of a task, not its topic.
+func (r *kbRouter) Route(prompt string, kbs []string, topK int) []kbQuery {
+	otherTopK := 0
+	if r != nil {
+		otherTopK = r.otherTopK
+	}
+	return r.route(prompt, kbs, topK, otherTopK)
+}
+
+// Essential is Route for a run over its latency budget: no Mind-KB playbook
+// lookup and, when a rule matched, none of the other KBs.
+func (r *kbRouter) Essential(prompt string, kbs []string, topK int) []kbQuery {
+	kbs = slices.DeleteFunc(slices.Clone(kbs), func(kb string) bool { return kb == "Mind-KB" })
+	return r.route(prompt, kbs, topK, 0)
+}
+
+func (r *kbRouter) route(prompt string, kbs []string, topK, otherTopK int) []kbQuery {
+	depth := map[string]int{}
+	if r != nil {
+		text := " " + strings.Join(strings.FieldsFunc(strings.ToLower(prompt), func(c rune) bool {
+			return !unicode.IsLetter(c) && !unicode.IsNumber(c)
+		}), " ") + " "
+		for _, rule := range r.rules {
+			if !slices.Contains(kbs, rule.KB) {
+				continue
+			}
+			for _, kw := range rule.Keywords {
+				if strings.Contains(text, " "+strings.ToLower(kw)+" ") {
+					d := rule.TopK
+					if d == 0 {
+						d = topK
+					}
+					depth[rule.KB] = max(depth[rule.KB], d)
+					break
+				}
+			}
+		}
+	}
+
+	queries := make([]kbQuery, 0, len(kbs))
+	for _, kb := range kbs {
+		d, routed := depth[kb]
+		switch {
+		case len(depth) == 0 || kb == "Mind-KB":
+			d = topK
+		case !routed:
+			d = otherTopK
+		}
+		if d > 0 {
+			queries = append(queries, kbQuery{KB: kb, TopK: d})
+		}
+	}
+	return queries
+}
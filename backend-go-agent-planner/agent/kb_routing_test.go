@@ -0,0 +1,95 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestKBRouter_Route(t *testing.T) {
+	kbs := []string{"Mind-KB", "Domain-KB", "Body-KB", "Soul-KB"}
+	r, err := newKBRouter(Config{KBRoutingOtherTopK: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		prompt string
+		want   []kbQuery
+	}{
+		{
+			prompt: "What's on my schedule tomorrow?",
+			want:   []kbQuery{{"Mind-KB", 3}, {"Domain-KB", 1}, {"Body-KB", 3}, {"Soul-KB", 1}},
+		},
+		{
+			prompt: "Which of my VALUES should guide this decision?",
+			want:   []kbQuery{{"Mind-KB", 3}, {"Domain-KB", 1}, {"Body-KB", 1}, {"Soul-KB", 3}},
+		},
+		{
+			// No rule matches: every KB at the default depth.
+			prompt: "hello there",
+			want:   []kbQuery{{"Mind-KB", 3}, {"Domain-KB", 3}, {"Body-KB", 3}, {"Soul-KB", 3}},
+		},
+	} {
+		if got := r.Route(tc.prompt, kbs, 3); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("Route(%q) = %v, want %v", tc.prompt, got, tc.want)
+		}
+	}
+
+	// Routing off: every KB at the default depth.
+	var off *kbRouter
+	if got := off.Route("what's on my schedule?", kbs[1:], 2); !reflect.DeepEqual(got, []kbQuery{{"Domain-KB", 2}, {"Body-KB", 2}, {"Soul-KB", 2}}) {
+		t.Errorf("nil router: got %v", got)
+	}
+}
+
+func TestKBRouter_Essential(t *testing.T) {
+	kbs := []string{"Mind-KB", "Domain-KB", "Body-KB", "Soul-KB"}
+	r, err := newKBRouter(Config{KBRoutingOtherTopK: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Routed: only the routed KBs, no playbooks.
+	if got := r.Essential("What's on my schedule tomorrow?", kbs, 3); !reflect.DeepEqual(got, []kbQuery{{"Body-KB", 3}}) {
+		t.Errorf("routed: got %v", got)
+	}
+	// Unrouted: every KB but Mind-KB.
+	got := r.Essential("hello there", kbs, 3)
+	if !reflect.DeepEqual(got, []kbQuery{{"Domain-KB", 3}, {"Body-KB", 3}, {"Soul-KB", 3}}) {
+		t.Errorf("unrouted: got %v", got)
+	}
+	if dropped := droppedKBs(r.Route("hello there", kbs, 3), got); !reflect.DeepEqual(dropped, []string{"Mind-KB"}) {
+		t.Errorf("dropped = %v", dropped)
+	}
+	if kbs[0] != "Mind-KB" {
+		t.Errorf("Essential modified its input: %v", kbs)
+	}
+}
+
+func TestNewKBRouter_RulesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "routes.json")
+	rules := `[{"kb": "Body-KB", "keywords": ["marathon", "race day"], "top_k": 6}]`
+	if err := os.WriteFile(path, []byte(rules), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	r, err := newKBRouter(Config{KBRoutesPath: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Other KBs are skipped with KBRoutingOtherTopK 0.
+	got := r.Route("How should I taper before race day?", []string{"Domain-KB", "Body-KB"}, 3)
+	if !reflect.DeepEqual(got, []kbQuery{{"Body-KB", 6}}) {
+		t.Fatalf("got %v, want only Body-KB at 6", got)
+	}
+
+	if _, err := newKBRouter(Config{KBRouting: "llm"}); err == nil {
+		t.Fatal("want an error for an unsupported mode")
+	}
+	if err := os.WriteFile(path, []byte(`[{"kb": "Body-KB"}]`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newKBRouter(Config{KBRoutesPath: path}); err == nil {
+		t.Fatal("want an error for a rule without keywords")
+	}
+}
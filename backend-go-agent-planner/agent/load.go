@@ -0,0 +1,137 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/sony/gobreaker"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// ErrOverloaded is returned when an AgentLoop waited AGENT_LOOP_QUEUE_TIMEOUT
+// for a slot under AGENT_MAX_CONCURRENT_LOOPS without getting one.
+var ErrOverloaded = errors.New("planner at capacity")
+
+// loopLoad counts running and queued AgentLoops for the autoscaling metrics
+// and, when a limit is set, makes loops over it wait for a slot. A nil
+// *loopLoad admits everything and counts nothing.
+type loopLoad struct {
+	slots        chan struct{} // nil: no limit
+	capacity     int
+	queueTimeout time.Duration
+
+	running atomic.Int64
+	pending atomic.Int64
+}
+
+func newLoopLoad(cfg Config) *loopLoad {
+	l := &loopLoad{capacity: cfg.LoopCapacity, queueTimeout: cfg.LoopQueueTimeout}
+	if cfg.MaxConcurrentLoops > 0 {
+		l.slots = make(chan struct{}, cfg.MaxConcurrentLoops)
+		l.capacity = cfg.MaxConcurrentLoops
+	}
+	if l.capacity <= 0 {
+		l.capacity = 1
+	}
+	return l
+}
+
+// acquire waits for a slot and counts the loop as running until release.
+func (l *loopLoad) acquire(ctx context.Context) (release func(), err error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	if l.slots != nil {
+		l.pending.Add(1)
+		wait := time.NewTimer(l.queueTimeout)
+		select {
+		case l.slots <- struct{}{}:
+		case <-wait.C:
+			err = ErrOverloaded
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+		wait.Stop()
+		l.pending.Add(-1)
+		if err != nil {
+			return nil, err
+		}
+	}
+	l.running.Add(1)
+	return func() {
+		l.running.Add(-1)
+		if l.slots != nil {
+			<-l.slots
+		}
+	}, nil
+}
+
+// saturation is (running + queued loops) / capacity: 1 means the replica is
+// at its sizing, above 1 loops are queueing.
+func (l *loopLoad) saturation() float64 {
+	if l == nil {
+		return 0
+	}
+	return float64(l.running.Load()+l.pending.Load()) / float64(l.capacity)
+}
+
+// registerLoadMetrics exports the planner's load as gauges on /metrics:
+//
+//	agent_loops_running, agent_loops_pending      AgentLoops in progress and queued
+//	agent_saturation                              see loopLoad.saturation
+//	agent_circuit_breaker_open{dependency}        1 while a breaker is open
+//	agent_tool_budget_remaining                   calls left in the hourly tool budget
+func (p *Planner) registerLoadMetrics() error {
+	m := otel.Meter("backend-go-agent-planner")
+	running, err := m.Int64ObservableGauge("agent_loops_running",
+		metric.WithDescription("AgentLoops currently executing."), metric.WithUnit("1"))
+	if err != nil {
+		return err
+	}
+	pending, err := m.Int64ObservableGauge("agent_loops_pending",
+		metric.WithDescription("AgentLoops waiting for a slot under AGENT_MAX_CONCURRENT_LOOPS."), metric.WithUnit("1"))
+	if err != nil {
+		return err
+	}
+	saturation, err := m.Float64ObservableGauge("agent_saturation",
+		metric.WithDescription("(running + pending AgentLoops) / capacity; scale out above the target, e.g. 0.8."), metric.WithUnit("1"))
+	if err != nil {
+		return err
+	}
+	breakerOpen, err := m.Int64ObservableGauge(breakerOpenMetric.name,
+		metric.WithDescription("1 while the circuit breaker for a dependency is open."), metric.WithUnit(breakerOpenMetric.unit))
+	if err != nil {
+		return err
+	}
+	budgetRemaining, err := m.Float64ObservableGauge(toolBudgetRemainingMetric.name,
+		metric.WithDescription("Calls to budgeted tools left in this replica's hourly budget (AGENT_TOOL_BUDGET_PER_HOUR)."), metric.WithUnit(toolBudgetRemainingMetric.unit))
+	if err != nil {
+		return err
+	}
+	_, err = m.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		if p.load != nil {
+			o.ObserveInt64(running, p.load.running.Load())
+			o.ObserveInt64(pending, p.load.pending.Load())
+		}
+		o.ObserveFloat64(saturation, p.load.saturation())
+		for name, cb := range map[string]*gobreaker.CircuitBreaker{"model_gateway": p.modelBreaker, "memory_service": p.memoryBreaker} {
+			if cb == nil {
+				continue
+			}
+			var open int64
+			if cb.State() == gobreaker.StateOpen {
+				open = 1
+			}
+			o.ObserveInt64(breakerOpen, open, metric.WithAttributes(attribute.String("dependency", name)))
+		}
+		if remaining := p.toolBudget.remaining(); remaining >= 0 {
+			o.ObserveFloat64(budgetRemaining, remaining)
+		}
+		return nil
+	}, running, pending, saturation, breakerOpen, budgetRemaining)
+	return err
+}
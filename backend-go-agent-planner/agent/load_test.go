@@ -0,0 +1,87 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestLoopLoad_QueuesOverLimit(t *testing.T) {
+	l := newLoopLoad(Config{MaxConcurrentLoops: 1, LoopQueueTimeout: 50 * time.Millisecond})
+	release, err := l.acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The second loop waits; past the queue timeout it is rejected.
+	if _, err := l.acquire(context.Background()); !errors.Is(err, ErrOverloaded) {
+		t.Fatalf("over limit: %v, want ErrOverloaded", err)
+	}
+
+	l.queueTimeout = time.Second
+	acquired := make(chan func())
+	go func() {
+		r, err := l.acquire(context.Background())
+		if err != nil {
+			t.Error(err)
+		}
+		acquired <- r
+	}()
+	deadline := time.Now().Add(time.Second)
+	for l.pending.Load() != 1 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if got := l.saturation(); got != 2 {
+		t.Fatalf("saturation with one running and one queued = %v, want 2", got)
+	}
+	release()
+	(<-acquired)()
+	if l.running.Load() != 0 || l.pending.Load() != 0 {
+		t.Fatalf("after release: running %d, pending %d", l.running.Load(), l.pending.Load())
+	}
+
+	var unlimited *loopLoad
+	if r, err := unlimited.acquire(context.Background()); err != nil || unlimited.saturation() != 0 {
+		t.Fatalf("nil load: %v", err)
+	} else {
+		r()
+	}
+}
+
+func TestRegisterLoadMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	prev := otel.GetMeterProvider()
+	otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+	t.Cleanup(func() { otel.SetMeterProvider(prev) })
+
+	p := &Planner{load: newLoopLoad(Config{LoopCapacity: 4})}
+	if err := p.registerLoadMetrics(); err != nil {
+		t.Fatal(err)
+	}
+	release, _ := p.load.acquire(context.Background())
+	defer release()
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]any{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			switch d := m.Data.(type) {
+			case metricdata.Gauge[int64]:
+				got[m.Name] = d.DataPoints[0].Value
+			case metricdata.Gauge[float64]:
+				got[m.Name] = d.DataPoints[0].Value
+			}
+		}
+	}
+	if got["agent_loops_running"] != int64(1) || got["agent_loops_pending"] != int64(0) || got["agent_saturation"] != 0.25 {
+		t.Fatalf("gauges = %v", got)
+	}
+}
@@ -0,0 +1,100 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"backend-go-agent-planner/internal/logger"
+	pb "backend-go-model-gateway/proto/proto"
+)
+
+// Mock tool modes (AGENT_MOCK_TOOLS).
+const (
+	// MockToolsAuto runs mock tools while the gateway reports mock mode.
+	MockToolsAuto = "auto"
+	MockToolsOn   = "on"
+	MockToolsOff  = "off"
+)
+
+// mockToolsRefresh is how long a GetCapabilities answer is trusted; the
+// gateway can switch providers on POST /admin/reload-config.
+const mockToolsRefresh = 30 * time.Second
+
+// mockTools decides whether tool calls go to the sandbox or to
+// mockprovider.ExecuteTool's canned results, so the whole loop can be demoed
+// against a mock gateway with no sandbox or web access.
+type mockTools struct {
+	mode string
+
+	mu      sync.Mutex
+	checked time.Time
+	mock    bool
+}
+
+func newMockTools(mode string) (*mockTools, error) {
+	switch mode {
+	case "", MockToolsAuto:
+		return &mockTools{mode: MockToolsAuto}, nil
+	case MockToolsOn, MockToolsOff:
+		return &mockTools{mode: mode}, nil
+	}
+	return nil, fmt.Errorf("unknown AGENT_MOCK_TOOLS mode %q (want auto, on or off)", mode)
+}
+
+// useMockTools reports whether this tool call should run against mock tools.
+// In auto mode it asks the gateway's GetCapabilities at most every
+// mockToolsRefresh; a gateway that cannot answer (an older one, or one that
+// is down) keeps the last answer, initially "not mock".
+func (p *Planner) useMockTools(ctx context.Context) bool {
+	m := p.mockTools
+	if m == nil || m.mode == MockToolsOff {
+		return false
+	}
+	if m.mode == MockToolsOn {
+		return true
+	}
+
+	m.mu.Lock()
+	if time.Since(m.checked) < mockToolsRefresh || p.modelClient == nil {
+		defer m.mu.Unlock()
+		return m.mock
+	}
+	// Other calls use the cached answer while this one refreshes it.
+	m.checked = time.Now()
+	was := m.mock
+	m.mu.Unlock()
+
+	checkCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	caps, err := p.modelClient.GetCapabilities(checkCtx, &pb.CapabilitiesRequest{})
+	lg := logger.NewContextLogger(ctx)
+	if err != nil {
+		lg.Warn("gateway_capabilities_unavailable", "error", err)
+		return was
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.mock = caps.GetMock()
+	if m.mock != was {
+		lg.Info("mock_tools_switched", "mock", m.mock, "gateway_provider", caps.GetProvider())
+	}
+	return m.mock
+}
+
+// mockToolsStatus is the mock tools' part of GET /admin/status.
+func (p *Planner) mockToolsStatus() map[string]any {
+	m := p.mockTools
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	active := m.mock
+	if m.mode != MockToolsAuto {
+		active = m.mode == MockToolsOn
+	}
+	return map[string]any{"mode": m.mode, "active": active}
+}
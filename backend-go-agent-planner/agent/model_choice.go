@@ -0,0 +1,52 @@
+package agent
+
+import (
+	"os"
+	"strconv"
+
+	pb "backend-go-model-gateway/proto/proto"
+)
+
+// ModelChoice is the gateway provider, model and sampling temperature a
+// planner turn asks for. Empty fields leave the gateway's defaults; the
+// gateway only honors a provider from its LLM_PROVIDERS and a model from its
+// LLM_ALLOWED_MODELS, and clamps the temperature to its range.
+type ModelChoice struct {
+	Provider    string   `json:"provider,omitempty"`
+	Model       string   `json:"model,omitempty"`
+	Temperature *float32 `json:"temperature,omitempty"`
+}
+
+// modelChoice picks the turn's model: RoutingModel while the model decides
+// which tool to call, SynthesisModel once a tool result is in the prompt and
+// it is likely to write the final answer.
+func (t *loopTuning) modelChoice(conv *Conversation) ModelChoice {
+	for _, turn := range conv.Turns {
+		if turn.Error == "" {
+			return t.synthesisModel
+		}
+	}
+	return t.routingModel
+}
+
+// apply sets the choice on a GetPlan request. A persona's own model wins:
+// it is the persona's, not a cost setting. The temperature applies either way.
+func (c ModelChoice) apply(req *pb.PlanRequest) {
+	if c.Temperature != nil {
+		req.Temperature = c.Temperature
+	}
+	if req.GetModel() != "" {
+		return
+	}
+	req.Provider, req.Model = c.Provider, c.Model
+}
+
+// envTemperature reads a temperature setting; unset or not a number is nil.
+func envTemperature(key string) *float32 {
+	f, err := strconv.ParseFloat(os.Getenv(key), 32)
+	if err != nil {
+		return nil
+	}
+	t := float32(f)
+	return &t
+}
@@ -0,0 +1,112 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+
+	pb "backend-go-model-gateway/proto/proto"
+)
+
+// ErrUnknownPersona is returned by AgentLoop for a persona that is not in
+// AGENT_PERSONAS_PATH.
+var ErrUnknownPersona = errors.New("unknown persona")
+
+// Persona is a named twin personality. One deployment serves several: each
+// request picks one (or gets AGENT_DEFAULT_PERSONA), and its settings are
+// applied to retrieval, tool calls and every GetPlan of the run.
+type Persona struct {
+	// SystemPrompt is placed before the gateway's own instructions.
+	SystemPrompt string `json:"system_prompt"`
+	// KnowledgeBases replaces the planner's KB list (empty: keep it).
+	KnowledgeBases []string `json:"knowledge_bases"`
+	// AllowedTools limits the tools offered to and run for the model. Omitted
+	// allows every tool; an empty list allows none.
+	AllowedTools []string `json:"allowed_tools"`
+	// Model is the preferred model, used when the gateway's
+	// LLM_ALLOWED_MODELS permits it.
+	Model string `json:"model"`
+}
+
+// noTools is sent as allowed_tools for a persona that allows none, since an
+// empty list means "every tool" on the wire.
+const noTools = "none"
+
+// loadPersonas reads AGENT_PERSONAS_PATH, a JSON object of Persona by name.
+func loadPersonas(cfg Config) (map[string]*Persona, error) {
+	personas := map[string]*Persona{}
+	if cfg.PersonasPath != "" {
+		b, err := os.ReadFile(cfg.PersonasPath)
+		if err != nil {
+			return nil, fmt.Errorf("AGENT_PERSONAS_PATH: %w", err)
+		}
+		if err := json.Unmarshal(b, &personas); err != nil {
+			return nil, fmt.Errorf("AGENT_PERSONAS_PATH %s: %w", cfg.PersonasPath, err)
+		}
+		for name, persona := range personas {
+			if name == "" || persona == nil {
+				return nil, fmt.Errorf("AGENT_PERSONAS_PATH %s: personas need a name and settings", cfg.PersonasPath)
+			}
+		}
+	}
+	if cfg.DefaultPersona != "" && personas[cfg.DefaultPersona] == nil {
+		return nil, fmt.Errorf("AGENT_DEFAULT_PERSONA %q: %w", cfg.DefaultPersona, ErrUnknownPersona)
+	}
+	return personas, nil
+}
+
+type personaKey struct{}
+
+// ContextWithPersona records the persona a request asked for.
+func ContextWithPersona(ctx context.Context, persona string) context.Context {
+	return context.WithValue(ctx, personaKey{}, persona)
+}
+
+// PersonaFromContext returns the persona the request asked for, or "".
+func PersonaFromContext(ctx context.Context) string {
+	persona, _ := ctx.Value(personaKey{}).(string)
+	return persona
+}
+
+// persona resolves the request's persona (or the default) to its name and
+// settings; both are empty when neither is set.
+func (t *loopTuning) persona(ctx context.Context) (string, *Persona, error) {
+	name := PersonaFromContext(ctx)
+	if name == "" {
+		name = t.defaultPersona
+	}
+	if name == "" {
+		return "", nil, nil
+	}
+	persona := t.personas[name]
+	if persona == nil {
+		return "", nil, fmt.Errorf("%w: %q", ErrUnknownPersona, name)
+	}
+	return name, persona, nil
+}
+
+// allowsTool reports whether the persona may run tool (a nil persona may run
+// every tool).
+func (p *Persona) allowsTool(tool string) bool {
+	return p == nil || p.AllowedTools == nil || slices.Contains(p.AllowedTools, tool)
+}
+
+// apply copies the persona's settings onto a GetPlan request.
+func (p *Persona) apply(name string, req *pb.PlanRequest) {
+	if p == nil {
+		return
+	}
+	req.Persona = name
+	req.SystemPrompt = p.SystemPrompt
+	req.Model = p.Model
+	switch {
+	case p.AllowedTools == nil:
+	case len(p.AllowedTools) == 0:
+		req.AllowedTools = []string{noTools}
+	default:
+		req.AllowedTools = p.AllowedTools
+	}
+}
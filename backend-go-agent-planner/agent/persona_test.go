@@ -0,0 +1,58 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	pb "backend-go-model-gateway/proto/proto"
+)
+
+func TestLoadPersonas(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "personas.json")
+	raw := `{"work-assistant": {"allowed_tools": ["web_search"], "model": "m"}, "companion": {"allowed_tools": []}, "open": {}}`
+	if err := os.WriteFile(path, []byte(raw), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	personas, err := loadPersonas(Config{PersonasPath: path, DefaultPersona: "open"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tuning := &loopTuning{personas: personas, defaultPersona: "open"}
+
+	name, persona, err := tuning.persona(ContextWithPersona(context.Background(), "work-assistant"))
+	if err != nil || name != "work-assistant" {
+		t.Fatalf("persona = %q, %v", name, err)
+	}
+	req := &pb.PlanRequest{}
+	persona.apply(name, req)
+	if req.GetPersona() != "work-assistant" || req.GetModel() != "m" || !reflect.DeepEqual(req.GetAllowedTools(), []string{"web_search"}) {
+		t.Fatalf("request = %+v", req)
+	}
+	if !persona.allowsTool("web_search") || persona.allowsTool("send_email") {
+		t.Fatal("work-assistant should allow web_search only")
+	}
+
+	// An empty list allows no tool; omitting it allows every tool.
+	req = &pb.PlanRequest{}
+	personas["companion"].apply("companion", req)
+	if personas["companion"].allowsTool("web_search") || !reflect.DeepEqual(req.GetAllowedTools(), []string{noTools}) {
+		t.Fatalf("companion: allowed_tools %v", req.GetAllowedTools())
+	}
+	if name, persona, _ := tuning.persona(context.Background()); name != "open" || !persona.allowsTool("anything") {
+		t.Fatalf("default persona = %q", name)
+	}
+
+	if _, _, err := tuning.persona(ContextWithPersona(context.Background(), "poet")); !errors.Is(err, ErrUnknownPersona) {
+		t.Fatalf("unknown persona: %v", err)
+	}
+	if _, err := loadPersonas(Config{PersonasPath: path, DefaultPersona: "poet"}); !errors.Is(err, ErrUnknownPersona) {
+		t.Fatalf("unknown default: %v", err)
+	}
+	if name, persona, err := (&loopTuning{}).persona(context.Background()); name != "" || persona != nil || err != nil {
+		t.Fatalf("no personas: %q %v %v", name, persona, err)
+	}
+}
@@ -3,22 +3,35 @@ package agent
 import (
 	"bytes"
 	"context"
-	"crypto/tls"
-	"crypto/x509"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
-	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"backend-go-agent-planner/audit"
 	"backend-go-agent-planner/internal/logger"
+	"backend-go-model-gateway/pkg/chaos"
+	"backend-go-model-gateway/pkg/discovery"
+	"backend-go-model-gateway/pkg/egress"
+	"backend-go-model-gateway/pkg/featureflags"
+	"backend-go-model-gateway/pkg/grpcpool"
+	"backend-go-model-gateway/pkg/httpsign"
+	"backend-go-model-gateway/pkg/mockprovider"
+	"backend-go-model-gateway/pkg/ragfilter"
+	"backend-go-model-gateway/pkg/secrets"
+	"backend-go-model-gateway/pkg/spiffe"
+	"backend-go-model-gateway/pkg/tlsreload"
+	"backend-go-model-gateway/pkg/tools"
 	pb "backend-go-model-gateway/proto/proto"
+	"backend-go-model-gateway/service"
 
 	"github.com/go-redis/redis/v8"
 	"github.com/sony/gobreaker"
@@ -29,57 +42,81 @@ import (
 	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/metric"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/metadata"
 )
 
-func loadMTLSClientCredsForAddr(addr string) (credentials.TransportCredentials, bool, error) {
-	clientCertPath := os.Getenv("TLS_CLIENT_CERT_PATH")
-	clientKeyPath := os.Getenv("TLS_CLIENT_KEY_PATH")
-	caCertPath := os.Getenv("TLS_CA_CERT_PATH")
+// loadMTLSClientCredsForAddr builds client mTLS credentials. Each of
+// TLS_CLIENT_CERT, TLS_CLIENT_KEY and TLS_CA_CERT may be given as a *_PATH file
+// or as a secret reference (see pkg/secrets). Rotated material is picked up
+// every TLS_RELOAD_SECONDS without a restart (see pkg/tlsreload).
+func loadMTLSClientCredsForAddr(ctx context.Context, store *secrets.Store, addr string) (credentials.TransportCredentials, bool, error) {
+	haveCert := store.PEMConfigured("TLS_CLIENT_CERT")
+	haveKey := store.PEMConfigured("TLS_CLIENT_KEY")
+	haveCA := store.PEMConfigured("TLS_CA_CERT")
 
 	// Allow non-TLS local dev unless explicitly configured.
-	if clientCertPath == "" && clientKeyPath == "" && caCertPath == "" {
+	if !haveCert && !haveKey && !haveCA {
 		return nil, false, nil
 	}
-	if clientCertPath == "" || clientKeyPath == "" || caCertPath == "" {
-		return nil, false, fmt.Errorf("mTLS misconfigured: TLS_CLIENT_CERT_PATH, TLS_CLIENT_KEY_PATH, TLS_CA_CERT_PATH must all be set")
+	if !haveCert || !haveKey || !haveCA {
+		return nil, false, fmt.Errorf("mTLS misconfigured: TLS_CLIENT_CERT_PATH, TLS_CLIENT_KEY_PATH, TLS_CA_CERT_PATH (or their secret equivalents) must all be set")
 	}
 
-	clientCert, err := tls.LoadX509KeyPair(clientCertPath, clientKeyPath)
-	if err != nil {
-		return nil, false, fmt.Errorf("load client keypair (%s, %s): %w", filepath.Clean(clientCertPath), filepath.Clean(clientKeyPath), err)
-	}
-
-	caPEM, err := os.ReadFile(caCertPath)
+	lg := logger.NewContextLogger(ctx)
+	reloader, err := tlsreload.New(ctx, tlsreload.Options{
+		Store:    store,
+		Cert:     "TLS_CLIENT_CERT",
+		Key:      "TLS_CLIENT_KEY",
+		CA:       "TLS_CA_CERT",
+		Interval: tlsreload.IntervalFromEnv(),
+		OnReload: func(e tlsreload.Event) {
+			if e.Err != nil {
+				lg.Warn("mtls_reload_rejected", "addr", addr, "error", e.Err.Error(), "not_after", e.NotAfter)
+				return
+			}
+			lg.Info("mtls_reloaded", "addr", addr, "not_after", e.NotAfter)
+		},
+	})
 	if err != nil {
-		return nil, false, fmt.Errorf("read CA cert (%s): %w", filepath.Clean(caCertPath), err)
-	}
-	caPool := x509.NewCertPool()
-	if ok := caPool.AppendCertsFromPEM(caPEM); !ok {
-		return nil, false, fmt.Errorf("append CA certs from PEM (%s): no certs parsed", filepath.Clean(caCertPath))
+		return nil, false, fmt.Errorf("load client mTLS material: %w", err)
 	}
 
-	host := addr
-	if i := strings.LastIndex(addr, ":"); i > 0 {
-		host = addr[:i]
-	}
-	// Hostname verification must match the server certificate's SAN/CN.
+	// Hostname verification must match the server certificate's SAN/CN. For
+	// discovery targets this is the logical service name, not a replica address.
 	serverName := os.Getenv("TLS_SERVER_NAME")
 	if strings.TrimSpace(serverName) == "" {
-		serverName = host
+		serverName = discovery.ServerName(addr)
 	}
+	return credentials.NewTLS(reloader.ClientConfig(serverName)), true, nil
+}
 
-	conf := &tls.Config{
-		MinVersion:   tls.VersionTLS12,
-		Certificates: []tls.Certificate{clientCert},
-		RootCAs:      caPool,
-		ServerName:   serverName,
-		NextProtos:   []string{"h2"},
+// newSPIFFESource connects to SPIFFE_ENDPOINT_SOCKET when TLS_SOURCE=spiffe,
+// so the planner's mTLS identity is its X.509-SVID instead of PEM material.
+// It returns nil for TLS_SOURCE=pem (the default).
+func newSPIFFESource(ctx context.Context) (*spiffe.Source, error) {
+	switch source := strings.ToLower(getenv("TLS_SOURCE", "pem")); source {
+	case "pem":
+		return nil, nil
+	case "spiffe":
+	default:
+		return nil, fmt.Errorf("unsupported TLS_SOURCE %q (supported: pem, spiffe)", source)
 	}
-
-	return credentials.NewTLS(conf), true, nil
+	opts, err := spiffe.OptionsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	lg := logger.NewContextLogger(ctx)
+	opts.OnUpdate = func(e spiffe.Event) {
+		if e.Err != nil {
+			lg.Warn("spiffe_stream_failed", "spiffe_id", e.ID, "error", e.Err.Error(), "not_after", e.NotAfter)
+			return
+		}
+		lg.Info("spiffe_svid_updated", "spiffe_id", e.ID, "not_after", e.NotAfter)
+	}
+	return spiffe.NewSource(ctx, opts)
 }
 
 type Config struct {
@@ -91,9 +128,127 @@ type Config struct {
 	AuditDBPath         string
 	RedisAddr           string
 
+	// Secrets resolves credentials (TLS material, Redis password) from Vault,
+	// AWS Secrets Manager or mounted files. Nil falls back to plain env vars.
+	Secrets *secrets.Store
+
 	MaxTurns int
 	TopK     int
 	KBs      []string
+
+	// KBRouting picks KBs and depth per prompt: "keywords" (default) or "off".
+	KBRouting string
+	// KBRoutesPath replaces the built-in routing rules with a JSON list of
+	// KBRoutingRule.
+	KBRoutesPath string
+	// KBRoutingOtherTopK is the depth for KBs a routed prompt did not pick.
+	KBRoutingOtherTopK int
+
+	// RAGFeedback reports which retrieved matches a successful run used to the
+	// Memory Service (POST /memory/feedback).
+	RAGFeedback bool
+
+	// ReadYourWrites merges a run's own session-history writes into the
+	// history its later turns fetch (see runWrites).
+	ReadYourWrites bool
+
+	// StreamPlans asks the gateway to stream plans (StreamPlan) and runs a
+	// tool call as soon as its part of the plan is complete (see
+	// early_tool.go).
+	StreamPlans bool
+
+	// MaxConcurrentLoops caps concurrent AgentLoops per replica (0: no cap);
+	// loops over it wait up to LoopQueueTimeout for a slot.
+	MaxConcurrentLoops int
+	LoopQueueTimeout   time.Duration
+	// LoopCapacity is the concurrent loops a replica is sized for when
+	// MaxConcurrentLoops is 0; it scales the agent_saturation metric.
+	LoopCapacity int
+
+	// RAGHedge sends a second GetRAGContext when the first is slower than the
+	// recent p95 (or RAGHedgeDelay, when set) and uses whichever answers first.
+	RAGHedge      bool
+	RAGHedgeDelay time.Duration
+
+	// Evaluation scores final answers after successful runs: "off" (default),
+	// "heuristic" (word overlap) or "llm" (the gateway's EvaluateAnswer), for
+	// EvaluationSampleRate of the runs.
+	Evaluation           string
+	EvaluationSampleRate float64
+
+	// RAGInjection screens retrieved matches for prompt injection before they
+	// go into the planner prompt: "quarantine" (default) leaves flagged
+	// matches out, "flag" only reports them, "off" skips the scan.
+	RAGInjection string
+
+	// AgentID names this planner in agent-to-agent messages (POST
+	// /agents/message).
+	AgentID string
+
+	// PersonasPath is a JSON object of Persona by name; requests pick one with
+	// "persona", and DefaultPersona applies when they do not.
+	PersonasPath   string
+	DefaultPersona string
+
+	// ScratchpadTTL keeps a session's Redis scratchpad (tool results and facts
+	// from earlier turns) after its last write; 0 disables the scratchpad.
+	ScratchpadTTL        time.Duration
+	ScratchpadMaxEntries int
+	// ScratchpadReuseTools are answered from the scratchpad when called again
+	// with the same args, instead of running in the sandbox.
+	ScratchpadReuseTools []string
+
+	// PromptsPath is a JSON object of prompt template versions by name, next
+	// to the built-in v1. PromptVersion is the version runs use, and
+	// PromptCandidate the one PromptCandidatePercent of sessions use instead.
+	PromptsPath            string
+	PromptVersion          string
+	PromptCandidate        string
+	PromptCandidatePercent int
+
+	// RoutingModel is asked for on turns that pick a tool, SynthesisModel on
+	// turns after a tool result, e.g. a cheap model to route and a strong one
+	// to answer, or a temperature of 0 to route deterministically. Empty
+	// fields use the gateway's defaults (see model_choice.go).
+	RoutingModel   ModelChoice
+	SynthesisModel ModelChoice
+
+	// ToolBudgetTools are the tools that reach external web APIs. Each
+	// session may call them ToolBudgetPerSession times per
+	// ToolBudgetSessionWindow, and each replica ToolBudgetPerHour times an
+	// hour; 0 disables a limit (see tool_budget.go).
+	ToolBudgetTools         []string
+	ToolBudgetPerSession    int
+	ToolBudgetSessionWindow time.Duration
+	ToolBudgetPerHour       int
+
+	// ToolOutputMaxBytes caps each of a tool's stdout and stderr before they
+	// are fed back to the model; longer output keeps its head and tail (see
+	// tool_output.go). 0 disables the cap.
+	ToolOutputMaxBytes int
+
+	// TurnLatencyBudget bounds a turn's retrieval and planning; once a turn
+	// exceeds it, later turns skip the optional stages (see turn_budget.go).
+	// 0 disables the budget.
+	TurnLatencyBudget time.Duration
+
+	// MockTools routes tool calls to canned results instead of the sandbox:
+	// "auto" (default) while the gateway reports mock mode, "on" or "off"
+	// (see mock_tools.go).
+	MockTools string
+
+	// Anomalies flags runs that stray from their session's recent runs (see
+	// anomaly.go).
+	Anomalies AnomalyConfig
+
+	// NotificationAdminURL is the notification service's admin API, whose
+	// channel the startup drift check compares with notificationsChannel
+	// (empty: not checked; see drift.go).
+	NotificationAdminURL string
+
+	// GRPCPool sizes the connection pool to each gRPC dependency and sets
+	// wait-for-ready (PAGI_GRPC_POOL_SIZE, PAGI_GRPC_WAIT_FOR_READY).
+	GRPCPool grpcpool.Options
 }
 
 // Resource represents a structured, optional multi-modal input reference.
@@ -116,6 +271,78 @@ func ConfigFromEnv() Config {
 		fmt.Sscanf(v, "%d", &topK)
 	}
 
+	otherTopK := 1
+	if v := os.Getenv("AGENT_KB_ROUTING_OTHER_TOP_K"); v != "" {
+		fmt.Sscanf(v, "%d", &otherTopK)
+	}
+
+	maxLoops := 0
+	if v := os.Getenv("AGENT_MAX_CONCURRENT_LOOPS"); v != "" {
+		fmt.Sscanf(v, "%d", &maxLoops)
+	}
+	loopCapacity := 4
+	if v := os.Getenv("AGENT_LOOP_CAPACITY"); v != "" {
+		fmt.Sscanf(v, "%d", &loopCapacity)
+	}
+	queueTimeout := 30 * time.Second
+	if d, err := time.ParseDuration(os.Getenv("AGENT_LOOP_QUEUE_TIMEOUT")); err == nil && d > 0 {
+		queueTimeout = d
+	}
+	scratchpadTTL := time.Hour
+	if v := os.Getenv("AGENT_SCRATCHPAD_TTL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			scratchpadTTL = d
+		}
+	}
+	scratchpadMax := 20
+	if v := os.Getenv("AGENT_SCRATCHPAD_MAX_ENTRIES"); v != "" {
+		fmt.Sscanf(v, "%d", &scratchpadMax)
+	}
+	var reuseTools []string
+	for _, t := range strings.Split(getenv("AGENT_SCRATCHPAD_REUSE_TOOLS", "web_search"), ",") {
+		if t = strings.TrimSpace(t); t != "" && t != "none" {
+			reuseTools = append(reuseTools, t)
+		}
+	}
+	evalSampleRate := 1.0
+	if v := os.Getenv("AGENT_EVALUATION_SAMPLE_RATE"); v != "" {
+		fmt.Sscanf(v, "%g", &evalSampleRate)
+	}
+	var budgetTools []string
+	for _, t := range strings.Split(getenv("AGENT_TOOL_BUDGET_TOOLS", "web_search,http_fetch"), ",") {
+		if t = strings.TrimSpace(t); t != "" && t != "none" {
+			budgetTools = append(budgetTools, t)
+		}
+	}
+	budgetPerSession := 20
+	if v := os.Getenv("AGENT_TOOL_BUDGET_PER_SESSION"); v != "" {
+		fmt.Sscanf(v, "%d", &budgetPerSession)
+	}
+	budgetWindow := 24 * time.Hour
+	if d, err := time.ParseDuration(os.Getenv("AGENT_TOOL_BUDGET_SESSION_WINDOW")); err == nil && d > 0 {
+		budgetWindow = d
+	}
+	budgetPerHour := 500
+	if v := os.Getenv("AGENT_TOOL_BUDGET_PER_HOUR"); v != "" {
+		fmt.Sscanf(v, "%d", &budgetPerHour)
+	}
+	toolOutputMax := defaultToolOutputMaxBytes
+	if v := os.Getenv("AGENT_TOOL_OUTPUT_MAX_BYTES"); v != "" {
+		fmt.Sscanf(v, "%d", &toolOutputMax)
+	}
+	turnBudget := defaultTurnLatencyBudget
+	if d, err := time.ParseDuration(os.Getenv("AGENT_TURN_LATENCY_BUDGET")); err == nil && d >= 0 {
+		turnBudget = d
+	}
+	promptCandidatePercent := 0
+	if v := os.Getenv("AGENT_PROMPT_CANDIDATE_PERCENT"); v != "" {
+		fmt.Sscanf(v, "%d", &promptCandidatePercent)
+	}
+	var hedgeDelay time.Duration
+	if d, err := time.ParseDuration(os.Getenv("AGENT_RAG_HEDGE_DELAY")); err == nil && d > 0 {
+		hedgeDelay = d
+	}
+
 	return Config{
 		ModelGatewayAddr:    getenv("MODEL_GATEWAY_ADDR", "localhost:50051"),
 		MemoryServiceAddr:   getenv("MEMORY_GRPC_ADDR", "localhost:50052"),
@@ -124,10 +351,65 @@ func ConfigFromEnv() Config {
 		RustSandboxHTTPURL:  getenv("RUST_SANDBOX_URL", "http://localhost:8001"),
 		AuditDBPath:         getenv("PAGI_AUDIT_DB_PATH", "./pagi_audit.db"),
 		RedisAddr:           getenv("REDIS_ADDR", "localhost:6379"),
+		Secrets:             secrets.FromEnv(),
 		MaxTurns:            maxTurns,
 		TopK:                topK,
 		// Include Mind-KB so the planner can retrieve evolving playbooks via the existing RAG call.
 		KBs: []string{"Mind-KB", "Domain-KB", "Body-KB", "Soul-KB"},
+
+		KBRouting:          strings.ToLower(getenv("AGENT_KB_ROUTING", "keywords")),
+		KBRoutesPath:       os.Getenv("AGENT_KB_ROUTES_PATH"),
+		KBRoutingOtherTopK: otherTopK,
+
+		RAGFeedback: !strings.EqualFold(getenv("AGENT_RAG_FEEDBACK", "on"), "off"),
+
+		ReadYourWrites: strings.EqualFold(getenv("AGENT_READ_YOUR_WRITES", "off"), "on"),
+		StreamPlans:    strings.EqualFold(getenv("AGENT_STREAM_PLANS", "off"), "on"),
+
+		MaxConcurrentLoops: maxLoops,
+		LoopQueueTimeout:   queueTimeout,
+		LoopCapacity:       loopCapacity,
+
+		RAGHedge:      strings.EqualFold(getenv("AGENT_RAG_HEDGE", "off"), "on"),
+		RAGHedgeDelay: hedgeDelay,
+
+		ScratchpadTTL:        scratchpadTTL,
+		ScratchpadMaxEntries: scratchpadMax,
+		ScratchpadReuseTools: reuseTools,
+
+		Evaluation:           strings.ToLower(getenv("AGENT_EVALUATION", EvaluationOff)),
+		EvaluationSampleRate: evalSampleRate,
+
+		RAGInjection: strings.ToLower(strings.TrimSpace(getenv("AGENT_RAG_INJECTION", "quarantine"))),
+
+		AgentID: getenv("AGENT_ID", "agent-planner"),
+
+		MockTools: strings.ToLower(getenv("AGENT_MOCK_TOOLS", MockToolsAuto)),
+
+		NotificationAdminURL: os.Getenv("NOTIFICATION_ADMIN_URL"),
+
+		PersonasPath:   os.Getenv("AGENT_PERSONAS_PATH"),
+		DefaultPersona: os.Getenv("AGENT_DEFAULT_PERSONA"),
+
+		PromptsPath:            os.Getenv("AGENT_PROMPTS_PATH"),
+		PromptVersion:          getenv("AGENT_PROMPT_VERSION", DefaultPromptVersion),
+		PromptCandidate:        os.Getenv("AGENT_PROMPT_CANDIDATE"),
+		PromptCandidatePercent: promptCandidatePercent,
+
+		RoutingModel:   ModelChoice{Provider: os.Getenv("AGENT_ROUTING_PROVIDER"), Model: os.Getenv("AGENT_ROUTING_MODEL"), Temperature: envTemperature("AGENT_ROUTING_TEMPERATURE")},
+		SynthesisModel: ModelChoice{Provider: os.Getenv("AGENT_SYNTHESIS_PROVIDER"), Model: os.Getenv("AGENT_SYNTHESIS_MODEL"), Temperature: envTemperature("AGENT_SYNTHESIS_TEMPERATURE")},
+
+		ToolBudgetTools:         budgetTools,
+		ToolBudgetPerSession:    budgetPerSession,
+		ToolBudgetSessionWindow: budgetWindow,
+		ToolBudgetPerHour:       budgetPerHour,
+
+		ToolOutputMaxBytes: toolOutputMax,
+		TurnLatencyBudget:  turnBudget,
+
+		Anomalies: AnomalyConfigFromEnv(),
+
+		GRPCPool: grpcpool.OptionsFromEnv(),
 	}
 }
 
@@ -141,13 +423,16 @@ func getenv(key, fallback string) string {
 type Planner struct {
 	cfg Config
 
-	modelConn  *grpc.ClientConn
-	memoryConn *grpc.ClientConn
-	rustConn   *grpc.ClientConn
+	modelConn  *grpcpool.Pool
+	memoryConn *grpcpool.Pool
+	rustConn   *grpcpool.Pool
 
 	modelClient  pb.ModelGatewayClient
 	memoryClient pb.ModelGatewayClient
 	toolClient   pb.ToolServiceClient
+	// tools are the sandbox's tools (ListTools), tools.Builtin until
+	// RunToolDiscovery succeeds.
+	tools *tools.Catalog
 
 	// Circuit breakers to prevent cascading failures when downstream dependencies
 	// are unhealthy or slow.
@@ -157,14 +442,71 @@ type Planner struct {
 	httpClient *http.Client
 	auditDB    *audit.AuditDB
 	redis      *redis.Client
+	flags      *featureflags.Provider
+	// chaos injects PAGI_CHAOS faults at the provider/RAG/tool/Redis boundaries.
+	chaos *chaos.Injector
+	// mockTools sends tool calls to canned results when the gateway is in
+	// mock mode (nil: never).
+	mockTools *mockTools
+	// router picks KBs and depth per prompt (nil: every KB at cfg.TopK).
+	router *kbRouter
+	// scratchpad is the per-session working memory in Redis (nil: off).
+	scratchpad *scratchpad
+	// toolBudget caps calls to web tools (nil: unlimited).
+	toolBudget *toolBudget
+	// evaluations tracks background answer evaluations, which Close waits
+	// for; evalStats keeps their recent scores.
+	evaluations sync.WaitGroup
+	evalStats   evaluationStats
+	// personas are the named personas from AGENT_PERSONAS_PATH.
+	personas map[string]*Persona
+	// prompts are the prompt template versions (see prompts.go).
+	prompts *promptSet
+	// reloaded replaces cfg's loop settings and router after ReloadConfig.
+	reloaded atomic.Pointer[loopTuning]
+	// svids is the SPIFFE identity for the model gateway connection (nil
+	// unless TLS_SOURCE=spiffe).
+	svids *spiffe.Source
+	// egress limits outbound HTTP, resource URIs and URLs in tool arguments
+	// (nil unless PAGI_EGRESS_ALLOW is set).
+	egress *egress.Policy
+	// load counts running and queued AgentLoops (see load.go).
+	load *loopLoad
+	// ragHedge hedges slow GetRAGContext calls (nil unless AGENT_RAG_HEDGE=on).
+	ragHedge *hedger
+	// lastProbe and lastProbeOK (unix seconds) track the canary (probe.go).
+	lastProbe   atomic.Pointer[ProbeResult]
+	lastProbeOK atomic.Int64
+	// anomalies keeps per-session run baselines (nil-safe: detection off).
+	anomalies *anomalyDetector
+	// noStreamPlan is set once the gateway answers StreamPlan with
+	// Unimplemented; plans are then requested with GetPlan.
+	noStreamPlan atomic.Bool
 }
 
 const notificationsChannel = "pagi_notifications"
 
+// gatewayPlanTimeout bounds one GetPlan call.
+const gatewayPlanTimeout = 60 * time.Second
+
 var (
 	metricsOnce   sync.Once
 	planCounter   metric.Int64Counter
 	loopDurationS metric.Float64Histogram
+	turnDurationS metric.Float64Histogram
+	breakerTrips  metric.Int64Counter
+	ragHedges     metric.Int64Counter
+	// Answer evaluation (see evaluation.go).
+	evaluationsTotal metric.Int64Counter
+	answerScore      metric.Float64Histogram
+	// Tool budget (see tool_budget.go).
+	toolBudgetCalls metric.Int64Counter
+	// Turn latency budget (see turn_budget.go).
+	degradedRuns metric.Int64Counter
+	// Prompt-injection screening of RAG matches (see rag_injection.go).
+	ragMatchesScanned metric.Int64Counter
+	// Anomaly detection (see anomaly.go).
+	anomaliesTotal metric.Int64Counter
 )
 
 func initMetrics() {
@@ -172,9 +514,9 @@ func initMetrics() {
 		m := otel.Meter("backend-go-agent-planner")
 		var err error
 		planCounter, err = m.Int64Counter(
-			"agent_plan_total",
+			planTotalMetric.name,
 			metric.WithDescription("Count of agent planner executions (success/failure)."),
-			metric.WithUnit("1"),
+			metric.WithUnit(planTotalMetric.unit),
 		)
 		if err != nil {
 			planCounter = nil
@@ -187,52 +529,191 @@ func initMetrics() {
 		if err != nil {
 			loopDurationS = nil
 		}
+		turnDurationS, err = m.Float64Histogram(
+			"agent_turn_duration_seconds",
+			metric.WithDescription("Duration of a single AgentLoop turn in seconds."),
+			metric.WithUnit("s"),
+		)
+		if err != nil {
+			turnDurationS = nil
+		}
+		breakerTrips, err = m.Int64Counter(
+			breakerTripsMetric.name,
+			metric.WithDescription("Count of circuit breaker transitions into the open state."),
+			metric.WithUnit(breakerTripsMetric.unit),
+		)
+		if err != nil {
+			breakerTrips = nil
+		}
+		ragHedges, err = m.Int64Counter(
+			"agent_rag_hedges_total",
+			metric.WithDescription("Count of hedged GetRAGContext calls by the attempt that answered."),
+			metric.WithUnit("1"),
+		)
+		if err != nil {
+			ragHedges = nil
+		}
+		evaluationsTotal, err = m.Int64Counter(
+			"agent_evaluations_total",
+			metric.WithDescription("Count of final-answer evaluations by mode and outcome (scored/error)."),
+			metric.WithUnit("1"),
+		)
+		if err != nil {
+			evaluationsTotal = nil
+		}
+		answerScore, err = m.Float64Histogram(
+			"agent_answer_score",
+			metric.WithDescription("Evaluation score of final answers, from 0 (worst) to 1 (best)."),
+			metric.WithUnit("1"),
+			metric.WithExplicitBucketBoundaries(0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9, 1),
+		)
+		if err != nil {
+			answerScore = nil
+		}
+		toolBudgetCalls, err = m.Int64Counter(
+			toolBudgetCallsMetric.name,
+			metric.WithDescription("Count of calls to budgeted tools by tool and outcome (allowed/session_exceeded/global_exceeded)."),
+			metric.WithUnit(toolBudgetCallsMetric.unit),
+		)
+		if err != nil {
+			toolBudgetCalls = nil
+		}
+		degradedRuns, err = m.Int64Counter(
+			"agent_runs_degraded_total",
+			metric.WithDescription("Count of AgentLoop runs that skipped optional stages after a turn exceeded its latency budget."),
+			metric.WithUnit("1"),
+		)
+		if err != nil {
+			degradedRuns = nil
+		}
+		ragMatchesScanned, err = m.Int64Counter(
+			"agent_rag_matches_scanned_total",
+			metric.WithDescription("Count of RAG matches scanned for prompt injection by KB and verdict (clean/injection)."),
+			metric.WithUnit("1"),
+		)
+		if err != nil {
+			ragMatchesScanned = nil
+		}
+		anomaliesTotal, err = m.Int64Counter(
+			"agent_anomalies_total",
+			metric.WithDescription("Count of unusual AgentLoop runs by kind (tool_call_spike/repeated_tool_call/latency/cost_jump)."),
+			metric.WithUnit("1"),
+		)
+		if err != nil {
+			anomaliesTotal = nil
+		}
 	})
 }
 
 func NewPlanner(ctx context.Context, cfg Config) (*Planner, error) {
 	lg := logger.NewContextLogger(ctx)
+	initMetrics()
 
-	dialInsecure := func(ctx context.Context, addr string) (*grpc.ClientConn, error) {
-		return grpc.DialContext(
-			ctx,
-			addr,
-			grpc.WithTransportCredentials(insecure.NewCredentials()),
-			grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
-		)
+	chaosInjector, err := chaos.FromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("chaos config: %w", err)
+	}
+	if chaosInjector.Enabled() {
+		lg.Warn("chaos_mode_enabled", "rules", chaosInjector.Describe())
 	}
 
-	dialModelGateway := func(ctx context.Context, addr string) (*grpc.ClientConn, error) {
-		if creds, enabled, err := loadMTLSClientCredsForAddr(addr); err != nil {
-			return nil, err
-		} else if enabled {
-			lg.Info("mtls_enabled_for_model_gateway", "addr", addr)
-			return grpc.DialContext(
-				ctx,
-				addr,
+	router, err := newKBRouter(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("kb routing config: %w", err)
+	}
+	if _, err := ragInjectionMode(cfg); err != nil {
+		return nil, err
+	}
+	personas, err := loadPersonas(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("persona config: %w", err)
+	}
+	prompts, err := loadPromptSet(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("prompt config: %w", err)
+	}
+	mockTools, err := newMockTools(cfg.MockTools)
+	if err != nil {
+		return nil, err
+	}
+
+	egressPolicy, err := egress.FromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("egress config: %w", err)
+	}
+
+	// Downstream addresses may be static host:port values or discovery targets
+	// (consul:///name, dnssrv:///_grpc._tcp.name); DialOptions enables
+	// round-robin across every resolved replica.
+	dialWith := func(creds credentials.TransportCredentials) func(context.Context, string) (*grpc.ClientConn, error) {
+		return func(ctx context.Context, addr string) (*grpc.ClientConn, error) {
+			opts := append(discovery.DialOptions(),
 				grpc.WithTransportCredentials(creds),
 				grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
 			)
+			return grpc.DialContext(ctx, addr, opts...)
+		}
+	}
+	dialInsecure := dialWith(insecure.NewCredentials())
+
+	// Each dependency gets a pool of cfg.GRPCPool.Size connections, so one bad
+	// TCP connection only slows its share of the calls.
+	dialPool := func(ctx context.Context, dependency, addr string, dial func(context.Context, string) (*grpc.ClientConn, error)) (*grpcpool.Pool, error) {
+		opts := cfg.GRPCPool
+		opts.OnStateChange = func(conn int, from, to connectivity.State) {
+			logState := lg.Info
+			if to == connectivity.TransientFailure {
+				logState = lg.Warn
+			}
+			logState("grpc_connectivity_changed", "dependency", dependency, "addr", addr, "conn", conn, "from", from.String(), "to", to.String())
+		}
+		return grpcpool.Dial(ctx, addr, opts, dial)
+	}
+
+	svids, err := newSPIFFESource(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("spiffe identity: %w", err)
+	}
+
+	modelGatewayCreds := func(ctx context.Context, addr string) (credentials.TransportCredentials, error) {
+		if svids != nil {
+			// The gateway is authenticated by its SPIFFE ID, not its host name.
+			lg.Info("spiffe_mtls_enabled_for_model_gateway", "addr", addr, "spiffe_id", svids.ID())
+			return credentials.NewTLS(svids.ClientConfig(spiffe.AuthorizerFromEnv(svids))), nil
+		}
+		if creds, enabled, err := loadMTLSClientCredsForAddr(ctx, cfg.Secrets, addr); err != nil {
+			return nil, err
+		} else if enabled {
+			lg.Info("mtls_enabled_for_model_gateway", "addr", addr)
+			return creds, nil
 		}
 		lg.Warn("mtls_not_enabled_for_model_gateway", "addr", addr)
-		return dialInsecure(ctx, addr)
+		return insecure.NewCredentials(), nil
 	}
 
-	modelConn, err := dialModelGateway(ctx, cfg.ModelGatewayAddr)
+	modelCreds, err := modelGatewayCreds(ctx, cfg.ModelGatewayAddr)
+	if err != nil {
+		svids.Close()
+		return nil, fmt.Errorf("dial model gateway: %w", err)
+	}
+	modelConn, err := dialPool(ctx, "model_gateway", cfg.ModelGatewayAddr, dialWith(modelCreds))
 	if err != nil {
+		svids.Close()
 		return nil, fmt.Errorf("dial model gateway: %w", err)
 	}
 
-	memoryConn, err := dialInsecure(ctx, cfg.MemoryServiceAddr)
+	memoryConn, err := dialPool(ctx, "memory_service", cfg.MemoryServiceAddr, dialInsecure)
 	if err != nil {
 		_ = modelConn.Close()
+		svids.Close()
 		return nil, fmt.Errorf("dial memory service: %w", err)
 	}
 
-	rustConn, err := dialInsecure(ctx, cfg.RustSandboxGRPCAddr)
+	rustConn, err := dialPool(ctx, "rust_sandbox", cfg.RustSandboxGRPCAddr, dialInsecure)
 	if err != nil {
 		_ = memoryConn.Close()
 		_ = modelConn.Close()
+		svids.Close()
 		return nil, fmt.Errorf("dial rust sandbox: %w", err)
 	}
 
@@ -245,14 +726,34 @@ func NewPlanner(ctx context.Context, cfg Config) (*Planner, error) {
 		lg.Warn("audit_db_unavailable_continuing_without_audit", "path", cfg.AuditDBPath, "error", err)
 		auditDB = nil
 	}
+	if err := enableSessionKeys(ctx, cfg.Secrets, auditDB); err != nil {
+		_ = auditDB.Close()
+		_ = rustConn.Close()
+		_ = memoryConn.Close()
+		_ = modelConn.Close()
+		svids.Close()
+		return nil, err
+	}
 
-	redisClient := redis.NewClient(&redis.Options{Addr: cfg.RedisAddr})
+	redisOpts := &redis.Options{Addr: cfg.RedisAddr}
+	cfg.Secrets.ConfigureRedis(redisOpts)
+	redisClient := redis.NewClient(redisOpts)
 	if err := redisClient.Ping(ctx).Err(); err != nil {
 		lg.Warn("redis_unavailable", "addr", cfg.RedisAddr, "error", err)
 		_ = redisClient.Close()
 		redisClient = nil
 	}
 
+	flagOpts := featureflags.OptionsFromEnv()
+	flagOpts.Redis = redisClient
+	flags, err := featureflags.New(flagOpts)
+	if err != nil {
+		// A broken flag file should not take the planner down; fall back to env/defaults.
+		lg.Warn("feature_flags_file_unavailable", "path", flagOpts.FilePath, "error", err)
+		flagOpts.FilePath = ""
+		flags, _ = featureflags.New(flagOpts)
+	}
+
 	// Circuit breaker defaults (production-like):
 	// - Open after 5 consecutive failures.
 	// - Stay open for 30s, then allow 1 request (half-open) to probe recovery.
@@ -264,13 +765,27 @@ func NewPlanner(ctx context.Context, cfg Config) (*Planner, error) {
 			ReadyToTrip: func(counts gobreaker.Counts) bool {
 				return counts.ConsecutiveFailures >= 5
 			},
+			// A full gateway queue is back-pressure, not an outage.
+			IsSuccessful: func(err error) bool {
+				return err == nil || errors.Is(err, ErrGatewayBusy)
+			},
 			OnStateChange: func(name string, from gobreaker.State, to gobreaker.State) {
 				logger.LogCircuitBreakerStateChange(lg, name, from.String(), to.String())
+				if to == gobreaker.StateOpen && breakerTrips != nil {
+					breakerTrips.Add(context.Background(), 1, metric.WithAttributes(attribute.String("dependency", name)))
+				}
 			},
 		})
 	}
 
-	return &Planner{
+	// Memory Service HTTP calls are HMAC-signed when PAGI_HMAC_KEY is set
+	// (see pkg/httpsign) and limited to PAGI_EGRESS_ALLOW.
+	httpClient := &http.Client{Timeout: 10 * time.Second, Transport: &httpsign.Transport{
+		Store: cfg.Secrets,
+		Base:  egressPolicy.Transport(http.DefaultTransport.(*http.Transport)),
+	}}
+
+	p := &Planner{
 		cfg:           cfg,
 		modelConn:     modelConn,
 		memoryConn:    memoryConn,
@@ -278,15 +793,37 @@ func NewPlanner(ctx context.Context, cfg Config) (*Planner, error) {
 		modelClient:   pb.NewModelGatewayClient(modelConn),
 		memoryClient:  pb.NewModelGatewayClient(memoryConn),
 		toolClient:    pb.NewToolServiceClient(rustConn),
+		tools:         tools.NewCatalog(pb.NewToolServiceClient(rustConn)),
 		modelBreaker:  newBreaker("model_gateway"),
 		memoryBreaker: newBreaker("memory_service"),
-		httpClient:    &http.Client{Timeout: 10 * time.Second},
+		httpClient:    httpClient,
 		auditDB:       auditDB,
 		redis:         redisClient,
-	}, nil
+		flags:         flags,
+		chaos:         chaosInjector,
+		router:        router,
+		personas:      personas,
+		prompts:       prompts,
+		scratchpad:    newScratchpad(redisClient, chaosInjector, cfg),
+		toolBudget:    newToolBudget(redisClient, chaosInjector, cfg),
+		svids:         svids,
+		egress:        egressPolicy,
+		load:          newLoopLoad(cfg),
+		ragHedge:      newHedger(cfg.RAGHedge, cfg.RAGHedgeDelay),
+		anomalies:     newAnomalyDetector(cfg.Anomalies),
+		mockTools:     mockTools,
+	}
+	if err := p.registerLoadMetrics(); err != nil {
+		lg.Warn("load_metrics_unavailable", "error", err.Error())
+	}
+	return p, nil
 }
 
-func (p *Planner) callModelGatewayGetPlan(ctx context.Context, prompt string, resources []Resource) (*pb.PlanResponse, error) {
+// callModelGatewayGetPlan asks the gateway for a plan of prompt followed by
+// the run's earlier turns. With onDelta set the plan is streamed (StreamPlan)
+// and onDelta gets its text as it arrives; gateways without StreamPlan are
+// asked with GetPlan.
+func (p *Planner) callModelGatewayGetPlan(ctx context.Context, prompt string, turns []*pb.ChatMessage, resources []Resource, filter *pb.RAGFilter, personaName string, persona *Persona, promptVersion string, choice ModelChoice, onDelta func(string)) (*pb.PlanResponse, error) {
 	if p == nil || p.modelClient == nil {
 		return nil, fmt.Errorf("model client is nil")
 	}
@@ -304,11 +841,27 @@ func (p *Planner) callModelGatewayGetPlan(ctx context.Context, prompt string, re
 		// Per-request timeout (separate from breaker open timeout).
 		// LLM generation can be slow; avoid premature timeouts that would cause false
 		// positives for the circuit breaker.
-		timeout := 60 * time.Second
+		timeout := gatewayPlanTimeout
 		logger.NewContextLogger(ctx).Info("grpc_timeout_applied", "dependency", "model_gateway", "timeout_seconds", int(timeout.Seconds()))
 		ctx2, cancel := context.WithTimeout(ctx, timeout)
 		defer cancel()
-		return p.modelClient.GetPlan(ctx2, &pb.PlanRequest{Prompt: prompt, Resources: pbResources})
+		if err := p.chaos.Inject(ctx2, chaos.Provider); err != nil {
+			return nil, err
+		}
+		req := &pb.PlanRequest{Prompt: prompt, Messages: turns, Resources: pbResources, RagFilter: filter, PromptVersion: promptVersion, Priority: PriorityFromContext(ctx)}
+		persona.apply(personaName, req)
+		choice.apply(req)
+		var resp *pb.PlanResponse
+		var err error
+		if onDelta != nil && !p.noStreamPlan.Load() {
+			resp, err = p.streamPlan(ctx2, req, onDelta)
+		} else {
+			resp, err = p.modelClient.GetPlan(ctx2, req)
+		}
+		if err == nil {
+			resp.Plan, _ = p.chaos.Malform(chaos.Provider, resp.GetPlan())
+		}
+		return resp, gatewayBusy(err)
 	}
 
 	if p.modelBreaker == nil {
@@ -331,12 +884,42 @@ func (p *Planner) callModelGatewayGetPlan(ctx context.Context, prompt string, re
 	return resp, nil
 }
 
-func (p *Planner) callMemoryGetRAGContext(ctx context.Context, query string) (*pb.RAGContextResponse, error) {
+// retrieveRAGContext runs the routed queries, one GetRAGContext call per
+// distinct depth. Matches keep the queries' KB order within each call. If some
+// calls fail, the others' matches are still returned.
+func (p *Planner) retrieveRAGContext(ctx context.Context, query string, queries []kbQuery, filter *pb.RAGFilter) (*pb.RAGContextResponse, error) {
+	var depths []int
+	byDepth := map[int][]string{}
+	for _, q := range queries {
+		if _, ok := byDepth[q.TopK]; !ok {
+			depths = append(depths, q.TopK)
+		}
+		byDepth[q.TopK] = append(byDepth[q.TopK], q.KB)
+	}
+
+	out := &pb.RAGContextResponse{}
+	var lastErr error
+	for _, depth := range depths {
+		resp, err := p.callMemoryGetRAGContext(ctx, query, byDepth[depth], depth, filter)
+		if err != nil {
+			logger.NewContextLogger(ctx).Warn("rag_context_partial_failure", "kbs", byDepth[depth], "top_k", depth, "error", err)
+			lastErr = err
+			continue
+		}
+		out.Matches = append(out.Matches, resp.GetMatches()...)
+	}
+	if lastErr != nil && len(out.Matches) == 0 {
+		return nil, lastErr
+	}
+	return out, nil
+}
+
+func (p *Planner) callMemoryGetRAGContext(ctx context.Context, query string, kbs []string, topK int, filter *pb.RAGFilter) (*pb.RAGContextResponse, error) {
 	if p == nil || p.memoryClient == nil {
 		return nil, fmt.Errorf("memory client is nil")
 	}
 
-	call := func() (*pb.RAGContextResponse, error) {
+	call := func(ctx context.Context) (*pb.RAGContextResponse, error) {
 		// Per-request timeout (separate from breaker open timeout).
 		// RAG calls can be moderately slow; use a larger timeout to avoid tripping
 		// the circuit breaker on transient slowness.
@@ -344,19 +927,24 @@ func (p *Planner) callMemoryGetRAGContext(ctx context.Context, query string) (*p
 		logger.NewContextLogger(ctx).Info("grpc_timeout_applied", "dependency", "memory_service", "timeout_seconds", int(timeout.Seconds()))
 		ctx2, cancel := context.WithTimeout(ctx, timeout)
 		defer cancel()
+		if err := p.chaos.Inject(ctx2, chaos.RAG); err != nil {
+			return nil, err
+		}
 		return p.memoryClient.GetRAGContext(ctx2, &pb.RAGContextRequest{
 			Query:          query,
-			TopK:           int32(p.cfg.TopK),
-			KnowledgeBases: p.cfg.KBs,
+			TopK:           int32(topK),
+			KnowledgeBases: kbs,
+			Filter:         filter,
 		})
 	}
 
+	// A hedged pair counts as one call for the breaker.
 	if p.memoryBreaker == nil {
-		return call()
+		return hedged(ctx, p.ragHedge, call)
 	}
 
 	respAny, err := p.memoryBreaker.Execute(func() (any, error) {
-		return call()
+		return hedged(ctx, p.ragHedge, call)
 	})
 	if err != nil {
 		if errors.Is(err, gobreaker.ErrOpenState) || errors.Is(err, gobreaker.ErrTooManyRequests) {
@@ -371,6 +959,33 @@ func (p *Planner) callMemoryGetRAGContext(ctx context.Context, query string) (*p
 	return resp, nil
 }
 
+// Flags returns the planner's feature-flag provider (may be nil).
+func (p *Planner) Flags() *featureflags.Provider {
+	if p == nil {
+		return nil
+	}
+	return p.flags
+}
+
+// knowledgeBasesFor returns the RAG KB list for a session (the persona's, when
+// it has one), dropping Mind-KB (playbooks) when playbook reuse is disabled.
+func (p *Planner) knowledgeBasesFor(ctx context.Context, sessionID string, persona *Persona) []string {
+	all := p.cfg.KBs
+	if persona != nil && len(persona.KnowledgeBases) > 0 {
+		all = persona.KnowledgeBases
+	}
+	if p.flags.Enabled(ctx, featureflags.PlaybookReuse, sessionID) {
+		return all
+	}
+	kbs := make([]string, 0, len(all))
+	for _, kb := range all {
+		if kb != "Mind-KB" {
+			kbs = append(kbs, kb)
+		}
+	}
+	return kbs
+}
+
 func (p *Planner) Close() {
 	if p == nil {
 		return
@@ -384,12 +999,14 @@ func (p *Planner) Close() {
 	if p.rustConn != nil {
 		_ = p.rustConn.Close()
 	}
+	p.evaluations.Wait()
 	if p.auditDB != nil {
 		_ = p.auditDB.Close()
 	}
 	if p.redis != nil {
 		_ = p.redis.Close()
 	}
+	p.svids.Close()
 }
 
 type ToolCall struct {
@@ -398,6 +1015,39 @@ type ToolCall struct {
 	Raw  map[string]any `json:"-"`
 }
 
+// injectSessionIDToOutgoingGRPC tags downstream gRPC calls with the session so
+// services can apply per-session behavior (e.g. feature-flag overrides).
+func injectSessionIDToOutgoingGRPC(ctx context.Context, sessionID string) context.Context {
+	if strings.TrimSpace(sessionID) == "" {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, "x-session-id", sessionID)
+}
+
+type tenantContextKey struct{}
+
+// ContextWithTenant records the tenant the request authenticated as.
+func ContextWithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantContextKey{}, tenant)
+}
+
+// TenantFromContext returns the authenticated tenant, or "" for single-tenant
+// deployments.
+func TenantFromContext(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantContextKey{}).(string)
+	return tenant
+}
+
+// injectTenantIDToOutgoingGRPC forwards the authenticated tenant so the
+// gateway can scope RAG retrieval to it (RAG_TENANCY=required).
+func injectTenantIDToOutgoingGRPC(ctx context.Context) context.Context {
+	tenant := TenantFromContext(ctx)
+	if tenant == "" {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, service.TenantIDMetadataKey, tenant)
+}
+
 func injectTraceIDToOutgoingGRPC(ctx context.Context) context.Context {
 	traceID, _ := ctx.Value(logger.TraceIDKey).(string)
 	if strings.TrimSpace(traceID) == "" {
@@ -413,10 +1063,28 @@ func (p *Planner) RecordStep(ctx context.Context, sessionID, eventType string, d
 		return nil
 	}
 	traceID, _ := ctx.Value(logger.TraceIDKey).(string)
-	return p.auditDB.RecordStep(ctx, traceID, sessionID, eventType, data)
+	// Steps of a run carry its prompt version, to compare experiment arms.
+	if version := promptVersionFromContext(ctx); version != "" {
+		if m, ok := data.(map[string]any); ok {
+			if _, set := m["prompt_version"]; !set {
+				m["prompt_version"] = version
+			}
+		}
+	}
+	return p.auditDB.RecordStepAs(ctx, traceID, sessionID, PrincipalFromContext(ctx), eventType, data)
 }
 
 func (p *Planner) PublishStatus(ctx context.Context, sessionID string, status string) error {
+	return p.publish(ctx, sessionID, map[string]any{"status": status})
+}
+
+func (p *Planner) PublishNotification(ctx context.Context, sessionID string, result string) error {
+	return p.publish(ctx, sessionID, map[string]any{"result": result})
+}
+
+// publish sends a notification carrying fields on the notifications channel
+// and keeps a copy in the audit DB, since pub/sub has no history.
+func (p *Planner) publish(ctx context.Context, sessionID string, fields map[string]any) error {
 	if p == nil || p.redis == nil {
 		return nil
 	}
@@ -424,31 +1092,70 @@ func (p *Planner) PublishStatus(ctx context.Context, sessionID string, status st
 	payload := map[string]any{
 		"trace_id":   traceID,
 		"session_id": sessionID,
-		"status":     status,
 		"timestamp":  time.Now().UTC().Format(time.RFC3339Nano),
 	}
+	for k, v := range fields {
+		payload[k] = v
+	}
 	b, _ := json.Marshal(payload)
-	return p.redis.Publish(ctx, notificationsChannel, string(b)).Err()
+	if err := p.chaos.Inject(ctx, chaos.Redis); err != nil {
+		return err
+	}
+	if err := p.redis.Publish(ctx, notificationsChannel, string(b)).Err(); err != nil {
+		return err
+	}
+	return p.auditDB.RecordNotification(ctx, traceID, sessionID, string(b))
 }
 
-func (p *Planner) PublishNotification(ctx context.Context, sessionID string, result string) error {
+// ErrAuditUnavailable is returned by audit queries when the audit DB could not be opened.
+var ErrAuditUnavailable = errors.New("audit log unavailable")
+
+// QueryAudit returns audit rows matching the filter. A tenant only sees the
+// rows of its own sessions.
+func (p *Planner) QueryAudit(ctx context.Context, f audit.QueryFilter) ([]audit.Entry, error) {
+	if p == nil || p.auditDB == nil {
+		return nil, ErrAuditUnavailable
+	}
+	if f.SessionID != "" {
+		if err := p.authorizeSession(ctx, f.SessionID, false); err != nil {
+			return nil, err
+		}
+	}
+	f.Tenant = TenantFromContext(ctx)
+	return p.auditDB.Query(ctx, f)
+}
+
+// AuditStats aggregates the audit log over [since, until): runs per day,
+// turns, tool usage and failure reasons. A tenant's stats cover its own
+// sessions.
+func (p *Planner) AuditStats(ctx context.Context, since, until time.Time) (*audit.Stats, error) {
+	if p == nil || p.auditDB == nil {
+		return nil, ErrAuditUnavailable
+	}
+	return p.auditDB.Stats(ctx, since, until, TenantFromContext(ctx))
+}
+
+// ErrNotificationsUnavailable is returned when Redis is not connected.
+var ErrNotificationsUnavailable = errors.New("notifications unavailable (redis not connected)")
+
+// SubscribeNotifications subscribes to the planner's notification channel.
+// Callers must Close the returned subscription.
+func (p *Planner) SubscribeNotifications(ctx context.Context) (*redis.PubSub, error) {
 	if p == nil || p.redis == nil {
-		return nil
+		return nil, ErrNotificationsUnavailable
 	}
-	traceID, _ := ctx.Value(logger.TraceIDKey).(string)
-	payload := map[string]any{
-		"trace_id":   traceID,
-		"session_id": sessionID,
-		"result":     result,
-		"timestamp":  time.Now().UTC().Format(time.RFC3339Nano),
+	sub := p.redis.Subscribe(ctx, notificationsChannel)
+	if _, err := sub.Receive(ctx); err != nil {
+		_ = sub.Close()
+		return nil, fmt.Errorf("subscribe %s: %w", notificationsChannel, err)
 	}
-	b, _ := json.Marshal(payload)
-	return p.redis.Publish(ctx, notificationsChannel, string(b)).Err()
+	return sub, nil
 }
 
 // AgentLoop orchestrates Memory -> Plan -> (Tool?) -> Persist, repeating up to MaxTurns.
+// filter (optional) scopes every RAG lookup of the run to matching documents.
 
-func (p *Planner) AgentLoop(ctx context.Context, prompt string, sessionID string, resources []Resource) (result string, err error) {
+func (p *Planner) AgentLoop(ctx context.Context, prompt string, sessionID string, resources []Resource, filter *ragfilter.Filter) (result string, err error) {
 	initMetrics()
 
 	tracer := otel.Tracer("backend-go-agent-planner")
@@ -458,6 +1165,8 @@ func (p *Planner) AgentLoop(ctx context.Context, prompt string, sessionID string
 		attribute.Int("resource_count", len(resources)),
 	)
 	start := time.Now()
+	// The run's prompt version, once chosen (empty when it fails before).
+	var promptVersion string
 	defer func() {
 		if loopDurationS != nil {
 			loopDurationS.Record(ctx, time.Since(start).Seconds())
@@ -467,7 +1176,7 @@ func (p *Planner) AgentLoop(ctx context.Context, prompt string, sessionID string
 			if err != nil {
 				outcome = "error"
 			}
-			planCounter.Add(ctx, 1, metric.WithAttributes(attribute.String("outcome", outcome)))
+			planCounter.Add(ctx, 1, metric.WithAttributes(attribute.String("outcome", outcome), attribute.String("prompt_version", promptVersion)))
 		}
 
 		if err != nil {
@@ -479,145 +1188,359 @@ func (p *Planner) AgentLoop(ctx context.Context, prompt string, sessionID string
 		span.End()
 	}()
 
+	// The first run claims the session for its tenant; other tenants' runs
+	// may not read or extend its history.
+	if p.auditDB != nil {
+		if err := p.authorizeSession(ctx, sessionID, true); err != nil {
+			return "", err
+		}
+	}
+
+	release, err := p.load.acquire(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+
 	ctx = injectTraceIDToOutgoingGRPC(ctx)
+	ctx = injectSessionIDToOutgoingGRPC(ctx, sessionID)
+	ctx = injectTenantIDToOutgoingGRPC(ctx)
+	ctx = injectPrincipalToOutgoingGRPC(ctx)
 	lg := logger.NewContextLogger(ctx)
 
+	// Finished runs are compared with the session's recent ones.
+	run := &runStats{}
+	defer func() {
+		if err == nil {
+			p.checkRun(ctx, sessionID, run, time.Since(start))
+		}
+	}()
+
+	if err := p.checkResources(ctx, resources); err != nil {
+		return "", err
+	}
+
+	tuning := p.tuning()
+	personaName, persona, err := tuning.persona(ctx)
+	if err != nil {
+		return "", err
+	}
+	prompts, candidate := tuning.prompts.choose(sessionID)
+	promptVersion = prompts.name
+	experiment := "control"
+	if candidate {
+		experiment = "candidate"
+	}
+	ctx = contextWithPromptVersion(ctx, promptVersion)
+	span.SetAttributes(attribute.String("prompt_version", promptVersion))
+	kbs := p.knowledgeBasesFor(ctx, sessionID, persona)
+	kbQueries := tuning.router.Route(prompt, kbs, tuning.topK)
+	playbookReuse := p.flags.Enabled(ctx, featureflags.PlaybookReuse, sessionID)
+
+	// Resolve relative bounds (within_days) once so every turn sees the same window.
+	now := time.Now()
+	filter = filter.Resolve(now)
+	ragFilter := filter.Proto(now)
+
 	basePrompt := prompt
-	_ = p.RecordStep(ctx, sessionID, "PLAN_START", map[string]any{"prompt": basePrompt, "resources": resources, "max_turns": p.cfg.MaxTurns, "top_k": p.cfg.TopK, "kbs": p.cfg.KBs})
+	_ = p.RecordStep(ctx, sessionID, "PLAN_START", map[string]any{"prompt": basePrompt, "resources": resources, "max_turns": tuning.maxTurns, "top_k": tuning.topK, "kbs": kbs, "kb_queries": kbQueries, "rag_filter": filter, "tenant": TenantFromContext(ctx), "persona": personaName, "experiment": experiment})
 	_ = p.PublishStatus(ctx, sessionID, "STARTED")
 	// Collect a per-run playbook sequence (user prompt + tool-plan/tool-result pairs + final answer).
 	// This is persisted to Mind-KB only on successful completion.
 	playbookSeq := []map[string]string{{"role": "user", "content": basePrompt}}
+	conv := &Conversation{Prompt: basePrompt}
 	hadToolStep := false
+	// Every match retrieved and every model output of the run, for retrieval feedback.
+	var retrieved retrievedMatches
+	var outputs []string
+	// Set once a turn's retrieval and planning exceed the latency budget.
+	var degraded *Degradation
+	// This run's session-history writes, merged into later turns' history.
+	var writes *runWrites
+	if tuning.readYourWrites {
+		writes = &runWrites{}
+	}
+	storeDelta := func(turn int, userPrompt, assistantText string) {
+		writes.add(userPrompt, assistantText)
+		observeStage(ctx, StageMemoryStore, p.storeSessionDelta(ctx, sessionID, turn, userPrompt, assistantText))
+	}
 
-	maxTurns := p.cfg.MaxTurns
+	maxTurns := tuning.maxTurns
 	if maxTurns <= 0 {
 		maxTurns = 3
 	}
 
+	// Each turn's duration is recorded when the next one starts or the run ends.
+	var turnStart time.Time
+	endTurn := func() {
+		if !turnStart.IsZero() && turnDurationS != nil {
+			turnDurationS.Record(ctx, time.Since(turnStart).Seconds())
+		}
+	}
+	defer endTurn()
+
 	for turn := 1; turn <= maxTurns; turn++ {
+		endTurn()
+		turnStart = time.Now()
 		span.SetAttributes(attribute.Int("turn", turn))
 
+		// This run's earlier tool turns, sent as messages after the prompt.
+		turns, err := conv.messages(prompts)
+		if err != nil {
+			_ = p.RecordStep(ctx, sessionID, "PLAN_ERROR", map[string]any{"error": err.Error()})
+			return "", err
+		}
+
 		// 1) Session history (Episodic/Heart) via Memory HTTP API.
 		var history []map[string]any
 		{
 			ctxStep, stepSpan := tracer.Start(ctx, "MemoryAccess.SessionHistory")
-			history, _ = p.fetchSessionHistory(ctxStep, sessionID)
+			var historyErr error
+			history, historyErr = p.fetchSessionHistory(ctxStep, sessionID)
+			observeStage(ctx, StageMemoryHistory, historyErr)
 			stepSpan.End()
+			var merged int
+			if history, merged = writes.merge(history); merged > 0 {
+				lg.Info("history_merged_run_writes", "messages", merged)
+			}
+		}
+		notes, notesErr := p.scratchpad.read(ctx, sessionID)
+		if notesErr != nil {
+			lg.Warn("scratchpad_unavailable", "error", notesErr)
 		}
 
 		// 2) RAG context (Domain/Body/Soul) via Memory gRPC.
 		var rag *pb.RAGContextResponse
 		{
 			ctxStep, stepSpan := tracer.Start(ctx, "MemoryAccess.RAGContext")
-			rag, err = p.callMemoryGetRAGContext(ctxStep, prompt)
+			rag, err = p.retrieveRAGContext(ctxStep, basePrompt, kbQueries, ragFilter)
 			if err != nil {
 				stepSpan.RecordError(err)
 			}
 			stepSpan.End()
 		}
+		observeStage(ctx, StageMemoryRAG, err)
 		if err != nil {
 			lg.Warn("rag_context_unavailable", "error", err)
 			rag = nil
 		}
+		rag = p.screenRAG(ctx, sessionID, turn, tuning.ragInjection, rag)
+		retrieved.add(rag)
 
-		plannerInput := buildPlannerPrompt(prompt, history, rag)
+		// This run's own notes are already in the turns as <tool_result>.
+		plannerInput, err := prompts.plannerPrompt(basePrompt, history, rag, notesBefore(notes, now))
+		if err != nil {
+			_ = p.RecordStep(ctx, sessionID, "PLAN_ERROR", map[string]any{"error": err.Error()})
+			return "", err
+		}
 
-		// 3) Planning via Model Gateway.
+		// 3) Planning via Model Gateway. A streamed plan's tool call starts
+		// as soon as it is complete, while the rest of the plan arrives.
 		var planResp *pb.PlanResponse
+		var early *earlyTool
+		var onDelta func(string)
+		if tuning.streamPlans && !probing(ctx) {
+			scanner := &toolCallScanner{}
+			onDelta = func(delta string) {
+				if call := scanner.feed(delta); call != nil {
+					early = p.dispatchEarly(ctx, sessionID, persona, notes, call)
+				}
+			}
+		}
 		{
 			ctxStep, stepSpan := tracer.Start(ctx, "PlanGeneration")
-			planResp, err = p.callModelGatewayGetPlan(ctxStep, plannerInput, resources)
+			planResp, err = p.callModelGatewayGetPlan(ctxStep, plannerInput, turns, resources, ragFilter, personaName, persona, promptVersion, tuning.modelChoice(conv), onDelta)
 			if err != nil {
 				stepSpan.RecordError(err)
 			}
 			stepSpan.End()
 		}
+		observeStage(ctx, StageModelGateway, err)
+		planned := time.Now()
 		if err != nil {
 			_ = p.RecordStep(ctx, sessionID, "PLAN_ERROR", map[string]any{"error": err.Error()})
 			return "", fmt.Errorf("GetPlan: %w", err)
 		}
-		_ = p.RecordStep(ctx, sessionID, "PLAN_MODEL_RESPONSE", map[string]any{"plan": planResp.GetPlan()})
+		modelResponse := map[string]any{"plan": planResp.GetPlan(), "ungrounded": planResp.GetUngrounded(), "gateway_prompt_version": planResp.GetPromptVersion()}
+		if provider := planResp.GetProvider(); provider != "" {
+			// Differs from the gateway's primary when LLM_PROVIDERS failed over.
+			modelResponse["provider"], modelResponse["model"] = provider, planResp.GetModelName()
+		}
+		if reasoning := planResp.GetReasoning(); reasoning != "" {
+			// Only returned with the gateway's LLM_REASONING=return.
+			modelResponse["reasoning"] = reasoning
+		}
+		if planResp.GetDegraded() {
+			// No provider was up: the plan lists what the gateway retrieved
+			// (its LLM_RETRIEVAL_FALLBACK).
+			modelResponse["retrieval_only"] = true
+			lg.Warn("plan_retrieval_only", "session_id", sessionID, "matches", len(planResp.GetMatches()))
+		}
+		_ = p.RecordStep(ctx, sessionID, "PLAN_MODEL_RESPONSE", modelResponse)
+		run.tokens += planResp.GetPromptTokens() + planResp.GetCompletionTokens()
+		if elapsed := time.Since(turnStart); degraded == nil && tuning.turnBudget > 0 && elapsed > tuning.turnBudget {
+			// Keep the remaining turns responsive: later retrievals skip the
+			// playbook lookup and the KBs the prompt was not routed to.
+			essential := tuning.router.Essential(prompt, kbs, tuning.topK)
+			degraded = &Degradation{Turn: turn, ElapsedMS: elapsed.Milliseconds(), BudgetMS: tuning.turnBudget.Milliseconds(), SkippedKBs: droppedKBs(kbQueries, essential)}
+			kbQueries = essential
+			lg.Warn("turn_over_budget", "session_id", sessionID, "turn", turn, "elapsed_ms", degraded.ElapsedMS, "budget_ms", degraded.BudgetMS, "skipped_kbs", degraded.SkippedKBs)
+			_ = p.RecordStep(ctx, sessionID, "TURN_DEGRADED", map[string]any{"turn": turn, "elapsed_ms": degraded.ElapsedMS, "budget_ms": degraded.BudgetMS, "skipped_kbs": degraded.SkippedKBs})
+			if degradedRuns != nil {
+				degradedRuns.Add(ctx, 1)
+			}
+			reportDegradation(ctx, degraded)
+		}
+		outputs = append(outputs, planResp.GetPlan())
+		if facts := planFacts(planResp.GetPlan()); len(facts) > 0 {
+			entries := make([]scratchpadEntry, 0, len(facts))
+			for _, f := range facts {
+				entries = append(entries, scratchpadEntry{Kind: "fact", Text: f, At: time.Now().UTC()})
+			}
+			if err := p.scratchpad.add(ctx, sessionID, entries...); err != nil {
+				lg.Warn("scratchpad_write_failed", "error", err)
+			}
+		}
 
 		toolCall := tryParseToolCall(planResp.GetPlan())
+		if early != nil && !early.matches(toolCall) {
+			// The final plan is authoritative.
+			early.discard(ctx, sessionID)
+			early = nil
+		}
 		if toolCall == nil {
 			// Successful completion path (non-tool-call final answer).
 			playbookSeq = append(playbookSeq, map[string]string{"role": "assistant", "content": planResp.GetPlan()})
-			_ = p.RecordStep(ctx, sessionID, "PLAN_END", map[string]any{"result": planResp.GetPlan()})
-			if hadToolStep {
-				_ = p.storePlaybook(ctx, sessionID, basePrompt, playbookSeq)
+			end := map[string]any{"result": planResp.GetPlan()}
+			if degraded != nil {
+				end["degraded"] = degraded
+			}
+			if planResp.GetDegraded() {
+				end["retrieval_only"] = true
+			}
+			_ = p.RecordStep(ctx, sessionID, "PLAN_END", end)
+			if hadToolStep && playbookReuse && !planResp.GetDegraded() {
+				// The audit copy is what session exports carry (see ExportSession).
+				if err := p.storePlaybook(ctx, sessionID, turn, basePrompt, playbookSeq); err == nil {
+					_ = p.RecordStep(ctx, sessionID, "PLAYBOOK_STORED", map[string]any{"prompt": basePrompt, "history_sequence": playbookSeq})
+				}
+			}
+			if tuning.ragFeedback && len(retrieved.matches) > 0 && !planResp.GetDegraded() {
+				feedback := retrievalFeedback(retrieved.matches, outputs)
+				used := 0
+				for _, f := range feedback {
+					if f.Used {
+						used++
+					}
+				}
+				_ = p.RecordStep(ctx, sessionID, "RAG_FEEDBACK", map[string]any{"matches": len(feedback), "used": used})
+				if err := p.storeRetrievalFeedback(ctx, sessionID, basePrompt, feedback); err != nil {
+					lg.Warn("rag_feedback_failed", "error", err)
+				}
 			}
-			_ = p.storeSessionDelta(ctx, sessionID, prompt, planResp.GetPlan())
+			if !planResp.GetDegraded() {
+				p.evaluateInBackground(ctx, tuning, sessionID, basePrompt, planResp.GetPlan(), retrieved.matches)
+			}
+			storeDelta(turn, basePrompt, planResp.GetPlan())
 			_ = p.PublishNotification(ctx, sessionID, planResp.GetPlan())
 			_ = p.PublishStatus(ctx, sessionID, "COMPLETED")
 			return planResp.GetPlan(), nil
 		}
 
 		_ = p.RecordStep(ctx, sessionID, "TOOL_CALL", map[string]any{"tool": toolCall.Name, "args": toolCall.Args})
+		run.toolCall(toolCall)
+		if !persona.allowsTool(toolCall.Name) {
+			// The gateway only offers the persona's tools, but the model may
+			// still name another one; it is refused like a failed call.
+			err := fmt.Errorf("tool %q is not allowed for persona %q", toolCall.Name, personaName)
+			_ = p.RecordStep(ctx, sessionID, "TOOL_ERROR", map[string]any{"tool": toolCall.Name, "error": err.Error()})
+			conv.addError(turn, planResp.GetPlan(), toolCall.Name, err)
+			continue
+		}
+		// A call that does not match the tool's schema goes back to the model
+		// to fix instead of reaching the sandbox.
+		if err := tools.Validate(p.Tools(), toolCall.Name, toolCall.Args); err != nil {
+			var problems []string
+			if verr, ok := err.(*tools.ValidationError); ok {
+				problems = verr.Problems
+			}
+			_ = p.RecordStep(ctx, sessionID, "TOOL_ERROR", map[string]any{"tool": toolCall.Name, "error": err.Error(), "validation": problems})
+			conv.addError(turn, planResp.GetPlan(), toolCall.Name, err)
+			continue
+		}
 
-		// 4) Tool execution via Rust sandbox ToolService over gRPC.
+		// 4) Tool execution via Rust sandbox ToolService over gRPC, unless the
+		// scratchpad already holds this call's output. The canary always runs
+		// the tool, since it checks the sandbox.
 		var toolOut string
-		{
-			ctxStep, stepSpan := tracer.Start(ctx, "ToolCallExecution")
-			stepSpan.SetAttributes(attribute.String("tool.name", toolCall.Name))
-			toolOut, err = p.executeTool(ctxStep, toolCall.Name, toolCall.Args)
+		if out, ok := p.scratchpad.reuse(notes, toolCall); ok && !probing(ctx) {
+			toolOut = out
+			_ = p.RecordStep(ctx, sessionID, "TOOL_RESULT", map[string]any{"tool": toolCall.Name, "output": toolOut, "scratchpad": true})
+		} else {
+			var budgetErr error
+			result := map[string]any{"tool": toolCall.Name}
+			if early != nil {
+				// Dispatched while the plan streamed; overlap_ms is how long
+				// it ran before the plan was complete.
+				result["early"] = true
+				result["overlap_ms"] = planned.Sub(early.started).Milliseconds()
+				toolOut, budgetErr, err = early.wait(ctx)
+			} else {
+				toolOut, budgetErr, err = p.runTool(ctx, sessionID, toolCall)
+			}
+			if budgetErr != nil {
+				_ = p.RecordStep(ctx, sessionID, "TOOL_ERROR", map[string]any{"tool": toolCall.Name, "error": budgetErr.Error(), "budget": true})
+				conv.addError(turn, planResp.GetPlan(), toolCall.Name, budgetErr)
+				continue
+			}
 			if err != nil {
-				stepSpan.RecordError(err)
+				_ = p.RecordStep(ctx, sessionID, "TOOL_ERROR", map[string]any{"tool": toolCall.Name, "error": err.Error()})
+				// Feed tool error back into the loop.
+				conv.addError(turn, planResp.GetPlan(), toolCall.Name, err)
+				continue
+			}
+			result["output"] = toolOut
+			_ = p.RecordStep(ctx, sessionID, "TOOL_RESULT", result)
+			if err := p.scratchpad.add(ctx, sessionID, scratchpadEntry{Kind: "tool", Tool: toolCall.Name, Args: toolCall.Args, Text: toolOut, At: time.Now().UTC()}); err != nil {
+				lg.Warn("scratchpad_write_failed", "error", err)
 			}
-			stepSpan.End()
-		}
-		if err != nil {
-			_ = p.RecordStep(ctx, sessionID, "TOOL_ERROR", map[string]any{"tool": toolCall.Name, "error": err.Error()})
-			// Feed tool error back into the loop.
-			prompt = prompt + "\n\nTool error: " + err.Error()
-			continue
 		}
-		_ = p.RecordStep(ctx, sessionID, "TOOL_RESULT", map[string]any{"tool": toolCall.Name, "output": toolOut})
 
 		hadToolStep = true
 		playbookSeq = append(playbookSeq, map[string]string{"role": "assistant", "content": planResp.GetPlan()})
 		playbookSeq = append(playbookSeq, map[string]string{"role": "tool_result", "content": toolOut})
 
 		// 5) Loop/feedback.
-		prompt = buildFollowupPrompt(prompt, planResp.GetPlan(), toolOut)
-		_ = p.storeSessionDelta(ctx, sessionID, "[tool-plan]", planResp.GetPlan())
-		_ = p.storeSessionDelta(ctx, sessionID, "[tool-output]", toolOut)
+		conv.addOutput(turn, planResp.GetPlan(), toolCall.Name, toolOut)
+		storeDelta(turn, "[tool-plan]", planResp.GetPlan())
+		storeDelta(turn, "[tool-output]", toolOut)
 	}
 
-	return "Max turns reached; unable to complete request.", nil
+	return maxTurnsResult, nil
 }
 
-func buildPlannerPrompt(userPrompt string, history []map[string]any, rag *pb.RAGContextResponse) string {
-	var b strings.Builder
-	b.WriteString("<session_history>\n")
-	for _, m := range history {
-		role, _ := m["role"].(string)
-		content, _ := m["content"].(string)
-		if role != "" || content != "" {
-			b.WriteString(role + ": " + content + "\n")
+// runTool runs a tool call in the sandbox. Web tools are budgeted per session
+// and per replica, except for the canary; budgetErr reports a call the budget
+// refused.
+func (p *Planner) runTool(ctx context.Context, sessionID string, call *ToolCall) (out string, budgetErr, err error) {
+	if !probing(ctx) {
+		if budgetErr = p.toolBudget.spend(ctx, sessionID, call.Name); budgetErr != nil {
+			return "", budgetErr, nil
 		}
 	}
-	b.WriteString("</session_history>\n\n")
-
-	b.WriteString("<rag_context>\n")
-	if rag != nil {
-		for _, m := range rag.GetMatches() {
-			b.WriteString("**" + m.GetKnowledgeBase() + "**\n")
-			b.WriteString("ID: " + m.GetId() + "\n")
-			b.WriteString("Text: " + m.GetText() + "\n---\n")
-		}
+	ctxStep, stepSpan := otel.Tracer("backend-go-agent-planner").Start(ctx, "ToolCallExecution")
+	stepSpan.SetAttributes(attribute.String("tool.name", call.Name))
+	out, err = p.executeTool(ctxStep, call.Name, call.Args)
+	if err != nil {
+		stepSpan.RecordError(err)
 	}
-	b.WriteString("</rag_context>\n\n")
-
-	b.WriteString("<user_prompt>\n")
-	b.WriteString(userPrompt)
-	b.WriteString("\n</user_prompt>\n")
-	return b.String()
+	stepSpan.End()
+	observeStage(ctx, StageTool, err)
+	return out, nil, err
 }
 
-func buildFollowupPrompt(originalPrompt, plan, toolResult string) string {
-	return originalPrompt + "\n\n<plan>\n" + plan + "\n</plan>\n\n<tool_result>\n" + toolResult + "\n</tool_result>\n"
-}
+// maxTurnsResult is AgentLoop's answer when no turn produced a final plan.
+const maxTurnsResult = "Max turns reached; unable to complete request."
 
 func tryParseToolCall(planJSON string) *ToolCall {
 	// Minimal parsing strategy:
@@ -641,6 +1564,7 @@ func tryParseToolCall(planJSON string) *ToolCall {
 func (p *Planner) fetchSessionHistory(ctx context.Context, sessionID string) ([]map[string]any, error) {
 	url := strings.TrimRight(p.cfg.MemoryServiceHTTP, "/") + "/memory/latest?session_id=" + sessionID
 	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	setPrincipalHeader(req)
 	resp, err := p.httpClient.Do(req)
 	if err != nil {
 		return nil, err
@@ -654,76 +1578,124 @@ func (p *Planner) fetchSessionHistory(ctx context.Context, sessionID string) ([]
 		Messages []map[string]any `json:"messages"`
 	}
 	_ = json.NewDecoder(resp.Body).Decode(&payload)
+	if err := p.openHistory(ctx, sessionID, payload.Messages); err != nil {
+		return nil, fmt.Errorf("memory/latest: %w", err)
+	}
 	return payload.Messages, nil
 }
 
-func (p *Planner) storeSessionDelta(ctx context.Context, sessionID, userPrompt, assistantText string) error {
-	url := strings.TrimRight(p.cfg.MemoryServiceHTTP, "/") + "/memory/store"
-	body := map[string]any{
+// storeSessionDelta appends a turn's exchange to the session history. Its
+// delta_id (see deltaID) lets the Memory Service drop a retried copy.
+// With session keys on, the prompt and answer are sealed with the session's
+// key; the delta ID is still derived from the plain text.
+func (p *Planner) storeSessionDelta(ctx context.Context, sessionID string, turn int, userPrompt, assistantText string) error {
+	id := deltaID(ctx, sessionID, turn, userPrompt, assistantText)
+	var err error
+	if userPrompt, err = p.auditDB.Seal(ctx, sessionID, userPrompt); err != nil {
+		return err
+	}
+	if assistantText, err = p.auditDB.Seal(ctx, sessionID, assistantText); err != nil {
+		return err
+	}
+	return p.postMemoryWrite(ctx, "/memory/store", map[string]any{
 		"session_id": sessionID,
+		"delta_id":   id,
 		"history": []map[string]any{
 			{"role": "user", "content": userPrompt},
 			{"role": "assistant", "content": assistantText},
 		},
 		"prompt":       userPrompt,
 		"llm_response": map[string]any{"text": assistantText},
-	}
-	b, _ := json.Marshal(body)
-	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(b))
-	req.Header.Set("Content-Type", "application/json")
-	resp, err := p.httpClient.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	return nil
+	})
 }
 
 func (p *Planner) storePlaybook(
 	ctx context.Context,
 	sessionID string,
+	turn int,
 	prompt string,
 	historySequence []map[string]string,
 ) error {
 	// POST to the Memory Service HTTP API to persist the playbook into Mind-KB.
 	// The Memory Service is responsible for converting this into a Chroma document.
-	url := strings.TrimRight(p.cfg.MemoryServiceHTTP, "/") + "/memory/playbook"
 
 	// Skip storing trivial 1-step sessions (no tool use), but keep the call-site simple.
 	if len(historySequence) < 3 {
 		return nil
 	}
 
-	payload := map[string]any{
+	content := []string{prompt}
+	for _, step := range historySequence {
+		content = append(content, step["role"], step["content"])
+	}
+	return p.postMemoryWrite(ctx, "/memory/playbook", map[string]any{
 		"session_id":       sessionID,
+		"delta_id":         deltaID(ctx, sessionID, turn, content...),
 		"prompt":           prompt,
 		"history_sequence": historySequence,
-	}
-	b, _ := json.Marshal(payload)
-	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(b))
-	req.Header.Set("Content-Type", "application/json")
+	})
+}
 
-	resp, err := p.httpClient.Do(req)
-	if err != nil {
-		return err
+// memoryWriteAttempts bounds postMemoryWrite's tries.
+const memoryWriteAttempts = 2
+
+// deltaID is the dedupe key of a memory write: the same run (trace ID),
+// session, turn and content always hash to the same ID. The trace ID keeps a
+// later run that repeats an earlier one's exchange from being dropped.
+func deltaID(ctx context.Context, sessionID string, turn int, content ...string) string {
+	traceID, _ := ctx.Value(logger.TraceIDKey).(string)
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%d", traceID, sessionID, turn)
+	for _, c := range content {
+		h.Write([]byte{0})
+		io.WriteString(h, c)
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode >= 300 {
-		out, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("memory/playbook: %s", string(out))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// postMemoryWrite POSTs a write carrying a delta_id to the Memory Service.
+// A write that timed out or failed with a 5xx may still have landed, so it is
+// retried; the service answers 409 to a delta ID it already stored, which
+// counts as success.
+func (p *Planner) postMemoryWrite(ctx context.Context, path string, body map[string]any) error {
+	b, _ := json.Marshal(body)
+	url := strings.TrimRight(p.cfg.MemoryServiceHTTP, "/") + path
+	name := strings.TrimPrefix(path, "/")
+	var err error
+	for attempt := 1; ; attempt++ {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(b))
+		req.Header.Set("Content-Type", "application/json")
+		setPrincipalHeader(req)
+		var resp *http.Response
+		resp, err = p.httpClient.Do(req)
+		if err == nil {
+			out, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+			resp.Body.Close()
+			switch {
+			case resp.StatusCode == http.StatusConflict:
+				logger.NewContextLogger(ctx).Info("memory_write_duplicate", "path", path, "delta_id", body["delta_id"], "attempt", attempt)
+				return nil
+			case resp.StatusCode < 300:
+				return nil
+			case resp.StatusCode < 500:
+				return fmt.Errorf("%s: %s", name, out)
+			}
+			err = fmt.Errorf("%s: %s", name, out)
+		}
+		if attempt == memoryWriteAttempts || ctx.Err() != nil {
+			return err
+		}
 	}
-	return nil
 }
 
 func (p *Planner) executeTool(ctx context.Context, toolName string, args map[string]any) (string, error) {
+	if err := p.checkToolURLs(ctx, args); err != nil {
+		return "", fmt.Errorf("tool %q: %w", toolName, err)
+	}
 	return p.executeToolGRPC(ctx, toolName, args)
 }
 
 func (p *Planner) executeToolGRPC(ctx context.Context, toolName string, args map[string]any) (string, error) {
-	if p.toolClient == nil {
-		return "", fmt.Errorf("rust sandbox tool client is nil")
-	}
-
 	if args == nil {
 		args = map[string]any{}
 	}
@@ -741,24 +1713,40 @@ func (p *Planner) executeToolGRPC(ctx context.Context, toolName string, args map
 	const defaultMemoryLimitMB int32 = 512
 	const defaultTimeoutSeconds int32 = 30
 
-	resp, err := p.toolClient.ExecuteTool(ctx, &pb.ToolRequest{
+	req := &pb.ToolRequest{
 		ToolName:             toolName,
 		ArgsJson:             string(argsJSON),
 		ExecutionEnvironment: defaultExecutionEnvironment,
 		CpuLimitMhz:          defaultCPULimitMHz,
 		MemoryLimitMb:        defaultMemoryLimitMB,
 		TimeoutSeconds:       defaultTimeoutSeconds,
-	})
-	if err != nil {
-		return "", fmt.Errorf("ExecuteTool(%q): %w", toolName, err)
+	}
+	var resp *pb.ToolResponse
+	if p.useMockTools(ctx) {
+		logger.NewContextLogger(ctx).Info("tool_mocked", "tool", toolName)
+		resp = mockprovider.ExecuteTool(req)
+	} else {
+		if p.toolClient == nil {
+			return "", fmt.Errorf("rust sandbox tool client is nil")
+		}
+		if err := p.chaos.Inject(ctx, chaos.Tool); err != nil {
+			return "", fmt.Errorf("ExecuteTool(%q): %w", toolName, err)
+		}
+		resp, err = p.toolClient.ExecuteTool(ctx, req)
+		if err != nil {
+			return "", fmt.Errorf("ExecuteTool(%q): %w", toolName, err)
+		}
 	}
 
 	// Keep the tool output structured (LLM-friendly) and consistent across tools.
+	// Output is cleaned and capped here, so the audit log, the scratchpad
+	// and the next prompt all see the same text.
+	stdout, _ := p.chaos.Malform(chaos.Tool, resp.GetStdout())
+	maxBytes := p.tuning().toolOutputMax
 	out := map[string]any{
 		"status": resp.GetStatus(),
-		"stdout": resp.GetStdout(),
-		"stderr": resp.GetStderr(),
+		"stdout": sanitizeToolOutput(stdout, maxBytes),
+		"stderr": sanitizeToolOutput(resp.GetStderr(), maxBytes),
 	}
-	encoded, _ := json.Marshal(out)
-	return string(encoded), nil
+	return encodeToolOutput(out), nil
 }
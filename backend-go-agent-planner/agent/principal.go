@@ -0,0 +1,56 @@
+package agent
+
+import (
+	"context"
+	"net/http"
+
+	"backend-go-model-gateway/service"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// Principal kinds: the credential a caller authenticated with.
+const (
+	// PrincipalAPIKey is a caller key; the ID is the tenant of a
+	// PAGI_TENANT_API_KEYS key, or "default" for PAGI_API_KEY.
+	PrincipalAPIKey = "api_key"
+	// PrincipalAgent is a peer agent key from PAGI_AGENT_KEYS; the ID is the
+	// agent.
+	PrincipalAgent = "agent"
+)
+
+// PrincipalHeader carries the principal on Memory Service HTTP calls, as
+// x-principal does on gRPC calls.
+const PrincipalHeader = "X-Principal"
+
+type principalContextKey struct{}
+
+// ContextWithPrincipal records the caller the request authenticated as, e.g.
+// ContextWithPrincipal(ctx, PrincipalAPIKey, "acme").
+func ContextWithPrincipal(ctx context.Context, kind, id string) context.Context {
+	return context.WithValue(ctx, principalContextKey{}, kind+":"+id)
+}
+
+// PrincipalFromContext returns the authenticated caller as "<kind>:<id>", or
+// "" when authentication is off.
+func PrincipalFromContext(ctx context.Context) string {
+	principal, _ := ctx.Value(principalContextKey{}).(string)
+	return principal
+}
+
+// injectPrincipalToOutgoingGRPC forwards the authenticated caller, so the
+// gateway, sandbox and Memory Service can attribute what they do to it.
+func injectPrincipalToOutgoingGRPC(ctx context.Context) context.Context {
+	principal := PrincipalFromContext(ctx)
+	if principal == "" {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, service.PrincipalMetadataKey, principal)
+}
+
+// setPrincipalHeader forwards the authenticated caller on an HTTP request.
+func setPrincipalHeader(req *http.Request) {
+	if principal := PrincipalFromContext(req.Context()); principal != "" {
+		req.Header.Set(PrincipalHeader, principal)
+	}
+}
@@ -0,0 +1,54 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Request priorities, sent to the gateway as PlanRequest.priority.
+const (
+	PriorityInteractive = "interactive"
+	PriorityBatch       = "batch"
+)
+
+// ErrInvalidPriority is returned for a priority other than PriorityInteractive
+// or PriorityBatch.
+var ErrInvalidPriority = errors.New("invalid priority")
+
+// ErrGatewayBusy is returned when the model gateway's request queue
+// (LLM_MAX_CONCURRENT_REQUESTS) had no capacity for a plan in time.
+var ErrGatewayBusy = errors.New("model gateway at capacity")
+
+type priorityKey struct{}
+
+// ContextWithPriority records a request's priority. Batch work (evaluation
+// runs, bulk imports) waits behind interactive chat for provider capacity.
+func ContextWithPriority(ctx context.Context, priority string) (context.Context, error) {
+	switch priority {
+	case "", PriorityInteractive:
+		return ctx, nil
+	case PriorityBatch:
+		return context.WithValue(ctx, priorityKey{}, priority), nil
+	}
+	return ctx, fmt.Errorf("%w: %q (want %q or %q)", ErrInvalidPriority, priority, PriorityInteractive, PriorityBatch)
+}
+
+// PriorityFromContext returns the request's priority; interactive unless set.
+func PriorityFromContext(ctx context.Context) string {
+	if priority, _ := ctx.Value(priorityKey{}).(string); priority != "" {
+		return priority
+	}
+	return PriorityInteractive
+}
+
+// gatewayBusy maps the gateway's queue rejection to ErrGatewayBusy.
+func gatewayBusy(err error) error {
+	if status.Code(err) == codes.ResourceExhausted {
+		return fmt.Errorf("%w: %s", ErrGatewayBusy, status.Convert(err).Message())
+	}
+	return err
+}
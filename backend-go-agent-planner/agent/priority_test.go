@@ -0,0 +1,37 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestContextWithPriority(t *testing.T) {
+	ctx := context.Background()
+	if got := PriorityFromContext(ctx); got != PriorityInteractive {
+		t.Fatalf("default priority = %q", got)
+	}
+	batch, err := ContextWithPriority(ctx, PriorityBatch)
+	if err != nil || PriorityFromContext(batch) != PriorityBatch {
+		t.Fatalf("batch = %q, %v", PriorityFromContext(batch), err)
+	}
+	if _, err := ContextWithPriority(ctx, "urgent"); !errors.Is(err, ErrInvalidPriority) {
+		t.Fatalf("urgent: err = %v", err)
+	}
+}
+
+func TestGatewayBusy(t *testing.T) {
+	err := gatewayBusy(status.Error(codes.ResourceExhausted, "gateway at capacity"))
+	if !errors.Is(err, ErrGatewayBusy) {
+		t.Fatalf("ResourceExhausted = %v, want ErrGatewayBusy", err)
+	}
+	if err := gatewayBusy(status.Error(codes.Unavailable, "down")); errors.Is(err, ErrGatewayBusy) {
+		t.Fatalf("Unavailable = %v", err)
+	}
+	if gatewayBusy(nil) != nil {
+		t.Fatal("nil error mapped")
+	}
+}
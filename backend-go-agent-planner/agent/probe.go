@@ -0,0 +1,240 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"backend-go-agent-planner/internal/logger"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Pipeline stages an AgentLoop reports to a stage observer. AgentLoop keeps
+// going when memory or a tool fails, so a run can "succeed" with a broken
+// pipeline; the canary probe uses these to catch that.
+const (
+	StageModelGateway  = "model_gateway"
+	StageMemoryHistory = "memory_history"
+	StageMemoryRAG     = "memory_rag"
+	StageTool          = "tool"
+	StageMemoryStore   = "memory_store"
+)
+
+// probeStages is the order in which a probe reports the first broken stage.
+var probeStages = []string{StageModelGateway, StageMemoryHistory, StageMemoryRAG, StageTool, StageMemoryStore}
+
+type stageObserverKey struct{}
+
+// stageObserver counts each stage's calls and keeps its first error.
+type stageObserver struct {
+	mu       sync.Mutex
+	calls    map[string]int
+	failures map[string]error
+}
+
+func withStageObserver(ctx context.Context) (context.Context, *stageObserver) {
+	o := &stageObserver{calls: map[string]int{}, failures: map[string]error{}}
+	return context.WithValue(ctx, stageObserverKey{}, o), o
+}
+
+// probing reports whether ctx is a canary run.
+func probing(ctx context.Context) bool {
+	return ctx.Value(stageObserverKey{}) != nil
+}
+
+// observeStage records a stage outcome for the run's observer, if any.
+func observeStage(ctx context.Context, stage string, err error) {
+	o, _ := ctx.Value(stageObserverKey{}).(*stageObserver)
+	if o == nil {
+		return
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.calls[stage]++
+	if err != nil && o.failures[stage] == nil {
+		o.failures[stage] = err
+	}
+}
+
+// ProbeConfig configures the synthetic canary run by RunProbes.
+type ProbeConfig struct {
+	// Interval between probes; 0 disables probing.
+	Interval time.Duration
+	Timeout  time.Duration
+	Prompt   string
+	// SessionID prefixes the session of each probe run. Every run gets a
+	// fresh one, so it never reads an earlier run's turns back: a stored
+	// <tool_result> would let the model answer without calling the tool.
+	SessionID string
+	// RequireTool fails a probe whose run made no tool call, so the sandbox
+	// path is covered even when the model answers directly.
+	RequireTool bool
+}
+
+// ProbeConfigFromEnv reads AGENT_PROBE_INTERVAL, AGENT_PROBE_TIMEOUT,
+// AGENT_PROBE_PROMPT, AGENT_PROBE_SESSION and AGENT_PROBE_REQUIRE_TOOL.
+func ProbeConfigFromEnv() ProbeConfig {
+	cfg := ProbeConfig{
+		Timeout:     60 * time.Second,
+		Prompt:      getenv("AGENT_PROBE_PROMPT", "Canary check: search the web for today's date and answer in one sentence."),
+		SessionID:   getenv("AGENT_PROBE_SESSION", "pagi-canary"),
+		RequireTool: !strings.EqualFold(getenv("AGENT_PROBE_REQUIRE_TOOL", "on"), "off"),
+	}
+	if d, err := time.ParseDuration(os.Getenv("AGENT_PROBE_INTERVAL")); err == nil && d > 0 {
+		cfg.Interval = d
+	}
+	if d, err := time.ParseDuration(os.Getenv("AGENT_PROBE_TIMEOUT")); err == nil && d > 0 {
+		cfg.Timeout = d
+	}
+	return cfg
+}
+
+// ProbeResult is the outcome of one canary run. Stage names the first broken
+// stage of a failed probe (one of the Stage constants, or "agent_loop").
+type ProbeResult struct {
+	OK       bool          `json:"ok"`
+	Stage    string        `json:"stage,omitempty"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration_ns"`
+	At       time.Time     `json:"at"`
+}
+
+// Probe runs the canary prompt end to end (gateway, tools, memory) once, in
+// a session of its own.
+func (p *Planner) Probe(ctx context.Context, cfg ProbeConfig) ProbeResult {
+	ctx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+	defer cancel()
+	ctx, obs := withStageObserver(ctx)
+
+	start := time.Now()
+	sessionID := fmt.Sprintf("%s-%d", cfg.SessionID, start.UnixNano())
+	result, err := p.AgentLoop(ctx, cfg.Prompt, sessionID, nil, nil)
+	res := ProbeResult{OK: true, Duration: time.Since(start), At: start.UTC()}
+
+	fail := func(stage string, err error) ProbeResult {
+		res.OK, res.Stage, res.Error = false, stage, err.Error()
+		return res
+	}
+	obs.mu.Lock()
+	defer obs.mu.Unlock()
+	for _, stage := range probeStages {
+		if e := obs.failures[stage]; e != nil {
+			return fail(stage, e)
+		}
+	}
+	switch {
+	case err != nil:
+		return fail("agent_loop", err)
+	case result == maxTurnsResult:
+		return fail("agent_loop", errors.New(result))
+	case cfg.RequireTool && obs.calls[StageTool] == 0:
+		return fail(StageTool, errors.New("the canary run made no tool call"))
+	}
+	return res
+}
+
+// RunProbes probes every cfg.Interval until ctx is done. Each probe is
+// exported as agent_probe_total{outcome,stage} and agent_probe_duration_seconds,
+// and agent_probe_last_success_timestamp_seconds tracks the last good one. The
+// first failure after a success (or at startup) publishes a CANARY_FAILED
+// notification; the next success publishes CANARY_RECOVERED.
+func (p *Planner) RunProbes(ctx context.Context, cfg ProbeConfig) {
+	lg := logger.NewContextLogger(ctx)
+	if err := p.registerProbeMetrics(); err != nil {
+		lg.Warn("probe_metrics_unavailable", "error", err.Error())
+	}
+	lg.Info("canary_probe_started", "interval", cfg.Interval.String(), "session_id", cfg.SessionID)
+
+	failing := false
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+	for {
+		res := p.Probe(ctx, cfg)
+		p.lastProbe.Store(&res)
+		p.recordProbe(ctx, res)
+
+		switch {
+		case !res.OK && ctx.Err() == nil:
+			lg.Warn("canary_probe_failed", "stage", res.Stage, "error", res.Error, "duration_ms", res.Duration.Milliseconds())
+			if !failing {
+				_ = p.publish(ctx, cfg.SessionID, map[string]any{"status": "CANARY_FAILED", "stage": res.Stage, "error": res.Error})
+			}
+			failing = true
+		case res.OK && failing:
+			lg.Info("canary_probe_recovered", "duration_ms", res.Duration.Milliseconds())
+			_ = p.PublishStatus(ctx, cfg.SessionID, "CANARY_RECOVERED")
+			failing = false
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProbeStatus is the planner's part of GET /admin/status for the canary.
+func (p *Planner) ProbeStatus() *ProbeResult {
+	return p.lastProbe.Load()
+}
+
+var (
+	probeMetricsOnce sync.Once
+	probeTotal       metric.Int64Counter
+	probeDurationS   metric.Float64Histogram
+)
+
+func (p *Planner) recordProbe(ctx context.Context, res ProbeResult) {
+	if res.OK {
+		p.lastProbeOK.Store(res.At.Unix())
+	}
+	outcome := "success"
+	if !res.OK {
+		outcome = "failure"
+	}
+	if probeTotal != nil {
+		probeTotal.Add(ctx, 1, metric.WithAttributes(attribute.String("outcome", outcome), attribute.String("stage", res.Stage)))
+	}
+	if probeDurationS != nil {
+		probeDurationS.Record(ctx, res.Duration.Seconds(), metric.WithAttributes(attribute.String("outcome", outcome)))
+	}
+}
+
+func (p *Planner) registerProbeMetrics() error {
+	m := otel.Meter("backend-go-agent-planner")
+	var errs []error
+	probeMetricsOnce.Do(func() {
+		var err error
+		if probeTotal, err = m.Int64Counter(probeTotalMetric.name,
+			metric.WithDescription("Canary probes by outcome and failed stage."), metric.WithUnit(probeTotalMetric.unit)); err != nil {
+			errs = append(errs, err)
+		}
+		if probeDurationS, err = m.Float64Histogram("agent_probe_duration_seconds",
+			metric.WithDescription("End-to-end canary probe duration in seconds."), metric.WithUnit("s")); err != nil {
+			errs = append(errs, err)
+		}
+	})
+	lastSuccess, err := m.Int64ObservableGauge(probeLastSuccessMetric.name,
+		metric.WithDescription("Unix time of the last successful canary probe (0 before the first)."), metric.WithUnit(probeLastSuccessMetric.unit))
+	if err != nil {
+		return errors.Join(append(errs, err)...)
+	}
+	if _, err := m.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		o.ObserveInt64(lastSuccess, p.lastProbeOK.Load())
+		return nil
+	}, lastSuccess); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("probe metrics: %w", errors.Join(errs...))
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package agent
+
+import (
+	"fmt"
+	"testing"
+
+	pb "backend-go-model-gateway/proto/proto"
+)
+
+func benchHistory(n int) []map[string]any {
+	h := make([]map[string]any, 0, n)
+	for i := 0; i < n; i++ {
+		role := "user"
+		if i%2 == 1 {
+			role = "assistant"
+		}
+		h = append(h, map[string]any{"role": role, "content": fmt.Sprintf("turn %d: please summarize the notes from yesterday's planning session", i)})
+	}
+	return h
+}
+
+func benchRAG(n int) *pb.RAGContextResponse {
+	kbs := []string{"Domain-KB", "Body-KB", "Soul-KB", "Mind-KB"}
+	matches := make([]*pb.RAGMatch, 0, n)
+	for i := 0; i < n; i++ {
+		matches = append(matches, &pb.RAGMatch{
+			Id:            fmt.Sprintf("doc-%d", i),
+			Text:          "Relevant passage about morning routines, calendar hygiene and deep-work blocks.",
+			KnowledgeBase: kbs[i%len(kbs)],
+		})
+	}
+	return &pb.RAGContextResponse{Matches: matches}
+}
+
+func BenchmarkBuildPlannerPrompt(b *testing.B) {
+	prompts, err := loadPromptSet(Config{})
+	if err != nil {
+		b.Fatal(err)
+	}
+	v1, _ := prompts.choose("bench")
+	for _, size := range []struct{ history, matches int }{{2, 3}, {20, 12}, {100, 40}} {
+		history := benchHistory(size.history)
+		rag := benchRAG(size.matches)
+		b.Run(fmt.Sprintf("history=%d/matches=%d", size.history, size.matches), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_, _ = v1.plannerPrompt("what is on my calendar today?", history, rag, nil)
+			}
+		})
+	}
+}
+
+func BenchmarkTryParseToolCall(b *testing.B) {
+	plan := `{"tool":{"name":"web_search","args":{"query":"weather in Lisbon"}},"model_type":"openrouter","prompt":"p"}`
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if tryParseToolCall(plan) == nil {
+			b.Fatal("expected tool call")
+		}
+	}
+}
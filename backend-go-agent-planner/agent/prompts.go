@@ -0,0 +1,223 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+
+	pb "backend-go-model-gateway/proto/proto"
+)
+
+// DefaultPromptVersion is the built-in prompt template set.
+const DefaultPromptVersion = "v1"
+
+// defaultPlannerTemplate and defaultFollowupTemplate are version v1 of the
+// planner's prompt assembly. Candidate versions in AGENT_PROMPTS_PATH are
+// written against the same data (plannerPromptData, followupPromptData).
+const (
+	defaultPlannerTemplate = `<session_history>
+{{range .History}}{{.Role}}: {{.Content}}
+{{end}}</session_history>
+
+<rag_context>
+{{range .Matches}}**{{.KnowledgeBase}}**
+ID: {{.ID}}
+Text: {{.Text}}
+---
+{{end}}</rag_context>
+
+{{if .Scratchpad}}<scratchpad>
+{{.Scratchpad}}</scratchpad>
+
+{{end}}<user_prompt>
+{{.Prompt}}
+</user_prompt>
+`
+	defaultFollowupTemplate = `<tool_result>
+{{.ToolResult}}
+</tool_result>
+`
+)
+
+// plannerPromptData is what planner templates render: the session history,
+// the turn's RAG matches, scratchpad notes and the user's prompt.
+type plannerPromptData struct {
+	History    []promptMessage
+	Matches    []promptMatch
+	Scratchpad string
+	Prompt     string
+}
+
+type promptMessage struct{ Role, Content string }
+
+type promptMatch struct{ KnowledgeBase, ID, Text string }
+
+// followupPromptData is what followup templates render after a tool call:
+// the user message answering the plan, which is sent as its own assistant
+// message before it.
+type followupPromptData struct {
+	Prompt, Plan, ToolResult string
+}
+
+// promptVersion is one named set of planner templates.
+type promptVersion struct {
+	name     string
+	planner  *template.Template
+	followup *template.Template
+}
+
+// promptTemplateFile is one entry of AGENT_PROMPTS_PATH; an omitted followup
+// template keeps v1's.
+type promptTemplateFile struct {
+	Planner  string `json:"planner"`
+	Followup string `json:"followup"`
+}
+
+// promptSet holds the prompt versions and how traffic is split between the
+// default and a candidate.
+type promptSet struct {
+	versions map[string]*promptVersion
+	current  string
+	// candidate gets candidatePercent of sessions (0: no experiment).
+	candidate        string
+	candidatePercent int
+}
+
+// loadPromptSet builds v1 plus the versions in AGENT_PROMPTS_PATH, and checks
+// that AGENT_PROMPT_VERSION and AGENT_PROMPT_CANDIDATE name one of them.
+func loadPromptSet(cfg Config) (*promptSet, error) {
+	v1, err := newPromptVersion(DefaultPromptVersion, promptTemplateFile{Planner: defaultPlannerTemplate, Followup: defaultFollowupTemplate})
+	if err != nil {
+		return nil, err
+	}
+	s := &promptSet{versions: map[string]*promptVersion{DefaultPromptVersion: v1}, current: cfg.PromptVersion, candidate: cfg.PromptCandidate, candidatePercent: cfg.PromptCandidatePercent}
+	if s.current == "" {
+		s.current = DefaultPromptVersion
+	}
+	if cfg.PromptsPath != "" {
+		b, err := os.ReadFile(cfg.PromptsPath)
+		if err != nil {
+			return nil, fmt.Errorf("AGENT_PROMPTS_PATH: %w", err)
+		}
+		var files map[string]promptTemplateFile
+		if err := json.Unmarshal(b, &files); err != nil {
+			return nil, fmt.Errorf("AGENT_PROMPTS_PATH %s: %w", cfg.PromptsPath, err)
+		}
+		for name, f := range files {
+			if name == DefaultPromptVersion {
+				return nil, fmt.Errorf("AGENT_PROMPTS_PATH %s: %s is built in", cfg.PromptsPath, DefaultPromptVersion)
+			}
+			if f.Followup == "" {
+				f.Followup = defaultFollowupTemplate
+			}
+			v, err := newPromptVersion(name, f)
+			if err != nil {
+				return nil, fmt.Errorf("AGENT_PROMPTS_PATH %s: %w", cfg.PromptsPath, err)
+			}
+			s.versions[name] = v
+		}
+	}
+	if s.versions[s.current] == nil {
+		return nil, fmt.Errorf("AGENT_PROMPT_VERSION %q: no such prompt version", s.current)
+	}
+	if s.candidate != "" && s.versions[s.candidate] == nil {
+		return nil, fmt.Errorf("AGENT_PROMPT_CANDIDATE %q: no such prompt version", s.candidate)
+	}
+	if s.candidatePercent < 0 || s.candidatePercent > 100 {
+		return nil, fmt.Errorf("AGENT_PROMPT_CANDIDATE_PERCENT %d: want 0-100", s.candidatePercent)
+	}
+	return s, nil
+}
+
+func newPromptVersion(name string, f promptTemplateFile) (*promptVersion, error) {
+	if strings.TrimSpace(f.Planner) == "" {
+		return nil, fmt.Errorf("prompt version %q: planner template is required", name)
+	}
+	planner, err := template.New(name + "/planner").Option("missingkey=error").Parse(f.Planner)
+	if err != nil {
+		return nil, fmt.Errorf("prompt version %q: %w", name, err)
+	}
+	followup, err := template.New(name + "/followup").Option("missingkey=error").Parse(f.Followup)
+	if err != nil {
+		return nil, fmt.Errorf("prompt version %q: %w", name, err)
+	}
+	// Unknown fields only fail when rendered; catch them at load.
+	if err := planner.Execute(io.Discard, plannerPromptData{}); err != nil {
+		return nil, fmt.Errorf("prompt version %q: %w", name, err)
+	}
+	if err := followup.Execute(io.Discard, followupPromptData{}); err != nil {
+		return nil, fmt.Errorf("prompt version %q: %w", name, err)
+	}
+	return &promptVersion{name: name, planner: planner, followup: followup}, nil
+}
+
+// choose returns the prompt version for a session and whether it is the
+// candidate. Sessions are bucketed by a hash of their ID, so one session never
+// switches versions mid-conversation.
+func (s *promptSet) choose(sessionID string) (*promptVersion, bool) {
+	if s.candidate != "" && s.candidate != s.current && s.candidatePercent > 0 {
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(sessionID))
+		if int(h.Sum32()%100) < s.candidatePercent {
+			return s.versions[s.candidate], true
+		}
+	}
+	return s.versions[s.current], false
+}
+
+// names lists the loaded versions.
+func (s *promptSet) names() []string {
+	names := make([]string, 0, len(s.versions))
+	for name := range s.versions {
+		names = append(names, name)
+	}
+	return names
+}
+
+// plannerPrompt renders the planner input for one turn.
+func (v *promptVersion) plannerPrompt(userPrompt string, history []map[string]any, rag *pb.RAGContextResponse, notes []scratchpadEntry) (string, error) {
+	data := plannerPromptData{Prompt: userPrompt, Scratchpad: renderScratchpad(notes)}
+	for _, m := range history {
+		role, _ := m["role"].(string)
+		content, _ := m["content"].(string)
+		if role != "" || content != "" {
+			data.History = append(data.History, promptMessage{Role: role, Content: content})
+		}
+	}
+	for _, m := range rag.GetMatches() {
+		data.Matches = append(data.Matches, promptMatch{KnowledgeBase: m.GetKnowledgeBase(), ID: m.GetId(), Text: m.GetText()})
+	}
+	var b strings.Builder
+	if err := v.planner.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("prompt version %q: %w", v.name, err)
+	}
+	return b.String(), nil
+}
+
+// followupPrompt renders the message feeding a tool result to the next turn.
+func (v *promptVersion) followupPrompt(originalPrompt, plan, toolResult string) (string, error) {
+	var b strings.Builder
+	if err := v.followup.Execute(&b, followupPromptData{Prompt: originalPrompt, Plan: plan, ToolResult: toolResult}); err != nil {
+		return "", fmt.Errorf("prompt version %q: %w", v.name, err)
+	}
+	return b.String(), nil
+}
+
+type promptVersionKey struct{}
+
+// contextWithPromptVersion tags a run's context with its prompt version, which
+// RecordStep adds to every audit step of the run.
+func contextWithPromptVersion(ctx context.Context, version string) context.Context {
+	return context.WithValue(ctx, promptVersionKey{}, version)
+}
+
+// promptVersionFromContext returns the run's prompt version, or "".
+func promptVersionFromContext(ctx context.Context) string {
+	v, _ := ctx.Value(promptVersionKey{}).(string)
+	return v
+}
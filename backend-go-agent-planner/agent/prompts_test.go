@@ -0,0 +1,99 @@
+package agent
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	pb "backend-go-model-gateway/proto/proto"
+)
+
+func TestPromptVersion_V1(t *testing.T) {
+	prompts, err := loadPromptSet(Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v1, candidate := prompts.choose("s1")
+	if v1.name != DefaultPromptVersion || candidate {
+		t.Fatalf("choose = %s, %v", v1.name, candidate)
+	}
+	history := []map[string]any{{"role": "user", "content": "hi"}, {}, {"role": "assistant", "content": "hello"}}
+	rag := &pb.RAGContextResponse{Matches: []*pb.RAGMatch{{Id: "d1", Text: "Sam runs on Tuesdays.", KnowledgeBase: "Body-KB"}}}
+	got, err := v1.plannerPrompt("plan my week", history, rag, []scratchpadEntry{{Kind: "fact", Text: "likes mornings"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "<session_history>\nuser: hi\nassistant: hello\n</session_history>\n\n" +
+		"<rag_context>\n**Body-KB**\nID: d1\nText: Sam runs on Tuesdays.\n---\n</rag_context>\n\n" +
+		"<scratchpad>\n- likes mornings\n</scratchpad>\n\n" +
+		"<user_prompt>\nplan my week\n</user_prompt>\n"
+	if got != want {
+		t.Fatalf("planner prompt:\n%s\nwant:\n%s", got, want)
+	}
+	if got, _ := v1.plannerPrompt("p", nil, nil, nil); got != "<session_history>\n</session_history>\n\n<rag_context>\n</rag_context>\n\n<user_prompt>\np\n</user_prompt>\n" {
+		t.Fatalf("empty planner prompt:\n%s", got)
+	}
+	if got, _ := v1.followupPrompt("p", `{"tool":{}}`, "out"); got != "<tool_result>\nout\n</tool_result>\n" {
+		t.Fatalf("followup prompt:\n%s", got)
+	}
+}
+
+func TestPromptSet_Candidate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prompts.json")
+	if err := os.WriteFile(path, []byte(`{"v2": {"planner": "{{.Prompt}}"}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	prompts, err := loadPromptSet(Config{PromptsPath: path, PromptCandidate: "v2", PromptCandidatePercent: 30})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for i := range 1000 {
+		session := fmt.Sprintf("session-%d", i)
+		v, candidate := prompts.choose(session)
+		if candidate != (v.name == "v2") {
+			t.Fatalf("%s: version %s, candidate %v", session, v.name, candidate)
+		}
+		// Assignment is sticky per session.
+		if again, _ := prompts.choose(session); again != v {
+			t.Fatalf("%s switched from %s to %s", session, v.name, again.name)
+		}
+		if candidate {
+			n++
+		}
+	}
+	if n < 250 || n > 350 {
+		t.Fatalf("candidate share = %d/1000, want about 30%%", n)
+	}
+	// An omitted followup template keeps v1's.
+	v2 := prompts.versions["v2"]
+	if got, _ := v2.followupPrompt("p", "plan", "out"); !strings.Contains(got, "<tool_result>\nout\n</tool_result>") {
+		t.Fatalf("v2 followup = %q", got)
+	}
+}
+
+func TestLoadPromptSet_Errors(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, body string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	for name, cfg := range map[string]Config{
+		"unknown version":   {PromptVersion: "v2"},
+		"unknown candidate": {PromptCandidate: "v2", PromptCandidatePercent: 10},
+		"percent":           {PromptCandidate: "v1", PromptCandidatePercent: 101},
+		"missing file":      {PromptsPath: filepath.Join(dir, "nope.json")},
+		"overrides v1":      {PromptsPath: write("v1.json", `{"v1": {"planner": "x"}}`)},
+		"no planner":        {PromptsPath: write("empty.json", `{"v2": {"followup": "x"}}`)},
+		"unknown field":     {PromptsPath: write("field.json", `{"v2": {"planner": "{{.Nope}}"}}`)},
+	} {
+		if _, err := loadPromptSet(cfg); err == nil {
+			t.Errorf("%s: loaded", name)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"unicode"
+
+	pb "backend-go-model-gateway/proto/proto"
+)
+
+// feedbackUsedOverlap is the share of a match's distinctive words that must
+// appear in the run's output for the match to count as used.
+const feedbackUsedOverlap = 0.5
+
+// matchFeedback tells the memory service whether a retrieved match helped.
+type matchFeedback struct {
+	ID            string `json:"id"`
+	KnowledgeBase string `json:"knowledge_base"`
+	// Cited is set when the output quotes the match ID.
+	Cited bool `json:"cited"`
+	// Overlap is the share of the match's distinctive words found in the output.
+	Overlap float64 `json:"overlap"`
+	Used    bool    `json:"used"`
+}
+
+// retrievedMatches collects the distinct matches retrieved over a run, in
+// first-retrieved order.
+type retrievedMatches struct {
+	seen    map[string]bool
+	matches []*pb.RAGMatch
+}
+
+func (r *retrievedMatches) add(resp *pb.RAGContextResponse) {
+	for _, m := range resp.GetMatches() {
+		key := m.GetKnowledgeBase() + "/" + m.GetId()
+		if r.seen[key] {
+			continue
+		}
+		if r.seen == nil {
+			r.seen = map[string]bool{}
+		}
+		r.seen[key] = true
+		r.matches = append(r.matches, m)
+	}
+}
+
+// retrievalFeedback judges each match against the run's model outputs (tool
+// plans and the final answer). Models rarely cite IDs, so a match also counts
+// as used when most of its distinctive words (four letters or more) appear in
+// the output.
+func retrievalFeedback(matches []*pb.RAGMatch, outputs []string) []matchFeedback {
+	output := strings.ToLower(strings.Join(outputs, "\n"))
+	outputWords := map[string]bool{}
+	for _, w := range feedbackWords(output) {
+		outputWords[w] = true
+	}
+
+	feedback := make([]matchFeedback, 0, len(matches))
+	for _, m := range matches {
+		f := matchFeedback{
+			ID:            m.GetId(),
+			KnowledgeBase: m.GetKnowledgeBase(),
+			Cited:         m.GetId() != "" && strings.Contains(output, strings.ToLower(m.GetId())),
+		}
+		words := feedbackWords(m.GetText())
+		if len(words) > 0 {
+			found := 0
+			for _, w := range words {
+				if outputWords[w] {
+					found++
+				}
+			}
+			f.Overlap = float64(found) / float64(len(words))
+		}
+		f.Used = f.Cited || f.Overlap >= feedbackUsedOverlap
+		feedback = append(feedback, f)
+	}
+	return feedback
+}
+
+// feedbackWords returns the distinct lowercase words of text with at least
+// four letters.
+func feedbackWords(text string) []string {
+	seen := map[string]bool{}
+	var words []string
+	for _, w := range strings.FieldsFunc(strings.ToLower(text), func(c rune) bool {
+		return !unicode.IsLetter(c) && !unicode.IsNumber(c)
+	}) {
+		if len([]rune(w)) >= 4 && !seen[w] {
+			seen[w] = true
+			words = append(words, w)
+		}
+	}
+	return words
+}
+
+// storeRetrievalFeedback reports which retrieved matches a successful run
+// used, so the Memory Service can boost or decay their relevance.
+func (p *Planner) storeRetrievalFeedback(ctx context.Context, sessionID, prompt string, feedback []matchFeedback) error {
+	if len(feedback) == 0 {
+		return nil
+	}
+	url := strings.TrimRight(p.cfg.MemoryServiceHTTP, "/") + "/memory/feedback"
+	payload := map[string]any{
+		"session_id": sessionID,
+		"prompt":     prompt,
+		"matches":    feedback,
+	}
+	b, _ := json.Marshal(payload)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(b))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		out, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("memory/feedback: %s", string(out))
+	}
+	return nil
+}
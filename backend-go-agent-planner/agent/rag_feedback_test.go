@@ -0,0 +1,38 @@
+package agent
+
+import (
+	"testing"
+
+	pb "backend-go-model-gateway/proto/proto"
+)
+
+func TestRetrievalFeedback(t *testing.T) {
+	var retrieved retrievedMatches
+	retrieved.add(&pb.RAGContextResponse{Matches: []*pb.RAGMatch{
+		{Id: "body-7", KnowledgeBase: "Body-KB", Text: "Long runs happen on Sunday mornings before breakfast."},
+		{Id: "soul-2", KnowledgeBase: "Soul-KB", Text: "Family dinners are never rescheduled."},
+		{Id: "domain-4", KnowledgeBase: "Domain-KB", Text: "Glycogen stores deplete after ninety minutes of exercise."},
+	}})
+	// A later turn retrieves one of them again.
+	retrieved.add(&pb.RAGContextResponse{Matches: []*pb.RAGMatch{{Id: "body-7", KnowledgeBase: "Body-KB"}}})
+	retrieved.add(nil)
+	if len(retrieved.matches) != 3 {
+		t.Fatalf("got %d distinct matches, want 3", len(retrieved.matches))
+	}
+
+	outputs := []string{
+		`{"tool":{"name":"calendar_lookup","args":{"day":"sunday"}}}`,
+		`{"steps":["Schedule the long run on Sunday morning before breakfast","Keep dinner free (see soul-2)"]}`,
+	}
+	got := retrievalFeedback(retrieved.matches, outputs)
+
+	want := map[string]bool{"body-7": true, "soul-2": true, "domain-4": false}
+	for _, f := range got {
+		if f.Used != want[f.ID] {
+			t.Errorf("%s: used = %t (cited %t, overlap %.2f), want %t", f.ID, f.Used, f.Cited, f.Overlap, want[f.ID])
+		}
+	}
+	if !got[1].Cited || got[0].Cited {
+		t.Errorf("cited: got %+v", got)
+	}
+}
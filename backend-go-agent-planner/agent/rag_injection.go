@@ -0,0 +1,73 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"unicode/utf8"
+
+	"backend-go-agent-planner/internal/logger"
+	"backend-go-model-gateway/pkg/promptguard"
+	pb "backend-go-model-gateway/proto/proto"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// ragInjectionMode validates AGENT_RAG_INJECTION ("": quarantine).
+func ragInjectionMode(cfg Config) (string, error) {
+	mode, ok := promptguard.ParseMode(cfg.RAGInjection)
+	if !ok {
+		return "", fmt.Errorf("unsupported AGENT_RAG_INJECTION=%q (supported: quarantine, flag, off)", cfg.RAGInjection)
+	}
+	return mode, nil
+}
+
+// screenRAG scans a turn's retrieved matches for prompt injection
+// (pkg/promptguard) before they go into the planner prompt. In quarantine
+// mode flagged matches are left out; either way they are logged, recorded as a
+// RAG_QUARANTINED step and counted in agent_rag_matches_scanned_total.
+func (p *Planner) screenRAG(ctx context.Context, sessionID string, turn int, mode string, rag *pb.RAGContextResponse) *pb.RAGContextResponse {
+	if mode == promptguard.ModeOff || len(rag.GetMatches()) == 0 {
+		return rag
+	}
+	var flagged []map[string]any
+	kept := make([]*pb.RAGMatch, 0, len(rag.GetMatches()))
+	for _, m := range rag.GetMatches() {
+		rules := promptguard.ScanMatch(m.GetKnowledgeBase(), m.GetText())
+		countRAGScan(ctx, m.GetKnowledgeBase(), len(rules) > 0)
+		if len(rules) == 0 || mode == promptguard.ModeFlag {
+			kept = append(kept, m)
+		}
+		if len(rules) > 0 {
+			logger.NewContextLogger(ctx).Warn("rag_injection_detected", "session_id", sessionID, "kb", m.GetKnowledgeBase(), "id", m.GetId(), "rules", rules, "mode", mode)
+			flagged = append(flagged, map[string]any{"id": m.GetId(), "kb": m.GetKnowledgeBase(), "source": m.GetSource(), "rules": rules, "excerpt": excerpt(m.GetText(), 200)})
+		}
+	}
+	if len(flagged) == 0 {
+		return rag
+	}
+	_ = p.RecordStep(ctx, sessionID, "RAG_QUARANTINED", map[string]any{"turn": turn, "mode": mode, "matches": flagged})
+	return &pb.RAGContextResponse{Matches: kept}
+}
+
+func countRAGScan(ctx context.Context, kb string, flagged bool) {
+	if ragMatchesScanned == nil {
+		return
+	}
+	verdict := "clean"
+	if flagged {
+		verdict = "injection"
+	}
+	ragMatchesScanned.Add(ctx, 1, metric.WithAttributes(attribute.String("kb", kb), attribute.String("verdict", verdict)))
+}
+
+// excerpt cuts s to at most n bytes, on a rune boundary.
+func excerpt(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n] + "…"
+}
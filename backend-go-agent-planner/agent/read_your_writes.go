@@ -0,0 +1,57 @@
+package agent
+
+// runWrites are the session-history messages a run has written, kept when
+// AGENT_READ_YOUR_WRITES is on. Later turns merge them into the history they
+// fetch, so they see the run's earlier turns even while the Memory Service
+// write is in flight, failed or not yet visible. A nil *runWrites keeps
+// nothing.
+type runWrites struct {
+	messages []map[string]any
+}
+
+// add records one storeSessionDelta exchange, in the same shape.
+func (w *runWrites) add(userPrompt, assistantText string) {
+	if w == nil {
+		return
+	}
+	w.messages = append(w.messages,
+		map[string]any{"role": "user", "content": userPrompt},
+		map[string]any{"role": "assistant", "content": assistantText},
+	)
+}
+
+// merge appends the run's messages that history is missing. The run's
+// messages are looked for in order, so a message already in history is not
+// repeated and the run's turns keep their order. added is how many were
+// appended.
+func (w *runWrites) merge(history []map[string]any) (merged []map[string]any, added int) {
+	if w == nil || len(w.messages) == 0 {
+		return history, 0
+	}
+	merged = history
+	next := 0
+	for _, m := range w.messages {
+		found := -1
+		for i := next; i < len(history); i++ {
+			if sameMessage(history[i], m) {
+				found = i
+				break
+			}
+		}
+		if found >= 0 {
+			next = found + 1
+			continue
+		}
+		merged = append(merged, m)
+		added++
+	}
+	return merged, added
+}
+
+func sameMessage(a, b map[string]any) bool {
+	ra, _ := a["role"].(string)
+	rb, _ := b["role"].(string)
+	ca, _ := a["content"].(string)
+	cb, _ := b["content"].(string)
+	return ra == rb && ca == cb
+}
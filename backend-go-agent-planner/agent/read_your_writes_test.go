@@ -0,0 +1,32 @@
+package agent
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRunWritesMerge(t *testing.T) {
+	msg := func(role, content string) map[string]any { return map[string]any{"role": role, "content": content} }
+	w := &runWrites{}
+	w.add("[tool-plan]", "search")
+	w.add("[tool-output]", "sunny")
+
+	// Only the first exchange has reached the memory service.
+	history := []map[string]any{msg("user", "earlier"), msg("assistant", "answer"), msg("user", "[tool-plan]"), msg("assistant", "search")}
+	merged, added := w.merge(history)
+	want := append(append([]map[string]any{}, history...), msg("user", "[tool-output]"), msg("assistant", "sunny"))
+	if added != 2 || !reflect.DeepEqual(merged, want) {
+		t.Fatalf("merge = %v (%d added)", merged, added)
+	}
+
+	// Everything visible: nothing is repeated.
+	if merged, added := w.merge(want); added != 0 || len(merged) != len(want) {
+		t.Fatalf("merge of a complete history added %d", added)
+	}
+
+	var off *runWrites
+	off.add("u", "a")
+	if merged, added := off.merge(history); added != 0 || len(merged) != len(history) {
+		t.Fatalf("nil runWrites merged %d", added)
+	}
+}
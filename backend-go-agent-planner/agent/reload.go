@@ -0,0 +1,246 @@
+package agent
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"backend-go-model-gateway/pkg/buildinfo"
+	"backend-go-model-gateway/pkg/promptguard"
+	"backend-go-model-gateway/pkg/tools"
+)
+
+// loopTuning holds the AgentLoop settings POST /admin/reload-config can
+// change. Each run reads them once, so a reload never affects a run midway.
+type loopTuning struct {
+	maxTurns    int
+	topK        int
+	ragFeedback bool
+	kbRouting   string
+	// readYourWrites is AGENT_READ_YOUR_WRITES.
+	readYourWrites bool
+	// streamPlans is AGENT_STREAM_PLANS.
+	streamPlans bool
+	router      *kbRouter
+
+	personas       map[string]*Persona
+	defaultPersona string
+
+	evaluation     string
+	evalSampleRate float64
+
+	// ragInjection is AGENT_RAG_INJECTION, validated.
+	ragInjection string
+
+	prompts *promptSet
+
+	toolOutputMax int
+	turnBudget    time.Duration
+
+	routingModel, synthesisModel ModelChoice
+}
+
+// tuning returns the current loop settings: the last reload's, or cfg's.
+func (p *Planner) tuning() *loopTuning {
+	if t := p.reloaded.Load(); t != nil {
+		return t
+	}
+	// Validated by NewPlanner.
+	ragInjection, _ := ragInjectionMode(p.cfg)
+	return &loopTuning{
+		maxTurns:    p.cfg.MaxTurns,
+		topK:        p.cfg.TopK,
+		ragFeedback: p.cfg.RAGFeedback,
+		kbRouting:   p.cfg.KBRouting,
+		router:      p.router,
+
+		readYourWrites: p.cfg.ReadYourWrites,
+		streamPlans:    p.cfg.StreamPlans,
+
+		personas:       p.personas,
+		defaultPersona: p.cfg.DefaultPersona,
+
+		evaluation:     p.cfg.Evaluation,
+		evalSampleRate: p.cfg.EvaluationSampleRate,
+
+		ragInjection: ragInjection,
+
+		prompts: p.prompts,
+
+		toolOutputMax: p.cfg.ToolOutputMaxBytes,
+		turnBudget:    p.cfg.TurnLatencyBudget,
+
+		routingModel:   p.cfg.RoutingModel,
+		synthesisModel: p.cfg.SynthesisModel,
+	}
+}
+
+// ReloadConfig re-reads the loop settings from the environment: max turns,
+// RAG depth, KB routing (including AGENT_KB_ROUTES_PATH), retrieval feedback,
+// read-your-writes, plan streaming, personas, prompt versions, RAG injection
+// screening, tool budgets, anomaly detection, the tool output cap, the turn
+// latency budget and the routing/synthesis models. On error the running settings are kept. Connections and the audit
+// DB are not rebuilt.
+func (p *Planner) ReloadConfig(ctx context.Context) (map[string]any, error) {
+	cfg := ConfigFromEnv()
+	router, err := newKBRouter(cfg)
+	if err != nil {
+		return nil, err
+	}
+	personas, err := loadPersonas(cfg)
+	if err != nil {
+		return nil, err
+	}
+	prompts, err := loadPromptSet(cfg)
+	if err != nil {
+		return nil, err
+	}
+	ragInjection, err := ragInjectionMode(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if p.toolBudget != nil {
+		p.toolBudget.configure(cfg)
+	}
+	if p.anomalies != nil {
+		p.anomalies.configure(cfg.Anomalies)
+	}
+	p.reloaded.Store(&loopTuning{
+		maxTurns:    cfg.MaxTurns,
+		topK:        cfg.TopK,
+		ragFeedback: cfg.RAGFeedback,
+		kbRouting:   cfg.KBRouting,
+		router:      router,
+
+		readYourWrites: cfg.ReadYourWrites,
+		streamPlans:    cfg.StreamPlans,
+
+		personas:       personas,
+		defaultPersona: cfg.DefaultPersona,
+
+		evaluation:     cfg.Evaluation,
+		evalSampleRate: cfg.EvaluationSampleRate,
+
+		ragInjection: ragInjection,
+
+		prompts: prompts,
+
+		toolOutputMax: cfg.ToolOutputMaxBytes,
+		turnBudget:    cfg.TurnLatencyBudget,
+
+		routingModel:   cfg.RoutingModel,
+		synthesisModel: cfg.SynthesisModel,
+	})
+	return p.AdminStatus(ctx), nil
+}
+
+// Features lists the optional features enabled now, for GET /version: the
+// loop settings switched on, the optional backends connected and the feature
+// flags on by default.
+func (p *Planner) Features(ctx context.Context) []string {
+	t := p.tuning()
+	out := buildinfo.Enabled(p.Flags().Snapshot(ctx, ""))
+	for name, on := range map[string]bool{
+		"kb_routing":        t.kbRouting != "off",
+		"rag_feedback":      t.ragFeedback,
+		"read_your_writes":  t.readYourWrites,
+		"stream_plans":      t.streamPlans,
+		"rag_injection":     t.ragInjection != promptguard.ModeOff,
+		"evaluation":        t.evaluation != "" && t.evaluation != EvaluationOff,
+		"personas":          len(t.personas) > 0,
+		"prompt_experiment": t.prompts != nil && t.prompts.candidate != "",
+		"audit":             p.auditDB != nil,
+		"session_keys":      p.auditDB.SessionKeysEnabled(),
+		"notifications":     p.redis != nil,
+		"scratchpad":        p.scratchpad != nil,
+		"mock_tools":        p.mockToolsStatus() != nil,
+		"tool_budget":       p.toolBudget.status() != nil,
+		"canary":            p.ProbeStatus() != nil,
+		"tool_discovery":    p.toolsDiscovered(),
+		"anomalies":         p.anomalies.status() != nil,
+	} {
+		if on {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// AdminStatus is the planner's part of GET /admin/status.
+func (p *Planner) AdminStatus(context.Context) map[string]any {
+	t := p.tuning()
+	status := map[string]any{
+		"max_turns":        t.maxTurns,
+		"top_k":            t.topK,
+		"kb_routing":       t.kbRouting,
+		"rag_feedback":     t.ragFeedback,
+		"read_your_writes": t.readYourWrites,
+		"stream_plans":     t.streamPlans,
+		"rag_injection":    t.ragInjection,
+		"audit":            p.auditDB != nil,
+		"session_keys":     p.auditDB.SessionKeysEnabled(),
+		"notifications":    p.redis != nil,
+		"scratchpad":       p.scratchpad != nil,
+		"saturation":       p.load.saturation(),
+		"turn_budget":      t.turnBudget.String(),
+	}
+	if len(t.personas) > 0 {
+		names := make([]string, 0, len(t.personas))
+		for name := range t.personas {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		status["personas"] = names
+		status["default_persona"] = t.defaultPersona
+	}
+	if t.routingModel != (ModelChoice{}) {
+		status["routing_model"] = t.routingModel
+	}
+	if t.synthesisModel != (ModelChoice{}) {
+		status["synthesis_model"] = t.synthesisModel
+	}
+	if mock := p.mockToolsStatus(); mock != nil {
+		status["mock_tools"] = mock
+	}
+	if anomalies := p.anomalies.status(); anomalies != nil {
+		status["anomalies"] = anomalies
+	}
+	if p.toolsDiscovered() {
+		_, refreshed := p.ToolSource()
+		status["tools"] = map[string]any{"source": tools.SourceSandbox, "count": len(p.Tools()), "refreshed_at": refreshed.UTC().Format(time.RFC3339)}
+	}
+	if budget := p.toolBudget.status(); budget != nil {
+		status["tool_budget"] = budget
+	}
+	if t.prompts != nil {
+		versions := t.prompts.names()
+		sort.Strings(versions)
+		prompts := map[string]any{"version": t.prompts.current, "versions": versions}
+		if t.prompts.candidate != "" {
+			prompts["candidate"] = t.prompts.candidate
+			prompts["candidate_percent"] = t.prompts.candidatePercent
+		}
+		status["prompts"] = prompts
+	}
+	if t.evaluation != "" && t.evaluation != EvaluationOff {
+		eval := p.evalStats.snapshot()
+		eval["mode"] = t.evaluation
+		eval["sample_rate"] = t.evalSampleRate
+		status["evaluation"] = eval
+	}
+	if probe := p.ProbeStatus(); probe != nil {
+		status["canary"] = probe
+	}
+	if p.modelConn != nil {
+		status["grpc_connections"] = map[string][]string{
+			"model_gateway":  p.modelConn.States(),
+			"memory_service": p.memoryConn.States(),
+			"rust_sandbox":   p.rustConn.States(),
+		}
+	}
+	if p.load != nil {
+		status["loops_running"] = p.load.running.Load()
+		status["loops_pending"] = p.load.pending.Load()
+	}
+	return status
+}
@@ -0,0 +1,188 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"backend-go-model-gateway/pkg/chaos"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// scratchpadRenderChars caps how much of each entry goes into the planner
+// prompt; tool outputs are kept whole for reuse.
+const scratchpadRenderChars = 500
+
+// scratchpadEntry is one line of a session's working memory: a tool call and
+// its output, or a fact the model asked to keep.
+type scratchpadEntry struct {
+	Kind string         `json:"kind"` // "tool" or "fact"
+	Tool string         `json:"tool,omitempty"`
+	Args map[string]any `json:"args,omitempty"`
+	Text string         `json:"text"`
+	At   time.Time      `json:"at"`
+}
+
+// scratchpad is short-term working memory in Redis, one capped list per
+// session that expires after TTL of inactivity. Unlike the Memory Service it
+// is never durable; it saves the model from re-running the same lookups within
+// a session. A nil *scratchpad stores nothing.
+type scratchpad struct {
+	rdb        *redis.Client
+	chaos      *chaos.Injector
+	ttl        time.Duration
+	maxEntries int
+	// reuseTools are the tools whose repeated calls are answered from the
+	// scratchpad instead of the sandbox (side-effect free tools only).
+	reuseTools []string
+}
+
+func newScratchpad(rdb *redis.Client, injector *chaos.Injector, cfg Config) *scratchpad {
+	if rdb == nil || cfg.ScratchpadTTL <= 0 || cfg.ScratchpadMaxEntries <= 0 {
+		return nil
+	}
+	return &scratchpad{rdb: rdb, chaos: injector, ttl: cfg.ScratchpadTTL, maxEntries: cfg.ScratchpadMaxEntries, reuseTools: cfg.ScratchpadReuseTools}
+}
+
+func scratchpadKey(sessionID string) string {
+	return "pagi:scratchpad:" + sessionID
+}
+
+// read returns the session's entries, oldest first.
+func (s *scratchpad) read(ctx context.Context, sessionID string) ([]scratchpadEntry, error) {
+	if s == nil {
+		return nil, nil
+	}
+	if err := s.chaos.Inject(ctx, chaos.Redis); err != nil {
+		return nil, err
+	}
+	raw, err := s.rdb.LRange(ctx, scratchpadKey(sessionID), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]scratchpadEntry, 0, len(raw))
+	for _, r := range raw {
+		var e scratchpadEntry
+		if json.Unmarshal([]byte(r), &e) == nil {
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+// add appends entries, drops the oldest beyond maxEntries and restarts the
+// session's TTL.
+func (s *scratchpad) add(ctx context.Context, sessionID string, entries ...scratchpadEntry) error {
+	if s == nil || len(entries) == 0 {
+		return nil
+	}
+	if err := s.chaos.Inject(ctx, chaos.Redis); err != nil {
+		return err
+	}
+	values := make([]any, 0, len(entries))
+	for _, e := range entries {
+		b, _ := json.Marshal(e)
+		values = append(values, string(b))
+	}
+	key := scratchpadKey(sessionID)
+	_, err := s.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.RPush(ctx, key, values...)
+		pipe.LTrim(ctx, key, int64(-s.maxEntries), -1)
+		pipe.Expire(ctx, key, s.ttl)
+		return nil
+	})
+	return err
+}
+
+// clear drops the session's entries and reports whether it had any.
+func (s *scratchpad) clear(ctx context.Context, sessionID string) (bool, error) {
+	if s == nil {
+		return false, nil
+	}
+	n, err := s.rdb.Del(ctx, scratchpadKey(sessionID)).Result()
+	return n > 0, err
+}
+
+// reuse returns the output of an earlier identical call to a reusable tool,
+// newest first.
+func (s *scratchpad) reuse(entries []scratchpadEntry, call *ToolCall) (string, bool) {
+	if s == nil || !slices.Contains(s.reuseTools, call.Name) {
+		return "", false
+	}
+	want := argsKey(call.Args)
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		if e.Kind == "tool" && e.Tool == call.Name && argsKey(e.Args) == want {
+			return e.Text, true
+		}
+	}
+	return "", false
+}
+
+// argsKey compares tool args; encoding/json sorts map keys, so equal args
+// marshal equally.
+func argsKey(args map[string]any) string {
+	if len(args) == 0 {
+		return "{}"
+	}
+	b, _ := json.Marshal(args)
+	return string(b)
+}
+
+// notesBefore returns the entries written before t.
+func notesBefore(entries []scratchpadEntry, t time.Time) []scratchpadEntry {
+	var out []scratchpadEntry
+	for _, e := range entries {
+		if e.At.Before(t) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// planFacts returns the facts a plan asked to keep: a "scratchpad" string or
+// list of strings next to its steps or tool call.
+func planFacts(plan string) []string {
+	var raw struct {
+		Scratchpad json.RawMessage `json:"scratchpad"`
+	}
+	if json.Unmarshal([]byte(plan), &raw) != nil || len(raw.Scratchpad) == 0 {
+		return nil
+	}
+	var facts []string
+	if json.Unmarshal(raw.Scratchpad, &facts) != nil {
+		var fact string
+		if json.Unmarshal(raw.Scratchpad, &fact) != nil {
+			return nil
+		}
+		facts = []string{fact}
+	}
+	out := facts[:0]
+	for _, f := range facts {
+		if f = strings.TrimSpace(f); f != "" {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// renderScratchpad formats entries for the planner prompt.
+func renderScratchpad(entries []scratchpadEntry) string {
+	var b strings.Builder
+	for _, e := range entries {
+		text := e.Text
+		if r := []rune(text); len(r) > scratchpadRenderChars {
+			text = string(r[:scratchpadRenderChars]) + "..."
+		}
+		if e.Kind == "tool" {
+			fmt.Fprintf(&b, "- %s %s: %s\n", e.Tool, argsKey(e.Args), text)
+			continue
+		}
+		b.WriteString("- " + text + "\n")
+	}
+	return b.String()
+}
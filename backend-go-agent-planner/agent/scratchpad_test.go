@@ -0,0 +1,58 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPlanFacts(t *testing.T) {
+	for plan, want := range map[string][]string{
+		`{"steps":["a"],"scratchpad":["Sam runs on Tuesdays", " "]}`: {"Sam runs on Tuesdays"},
+		`{"steps":["a"],"scratchpad":"one fact"}`:                    {"one fact"},
+		`{"steps":["a"]}`:   nil,
+		`not json`:          nil,
+		`{"scratchpad":42}`: nil,
+	} {
+		if got := planFacts(plan); strings.Join(got, "|") != strings.Join(want, "|") {
+			t.Errorf("planFacts(%s) = %q, want %q", plan, got, want)
+		}
+	}
+}
+
+func TestScratchpad_Reuse(t *testing.T) {
+	s := &scratchpad{reuseTools: []string{"web_search"}}
+	entries := []scratchpadEntry{
+		{Kind: "tool", Tool: "web_search", Args: map[string]any{"query": "go", "n": 3.0}, Text: "old"},
+		{Kind: "fact", Text: "unrelated"},
+		{Kind: "tool", Tool: "web_search", Args: map[string]any{"n": 3.0, "query": "go"}, Text: "new"},
+		{Kind: "tool", Tool: "send_email", Text: "sent"},
+	}
+	if out, ok := s.reuse(entries, &ToolCall{Name: "web_search", Args: map[string]any{"query": "go", "n": 3.0}}); !ok || out != "new" {
+		t.Fatalf("reuse = %q, %v; want the newest output", out, ok)
+	}
+	if _, ok := s.reuse(entries, &ToolCall{Name: "web_search", Args: map[string]any{"query": "rust"}}); ok {
+		t.Fatal("different args reused")
+	}
+	// Only listed tools are reused: send_email has side effects.
+	if _, ok := s.reuse(entries, &ToolCall{Name: "send_email"}); ok {
+		t.Fatal("send_email reused")
+	}
+	if _, ok := (*scratchpad)(nil).reuse(entries, &ToolCall{Name: "web_search"}); ok {
+		t.Fatal("nil scratchpad reused")
+	}
+}
+
+func TestRenderScratchpad(t *testing.T) {
+	start := time.Now()
+	entries := []scratchpadEntry{
+		{Kind: "tool", Tool: "web_search", Args: map[string]any{"query": "go"}, Text: strings.Repeat("x", scratchpadRenderChars+10), At: start.Add(-time.Minute)},
+		{Kind: "fact", Text: "Sam prefers mornings", At: start.Add(-time.Second)},
+		{Kind: "fact", Text: "written this run", At: start.Add(time.Second)},
+	}
+	got := renderScratchpad(notesBefore(entries, start))
+	want := `- web_search {"query":"go"}: ` + strings.Repeat("x", scratchpadRenderChars) + "...\n- Sam prefers mornings\n"
+	if got != want {
+		t.Fatalf("render =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,206 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"backend-go-agent-planner/audit"
+)
+
+// SessionArchiveVersion is the archive format ExportSession writes and
+// ImportSession accepts.
+const SessionArchiveVersion = 1
+
+var (
+	// ErrArchiveInvalid is returned by ImportSession for an archive it cannot
+	// import (wrong version, no session ID).
+	ErrArchiveInvalid = errors.New("invalid session archive")
+	// ErrSessionExists is returned by ImportSession when the target session
+	// already has history or audit rows; imports never merge into a session.
+	ErrSessionExists = errors.New("session already exists")
+	// ErrSessionMemory is returned when the Memory Service cannot read or
+	// store the session's history.
+	ErrSessionMemory = errors.New("memory service request failed")
+)
+
+// SessionArchive is a portable copy of one session: its Memory Service
+// history, the playbooks its runs stored in Mind-KB, its audit trail and its
+// tags.
+type SessionArchive struct {
+	Version    int                `json:"version"`
+	SessionID  string             `json:"session_id"`
+	ExportedAt time.Time          `json:"exported_at"`
+	History    []map[string]any   `json:"history"`
+	Playbooks  []ArchivedPlaybook `json:"playbooks"`
+	Audit      []audit.Entry      `json:"audit"`
+	Tags       []string           `json:"tags,omitempty"`
+}
+
+// ArchivedPlaybook is a playbook as posted to POST /memory/playbook.
+type ArchivedPlaybook struct {
+	Prompt          string              `json:"prompt"`
+	HistorySequence []map[string]string `json:"history_sequence"`
+}
+
+// SessionImport summarizes what ImportSession wrote.
+type SessionImport struct {
+	SessionID string `json:"session_id"`
+	From      string `json:"from"`
+	History   int    `json:"history"`
+	Playbooks int    `json:"playbooks"`
+	AuditRows int    `json:"audit_rows"`
+}
+
+// ExportSession collects sessionID's state. Playbooks come from the
+// PLAYBOOK_STORED audit steps, since the Memory Service cannot list them.
+func (p *Planner) ExportSession(ctx context.Context, sessionID string) (*SessionArchive, error) {
+	if err := p.authorizeSession(ctx, sessionID, false); err != nil {
+		return nil, err
+	}
+	a := &SessionArchive{Version: SessionArchiveVersion, SessionID: sessionID, ExportedAt: time.Now().UTC(), Playbooks: []ArchivedPlaybook{}}
+
+	f := audit.QueryFilter{SessionID: sessionID, Limit: bundlePageSize}
+	for {
+		entries, err := p.auditDB.Query(ctx, f)
+		if err != nil {
+			return nil, err
+		}
+		a.Audit = append(a.Audit, entries...)
+		if len(entries) < bundlePageSize {
+			break
+		}
+		f.AfterID = entries[len(entries)-1].ID
+	}
+	for _, e := range a.Audit {
+		if e.EventType != "PLAYBOOK_STORED" {
+			continue
+		}
+		var pb ArchivedPlaybook
+		if err := json.Unmarshal(e.Data, &pb); err != nil {
+			return nil, fmt.Errorf("audit row %d: %w", e.ID, err)
+		}
+		a.Playbooks = append(a.Playbooks, pb)
+	}
+
+	tags, err := p.auditDB.SessionTags(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if len(tags) > 0 {
+		a.Tags = tags
+	}
+
+	history, err := p.fetchSessionHistory(ctx, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSessionMemory, err)
+	}
+	a.History = history
+	if a.History == nil {
+		a.History = []map[string]any{}
+	}
+	if a.Audit == nil {
+		a.Audit = []audit.Entry{}
+	}
+	return a, nil
+}
+
+// ImportSession recreates an exported session as sessionID (the archive's own
+// ID when empty). History and playbooks go to the Memory Service first and the
+// audit rows are added in one transaction afterwards, followed by a
+// SESSION_IMPORTED step, so a failed import leaves no audit trail behind.
+func (p *Planner) ImportSession(ctx context.Context, a *SessionArchive, sessionID string) (*SessionImport, error) {
+	if p == nil || p.auditDB == nil {
+		return nil, ErrAuditUnavailable
+	}
+	if a.Version != SessionArchiveVersion {
+		return nil, fmt.Errorf("%w: version %d (want %d)", ErrArchiveInvalid, a.Version, SessionArchiveVersion)
+	}
+	if a.SessionID == "" {
+		return nil, fmt.Errorf("%w: session_id is required", ErrArchiveInvalid)
+	}
+	if sessionID == "" {
+		sessionID = a.SessionID
+	}
+	tags, err := normalizeTags(a.Tags)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrArchiveInvalid, err)
+	}
+
+	if err := p.checkSessionOwner(ctx, sessionID); err != nil {
+		return nil, err
+	}
+	existing, err := p.auditDB.Query(ctx, audit.QueryFilter{SessionID: sessionID, Limit: 1})
+	if err != nil {
+		return nil, err
+	}
+	history, err := p.fetchSessionHistory(ctx, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSessionMemory, err)
+	}
+	if len(existing) > 0 || len(history) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrSessionExists, sessionID)
+	}
+	if err := p.authorizeSession(ctx, sessionID, true); err != nil {
+		return nil, err
+	}
+
+	if len(a.History) > 0 {
+		if err := p.storeSessionHistory(ctx, sessionID, a.History); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrSessionMemory, err)
+		}
+	}
+	// Imported playbooks are numbered by their position for their delta IDs.
+	for i, pb := range a.Playbooks {
+		if err := p.storePlaybook(ctx, sessionID, i, pb.Prompt, pb.HistorySequence); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrSessionMemory, err)
+		}
+	}
+	if err := p.auditDB.ImportEntries(ctx, sessionID, a.Audit); err != nil {
+		return nil, err
+	}
+	if len(tags) > 0 {
+		if err := p.auditDB.TagSession(ctx, sessionID, tags); err != nil {
+			return nil, err
+		}
+	}
+
+	res := &SessionImport{SessionID: sessionID, From: a.SessionID, History: len(a.History), Playbooks: len(a.Playbooks), AuditRows: len(a.Audit)}
+	_ = p.RecordStep(ctx, sessionID, "SESSION_IMPORTED", map[string]any{"from": a.SessionID, "exported_at": a.ExportedAt, "history": res.History, "playbooks": res.Playbooks, "audit_rows": res.AuditRows})
+	return res, nil
+}
+
+// storeSessionHistory writes a whole history to the Memory Service in one
+// POST /memory/store.
+func (p *Planner) storeSessionHistory(ctx context.Context, sessionID string, history []map[string]any) error {
+	history, err := p.sealHistory(ctx, sessionID, history)
+	if err != nil {
+		return err
+	}
+	url := strings.TrimRight(p.cfg.MemoryServiceHTTP, "/") + "/memory/store"
+	body := map[string]any{
+		"session_id":   sessionID,
+		"history":      history,
+		"prompt":       "[session-import]",
+		"llm_response": map[string]any{"text": ""},
+	}
+	b, _ := json.Marshal(body)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(b))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		out, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("memory/store: %s", string(out))
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"backend-go-agent-planner/audit"
+)
+
+// ForgetSession deletes everything the stack keeps about sessionID and
+// returns a signed receipt (see audit.DeletionReceipt):
+//
+//   - the Memory Service history and Mind-KB playbooks (DELETE /memory/session)
+//   - the Redis scratchpad
+//   - the session's encryption key, when session keys are on
+//   - its audit rows, notification history and tags
+//
+// The Memory Service goes first: if it fails, nothing is deleted here and the
+// request can be retried. The receipt is also recorded as a SESSION_DELETED
+// step outside the session, so the deletion itself stays auditable.
+func (p *Planner) ForgetSession(ctx context.Context, sessionID string) (*audit.DeletionReceipt, error) {
+	if err := p.authorizeSession(ctx, sessionID, false); err != nil {
+		return nil, err
+	}
+	key, err := p.signingKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	memory, err := p.deleteSessionMemory(ctx, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSessionMemory, err)
+	}
+	receipt := &audit.DeletionReceipt{
+		SessionID:   sessionID,
+		RequestedBy: PrincipalFromContext(ctx),
+		Deleted: map[string]int64{
+			"memory_messages":  memory.Messages,
+			"memory_playbooks": memory.Playbooks,
+		},
+	}
+	cleared, err := p.scratchpad.clear(ctx, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("scratchpad: %w", err)
+	}
+	p.anomalies.forget(sessionID)
+	if cleared {
+		receipt.Deleted["scratchpad"] = 1
+	}
+	if p.auditDB.SessionKeysEnabled() {
+		if receipt.KeyErased, err = p.auditDB.DeleteSessionKey(ctx, sessionID); err != nil {
+			return nil, err
+		}
+	}
+	deleted, err := p.auditDB.DeleteSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	receipt.Deleted["audit_rows"] = deleted.AuditRows
+	receipt.Deleted["notifications"] = deleted.Notifications
+	receipt.Deleted["tags"] = deleted.Tags
+
+	receipt.DeletedAt = time.Now()
+	if err := audit.SignReceipt(receipt, key); err != nil {
+		return nil, err
+	}
+	_ = p.RecordStep(ctx, "", "SESSION_DELETED", receipt)
+	return receipt, nil
+}
+
+// memoryDeletion is the Memory Service's answer to DELETE /memory/session.
+type memoryDeletion struct {
+	Messages  int64 `json:"messages"`
+	Playbooks int64 `json:"playbooks"`
+}
+
+func (p *Planner) deleteSessionMemory(ctx context.Context, sessionID string) (memoryDeletion, error) {
+	var out memoryDeletion
+	u := strings.TrimRight(p.cfg.MemoryServiceHTTP, "/") + "/memory/session?session_id=" + url.QueryEscape(sessionID)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodDelete, u, nil)
+	setPrincipalHeader(req)
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return out, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		return out, fmt.Errorf("memory/session: %s", b)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return out, fmt.Errorf("memory/session: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,107 @@
+package agent
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"backend-go-agent-planner/audit"
+	"backend-go-model-gateway/pkg/secrets"
+)
+
+// ErrSessionKeysDisabled is returned by EraseSessionKey when
+// PAGI_SESSION_MASTER_KEY is not set.
+var ErrSessionKeysDisabled = errors.New("session keys not enabled (PAGI_SESSION_MASTER_KEY unset)")
+
+// enableSessionKeys turns on per-session encryption of stored prompts and
+// answers when PAGI_SESSION_MASTER_KEY (via pkg/secrets; base64, 32 bytes)
+// is set. The session keys live in the audit DB, so it is required then.
+func enableSessionKeys(ctx context.Context, store *secrets.Store, db *audit.AuditDB) error {
+	raw, err := store.Lookup(ctx, "PAGI_SESSION_MASTER_KEY")
+	if err != nil || raw == "" {
+		return err
+	}
+	master, err := base64.StdEncoding.DecodeString(strings.TrimSpace(raw))
+	if err != nil {
+		return fmt.Errorf("PAGI_SESSION_MASTER_KEY: %w", err)
+	}
+	if db == nil {
+		return fmt.Errorf("PAGI_SESSION_MASTER_KEY needs the audit DB (PAGI_AUDIT_DB_PATH), which is unavailable")
+	}
+	if err := db.EnableSessionKeys(master); err != nil {
+		return fmt.Errorf("PAGI_SESSION_MASTER_KEY: %w", err)
+	}
+	return nil
+}
+
+// sealHistory returns history with each message's content sealed with the
+// session key; it is history itself when session keys are off.
+func (p *Planner) sealHistory(ctx context.Context, sessionID string, history []map[string]any) ([]map[string]any, error) {
+	if !p.auditDB.SessionKeysEnabled() {
+		return history, nil
+	}
+	out := make([]map[string]any, len(history))
+	for i, msg := range history {
+		sealed := make(map[string]any, len(msg))
+		for k, v := range msg {
+			sealed[k] = v
+		}
+		if content, ok := msg["content"].(string); ok {
+			var err error
+			if sealed["content"], err = p.auditDB.Seal(ctx, sessionID, content); err != nil {
+				return nil, err
+			}
+		}
+		out[i] = sealed
+	}
+	return out, nil
+}
+
+// openHistory opens the sealed contents of messages read back from the
+// Memory Service, in place. Messages whose session key was erased keep an
+// empty content and are marked "erased".
+func (p *Planner) openHistory(ctx context.Context, sessionID string, messages []map[string]any) error {
+	for _, msg := range messages {
+		content, ok := msg["content"].(string)
+		if !ok || !audit.IsSealed(content) {
+			continue
+		}
+		opened, err := p.auditDB.Open(ctx, sessionID, content)
+		switch {
+		case errors.Is(err, audit.ErrSessionKeyErased):
+			msg["content"], msg["erased"] = "", true
+		case err != nil:
+			return err
+		default:
+			msg["content"] = opened
+		}
+	}
+	return nil
+}
+
+// EraseSessionKey deletes sessionID's key: its sealed audit rows,
+// notifications and memory deltas can no longer be read, wherever they are
+// kept (cryptographic erasure). It reports whether the session had a key.
+func (p *Planner) EraseSessionKey(ctx context.Context, sessionID string) (bool, error) {
+	if p == nil || p.auditDB == nil {
+		return false, ErrAuditUnavailable
+	}
+	if !p.auditDB.SessionKeysEnabled() {
+		return false, ErrSessionKeysDisabled
+	}
+	if err := p.authorizeSession(ctx, sessionID, false); err != nil {
+		return false, err
+	}
+	erased, err := p.auditDB.DeleteSessionKey(ctx, sessionID)
+	if err != nil {
+		return false, err
+	}
+	if erased {
+		// Recorded outside the session: a row under it would be sealed with
+		// a new key.
+		_ = p.RecordStep(ctx, "", "SESSION_KEY_ERASED", map[string]any{"session_id": sessionID})
+	}
+	return erased, nil
+}
@@ -0,0 +1,99 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+)
+
+// ErrSessionNotFound is returned when the caller's tenant does not own the
+// session. It is the same answer as for a session that does not exist, so a
+// tenant cannot learn another's session IDs.
+var ErrSessionNotFound = errors.New("session not found")
+
+// authorizeSession checks that the context's tenant owns sessionID. With
+// claim set, a session nobody owns yet becomes the tenant's; the default key
+// ("" tenant) claims sessions too, so tenants cannot take them over later,
+// but is never refused.
+func (p *Planner) authorizeSession(ctx context.Context, sessionID string, claim bool) error {
+	if p == nil || p.auditDB == nil {
+		return ErrAuditUnavailable
+	}
+	tenant := TenantFromContext(ctx)
+	var owner string
+	var err error
+	if claim {
+		owner, err = p.auditDB.ClaimSession(ctx, sessionID, tenant)
+	} else {
+		owner, _, err = p.auditDB.SessionOwner(ctx, sessionID)
+	}
+	if err != nil {
+		return err
+	}
+	if tenant != "" && owner != tenant {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// checkSessionOwner is authorizeSession for a session about to be created:
+// one nobody owns yet is allowed and left unclaimed.
+func (p *Planner) checkSessionOwner(ctx context.Context, sessionID string) error {
+	if p == nil || p.auditDB == nil {
+		return ErrAuditUnavailable
+	}
+	tenant := TenantFromContext(ctx)
+	owner, ok, err := p.auditDB.SessionOwner(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	if tenant != "" && ok && owner != tenant {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// NotificationFilter returns which payloads of the notifications channel the
+// context's caller may see: those of sessionID when set, which the tenant
+// must own, and otherwise, for a tenant, those of its own sessions. The
+// default key sees every session. The filter is not safe for concurrent use.
+func (p *Planner) NotificationFilter(ctx context.Context, sessionID string) (func(payload string) bool, error) {
+	tenant := TenantFromContext(ctx)
+	if sessionID != "" && tenant != "" {
+		if err := p.authorizeSession(ctx, sessionID, false); err != nil {
+			return nil, err
+		}
+	}
+	// visible caches ownership decisions; an owner never changes, but an
+	// unowned session may still become the tenant's, so those are rechecked.
+	visible := map[string]bool{}
+	return func(payload string) bool {
+		var msg struct {
+			SessionID string `json:"session_id"`
+		}
+		if err := json.Unmarshal([]byte(payload), &msg); err != nil {
+			return false
+		}
+		if sessionID != "" {
+			return msg.SessionID == sessionID
+		}
+		if tenant == "" {
+			return true
+		}
+		if ok, cached := visible[msg.SessionID]; cached {
+			return ok
+		}
+		if p == nil || p.auditDB == nil {
+			return false
+		}
+		owner, owned, err := p.auditDB.SessionOwner(ctx, msg.SessionID)
+		if err != nil || !owned {
+			return false
+		}
+		if len(visible) >= 4096 {
+			clear(visible)
+		}
+		visible[msg.SessionID] = owner == tenant
+		return owner == tenant
+	}, nil
+}
@@ -0,0 +1,141 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"backend-go-agent-planner/audit"
+)
+
+func TestSessionOwnership(t *testing.T) {
+	db, err := audit.NewAuditDB(filepath.Join(t.TempDir(), "audit.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	p := &Planner{auditDB: db}
+	acme := ContextWithTenant(context.Background(), "acme")
+	globex := ContextWithTenant(context.Background(), "globex")
+	admin := context.Background()
+
+	// Tagging before the first run claims the session.
+	if _, err := p.TagSession(acme, "s1", []string{"health"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.TagSession(globex, "s2", []string{"health"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, call := range map[string]func() error{
+		"run": func() error {
+			_, err := p.AgentLoop(globex, "hi", "s1", nil, nil)
+			return err
+		},
+		"tag":   func() error { _, err := p.TagSession(globex, "s1", []string{"x"}); return err },
+		"untag": func() error { _, err := p.UntagSession(globex, "s1", "health"); return err },
+		"tags":  func() error { _, err := p.SessionTags(globex, "s1"); return err },
+		"export": func() error {
+			_, err := p.ExportSession(globex, "s1")
+			return err
+		},
+		"import": func() error {
+			_, err := p.ImportSession(globex, &SessionArchive{Version: SessionArchiveVersion, SessionID: "elsewhere"}, "s1")
+			return err
+		},
+		"forget": func() error { _, err := p.ForgetSession(globex, "s1"); return err },
+		"audit": func() error {
+			_, err := p.QueryAudit(globex, audit.QueryFilter{SessionID: "s1"})
+			return err
+		},
+		"unknown session": func() error { _, err := p.SessionTags(globex, "s9"); return err },
+	} {
+		if err := call(); !errors.Is(err, ErrSessionNotFound) {
+			t.Errorf("%s: %v, want ErrSessionNotFound", name, err)
+		}
+	}
+
+	// Listings leave out other tenants' sessions.
+	entries, err := p.QueryAudit(globex, audit.QueryFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if e.SessionID != "s2" {
+			t.Fatalf("globex saw %+v", e)
+		}
+	}
+	if sessions, err := p.SearchSessions(globex, audit.SessionFilter{Tags: []string{"health"}}); err != nil || len(sessions) != 1 || sessions[0].SessionID != "s2" {
+		t.Fatalf("globex sessions = %+v, %v", sessions, err)
+	}
+
+	// The default key sees everything; its sessions are not up for grabs.
+	if tags, err := p.SessionTags(admin, "s1"); err != nil || len(tags) != 1 {
+		t.Fatalf("admin tags = %v, %v", tags, err)
+	}
+	if sessions, err := p.SearchSessions(admin, audit.SessionFilter{Tags: []string{"health"}}); err != nil || len(sessions) != 2 {
+		t.Fatalf("admin sessions = %+v, %v", sessions, err)
+	}
+	if _, err := p.TagSession(admin, "s3", []string{"ops"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.SessionTags(acme, "s3"); !errors.Is(err, ErrSessionNotFound) {
+		t.Fatalf("acme on the default key's session: %v", err)
+	}
+}
+
+func TestNotificationFilter(t *testing.T) {
+	db, err := audit.NewAuditDB(filepath.Join(t.TempDir(), "audit.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	p := &Planner{auditDB: db}
+	acme := ContextWithTenant(context.Background(), "acme")
+	globex := ContextWithTenant(context.Background(), "globex")
+	admin := context.Background()
+	for session, ctx := range map[string]context.Context{"s1": acme, "s2": globex, "s3": admin} {
+		if err := p.authorizeSession(ctx, session, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	payload := func(session string) string { return `{"session_id":"` + session + `","result":"answer"}` }
+
+	for _, tc := range []struct {
+		name    string
+		ctx     context.Context
+		session string
+		want    []string
+	}{
+		{"tenant sees its own sessions", acme, "", []string{"s1"}},
+		{"other tenant", globex, "", []string{"s2"}},
+		{"default key sees everything", admin, "", []string{"s1", "s2", "s3", "s4"}},
+		{"session_id narrows", acme, "s1", []string{"s1"}},
+		{"default key with session_id", admin, "s2", []string{"s2"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			visible, err := p.NotificationFilter(tc.ctx, tc.session)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, s := range []string{"s1", "s2", "s3", "s4"} {
+				if visible(payload(s)) {
+					got = append(got, s)
+				}
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Fatalf("visible = %v, want %v", got, tc.want)
+			}
+			if visible("not json") {
+				t.Fatal("a malformed payload must not be relayed")
+			}
+		})
+	}
+
+	if _, err := p.NotificationFilter(acme, "s2"); !errors.Is(err, ErrSessionNotFound) {
+		t.Fatalf("acme subscribing to globex's session: %v, want ErrSessionNotFound", err)
+	}
+}
@@ -0,0 +1,99 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"backend-go-agent-planner/audit"
+)
+
+// ErrInvalidTags is returned for session tags that are malformed or too many.
+var ErrInvalidTags = errors.New("invalid session tags")
+
+// maxTagsPerRequest bounds the tags one /plan or tag request may set.
+const maxTagsPerRequest = 20
+
+// sessionTagPattern matches a normalized tag: lowercase, no commas or spaces,
+// e.g. "health", "project:offsite", "flag.playbook_reuse".
+var sessionTagPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.:-]{0,63}$`)
+
+// normalizeTags lowercases, trims and deduplicates tags, keeping their order.
+func normalizeTags(tags []string) ([]string, error) {
+	if len(tags) > maxTagsPerRequest {
+		return nil, fmt.Errorf("%w: %d tags (max %d)", ErrInvalidTags, len(tags), maxTagsPerRequest)
+	}
+	out := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if !sessionTagPattern.MatchString(tag) {
+			return nil, fmt.Errorf("%w: %q (want letters, digits, '_', '.', ':' or '-', up to 64)", ErrInvalidTags, tag)
+		}
+		if !slices.Contains(out, tag) {
+			out = append(out, tag)
+		}
+	}
+	return out, nil
+}
+
+// TagSession adds tags to sessionID and returns all of its tags. Tagging is
+// recorded as a SESSION_TAGGED audit step, so a session tagged before its
+// first run is already found by SearchSessions.
+func (p *Planner) TagSession(ctx context.Context, sessionID string, tags []string) ([]string, error) {
+	tags, err := normalizeTags(tags)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.authorizeSession(ctx, sessionID, true); err != nil {
+		return nil, err
+	}
+	if len(tags) > 0 {
+		if err := p.auditDB.TagSession(ctx, sessionID, tags); err != nil {
+			return nil, err
+		}
+		_ = p.RecordStep(ctx, sessionID, "SESSION_TAGGED", map[string]any{"tags": tags})
+	}
+	return p.auditDB.SessionTags(ctx, sessionID)
+}
+
+// UntagSession removes tag from sessionID and returns its remaining tags.
+func (p *Planner) UntagSession(ctx context.Context, sessionID, tag string) ([]string, error) {
+	tags, err := normalizeTags([]string{tag})
+	if err != nil {
+		return nil, err
+	}
+	if err := p.authorizeSession(ctx, sessionID, false); err != nil {
+		return nil, err
+	}
+	if err := p.auditDB.UntagSession(ctx, sessionID, tags[0]); err != nil {
+		return nil, err
+	}
+	_ = p.RecordStep(ctx, sessionID, "SESSION_UNTAGGED", map[string]any{"tag": tags[0]})
+	return p.auditDB.SessionTags(ctx, sessionID)
+}
+
+// SessionTags returns sessionID's tags.
+func (p *Planner) SessionTags(ctx context.Context, sessionID string) ([]string, error) {
+	if err := p.authorizeSession(ctx, sessionID, false); err != nil {
+		return nil, err
+	}
+	return p.auditDB.SessionTags(ctx, sessionID)
+}
+
+// SearchSessions lists the sessions active in f's window that have all of
+// f's tags. A tenant only finds its own sessions.
+func (p *Planner) SearchSessions(ctx context.Context, f audit.SessionFilter) ([]audit.SessionSummary, error) {
+	tags, err := normalizeTags(f.Tags)
+	if err != nil {
+		return nil, err
+	}
+	f.Tags = tags
+	f.Tenant = TenantFromContext(ctx)
+	if p == nil || p.auditDB == nil {
+		return nil, ErrAuditUnavailable
+	}
+	return p.auditDB.SearchSessions(ctx, f)
+}
@@ -0,0 +1,187 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"backend-go-agent-planner/internal/logger"
+	"backend-go-model-gateway/pkg/chaos"
+
+	"github.com/go-redis/redis/v8"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// ErrToolBudgetExceeded is returned for a budgeted tool call over the
+// session's cap or the replica's hourly budget. AgentLoop feeds it back to
+// the model like a failed call.
+var ErrToolBudgetExceeded = errors.New("tool budget exceeded")
+
+// toolBudget limits calls to tools that reach external web APIs
+// (AGENT_TOOL_BUDGET_TOOLS), so a looping session cannot hammer them:
+//
+//   - each session may make perSession calls per sessionWindow, counted in
+//     Redis so every replica sees the same count (no Redis: no session cap);
+//   - the replica makes at most perHour calls an hour, a token bucket that
+//     refills continuously.
+//
+// The limits are re-read by ReloadConfig; the bucket keeps its tokens. A nil
+// *toolBudget allows every call.
+type toolBudget struct {
+	rdb   *redis.Client
+	chaos *chaos.Injector
+	now   func() time.Time
+
+	mu            sync.Mutex
+	tools         []string
+	perSession    int
+	sessionWindow time.Duration
+	perHour       float64 // bucket size and refill per hour; 0: no global budget
+	tokens        float64
+	refilled      time.Time
+}
+
+func newToolBudget(rdb *redis.Client, injector *chaos.Injector, cfg Config) *toolBudget {
+	b := &toolBudget{rdb: rdb, chaos: injector, now: time.Now}
+	b.configure(cfg)
+	return b
+}
+
+// configure applies cfg's limits. A full bucket stays full when the hourly
+// budget grows; otherwise tokens are kept, up to the new size.
+func (b *toolBudget) configure(cfg Config) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill()
+	wasFull := b.tokens >= b.perHour
+	b.tools = cfg.ToolBudgetTools
+	b.perSession, b.sessionWindow = 0, cfg.ToolBudgetSessionWindow
+	if b.rdb != nil && cfg.ToolBudgetPerSession > 0 && cfg.ToolBudgetSessionWindow > 0 {
+		b.perSession = cfg.ToolBudgetPerSession
+	}
+	b.perHour = float64(max(cfg.ToolBudgetPerHour, 0))
+	if wasFull {
+		b.tokens = b.perHour
+	}
+	b.tokens = min(b.tokens, b.perHour)
+	b.refilled = b.now()
+}
+
+func toolBudgetKey(sessionID string) string {
+	return "pagi:toolbudget:" + sessionID
+}
+
+// spend takes one call of tool from the session's and the replica's budgets.
+// Tools outside the budgeted list are always allowed. A Redis failure leaves
+// the session cap unchecked rather than failing the call.
+func (b *toolBudget) spend(ctx context.Context, sessionID, tool string) error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	budgeted := slices.Contains(b.tools, tool)
+	perSession, window := b.perSession, b.sessionWindow
+	b.mu.Unlock()
+	if !budgeted {
+		return nil
+	}
+	outcome := "allowed"
+	defer func() {
+		if toolBudgetCalls != nil {
+			toolBudgetCalls.Add(ctx, 1, metric.WithAttributes(attribute.String("tool", tool), attribute.String("outcome", outcome)))
+		}
+	}()
+
+	if perSession > 0 {
+		n, err := b.sessionCalls(ctx, sessionID, window)
+		if err != nil {
+			logger.NewContextLogger(ctx).Warn("tool_budget_unavailable", "error", err)
+		} else if n > int64(perSession) {
+			outcome = "session_exceeded"
+			return fmt.Errorf("%w: session %s used its %d calls to budgeted tools", ErrToolBudgetExceeded, sessionID, perSession)
+		}
+	}
+	if perHour, ok := b.take(); !ok {
+		outcome = "global_exceeded"
+		return fmt.Errorf("%w: the hourly budget of %.0f calls to budgeted tools is used up", ErrToolBudgetExceeded, perHour)
+	}
+	return nil
+}
+
+// sessionCalls counts this call against the session and returns the count.
+// The window starts with the session's first budgeted call.
+func (b *toolBudget) sessionCalls(ctx context.Context, sessionID string, window time.Duration) (int64, error) {
+	if err := b.chaos.Inject(ctx, chaos.Redis); err != nil {
+		return 0, err
+	}
+	key := toolBudgetKey(sessionID)
+	n, err := b.rdb.Incr(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+	if n == 1 {
+		err = b.rdb.Expire(ctx, key, window).Err()
+	}
+	return n, err
+}
+
+// take removes a token from the hourly bucket after refilling it for the
+// time since the last call. It returns the bucket size for error messages.
+func (b *toolBudget) take() (perHour float64, ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.perHour <= 0 {
+		return 0, true
+	}
+	b.refill()
+	if b.tokens < 1 {
+		return b.perHour, false
+	}
+	b.tokens--
+	return b.perHour, true
+}
+
+// refill adds the tokens earned since the last refill; b.mu must be held.
+func (b *toolBudget) refill() {
+	now := b.now()
+	if !b.refilled.IsZero() {
+		b.tokens = min(b.perHour, b.tokens+now.Sub(b.refilled).Hours()*b.perHour)
+	}
+	b.refilled = now
+}
+
+// remaining is the calls left in the hourly bucket (-1: no global budget).
+func (b *toolBudget) remaining() float64 {
+	if b == nil {
+		return -1
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.perHour <= 0 {
+		return -1
+	}
+	b.refill()
+	return b.tokens
+}
+
+// status is the tool budget's part of GET /admin/status.
+func (b *toolBudget) status() map[string]any {
+	if b == nil {
+		return nil
+	}
+	remaining := b.remaining()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.tools) == 0 || (b.perSession == 0 && b.perHour == 0) {
+		return nil
+	}
+	out := map[string]any{"tools": b.tools, "per_session": b.perSession}
+	if b.perHour > 0 {
+		out["per_hour"], out["remaining"] = b.perHour, remaining
+	}
+	return out
+}
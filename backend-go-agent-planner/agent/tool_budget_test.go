@@ -0,0 +1,53 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestToolBudget_HourlyBucket(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := &toolBudget{now: func() time.Time { return now }}
+	b.configure(Config{ToolBudgetTools: []string{"web_search"}, ToolBudgetPerHour: 2})
+	ctx := context.Background()
+
+	if err := b.spend(ctx, "s1", "send_email"); err != nil {
+		t.Fatalf("unbudgeted tool: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := b.spend(ctx, "s1", "web_search"); err != nil {
+			t.Fatalf("call %d: %v", i+1, err)
+		}
+	}
+	if err := b.spend(ctx, "s2", "web_search"); !errors.Is(err, ErrToolBudgetExceeded) {
+		t.Fatalf("third call: %v, want ErrToolBudgetExceeded", err)
+	}
+	// Two calls an hour refill one token every 30 minutes.
+	now = now.Add(30 * time.Minute)
+	if got := b.remaining(); got != 1 {
+		t.Fatalf("remaining after 30m = %v, want 1", got)
+	}
+	if err := b.spend(ctx, "s2", "web_search"); err != nil {
+		t.Fatalf("after refill: %v", err)
+	}
+	now = now.Add(10 * time.Hour)
+	if got := b.remaining(); got != 2 {
+		t.Fatalf("remaining after 10h = %v, want the bucket size", got)
+	}
+
+	// Reloading with a smaller budget keeps no more than the new size.
+	b.configure(Config{ToolBudgetTools: []string{"web_search"}, ToolBudgetPerHour: 1})
+	if got := b.remaining(); got != 1 {
+		t.Fatalf("remaining after shrinking = %v, want 1", got)
+	}
+	b.configure(Config{ToolBudgetTools: []string{"web_search"}})
+	if got := b.remaining(); got != -1 || b.status() != nil {
+		t.Fatalf("disabled budget: remaining %v, status %v", got, b.status())
+	}
+	var nilBudget *toolBudget
+	if err := nilBudget.spend(ctx, "s1", "web_search"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,64 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"strings"
+	"time"
+
+	"backend-go-agent-planner/internal/logger"
+
+	"backend-go-model-gateway/pkg/tools"
+)
+
+const defaultToolDiscoveryInterval = time.Minute
+
+// ToolDiscoveryIntervalFromEnv reads AGENT_TOOL_DISCOVERY_INTERVAL, how often
+// the sandbox's ListTools is asked for its tools (default 1m; 0 disables
+// discovery, leaving tools.Builtin).
+func ToolDiscoveryIntervalFromEnv() time.Duration {
+	v := strings.TrimSpace(os.Getenv("AGENT_TOOL_DISCOVERY_INTERVAL"))
+	if v == "" {
+		return defaultToolDiscoveryInterval
+	}
+	if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+		return d
+	}
+	return defaultToolDiscoveryInterval
+}
+
+// Tools returns the tool definitions tool calls are validated against: the
+// sandbox's own list once discovered, tools.Builtin until then.
+func (p *Planner) Tools() []tools.Definition {
+	return p.tools.Definitions()
+}
+
+// ToolSource reports where Tools came from (tools.SourceBuiltin or
+// tools.SourceSandbox) and when the sandbox last listed them.
+func (p *Planner) ToolSource() (string, time.Time) {
+	return p.tools.Source()
+}
+
+func (p *Planner) toolsDiscovered() bool {
+	source, _ := p.ToolSource()
+	return source == tools.SourceSandbox
+}
+
+// RunToolDiscovery refreshes Tools from the sandbox now and every interval
+// until ctx is done. A failed refresh keeps the current tools.
+func (p *Planner) RunToolDiscovery(ctx context.Context, interval time.Duration) {
+	lg := logger.NewContextLogger(ctx)
+	lg.Info("tool_discovery_started", "interval", interval.String())
+	p.tools.Run(ctx, interval, func(changed bool, err error) {
+		switch {
+		case err != nil:
+			lg.Warn("tool_discovery_failed", "error", err.Error())
+		case changed:
+			names := make([]string, 0, len(p.Tools()))
+			for _, d := range p.Tools() {
+				names = append(names, d.Name)
+			}
+			lg.Info("tools_discovered", "tools", strings.Join(names, ","))
+		}
+	})
+}
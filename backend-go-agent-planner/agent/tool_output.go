@@ -0,0 +1,72 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// defaultToolOutputMaxBytes is AGENT_TOOL_OUTPUT_MAX_BYTES' default: about
+// 2k tokens per stream, enough for a search result page.
+const defaultToolOutputMaxBytes = 8192
+
+// ansiEscape matches terminal escape sequences (colors, cursor movement).
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)|\x1b[@-_]`)
+
+// promptDelimiter matches the tags the prompt templates wrap sections in, so
+// a tool cannot close <tool_result> and write its own <user_prompt>.
+var promptDelimiter = regexp.MustCompile(`(?i)<(/?\s*(?:session_history|rag_context|scratchpad|user_prompt|plan|tool_result|available_tools)\b[^>]*)>`)
+
+// sanitizeToolOutput makes a tool stream safe to put in a prompt: invalid
+// UTF-8 is replaced, terminal escapes and control characters other than
+// newline and tab are dropped, prompt delimiters are escaped as &lt;...&gt;,
+// and text over maxBytes keeps its head and tail around a note saying how
+// much was cut. maxBytes <= 0 disables the cap.
+func sanitizeToolOutput(s string, maxBytes int) string {
+	s = strings.ToValidUTF8(s, "\uFFFD")
+	s = ansiEscape.ReplaceAllString(s, "")
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case r == '\n' || r == '\t':
+			return r
+		case unicode.IsControl(r), unicode.Is(unicode.Bidi_Control, r):
+			return -1
+		}
+		return r
+	}, s)
+	s = promptDelimiter.ReplaceAllString(s, "&lt;$1&gt;")
+	return truncateMiddle(s, maxBytes)
+}
+
+// truncateMiddle keeps the first three quarters and the last quarter of
+// maxBytes of s, cut at rune boundaries: the head usually holds the answer,
+// the tail the error or summary a command ends with.
+func truncateMiddle(s string, maxBytes int) string {
+	if maxBytes <= 0 || len(s) <= maxBytes {
+		return s
+	}
+	head := maxBytes * 3 / 4
+	for head > 0 && !utf8.RuneStart(s[head]) {
+		head--
+	}
+	tail := len(s) - (maxBytes - maxBytes*3/4)
+	for tail < len(s) && !utf8.RuneStart(s[tail]) {
+		tail++
+	}
+	return fmt.Sprintf("%s\n[... %d of %d bytes omitted ...]\n%s", s[:head], tail-head, len(s), s[tail:])
+}
+
+// encodeToolOutput is json.Marshal without HTML escaping, so the model reads
+// tool text as written rather than \u003c sequences.
+func encodeToolOutput(v any) string {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(v)
+	return strings.TrimSuffix(b.String(), "\n")
+}
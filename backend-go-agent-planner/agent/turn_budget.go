@@ -0,0 +1,53 @@
+package agent
+
+import (
+	"context"
+	"slices"
+	"time"
+)
+
+// defaultTurnLatencyBudget is AGENT_TURN_LATENCY_BUDGET's default: a turn's
+// retrieval and planning normally take a second or two.
+const defaultTurnLatencyBudget = 10 * time.Second
+
+// Degradation says which optional stages a run skipped after a turn went over
+// its latency budget. The rest of the run queries only the KBs the prompt was
+// routed to: no Mind-KB playbook lookup and no other KBs.
+type Degradation struct {
+	Turn       int      `json:"turn"`
+	ElapsedMS  int64    `json:"elapsed_ms"`
+	BudgetMS   int64    `json:"budget_ms"`
+	SkippedKBs []string `json:"skipped_kbs,omitempty"`
+}
+
+type runReportKey struct{}
+
+// RunReport is what AgentLoop tells its caller besides the answer. POST
+// /plan returns it so clients can tell a degraded answer from a full one.
+type RunReport struct {
+	Degraded *Degradation `json:"degraded,omitempty"`
+}
+
+// WithRunReport returns a context whose AgentLoop run fills in the report.
+func WithRunReport(ctx context.Context) (context.Context, *RunReport) {
+	r := &RunReport{}
+	return context.WithValue(ctx, runReportKey{}, r), r
+}
+
+// reportDegradation records d on the run's report, if any.
+func reportDegradation(ctx context.Context, d *Degradation) {
+	if r, _ := ctx.Value(runReportKey{}).(*RunReport); r != nil {
+		r.Degraded = d
+	}
+}
+
+// droppedKBs lists the KBs in before that after no longer queries.
+func droppedKBs(before, after []kbQuery) []string {
+	var dropped []string
+	for _, q := range before {
+		if !slices.ContainsFunc(after, func(a kbQuery) bool { return a.KB == q.KB }) {
+			dropped = append(dropped, q.KB)
+		}
+	}
+	return dropped
+}
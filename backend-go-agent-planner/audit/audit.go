@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -15,6 +16,9 @@ import (
 // It writes an append-only chronological record of key AgentLoop events to SQLite.
 type AuditDB struct {
 	db *sql.DB
+	// keys seals row data per session (nil: stored in the clear; see
+	// EnableSessionKeys).
+	keys *sessionKeys
 }
 
 const createTableSQL = `
@@ -30,6 +34,34 @@ CREATE TABLE IF NOT EXISTS audit_log (
 CREATE INDEX IF NOT EXISTS idx_audit_log_trace_id ON audit_log(trace_id);
 CREATE INDEX IF NOT EXISTS idx_audit_log_session_id ON audit_log(session_id);
 CREATE INDEX IF NOT EXISTS idx_audit_log_timestamp ON audit_log(timestamp);
+
+CREATE TABLE IF NOT EXISTS notification_log (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	trace_id TEXT,
+	session_id TEXT,
+	timestamp DATETIME NOT NULL,
+	payload TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_notification_log_session_id ON notification_log(session_id);
+CREATE INDEX IF NOT EXISTS idx_notification_log_timestamp ON notification_log(timestamp);
+
+CREATE TABLE IF NOT EXISTS session_tags (
+	session_id TEXT NOT NULL,
+	tag TEXT NOT NULL,
+	created_at DATETIME NOT NULL,
+	PRIMARY KEY (session_id, tag)
+);
+
+CREATE INDEX IF NOT EXISTS idx_session_tags_tag ON session_tags(tag);
+
+CREATE TABLE IF NOT EXISTS session_owners (
+	session_id TEXT PRIMARY KEY,
+	tenant TEXT NOT NULL,
+	created_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_session_owners_tenant ON session_owners(tenant);
 `
 
 // NewAuditDB opens/creates the SQLite database at dbPath and ensures the schema exists.
@@ -52,14 +84,52 @@ func NewAuditDB(dbPath string) (*AuditDB, error) {
 		return nil, fmt.Errorf("ping sqlite: %w", err)
 	}
 
+	var hadOwners int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'session_owners'`).Scan(&hadOwners); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("inspect schema: %w", err)
+	}
 	if _, err := db.Exec(createTableSQL); err != nil {
 		_ = db.Close()
 		return nil, fmt.Errorf("create schema: %w", err)
 	}
+	if err := addColumn(db, "audit_log", "principal", "TEXT"); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("migrate schema: %w", err)
+	}
+	if hadOwners == 0 {
+		if err := backfillSessionOwners(db); err != nil {
+			_ = db.Close()
+			return nil, fmt.Errorf("migrate schema: %w", err)
+		}
+	}
 
 	return &AuditDB{db: db}, nil
 }
 
+// addColumn adds a column that databases created by older versions lack.
+func addColumn(db *sql.DB, table, column, decl string) error {
+	rows, err := db.Query(`SELECT name FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	_, err = db.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, table, column, decl))
+	return err
+}
+
 func (a *AuditDB) Close() error {
 	if a == nil || a.db == nil {
 		return nil
@@ -74,6 +144,12 @@ func (a *AuditDB) Close() error {
 // - eventType: e.g. PLAN_START, TOOL_CALL, PLAN_END
 // - data: JSON-encoded payload (best-effort)
 func (a *AuditDB) RecordStep(ctx context.Context, traceID, sessionID, eventType string, data any) error {
+	return a.RecordStepAs(ctx, traceID, sessionID, "", eventType, data)
+}
+
+// RecordStepAs is RecordStep for a step taken on behalf of principal, the
+// authenticated caller (e.g. "api_key:acme"; "" when unauthenticated).
+func (a *AuditDB) RecordStepAs(ctx context.Context, traceID, sessionID, principal, eventType string, data any) error {
 	if a == nil || a.db == nil {
 		return nil
 	}
@@ -87,13 +163,18 @@ func (a *AuditDB) RecordStep(ctx context.Context, traceID, sessionID, eventType
 			payload = string(b)
 		}
 	}
+	payload, err := a.sealJSON(ctx, sessionID, payload)
+	if err != nil {
+		return fmt.Errorf("insert audit_log: %w", err)
+	}
 
-	_, err := a.db.ExecContext(
+	_, err = a.db.ExecContext(
 		ctx,
-		`INSERT INTO audit_log (trace_id, session_id, timestamp, event_type, data)
-		 VALUES (?, ?, ?, ?, ?)`,
+		`INSERT INTO audit_log (trace_id, session_id, principal, timestamp, event_type, data)
+		 VALUES (?, ?, ?, ?, ?, ?)`,
 		traceID,
 		sessionID,
+		nullIfEmpty(principal),
 		time.Now().UTC(),
 		eventType,
 		payload,
@@ -104,3 +185,277 @@ func (a *AuditDB) RecordStep(ctx context.Context, traceID, sessionID, eventType
 
 	return nil
 }
+
+// ImportEntries appends rows exported from another audit log under
+// sessionID, keeping their trace IDs, principals, timestamps, event types and
+// data. Rows get new IDs. Either every row is inserted or none is.
+func (a *AuditDB) ImportEntries(ctx context.Context, sessionID string, entries []Entry) error {
+	if a == nil || a.db == nil {
+		return fmt.Errorf("audit db not initialized")
+	}
+	// Seal first: the session key lookup needs the connection the
+	// transaction would hold.
+	data := make([]string, len(entries))
+	for i, e := range entries {
+		sealed, err := a.sealJSON(ctx, sessionID, string(e.Data))
+		if err != nil {
+			return fmt.Errorf("import audit_log: %w", err)
+		}
+		data[i] = sealed
+	}
+	tx, err := a.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("import audit_log: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	for i, e := range entries {
+		if _, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO audit_log (trace_id, session_id, principal, timestamp, event_type, data)
+			 VALUES (?, ?, ?, ?, ?, ?)`,
+			e.TraceID,
+			sessionID,
+			nullIfEmpty(e.Principal),
+			e.Timestamp.UTC(),
+			e.EventType,
+			data[i],
+		); err != nil {
+			return fmt.Errorf("import audit_log: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("import audit_log: %w", err)
+	}
+	return nil
+}
+
+// Entry is a single audit_log row as returned by Query.
+type Entry struct {
+	ID        int64  `json:"id"`
+	TraceID   string `json:"trace_id"`
+	SessionID string `json:"session_id"`
+	// Principal is the authenticated caller the step was taken for.
+	Principal string          `json:"principal,omitempty"`
+	Timestamp time.Time       `json:"timestamp"`
+	EventType string          `json:"event_type"`
+	Data      json.RawMessage `json:"data,omitempty"`
+	// Erased is set, and Data left out, when the row was sealed with a
+	// session key that has since been deleted.
+	Erased bool `json:"erased,omitempty"`
+}
+
+// QueryFilter narrows an audit query. Zero-valued fields are ignored.
+type QueryFilter struct {
+	SessionID string
+	TraceID   string
+	EventType string
+	// Principal only applies to audit_log.
+	Principal string
+	Since     time.Time
+	Until     time.Time
+	// AfterID skips rows up to and including this ID, for paging.
+	AfterID int64
+	// Limit caps the number of rows (default 100, max 1000).
+	Limit int
+	// Tenant keeps the rows of sessions owned by this tenant (see
+	// ClaimSession).
+	Tenant string
+}
+
+// clauses returns the WHERE clause and arguments for f, followed by the limit.
+// EventType and Principal only apply to audit_log.
+func (f QueryFilter) clauses(auditLog bool) (string, []any) {
+	limit := f.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	if limit > 1000 {
+		limit = 1000
+	}
+
+	where := []string{"1=1"}
+	args := []any{}
+	if f.SessionID != "" {
+		where = append(where, "session_id = ?")
+		args = append(args, f.SessionID)
+	}
+	if f.TraceID != "" {
+		where = append(where, "trace_id = ?")
+		args = append(args, f.TraceID)
+	}
+	if auditLog && f.EventType != "" {
+		where = append(where, "event_type = ?")
+		args = append(args, f.EventType)
+	}
+	if auditLog && f.Principal != "" {
+		where = append(where, "principal = ?")
+		args = append(args, f.Principal)
+	}
+	if !f.Since.IsZero() {
+		where = append(where, "timestamp >= ?")
+		args = append(args, f.Since.UTC())
+	}
+	if !f.Until.IsZero() {
+		where = append(where, "timestamp < ?")
+		args = append(args, f.Until.UTC())
+	}
+	if f.AfterID > 0 {
+		where = append(where, "id > ?")
+		args = append(args, f.AfterID)
+	}
+	if f.Tenant != "" {
+		where = append(where, ownedByTenantSQL)
+		args = append(args, f.Tenant)
+	}
+	return strings.Join(where, " AND "), append(args, limit)
+}
+
+// Query returns audit rows matching the filter in chronological order.
+func (a *AuditDB) Query(ctx context.Context, f QueryFilter) ([]Entry, error) {
+	if a == nil || a.db == nil {
+		return nil, fmt.Errorf("audit db not initialized")
+	}
+
+	where, args := f.clauses(true)
+	rows, err := a.db.QueryContext(
+		ctx,
+		`SELECT id, trace_id, session_id, principal, timestamp, event_type, data
+		 FROM audit_log
+		 WHERE `+where+`
+		 ORDER BY id
+		 LIMIT ?`,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query audit_log: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []Entry{}
+	stored := []string{}
+	for rows.Next() {
+		var e Entry
+		var traceID, sessionID, principal, data sql.NullString
+		if err := rows.Scan(&e.ID, &traceID, &sessionID, &principal, &e.Timestamp, &e.EventType, &data); err != nil {
+			return nil, fmt.Errorf("scan audit_log: %w", err)
+		}
+		e.TraceID = traceID.String
+		e.SessionID = sessionID.String
+		e.Principal = principal.String
+		entries = append(entries, e)
+		stored = append(stored, data.String)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	// Open sealed data once the rows are closed: session key lookups need
+	// the connection.
+	rows.Close()
+	for i := range entries {
+		payload, erased, err := a.openJSON(ctx, entries[i].SessionID, stored[i])
+		if err != nil {
+			return nil, fmt.Errorf("open audit_log %d: %w", entries[i].ID, err)
+		}
+		entries[i].Erased = erased
+		if payload != "" {
+			entries[i].Data = json.RawMessage(payload)
+		}
+	}
+	return entries, nil
+}
+
+// Notification is a notification the planner published, as kept in
+// notification_log.
+type Notification struct {
+	ID        int64           `json:"id"`
+	TraceID   string          `json:"trace_id"`
+	SessionID string          `json:"session_id"`
+	Timestamp time.Time       `json:"timestamp"`
+	Payload   json.RawMessage `json:"payload"`
+	// Erased is set, and Payload null, when the payload was sealed with a
+	// session key that has since been deleted.
+	Erased bool `json:"erased,omitempty"`
+}
+
+// RecordNotification keeps a copy of a published notification payload (JSON),
+// since Redis pub/sub keeps no history.
+func (a *AuditDB) RecordNotification(ctx context.Context, traceID, sessionID, payload string) error {
+	if a == nil || a.db == nil {
+		return nil
+	}
+	payload, err := a.sealJSON(ctx, sessionID, payload)
+	if err != nil {
+		return fmt.Errorf("insert notification_log: %w", err)
+	}
+	_, err = a.db.ExecContext(
+		ctx,
+		`INSERT INTO notification_log (trace_id, session_id, timestamp, payload)
+		 VALUES (?, ?, ?, ?)`,
+		traceID,
+		sessionID,
+		time.Now().UTC(),
+		payload,
+	)
+	if err != nil {
+		return fmt.Errorf("insert notification_log: %w", err)
+	}
+	return nil
+}
+
+// QueryNotifications returns recorded notifications matching the filter in
+// chronological order. EventType is ignored.
+func (a *AuditDB) QueryNotifications(ctx context.Context, f QueryFilter) ([]Notification, error) {
+	if a == nil || a.db == nil {
+		return nil, fmt.Errorf("audit db not initialized")
+	}
+
+	where, args := f.clauses(false)
+	rows, err := a.db.QueryContext(
+		ctx,
+		`SELECT id, trace_id, session_id, timestamp, payload
+		 FROM notification_log
+		 WHERE `+where+`
+		 ORDER BY id
+		 LIMIT ?`,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query notification_log: %w", err)
+	}
+	defer rows.Close()
+
+	notifications := []Notification{}
+	stored := []string{}
+	for rows.Next() {
+		var n Notification
+		var traceID, sessionID sql.NullString
+		var payload string
+		if err := rows.Scan(&n.ID, &traceID, &sessionID, &n.Timestamp, &payload); err != nil {
+			return nil, fmt.Errorf("scan notification_log: %w", err)
+		}
+		n.TraceID = traceID.String
+		n.SessionID = sessionID.String
+		notifications = append(notifications, n)
+		stored = append(stored, payload)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+	for i := range notifications {
+		payload, erased, err := a.openJSON(ctx, notifications[i].SessionID, stored[i])
+		if err != nil {
+			return nil, fmt.Errorf("open notification_log %d: %w", notifications[i].ID, err)
+		}
+		notifications[i].Erased = erased
+		notifications[i].Payload = json.RawMessage("null")
+		if !erased {
+			notifications[i].Payload = json.RawMessage(payload)
+		}
+	}
+	return notifications, nil
+}
+
+func nullIfEmpty(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
@@ -0,0 +1,51 @@
+package audit
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func BenchmarkRecordStep(b *testing.B) {
+	db, err := NewAuditDB(filepath.Join(b.TempDir(), "audit.db"))
+	if err != nil {
+		b.Fatalf("NewAuditDB: %v", err)
+	}
+	b.Cleanup(func() { _ = db.Close() })
+
+	ctx := context.Background()
+	data := map[string]any{
+		"prompt":    "what is on my calendar today?",
+		"max_turns": 5,
+		"top_k":     3,
+		"kbs":       []string{"Domain-KB", "Body-KB", "Soul-KB", "Mind-KB"},
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := db.RecordStep(ctx, "trace-bench", "session-bench", "PLAN_START", data); err != nil {
+			b.Fatalf("RecordStep: %v", err)
+		}
+	}
+}
+
+func BenchmarkRecordStepParallel(b *testing.B) {
+	db, err := NewAuditDB(filepath.Join(b.TempDir(), "audit.db"))
+	if err != nil {
+		b.Fatalf("NewAuditDB: %v", err)
+	}
+	b.Cleanup(func() { _ = db.Close() })
+
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if err := db.RecordStep(ctx, "trace-bench", "session-bench", "TOOL_RESULT", map[string]any{"stdout": "ok"}); err != nil {
+				b.Errorf("RecordStep: %v", err)
+				return
+			}
+		}
+	})
+}
@@ -0,0 +1,219 @@
+package audit
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// A compliance bundle is a zip archive for data-subject-access requests and
+// incident reviews:
+//
+//	audit.jsonl            audit_log rows, one JSON object per line
+//	notifications.jsonl    notification_log rows
+//	memory/<session>.json  Memory Service history per session at export time
+//	manifest.json          the filter, plus size and SHA-256 of every file above
+//	manifest.sig           base64 Ed25519 signature of manifest.json
+const (
+	bundleManifest  = "manifest.json"
+	bundleSignature = "manifest.sig"
+)
+
+// ErrBundleInvalid is returned by VerifyBundle when a bundle does not match
+// its manifest or signature.
+var ErrBundleInvalid = errors.New("invalid audit bundle")
+
+// Bundle is the content of a compliance export.
+type Bundle struct {
+	SessionID string
+	Since     time.Time
+	Until     time.Time
+
+	Entries       []Entry
+	Notifications []Notification
+	// Memory maps session IDs to their Memory Service history.
+	Memory map[string]json.RawMessage
+}
+
+// Manifest describes a bundle's files; it is what the signature covers.
+type Manifest struct {
+	Version   int            `json:"version"`
+	CreatedAt time.Time      `json:"created_at"`
+	SessionID string         `json:"session_id,omitempty"`
+	Since     *time.Time     `json:"since,omitempty"`
+	Until     *time.Time     `json:"until,omitempty"`
+	PublicKey string         `json:"public_key"`
+	Files     []ManifestFile `json:"files"`
+}
+
+// ManifestFile is one bundle file in the manifest.
+type ManifestFile struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// ParseSigningKey decodes PAGI_AUDIT_SIGNING_KEY: a base64 Ed25519 seed (32
+// bytes) or private key (64 bytes).
+func ParseSigningKey(s string) (ed25519.PrivateKey, error) {
+	b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("signing key: not base64: %w", err)
+	}
+	switch len(b) {
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(b), nil
+	case ed25519.PrivateKeySize:
+		return ed25519.PrivateKey(b), nil
+	default:
+		return nil, fmt.Errorf("signing key: want a %d-byte seed or %d-byte key, got %d bytes", ed25519.SeedSize, ed25519.PrivateKeySize, len(b))
+	}
+}
+
+// WriteBundle writes b to w as a signed zip archive.
+func WriteBundle(w io.Writer, b Bundle, key ed25519.PrivateKey, now time.Time) error {
+	manifest := Manifest{
+		Version:   1,
+		CreatedAt: now.UTC(),
+		SessionID: b.SessionID,
+		PublicKey: base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey)),
+	}
+	if !b.Since.IsZero() {
+		since := b.Since.UTC()
+		manifest.Since = &since
+	}
+	if !b.Until.IsZero() {
+		until := b.Until.UTC()
+		manifest.Until = &until
+	}
+
+	zw := zip.NewWriter(w)
+	create := func(name string, data []byte) error {
+		f, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: manifest.CreatedAt})
+		if err != nil {
+			return err
+		}
+		_, err = f.Write(data)
+		return err
+	}
+	add := func(name string, data []byte) error {
+		if err := create(name, data); err != nil {
+			return err
+		}
+		sum := sha256.Sum256(data)
+		manifest.Files = append(manifest.Files, ManifestFile{Name: name, Size: int64(len(data)), SHA256: hex.EncodeToString(sum[:])})
+		return nil
+	}
+
+	var auditLines, notificationLines bytes.Buffer
+	enc := json.NewEncoder(&auditLines)
+	for _, e := range b.Entries {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	enc = json.NewEncoder(&notificationLines)
+	for _, n := range b.Notifications {
+		if err := enc.Encode(n); err != nil {
+			return err
+		}
+	}
+	if err := add("audit.jsonl", auditLines.Bytes()); err != nil {
+		return err
+	}
+	if err := add("notifications.jsonl", notificationLines.Bytes()); err != nil {
+		return err
+	}
+	sessions := make([]string, 0, len(b.Memory))
+	for id := range b.Memory {
+		sessions = append(sessions, id)
+	}
+	slices.Sort(sessions)
+	for _, id := range sessions {
+		if err := add("memory/"+url.PathEscape(id)+".json", b.Memory[id]); err != nil {
+			return err
+		}
+	}
+
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := create(bundleManifest, manifestJSON); err != nil {
+		return err
+	}
+	sig := base64.StdEncoding.EncodeToString(ed25519.Sign(key, manifestJSON))
+	if err := create(bundleSignature, []byte(sig)); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// VerifyBundle checks a bundle's signature against pub and every file against
+// the manifest, and returns the manifest. Files the manifest does not list
+// make the bundle invalid.
+func VerifyBundle(r io.ReaderAt, size int64, pub ed25519.PublicKey) (*Manifest, error) {
+	if len(pub) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("public key: want %d bytes, got %d", ed25519.PublicKeySize, len(pub))
+	}
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBundleInvalid, err)
+	}
+	files := map[string][]byte{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrBundleInvalid, f.Name, err)
+		}
+		data, err := io.ReadAll(rc)
+		_ = rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrBundleInvalid, f.Name, err)
+		}
+		if _, dup := files[f.Name]; dup {
+			return nil, fmt.Errorf("%w: duplicate file %s", ErrBundleInvalid, f.Name)
+		}
+		files[f.Name] = data
+	}
+
+	manifestJSON, sig := files[bundleManifest], files[bundleSignature]
+	rawSig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig)))
+	if manifestJSON == nil || err != nil || !ed25519.Verify(pub, manifestJSON, rawSig) {
+		return nil, fmt.Errorf("%w: bad or missing signature", ErrBundleInvalid)
+	}
+	var m Manifest
+	if err := json.Unmarshal(manifestJSON, &m); err != nil {
+		return nil, fmt.Errorf("%w: manifest: %v", ErrBundleInvalid, err)
+	}
+
+	listed := map[string]bool{bundleManifest: true, bundleSignature: true}
+	for _, mf := range m.Files {
+		data, ok := files[mf.Name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s is missing", ErrBundleInvalid, mf.Name)
+		}
+		sum := sha256.Sum256(data)
+		if int64(len(data)) != mf.Size || hex.EncodeToString(sum[:]) != mf.SHA256 {
+			return nil, fmt.Errorf("%w: %s does not match the manifest", ErrBundleInvalid, mf.Name)
+		}
+		listed[mf.Name] = true
+	}
+	for name := range files {
+		if !listed[name] {
+			return nil, fmt.Errorf("%w: %s is not in the manifest", ErrBundleInvalid, name)
+		}
+	}
+	return &m, nil
+}
@@ -0,0 +1,124 @@
+package audit
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBundle_WriteAndVerify(t *testing.T) {
+	db, err := NewAuditDB(filepath.Join(t.TempDir(), "audit.db"))
+	if err != nil {
+		t.Fatalf("NewAuditDB: %v", err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		_ = db.RecordStep(ctx, "t1", "s1", "TOOL_CALL", map[string]any{"i": i})
+	}
+	_ = db.RecordStep(ctx, "t2", "s2", "PLAN_START", nil)
+	_ = db.RecordNotification(ctx, "t1", "s1", `{"session_id":"s1","status":"COMPLETED"}`)
+
+	// AfterID pages through the rows in order.
+	page, err := db.Query(ctx, QueryFilter{SessionID: "s1", Limit: 2})
+	if err != nil || len(page) != 2 {
+		t.Fatalf("first page = %d rows, %v", len(page), err)
+	}
+	rest, err := db.Query(ctx, QueryFilter{SessionID: "s1", Limit: 2, AfterID: page[1].ID})
+	if err != nil || len(rest) != 1 || rest[0].ID <= page[1].ID {
+		t.Fatalf("second page = %+v, %v", rest, err)
+	}
+	notifications, err := db.QueryNotifications(ctx, QueryFilter{SessionID: "s1", EventType: "ignored"})
+	if err != nil || len(notifications) != 1 || !json.Valid(notifications[0].Payload) {
+		t.Fatalf("notifications = %+v, %v", notifications, err)
+	}
+
+	key, err := ParseSigningKey(base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, ed25519.SeedSize)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	b := Bundle{
+		SessionID:     "s1",
+		Entries:       append(page, rest...),
+		Notifications: notifications,
+		Memory:        map[string]json.RawMessage{"s1": json.RawMessage(`{"messages":[]}`), "../s2": json.RawMessage(`{}`)},
+	}
+	if err := WriteBundle(&buf, b, key, time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("WriteBundle: %v", err)
+	}
+	pub := key.Public().(ed25519.PublicKey)
+	m, err := VerifyBundle(bytes.NewReader(buf.Bytes()), int64(buf.Len()), pub)
+	if err != nil {
+		t.Fatalf("VerifyBundle: %v", err)
+	}
+	var names []string
+	for _, f := range m.Files {
+		names = append(names, f.Name)
+	}
+	if got := strings.Join(names, ","); got != "audit.jsonl,notifications.jsonl,memory/..%2Fs2.json,memory/s1.json" {
+		t.Fatalf("files = %s", got)
+	}
+	if m.SessionID != "s1" || m.PublicKey != base64.StdEncoding.EncodeToString(pub) {
+		t.Fatalf("manifest = %+v", m)
+	}
+
+	// Another key, an edited file or an extra file all fail verification.
+	other := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{8}, ed25519.SeedSize)).Public().(ed25519.PublicKey)
+	if _, err := VerifyBundle(bytes.NewReader(buf.Bytes()), int64(buf.Len()), other); !errors.Is(err, ErrBundleInvalid) {
+		t.Fatalf("other key: %v, want ErrBundleInvalid", err)
+	}
+	edited := rewriteZip(t, buf.Bytes(), func(name string, data []byte) []byte {
+		if name == "audit.jsonl" {
+			return bytes.Replace(data, []byte("TOOL_CALL"), []byte("TOOL_NONE"), 1)
+		}
+		return data
+	}, nil)
+	if _, err := VerifyBundle(bytes.NewReader(edited), int64(len(edited)), pub); err == nil || !strings.Contains(err.Error(), "audit.jsonl does not match") {
+		t.Errorf("edited file: %v", err)
+	}
+	extra := rewriteZip(t, buf.Bytes(), nil, map[string][]byte{"memory/s3.json": []byte("{}")})
+	if _, err := VerifyBundle(bytes.NewReader(extra), int64(len(extra)), pub); err == nil || !strings.Contains(err.Error(), "memory/s3.json is not in the manifest") {
+		t.Errorf("extra file: %v", err)
+	}
+
+	if _, err := ParseSigningKey("c2hvcnQ="); err == nil {
+		t.Fatal("want an error for a short key")
+	}
+}
+
+// rewriteZip copies a zip archive through edit (when set), then adds extra.
+func rewriteZip(t *testing.T, zipped []byte, edit func(name string, data []byte) []byte, extra map[string][]byte) []byte {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(zipped), int64(len(zipped)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	zw := zip.NewWriter(&out)
+	for _, f := range zr.File {
+		rc, _ := f.Open()
+		data, _ := io.ReadAll(rc)
+		_ = rc.Close()
+		if edit != nil {
+			data = edit(f.Name, data)
+		}
+		w, _ := zw.Create(f.Name)
+		_, _ = w.Write(data)
+	}
+	for name, data := range extra {
+		w, _ := zw.Create(name)
+		_, _ = w.Write(data)
+	}
+	_ = zw.Close()
+	return out.Bytes()
+}
@@ -0,0 +1,51 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+func TestPrincipal(t *testing.T) {
+	// A database from before the principal column.
+	path := filepath.Join(t.TempDir(), "audit.db")
+	old, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := old.Exec(`CREATE TABLE audit_log (id INTEGER PRIMARY KEY AUTOINCREMENT, trace_id TEXT, session_id TEXT, timestamp DATETIME NOT NULL, event_type TEXT NOT NULL, data TEXT);
+		INSERT INTO audit_log (trace_id, session_id, timestamp, event_type) VALUES ('t0', 's1', '2026-01-01 00:00:00', 'PLAN_START')`); err != nil {
+		t.Fatal(err)
+	}
+	_ = old.Close()
+
+	db, err := NewAuditDB(path)
+	if err != nil {
+		t.Fatalf("NewAuditDB on an old database: %v", err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	_ = db.RecordStepAs(ctx, "t1", "s1", "api_key:acme", "TOOL_CALL", nil)
+	_ = db.RecordStep(ctx, "t2", "s1", "PLAN_END", nil)
+
+	all, err := db.Query(ctx, QueryFilter{SessionID: "s1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 3 || all[0].Principal != "" || all[1].Principal != "api_key:acme" || all[2].Principal != "" {
+		t.Fatalf("rows = %+v", all)
+	}
+	acme, _ := db.Query(ctx, QueryFilter{Principal: "api_key:acme"})
+	if len(acme) != 1 || acme[0].TraceID != "t1" {
+		t.Fatalf("principal filter = %+v", acme)
+	}
+
+	// Imported rows keep their principal.
+	if err := db.ImportEntries(ctx, "s2", all); err != nil {
+		t.Fatal(err)
+	}
+	if acme, _ := db.Query(ctx, QueryFilter{SessionID: "s2", Principal: "api_key:acme"}); len(acme) != 1 {
+		t.Fatalf("imported principal rows = %+v", acme)
+	}
+}
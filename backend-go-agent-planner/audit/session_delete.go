@@ -0,0 +1,104 @@
+package audit
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// SessionDeletion counts the rows DeleteSession removed.
+type SessionDeletion struct {
+	AuditRows     int64
+	Notifications int64
+	Tags          int64
+}
+
+// DeleteSession removes every audit row, notification and tag of sessionID in
+// one transaction. Rows recorded outside the session (session_id empty) are
+// kept, even when they mention it.
+func (a *AuditDB) DeleteSession(ctx context.Context, sessionID string) (SessionDeletion, error) {
+	var d SessionDeletion
+	if a == nil || a.db == nil {
+		return d, fmt.Errorf("audit db not initialized")
+	}
+	tx, err := a.db.BeginTx(ctx, nil)
+	if err != nil {
+		return d, fmt.Errorf("delete session: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	for _, del := range []struct {
+		table string
+		n     *int64
+	}{
+		{"audit_log", &d.AuditRows},
+		{"notification_log", &d.Notifications},
+		{"session_tags", &d.Tags},
+	} {
+		res, err := tx.ExecContext(ctx, `DELETE FROM `+del.table+` WHERE session_id = ?`, sessionID)
+		if err != nil {
+			return SessionDeletion{}, fmt.Errorf("delete %s: %w", del.table, err)
+		}
+		*del.n, _ = res.RowsAffected()
+	}
+	if err := tx.Commit(); err != nil {
+		return SessionDeletion{}, fmt.Errorf("delete session: %w", err)
+	}
+	return d, nil
+}
+
+// DeletionReceipt records a data-subject deletion. It is signed with the
+// bundle signing key, so whoever asked for the deletion can later prove it
+// happened without the service keeping any of the session's data.
+type DeletionReceipt struct {
+	Version   int       `json:"version"`
+	SessionID string    `json:"session_id"`
+	DeletedAt time.Time `json:"deleted_at"`
+	// RequestedBy is the principal that asked for the deletion.
+	RequestedBy string `json:"requested_by,omitempty"`
+	// Deleted counts what was removed per store, e.g. "audit_rows" or
+	// "memory_messages".
+	Deleted map[string]int64 `json:"deleted"`
+	// KeyErased is set when the session's encryption key was erased too.
+	KeyErased bool   `json:"key_erased"`
+	PublicKey string `json:"public_key"`
+	// Signature is the base64 Ed25519 signature of the receipt's JSON with
+	// Signature empty.
+	Signature string `json:"signature,omitempty"`
+}
+
+// SignReceipt sets r's version, public key and signature.
+func SignReceipt(r *DeletionReceipt, key ed25519.PrivateKey) error {
+	r.Version = 1
+	r.DeletedAt = r.DeletedAt.UTC()
+	r.PublicKey = base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey))
+	r.Signature = ""
+	payload, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	r.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(key, payload))
+	return nil
+}
+
+// VerifyReceipt checks r's signature against pub.
+func VerifyReceipt(r DeletionReceipt, pub ed25519.PublicKey) error {
+	if len(pub) != ed25519.PublicKeySize {
+		return fmt.Errorf("public key: want %d bytes, got %d", ed25519.PublicKeySize, len(pub))
+	}
+	sig, err := base64.StdEncoding.DecodeString(r.Signature)
+	if err != nil {
+		return fmt.Errorf("receipt signature: %w", err)
+	}
+	r.Signature = ""
+	payload, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(pub, payload, sig) {
+		return fmt.Errorf("receipt signature does not match")
+	}
+	return nil
+}
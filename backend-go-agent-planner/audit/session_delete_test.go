@@ -0,0 +1,68 @@
+package audit
+
+import (
+	"context"
+	"crypto/ed25519"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDeleteSession(t *testing.T) {
+	db, err := NewAuditDB(filepath.Join(t.TempDir(), "audit.db"))
+	if err != nil {
+		t.Fatalf("NewAuditDB: %v", err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+
+	for _, s := range []string{"s1", "s2"} {
+		_ = db.RecordStep(ctx, "t", s, "PLAN_START", nil)
+		_ = db.RecordStep(ctx, "t", s, "PLAN_END", nil)
+		_ = db.RecordNotification(ctx, "t", s, `{"msg":"done"}`)
+	}
+	if err := db.TagSession(ctx, "s1", []string{"health"}); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := db.DeleteSession(ctx, "s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d != (SessionDeletion{AuditRows: 2, Notifications: 1, Tags: 1}) {
+		t.Fatalf("deleted %+v", d)
+	}
+	if rows, _ := db.Query(ctx, QueryFilter{SessionID: "s1"}); len(rows) != 0 {
+		t.Fatalf("s1 rows left: %+v", rows)
+	}
+	if rows, _ := db.Query(ctx, QueryFilter{SessionID: "s2"}); len(rows) != 2 {
+		t.Fatalf("s2 rows = %+v, want untouched", rows)
+	}
+	if d, err := db.DeleteSession(ctx, "s1"); err != nil || d != (SessionDeletion{}) {
+		t.Fatalf("second delete = %+v, %v", d, err)
+	}
+}
+
+func TestDeletionReceiptSignature(t *testing.T) {
+	pub, key, _ := ed25519.GenerateKey(nil)
+	r := DeletionReceipt{
+		SessionID: "s1",
+		DeletedAt: time.Now(),
+		Deleted:   map[string]int64{"audit_rows": 2, "memory_messages": 4},
+	}
+	if err := SignReceipt(&r, key); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyReceipt(r, pub); err != nil {
+		t.Fatalf("VerifyReceipt: %v", err)
+	}
+	tampered := r
+	tampered.Deleted = map[string]int64{"audit_rows": 0}
+	if err := VerifyReceipt(tampered, pub); err == nil {
+		t.Fatal("tampered receipt verified")
+	}
+	other, _, _ := ed25519.GenerateKey(nil)
+	if err := VerifyReceipt(r, other); err == nil {
+		t.Fatal("receipt verified with another key")
+	}
+}
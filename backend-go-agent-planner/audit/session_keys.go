@@ -0,0 +1,287 @@
+package audit
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sealedPrefix marks a payload sealed with a session key. Payloads written
+// by this version continue "<generation>:" and then the base64 nonce and
+// AES-256-GCM ciphertext; older ones, sealed with a session's first key,
+// continue with the base64 directly.
+const sealedPrefix = "enc:v1:"
+
+// ErrSessionKeyErased is returned when opening a payload whose session key was
+// deleted: the payload can no longer be read.
+var ErrSessionKeyErased = errors.New("session key erased")
+
+const createSessionKeysSQL = `
+CREATE TABLE IF NOT EXISTS session_keys (
+	session_id TEXT PRIMARY KEY,
+	wrapped_key BLOB NOT NULL,
+	created_at DATETIME NOT NULL
+);
+`
+
+// sessionKeys seals payloads with per-session data keys (envelope
+// encryption). The data keys are kept in session_keys, wrapped with the
+// master key, so erasing a session's key erases everything sealed for it.
+//
+// An erased key leaves a tombstone (an empty wrapped_key and erased_at). A
+// later write under the session gets a key of the next generation, and
+// payloads name the generation they were sealed with, so those of an
+// erased generation read as erased rather than failing to authenticate.
+type sessionKeys struct {
+	db     *sql.DB
+	master cipher.AEAD
+
+	mu sync.Mutex
+	// keys caches the current data key of each session.
+	keys map[string]sessionKey
+}
+
+// sessionKey is a session's data key and its generation (1 for the first).
+type sessionKey struct {
+	aead       cipher.AEAD
+	generation int
+}
+
+// EnableSessionKeys turns on sealing: from now on the data of audit rows and
+// notifications with a session ID is written sealed with that session's key.
+// master is the 32-byte key wrapping the session keys.
+func (a *AuditDB) EnableSessionKeys(master []byte) error {
+	if a == nil || a.db == nil {
+		return fmt.Errorf("audit db not initialized")
+	}
+	aead, err := newAEAD(master)
+	if err != nil {
+		return fmt.Errorf("master key: %w", err)
+	}
+	if _, err := a.db.Exec(createSessionKeysSQL); err != nil {
+		return fmt.Errorf("create session_keys: %w", err)
+	}
+	if err := addColumn(a.db, "session_keys", "generation", "INTEGER NOT NULL DEFAULT 1"); err != nil {
+		return fmt.Errorf("migrate session_keys: %w", err)
+	}
+	if err := addColumn(a.db, "session_keys", "erased_at", "DATETIME"); err != nil {
+		return fmt.Errorf("migrate session_keys: %w", err)
+	}
+	a.keys = &sessionKeys{db: a.db, master: aead, keys: map[string]sessionKey{}}
+	return nil
+}
+
+// SessionKeysEnabled reports whether EnableSessionKeys was called.
+func (a *AuditDB) SessionKeysEnabled() bool {
+	return a != nil && a.keys != nil
+}
+
+// Seal encrypts plaintext with sessionID's key, creating the key on first
+// use. Without session keys, or for an empty session ID, it returns
+// plaintext as is.
+func (a *AuditDB) Seal(ctx context.Context, sessionID, plaintext string) (string, error) {
+	if !a.SessionKeysEnabled() || sessionID == "" {
+		return plaintext, nil
+	}
+	key, err := a.keys.key(ctx, sessionID, true)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, key.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("seal: %w", err)
+	}
+	sealed := key.aead.Seal(nonce, nonce, []byte(plaintext), []byte(sessionID))
+	return fmt.Sprintf("%s%d:%s", sealedPrefix, key.generation, base64.StdEncoding.EncodeToString(sealed)), nil
+}
+
+// Open reverses Seal. Text that was not sealed is returned as is, so rows
+// written before sealing was enabled stay readable. It returns
+// ErrSessionKeyErased once the key it was sealed with is erased, even when
+// the session has a newer key.
+func (a *AuditDB) Open(ctx context.Context, sessionID, text string) (string, error) {
+	if !IsSealed(text) {
+		return text, nil
+	}
+	if !a.SessionKeysEnabled() {
+		return "", fmt.Errorf("open: sealed payload but session keys are not enabled")
+	}
+	generation, encoded := 1, strings.TrimPrefix(text, sealedPrefix)
+	if g, rest, ok := strings.Cut(encoded, ":"); ok {
+		n, err := strconv.Atoi(g)
+		if err != nil || n < 1 {
+			return "", fmt.Errorf("open: bad key generation %q", g)
+		}
+		generation, encoded = n, rest
+	}
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("open: %w", err)
+	}
+	key, err := a.keys.key(ctx, sessionID, false)
+	if err != nil {
+		return "", err
+	}
+	if key.generation != generation {
+		return "", ErrSessionKeyErased
+	}
+	aead := key.aead
+	if len(raw) < aead.NonceSize() {
+		return "", fmt.Errorf("open: payload too short")
+	}
+	plain, err := aead.Open(nil, raw[:aead.NonceSize()], raw[aead.NonceSize():], []byte(sessionID))
+	if err != nil {
+		return "", fmt.Errorf("open: %w", err)
+	}
+	return string(plain), nil
+}
+
+// IsSealed reports whether text was produced by Seal.
+func IsSealed(text string) bool {
+	return strings.HasPrefix(text, sealedPrefix)
+}
+
+// DeleteSessionKey erases sessionID's key, which makes everything sealed
+// for the session unreadable, wherever it is stored. It reports whether the
+// session had a key. The key's row stays as a tombstone, so the session's
+// next key is of a new generation.
+func (a *AuditDB) DeleteSessionKey(ctx context.Context, sessionID string) (bool, error) {
+	if !a.SessionKeysEnabled() {
+		return false, fmt.Errorf("session keys not enabled")
+	}
+	k := a.keys
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	res, err := k.db.ExecContext(ctx,
+		`UPDATE session_keys SET wrapped_key = x'', erased_at = ? WHERE session_id = ? AND erased_at IS NULL`,
+		time.Now().UTC(), sessionID,
+	)
+	if err != nil {
+		return false, fmt.Errorf("erase session_keys: %w", err)
+	}
+	delete(k.keys, sessionID)
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// key returns sessionID's current data key. When create is set, a session
+// without a key gets one, and one whose key was erased gets a key of the
+// next generation; otherwise both return ErrSessionKeyErased.
+func (k *sessionKeys) key(ctx context.Context, sessionID string, create bool) (sessionKey, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if key, ok := k.keys[sessionID]; ok {
+		return key, nil
+	}
+	var (
+		wrapped    []byte
+		generation int
+		erasedAt   sql.NullTime
+	)
+	err := k.db.QueryRowContext(ctx,
+		`SELECT wrapped_key, generation, erased_at FROM session_keys WHERE session_id = ?`, sessionID,
+	).Scan(&wrapped, &generation, &erasedAt)
+	switch {
+	case err != nil && !errors.Is(err, sql.ErrNoRows):
+		return sessionKey{}, fmt.Errorf("query session_keys: %w", err)
+	case (errors.Is(err, sql.ErrNoRows) || erasedAt.Valid) && !create:
+		return sessionKey{}, ErrSessionKeyErased
+	case errors.Is(err, sql.ErrNoRows):
+		generation = 1
+		if wrapped, err = k.newWrappedKey(sessionID); err != nil {
+			return sessionKey{}, err
+		}
+		if _, err := k.db.ExecContext(ctx,
+			`INSERT INTO session_keys (session_id, wrapped_key, generation, created_at) VALUES (?, ?, ?, ?)`,
+			sessionID, wrapped, generation, time.Now().UTC(),
+		); err != nil {
+			return sessionKey{}, fmt.Errorf("insert session_keys: %w", err)
+		}
+	case erasedAt.Valid:
+		generation++
+		if wrapped, err = k.newWrappedKey(sessionID); err != nil {
+			return sessionKey{}, err
+		}
+		if _, err := k.db.ExecContext(ctx,
+			`UPDATE session_keys SET wrapped_key = ?, generation = ?, created_at = ?, erased_at = NULL WHERE session_id = ?`,
+			wrapped, generation, time.Now().UTC(), sessionID,
+		); err != nil {
+			return sessionKey{}, fmt.Errorf("update session_keys: %w", err)
+		}
+	}
+	if len(wrapped) < k.master.NonceSize() {
+		return sessionKey{}, fmt.Errorf("session key: wrapped key too short")
+	}
+	dek, err := k.master.Open(nil, wrapped[:k.master.NonceSize()], wrapped[k.master.NonceSize():], []byte(sessionID))
+	if err != nil {
+		return sessionKey{}, fmt.Errorf("session key: unwrap (wrong master key?): %w", err)
+	}
+	aead, err := newAEAD(dek)
+	if err != nil {
+		return sessionKey{}, err
+	}
+	key := sessionKey{aead: aead, generation: generation}
+	k.keys[sessionID] = key
+	return key, nil
+}
+
+// newWrappedKey generates a data key and wraps it with the master key.
+func (k *sessionKeys) newWrappedKey(sessionID string) ([]byte, error) {
+	dek := make([]byte, 32)
+	if _, err := rand.Read(dek); err != nil {
+		return nil, fmt.Errorf("session key: %w", err)
+	}
+	nonce := make([]byte, k.master.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("session key: %w", err)
+	}
+	return k.master.Seal(nonce, nonce, dek, []byte(sessionID)), nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("want a 32-byte key, got %d bytes", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// sealJSON seals a JSON payload for storage; a sealed payload is stored as a
+// JSON string so the column keeps holding JSON.
+func (a *AuditDB) sealJSON(ctx context.Context, sessionID, payload string) (string, error) {
+	if !a.SessionKeysEnabled() || sessionID == "" || payload == "" {
+		return payload, nil
+	}
+	sealed, err := a.Seal(ctx, sessionID, payload)
+	if err != nil {
+		return "", err
+	}
+	b, _ := json.Marshal(sealed)
+	return string(b), nil
+}
+
+// openJSON reverses sealJSON. erased is set when the session key is gone.
+func (a *AuditDB) openJSON(ctx context.Context, sessionID, stored string) (payload string, erased bool, err error) {
+	var sealed string
+	if !strings.HasPrefix(stored, `"`+sealedPrefix) || json.Unmarshal([]byte(stored), &sealed) != nil {
+		return stored, false, nil
+	}
+	payload, err = a.Open(ctx, sessionID, sealed)
+	if errors.Is(err, ErrSessionKeyErased) {
+		return "", true, nil
+	}
+	return payload, false, err
+}
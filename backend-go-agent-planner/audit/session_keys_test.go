@@ -0,0 +1,141 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSessionKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.db")
+	db, err := NewAuditDB(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	// A row from before sealing was enabled stays readable.
+	_ = db.RecordStep(ctx, "t0", "s1", "PLAN_START", map[string]any{"prompt": "before"})
+	if err := db.EnableSessionKeys(bytes.Repeat([]byte{7}, 32)); err != nil {
+		t.Fatal(err)
+	}
+	_ = db.RecordStep(ctx, "t1", "s1", "PLAN_END", map[string]any{"answer": "my secret answer"})
+	_ = db.RecordStep(ctx, "t2", "s2", "PLAN_END", map[string]any{"answer": "other session"})
+	_ = db.RecordNotification(ctx, "t1", "s1", `{"result":"my secret answer"}`)
+
+	var stored string
+	if err := db.db.QueryRow(`SELECT data FROM audit_log WHERE trace_id = 't1'`).Scan(&stored); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(stored, "secret") || !strings.HasPrefix(stored, `"`+sealedPrefix) {
+		t.Fatalf("stored data = %s, want it sealed", stored)
+	}
+
+	rows, err := db.Query(ctx, QueryFilter{SessionID: "s1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || string(rows[0].Data) != `{"prompt":"before"}` || string(rows[1].Data) != `{"answer":"my secret answer"}` {
+		t.Fatalf("rows = %+v", rows)
+	}
+
+	// Imported rows are sealed with the new session's key.
+	if err := db.ImportEntries(ctx, "s3", rows[1:]); err != nil {
+		t.Fatal(err)
+	}
+	if imported, _ := db.Query(ctx, QueryFilter{SessionID: "s3"}); len(imported) != 1 || string(imported[0].Data) != `{"answer":"my secret answer"}` {
+		t.Fatalf("imported = %+v", imported)
+	}
+
+	if erased, err := db.DeleteSessionKey(ctx, "s1"); err != nil || !erased {
+		t.Fatalf("DeleteSessionKey = %v, %v", erased, err)
+	}
+	rows, err = db.Query(ctx, QueryFilter{SessionID: "s1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows[0].Erased || !rows[1].Erased || rows[1].Data != nil {
+		t.Fatalf("after erasure: %+v", rows)
+	}
+	notes, err := db.QueryNotifications(ctx, QueryFilter{SessionID: "s1"})
+	if err != nil || len(notes) != 1 || !notes[0].Erased || string(notes[0].Payload) != "null" {
+		t.Fatalf("notifications after erasure: %+v, %v", notes, err)
+	}
+	if other, _ := db.Query(ctx, QueryFilter{SessionID: "s2"}); other[0].Erased {
+		t.Fatalf("another session was erased: %+v", other)
+	}
+	if erased, _ := db.DeleteSessionKey(ctx, "s1"); erased {
+		t.Fatal("second DeleteSessionKey reported a key")
+	}
+
+	// Sealed text of an erased session cannot be opened, even by a reopened
+	// database with the right master key.
+	sealed, _ := db.Seal(ctx, "s2", "hello")
+	db2, err := NewAuditDB(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db2.Close()
+	if err := db2.EnableSessionKeys(bytes.Repeat([]byte{7}, 32)); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := db2.Open(ctx, "s2", sealed); err != nil || got != "hello" {
+		t.Fatalf("Open = %q, %v", got, err)
+	}
+	if _, err := db2.Open(ctx, "s1", sealedPrefix+"AAAAAAAAAAAAAAAAAAAAAAAA"); !errors.Is(err, ErrSessionKeyErased) {
+		t.Fatalf("Open of an erased session: %v", err)
+	}
+	if _, err := db2.Open(ctx, "s3", sealed); err == nil {
+		t.Fatal("text sealed for s2 opened as s3")
+	}
+}
+
+func TestSessionKeys_WriteAfterErasure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.db")
+	db, err := NewAuditDB(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err := db.EnableSessionKeys(bytes.Repeat([]byte{7}, 32)); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	_ = db.RecordStep(ctx, "t1", "s1", "PLAN_END", map[string]any{"answer": "before erasure"})
+	if erased, err := db.DeleteSessionKey(ctx, "s1"); err != nil || !erased {
+		t.Fatalf("DeleteSessionKey = %v, %v", erased, err)
+	}
+	// The session is used again: it gets a new key, and the rows sealed with
+	// the erased one still read as erased.
+	_ = db.RecordStep(ctx, "t2", "s1", "PLAN_END", map[string]any{"answer": "after erasure"})
+
+	rows, err := db.Query(ctx, QueryFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || !rows[0].Erased || rows[1].Erased || string(rows[1].Data) != `{"answer":"after erasure"}` {
+		t.Fatalf("rows = %+v", rows)
+	}
+
+	// So do they for a reopened database, which has no key cached.
+	db2, err := NewAuditDB(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db2.Close()
+	if err := db2.EnableSessionKeys(bytes.Repeat([]byte{7}, 32)); err != nil {
+		t.Fatal(err)
+	}
+	if rows, err := db2.Query(ctx, QueryFilter{SessionID: "s1"}); err != nil || len(rows) != 2 || !rows[0].Erased || rows[1].Erased {
+		t.Fatalf("reopened rows = %+v, %v", rows, err)
+	}
+	if erased, err := db2.DeleteSessionKey(ctx, "s1"); err != nil || !erased {
+		t.Fatalf("erasing the second key = %v, %v", erased, err)
+	}
+	if rows, _ := db2.Query(ctx, QueryFilter{SessionID: "s1"}); !rows[0].Erased || !rows[1].Erased {
+		t.Fatalf("after the second erasure: %+v", rows)
+	}
+}
@@ -0,0 +1,59 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ownedByTenantSQL restricts a query on a session_id column to the sessions
+// of one tenant, its only argument.
+const ownedByTenantSQL = "session_id IN (SELECT session_id FROM session_owners WHERE tenant = ?)"
+
+// ClaimSession records tenant ("" for the default API key) as the owner of
+// sessionID unless the session already has one, and returns the owner.
+func (a *AuditDB) ClaimSession(ctx context.Context, sessionID, tenant string) (string, error) {
+	if a == nil || a.db == nil {
+		return "", fmt.Errorf("audit db not initialized")
+	}
+	if _, err := a.db.ExecContext(ctx,
+		`INSERT OR IGNORE INTO session_owners (session_id, tenant, created_at) VALUES (?, ?, ?)`,
+		sessionID, tenant, time.Now().UTC(),
+	); err != nil {
+		return "", fmt.Errorf("insert session_owners: %w", err)
+	}
+	owner, _, err := a.SessionOwner(ctx, sessionID)
+	return owner, err
+}
+
+// SessionOwner returns the tenant that owns sessionID; ok is false when no
+// tenant has claimed it.
+func (a *AuditDB) SessionOwner(ctx context.Context, sessionID string) (owner string, ok bool, err error) {
+	if a == nil || a.db == nil {
+		return "", false, fmt.Errorf("audit db not initialized")
+	}
+	err = a.db.QueryRowContext(ctx, `SELECT tenant FROM session_owners WHERE session_id = ?`, sessionID).Scan(&owner)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("query session_owners: %w", err)
+	}
+	return owner, true, nil
+}
+
+// backfillSessionOwners gives the sessions of a database that predates
+// session_owners the tenant of their first PLAN_START. Rows whose data is
+// sealed with a session key carry no readable tenant and are left unowned.
+func backfillSessionOwners(db *sql.DB) error {
+	_, err := db.Exec(`
+INSERT OR IGNORE INTO session_owners (session_id, tenant, created_at)
+SELECT session_id, COALESCE(json_extract(data, '$.tenant'), ''), MIN(timestamp)
+FROM audit_log
+WHERE event_type = 'PLAN_START' AND session_id IS NOT NULL AND session_id != '' AND json_valid(data)
+GROUP BY session_id
+ORDER BY MIN(id)`)
+	return err
+}
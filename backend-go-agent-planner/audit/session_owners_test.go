@@ -0,0 +1,94 @@
+package audit
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSessionOwners(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.db")
+	db, err := NewAuditDB(path)
+	if err != nil {
+		t.Fatalf("NewAuditDB: %v", err)
+	}
+	ctx := context.Background()
+
+	if owner, err := db.ClaimSession(ctx, "s1", "acme"); err != nil || owner != "acme" {
+		t.Fatalf("claim s1 = %q, %v", owner, err)
+	}
+	// The first claim sticks.
+	if owner, err := db.ClaimSession(ctx, "s1", "globex"); err != nil || owner != "acme" {
+		t.Fatalf("second claim = %q, %v", owner, err)
+	}
+	if _, ok, err := db.SessionOwner(ctx, "s2"); err != nil || ok {
+		t.Fatalf("unclaimed s2: ok %v, %v", ok, err)
+	}
+	if _, err := db.ClaimSession(ctx, "s2", "globex"); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"s1", "s2"} {
+		_ = db.RecordStep(ctx, "t", s, "PLAN_START", nil)
+		_ = db.RecordStep(ctx, "t", s, "TOOL_CALL", map[string]any{"tool": "web_search"})
+		_ = db.RecordStep(ctx, "t", s, "PLAN_END", nil)
+	}
+	_ = db.RecordStep(ctx, "t", "", "SESSION_KEY_ERASED", nil)
+
+	entries, err := db.Query(ctx, QueryFilter{Tenant: "acme"})
+	if err != nil || len(entries) != 3 {
+		t.Fatalf("acme rows = %d, %v", len(entries), err)
+	}
+	for _, e := range entries {
+		if e.SessionID != "s1" {
+			t.Fatalf("acme saw %+v", e)
+		}
+	}
+	if all, err := db.Query(ctx, QueryFilter{}); err != nil || len(all) != 7 {
+		t.Fatalf("all rows = %d, %v", len(all), err)
+	}
+	if sessions, err := db.SearchSessions(ctx, SessionFilter{Tenant: "globex"}); err != nil || len(sessions) != 1 || sessions[0].SessionID != "s2" {
+		t.Fatalf("globex sessions = %+v, %v", sessions, err)
+	}
+	now := time.Now()
+	stats, err := db.Stats(ctx, now.Add(-time.Hour), now.Add(time.Hour), "acme")
+	if err != nil || stats.Runs.Total != 1 || len(stats.Tools) != 1 || stats.Tools[0].Calls != 1 {
+		t.Fatalf("acme stats = %+v, %v", stats, err)
+	}
+	if stats, err := db.Stats(ctx, now.Add(-time.Hour), now.Add(time.Hour), "initech"); err != nil || stats.Runs.Total != 0 {
+		t.Fatalf("initech stats = %+v, %v", stats, err)
+	}
+	_ = db.Close()
+}
+
+func TestSessionOwners_Backfill(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.db")
+	db, err := NewAuditDB(path)
+	if err != nil {
+		t.Fatalf("NewAuditDB: %v", err)
+	}
+	ctx := context.Background()
+	_ = db.RecordStep(ctx, "t1", "s1", "PLAN_START", map[string]any{"tenant": "acme"})
+	_ = db.RecordStep(ctx, "t2", "s1", "PLAN_START", map[string]any{"tenant": "globex"})
+	_ = db.RecordStep(ctx, "t3", "s2", "PLAN_START", map[string]any{"tenant": ""})
+	_ = db.RecordStep(ctx, "t4", "s3", "SESSION_TAGGED", nil)
+	// A database from before session_owners.
+	if _, err := db.db.Exec(`DROP TABLE session_owners`); err != nil {
+		t.Fatal(err)
+	}
+	_ = db.Close()
+
+	db, err = NewAuditDB(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer db.Close()
+	for _, tc := range []struct {
+		session, owner string
+		ok             bool
+	}{{"s1", "acme", true}, {"s2", "", true}, {"s3", "", false}} {
+		if owner, ok, err := db.SessionOwner(ctx, tc.session); err != nil || owner != tc.owner || ok != tc.ok {
+			t.Fatalf("%s owner = %q %v, %v; want %q %v", tc.session, owner, ok, err, tc.owner, tc.ok)
+		}
+	}
+}
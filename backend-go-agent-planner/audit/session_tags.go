@@ -0,0 +1,182 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// SessionSummary is one session found by SearchSessions: its tags and the
+// span of its audit rows within the search window.
+type SessionSummary struct {
+	SessionID string    `json:"session_id"`
+	Tags      []string  `json:"tags"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+	Events    int       `json:"events"`
+}
+
+// SessionFilter narrows SearchSessions. Zero-valued fields are ignored.
+type SessionFilter struct {
+	// Tags must all be set on a session.
+	Tags  []string
+	Since time.Time
+	Until time.Time
+	// Limit caps the number of sessions (default 100, max 1000).
+	Limit int
+	// Tenant keeps the sessions owned by this tenant.
+	Tenant string
+}
+
+// TagSession adds tags to sessionID; tags it already has are kept as they
+// are.
+func (a *AuditDB) TagSession(ctx context.Context, sessionID string, tags []string) error {
+	if a == nil || a.db == nil {
+		return fmt.Errorf("audit db not initialized")
+	}
+	tx, err := a.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("insert session_tags: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	now := time.Now().UTC()
+	for _, tag := range tags {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT OR IGNORE INTO session_tags (session_id, tag, created_at) VALUES (?, ?, ?)`,
+			sessionID, tag, now,
+		); err != nil {
+			return fmt.Errorf("insert session_tags: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("insert session_tags: %w", err)
+	}
+	return nil
+}
+
+// UntagSession removes a tag from sessionID. Removing a tag the session does
+// not have is not an error.
+func (a *AuditDB) UntagSession(ctx context.Context, sessionID, tag string) error {
+	if a == nil || a.db == nil {
+		return fmt.Errorf("audit db not initialized")
+	}
+	if _, err := a.db.ExecContext(ctx, `DELETE FROM session_tags WHERE session_id = ? AND tag = ?`, sessionID, tag); err != nil {
+		return fmt.Errorf("delete session_tags: %w", err)
+	}
+	return nil
+}
+
+// SessionTags returns sessionID's tags in alphabetical order.
+func (a *AuditDB) SessionTags(ctx context.Context, sessionID string) ([]string, error) {
+	if a == nil || a.db == nil {
+		return nil, fmt.Errorf("audit db not initialized")
+	}
+	rows, err := a.db.QueryContext(ctx, `SELECT tag FROM session_tags WHERE session_id = ? ORDER BY tag`, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("query session_tags: %w", err)
+	}
+	defer rows.Close()
+	tags := []string{}
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, fmt.Errorf("scan session_tags: %w", err)
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// SearchSessions returns the sessions with audit rows in the filter's window
+// that carry all of its tags, most recently active first.
+func (a *AuditDB) SearchSessions(ctx context.Context, f SessionFilter) ([]SessionSummary, error) {
+	if a == nil || a.db == nil {
+		return nil, fmt.Errorf("audit db not initialized")
+	}
+	limit := f.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	if limit > 1000 {
+		limit = 1000
+	}
+
+	where := []string{"session_id IS NOT NULL", "session_id != ''"}
+	args := []any{}
+	if len(f.Tags) > 0 {
+		// The tag index finds the candidate sessions; HAVING keeps those
+		// with every tag.
+		where = append(where, `session_id IN (SELECT session_id FROM session_tags WHERE tag IN (?`+strings.Repeat(", ?", len(f.Tags)-1)+`)
+			GROUP BY session_id HAVING COUNT(DISTINCT tag) = ?)`)
+		for _, tag := range f.Tags {
+			args = append(args, tag)
+		}
+		args = append(args, len(f.Tags))
+	}
+	if f.Tenant != "" {
+		where = append(where, ownedByTenantSQL)
+		args = append(args, f.Tenant)
+	}
+	if !f.Since.IsZero() {
+		where = append(where, "timestamp >= ?")
+		args = append(args, f.Since.UTC())
+	}
+	if !f.Until.IsZero() {
+		where = append(where, "timestamp < ?")
+		args = append(args, f.Until.UTC())
+	}
+	rows, err := a.db.QueryContext(
+		ctx,
+		`SELECT session_id, MIN(timestamp), MAX(timestamp), COUNT(*),
+			(SELECT GROUP_CONCAT(tag, ',') FROM (SELECT tag FROM session_tags t WHERE t.session_id = audit_log.session_id ORDER BY tag))
+		 FROM audit_log
+		 WHERE `+strings.Join(where, " AND ")+`
+		 GROUP BY session_id
+		 ORDER BY MAX(timestamp) DESC
+		 LIMIT ?`,
+		append(args, limit)...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := []SessionSummary{}
+	for rows.Next() {
+		var s SessionSummary
+		var first, last string
+		var tags sql.NullString
+		if err := rows.Scan(&s.SessionID, &first, &last, &s.Events, &tags); err != nil {
+			return nil, fmt.Errorf("scan sessions: %w", err)
+		}
+		// Aggregates lose the column's DATETIME type, so the driver hands
+		// back the stored text.
+		if s.FirstSeen, err = parseTimestamp(first); err != nil {
+			return nil, err
+		}
+		if s.LastSeen, err = parseTimestamp(last); err != nil {
+			return nil, err
+		}
+		s.Tags = []string{}
+		if tags.String != "" {
+			s.Tags = strings.Split(tags.String, ",")
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, rows.Err()
+}
+
+// parseTimestamp parses a timestamp as go-sqlite3 stores time.Time values.
+func parseTimestamp(s string) (time.Time, error) {
+	s = strings.TrimSuffix(s, "Z")
+	for _, layout := range sqlite3.SQLiteTimestampFormats {
+		if t, err := time.ParseInLocation(layout, s, time.UTC); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("scan sessions: unrecognized timestamp %q", s)
+}
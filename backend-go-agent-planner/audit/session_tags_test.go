@@ -0,0 +1,64 @@
+package audit
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSearchSessions(t *testing.T) {
+	db, err := NewAuditDB(filepath.Join(t.TempDir(), "audit.db"))
+	if err != nil {
+		t.Fatalf("NewAuditDB: %v", err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+
+	for _, s := range []string{"s1", "s2", "s3"} {
+		_ = db.RecordStep(ctx, "t", s, "PLAN_START", nil)
+		_ = db.RecordStep(ctx, "t", s, "PLAN_END", nil)
+	}
+	if err := db.TagSession(ctx, "s1", []string{"health", "project:offsite"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.TagSession(ctx, "s2", []string{"health", "health"}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := db.SearchSessions(ctx, SessionFilter{Tags: []string{"health"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].SessionID != "s2" || got[1].SessionID != "s1" {
+		t.Fatalf("health sessions = %+v, want s2 then s1", got)
+	}
+	if s1 := got[1]; !reflect.DeepEqual(s1.Tags, []string{"health", "project:offsite"}) || s1.Events != 2 || s1.FirstSeen.IsZero() || s1.LastSeen.Before(s1.FirstSeen) {
+		t.Fatalf("s1 = %+v", s1)
+	}
+
+	// Every tag must match.
+	got, err = db.SearchSessions(ctx, SessionFilter{Tags: []string{"health", "project:offsite"}})
+	if err != nil || len(got) != 1 || got[0].SessionID != "s1" {
+		t.Fatalf("both tags = %+v, %v", got, err)
+	}
+
+	// No tags: every session, untagged ones with an empty list.
+	got, err = db.SearchSessions(ctx, SessionFilter{Limit: 10})
+	if err != nil || len(got) != 3 || got[0].SessionID != "s3" || got[0].Tags == nil || len(got[0].Tags) != 0 {
+		t.Fatalf("all sessions = %+v, %v", got, err)
+	}
+
+	got, err = db.SearchSessions(ctx, SessionFilter{Tags: []string{"health"}, Since: time.Now().Add(time.Hour)})
+	if err != nil || len(got) != 0 {
+		t.Fatalf("future window = %+v, %v", got, err)
+	}
+
+	if err := db.UntagSession(ctx, "s1", "health"); err != nil {
+		t.Fatal(err)
+	}
+	if tags, err := db.SessionTags(ctx, "s1"); err != nil || !reflect.DeepEqual(tags, []string{"project:offsite"}) {
+		t.Fatalf("s1 tags after untag = %v, %v", tags, err)
+	}
+}
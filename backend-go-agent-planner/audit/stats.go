@@ -0,0 +1,161 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Stats aggregates the audit log over a window for an operations overview.
+// A run is an AgentLoop: the rows of a session and trace from one PLAN_START
+// to the next. It failed when it recorded a PLAN_ERROR, and ran out of turns
+// when it ended with neither PLAN_END nor PLAN_ERROR (a run still in progress
+// counts as one until it ends).
+type Stats struct {
+	Since time.Time `json:"since"`
+	Until time.Time `json:"until"`
+	Runs  RunStats  `json:"runs"`
+	// PerDay has one entry per UTC day with runs, oldest first.
+	PerDay []DayStats  `json:"per_day"`
+	Tools  []ToolStats `json:"tools"`
+	// FailureReasons counts failed runs and failed tool calls by reason,
+	// most frequent first: plan_error, max_turns, tool_budget,
+	// tool_validation and tool_error.
+	FailureReasons []ReasonCount `json:"failure_reasons"`
+}
+
+// RunStats counts runs and their turns (model responses).
+type RunStats struct {
+	Total    int64   `json:"total"`
+	Failed   int64   `json:"failed"`
+	MaxTurns int64   `json:"max_turns"`
+	AvgTurns float64 `json:"avg_turns"`
+}
+
+// DayStats is RunStats for one UTC day.
+type DayStats struct {
+	Date string `json:"date"`
+	RunStats
+}
+
+// ToolStats counts a tool's calls and failed calls. Tool is empty for rows
+// whose data is sealed with a session key.
+type ToolStats struct {
+	Tool   string `json:"tool"`
+	Calls  int64  `json:"calls"`
+	Errors int64  `json:"errors"`
+}
+
+// ReasonCount is how often a failure reason occurred.
+type ReasonCount struct {
+	Reason string `json:"reason"`
+	Count  int64  `json:"count"`
+}
+
+// runsSQL numbers each session and trace's runs by counting PLAN_STARTs,
+// then summarizes every run that started in the window.
+const runsSQL = `
+WITH rows AS (
+	SELECT timestamp, event_type,
+		SUM(event_type = 'PLAN_START') OVER (PARTITION BY session_id, COALESCE(trace_id, '') ORDER BY id) AS run,
+		session_id, COALESCE(trace_id, '') AS trace
+	FROM audit_log
+	WHERE timestamp >= ? AND timestamp < ? AND (? = '' OR ` + ownedByTenantSQL + `)
+), runs AS (
+	SELECT MIN(timestamp) AS started,
+		SUM(event_type = 'PLAN_MODEL_RESPONSE') AS turns,
+		SUM(event_type = 'PLAN_END') AS ended,
+		SUM(event_type = 'PLAN_ERROR') AS errors
+	FROM rows
+	WHERE run > 0
+	GROUP BY session_id, trace, run
+)
+SELECT substr(started, 1, 10), COUNT(*), SUM(errors > 0), SUM(ended = 0 AND errors = 0), AVG(turns)
+FROM runs
+GROUP BY 1
+ORDER BY 1`
+
+// toolsSQL counts TOOL_CALL and TOOL_ERROR rows by tool, classifying errors.
+const toolsSQL = `
+SELECT COALESCE(CASE WHEN json_valid(data) THEN json_extract(data, '$.tool') END, ''),
+	SUM(event_type = 'TOOL_CALL'),
+	SUM(event_type = 'TOOL_ERROR'),
+	SUM(event_type = 'TOOL_ERROR' AND json_valid(data) AND json_extract(data, '$.budget') = 1),
+	SUM(event_type = 'TOOL_ERROR' AND json_valid(data) AND json_type(data, '$.validation') = 'array')
+FROM audit_log
+WHERE event_type IN ('TOOL_CALL', 'TOOL_ERROR') AND timestamp >= ? AND timestamp < ? AND (? = '' OR ` + ownedByTenantSQL + `)
+GROUP BY 1
+ORDER BY 2 DESC, 1`
+
+// Stats aggregates the rows timestamped in [since, until). A non-empty
+// tenant limits it to the sessions that tenant owns.
+func (a *AuditDB) Stats(ctx context.Context, since, until time.Time, tenant string) (*Stats, error) {
+	if a == nil || a.db == nil {
+		return nil, fmt.Errorf("audit db not initialized")
+	}
+	since, until = since.UTC(), until.UTC()
+	out := &Stats{Since: since, Until: until, PerDay: []DayStats{}, Tools: []ToolStats{}, FailureReasons: []ReasonCount{}}
+
+	rows, err := a.db.QueryContext(ctx, runsSQL, since, until, tenant, tenant)
+	if err != nil {
+		return nil, fmt.Errorf("query run stats: %w", err)
+	}
+	defer rows.Close()
+	var turns float64
+	for rows.Next() {
+		var d DayStats
+		var avg sql.NullFloat64
+		if err := rows.Scan(&d.Date, &d.Total, &d.Failed, &d.MaxTurns, &avg); err != nil {
+			return nil, fmt.Errorf("scan run stats: %w", err)
+		}
+		d.AvgTurns = avg.Float64
+		out.PerDay = append(out.PerDay, d)
+		out.Runs.Total += d.Total
+		out.Runs.Failed += d.Failed
+		out.Runs.MaxTurns += d.MaxTurns
+		turns += d.AvgTurns * float64(d.Total)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+	if out.Runs.Total > 0 {
+		out.Runs.AvgTurns = turns / float64(out.Runs.Total)
+	}
+
+	rows, err = a.db.QueryContext(ctx, toolsSQL, since, until, tenant, tenant)
+	if err != nil {
+		return nil, fmt.Errorf("query tool stats: %w", err)
+	}
+	defer rows.Close()
+	reasons := map[string]int64{"plan_error": out.Runs.Failed, "max_turns": out.Runs.MaxTurns}
+	for rows.Next() {
+		var t ToolStats
+		var budget, validation int64
+		if err := rows.Scan(&t.Tool, &t.Calls, &t.Errors, &budget, &validation); err != nil {
+			return nil, fmt.Errorf("scan tool stats: %w", err)
+		}
+		out.Tools = append(out.Tools, t)
+		reasons["tool_budget"] += budget
+		reasons["tool_validation"] += validation
+		reasons["tool_error"] += t.Errors - budget - validation
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for reason, n := range reasons {
+		if n > 0 {
+			out.FailureReasons = append(out.FailureReasons, ReasonCount{Reason: reason, Count: n})
+		}
+	}
+	sort.Slice(out.FailureReasons, func(i, j int) bool {
+		if out.FailureReasons[i].Count != out.FailureReasons[j].Count {
+			return out.FailureReasons[i].Count > out.FailureReasons[j].Count
+		}
+		return out.FailureReasons[i].Reason < out.FailureReasons[j].Reason
+	})
+	return out, nil
+}
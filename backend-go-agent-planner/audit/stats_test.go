@@ -0,0 +1,90 @@
+package audit
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	db, err := NewAuditDB(filepath.Join(t.TempDir(), "audit.db"))
+	if err != nil {
+		t.Fatalf("NewAuditDB: %v", err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	step := func(session, event string, data any) {
+		t.Helper()
+		if err := db.RecordStep(ctx, "", session, event, data); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// s1: a search, a refused call, then an answer; then a run that fails.
+	step("s1", "PLAN_START", nil)
+	step("s1", "PLAN_MODEL_RESPONSE", nil)
+	step("s1", "TOOL_CALL", map[string]any{"tool": "web_search"})
+	step("s1", "PLAN_MODEL_RESPONSE", nil)
+	step("s1", "TOOL_CALL", map[string]any{"tool": "web_search"})
+	step("s1", "TOOL_ERROR", map[string]any{"tool": "web_search", "error": "over budget", "budget": true})
+	step("s1", "PLAN_MODEL_RESPONSE", nil)
+	step("s1", "PLAN_END", nil)
+	step("s1", "PLAN_START", nil)
+	step("s1", "PLAN_ERROR", map[string]any{"error": "GetPlan: unavailable"})
+	// s2: runs out of turns after an invalid call.
+	step("s2", "PLAN_START", nil)
+	step("s2", "PLAN_MODEL_RESPONSE", nil)
+	step("s2", "TOOL_CALL", map[string]any{"tool": "calendar"})
+	step("s2", "TOOL_ERROR", map[string]any{"tool": "calendar", "error": "bad", "validation": []string{"missing day"}})
+	step("s2", "PLAN_MODEL_RESPONSE", nil)
+
+	now := time.Now().UTC()
+	got, err := db.Stats(ctx, now.Add(-time.Hour), now.Add(time.Hour), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Runs != (RunStats{Total: 3, Failed: 1, MaxTurns: 1, AvgTurns: 5.0 / 3}) {
+		t.Fatalf("runs = %+v", got.Runs)
+	}
+	if len(got.PerDay) != 1 || got.PerDay[0].Date != now.Format(time.DateOnly) || got.PerDay[0].Total != 3 {
+		t.Fatalf("per day = %+v", got.PerDay)
+	}
+	if want := []ToolStats{{"web_search", 2, 1}, {"calendar", 1, 1}}; !reflect.DeepEqual(got.Tools, want) {
+		t.Fatalf("tools = %+v", got.Tools)
+	}
+	want := []ReasonCount{{"max_turns", 1}, {"plan_error", 1}, {"tool_budget", 1}, {"tool_validation", 1}}
+	if !reflect.DeepEqual(got.FailureReasons, want) {
+		t.Fatalf("failure reasons = %+v", got.FailureReasons)
+	}
+
+	// Nothing in an earlier window.
+	if empty, err := db.Stats(ctx, now.Add(-48*time.Hour), now.Add(-24*time.Hour), ""); err != nil || empty.Runs.Total != 0 || len(empty.Tools) != 0 {
+		t.Fatalf("earlier window = %+v, %v", empty, err)
+	}
+}
+
+func TestStats_SealedRows(t *testing.T) {
+	db, err := NewAuditDB(filepath.Join(t.TempDir(), "audit.db"))
+	if err != nil {
+		t.Fatalf("NewAuditDB: %v", err)
+	}
+	defer db.Close()
+	if err := db.EnableSessionKeys([]byte(strings.Repeat("k", 32))); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	_ = db.RecordStep(ctx, "", "s1", "PLAN_START", map[string]any{"prompt": "hi"})
+	_ = db.RecordStep(ctx, "", "s1", "TOOL_CALL", map[string]any{"tool": "web_search"})
+
+	now := time.Now().UTC()
+	got, err := db.Stats(ctx, now.Add(-time.Hour), now.Add(time.Hour), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Runs.Total != 1 || len(got.Tools) != 1 || got.Tools[0] != (ToolStats{Tool: "", Calls: 1}) {
+		t.Fatalf("stats over sealed rows = %+v", got)
+	}
+}
@@ -0,0 +1,399 @@
+// Command loadgen replays recorded prompts against the Agent Planner at a
+// configurable concurrency and reports latency percentiles, circuit-breaker
+// trips and Model Gateway token usage for the run.
+//
+// Prompts come from either a JSONL file of /plan request bodies (plain text
+// lines are accepted as bare prompts) or the PLAN_START rows of an audit DB:
+//
+//	loadgen -prompts prompts.jsonl -c 8 -n 200
+//	loadgen -audit-db ./agent_audit.db -c 4 -duration 2m
+//
+// Breaker trips are read from the planner's /metrics endpoint and token usage
+// from the gateway's /api/v1/usage endpoint; both are reported as deltas over
+// the run and skipped when the endpoint is unreachable.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"backend-go-agent-planner/agent"
+	"backend-go-agent-planner/audit"
+
+	"github.com/google/uuid"
+)
+
+type planRequest struct {
+	Prompt    string           `json:"prompt"`
+	SessionID string           `json:"session_id"`
+	Resources []agent.Resource `json:"resources,omitempty"`
+}
+
+type sample struct {
+	latency time.Duration
+	status  int
+	err     error
+}
+
+type tokenUsage struct {
+	Requests         int64 `json:"requests"`
+	PromptTokens     int64 `json:"prompt_tokens"`
+	CompletionTokens int64 `json:"completion_tokens"`
+	TotalTokens      int64 `json:"total_tokens"`
+}
+
+type report struct {
+	Requests      int            `json:"requests"`
+	Errors        int            `json:"errors"`
+	StatusCounts  map[string]int `json:"status_counts"`
+	DurationS     float64        `json:"duration_seconds"`
+	ThroughputRPS float64        `json:"throughput_rps"`
+	LatencyMs     map[string]int `json:"latency_ms"`
+	BreakerTrips  *float64       `json:"breaker_trips,omitempty"`
+	Tokens        *tokenUsage    `json:"gateway_tokens,omitempty"`
+}
+
+func getenv(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+func main() {
+	plannerURL := flag.String("planner-url", getenv("PAGI_PLANNER_URL", "http://localhost:8585"), "Agent Planner base URL")
+	apiKey := flag.String("api-key", os.Getenv("PAGI_API_KEY"), "Planner API key (X-API-Key)")
+	gatewayHTTPURL := flag.String("gateway-http-url", getenv("MODEL_GATEWAY_HTTP_URL", "http://localhost:8005"), "Model Gateway HTTP base URL (token usage); empty to skip")
+	promptsPath := flag.String("prompts", "", "JSONL file of recorded /plan requests (or one prompt per line)")
+	auditDBPath := flag.String("audit-db", "", "Replay PLAN_START prompts from this audit SQLite DB")
+	auditLimit := flag.Int("audit-limit", 1000, "Maximum prompts to load from -audit-db")
+	concurrency := flag.Int("c", 4, "Concurrent workers")
+	total := flag.Int("n", 100, "Total requests (ignored when -duration is set)")
+	duration := flag.Duration("duration", 0, "Run for this long instead of a fixed request count")
+	timeout := flag.Duration("timeout", 120*time.Second, "Per-request timeout")
+	sessionMode := flag.String("session-mode", "unique", "Session IDs: unique (fresh per request), recorded (as captured), or shared")
+	jsonOut := flag.Bool("json", false, "Print the report as JSON")
+	flag.Parse()
+
+	prompts, err := loadPrompts(*promptsPath, *auditDBPath, *auditLimit)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "loadgen: %v\n", err)
+		os.Exit(2)
+	}
+	if *concurrency < 1 {
+		*concurrency = 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if *duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *duration)
+		defer cancel()
+	}
+
+	planner := strings.TrimRight(*plannerURL, "/")
+	gateway := strings.TrimRight(*gatewayHTTPURL, "/")
+	client := &http.Client{Timeout: *timeout}
+
+	tripsBefore, tripsErr := scrapeBreakerTrips(client, planner+"/metrics")
+	usageBefore, usageErr := fetchUsage(client, gateway)
+
+	var next atomic.Int64
+	sharedSession := "loadgen-" + uuid.New().String()
+	var mu sync.Mutex
+	samples := make([]sample, 0, *total)
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for w := 0; w < *concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				i := next.Add(1) - 1
+				if *duration == 0 && i >= int64(*total) {
+					return
+				}
+				req := prompts[int(i)%len(prompts)]
+				switch *sessionMode {
+				case "shared":
+					req.SessionID = sharedSession
+				case "recorded":
+					if req.SessionID == "" {
+						req.SessionID = sharedSession
+					}
+				default:
+					req.SessionID = "loadgen-" + uuid.New().String()
+				}
+				s := doPlan(ctx, client, planner+"/plan", *apiKey, req)
+				if s.err != nil && ctx.Err() != nil {
+					// Cut short by -duration or Ctrl-C; not a server-side failure.
+					return
+				}
+				mu.Lock()
+				samples = append(samples, s)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	r := summarize(samples, elapsed)
+	if tripsErr == nil {
+		if after, err := scrapeBreakerTrips(client, planner+"/metrics"); err == nil {
+			d := after - tripsBefore
+			r.BreakerTrips = &d
+		}
+	}
+	if usageErr == nil && usageBefore != nil {
+		if after, err := fetchUsage(client, gateway); err == nil && after != nil {
+			r.Tokens = &tokenUsage{
+				Requests:         after.Requests - usageBefore.Requests,
+				PromptTokens:     after.PromptTokens - usageBefore.PromptTokens,
+				CompletionTokens: after.CompletionTokens - usageBefore.CompletionTokens,
+				TotalTokens:      after.TotalTokens - usageBefore.TotalTokens,
+			}
+		}
+	}
+
+	if *jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(r)
+	} else {
+		printReport(r)
+	}
+	if r.Requests > 0 && r.Errors == r.Requests {
+		os.Exit(1)
+	}
+}
+
+func loadPrompts(promptsPath, auditDBPath string, auditLimit int) ([]planRequest, error) {
+	var out []planRequest
+	switch {
+	case promptsPath != "":
+		f, err := os.Open(promptsPath)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		sc := bufio.NewScanner(f)
+		sc.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+		for sc.Scan() {
+			line := strings.TrimSpace(sc.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			var req planRequest
+			if strings.HasPrefix(line, "{") {
+				if err := json.Unmarshal([]byte(line), &req); err != nil {
+					return nil, fmt.Errorf("%s: %w", promptsPath, err)
+				}
+			} else {
+				req.Prompt = line
+			}
+			if strings.TrimSpace(req.Prompt) != "" {
+				out = append(out, req)
+			}
+		}
+		if err := sc.Err(); err != nil {
+			return nil, err
+		}
+
+	case auditDBPath != "":
+		db, err := audit.NewAuditDB(auditDBPath)
+		if err != nil {
+			return nil, err
+		}
+		defer db.Close()
+		entries, err := db.Query(context.Background(), audit.QueryFilter{EventType: "PLAN_START", Limit: auditLimit})
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			var data struct {
+				Prompt    string           `json:"prompt"`
+				Resources []agent.Resource `json:"resources"`
+			}
+			if json.Unmarshal(e.Data, &data) != nil || strings.TrimSpace(data.Prompt) == "" {
+				continue
+			}
+			out = append(out, planRequest{Prompt: data.Prompt, SessionID: e.SessionID, Resources: data.Resources})
+		}
+
+	default:
+		return nil, errors.New("one of -prompts or -audit-db is required")
+	}
+	if len(out) == 0 {
+		return nil, errors.New("no prompts loaded")
+	}
+	return out, nil
+}
+
+func doPlan(ctx context.Context, client *http.Client, url, apiKey string, body planRequest) sample {
+	b, _ := json.Marshal(body)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(b))
+	if err != nil {
+		return sample{err: err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		req.Header.Set("X-API-Key", apiKey)
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return sample{latency: time.Since(start), err: err}
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+	s := sample{latency: time.Since(start), status: resp.StatusCode}
+	if resp.StatusCode >= 300 {
+		s.err = fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return s
+}
+
+func summarize(samples []sample, elapsed time.Duration) report {
+	r := report{
+		Requests:     len(samples),
+		StatusCounts: map[string]int{},
+		DurationS:    elapsed.Seconds(),
+		LatencyMs:    map[string]int{},
+	}
+	lat := make([]time.Duration, 0, len(samples))
+	for _, s := range samples {
+		if s.err != nil {
+			r.Errors++
+		}
+		key := "error"
+		if s.status != 0 {
+			key = strconv.Itoa(s.status)
+		}
+		r.StatusCounts[key]++
+		lat = append(lat, s.latency)
+	}
+	if elapsed > 0 {
+		r.ThroughputRPS = float64(len(samples)) / elapsed.Seconds()
+	}
+	sort.Slice(lat, func(i, j int) bool { return lat[i] < lat[j] })
+	for _, p := range []struct {
+		name string
+		q    float64
+	}{{"p50", 0.50}, {"p95", 0.95}, {"p99", 0.99}, {"max", 1}} {
+		r.LatencyMs[p.name] = int(percentile(lat, p.q).Milliseconds())
+	}
+	return r
+}
+
+// percentile returns the nearest-rank percentile of an ascending slice.
+func percentile(sorted []time.Duration, q float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(q*float64(len(sorted))+0.5) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return sorted[idx]
+}
+
+// scrapeBreakerTrips sums agent_circuit_breaker_trips samples across all
+// dependencies from a Prometheus text exposition.
+func scrapeBreakerTrips(client *http.Client, url string) (float64, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("GET %s: HTTP %d", url, resp.StatusCode)
+	}
+	var sum float64
+	sc := bufio.NewScanner(resp.Body)
+	sc.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for sc.Scan() {
+		line := sc.Text()
+		if !strings.HasPrefix(line, "agent_circuit_breaker_trips") {
+			continue
+		}
+		name := line
+		if i := strings.IndexAny(line, "{ "); i >= 0 {
+			name = line[:i]
+		}
+		if strings.HasSuffix(name, "_created") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if v, err := strconv.ParseFloat(fields[len(fields)-1], 64); err == nil {
+			sum += v
+		}
+	}
+	return sum, sc.Err()
+}
+
+func fetchUsage(client *http.Client, gatewayURL string) (*tokenUsage, error) {
+	if gatewayURL == "" {
+		return nil, nil
+	}
+	resp, err := client.Get(gatewayURL + "/api/v1/usage")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET /api/v1/usage: HTTP %d", resp.StatusCode)
+	}
+	var u tokenUsage
+	if err := json.NewDecoder(resp.Body).Decode(&u); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+func printReport(r report) {
+	fmt.Printf("requests:     %d (%d errors) in %.1fs, %.2f req/s\n", r.Requests, r.Errors, r.DurationS, r.ThroughputRPS)
+	codes := make([]string, 0, len(r.StatusCounts))
+	for k := range r.StatusCounts {
+		codes = append(codes, k)
+	}
+	sort.Strings(codes)
+	parts := make([]string, 0, len(codes))
+	for _, k := range codes {
+		parts = append(parts, fmt.Sprintf("%s=%d", k, r.StatusCounts[k]))
+	}
+	fmt.Printf("status:       %s\n", strings.Join(parts, " "))
+	fmt.Printf("latency (ms): p50=%d p95=%d p99=%d max=%d\n", r.LatencyMs["p50"], r.LatencyMs["p95"], r.LatencyMs["p99"], r.LatencyMs["max"])
+	if r.BreakerTrips != nil {
+		fmt.Printf("breaker trips: %.0f\n", *r.BreakerTrips)
+	} else {
+		fmt.Println("breaker trips: n/a (planner /metrics unreachable)")
+	}
+	if r.Tokens != nil {
+		fmt.Printf("gateway tokens: prompt=%d completion=%d total=%d over %d completions\n",
+			r.Tokens.PromptTokens, r.Tokens.CompletionTokens, r.Tokens.TotalTokens, r.Tokens.Requests)
+	} else {
+		fmt.Println("gateway tokens: n/a (gateway /api/v1/usage unreachable)")
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"backend-go-agent-planner/audit"
+	"backend-go-model-gateway/pkg/client"
+
+	"github.com/spf13/cobra"
+)
+
+func newAuditCmd(opts *globalOptions) *cobra.Command {
+	var sessionID, traceID, eventType string
+	var since time.Duration
+	var limit int
+
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Query the planner audit log (GET /audit)",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			q := client.AuditQuery{SessionID: sessionID, TraceID: traceID, EventType: eventType, Limit: limit}
+			if since > 0 {
+				q.Since = time.Now().Add(-since)
+			}
+
+			ctx, cancel := context.WithTimeout(cmd.Context(), opts.timeout)
+			defer cancel()
+
+			entries, err := opts.planner().Audit(ctx, q)
+			if err != nil {
+				return err
+			}
+			if opts.output == "json" {
+				return printJSON(map[string]any{"entries": entries, "count": len(entries)})
+			}
+
+			tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(tw, "ID\tTIMESTAMP\tSESSION\tEVENT\tDATA")
+			for _, e := range entries {
+				data := string(e.Data)
+				if len(data) > 120 {
+					data = data[:117] + "..."
+				}
+				fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n", e.ID, e.Timestamp.Format(time.RFC3339), e.SessionID, e.EventType, data)
+			}
+			return tw.Flush()
+		},
+	}
+
+	cmd.Flags().StringVarP(&sessionID, "session", "s", "", "Filter by session ID")
+	cmd.Flags().StringVar(&traceID, "trace", "", "Filter by trace ID")
+	cmd.Flags().StringVar(&eventType, "event", "", "Filter by event type (e.g. TOOL_CALL)")
+	cmd.Flags().DurationVar(&since, "since", 0, "Only rows newer than this duration (e.g. 1h)")
+	cmd.Flags().IntVar(&limit, "limit", 100, "Maximum rows to return")
+
+	cmd.AddCommand(newAuditBundleCmd(opts), newAuditVerifyCmd())
+	return cmd
+}
+
+func newAuditBundleCmd(opts *globalOptions) *cobra.Command {
+	var sessionID, file, publicKey string
+	var since, until time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "bundle",
+		Short: "Download a signed compliance bundle (POST /audit/bundle)",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			now := time.Now().UTC().Truncate(time.Second)
+			req := client.BundleRequest{SessionID: sessionID}
+			if since > 0 {
+				t := now.Add(-since)
+				req.Since = &t
+			}
+			if until > 0 {
+				t := now.Add(-until)
+				req.Until = &t
+			}
+
+			ctx, cancel := context.WithTimeout(cmd.Context(), opts.timeout)
+			defer cancel()
+
+			zipped, err := opts.planner().AuditBundle(ctx, req)
+			if err != nil {
+				return err
+			}
+			if publicKey != "" {
+				if _, err := verifyBundle(zipped, publicKey); err != nil {
+					return err
+				}
+			}
+			if file == "" {
+				file = fmt.Sprintf("pagi-audit-%s.zip", now.Format("20060102T150405Z"))
+			}
+			if err := os.WriteFile(file, zipped, 0o600); err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "wrote %s (%d bytes)\n", file, len(zipped))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&sessionID, "session", "s", "", "Export this session")
+	cmd.Flags().DurationVar(&since, "since", 0, "Only rows newer than this duration (e.g. 720h)")
+	cmd.Flags().DurationVar(&until, "until", 0, "Only rows older than this duration")
+	cmd.Flags().StringVarP(&file, "file", "f", "", "Output file (default pagi-audit-<time>.zip)")
+	cmd.Flags().StringVar(&publicKey, "public-key", os.Getenv("PAGI_AUDIT_PUBLIC_KEY"), "Verify against this base64 Ed25519 public key (env PAGI_AUDIT_PUBLIC_KEY)")
+	return cmd
+}
+
+func newAuditVerifyCmd() *cobra.Command {
+	var publicKey string
+
+	cmd := &cobra.Command{
+		Use:   "verify <bundle.zip>",
+		Short: "Check a compliance bundle's signature and file hashes",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			if publicKey == "" {
+				return fmt.Errorf("--public-key (or PAGI_AUDIT_PUBLIC_KEY) is required")
+			}
+			zipped, err := os.ReadFile(args[0])
+			if err != nil {
+				return err
+			}
+			m, err := verifyBundle(zipped, publicKey)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("%s: OK (created %s, %d files)\n", args[0], m.CreatedAt.Format(time.RFC3339), len(m.Files))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&publicKey, "public-key", os.Getenv("PAGI_AUDIT_PUBLIC_KEY"), "Base64 Ed25519 public key (env PAGI_AUDIT_PUBLIC_KEY)")
+	return cmd
+}
+
+func verifyBundle(zipped []byte, publicKey string) (*audit.Manifest, error) {
+	pub, err := base64.StdEncoding.DecodeString(strings.TrimSpace(publicKey))
+	if err != nil {
+		return nil, fmt.Errorf("public key: not base64: %w", err)
+	}
+	return audit.VerifyBundle(bytes.NewReader(zipped), int64(len(zipped)), ed25519.PublicKey(pub))
+}
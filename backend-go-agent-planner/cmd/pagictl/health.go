@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"backend-go-model-gateway/pkg/discovery"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	grpc_health_v1 "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+type healthResult struct {
+	Name      string `json:"name"`
+	Target    string `json:"target"`
+	Healthy   bool   `json:"healthy"`
+	LatencyMs int64  `json:"latency_ms"`
+	Detail    string `json:"detail,omitempty"`
+}
+
+func newHealthCmd(opts *globalOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "health",
+		Short: "Check health of planner, gateway, memory service and BFF",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			ctx, cancel := context.WithTimeout(cmd.Context(), 10*time.Second)
+			defer cancel()
+
+			checks := []func(context.Context) healthResult{
+				func(ctx context.Context) healthResult {
+					return checkHTTP(ctx, "agent-planner", strings.TrimRight(opts.plannerURL, "/")+"/health")
+				},
+				func(ctx context.Context) healthResult {
+					return checkGRPC(ctx, "model-gateway", opts.gatewayAddr)
+				},
+				func(ctx context.Context) healthResult {
+					return checkHTTP(ctx, "memory-service", strings.TrimRight(opts.memoryURL, "/")+"/health")
+				},
+				func(ctx context.Context) healthResult {
+					return checkHTTP(ctx, "bff", strings.TrimRight(opts.bffURL, "/")+"/health")
+				},
+			}
+
+			results := make([]healthResult, len(checks))
+			var wg sync.WaitGroup
+			for i, check := range checks {
+				wg.Add(1)
+				go func(i int, check func(context.Context) healthResult) {
+					defer wg.Done()
+					results[i] = check(ctx)
+				}(i, check)
+			}
+			wg.Wait()
+
+			allHealthy := true
+			for _, r := range results {
+				allHealthy = allHealthy && r.Healthy
+			}
+
+			if opts.output == "json" {
+				if err := printJSON(map[string]any{"healthy": allHealthy, "services": results}); err != nil {
+					return err
+				}
+			} else {
+				tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+				fmt.Fprintln(tw, "SERVICE\tSTATUS\tLATENCY\tTARGET\tDETAIL")
+				for _, r := range results {
+					status := "UP"
+					if !r.Healthy {
+						status = "DOWN"
+					}
+					fmt.Fprintf(tw, "%s\t%s\t%dms\t%s\t%s\n", r.Name, status, r.LatencyMs, r.Target, r.Detail)
+				}
+				_ = tw.Flush()
+			}
+
+			if !allHealthy {
+				return fmt.Errorf("one or more services are unhealthy")
+			}
+			return nil
+		},
+	}
+}
+
+func checkHTTP(ctx context.Context, name, url string) healthResult {
+	start := time.Now()
+	res := healthResult{Name: name, Target: url}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		res.Detail = err.Error()
+		return res
+	}
+	resp, err := http.DefaultClient.Do(req)
+	res.LatencyMs = time.Since(start).Milliseconds()
+	if err != nil {
+		res.Detail = err.Error()
+		return res
+	}
+	defer resp.Body.Close()
+	res.Healthy = resp.StatusCode == http.StatusOK
+	res.Detail = resp.Status
+	return res
+}
+
+func checkGRPC(ctx context.Context, name, addr string) healthResult {
+	start := time.Now()
+	res := healthResult{Name: name, Target: addr}
+	opts := append(discovery.DialOptions(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.NewClient(addr, opts...)
+	if err != nil {
+		res.Detail = err.Error()
+		return res
+	}
+	defer conn.Close()
+
+	resp, err := grpc_health_v1.NewHealthClient(conn).Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+	res.LatencyMs = time.Since(start).Milliseconds()
+	if err != nil {
+		res.Detail = err.Error()
+		return res
+	}
+	res.Healthy = resp.GetStatus() == grpc_health_v1.HealthCheckResponse_SERVING
+	res.Detail = resp.GetStatus().String()
+	return res
+}
@@ -0,0 +1,102 @@
+// Command pagictl is a command-line client for the PAGI agent stack.
+//
+// It wraps the HTTP/gRPC surfaces that otherwise require hand-rolled curl or
+// grpcurl invocations:
+//
+//	pagictl plan --session s1 "what is on my calendar today?"
+//	pagictl notifications tail --session s1
+//	pagictl audit --session s1 --event TOOL_CALL
+//	pagictl audit bundle --session s1 -f s1.zip
+//	pagictl session list --tag health --since 24h
+//	pagictl vector-test "morning routine" -k 3
+//	pagictl rag-eval knowledge_bases/golden_queries.jsonl -k 5
+//	pagictl health
+//
+// Endpoints default to the docker-compose ports and can be overridden with
+// flags or the environment variables listed in `pagictl --help`.
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	"backend-go-model-gateway/pkg/client"
+
+	"github.com/spf13/cobra"
+)
+
+type globalOptions struct {
+	plannerURL     string
+	apiKey         string
+	gatewayAddr    string
+	gatewayHTTPURL string
+	memoryURL      string
+	bffURL         string
+	redisAddr      string
+	timeout        time.Duration
+	output         string
+}
+
+func getenv(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+func main() {
+	if err := newRootCmd().Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+func newRootCmd() *cobra.Command {
+	opts := &globalOptions{}
+
+	root := &cobra.Command{
+		Use:           "pagictl",
+		Short:         "Command-line client for the PAGI agent planner, model gateway and friends",
+		SilenceUsage:  true,
+		SilenceErrors: false,
+	}
+
+	pf := root.PersistentFlags()
+	pf.StringVar(&opts.plannerURL, "planner-url", getenv("PAGI_PLANNER_URL", "http://localhost:8585"), "Agent Planner base URL (env PAGI_PLANNER_URL)")
+	pf.StringVar(&opts.apiKey, "api-key", os.Getenv("PAGI_API_KEY"), "Planner API key (env PAGI_API_KEY)")
+	pf.StringVar(&opts.gatewayAddr, "gateway-addr", getenv("MODEL_GATEWAY_ADDR", "localhost:50051"), "Model Gateway gRPC address (env MODEL_GATEWAY_ADDR)")
+	pf.StringVar(&opts.gatewayHTTPURL, "gateway-http-url", getenv("MODEL_GATEWAY_HTTP_URL", "http://localhost:8005"), "Model Gateway HTTP base URL (env MODEL_GATEWAY_HTTP_URL)")
+	pf.StringVar(&opts.memoryURL, "memory-url", getenv("MEMORY_URL", "http://localhost:8003"), "Memory Service HTTP base URL (env MEMORY_URL)")
+	pf.StringVar(&opts.bffURL, "bff-url", getenv("GO_BFF_URL", "http://localhost:8002"), "BFF base URL (env GO_BFF_URL)")
+	pf.StringVar(&opts.redisAddr, "redis-addr", getenv("REDIS_ADDR", "localhost:6379"), "Redis address for notification tailing (env REDIS_ADDR)")
+	pf.DurationVar(&opts.timeout, "timeout", 120*time.Second, "Request timeout")
+	pf.StringVarP(&opts.output, "output", "o", "text", "Output format: text or json")
+
+	root.AddCommand(
+		newPlanCmd(opts),
+		newNotificationsCmd(opts),
+		newAuditCmd(opts),
+		newSessionCmd(opts),
+		newVectorTestCmd(opts),
+		newRAGEvalCmd(opts),
+		newHealthCmd(opts),
+	)
+	return root
+}
+
+// planner returns a client for the planner's HTTP API.
+func (o *globalOptions) planner() *client.Planner {
+	return client.NewPlanner(o.plannerURL, client.Options{APIKey: o.apiKey})
+}
+
+// gatewayHTTP returns a client for the gateway's HTTP endpoints.
+func (o *globalOptions) gatewayHTTP() *client.Gateway {
+	return client.NewGateway(nil, o.gatewayHTTPURL, client.Options{})
+}
+
+// printJSON writes v as indented JSON to stdout.
+func printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
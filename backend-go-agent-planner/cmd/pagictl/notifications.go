@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/spf13/cobra"
+)
+
+func newNotificationsCmd(opts *globalOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "notifications",
+		Short: "Work with planner notifications",
+	}
+
+	var sessionID, source, channel string
+	tail := &cobra.Command{
+		Use:   "tail",
+		Short: "Stream notifications (planner SSE or Redis pub/sub) until interrupted",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+			defer stop()
+
+			emit := func(payload string) {
+				if sessionID != "" {
+					var p struct {
+						SessionID string `json:"session_id"`
+					}
+					if json.Unmarshal([]byte(payload), &p) != nil || p.SessionID != sessionID {
+						return
+					}
+				}
+				fmt.Println(payload)
+			}
+
+			switch source {
+			case "redis":
+				rdb := redis.NewClient(&redis.Options{Addr: opts.redisAddr})
+				defer func() { _ = rdb.Close() }()
+				sub := rdb.Subscribe(ctx, channel)
+				defer func() { _ = sub.Close() }()
+				if _, err := sub.Receive(ctx); err != nil {
+					return fmt.Errorf("subscribe %s on %s: %w", channel, opts.redisAddr, err)
+				}
+				fmt.Fprintf(os.Stderr, "tailing redis channel %s on %s (Ctrl-C to stop)\n", channel, opts.redisAddr)
+				for {
+					select {
+					case <-ctx.Done():
+						return nil
+					case msg, ok := <-sub.Channel():
+						if !ok {
+							return nil
+						}
+						emit(msg.Payload)
+					}
+				}
+
+			case "sse":
+				u := strings.TrimRight(opts.plannerURL, "/") + "/notifications/stream"
+				if sessionID != "" {
+					u += "?session_id=" + url.QueryEscape(sessionID)
+				}
+				req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+				if err != nil {
+					return err
+				}
+				req.Header.Set("Accept", "text/event-stream")
+				if opts.apiKey != "" {
+					req.Header.Set("X-API-Key", opts.apiKey)
+				}
+				resp, err := http.DefaultClient.Do(req)
+				if err != nil {
+					if ctx.Err() != nil {
+						return nil
+					}
+					return err
+				}
+				defer resp.Body.Close()
+				if resp.StatusCode != http.StatusOK {
+					return fmt.Errorf("GET %s: HTTP %d", u, resp.StatusCode)
+				}
+				fmt.Fprintf(os.Stderr, "tailing %s (Ctrl-C to stop)\n", u)
+				scanner := bufio.NewScanner(resp.Body)
+				scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+				for scanner.Scan() {
+					if data, ok := strings.CutPrefix(scanner.Text(), "data: "); ok {
+						emit(data)
+					}
+				}
+				if ctx.Err() != nil {
+					return nil
+				}
+				return scanner.Err()
+
+			default:
+				return fmt.Errorf("unknown --source %q (want sse or redis)", source)
+			}
+		},
+	}
+	tail.Flags().StringVarP(&sessionID, "session", "s", "", "Only show notifications for this session")
+	tail.Flags().StringVar(&source, "source", "sse", "Notification source: sse (planner) or redis")
+	tail.Flags().StringVar(&channel, "channel", getenv("PAGI_NOTIFICATIONS_CHANNEL", "pagi_notifications"), "Redis channel (with --source redis)")
+
+	cmd.AddCommand(tail)
+	return cmd
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"backend-go-model-gateway/pkg/client"
+	"backend-go-model-gateway/pkg/ragfilter"
+
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+)
+
+func newPlanCmd(opts *globalOptions) *cobra.Command {
+	var sessionID, priority string
+	var resources, tags []string
+	var filter ragfilter.Filter
+
+	cmd := &cobra.Command{
+		Use:   "plan [prompt]",
+		Short: "Submit a prompt to the Agent Planner (POST /plan)",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if sessionID == "" {
+				sessionID = "pagictl-" + uuid.New().String()
+			}
+
+			req := client.PlanRequest{
+				Prompt:    strings.Join(args, " "),
+				SessionID: sessionID,
+				Tags:      tags,
+				Priority:  priority,
+			}
+			for _, r := range resources {
+				typ, uri, ok := strings.Cut(r, "=")
+				if !ok || typ == "" || uri == "" {
+					return fmt.Errorf("invalid --resource %q (want type=uri)", r)
+				}
+				req.Resources = append(req.Resources, client.Resource{Type: typ, URI: uri})
+			}
+			if !filter.IsZero() {
+				req.RAGFilter = &filter
+			}
+
+			ctx, cancel := context.WithTimeout(cmd.Context(), opts.timeout)
+			defer cancel()
+
+			resp, err := opts.planner().Plan(ctx, req)
+			if err != nil {
+				return err
+			}
+			if opts.output == "json" {
+				return printJSON(resp)
+			}
+			fmt.Printf("session: %s\n", sessionID)
+			fmt.Printf("result:  %v\n", resp.Result)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&sessionID, "session", "s", "", "Session ID (default: random)")
+	cmd.Flags().StringArrayVar(&resources, "resource", nil, "Attach a resource as type=uri (repeatable)")
+	cmd.Flags().StringArrayVarP(&tags, "session-tag", "t", nil, "Tag the session, e.g. project:offsite (repeatable)")
+	cmd.Flags().StringVar(&priority, "priority", "", "interactive (default) or batch; batch waits behind chat for model capacity")
+	cmd.Flags().StringSliceVar(&filter.Sources, "source", nil, "Only retrieve documents from these sources")
+	cmd.Flags().StringSliceVar(&filter.Tags, "tag", nil, "Only retrieve documents carrying all of these tags")
+	cmd.Flags().StringSliceVar(&filter.DocumentIDs, "document", nil, "Only retrieve chunks of these document IDs")
+	cmd.Flags().IntVar(&filter.WithinDays, "within-days", 0, "Only retrieve documents created in the last N days")
+	return cmd
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"backend-go-model-gateway/pkg/client"
+
+	"github.com/spf13/cobra"
+)
+
+// goldenQuery is one line of a golden query set: a query and the matches that
+// should answer it, identified by ID or by source document.
+type goldenQuery struct {
+	Query string `json:"query"`
+	// KB defaults to Body-KB, the gateway's default.
+	KB              string   `json:"kb"`
+	RelevantIDs     []string `json:"relevant_ids"`
+	RelevantSources []string `json:"relevant_sources"`
+}
+
+// evalResult is the outcome of one golden query.
+type evalResult struct {
+	Query string `json:"query"`
+	KB    string `json:"kb"`
+	// Recall is the share of relevant IDs and sources found in the top k.
+	Recall float64 `json:"recall"`
+	// Rank is the 1-based rank of the first relevant match, 0 if none.
+	Rank  int    `json:"rank"`
+	Error string `json:"error,omitempty"`
+}
+
+// evalSummary aggregates results for one KB, or for all of them.
+type evalSummary struct {
+	KB      string  `json:"kb"`
+	Queries int     `json:"queries"`
+	Errors  int     `json:"errors"`
+	Recall  float64 `json:"recall_at_k"`
+	MRR     float64 `json:"mrr"`
+}
+
+func newRAGEvalCmd(opts *globalOptions) *cobra.Command {
+	var k int
+	var minScore float64
+
+	cmd := &cobra.Command{
+		Use:   "rag-eval [golden.jsonl]",
+		Short: "Score retrieval against a golden query set (recall@k and MRR per KB)",
+		Long: `Runs every query in a golden set through the Model Gateway's retrieval stack
+(GET /api/v1/vector-test) and reports recall@k and mean reciprocal rank per KB.
+
+The golden set is JSONL, one query per line:
+
+  {"query": "...", "kb": "Domain-KB", "relevant_ids": ["..."], "relevant_sources": ["..."]}
+
+A match is relevant when its ID or its source is listed. Compare runs before
+and after changing the backend, RAG_RETRIEVAL_MODE, RAG_RERANKER or
+embeddings; --min-score previews a RAG_MIN_SCORE value. Set RAG_CACHE_SIZE=0
+on the gateway so repeated runs are not served from the cache.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			golden, err := readGoldenQueries(args[0])
+			if err != nil {
+				return err
+			}
+			minScoreSet := cmd.Flags().Changed("min-score")
+
+			results := make([]evalResult, len(golden))
+			for i, g := range golden {
+				ids, err := opts.retrieveIDs(cmd.Context(), g, k, minScore, minScoreSet)
+				results[i] = scoreGoldenQuery(g, ids)
+				if err != nil {
+					results[i].Error = err.Error()
+				}
+			}
+			summaries := summarizeEval(results)
+
+			if opts.output == "json" {
+				return printJSON(map[string]any{"k": k, "summary": summaries, "results": results})
+			}
+			tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintf(tw, "KB\tQUERIES\tERRORS\tRECALL@%d\tMRR\n", k)
+			for _, s := range summaries {
+				fmt.Fprintf(tw, "%s\t%d\t%d\t%.3f\t%.3f\n", s.KB, s.Queries, s.Errors, s.Recall, s.MRR)
+			}
+			if err := tw.Flush(); err != nil {
+				return err
+			}
+			for _, r := range results {
+				if r.Error != "" {
+					fmt.Fprintf(os.Stderr, "error: %s %q: %s\n", r.KB, r.Query, r.Error)
+				}
+			}
+			return nil
+		},
+	}
+	cmd.Flags().IntVarP(&k, "k", "k", 5, "Top-k matches retrieved per query")
+	cmd.Flags().Float64Var(&minScore, "min-score", 0, "Ignore matches scoring below this (default: keep all)")
+	return cmd
+}
+
+func readGoldenQueries(path string) ([]goldenQuery, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var golden []goldenQuery
+	sc := bufio.NewScanner(f)
+	for line := 1; sc.Scan(); line++ {
+		if strings.TrimSpace(sc.Text()) == "" {
+			continue
+		}
+		var g goldenQuery
+		if err := json.Unmarshal(sc.Bytes(), &g); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		if g.Query == "" || len(g.RelevantIDs)+len(g.RelevantSources) == 0 {
+			return nil, fmt.Errorf("%s:%d: want a query and at least one relevant_ids or relevant_sources entry", path, line)
+		}
+		if g.KB == "" {
+			g.KB = "Body-KB"
+		}
+		golden = append(golden, g)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(golden) == 0 {
+		return nil, fmt.Errorf("%s: no golden queries", path)
+	}
+	return golden, nil
+}
+
+// retrieveIDs runs one golden query and returns the ID and source of each
+// match, best first.
+func (o *globalOptions) retrieveIDs(ctx context.Context, g goldenQuery, k int, minScore float64, minScoreSet bool) ([][2]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, o.timeout)
+	defer cancel()
+
+	matches, err := o.gatewayHTTP().VectorTest(ctx, client.VectorQuery{Query: g.Query, K: k, KB: g.KB})
+	if err != nil {
+		return nil, err
+	}
+	ids := make([][2]string, 0, len(matches))
+	for _, m := range matches {
+		if minScoreSet && m.Score < minScore {
+			continue
+		}
+		ids = append(ids, [2]string{m.ID, m.Source})
+	}
+	return ids, nil
+}
+
+// scoreGoldenQuery computes recall and the first relevant rank for one query
+// from its retrieved (ID, source) pairs.
+func scoreGoldenQuery(g goldenQuery, retrieved [][2]string) evalResult {
+	r := evalResult{Query: g.Query, KB: g.KB}
+	found := 0
+	for _, id := range g.RelevantIDs {
+		if slices.ContainsFunc(retrieved, func(m [2]string) bool { return m[0] == id }) {
+			found++
+		}
+	}
+	for _, src := range g.RelevantSources {
+		if slices.ContainsFunc(retrieved, func(m [2]string) bool { return m[1] == src }) {
+			found++
+		}
+	}
+	r.Recall = float64(found) / float64(len(g.RelevantIDs)+len(g.RelevantSources))
+	for i, m := range retrieved {
+		if slices.Contains(g.RelevantIDs, m[0]) || slices.Contains(g.RelevantSources, m[1]) {
+			r.Rank = i + 1
+			break
+		}
+	}
+	return r
+}
+
+// summarizeEval averages results per KB, in first-seen order, followed by an
+// "ALL" row. Failed queries count as misses.
+func summarizeEval(results []evalResult) []evalSummary {
+	var order []string
+	byKB := map[string]*evalSummary{}
+	all := &evalSummary{KB: "ALL"}
+	for _, r := range results {
+		s, ok := byKB[r.KB]
+		if !ok {
+			s = &evalSummary{KB: r.KB}
+			byKB[r.KB] = s
+			order = append(order, r.KB)
+		}
+		for _, s := range []*evalSummary{s, all} {
+			s.Queries++
+			if r.Error != "" {
+				s.Errors++
+			}
+			s.Recall += r.Recall
+			if r.Rank > 0 {
+				s.MRR += 1 / float64(r.Rank)
+			}
+		}
+	}
+
+	out := make([]evalSummary, 0, len(order)+1)
+	for _, kb := range order {
+		out = append(out, *byKB[kb])
+	}
+	out = append(out, *all)
+	for i := range out {
+		out[i].Recall /= float64(out[i].Queries)
+		out[i].MRR /= float64(out[i].Queries)
+	}
+	return out
+}
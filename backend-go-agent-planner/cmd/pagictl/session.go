@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"backend-go-model-gateway/pkg/client"
+
+	"github.com/spf13/cobra"
+)
+
+func newSessionCmd(opts *globalOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "session",
+		Short: "Export, import, tag, search and delete sessions",
+	}
+	cmd.AddCommand(newSessionExportCmd(opts), newSessionImportCmd(opts), newSessionListCmd(opts), newSessionTagCmd(opts), newSessionForgetCmd(opts))
+	return cmd
+}
+
+func newSessionListCmd(opts *globalOptions) *cobra.Command {
+	var tags []string
+	var since time.Duration
+	var limit int
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List sessions by tag and recent activity (GET /sessions)",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			q := client.SessionQuery{Tags: tags, Limit: limit}
+			if since > 0 {
+				q.Since = time.Now().Add(-since)
+			}
+
+			ctx, cancel := context.WithTimeout(cmd.Context(), opts.timeout)
+			defer cancel()
+
+			sessions, err := opts.planner().Sessions(ctx, q)
+			if err != nil {
+				return err
+			}
+			if opts.output == "json" {
+				return printJSON(map[string]any{"sessions": sessions, "count": len(sessions)})
+			}
+
+			tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(tw, "SESSION\tLAST SEEN\tEVENTS\tTAGS")
+			for _, s := range sessions {
+				fmt.Fprintf(tw, "%s\t%s\t%d\t%s\n", s.SessionID, s.LastSeen.Format(time.RFC3339), s.Events, strings.Join(s.Tags, ","))
+			}
+			return tw.Flush()
+		},
+	}
+
+	cmd.Flags().StringArrayVarP(&tags, "tag", "t", nil, "Only sessions with this tag (repeatable; all must match)")
+	cmd.Flags().DurationVar(&since, "since", 0, "Only sessions active within this duration (e.g. 24h)")
+	cmd.Flags().IntVar(&limit, "limit", 100, "Maximum sessions to return")
+	return cmd
+}
+
+func newSessionTagCmd(opts *globalOptions) *cobra.Command {
+	var remove bool
+
+	cmd := &cobra.Command{
+		Use:   "tag <session-id> <tag>...",
+		Short: "Add tags to a session, or remove them with --remove (/sessions/{id}/tags)",
+		Args:  cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := context.WithTimeout(cmd.Context(), opts.timeout)
+			defer cancel()
+
+			planner := opts.planner()
+			var tags []string
+			var err error
+			if remove {
+				for _, tag := range args[1:] {
+					if tags, err = planner.RemoveSessionTag(ctx, args[0], tag); err != nil {
+						return err
+					}
+				}
+			} else if tags, err = planner.AddSessionTags(ctx, args[0], args[1:]...); err != nil {
+				return err
+			}
+			if opts.output == "json" {
+				return printJSON(map[string]any{"session_id": args[0], "tags": tags})
+			}
+			fmt.Printf("%s: %s\n", args[0], strings.Join(tags, ","))
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&remove, "remove", false, "Remove the tags instead of adding them")
+	return cmd
+}
+
+func newSessionExportCmd(opts *globalOptions) *cobra.Command {
+	var file string
+
+	cmd := &cobra.Command{
+		Use:   "export <session-id>",
+		Short: "Download a session archive (GET /sessions/{id}/export)",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := context.WithTimeout(cmd.Context(), opts.timeout)
+			defer cancel()
+
+			archive, err := opts.planner().ExportSession(ctx, args[0])
+			if err != nil {
+				return err
+			}
+			if file == "" {
+				file = fmt.Sprintf("pagi-session-%s.json", args[0])
+			}
+			if err := os.WriteFile(file, archive, 0o600); err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "wrote %s (%d bytes)\n", file, len(archive))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&file, "file", "f", "", "Output file (default pagi-session-<id>.json)")
+	return cmd
+}
+
+func newSessionImportCmd(opts *globalOptions) *cobra.Command {
+	var as string
+
+	cmd := &cobra.Command{
+		Use:   "import <archive.json>",
+		Short: "Recreate a session from an archive (POST /sessions/import)",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			raw, err := os.ReadFile(args[0])
+			if err != nil {
+				return err
+			}
+			if !json.Valid(raw) {
+				return fmt.Errorf("%s is not a JSON session archive", args[0])
+			}
+
+			ctx, cancel := context.WithTimeout(cmd.Context(), opts.timeout)
+			defer cancel()
+
+			resp, err := opts.planner().ImportSession(ctx, raw, as)
+			if err != nil {
+				return err
+			}
+			if opts.output == "json" {
+				return printJSON(resp)
+			}
+			fmt.Printf("imported %s as %s: %d history messages, %d playbooks, %d audit rows\n",
+				resp.From, resp.SessionID, resp.History, resp.Playbooks, resp.AuditRows)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&as, "as", "", "Import under this session ID instead of the archived one")
+	return cmd
+}
+
+func newSessionForgetCmd(opts *globalOptions) *cobra.Command {
+	var file string
+
+	cmd := &cobra.Command{
+		Use:   "forget <session-id>",
+		Short: "Delete a session everywhere and save the signed receipt (DELETE /sessions/{id}/data)",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := context.WithTimeout(cmd.Context(), opts.timeout)
+			defer cancel()
+
+			receipt, err := opts.planner().ForgetSession(ctx, args[0])
+			if err != nil {
+				return err
+			}
+			if file == "" {
+				file = fmt.Sprintf("pagi-deletion-%s.json", args[0])
+			}
+			b, _ := json.MarshalIndent(receipt, "", "  ")
+			if err := os.WriteFile(file, b, 0o600); err != nil {
+				return err
+			}
+			if opts.output == "json" {
+				return printJSON(receipt)
+			}
+			fmt.Printf("deleted %s: %d audit rows, %d notifications, %d memory messages, %d playbooks (key erased: %t)\n",
+				receipt.SessionID, receipt.Deleted["audit_rows"], receipt.Deleted["notifications"],
+				receipt.Deleted["memory_messages"], receipt.Deleted["memory_playbooks"], receipt.KeyErased)
+			fmt.Fprintf(os.Stderr, "wrote receipt %s\n", file)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&file, "file", "f", "", "Receipt file (default pagi-deletion-<id>.json)")
+	return cmd
+}
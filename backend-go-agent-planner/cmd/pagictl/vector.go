@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"backend-go-model-gateway/pkg/client"
+
+	"github.com/spf13/cobra"
+)
+
+func newVectorTestCmd(opts *globalOptions) *cobra.Command {
+	var k int
+
+	cmd := &cobra.Command{
+		Use:   "vector-test [query]",
+		Short: "Run a retrieval query against the Model Gateway vector-test endpoint",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := context.WithTimeout(cmd.Context(), opts.timeout)
+			defer cancel()
+
+			matches, err := opts.gatewayHTTP().VectorTest(ctx, client.VectorQuery{Query: strings.Join(args, " "), K: k})
+			if err != nil {
+				return err
+			}
+			if opts.output == "json" {
+				return printJSON(matches)
+			}
+
+			tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(tw, "SCORE\tKB\tID\tTEXT")
+			for _, m := range matches {
+				text := strings.ReplaceAll(m.Text, "\n", " ")
+				if len(text) > 100 {
+					text = text[:97] + "..."
+				}
+				fmt.Fprintf(tw, "%.3f\t%s\t%s\t%s\n", m.Score, m.KnowledgeBase, m.ID, text)
+			}
+			return tw.Flush()
+		},
+	}
+	cmd.Flags().IntVarP(&k, "k", "k", 3, "Top-k matches")
+	return cmd
+}
@@ -1,19 +1,30 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"os"
-	"os/signal"
+	"regexp"
+	"strconv"
 	"strings"
-	"syscall"
 	"time"
 
 	"backend-go-agent-planner/agent"
+	"backend-go-agent-planner/audit"
 	"backend-go-agent-planner/internal/logger"
+	"backend-go-model-gateway/pkg/admin"
+	"backend-go-model-gateway/pkg/buildinfo"
+	"backend-go-model-gateway/pkg/envelope"
+	"backend-go-model-gateway/pkg/lifecycle"
+	"backend-go-model-gateway/pkg/ragfilter"
+	"backend-go-model-gateway/pkg/secrets"
+	"backend-go-model-gateway/pkg/tracing"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -30,14 +41,16 @@ import (
 
 	promclient "github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 )
 
+// defaultServiceName identifies the planner in GET /version, GET /admin/status
+// and traces (where OTEL_SERVICE_NAME overrides it).
+const defaultServiceName = "backend-go-agent-planner"
+
 func initOpenTelemetry(ctx context.Context) (shutdown func(context.Context) error, promHandler http.Handler, err error) {
 	serviceName := os.Getenv("OTEL_SERVICE_NAME")
 	if strings.TrimSpace(serviceName) == "" {
-		serviceName = "backend-go-agent-planner"
+		serviceName = defaultServiceName
 	}
 
 	res, err := sdkresource.Merge(
@@ -51,17 +64,12 @@ func initOpenTelemetry(ctx context.Context) (shutdown func(context.Context) erro
 		return nil, nil, err
 	}
 
-	// --- Tracing (OTLP/gRPC exporter) ---
-	otlpEndpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
-	if strings.TrimSpace(otlpEndpoint) == "" {
-		otlpEndpoint = "localhost:4317"
+	// --- Tracing (OTLP/gRPC exporter; endpoint, TLS and sampler per pkg/tracing) ---
+	traceCfg, err := tracing.FromEnv()
+	if err != nil {
+		return nil, nil, err
 	}
-
-	traceExp, err := otlptracegrpc.New(
-		ctx,
-		otlptracegrpc.WithEndpoint(otlpEndpoint),
-		otlptracegrpc.WithDialOption(grpc.WithTransportCredentials(insecure.NewCredentials())),
-	)
+	traceExp, err := otlptracegrpc.New(ctx, traceCfg.ExporterOptions()...)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -69,6 +77,7 @@ func initOpenTelemetry(ctx context.Context) (shutdown func(context.Context) erro
 	tp := trace.NewTracerProvider(
 		trace.WithBatcher(traceExp),
 		trace.WithResource(res),
+		trace.WithSampler(traceCfg.Sampler),
 	)
 	otel.SetTracerProvider(tp)
 	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
@@ -100,57 +109,161 @@ func initOpenTelemetry(ctx context.Context) (shutdown func(context.Context) erro
 
 // apiKeyMiddleware validates the X-API-Key header against the configured API key.
 // This is a critical security control for production deployments.
-// If PAGI_API_KEY is not set, authentication is DISABLED (dev mode only).
-func apiKeyMiddleware(next http.Handler) http.Handler {
-	apiKey := os.Getenv("PAGI_API_KEY")
-	authEnabled := strings.TrimSpace(apiKey) != ""
+// If neither PAGI_API_KEY nor PAGI_TENANT_API_KEYS is set, authentication is
+// DISABLED (dev mode only).
+//
+// PAGI_TENANT_API_KEYS ("tenant=key,tenant=key") authenticates multi-tenant
+// callers: the matching key's tenant is attached to the request context and
+// forwarded to the gateway, which scopes RAG retrieval to it. PAGI_API_KEY
+// stays tenant-less.
+//
+// Both are resolved through pkg/secrets on every request (cached by the
+// store), so they may point at Vault/AWS SM/a mounted file and be rotated
+// without a restart.
+func apiKeyMiddleware(store *secrets.Store) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Skip auth for health checks (required for K8s probes)
+			if r.URL.Path == "/health" || r.URL.Path == "/ready" || r.URL.Path == "/live" || r.URL.Path == "/metrics" || r.URL.Path == "/version" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			// The admin API checks PAGI_ADMIN_API_KEY itself; caller keys must
+			// not grant drain or reload.
+			if strings.HasPrefix(r.URL.Path, "/admin/") {
+				next.ServeHTTP(w, r)
+				return
+			}
+			// Peer agents authenticate with PAGI_AGENT_KEYS (see agentAuth).
+			if strings.HasPrefix(r.URL.Path, "/agents/") {
+				next.ServeHTTP(w, r)
+				return
+			}
 
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Skip auth for health checks (required for K8s probes)
-		if r.URL.Path == "/health" || r.URL.Path == "/ready" || r.URL.Path == "/live" || r.URL.Path == "/metrics" {
-			next.ServeHTTP(w, r)
-			return
-		}
+			apiKey, err := store.Lookup(r.Context(), "PAGI_API_KEY")
+			var tenants map[string]string
+			if err == nil {
+				var raw string
+				if raw, err = store.Lookup(r.Context(), "PAGI_TENANT_API_KEYS"); err == nil {
+					tenants, err = parseNamedKeys("PAGI_TENANT_API_KEYS", raw)
+				}
+			}
+			if err != nil {
+				// Configured but unreadable: fail closed rather than disabling auth.
+				logger.NewContextLogger(r.Context()).Error("api_key_unavailable", "error", err)
+				envelope.WriteError(w, r, http.StatusServiceUnavailable, "authentication unavailable")
+				return
+			}
+			authenticate(w, r, next, apiKey, tenants)
+		})
+	}
+}
+
+// authenticate checks the request's key against apiKey and the tenant keys
+// (key -> tenant) and serves it with the matching tenant attached.
+func authenticate(w http.ResponseWriter, r *http.Request, next http.Handler, apiKey string, tenants map[string]string) {
+	authEnabled := strings.TrimSpace(apiKey) != "" || len(tenants) > 0
 
-		// If no API key configured, log warning and allow (dev mode)
-		if !authEnabled {
-			logger.NewContextLogger(r.Context()).Warn(
-				"auth_disabled",
-				"path", r.URL.Path,
-				"warning", "PAGI_API_KEY not set - authentication disabled (INSECURE)",
-			)
-			next.ServeHTTP(w, r)
+	// If no API key configured, log warning and allow (dev mode)
+	if !authEnabled {
+		logger.NewContextLogger(r.Context()).Warn(
+			"auth_disabled",
+			"path", r.URL.Path,
+			"warning", "PAGI_API_KEY not set - authentication disabled (INSECURE)",
+		)
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	providedKey := requestKey(r)
+
+	// Constant-time comparison to prevent timing attacks
+	if apiKey != "" && subtle.ConstantTimeCompare([]byte(providedKey), []byte(apiKey)) == 1 {
+		next.ServeHTTP(w, r.WithContext(agent.ContextWithPrincipal(r.Context(), agent.PrincipalAPIKey, "default")))
+		return
+	}
+	for key, tenant := range tenants {
+		if subtle.ConstantTimeCompare([]byte(providedKey), []byte(key)) == 1 {
+			ctx := agent.ContextWithTenant(r.Context(), tenant)
+			next.ServeHTTP(w, r.WithContext(agent.ContextWithPrincipal(ctx, agent.PrincipalAPIKey, tenant)))
 			return
 		}
+	}
 
-		// Extract API key from header
-		providedKey := r.Header.Get("X-API-Key")
-		if providedKey == "" {
-			// Also check Authorization: Bearer <token>
-			authHeader := r.Header.Get("Authorization")
-			if strings.HasPrefix(authHeader, "Bearer ") {
-				providedKey = strings.TrimPrefix(authHeader, "Bearer ")
-			}
+	logger.NewContextLogger(r.Context()).Warn(
+		"auth_failed",
+		"path", r.URL.Path,
+		"remote_addr", r.RemoteAddr,
+	)
+	envelope.WriteError(w, r, http.StatusUnauthorized, "Invalid or missing API key")
+}
+
+// requestKey returns the caller's key from X-API-Key or a bearer token.
+func requestKey(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	if authHeader := r.Header.Get("Authorization"); strings.HasPrefix(authHeader, "Bearer ") {
+		return strings.TrimPrefix(authHeader, "Bearer ")
+	}
+	return ""
+}
+
+// namedKeyIDPattern matches tenant and peer agent IDs (tenant IDs are used by
+// the gateway as namespaces).
+var namedKeyIDPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,63}$`)
+
+// parseNamedKeys parses a "name=key,..." setting such as PAGI_TENANT_API_KEYS
+// into a key -> name map.
+func parseNamedKeys(setting, v string) (map[string]string, error) {
+	names := map[string]string{}
+	for _, pair := range strings.Split(v, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, key, ok := strings.Cut(pair, "=")
+		name, key = strings.TrimSpace(name), strings.TrimSpace(key)
+		if !ok || key == "" || !namedKeyIDPattern.MatchString(name) {
+			return nil, fmt.Errorf("%s: invalid entry for %q (want name=key)", setting, name)
 		}
+		if _, dup := names[key]; dup {
+			return nil, fmt.Errorf("%s: key for %q is shared with another entry", setting, name)
+		}
+		names[key] = name
+	}
+	return names, nil
+}
 
-		// Constant-time comparison to prevent timing attacks
-		if subtle.ConstantTimeCompare([]byte(providedKey), []byte(apiKey)) != 1 {
-			logger.NewContextLogger(r.Context()).Warn(
-				"auth_failed",
-				"path", r.URL.Path,
-				"remote_addr", r.RemoteAddr,
-			)
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusUnauthorized)
-			_ = json.NewEncoder(w).Encode(map[string]string{
-				"error":   "unauthorized",
-				"message": "Invalid or missing API key",
-			})
+// agentAuth authenticates peer agents against PAGI_AGENT_KEYS ("agent=key,...")
+// and passes the matching agent ID on. Unlike caller auth it never runs open:
+// with no keys configured the endpoint answers 503.
+func agentAuth(store *secrets.Store, next func(w http.ResponseWriter, r *http.Request, agentID string)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		raw, err := store.Lookup(r.Context(), "PAGI_AGENT_KEYS")
+		var agents map[string]string
+		if err == nil {
+			agents, err = parseNamedKeys("PAGI_AGENT_KEYS", raw)
+		}
+		if err != nil {
+			logger.NewContextLogger(r.Context()).Error("agent_keys_unavailable", "error", err)
+			envelope.WriteError(w, r, http.StatusServiceUnavailable, "authentication unavailable")
 			return
 		}
-
-		next.ServeHTTP(w, r)
-	})
+		if len(agents) == 0 {
+			envelope.WriteError(w, r, http.StatusServiceUnavailable, "agent messaging disabled (PAGI_AGENT_KEYS not set)")
+			return
+		}
+		provided := requestKey(r)
+		for key, agentID := range agents {
+			if subtle.ConstantTimeCompare([]byte(provided), []byte(key)) == 1 {
+				next(w, r.WithContext(agent.ContextWithPrincipal(r.Context(), agent.PrincipalAgent, agentID)), agentID)
+				return
+			}
+		}
+		logger.NewContextLogger(r.Context()).Warn("agent_auth_failed", "path", r.URL.Path, "remote_addr", r.RemoteAddr)
+		envelope.WriteError(w, r, http.StatusUnauthorized, "unauthorized")
+	}
 }
 
 // traceIDMiddleware generates or extracts a trace ID from the request header
@@ -165,8 +278,9 @@ func traceIDMiddleware(next http.Handler) http.Handler {
 		// Propagate ID in response header for client visibility.
 		w.Header().Set(string(logger.TraceIDKey), traceID)
 
-		// Inject ID into context.
+		// Inject ID into context, and into the meta of response envelopes.
 		ctx := context.WithValue(r.Context(), logger.TraceIDKey, traceID)
+		ctx = envelope.NewContext(ctx, traceID, buildinfo.Version)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
@@ -188,10 +302,29 @@ func requestLogMiddleware(next http.Handler) http.Handler {
 }
 
 func main() {
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	// The HTTP server and background workers run in this group; SIGINT or
+	// SIGTERM, or any of them exiting, shuts the rest down.
+	log := logger.NewContextLogger(context.Background())
+	group, ctx := lifecycle.New(context.Background(), lifecycle.Options{
+		ShutdownTimeout: 5 * time.Second,
+		OnExit: func(component string, err error) {
+			if err != nil && !errors.Is(err, context.Canceled) {
+				log.Error("component_stopped", "component", component, "error", err)
+				return
+			}
+			log.Info("component_stopped", "component", component)
+		},
+	})
 
-	log := logger.NewContextLogger(ctx)
+	// PAGI_CONFIG_FILE overrides the environment; POST /admin/reload-config
+	// re-applies it.
+	adminOpts := admin.OptionsFromEnv()
+	if adminOpts.ConfigFile != "" {
+		if _, err := admin.LoadEnvFile(adminOpts.ConfigFile); err != nil {
+			log.Error("config_file_invalid", "path", adminOpts.ConfigFile, "error", err)
+			os.Exit(1)
+		}
+	}
 
 	shutdownOTel, promHandler, err := initOpenTelemetry(ctx)
 	if err != nil {
@@ -212,6 +345,50 @@ func main() {
 	}
 	defer planner.Close()
 
+	// Synthetic canary (AGENT_PROBE_INTERVAL): runs a prompt through gateway,
+	// tools and memory and alerts on CANARY_FAILED.
+	if probeCfg := agent.ProbeConfigFromEnv(); probeCfg.Interval > 0 {
+		group.Go("canary_probe", func(ctx context.Context) error {
+			planner.RunProbes(ctx, probeCfg)
+			return ctx.Err()
+		})
+	}
+
+	// Tool discovery (AGENT_TOOL_DISCOVERY_INTERVAL): validate tool calls
+	// against the tools the sandbox lists rather than the built-in ones.
+	if interval := agent.ToolDiscoveryIntervalFromEnv(); interval > 0 {
+		group.Go("tool_discovery", func(ctx context.Context) error {
+			planner.RunToolDiscovery(ctx, interval)
+			return ctx.Err()
+		})
+	}
+
+	// Config drift: compare shared settings with the gateway (and the
+	// notification service) once they answer; mismatches log config_drift.
+	// Not in the group: finishing must not shut the planner down.
+	go func() {
+		checkCtx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+		defer cancel()
+		planner.CheckConfigDrift(checkCtx, 5*time.Second)
+	}()
+
+	// Operator API (/admin/status, /admin/drain, /admin/reload-config), behind
+	// PAGI_ADMIN_API_KEY rather than the caller keys.
+	adminOpts.Service, adminOpts.Version = defaultServiceName, buildinfo.Version
+	adminOpts.Store, adminOpts.KeyName = cfg.Secrets, "PAGI_ADMIN_API_KEY"
+	adminOpts.Status = planner.AdminStatus
+	adminOpts.Reload = func(ctx context.Context) (map[string]any, error) {
+		out, err := planner.ReloadConfig(ctx)
+		if err == nil {
+			logger.NewContextLogger(ctx).Info("config_reloaded", "config", out)
+		}
+		return out, err
+	}
+	adminOpts.OnDrain = func(draining bool) {
+		log.Info("drain_state_changed", "draining", draining)
+	}
+	ops := admin.New(adminOpts)
+
 	// 2) Setup Router with Security Middleware
 	r := chi.NewRouter()
 	r.Use(middleware.Recoverer)
@@ -225,8 +402,9 @@ func main() {
 		)
 	})
 	r.Use(traceIDMiddleware)
-	r.Use(apiKeyMiddleware) // SECURITY: API key authentication
+	r.Use(apiKeyMiddleware(cfg.Secrets)) // SECURITY: API key authentication
 	r.Use(requestLogMiddleware)
+	r.Use(ops.Track)
 
 	port := os.Getenv("AGENT_PLANNER_PORT")
 	if port == "" {
@@ -234,16 +412,68 @@ func main() {
 	}
 
 	// Health Check Endpoint
-	r.Get("/health", func(w http.ResponseWriter, _r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		_ = json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
+		envelope.WriteData(w, r, http.StatusOK, map[string]string{"status": "ok"})
+	})
+
+	// Readiness: fails while draining so the replica is taken out of rotation.
+	r.Get("/ready", func(w http.ResponseWriter, r *http.Request) {
+		if ops.Draining() {
+			envelope.WriteError(w, r, http.StatusServiceUnavailable, "draining")
+			return
+		}
+		envelope.WriteData(w, r, http.StatusOK, map[string]string{"status": "ready"})
 	})
 
+	// Build info: version, commit, Go version and the features enabled now.
+	r.Get("/version", func(w http.ResponseWriter, r *http.Request) {
+		envelope.WriteData(w, r, http.StatusOK, buildinfo.Get(defaultServiceName, planner.Features(r.Context())))
+	})
+
+	r.Handle("/admin/*", ops.Handler())
+
 	// Prometheus metrics endpoint (OpenTelemetry Prometheus exporter).
 	if promHandler != nil {
 		r.Handle("/metrics", promHandler)
 	}
 
+	// Effective feature flags (optionally for a specific session).
+	r.Get("/flags", handleFlags(planner))
+	// The tool registry: the tools the model is offered and calls are
+	// validated against.
+	r.Get("/tools", handleTools(planner))
+	// Settings shared with other services, for their drift checks.
+	r.Get("/capabilities", func(w http.ResponseWriter, r *http.Request) {
+		envelope.WriteData(w, r, http.StatusOK, planner.Settings())
+	})
+	// Recommended Prometheus alerting rules for this build's /metrics.
+	r.Get("/alerts/rules", handleAlertRules(agent.ProbeConfigFromEnv().Interval))
+
+	// Audit log query (read-only).
+	r.Get("/audit", handleAuditQuery(planner))
+	// Aggregates for the operations overview (read-only).
+	r.Get("/audit/stats", handleAuditStats(planner))
+	// Signed compliance export (audit rows, notifications, memory snapshots).
+	r.Post("/audit/bundle", handleAuditBundle(planner))
+
+	// Portable session archives (history, playbooks, audit trail) for moving
+	// a session between environments.
+	r.Get("/sessions/{sessionID}/export", handleSessionExport(planner))
+	r.Post("/sessions/import", handleSessionImport(planner))
+	// Session tags, and search by tag and activity window.
+	r.Get("/sessions", handleSessionSearch(planner))
+	r.Get("/sessions/{sessionID}/tags", handleSessionTags(planner))
+	r.Post("/sessions/{sessionID}/tags", handleSessionTags(planner))
+	r.Delete("/sessions/{sessionID}/tags/{tag}", handleSessionTags(planner))
+	r.Delete("/sessions/{sessionID}/key", handleSessionKeyErase(planner))
+	r.Delete("/sessions/{sessionID}/data", handleSessionForget(planner))
+
+	// Server-Sent Events stream of planner notifications (optionally per session).
+	r.Get("/notifications/stream", handleNotificationStream(planner))
+
+	// Agent-to-agent tasks from peer agents (other twins, planners).
+	r.Post("/agents/message", agentAuth(cfg.Secrets, handleAgentMessage(planner)))
+
 	// Main Planning/Execution Endpoint
 	r.Post("/plan", handlePlan(planner))
 	// Backwards/alternate naming: allow either endpoint.
@@ -255,81 +485,544 @@ func main() {
 		Handler: r,
 	}
 
-	go func() {
-		log.Info("agent_planner_listening", "port", port)
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Error("http_server_failed", "port", port, "error", err)
-			os.Exit(1)
-		}
-	}()
+	group.HTTPServer("http", server)
+	log.Info("agent_planner_listening", "port", port)
 
-	// 4) Graceful Shutdown
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
-	<-quit
-
-	log.Info("server_shutdown_start")
-	ctxTimeout, cancelTimeout := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancelTimeout()
-
-	if err := server.Shutdown(ctxTimeout); err != nil {
-		log.Error("server_shutdown_forced", "error", err)
+	// 4) Graceful Shutdown: on a signal the server stops accepting requests
+	// and gets 5s to finish the ones in flight.
+	if err := group.Wait(); err != nil {
+		log.Error("server_shutdown_forced", "reason", group.Cause().Error(), "error", err)
 		os.Exit(1)
 	}
-	log.Info("server_shutdown_complete")
+	log.Info("server_shutdown_complete", "reason", group.Cause().Error())
 }
 
 type PlanRequest struct {
 	Prompt    string           `json:"prompt"`
 	SessionID string           `json:"session_id"`
 	Resources []agent.Resource `json:"resources"`
+	// RAGFilter optionally scopes retrieval, e.g. {"tags":["health"],"within_days":30}.
+	RAGFilter *ragfilter.Filter `json:"rag_filter,omitempty"`
+	// Persona optionally names a persona from AGENT_PERSONAS_PATH.
+	Persona string `json:"persona,omitempty"`
+	// Tags are added to the session before the run, e.g. ["health"].
+	Tags []string `json:"tags,omitempty"`
+	// Priority is "interactive" (default) or "batch"; batch runs wait behind
+	// interactive ones for model gateway capacity.
+	Priority string `json:"priority,omitempty"`
 }
 
 type PlanResponse struct {
 	Result string `json:"result"`
-}
-
-func writeJSONError(w http.ResponseWriter, status int, msg string) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	_ = json.NewEncoder(w).Encode(map[string]string{"error": msg})
+	// Degraded is set when a turn went over AGENT_TURN_LATENCY_BUDGET and the
+	// answer was planned without the optional retrieval stages.
+	Degraded *agent.Degradation `json:"degraded,omitempty"`
 }
 
 func handlePlan(p *agent.Planner) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
 		log := logger.NewContextLogger(r.Context())
 
 		var req PlanRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			writeJSONError(w, http.StatusBadRequest, "Invalid request body")
+			envelope.WriteError(w, r, http.StatusBadRequest, "Invalid request body")
 			return
 		}
 
 		if req.Prompt == "" || req.SessionID == "" {
-			writeJSONError(w, http.StatusBadRequest, "Prompt and session_id are required")
+			envelope.WriteError(w, r, http.StatusBadRequest, "Prompt and session_id are required")
 			return
 		}
 
 		for i, res := range req.Resources {
 			if strings.TrimSpace(res.Type) == "" || strings.TrimSpace(res.URI) == "" {
-				writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("resources[%d] must include non-empty type and uri", i))
+				envelope.WriteError(w, r, http.StatusBadRequest, fmt.Sprintf("resources[%d] must include non-empty type and uri", i))
 				return
 			}
 		}
 
-		log.Info("agent_loop_start", "session_id", req.SessionID)
-		result, err := p.AgentLoop(r.Context(), req.Prompt, req.SessionID, req.Resources)
+		ctx, err := agent.ContextWithPriority(r.Context(), req.Priority)
+		if err != nil {
+			envelope.WriteError(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if len(req.Tags) > 0 {
+			_, err := p.TagSession(r.Context(), req.SessionID, req.Tags)
+			if errors.Is(err, agent.ErrInvalidTags) {
+				envelope.WriteError(w, r, http.StatusBadRequest, err.Error())
+				return
+			}
+			if errors.Is(err, agent.ErrSessionNotFound) {
+				envelope.WriteError(w, r, http.StatusNotFound, err.Error())
+				return
+			}
+			if err != nil {
+				// Tags are for slicing activity later; the run goes ahead.
+				log.Warn("session_tag_failed", "session_id", req.SessionID, "error", err)
+			}
+		}
+
+		if req.Persona != "" {
+			ctx = agent.ContextWithPersona(ctx, req.Persona)
+		}
+		ctx, report := agent.WithRunReport(ctx)
+		log.Info("agent_loop_start", "session_id", req.SessionID, "persona", req.Persona, "priority", agent.PriorityFromContext(ctx))
+		result, err := p.AgentLoop(ctx, req.Prompt, req.SessionID, req.Resources, req.RAGFilter)
+		if errors.Is(err, agent.ErrResourceNotAllowed) || errors.Is(err, agent.ErrUnknownPersona) {
+			envelope.WriteError(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		if errors.Is(err, agent.ErrSessionNotFound) {
+			envelope.WriteError(w, r, http.StatusNotFound, err.Error())
+			return
+		}
+		if errors.Is(err, agent.ErrOverloaded) || errors.Is(err, agent.ErrGatewayBusy) {
+			log.Warn("agent_loop_rejected", "session_id", req.SessionID, "error", err)
+			w.Header().Set("Retry-After", "5")
+			envelope.WriteError(w, r, http.StatusServiceUnavailable, err.Error())
+			return
+		}
 		if err != nil {
 			log.Error("agent_loop_failed", "session_id", req.SessionID, "error", err)
-			writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Agent execution failed: %s", err.Error()))
+			envelope.WriteError(w, r, http.StatusInternalServerError, fmt.Sprintf("Agent execution failed: %s", err.Error()))
 			return
 		}
 		log.Info("agent_loop_complete", "session_id", req.SessionID)
 
-		resp := PlanResponse{Result: result}
-		if err := json.NewEncoder(w).Encode(resp); err != nil {
-			log.Error("encode_response_failed", "error", err)
+		envelope.WriteData(w, r, http.StatusOK, PlanResponse{Result: result, Degraded: report.Degraded})
+	}
+}
+
+// maxAgentMessageBytes bounds POST /agents/message bodies.
+const maxAgentMessageBytes = 1 << 20
+
+func handleAgentMessage(p *agent.Planner) func(http.ResponseWriter, *http.Request, string) {
+	return func(w http.ResponseWriter, r *http.Request, from string) {
+		log := logger.NewContextLogger(r.Context())
+
+		var msg agent.AgentMessage
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAgentMessageBytes)).Decode(&msg); err != nil {
+			envelope.WriteError(w, r, http.StatusBadRequest, "Invalid agent message")
+			return
+		}
+		log.Info("agent_message_received", "from", from, "id", msg.ID, "correlation_id", msg.CorrelationID, "type", msg.Type)
+		reply, err := p.HandleAgentMessage(r.Context(), from, msg)
+
+		status := http.StatusOK
+		switch {
+		case err == nil:
+		case errors.Is(err, agent.ErrAgentMessageInvalid), errors.Is(err, agent.ErrUnknownPersona):
+			status = http.StatusBadRequest
+		case errors.Is(err, agent.ErrOverloaded), errors.Is(err, agent.ErrGatewayBusy):
+			w.Header().Set("Retry-After", "5")
+			status = http.StatusServiceUnavailable
+		default:
+			log.Error("agent_message_failed", "from", from, "correlation_id", reply.CorrelationID, "error", err)
+			status = http.StatusBadGateway
+		}
+		if err != nil {
+			// The reply still carries the correlation ID.
+			envelope.Write(w, r, status, reply, &envelope.Error{Code: envelope.Code(status), Message: err.Error()})
+			return
+		}
+		envelope.WriteData(w, r, status, reply)
+	}
+}
+
+func handleFlags(p *agent.Planner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sessionID := r.URL.Query().Get("session_id")
+		envelope.WriteData(w, r, http.StatusOK, map[string]any{
+			"session_id": sessionID,
+			"flags":      p.Flags().Snapshot(r.Context(), sessionID),
+		})
+	}
+}
+
+func handleTools(p *agent.Planner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		source, refreshed := p.ToolSource()
+		out := map[string]any{"tools": p.Tools(), "source": source}
+		if !refreshed.IsZero() {
+			out["refreshed_at"] = refreshed.UTC()
+		}
+		envelope.WriteData(w, r, http.StatusOK, out)
+	}
+}
+
+// handleAlertRules serves AlertRules as a Prometheus rule file, unwrapped so
+// it can be saved and loaded as is.
+func handleAlertRules(probeInterval time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rules, err := agent.AlertRules(probeInterval)
+		if err != nil {
+			envelope.WriteError(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+		w.Header().Set("Content-Type", "application/yaml")
+		_, _ = w.Write(rules)
+	}
+}
+
+func handleAuditQuery(p *agent.Planner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		f := audit.QueryFilter{
+			SessionID: q.Get("session_id"),
+			TraceID:   q.Get("trace_id"),
+			EventType: q.Get("event_type"),
+			Principal: q.Get("principal"),
+		}
+		if v := q.Get("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 {
+				envelope.WriteError(w, r, http.StatusBadRequest, "limit must be a positive integer")
+				return
+			}
+			f.Limit = n
+		}
+		for name, dst := range map[string]*time.Time{"since": &f.Since, "until": &f.Until} {
+			if v := q.Get(name); v != "" {
+				t, err := time.Parse(time.RFC3339, v)
+				if err != nil {
+					envelope.WriteError(w, r, http.StatusBadRequest, fmt.Sprintf("%s must be RFC3339", name))
+					return
+				}
+				*dst = t
+			}
+		}
+
+		entries, err := p.QueryAudit(r.Context(), f)
+		if err != nil {
+			status := http.StatusInternalServerError
+			switch {
+			case errors.Is(err, agent.ErrAuditUnavailable):
+				status = http.StatusServiceUnavailable
+			case errors.Is(err, agent.ErrSessionNotFound):
+				status = http.StatusNotFound
+			}
+			envelope.WriteError(w, r, status, err.Error())
+			return
+		}
+		envelope.WriteData(w, r, http.StatusOK, map[string]any{"entries": entries, "count": len(entries)})
+	}
+}
+
+// defaultAuditStatsWindow and maxAuditStatsWindow bound GET /audit/stats.
+const (
+	defaultAuditStatsWindow = 30 * 24 * time.Hour
+	maxAuditStatsWindow     = 366 * 24 * time.Hour
+)
+
+// handleAuditStats serves GET /audit/stats?since=&until= (RFC3339; default
+// the last 30 days, at most a year).
+func handleAuditStats(p *agent.Planner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		until := time.Now().UTC()
+		var since time.Time
+		for name, dst := range map[string]*time.Time{"since": &since, "until": &until} {
+			if v := q.Get(name); v != "" {
+				t, err := time.Parse(time.RFC3339, v)
+				if err != nil {
+					envelope.WriteError(w, r, http.StatusBadRequest, fmt.Sprintf("%s must be RFC3339", name))
+					return
+				}
+				*dst = t
+			}
+		}
+		if since.IsZero() {
+			since = until.Add(-defaultAuditStatsWindow)
+		}
+		if !since.Before(until) || until.Sub(since) > maxAuditStatsWindow {
+			envelope.WriteError(w, r, http.StatusBadRequest, "since must be before until, at most a year apart")
+			return
+		}
+
+		stats, err := p.AuditStats(r.Context(), since, until)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, agent.ErrAuditUnavailable) {
+				status = http.StatusServiceUnavailable
+			}
+			envelope.WriteError(w, r, status, err.Error())
+			return
+		}
+		envelope.WriteData(w, r, http.StatusOK, stats)
+	}
+}
+
+// AuditBundleRequest selects what POST /audit/bundle exports. At least one
+// field is required.
+type AuditBundleRequest struct {
+	SessionID string    `json:"session_id"`
+	Since     time.Time `json:"since"`
+	Until     time.Time `json:"until"`
+}
+
+func handleAuditBundle(p *agent.Planner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logger.NewContextLogger(r.Context())
+
+		var req AuditBundleRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			envelope.WriteError(w, r, http.StatusBadRequest, "Invalid request body (since/until must be RFC3339)")
+			return
+		}
+		if req.SessionID == "" && req.Since.IsZero() && req.Until.IsZero() {
+			envelope.WriteError(w, r, http.StatusBadRequest, "session_id, since or until is required")
+			return
+		}
+		if !req.Since.IsZero() && !req.Until.IsZero() && !req.Since.Before(req.Until) {
+			envelope.WriteError(w, r, http.StatusBadRequest, "since must be before until")
+			return
+		}
+
+		// Buffer the archive so a failure can still be reported as JSON.
+		var buf bytes.Buffer
+		err := p.ExportAuditBundle(r.Context(), &buf, audit.QueryFilter{SessionID: req.SessionID, Since: req.Since, Until: req.Until})
+		if err != nil {
+			status := http.StatusInternalServerError
+			switch {
+			case errors.Is(err, agent.ErrAuditUnavailable), errors.Is(err, agent.ErrBundleKeyUnset):
+				status = http.StatusServiceUnavailable
+			case errors.Is(err, agent.ErrBundleMemory):
+				status = http.StatusBadGateway
+			case errors.Is(err, agent.ErrSessionNotFound):
+				status = http.StatusNotFound
+			}
+			log.Error("audit_bundle_failed", "session_id", req.SessionID, "error", err)
+			envelope.WriteError(w, r, status, err.Error())
+			return
+		}
+		log.Info("audit_bundle_exported", "session_id", req.SessionID, "since", req.Since, "until", req.Until, "bytes", buf.Len())
+
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="pagi-audit-%s.zip"`, time.Now().UTC().Format("20060102T150405Z")))
+		_, _ = buf.WriteTo(w)
+	}
+}
+
+// sessionErrorStatus maps the session API's errors to HTTP.
+func sessionErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, agent.ErrAuditUnavailable), errors.Is(err, agent.ErrSessionKeysDisabled), errors.Is(err, agent.ErrBundleKeyUnset):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, agent.ErrSessionMemory):
+		return http.StatusBadGateway
+	case errors.Is(err, agent.ErrArchiveInvalid), errors.Is(err, agent.ErrInvalidTags):
+		return http.StatusBadRequest
+	case errors.Is(err, agent.ErrSessionExists):
+		return http.StatusConflict
+	case errors.Is(err, agent.ErrSessionNotFound):
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+func handleSessionExport(p *agent.Planner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logger.NewContextLogger(r.Context())
+		sessionID := chi.URLParam(r, "sessionID")
+
+		archive, err := p.ExportSession(r.Context(), sessionID)
+		if err != nil {
+			log.Error("session_export_failed", "session_id", sessionID, "error", err)
+			envelope.WriteError(w, r, sessionErrorStatus(err), err.Error())
+			return
+		}
+		log.Info("session_exported", "session_id", sessionID, "history", len(archive.History), "playbooks", len(archive.Playbooks), "audit_rows", len(archive.Audit))
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="pagi-session-%s.json"`, url.PathEscape(sessionID)))
+		_ = json.NewEncoder(w).Encode(archive)
+	}
+}
+
+// maxSessionArchiveBytes caps POST /sessions/import bodies.
+const maxSessionArchiveBytes = 64 << 20
+
+func handleSessionImport(p *agent.Planner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logger.NewContextLogger(r.Context())
+
+		var archive agent.SessionArchive
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxSessionArchiveBytes)).Decode(&archive); err != nil {
+			envelope.WriteError(w, r, http.StatusBadRequest, "Invalid session archive")
+			return
+		}
+		res, err := p.ImportSession(r.Context(), &archive, r.URL.Query().Get("session_id"))
+		if err != nil {
+			log.Error("session_import_failed", "from", archive.SessionID, "error", err)
+			envelope.WriteError(w, r, sessionErrorStatus(err), err.Error())
+			return
+		}
+		log.Info("session_imported", "session_id", res.SessionID, "from", res.From, "history", res.History, "playbooks", res.Playbooks, "audit_rows", res.AuditRows)
+
+		envelope.WriteData(w, r, http.StatusCreated, res)
+	}
+}
+
+func handleSessionSearch(p *agent.Planner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		f := audit.SessionFilter{Tags: q["tag"]}
+		if v := q.Get("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 {
+				envelope.WriteError(w, r, http.StatusBadRequest, "limit must be a positive integer")
+				return
+			}
+			f.Limit = n
+		}
+		for name, dst := range map[string]*time.Time{"since": &f.Since, "until": &f.Until} {
+			if v := q.Get(name); v != "" {
+				t, err := time.Parse(time.RFC3339, v)
+				if err != nil {
+					envelope.WriteError(w, r, http.StatusBadRequest, fmt.Sprintf("%s must be RFC3339", name))
+					return
+				}
+				*dst = t
+			}
+		}
+
+		sessions, err := p.SearchSessions(r.Context(), f)
+		if err != nil {
+			envelope.WriteError(w, r, sessionErrorStatus(err), err.Error())
+			return
+		}
+		envelope.WriteData(w, r, http.StatusOK, map[string]any{"sessions": sessions, "count": len(sessions)})
+	}
+}
+
+// SessionTagsRequest is the body of POST /sessions/{id}/tags.
+type SessionTagsRequest struct {
+	Tags []string `json:"tags"`
+}
+
+// handleSessionTags lists (GET), adds (POST) or removes (DELETE .../{tag})
+// a session's tags, answering with the tags it has afterwards.
+func handleSessionTags(p *agent.Planner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sessionID := chi.URLParam(r, "sessionID")
+
+		var tags []string
+		var err error
+		switch r.Method {
+		case http.MethodPost:
+			var req SessionTagsRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Tags) == 0 {
+				envelope.WriteError(w, r, http.StatusBadRequest, "Invalid request body (want {\"tags\": [...]})")
+				return
+			}
+			tags, err = p.TagSession(r.Context(), sessionID, req.Tags)
+		case http.MethodDelete:
+			tags, err = p.UntagSession(r.Context(), sessionID, chi.URLParam(r, "tag"))
+		default:
+			tags, err = p.SessionTags(r.Context(), sessionID)
+		}
+		if err != nil {
+			status := sessionErrorStatus(err)
+			if status >= http.StatusInternalServerError {
+				logger.NewContextLogger(r.Context()).Error("session_tags_failed", "session_id", sessionID, "method", r.Method, "error", err)
+			}
+			envelope.WriteError(w, r, status, err.Error())
+			return
+		}
+		envelope.WriteData(w, r, http.StatusOK, map[string]any{"session_id": sessionID, "tags": tags})
+	}
+}
+
+// handleSessionKeyErase deletes a session's encryption key, which makes its
+// stored prompts and answers unreadable.
+func handleSessionKeyErase(p *agent.Planner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sessionID := chi.URLParam(r, "sessionID")
+		erased, err := p.EraseSessionKey(r.Context(), sessionID)
+		if err != nil {
+			status := sessionErrorStatus(err)
+			if status >= http.StatusInternalServerError {
+				logger.NewContextLogger(r.Context()).Error("session_key_erase_failed", "session_id", sessionID, "error", err)
+			}
+			envelope.WriteError(w, r, status, err.Error())
+			return
+		}
+		if !erased {
+			envelope.WriteError(w, r, http.StatusNotFound, "no key for session "+sessionID)
+			return
+		}
+		logger.NewContextLogger(r.Context()).Info("session_key_erased", "session_id", sessionID)
+		envelope.WriteData(w, r, http.StatusOK, map[string]any{"session_id": sessionID, "erased": true})
+	}
+}
+
+// handleSessionForget deletes a session across the stack (right to be
+// forgotten) and answers with the signed deletion receipt.
+func handleSessionForget(p *agent.Planner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sessionID := chi.URLParam(r, "sessionID")
+		receipt, err := p.ForgetSession(r.Context(), sessionID)
+		if err != nil {
+			status := sessionErrorStatus(err)
+			if status >= http.StatusInternalServerError {
+				logger.NewContextLogger(r.Context()).Error("session_delete_failed", "session_id", sessionID, "error", err)
+			}
+			envelope.WriteError(w, r, status, err.Error())
+			return
+		}
+		logger.NewContextLogger(r.Context()).Info("session_deleted", "session_id", sessionID, "deleted", receipt.Deleted, "key_erased", receipt.KeyErased)
+		envelope.WriteData(w, r, http.StatusOK, receipt)
+	}
+}
+
+// handleNotificationStream relays notifications as server-sent events. A
+// tenant key only gets those of its own sessions.
+func handleNotificationStream(p *agent.Planner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			envelope.WriteError(w, r, http.StatusInternalServerError, "streaming unsupported")
+			return
+		}
+		visible, err := p.NotificationFilter(r.Context(), r.URL.Query().Get("session_id"))
+		if err != nil {
+			envelope.WriteError(w, r, sessionErrorStatus(err), err.Error())
+			return
+		}
+		sub, err := p.SubscribeNotifications(r.Context())
+		if err != nil {
+			envelope.WriteError(w, r, http.StatusServiceUnavailable, err.Error())
+			return
+		}
+		defer func() { _ = sub.Close() }()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		keepalive := time.NewTicker(15 * time.Second)
+		defer keepalive.Stop()
+		msgs := sub.Channel()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-keepalive.C:
+				_, _ = fmt.Fprint(w, ": keepalive\n\n")
+				flusher.Flush()
+			case msg, ok := <-msgs:
+				if !ok {
+					return
+				}
+				if !visible(msg.Payload) {
+					continue
+				}
+				_, _ = fmt.Fprintf(w, "event: notification\ndata: %s\n\n", msg.Payload)
+				flusher.Flush()
+			}
 		}
 	}
 }
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"backend-go-model-gateway/pkg/envelope"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultAuditStatsCacheTTL is how long an audit overview is reused
+// (AUDIT_STATS_CACHE_SECONDS).
+const defaultAuditStatsCacheTTL = time.Minute
+
+// maxAuditStatsCached bounds the windows cached at once.
+const maxAuditStatsCached = 64
+
+// auditOverview is the GET /api/v1/audit/stats payload: the planner's
+// GET /audit/stats as of CachedAt.
+type auditOverview struct {
+	Stats    json.RawMessage `json:"stats"`
+	CachedAt time.Time       `json:"cached_at"`
+}
+
+// auditStatsCache keeps recent overviews by window. The SQL aggregation runs
+// on the planner's audit DB, which also takes every audit write, so a busy
+// dashboard must not re-run it on every refresh.
+type auditStatsCache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[string]auditOverview
+}
+
+func (c *auditStatsCache) get(key string, now time.Time) (auditOverview, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	o, ok := c.entries[key]
+	if !ok || now.Sub(o.CachedAt) >= c.ttl {
+		return auditOverview{}, false
+	}
+	return o, true
+}
+
+func (c *auditStatsCache) put(key string, o auditOverview) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= maxAuditStatsCached {
+		for k, e := range c.entries {
+			if o.CachedAt.Sub(e.CachedAt) >= c.ttl {
+				delete(c.entries, k)
+			}
+		}
+	}
+	if len(c.entries) >= maxAuditStatsCached {
+		c.entries = map[string]auditOverview{}
+	}
+	c.entries[key] = o
+}
+
+// auditStatsCacheTTLFromEnv reads AUDIT_STATS_CACHE_SECONDS (0 disables the
+// cache).
+func auditStatsCacheTTLFromEnv() time.Duration {
+	sec, err := strconv.Atoi(os.Getenv("AUDIT_STATS_CACHE_SECONDS"))
+	if err != nil || sec < 0 {
+		return defaultAuditStatsCacheTTL
+	}
+	return time.Duration(sec) * time.Second
+}
+
+// GET /api/v1/audit/stats?since=&until= - the operations overview: runs per
+// day, average turns, tool usage and failure reasons, aggregated by the
+// planner over its audit log and cached here. The BFF only reads the audit
+// log; exports and deletions stay on the planner's own API.
+func auditStatsHandler(cfg Config) gin.HandlerFunc {
+	cache := &auditStatsCache{ttl: auditStatsCacheTTLFromEnv(), entries: map[string]auditOverview{}}
+	client := &http.Client{Timeout: cfg.Timeout}
+	return func(c *gin.Context) {
+		requestID := c.GetString("request_id")
+		// Only the window is passed on, so other parameters cannot split the cache.
+		window := url.Values{}
+		for _, name := range []string{"since", "until"} {
+			if v := c.Query(name); v != "" {
+				window.Set(name, v)
+			}
+		}
+		key := window.Encode()
+		if o, ok := cache.get(key, time.Now()); ok {
+			c.Header("X-Cache", "HIT")
+			envelope.WriteData(c.Writer, c.Request, http.StatusOK, o)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), cfg.Timeout)
+		defer cancel()
+		stats, status, err := fetchAuditStats(ctx, client, cfg, key, requestID)
+		if err != nil {
+			logJSON("warn", "Audit stats unavailable", map[string]interface{}{"request_id": requestID, "error": err.Error()})
+			envelope.WriteError(c.Writer, c.Request, status, err.Error())
+			return
+		}
+		o := auditOverview{Stats: stats, CachedAt: time.Now().UTC()}
+		if cache.ttl > 0 {
+			cache.put(key, o)
+		}
+		c.Header("X-Cache", "MISS")
+		envelope.WriteData(c.Writer, c.Request, http.StatusOK, o)
+	}
+}
+
+// fetchAuditStats reads the planner's GET /audit/stats. A planner error is
+// returned with its status when it is the caller's (400), as 502 otherwise.
+func fetchAuditStats(ctx context.Context, client *http.Client, cfg Config, query, requestID string) (json.RawMessage, int, error) {
+	u := strings.TrimRight(cfg.PlannerURL, "/") + "/audit/stats"
+	if query != "" {
+		u += "?" + query
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("request creation failed: %w", err)
+	}
+	req.Header.Set("X-Request-Id", requestID)
+	if cfg.PlannerAPIKey != "" {
+		req.Header.Set("X-API-Key", cfg.PlannerAPIKey)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, http.StatusBadGateway, fmt.Errorf("network error: %w", err)
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, http.StatusBadGateway, fmt.Errorf("failed to read response body: %w", err)
+	}
+	data, apiErr, _ := envelope.Unwrap(raw)
+	if resp.StatusCode != http.StatusOK {
+		status := http.StatusBadGateway
+		if resp.StatusCode == http.StatusBadRequest {
+			status = http.StatusBadRequest
+		}
+		if apiErr != nil {
+			return nil, status, fmt.Errorf("planner: %s", apiErr.Message)
+		}
+		return nil, status, fmt.Errorf("planner: status code %d", resp.StatusCode)
+	}
+	return data, http.StatusOK, nil
+}
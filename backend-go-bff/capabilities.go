@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"backend-go-model-gateway/pkg/envelope"
+	"backend-go-model-gateway/pkg/tools"
+	pb "backend-go-model-gateway/proto/proto"
+
+	"github.com/gin-gonic/gin"
+)
+
+// capabilities is the GET /api/v1/system/capabilities payload for the
+// dashboard settings page. Errors names the sources that could not be read;
+// their fields are left empty.
+type capabilities struct {
+	Provider       string             `json:"provider"`
+	Model          string             `json:"model"`
+	Providers      []string           `json:"providers"`
+	Mock           bool               `json:"mock"`
+	GatewayVersion string             `json:"gateway_version"`
+	KnowledgeBases []string           `json:"knowledge_bases"`
+	Tools          []tools.Definition `json:"tools"`
+	Errors         map[string]string  `json:"errors,omitempty"`
+}
+
+// GET /api/v1/system/capabilities - the gateway's provider, model and KBs
+// (GetCapabilities) and the planner's tool registry (GET /tools), fetched
+// concurrently. A source that fails is reported under "errors"; only when
+// both fail does the endpoint answer 502.
+func capabilitiesHandler(cfg Config, gateway pb.ModelGatewayClient) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetString("request_id")
+		ctx, cancel := context.WithTimeout(c.Request.Context(), cfg.Timeout)
+		defer cancel()
+
+		out := capabilities{Providers: []string{}, KnowledgeBases: []string{}, Tools: []tools.Definition{}}
+		var mu sync.Mutex
+		fail := func(source string, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			if out.Errors == nil {
+				out.Errors = map[string]string{}
+			}
+			out.Errors[source] = err.Error()
+		}
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			caps, err := gateway.GetCapabilities(ctx, &pb.CapabilitiesRequest{})
+			if err != nil {
+				fail("gateway", err)
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			out.Provider, out.Model, out.Mock, out.GatewayVersion = caps.GetProvider(), caps.GetModel(), caps.GetMock(), caps.GetVersion()
+			out.Providers = append(out.Providers, caps.GetProviders()...)
+			out.KnowledgeBases = append(out.KnowledgeBases, caps.GetKnowledgeBases()...)
+		}()
+		go func() {
+			defer wg.Done()
+			defs, err := fetchPlannerTools(ctx, cfg, requestID)
+			if err != nil {
+				fail("planner", err)
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			out.Tools = append(out.Tools, defs...)
+		}()
+		wg.Wait()
+
+		status := http.StatusOK
+		if len(out.Errors) == 2 {
+			status = http.StatusBadGateway
+		}
+		if len(out.Errors) > 0 {
+			logJSON("warn", "Capabilities incomplete", map[string]interface{}{"request_id": requestID, "errors": out.Errors})
+		}
+		envelope.Write(c.Writer, c.Request, status, out, nil)
+	}
+}
+
+// fetchPlannerTools reads the planner's GET /tools.
+func fetchPlannerTools(ctx context.Context, cfg Config, requestID string) ([]tools.Definition, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(cfg.PlannerURL, "/")+"/tools", nil)
+	if err != nil {
+		return nil, fmt.Errorf("request creation failed: %w", err)
+	}
+	req.Header.Set("X-Request-Id", requestID)
+	if cfg.PlannerAPIKey != "" {
+		req.Header.Set("X-API-Key", cfg.PlannerAPIKey)
+	}
+	resp, err := (&http.Client{Timeout: cfg.Timeout}).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("network error: %w", err)
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	data, apiErr, _ := envelope.Unwrap(raw)
+	if resp.StatusCode != http.StatusOK {
+		if apiErr != nil {
+			return nil, fmt.Errorf("status code %d: %s", resp.StatusCode, apiErr.Message)
+		}
+		return nil, fmt.Errorf("status code %d", resp.StatusCode)
+	}
+	var body struct {
+		Tools []tools.Definition `json:"tools"`
+	}
+	if err := json.Unmarshal(data, &body); err != nil {
+		return nil, fmt.Errorf("decode tools: %w", err)
+	}
+	return body.Tools, nil
+}
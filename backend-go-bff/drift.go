@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"backend-go-model-gateway/pkg/drift"
+	"backend-go-model-gateway/pkg/envelope"
+)
+
+// bffSettings are the BFF's side of the configuration it shares with the
+// planner: the header request IDs are forwarded under.
+func bffSettings() drift.Settings {
+	return drift.Settings{Service: SERVICE_NAME, TraceHeader: "X-Request-Id"}
+}
+
+// checkConfigDrift compares the BFF's settings with the planner's (GET
+// /capabilities) and logs a "Config drift" warning per mismatch. The services
+// start together, so an unreachable planner is retried every retry until ctx
+// ends.
+func checkConfigDrift(ctx context.Context, cfg Config, retry time.Duration) {
+	for {
+		remote, err := fetchPlannerSettings(ctx, cfg)
+		if err == nil {
+			mismatches := drift.Check(bffSettings(), 0, remote)
+			for _, m := range mismatches {
+				logJSON("warn", "Config drift", map[string]interface{}{
+					"peer":    "planner",
+					"setting": m.Setting,
+					"local":   m.Local,
+					"remote":  m.Remote,
+					"problem": m.Problem,
+				})
+			}
+			logJSON("info", "Config drift checked", map[string]interface{}{"peer": "planner", "mismatches": len(mismatches)})
+			return
+		}
+		select {
+		case <-ctx.Done():
+			logJSON("warn", "Config drift unchecked", map[string]interface{}{"peer": "planner", "error": err.Error()})
+			return
+		case <-time.After(retry):
+		}
+	}
+}
+
+// fetchPlannerSettings reads the planner's GET /capabilities.
+func fetchPlannerSettings(ctx context.Context, cfg Config) (drift.Settings, error) {
+	ctx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(cfg.PlannerURL, "/")+"/capabilities", nil)
+	if err != nil {
+		return drift.Settings{}, fmt.Errorf("request creation failed: %w", err)
+	}
+	if cfg.PlannerAPIKey != "" {
+		req.Header.Set("X-API-Key", cfg.PlannerAPIKey)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return drift.Settings{}, fmt.Errorf("network error: %w", err)
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return drift.Settings{}, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return drift.Settings{}, fmt.Errorf("status code %d", resp.StatusCode)
+	}
+	data, _, _ := envelope.Unwrap(raw)
+	var s drift.Settings
+	if err := json.Unmarshal(data, &s); err != nil {
+		return drift.Settings{}, fmt.Errorf("decode capabilities: %w", err)
+	}
+	return s, nil
+}
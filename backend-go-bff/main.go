@@ -11,12 +11,17 @@ import (
 	"strconv"
 	"time"
 
+	"backend-go-model-gateway/pkg/buildinfo"
+	"backend-go-model-gateway/pkg/envelope"
+	pb "backend-go-model-gateway/proto/proto"
+
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
 )
 
 const SERVICE_NAME = "backend-go-bff"
-const VERSION = "1.0.0"
 const DEFAULT_TIMEOUT_SECONDS = 2
 const DEFAULT_BFF_PORT = 8002
 
@@ -25,6 +30,10 @@ type Config struct {
 	PyAgentURL     string
 	RustSandboxURL string
 	MemoryURL      string
+	GatewayAddr    string
+	GatewayHTTPURL string
+	PlannerURL     string
+	PlannerAPIKey  string
 	Timeout        time.Duration
 	Port           int
 }
@@ -56,10 +65,29 @@ func loadConfig() Config {
 		memoryURL = "http://localhost:8003"
 	}
 
+	gatewayAddr := os.Getenv("MODEL_GATEWAY_ADDR")
+	if gatewayAddr == "" {
+		gatewayAddr = "localhost:50051"
+	}
+
+	gatewayHTTPURL := os.Getenv("MODEL_GATEWAY_HTTP_URL")
+	if gatewayHTTPURL == "" {
+		gatewayHTTPURL = "http://localhost:8005"
+	}
+
+	plannerURL := os.Getenv("PAGI_PLANNER_URL")
+	if plannerURL == "" {
+		plannerURL = "http://localhost:8585"
+	}
+
 	return Config{
 		PyAgentURL:     pyAgentURL,
 		RustSandboxURL: rustSandboxURL,
 		MemoryURL:      memoryURL,
+		GatewayAddr:    gatewayAddr,
+		GatewayHTTPURL: gatewayHTTPURL,
+		PlannerURL:     plannerURL,
+		PlannerAPIKey:  os.Getenv("PAGI_API_KEY"),
 		Timeout:        time.Duration(timeoutSeconds) * time.Second,
 		Port:           port,
 	}
@@ -84,10 +112,9 @@ func logJSON(level string, message string, fields map[string]interface{}) {
 
 // GET /health
 func healthCheck(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
+	envelope.WriteData(c.Writer, c.Request, http.StatusOK, gin.H{
 		"service": SERVICE_NAME,
 		"status":  "ok",
-		"version": VERSION,
 	})
 }
 
@@ -96,13 +123,13 @@ func echoHandler(c *gin.Context) {
 	var body map[string]interface{}
 	_ = c.BindJSON(&body)
 
-	// Use X-Request-Id from header or body, or generate a new one
+	// Use X-Request-Id from header or body, or the one generated for this request
 	requestID := c.GetHeader("X-Request-Id")
 	if requestID == "" {
 		if v, ok := body["request_id"].(string); ok && v != "" {
 			requestID = v
 		} else {
-			requestID = uuid.New().String()
+			requestID = c.GetString("request_id")
 		}
 	}
 
@@ -117,7 +144,7 @@ func echoHandler(c *gin.Context) {
 		"received_fields": body,
 	})
 
-	c.JSON(http.StatusOK, gin.H{
+	envelope.WriteData(c.Writer, c.Request, http.StatusOK, gin.H{
 		"service":    SERVICE_NAME,
 		"received":   body,
 		"request_id": requestID,
@@ -172,11 +199,7 @@ func concurrentFetch(ctx context.Context, client *http.Client, method, url, name
 func dashboardDataHandler(cfg Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		startTime := time.Now()
-
-		requestID := c.GetHeader("X-Request-Id")
-		if requestID == "" {
-			requestID = uuid.New().String()
-		}
+		requestID := c.GetString("request_id")
 
 		logJSON("info", "Starting dashboard aggregation", map[string]interface{}{"request_id": requestID})
 
@@ -214,11 +237,10 @@ func dashboardDataHandler(cfg Config) gin.HandlerFunc {
 			"latency_ms": elapsed.Milliseconds(),
 		})
 
-		c.JSON(http.StatusOK, gin.H{
-			"service":    SERVICE_NAME,
-			"status":     "ok",
-			"request_id": requestID,
-			"data":       results,
+		envelope.WriteData(c.Writer, c.Request, http.StatusOK, gin.H{
+			"service": SERVICE_NAME,
+			"status":  "ok",
+			"results": results,
 		})
 	}
 }
@@ -226,21 +248,43 @@ func dashboardDataHandler(cfg Config) gin.HandlerFunc {
 func main() {
 	cfg := loadConfig()
 
+	// The gateway connection is lazy: a gateway that is down only fails the
+	// capabilities endpoint, not startup.
+	gatewayConn, err := grpc.NewClient(cfg.GatewayAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		logJSON("fatal", "Invalid model gateway address", map[string]interface{}{"addr": cfg.GatewayAddr, "error": err.Error()})
+		os.Exit(1)
+	}
+	defer gatewayConn.Close()
+
+	// Best effort: drift is only logged, and a planner that never comes up
+	// stops the check after two minutes.
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		defer cancel()
+		checkConfigDrift(ctx, cfg, 5*time.Second)
+	}()
+
 	// Configure Gin for structured logging (optional, as we use a custom logger here)
 	gin.SetMode(gin.ReleaseMode)
 
 	router := gin.New()
 	router.Use(gin.Recovery())
 	router.Use(func(c *gin.Context) {
-		// Log request details via custom logger
+		// Resolve the request ID once: handlers, logs and the meta of
+		// response envelopes all use it.
 		startTime := time.Now()
-		c.Next()
-		latency := time.Since(startTime)
-
 		requestID := c.GetHeader("X-Request-Id")
 		if requestID == "" {
 			requestID = uuid.New().String()
 		}
+		c.Set("request_id", requestID)
+		c.Header("X-Request-Id", requestID)
+		c.Request = c.Request.WithContext(envelope.NewContext(c.Request.Context(), requestID, buildinfo.Version))
+
+		// Log request details via custom logger
+		c.Next()
+		latency := time.Since(startTime)
 
 		logJSON("info", "Request processed", map[string]interface{}{
 			"request_id":  requestID,
@@ -253,10 +297,18 @@ func main() {
 	})
 
 	router.GET("/health", healthCheck)
+	router.GET("/version", versionHandler)
 	router.POST("/api/v1/echo", echoHandler)
 	router.GET("/api/v1/agi/dashboard-data", dashboardDataHandler(cfg))
+	router.GET("/api/v1/system/capabilities", capabilitiesHandler(cfg, pb.NewModelGatewayClient(gatewayConn)))
+	router.GET("/api/v1/system/versions", versionsHandler(cfg, pb.NewModelGatewayClient(gatewayConn)))
+	router.GET("/api/v1/system/metrics-summary", metricsSummaryHandler(cfg))
+	router.GET("/api/v1/audit/stats", auditStatsHandler(cfg))
+	router.NoRoute(func(c *gin.Context) {
+		envelope.WriteError(c.Writer, c.Request, http.StatusNotFound, "no route for "+c.Request.Method+" "+c.Request.URL.Path)
+	})
 
-	logJSON("info", "Starting server", map[string]interface{}{"port": cfg.Port, "version": VERSION})
+	logJSON("info", "Starting server", map[string]interface{}{"port": cfg.Port, "version": buildinfo.Version})
 	if err := router.Run(fmt.Sprintf(":%d", cfg.Port)); err != nil {
 		logJSON("fatal", "Failed to run server", map[string]interface{}{"error": err.Error()})
 		os.Exit(1)
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"backend-go-model-gateway/pkg/envelope"
+
+	"github.com/gin-gonic/gin"
+)
+
+// metricsSummary is the GET /api/v1/system/metrics-summary payload: a few
+// vitals per service, computed server-side so the dashboard needs no
+// Grafana. Errors names the services that could not be read; they are
+// missing from Services.
+type metricsSummary struct {
+	GeneratedAt time.Time                 `json:"generated_at"`
+	Services    map[string]*serviceVitals `json:"services"`
+	// LLM is the gateway's provider usage for the current UTC day.
+	LLM    *llmSpend         `json:"llm,omitempty"`
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// serviceVitals are one service's request counters and health signals.
+type serviceVitals struct {
+	// Requests and Failures are cumulative since the service started.
+	Requests int64 `json:"requests"`
+	Failures int64 `json:"failures"`
+	// RPS and ErrorRate cover the WindowSeconds since the previous summary.
+	// They are omitted on the first summary and after the service restarts.
+	RPS           *float64 `json:"rps,omitempty"`
+	ErrorRate     *float64 `json:"error_rate,omitempty"`
+	WindowSeconds float64  `json:"window_seconds,omitempty"`
+	// Breakers maps a dependency to "open" or "closed".
+	Breakers   map[string]string `json:"breakers,omitempty"`
+	Saturation *float64          `json:"saturation,omitempty"`
+}
+
+// llmSpend mirrors the "today" part of the gateway's GET /api/v1/usage.
+type llmSpend struct {
+	Date             string   `json:"date"`
+	Requests         int64    `json:"requests"`
+	PromptTokens     int64    `json:"prompt_tokens"`
+	CompletionTokens int64    `json:"completion_tokens"`
+	CostUSD          *float64 `json:"cost_usd,omitempty"`
+}
+
+// rateTracker keeps each service's counters from the previous summary, so
+// rates come from two scrapes without a time-series store.
+type rateTracker struct {
+	mu   sync.Mutex
+	last map[string]counterSample
+}
+
+type counterSample struct {
+	at                 time.Time
+	requests, failures int64
+}
+
+// minRateWindow avoids rates over intervals too short to mean anything.
+const minRateWindow = time.Second
+
+// observe sets v's rates from the counters last seen for service.
+func (t *rateTracker) observe(service string, now time.Time, v *serviceVitals) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	prev, ok := t.last[service]
+	window := now.Sub(prev.at)
+	if ok && window < minRateWindow {
+		return
+	}
+	t.last[service] = counterSample{at: now, requests: v.Requests, failures: v.Failures}
+	if !ok || v.Requests < prev.requests || v.Failures < prev.failures {
+		return
+	}
+	requests, failures := v.Requests-prev.requests, v.Failures-prev.failures
+	rps := float64(requests) / window.Seconds()
+	v.RPS, v.WindowSeconds = &rps, window.Seconds()
+	if total := requests + failures; total > 0 {
+		rate := float64(failures) / float64(total)
+		v.ErrorRate = &rate
+	}
+}
+
+// GET /api/v1/system/metrics-summary - vitals of the planner (its Prometheus
+// /metrics: AgentLoop runs, breaker states, saturation) and of the gateway
+// (GET /api/v1/usage: provider calls and today's LLM spend), fetched
+// concurrently. A service that fails is reported under "errors"; only when
+// both fail does the endpoint answer 502.
+func metricsSummaryHandler(cfg Config) gin.HandlerFunc {
+	rates := &rateTracker{last: map[string]counterSample{}}
+	client := &http.Client{Timeout: cfg.Timeout}
+	return func(c *gin.Context) {
+		requestID := c.GetString("request_id")
+		ctx, cancel := context.WithTimeout(c.Request.Context(), cfg.Timeout)
+		defer cancel()
+
+		out := metricsSummary{GeneratedAt: time.Now().UTC(), Services: map[string]*serviceVitals{}}
+		var mu sync.Mutex
+		done := func(service string, v *serviceVitals, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if out.Errors == nil {
+					out.Errors = map[string]string{}
+				}
+				out.Errors[service] = err.Error()
+				return
+			}
+			rates.observe(service, time.Now(), v)
+			out.Services[service] = v
+		}
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			v, err := plannerVitals(ctx, client, cfg, requestID)
+			done("planner", v, err)
+		}()
+		go func() {
+			defer wg.Done()
+			v, spend, err := gatewayVitals(ctx, client, cfg, requestID)
+			if spend != nil {
+				mu.Lock()
+				out.LLM = spend
+				mu.Unlock()
+			}
+			done("gateway", v, err)
+		}()
+		wg.Wait()
+
+		status := http.StatusOK
+		if len(out.Errors) == 2 {
+			status = http.StatusBadGateway
+		}
+		if len(out.Errors) > 0 {
+			logJSON("warn", "Metrics summary incomplete", map[string]interface{}{"request_id": requestID, "errors": out.Errors})
+		}
+		envelope.Write(c.Writer, c.Request, status, out, nil)
+	}
+}
+
+// plannerVitals reads the planner's /metrics.
+func plannerVitals(ctx context.Context, client *http.Client, cfg Config, requestID string) (*serviceVitals, error) {
+	body, err := getForSummary(ctx, client, strings.TrimRight(cfg.PlannerURL, "/")+"/metrics", requestID)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	samples, err := parsePromText(body)
+	if err != nil {
+		return nil, fmt.Errorf("parse metrics: %w", err)
+	}
+
+	v := &serviceVitals{}
+	for _, s := range samples {
+		switch s.name {
+		case "agent_plan_total":
+			v.Requests += int64(s.value)
+			if s.labels["outcome"] == "error" {
+				v.Failures += int64(s.value)
+			}
+		case "agent_circuit_breaker_open_ratio":
+			if v.Breakers == nil {
+				v.Breakers = map[string]string{}
+			}
+			state := "closed"
+			if s.value > 0 {
+				state = "open"
+			}
+			v.Breakers[s.labels["dependency"]] = state
+		case "agent_saturation_ratio":
+			saturation := s.value
+			v.Saturation = &saturation
+		}
+	}
+	// agent_plan_total counts every run, failed ones included.
+	v.Requests -= v.Failures
+	return v, nil
+}
+
+// gatewayVitals reads the gateway's GET /api/v1/usage.
+func gatewayVitals(ctx context.Context, client *http.Client, cfg Config, requestID string) (*serviceVitals, *llmSpend, error) {
+	body, err := getForSummary(ctx, client, strings.TrimRight(cfg.GatewayHTTPURL, "/")+"/api/v1/usage", requestID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer body.Close()
+	var usage struct {
+		Requests int64    `json:"requests"`
+		Errors   int64    `json:"errors"`
+		Today    llmSpend `json:"today"`
+	}
+	if err := json.NewDecoder(body).Decode(&usage); err != nil {
+		return nil, nil, fmt.Errorf("decode usage: %w", err)
+	}
+	return &serviceVitals{Requests: usage.Requests, Failures: usage.Errors}, &usage.Today, nil
+}
+
+func getForSummary(ctx context.Context, client *http.Client, url, requestID string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("request creation failed: %w", err)
+	}
+	req.Header.Set("X-Request-Id", requestID)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("network error: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("status code %d", resp.StatusCode)
+	}
+	return resp.Body, nil
+}
+
+// promSample is one line of the Prometheus text format.
+type promSample struct {
+	name   string
+	labels map[string]string
+	value  float64
+}
+
+// parsePromText reads the Prometheus text exposition format, skipping
+// comments and the _created series of counters.
+func parsePromText(r io.Reader) ([]promSample, error) {
+	var out []promSample
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		s := promSample{labels: map[string]string{}}
+		rest := line
+		if i := strings.IndexAny(line, "{ "); i >= 0 {
+			s.name, rest = line[:i], line[i:]
+		}
+		if strings.HasSuffix(s.name, "_created") {
+			continue
+		}
+		if strings.HasPrefix(rest, "{") {
+			end := strings.LastIndex(rest, "}")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated labels: %q", line)
+			}
+			if err := parsePromLabels(rest[1:end], s.labels); err != nil {
+				return nil, fmt.Errorf("%w: %q", err, line)
+			}
+			rest = rest[end+1:]
+		}
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("no value: %q", line)
+		}
+		v, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			return nil, fmt.Errorf("bad value: %q", line)
+		}
+		s.value = v
+		out = append(out, s)
+	}
+	return out, sc.Err()
+}
+
+// parsePromLabels parses `a="x",b="y"` into labels.
+func parsePromLabels(s string, labels map[string]string) error {
+	for s = strings.TrimSpace(s); s != ""; {
+		eq := strings.Index(s, "=")
+		if eq < 0 || len(s) < eq+2 || s[eq+1] != '"' {
+			return fmt.Errorf("bad labels")
+		}
+		name := strings.TrimSpace(s[:eq])
+		// Find the closing quote, skipping escaped ones.
+		end := eq + 2
+		for ; end < len(s) && s[end] != '"'; end++ {
+			if s[end] == '\\' {
+				end++
+			}
+		}
+		if end >= len(s) {
+			return fmt.Errorf("bad labels")
+		}
+		value, err := strconv.Unquote(s[eq+1 : end+1])
+		if err != nil {
+			return fmt.Errorf("bad labels")
+		}
+		labels[name] = value
+		s = strings.TrimLeft(strings.TrimSpace(s[end+1:]), ",")
+		s = strings.TrimSpace(s)
+	}
+	return nil
+}
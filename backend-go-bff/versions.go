@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"backend-go-model-gateway/pkg/buildinfo"
+	"backend-go-model-gateway/pkg/envelope"
+	pb "backend-go-model-gateway/proto/proto"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GET /version - the BFF's own build info.
+func versionHandler(c *gin.Context) {
+	envelope.WriteData(c.Writer, c.Request, http.StatusOK, buildinfo.Get(SERVICE_NAME, nil))
+}
+
+// versions is the GET /api/v1/system/versions payload: the build info of
+// each Go service, keyed by service. Errors names the services that could
+// not be read; they are missing from Services.
+type versions struct {
+	Services map[string]buildinfo.Info `json:"services"`
+	Errors   map[string]string         `json:"errors,omitempty"`
+}
+
+// GET /api/v1/system/versions - the BFF's build info with the gateway's
+// (GetVersion) and the planner's (GET /version), fetched concurrently. A
+// service that fails is reported under "errors"; only when both fail does the
+// endpoint answer 502.
+func versionsHandler(cfg Config, gateway pb.ModelGatewayClient) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetString("request_id")
+		ctx, cancel := context.WithTimeout(c.Request.Context(), cfg.Timeout)
+		defer cancel()
+
+		out := versions{Services: map[string]buildinfo.Info{SERVICE_NAME: buildinfo.Get(SERVICE_NAME, nil)}}
+		var mu sync.Mutex
+		done := func(service string, info buildinfo.Info, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if out.Errors == nil {
+					out.Errors = map[string]string{}
+				}
+				out.Errors[service] = err.Error()
+				return
+			}
+			out.Services[info.Service] = info
+		}
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			v, err := gateway.GetVersion(ctx, &pb.VersionRequest{})
+			done("gateway", buildinfo.Info{
+				Service:   v.GetService(),
+				Version:   v.GetVersion(),
+				GitSHA:    v.GetGitSha(),
+				BuildTime: v.GetBuildTime(),
+				GoVersion: v.GetGoVersion(),
+				Features:  append([]string{}, v.GetFeatures()...),
+			}, err)
+		}()
+		go func() {
+			defer wg.Done()
+			info, err := fetchPlannerVersion(ctx, cfg, requestID)
+			done("planner", info, err)
+		}()
+		wg.Wait()
+
+		status := http.StatusOK
+		if len(out.Errors) == 2 {
+			status = http.StatusBadGateway
+		}
+		if len(out.Errors) > 0 {
+			logJSON("warn", "Versions incomplete", map[string]interface{}{"request_id": requestID, "errors": out.Errors})
+		}
+		envelope.Write(c.Writer, c.Request, status, out, nil)
+	}
+}
+
+// fetchPlannerVersion reads the planner's GET /version.
+func fetchPlannerVersion(ctx context.Context, cfg Config, requestID string) (buildinfo.Info, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(cfg.PlannerURL, "/")+"/version", nil)
+	if err != nil {
+		return buildinfo.Info{}, fmt.Errorf("request creation failed: %w", err)
+	}
+	req.Header.Set("X-Request-Id", requestID)
+	resp, err := (&http.Client{Timeout: cfg.Timeout}).Do(req)
+	if err != nil {
+		return buildinfo.Info{}, fmt.Errorf("network error: %w", err)
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return buildinfo.Info{}, fmt.Errorf("failed to read response body: %w", err)
+	}
+	data, apiErr, _ := envelope.Unwrap(raw)
+	if resp.StatusCode != http.StatusOK {
+		if apiErr != nil {
+			return buildinfo.Info{}, fmt.Errorf("status code %d: %s", resp.StatusCode, apiErr.Message)
+		}
+		return buildinfo.Info{}, fmt.Errorf("status code %d", resp.StatusCode)
+	}
+	var info buildinfo.Info
+	if err := json.Unmarshal(data, &info); err != nil {
+		return buildinfo.Info{}, fmt.Errorf("decode version: %w", err)
+	}
+	return info, nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"backend-go-model-gateway/pkg/admin"
+	pb "backend-go-model-gateway/proto/proto"
+
+	grpc_health_v1 "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestAdmin_ReloadAndDrain(t *testing.T) {
+	t.Setenv("LLM_PROVIDER", "mock")
+	llm, err := newLLMClient(context.Background(), nil, envConfig(t).LLM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gw := &server{llm: llm}
+
+	t.Setenv("LLM_PROVIDER", "ollama")
+	t.Setenv("OLLAMA_MODEL_NAME", "llama3.1")
+	t.Setenv("PII_SCRUB", "ollama")
+	cfg, err := gw.reloadConfig(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg["provider"] != providerOllama || cfg["model"] != "llama3.1" || cfg["pii_scrub"] != true {
+		t.Fatalf("reloaded config = %v", cfg)
+	}
+
+	// A bad provider config keeps the running one.
+	t.Setenv("LLM_PROVIDER", "gpt")
+	if _, err := gw.reloadConfig(context.Background(), nil); err == nil {
+		t.Fatal("want an error for an unsupported provider")
+	}
+	if llm, pii := gw.runtime(); llm.Provider != providerOllama || !pii.appliesTo(providerOllama) {
+		t.Fatalf("runtime after failed reload = %v, %v", llm.Provider, pii)
+	}
+
+	t.Setenv("GATEWAY_ADMIN_API_KEY", "ops")
+	ops := admin.New(admin.Options{KeyName: "GATEWAY_ADMIN_API_KEY", Status: gw.adminStatus})
+	mux := NewHTTPMux(fakeRAGClient{}, adminRoutes{ops: ops})
+	req := httptest.NewRequest(http.MethodPost, "/admin/drain", nil)
+	req.Header.Set("Authorization", "Bearer ops")
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || !ops.Draining() {
+		t.Fatalf("drain = %d %s", rec.Code, rec.Body)
+	}
+
+	gw.llm = &llmRuntime{Provider: providerMock}
+	resp, err := (&healthServer{gateway: gw, ops: ops}).Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+	if err != nil || resp.GetStatus() != grpc_health_v1.HealthCheckResponse_NOT_SERVING {
+		t.Fatalf("health while draining = %v, %v", resp.GetStatus(), err)
+	}
+	// Draining only fails readiness; requests are still served.
+	if _, err := gw.GetPlan(context.Background(), &pb.PlanRequest{Prompt: "hi"}); err != nil {
+		t.Fatalf("GetPlan while draining: %v", err)
+	}
+}
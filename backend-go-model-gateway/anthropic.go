@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+const (
+	defaultAnthropicBaseURL   = "https://api.anthropic.com"
+	defaultAnthropicMaxTokens = 1024
+	// anthropicVersion is the Messages API version the client speaks.
+	anthropicVersion = "2023-06-01"
+)
+
+// chatCompleter is the chat completion call GetPlan, EvaluateAnswer and the
+// LLM-backed RAG stages make. *openai.Client serves the OpenAI-compatible
+// providers; anthropicClient translates to Anthropic's Messages API.
+type chatCompleter interface {
+	CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error)
+}
+
+// anthropicClient calls Anthropic's Messages API (POST /v1/messages) with
+// OpenAI-shaped requests and responses, so the gateway's prompting, native
+// tool calls and 429 handling work unchanged. The API key is set by the HTTP
+// client's transport (x-api-key).
+type anthropicClient struct {
+	baseURL   string
+	maxTokens int
+	http      *http.Client
+}
+
+type anthropicRequest struct {
+	Model       string             `json:"model"`
+	System      string             `json:"system,omitempty"`
+	Messages    []anthropicMessage `json:"messages"`
+	MaxTokens   int                `json:"max_tokens"`
+	Temperature float32            `json:"temperature"`
+	TopP        float32            `json:"top_p,omitempty"`
+	Stop        []string           `json:"stop_sequences,omitempty"`
+	Tools       []anthropicTool    `json:"tools,omitempty"`
+}
+
+type anthropicMessage struct {
+	Role string `json:"role"`
+	// Content is a string, or []anthropicBlock for messages with images.
+	Content any `json:"content"`
+}
+
+type anthropicBlock struct {
+	Type   string                `json:"type"`
+	Text   string                `json:"text,omitempty"`
+	Source *anthropicImageSource `json:"source,omitempty"`
+}
+
+type anthropicImageSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+type anthropicTool struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	InputSchema any    `json:"input_schema"`
+}
+
+type anthropicResponse struct {
+	ID      string `json:"id"`
+	Model   string `json:"model"`
+	Content []struct {
+		Type  string          `json:"type"`
+		Text  string          `json:"text"`
+		ID    string          `json:"id"`
+		Name  string          `json:"name"`
+		Input json.RawMessage `json:"input"`
+	} `json:"content"`
+	StopReason string `json:"stop_reason"`
+	Usage      struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+}
+
+// anthropicContent converts a message's content parts to Anthropic blocks:
+// data: image URLs become base64 sources, others URL sources. A message
+// without parts keeps its string content.
+func anthropicContent(m openai.ChatCompletionMessage) any {
+	if len(m.MultiContent) == 0 {
+		return m.Content
+	}
+	blocks := make([]anthropicBlock, 0, len(m.MultiContent))
+	for _, part := range m.MultiContent {
+		switch {
+		case part.Type == openai.ChatMessagePartTypeText:
+			blocks = append(blocks, anthropicBlock{Type: "text", Text: part.Text})
+		case part.Type == openai.ChatMessagePartTypeImageURL && part.ImageURL != nil:
+			source := &anthropicImageSource{Type: "url", URL: part.ImageURL.URL}
+			// data:image/png;base64,<data>
+			if meta, data, ok := strings.Cut(strings.TrimPrefix(part.ImageURL.URL, "data:"), ","); ok && strings.HasPrefix(part.ImageURL.URL, "data:") {
+				source = &anthropicImageSource{Type: "base64", MediaType: strings.TrimSuffix(meta, ";base64"), Data: data}
+			}
+			blocks = append(blocks, anthropicBlock{Type: "image", Source: source})
+		}
+	}
+	return blocks
+}
+
+// CreateChatCompletion sends req as a Messages API request: system messages
+// become the system prompt, and tools become Anthropic tools. tool_use blocks
+// come back as tool calls. API errors are returned as *openai.APIError.
+func (c *anthropicClient) CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	// Anthropic's temperature range is 0 to 1, not OpenAI's 0 to 2.
+	body := anthropicRequest{Model: req.Model, MaxTokens: req.MaxTokens, Temperature: min(req.Temperature, 1), TopP: req.TopP, Stop: req.Stop}
+	if body.MaxTokens <= 0 {
+		body.MaxTokens = c.maxTokens
+	}
+	var system []string
+	for _, m := range req.Messages {
+		switch m.Role {
+		case openai.ChatMessageRoleSystem:
+			system = append(system, m.Content)
+		case openai.ChatMessageRoleUser, openai.ChatMessageRoleAssistant:
+			body.Messages = append(body.Messages, anthropicMessage{Role: m.Role, Content: anthropicContent(m)})
+		default:
+			return openai.ChatCompletionResponse{}, fmt.Errorf("anthropic: unsupported message role %q", m.Role)
+		}
+	}
+	body.System = strings.Join(system, "\n\n")
+	for _, t := range req.Tools {
+		if t.Function == nil {
+			continue
+		}
+		body.Tools = append(body.Tools, anthropicTool{Name: t.Function.Name, Description: t.Function.Description, InputSchema: t.Function.Parameters})
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return openai.ChatCompletionResponse{}, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(c.baseURL, "/")+"/v1/messages", bytes.NewReader(payload))
+	if err != nil {
+		return openai.ChatCompletionResponse{}, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("anthropic-version", anthropicVersion)
+	resp, err := c.http.Do(httpReq)
+	if err != nil {
+		return openai.ChatCompletionResponse{}, err
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return openai.ChatCompletionResponse{}, err
+	}
+	if resp.StatusCode/100 != 2 {
+		var e struct {
+			Error struct {
+				Type    string `json:"type"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		_ = json.Unmarshal(raw, &e)
+		if e.Error.Message == "" {
+			e.Error.Message = strings.TrimSpace(string(raw))
+		}
+		return openai.ChatCompletionResponse{}, &openai.APIError{HTTPStatusCode: resp.StatusCode, HTTPStatus: resp.Status, Type: e.Error.Type, Message: e.Error.Message}
+	}
+
+	var out anthropicResponse
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return openai.ChatCompletionResponse{}, fmt.Errorf("anthropic: decode response: %w", err)
+	}
+	msg := openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant}
+	for _, block := range out.Content {
+		switch block.Type {
+		case "text":
+			msg.Content += block.Text
+		case "tool_use":
+			msg.ToolCalls = append(msg.ToolCalls, openai.ToolCall{
+				ID:       block.ID,
+				Type:     openai.ToolTypeFunction,
+				Function: openai.FunctionCall{Name: block.Name, Arguments: string(block.Input)},
+			})
+		}
+	}
+	finish := openai.FinishReasonStop
+	switch out.StopReason {
+	case "tool_use":
+		finish = openai.FinishReasonToolCalls
+	case "max_tokens":
+		finish = openai.FinishReasonLength
+	}
+	return openai.ChatCompletionResponse{
+		ID:      out.ID,
+		Object:  "chat.completion",
+		Model:   out.Model,
+		Choices: []openai.ChatCompletionChoice{{Message: msg, FinishReason: finish}},
+		Usage: openai.Usage{
+			PromptTokens:     out.Usage.InputTokens,
+			CompletionTokens: out.Usage.OutputTokens,
+			TotalTokens:      out.Usage.InputTokens + out.Usage.OutputTokens,
+		},
+	}, nil
+}
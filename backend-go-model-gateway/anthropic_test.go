@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"backend-go-model-gateway/pkg/secrets"
+	pb "backend-go-model-gateway/proto/proto"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func newAnthropicTestClient(t *testing.T, url string) *anthropicClient {
+	t.Helper()
+	t.Setenv("ANTHROPIC_API_KEY", "sk-ant-test")
+	return &anthropicClient{
+		baseURL:   url,
+		maxTokens: defaultAnthropicMaxTokens,
+		http: &http.Client{
+			Transport: &secrets.BearerTransport{Store: secrets.New(secrets.Options{}), Name: "ANTHROPIC_API_KEY", Header: "x-api-key", Base: http.DefaultTransport},
+		},
+	}
+}
+
+func TestGetPlan_Anthropic(t *testing.T) {
+	var (
+		header http.Header
+		path   string
+		sent   anthropicRequest
+	)
+	llm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header, path = r.Header.Clone(), r.URL.Path
+		_ = json.NewDecoder(r.Body).Decode(&sent)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"msg_1","model":"claude-test","stop_reason":"tool_use",
+			"content":[{"type":"text","text":"Searching."},{"type":"tool_use","id":"toolu_1","name":"web_search","input":{"query":"lisbon weather"}}],
+			"usage":{"input_tokens":120,"output_tokens":30}}`))
+	}))
+	defer llm.Close()
+	s := &server{
+		llm:            &llmRuntime{Provider: providerAnthropic, Model: "claude-test", Client: newAnthropicTestClient(t, llm.URL), ToolCalling: toolCallingNative},
+		requestTimeout: time.Duration(defaultRequestTimeoutSec) * time.Second,
+	}
+
+	resp, err := s.GetPlan(context.Background(), &pb.PlanRequest{Prompt: "weather in lisbon"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != "/v1/messages" || header.Get("x-api-key") != "sk-ant-test" || header.Get("anthropic-version") != anthropicVersion || header.Get("Authorization") != "" {
+		t.Fatalf("path = %s, headers = %v", path, header)
+	}
+	if sent.Model != "claude-test" || sent.MaxTokens != defaultAnthropicMaxTokens || sent.System == "" {
+		t.Fatalf("request = %+v", sent)
+	}
+	if len(sent.Messages) != 1 || sent.Messages[0].Role != "user" || !strings.Contains(fmt.Sprint(sent.Messages[0].Content), "weather in lisbon") {
+		t.Fatalf("messages = %+v", sent.Messages)
+	}
+	if len(sent.Tools) != 1 || sent.Tools[0].Name != "web_search" || sent.Tools[0].InputSchema == nil {
+		t.Fatalf("tools = %+v", sent.Tools)
+	}
+	var plan struct {
+		Tool struct {
+			Name string         `json:"name"`
+			Args map[string]any `json:"args"`
+		} `json:"tool"`
+		ModelType string `json:"model_type"`
+	}
+	if err := json.Unmarshal([]byte(resp.GetPlan()), &plan); err != nil || plan.Tool.Name != "web_search" || plan.Tool.Args["query"] != "lisbon weather" || plan.ModelType != "anthropic" {
+		t.Fatalf("plan = %s (%v)", resp.GetPlan(), err)
+	}
+}
+
+func TestAnthropicClient_Text(t *testing.T) {
+	var sent anthropicRequest
+	llm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&sent)
+		_, _ = w.Write([]byte(`{"id":"msg_2","model":"claude-test","stop_reason":"max_tokens",
+			"content":[{"type":"text","text":"Hello"},{"type":"text","text":" there"}],"usage":{"input_tokens":5,"output_tokens":2}}`))
+	}))
+	defer llm.Close()
+
+	resp, err := newAnthropicTestClient(t, llm.URL).CreateChatCompletion(context.Background(), openai.ChatCompletionRequest{
+		Model:     "claude-test",
+		MaxTokens: 64,
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: "Be brief."},
+			{Role: openai.ChatMessageRoleUser, Content: "hi"},
+			{Role: openai.ChatMessageRoleAssistant, Content: "hello"},
+			{Role: openai.ChatMessageRoleUser, Content: "again"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent.System != "Be brief." || len(sent.Messages) != 3 || sent.MaxTokens != 64 {
+		t.Fatalf("request = %+v", sent)
+	}
+	choice := resp.Choices[0]
+	if choice.Message.Content != "Hello there" || choice.FinishReason != openai.FinishReasonLength || resp.Usage.TotalTokens != 7 {
+		t.Fatalf("response = %+v", resp)
+	}
+}
+
+func TestAnthropicClient_Errors(t *testing.T) {
+	llm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`{"type":"error","error":{"type":"rate_limit_error","message":"Number of request tokens has exceeded your rate limit"}}`))
+	}))
+	defer llm.Close()
+	c := newAnthropicTestClient(t, llm.URL)
+
+	_, err := c.CreateChatCompletion(context.Background(), openai.ChatCompletionRequest{
+		Messages: []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: "hi"}},
+	})
+	var apiErr *openai.APIError
+	if !errors.As(err, &apiErr) || apiErr.HTTPStatusCode != http.StatusTooManyRequests || apiErr.Type != "rate_limit_error" || !strings.Contains(apiErr.Message, "rate limit") {
+		t.Fatalf("err = %#v", err)
+	}
+
+	_, err = c.CreateChatCompletion(context.Background(), openai.ChatCompletionRequest{
+		Messages: []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleTool, Content: "out"}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "unsupported message role") {
+		t.Fatalf("tool message: %v", err)
+	}
+}
+
+func TestAnthropicContent_Images(t *testing.T) {
+	got := anthropicContent(openai.ChatCompletionMessage{Role: "user", MultiContent: []openai.ChatMessagePart{
+		{Type: openai.ChatMessagePartTypeText, Text: "Compare"},
+		{Type: openai.ChatMessagePartTypeImageURL, ImageURL: &openai.ChatMessageImageURL{URL: "data:image/png;base64,iVBORw0KGgo="}},
+		{Type: openai.ChatMessagePartTypeImageURL, ImageURL: &openai.ChatMessageImageURL{URL: "https://example.com/b.jpg"}},
+	}})
+	want := []anthropicBlock{
+		{Type: "text", Text: "Compare"},
+		{Type: "image", Source: &anthropicImageSource{Type: "base64", MediaType: "image/png", Data: "iVBORw0KGgo="}},
+		{Type: "image", Source: &anthropicImageSource{Type: "url", URL: "https://example.com/b.jpg"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("content = %#v", got)
+	}
+	if got := anthropicContent(openai.ChatCompletionMessage{Role: "user", Content: "plain"}); got != "plain" {
+		t.Fatalf("plain content = %#v", got)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	"backend-go-model-gateway/internal/logger"
+	"backend-go-model-gateway/pkg/buildinfo"
+	pb "backend-go-model-gateway/proto/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// GetCapabilities reports the current provider configuration. Planners use
+// mock to switch to mock tools when the gateway serves mock plans, and check
+// the KBs, timeout and trace header against their own (pkg/drift).
+func (s *server) GetCapabilities(context.Context, *pb.CapabilitiesRequest) (*pb.CapabilitiesResponse, error) {
+	llm, _ := s.runtime()
+	if llm == nil {
+		return nil, status.Error(codes.Unavailable, "LLM runtime not initialized")
+	}
+	resp := &pb.CapabilitiesResponse{
+		Provider:       string(llm.Provider),
+		Model:          llm.Model,
+		Mock:           llm.Provider == providerMock,
+		Version:        buildinfo.Version,
+		KnowledgeBases: s.kbs.Names(),
+		TraceHeader:    strings.ToLower(string(logger.TraceIDKey)),
+	}
+	for _, p := range llm.chainNames() {
+		resp.Providers = append(resp.Providers, string(p))
+	}
+	resp.TimeoutSeconds = int32(s.requestTimeout.Seconds()) * int32(len(resp.Providers))
+	return resp, nil
+}
+
+// GetVersion reports the gateway's build info, as GET /version does.
+func (s *server) GetVersion(ctx context.Context, _ *pb.VersionRequest) (*pb.VersionResponse, error) {
+	info := buildinfo.Get(SERVICE_NAME, s.features(ctx))
+	return &pb.VersionResponse{
+		Service:   info.Service,
+		Version:   info.Version,
+		GitSha:    info.GitSHA,
+		BuildTime: info.BuildTime,
+		GoVersion: info.GoVersion,
+		Features:  info.Features,
+	}, nil
+}
+
+// features lists the optional features enabled now, for build info: the
+// subsystems configured on and the feature flags on by default.
+func (s *server) features(ctx context.Context) []string {
+	llm, pii := s.runtime()
+	out := buildinfo.Enabled(s.flags.Snapshot(ctx, ""))
+	for name, on := range map[string]bool{
+		"failover":           llm != nil && len(llm.Fallbacks) > 0,
+		"pii_scrub":          pii != nil,
+		"moderation":         s.moderation != nil,
+		"vision_fetch":       s.vision != nil && s.vision.policy != nil,
+		"rag":                s.vectorDB != nil,
+		"queue":              s.queue != nil,
+		"retry":              s.retry != nil,
+		"rate_limit":         s.rateLimit != nil,
+		"model_probes":       s.modelProbeInterval > 0,
+		"chaos":              s.chaos.Enabled(),
+		"tool_discovery":     s.tools != nil,
+		"retrieval_fallback": s.retrievalFallback,
+		"llm_record":         s.recorder != nil,
+		"race":               s.race && llm != nil && len(llm.Fallbacks) > 0,
+	} {
+		if on {
+			out = append(out, name)
+		}
+	}
+	return out
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+
+	pb "backend-go-model-gateway/proto/proto"
+)
+
+func TestGetCapabilities(t *testing.T) {
+	s := &server{llm: &llmRuntime{Provider: providerOpenRouter, Model: "mistralai/mistral-7b-instruct:free", Fallbacks: []*llmRuntime{{Provider: providerMock}}}, requestTimeout: 30 * time.Second}
+	resp, err := s.GetCapabilities(context.Background(), &pb.CapabilitiesRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.GetProvider() != "openrouter" || resp.GetModel() != "mistralai/mistral-7b-instruct:free" || resp.GetMock() || !reflect.DeepEqual(resp.GetProviders(), []string{"openrouter", "mock"}) {
+		t.Fatalf("capabilities = %v", resp)
+	}
+	if !reflect.DeepEqual(resp.GetKnowledgeBases(), defaultKnowledgeBases) {
+		t.Fatalf("knowledge bases = %v", resp.GetKnowledgeBases())
+	}
+	// Each provider in the chain gets the full request timeout.
+	if resp.GetTimeoutSeconds() != 60 || resp.GetTraceHeader() != "x-trace-id" {
+		t.Fatalf("timeout %d, trace header %q", resp.GetTimeoutSeconds(), resp.GetTraceHeader())
+	}
+
+	s.llm = &llmRuntime{Provider: providerMock}
+	if resp, err := s.GetCapabilities(context.Background(), &pb.CapabilitiesRequest{}); err != nil || !resp.GetMock() {
+		t.Fatalf("mock capabilities = %v, %v", resp, err)
+	}
+}
+
+func TestGetVersion(t *testing.T) {
+	s := &server{
+		llm:        &llmRuntime{Provider: providerOpenRouter, Fallbacks: []*llmRuntime{{Provider: providerMock}}},
+		pii:        &piiScrubber{},
+		moderation: &moderation{name: "local"},
+	}
+	resp, err := s.GetVersion(context.Background(), &pb.VersionRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.GetService() != SERVICE_NAME || resp.GetVersion() == "" || resp.GetGoVersion() == "" {
+		t.Fatalf("version = %v", resp)
+	}
+	features := resp.GetFeatures()
+	if !sort.StringsAreSorted(features) {
+		t.Fatalf("features %v not sorted", features)
+	}
+	for _, name := range []string{"failover", "moderation", "pii_scrub", flagRateLimitMockFallback} {
+		if !slices.Contains(features, name) {
+			t.Fatalf("features = %v, want %s", features, name)
+		}
+	}
+	for _, name := range []string{"rag", "queue", "vision_fetch", "chaos"} {
+		if slices.Contains(features, name) {
+			t.Fatalf("features = %v, %s is off", features, name)
+		}
+	}
+}
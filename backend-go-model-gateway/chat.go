@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"backend-go-model-gateway/internal/logger"
+	"backend-go-model-gateway/pkg/chaos"
+	pb "backend-go-model-gateway/proto/proto"
+	"backend-go-model-gateway/service"
+
+	"github.com/sashabaranov/go-openai"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Chat runs a general-purpose chat completion. The messages are sent as
+// they are, through the same provider chain, capacity queue, retries and PII
+// scrubbing as GetPlan. The mock provider echoes the last user message.
+func (s *server) Chat(ctx context.Context, in *pb.ChatRequest) (*pb.ChatResponse, error) {
+	start := time.Now()
+	ctx = service.ContextWithTraceIDFromIncomingGRPC(ctx)
+	messages, err := chatMessages(in.GetMessages())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	llm, scrubber := s.runtime()
+	if llm == nil {
+		return nil, status.Error(codes.Unavailable, "LLM runtime not initialized")
+	}
+
+	lg := logger.NewContextLogger(ctx)
+	peerName := ""
+	if id, ok := peerIdentityFromContext(ctx); ok {
+		peerName = id.Name
+	}
+	lg.Info("Chat", "peer", peerName, "principal", service.PrincipalFromIncomingGRPC(ctx), "provider", llm.Provider, "model", in.GetModel(), "priority", requestPriority(in.GetPriority()), "messages", len(messages))
+
+	callCtx, cancel := context.WithTimeout(ctx, s.requestTimeout)
+	defer cancel()
+	release, err := s.acquireProvider(callCtx, in.GetPriority())
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	gen, clamped := chatGeneration(in, s.maxTokensCap)
+	if len(clamped) > 0 {
+		lg.Warn("generation_params_clamped", "provider", llm.Provider, "params", clamped)
+	}
+	chain, providerAllowed := llm.chain(in.GetProvider())
+	if !providerAllowed {
+		lg.Warn("preferred_provider_not_allowed", "preferred", in.GetProvider(), "provider", llm.Provider)
+	}
+	for i, current := range chain {
+		attemptCtx := callCtx
+		if i > 0 {
+			var cancelAttempt context.CancelFunc
+			attemptCtx, cancelAttempt = context.WithTimeout(ctx, s.requestTimeout)
+			defer cancelAttempt()
+		}
+		resp, err := s.chatWith(attemptCtx, current, in, messages, gen, scrubber, i == 0)
+		if err == nil {
+			if i > 0 {
+				lg.Info("llm_failover_served", "provider", current.Provider, "model", resp.GetModel(), "primary", chain[0].Provider)
+			}
+			resp.LatencyMs = time.Since(start).Milliseconds()
+			return resp, nil
+		}
+		if i+1 < len(chain) && ctx.Err() == nil && failoverWorthy(err) {
+			lg.Warn("llm_failover", "provider", current.Provider, "next", chain[i+1].Provider, "error", err)
+			continue
+		}
+		return nil, err
+	}
+	return nil, status.Error(codes.Unavailable, "LLM runtime not initialized")
+}
+
+// chatWith sends a Chat request to one provider of the chain. Only the first
+// may use the request's preferred model.
+func (s *server) chatWith(ctx context.Context, llm *llmRuntime, in *pb.ChatRequest, messages []openai.ChatCompletionMessage, gen generation, scrubber *piiScrubber, first bool) (*pb.ChatResponse, error) {
+	if llm.Provider == providerMock {
+		if err := s.chaos.Inject(ctx, chaos.Provider); err != nil {
+			return nil, err
+		}
+		resp, err := mockChat(ctx, llm, in, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		resp.Provider = string(providerMock)
+		return resp, nil
+	}
+	if llm.Client == nil {
+		return nil, status.Error(codes.Unavailable, "LLM client not initialized")
+	}
+	model := llm.Model
+	if first {
+		var ok bool
+		if model, ok = llm.planModel(in.GetModel()); !ok {
+			logger.NewContextLogger(ctx).Warn("preferred_model_not_allowed", "preferred", in.GetModel(), "model", model)
+		}
+	}
+
+	var pii *piiSession
+	if scrubber.appliesTo(llm.Provider) {
+		pii = scrubber.session()
+		messages = scrubMessages(pii, messages)
+		pii.logScrubbed(ctx, "Chat", llm.Provider, model)
+	}
+	req := openai.ChatCompletionRequest{Model: model, Messages: messages}
+	gen.apply(&req)
+	resp, err := s.createChatCompletion(ctx, llm, req)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Choices) == 0 {
+		return nil, status.Error(codes.Internal, "chat: empty LLM response")
+	}
+	content := replyAnswer(resp.Choices[0].Message.Content)
+	if pii != nil {
+		content = pii.restoreText(content)
+	}
+	return &pb.ChatResponse{
+		Content:          content,
+		Provider:         string(llm.Provider),
+		Model:            model,
+		FinishReason:     string(resp.Choices[0].FinishReason),
+		PromptTokens:     int32(resp.Usage.PromptTokens),
+		CompletionTokens: int32(resp.Usage.CompletionTokens),
+	}, nil
+}
+
+// chatMessages validates a Chat request's messages and converts them. Parts
+// that are all text are joined into the message's content; messages with an
+// image keep their parts.
+func chatMessages(in []*pb.ChatMessage) ([]openai.ChatCompletionMessage, error) {
+	if len(in) == 0 {
+		return nil, fmt.Errorf("messages are required")
+	}
+	out := make([]openai.ChatCompletionMessage, 0, len(in))
+	for i, m := range in {
+		role := m.GetRole()
+		switch role {
+		case openai.ChatMessageRoleSystem, openai.ChatMessageRoleUser, openai.ChatMessageRoleAssistant:
+		default:
+			return nil, fmt.Errorf("message %d: unsupported role %q", i, role)
+		}
+		msg := openai.ChatCompletionMessage{Role: role, Content: m.GetContent()}
+		if len(m.GetParts()) > 0 {
+			var texts []string
+			var parts []openai.ChatMessagePart
+			image := false
+			for j, p := range m.GetParts() {
+				switch p.GetType() {
+				case "text":
+					texts = append(texts, p.GetText())
+					parts = append(parts, openai.ChatMessagePart{Type: openai.ChatMessagePartTypeText, Text: p.GetText()})
+				case "image_url":
+					url := p.GetImageUrl()
+					if !strings.HasPrefix(url, "https://") && !strings.HasPrefix(url, "data:image/") {
+						return nil, fmt.Errorf("message %d part %d: image_url must be an https or data:image/ URL", i, j)
+					}
+					image = true
+					parts = append(parts, openai.ChatMessagePart{Type: openai.ChatMessagePartTypeImageURL, ImageURL: &openai.ChatMessageImageURL{URL: url}})
+				default:
+					return nil, fmt.Errorf("message %d part %d: unsupported type %q", i, j, p.GetType())
+				}
+			}
+			msg.Content = strings.Join(texts, "\n")
+			if image {
+				if role != openai.ChatMessageRoleUser {
+					return nil, fmt.Errorf("message %d: only user messages may carry images", i)
+				}
+				msg.Content, msg.MultiContent = "", parts
+			}
+		}
+		if msg.Content == "" && len(msg.MultiContent) == 0 {
+			return nil, fmt.Errorf("message %d is empty", i)
+		}
+		out = append(out, msg)
+	}
+	return out, nil
+}
+
+// planTurns validates a PlanRequest's earlier turns and converts them. They
+// are plain text from the model ("assistant") or answering it ("user"); the
+// system prompt and images stay the gateway's and the prompt's.
+func planTurns(in []*pb.ChatMessage) ([]openai.ChatCompletionMessage, error) {
+	out := make([]openai.ChatCompletionMessage, 0, len(in))
+	for i, m := range in {
+		switch m.GetRole() {
+		case openai.ChatMessageRoleUser, openai.ChatMessageRoleAssistant:
+		default:
+			return nil, fmt.Errorf("messages[%d]: unsupported role %q", i, m.GetRole())
+		}
+		if len(m.GetParts()) > 0 {
+			return nil, fmt.Errorf("messages[%d]: parts are not supported, use content", i)
+		}
+		if m.GetContent() == "" {
+			return nil, fmt.Errorf("messages[%d] is empty", i)
+		}
+		out = append(out, openai.ChatCompletionMessage{Role: m.GetRole(), Content: m.GetContent()})
+	}
+	return out, nil
+}
+
+// scrubMessages returns a copy of messages with their text scrubbed.
+func scrubMessages(pii *piiSession, messages []openai.ChatCompletionMessage) []openai.ChatCompletionMessage {
+	out := make([]openai.ChatCompletionMessage, len(messages))
+	for i, m := range messages {
+		m.Content = pii.scrub(m.Content)
+		if len(m.MultiContent) > 0 {
+			parts := make([]openai.ChatMessagePart, len(m.MultiContent))
+			for j, p := range m.MultiContent {
+				if p.Type == openai.ChatMessagePartTypeText {
+					p.Text = pii.scrub(p.Text)
+				}
+				parts[j] = p
+			}
+			m.MultiContent = parts
+		}
+		out[i] = m
+	}
+	return out
+}
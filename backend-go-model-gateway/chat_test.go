@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	pb "backend-go-model-gateway/proto/proto"
+
+	"github.com/sashabaranov/go-openai"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestChat(t *testing.T) {
+	var sent map[string]any
+	code := http.StatusOK
+	llm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent = nil
+		_ = json.NewDecoder(r.Body).Decode(&sent)
+		w.Header().Set("Content-Type", "application/json")
+		if code != http.StatusOK {
+			w.WriteHeader(code)
+			_, _ = w.Write([]byte(`{"error":{"message":"overloaded"}}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(openai.ChatCompletionResponse{
+			Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Content: "<think>short</think>Summary for [EMAIL_1]."}, FinishReason: openai.FinishReasonStop}},
+			Usage:   openai.Usage{PromptTokens: 12, CompletionTokens: 4},
+		})
+	}))
+	defer llm.Close()
+	cfg := openai.DefaultConfig("key")
+	cfg.BaseURL = llm.URL
+	s := &server{
+		llm: &llmRuntime{
+			Provider:  providerOpenRouter,
+			Model:     "openai/gpt-4o",
+			Client:    openai.NewClientWithConfig(cfg),
+			Fallbacks: []*llmRuntime{{Provider: providerMock, Model: "mock"}},
+		},
+		pii:            &piiScrubber{providers: []llmProvider{providerOpenRouter}, patterns: builtinPIIPatterns},
+		requestTimeout: 5 * time.Second,
+	}
+
+	temperature := float32(0)
+	resp, err := s.Chat(context.Background(), &pb.ChatRequest{
+		Messages: []*pb.ChatMessage{
+			{Role: "system", Content: "Summarize in one sentence."},
+			{Role: "user", Parts: []*pb.ChatContentPart{{Type: "text", Text: "Mail jane@example.com"}, {Type: "text", Text: "about the offsite."}}},
+		},
+		Temperature: &temperature,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.GetContent() != "Summary for jane@example.com." || resp.GetProvider() != "openrouter" || resp.GetModel() != "openai/gpt-4o" || resp.GetFinishReason() != "stop" || resp.GetPromptTokens() != 12 {
+		t.Fatalf("response = %v", resp)
+	}
+	messages, _ := sent["messages"].([]any)
+	if len(messages) != 2 || sent["model"] != "openai/gpt-4o" || sent["temperature"] == nil {
+		t.Fatalf("request = %v", sent)
+	}
+	// Text parts are joined, and the address never reaches the provider.
+	if user := messages[1].(map[string]any); user["content"] != "Mail [EMAIL_1]\nabout the offsite." {
+		t.Fatalf("user message = %v", user)
+	}
+
+	// Images are sent as content parts.
+	if _, err := s.Chat(context.Background(), &pb.ChatRequest{Messages: []*pb.ChatMessage{{Role: "user", Parts: []*pb.ChatContentPart{
+		{Type: "text", Text: "What is this?"},
+		{Type: "image_url", ImageUrl: "data:image/png;base64,iVBORw0KGgo="},
+	}}}}); err != nil {
+		t.Fatal(err)
+	}
+	parts, _ := sent["messages"].([]any)[0].(map[string]any)["content"].([]any)
+	if len(parts) != 2 || parts[1].(map[string]any)["type"] != "image_url" {
+		t.Fatalf("image message = %v", sent["messages"])
+	}
+
+	// A 5xx fails over to the next provider.
+	code = http.StatusServiceUnavailable
+	resp, err = s.Chat(context.Background(), &pb.ChatRequest{Messages: []*pb.ChatMessage{{Role: "user", Content: "classify: refund request"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.GetProvider() != "mock" || resp.GetContent() != "Mock reply: classify: refund request" {
+		t.Fatalf("failover response = %v", resp)
+	}
+}
+
+func TestChat_InvalidMessages(t *testing.T) {
+	s := &server{llm: &llmRuntime{Provider: providerMock, Model: "mock"}}
+	for name, messages := range map[string][]*pb.ChatMessage{
+		"none":         nil,
+		"role":         {{Role: "tool", Content: "x"}},
+		"empty":        {{Role: "user"}},
+		"part type":    {{Role: "user", Parts: []*pb.ChatContentPart{{Type: "audio"}}}},
+		"http image":   {{Role: "user", Parts: []*pb.ChatContentPart{{Type: "image_url", ImageUrl: "http://example.com/a.png"}}}},
+		"system image": {{Role: "system", Parts: []*pb.ChatContentPart{{Type: "image_url", ImageUrl: "https://example.com/a.png"}}}},
+	} {
+		_, err := s.Chat(context.Background(), &pb.ChatRequest{Messages: messages})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("%s: err = %v, want InvalidArgument", name, err)
+		}
+	}
+
+	resp, err := s.Chat(context.Background(), &pb.ChatRequest{Messages: []*pb.ChatMessage{{Role: "user", Content: strings.Repeat("a", 300)}}})
+	if err != nil || len(resp.GetContent()) != len("Mock reply: ")+200 {
+		t.Fatalf("mock reply = %v, %v", resp, err)
+	}
+}
+
+func TestGetPlan_SendsTurns(t *testing.T) {
+	var sent []openai.ChatCompletionMessage
+	llm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req openai.ChatCompletionRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		sent = req.Messages
+		_ = json.NewEncoder(w).Encode(openai.ChatCompletionResponse{
+			Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Role: "assistant", Content: `{"steps":["Reply to [EMAIL_1]"]}`}}},
+		})
+	}))
+	defer llm.Close()
+	cfg := openai.DefaultConfig("")
+	cfg.BaseURL = llm.URL
+	s := &server{
+		llm:            &llmRuntime{Provider: providerOpenRouter, Model: "m", Client: openai.NewClientWithConfig(cfg)},
+		requestTimeout: 5 * time.Second,
+		pii:            &piiScrubber{providers: []llmProvider{providerOpenRouter}, patterns: builtinPIIPatterns},
+	}
+
+	plan := `{"tool":{"name":"contacts_lookup","args":{"name":"Sam"}}}`
+	resp, err := s.GetPlan(context.Background(), &pb.PlanRequest{Prompt: "email Sam", Messages: []*pb.ChatMessage{
+		{Role: "assistant", Content: plan},
+		{Role: "user", Content: "<tool_result>\nsam@example.com\n</tool_result>"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The turns follow the prompt as their own messages, scrubbed like it.
+	if len(sent) != 4 || sent[1].Role != "user" || !strings.Contains(sent[1].Content, "User prompt: email Sam") ||
+		sent[2].Role != "assistant" || sent[2].Content != plan ||
+		sent[3].Role != "user" || sent[3].Content != "<tool_result>\n[EMAIL_1]\n</tool_result>" {
+		t.Fatalf("messages = %+v", sent)
+	}
+	if !strings.Contains(resp.GetPlan(), "Reply to sam@example.com") {
+		t.Fatalf("plan = %s, want the email restored", resp.GetPlan())
+	}
+
+	for name, messages := range map[string][]*pb.ChatMessage{
+		"system": {{Role: "system", Content: "x"}},
+		"empty":  {{Role: "user"}},
+		"parts":  {{Role: "user", Parts: []*pb.ChatContentPart{{Type: "text", Text: "x"}}}},
+	} {
+		_, err := s.GetPlan(context.Background(), &pb.PlanRequest{Prompt: "p", Messages: messages})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("%s: err = %v, want InvalidArgument", name, err)
+		}
+	}
+}
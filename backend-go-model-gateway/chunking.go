@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	ingestFormatText     = "text"
+	ingestFormatMarkdown = "markdown"
+	ingestFormatPDF      = "pdf"
+)
+
+// chunkSeparators are tried in order when a piece of text is longer than the
+// chunk size: paragraphs, lines, sentences, then words.
+var chunkSeparators = []string{"\n\n", "\n", ". ", " "}
+
+var (
+	markdownHeading = regexp.MustCompile(`^#{1,6}\s`)
+	// pdfHyphenBreak joins words hyphenated across a line break.
+	pdfHyphenBreak = regexp.MustCompile(`(\p{L})-\n(\p{Ll})`)
+	// pdfLineBreak joins lines wrapped inside a paragraph.
+	pdfLineBreak = regexp.MustCompile(`([^\n])\n([^\n])`)
+)
+
+// chunkContent splits content into chunks of at most size characters, with
+// consecutive chunks sharing up to overlap characters of context. format
+// selects the pre-processing:
+//
+//   - text: split on paragraphs, then lines, sentences and words
+//   - markdown: as text, but chunks never span a heading, so each section's
+//     chunks stay on topic (headings inside fenced code are ignored)
+//   - pdf: text extracted from a PDF; form feeds are page breaks, wrapped
+//     lines and hyphenated words are re-joined first
+func chunkContent(content, format string, size, overlap int) ([]string, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("chunk size must be positive, got %d", size)
+	}
+	if overlap < 0 || overlap >= size {
+		return nil, fmt.Errorf("chunk overlap must be in [0, %d), got %d", size, overlap)
+	}
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+
+	var sections []string
+	switch format {
+	case ingestFormatText, "":
+		sections = []string{content}
+	case ingestFormatMarkdown:
+		sections = markdownSections(content)
+	case ingestFormatPDF:
+		sections = []string{normalizePDFText(content)}
+	default:
+		return nil, fmt.Errorf("unsupported format %q (supported: text, markdown, pdf)", format)
+	}
+
+	var chunks []string
+	for _, section := range sections {
+		for _, chunk := range splitText(section, size, overlap, chunkSeparators) {
+			if chunk = strings.TrimSpace(chunk); chunk != "" {
+				chunks = append(chunks, chunk)
+			}
+		}
+	}
+	return chunks, nil
+}
+
+// markdownSections splits a Markdown document before each ATX heading.
+func markdownSections(content string) []string {
+	var sections []string
+	var cur strings.Builder
+	inFence := false
+	for _, line := range strings.SplitAfter(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+		}
+		if !inFence && markdownHeading.MatchString(trimmed) && cur.Len() > 0 {
+			sections = append(sections, cur.String())
+			cur.Reset()
+		}
+		cur.WriteString(line)
+	}
+	if cur.Len() > 0 {
+		sections = append(sections, cur.String())
+	}
+	return sections
+}
+
+// normalizePDFText undoes the line layout of text extracted from a PDF: pages
+// become paragraphs and wrapped lines are joined.
+func normalizePDFText(content string) string {
+	content = strings.ReplaceAll(content, "\f", "\n\n")
+	content = pdfHyphenBreak.ReplaceAllString(content, "$1$2")
+	return pdfLineBreak.ReplaceAllString(content, "$1 $2")
+}
+
+// splitText recursively splits text on the first separator it contains,
+// descending to finer separators for pieces still longer than size, and
+// merges the pieces back into chunks of up to size characters.
+func splitText(text string, size, overlap int, seps []string) []string {
+	if utf8.RuneCountInString(text) <= size {
+		return []string{text}
+	}
+	sep, finer := "", []string(nil)
+	for i, s := range seps {
+		if strings.Contains(text, s) {
+			sep, finer = s, seps[i+1:]
+			break
+		}
+	}
+	if sep == "" {
+		return hardSplit(text, size, overlap)
+	}
+
+	var out, fitting []string
+	for _, piece := range strings.Split(text, sep) {
+		if utf8.RuneCountInString(piece) <= size {
+			fitting = append(fitting, piece)
+			continue
+		}
+		out = append(out, mergeSplits(fitting, sep, size, overlap)...)
+		fitting = nil
+		out = append(out, splitText(piece, size, overlap, finer)...)
+	}
+	return append(out, mergeSplits(fitting, sep, size, overlap)...)
+}
+
+// mergeSplits packs pieces (each at most size long) into chunks joined by sep.
+// A new chunk starts with the trailing pieces of the previous one that fit in
+// overlap characters.
+func mergeSplits(pieces []string, sep string, size, overlap int) []string {
+	sepLen := utf8.RuneCountInString(sep)
+	var out, cur []string
+	total := 0 // length of strings.Join(cur, sep)
+	for _, p := range pieces {
+		n := utf8.RuneCountInString(p)
+		if len(cur) > 0 && total+sepLen+n > size {
+			out = append(out, strings.Join(cur, sep))
+			for len(cur) > 0 && (total > overlap || total+sepLen+n > size) {
+				total -= utf8.RuneCountInString(cur[0])
+				if len(cur) > 1 {
+					total -= sepLen
+				}
+				cur = cur[1:]
+			}
+		}
+		if len(cur) > 0 {
+			total += sepLen
+		}
+		cur = append(cur, p)
+		total += n
+	}
+	if len(cur) > 0 {
+		out = append(out, strings.Join(cur, sep))
+	}
+	return out
+}
+
+// hardSplit cuts text with no separators left (e.g. a very long token) into
+// size-rune windows.
+func hardSplit(text string, size, overlap int) []string {
+	runes := []rune(text)
+	var out []string
+	for start := 0; start < len(runes); start += size - overlap {
+		end := min(start+size, len(runes))
+		out = append(out, string(runes[start:end]))
+		if end == len(runes) {
+			break
+		}
+	}
+	return out
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestChunkContent_RespectsSizeAndOverlap(t *testing.T) {
+	text := strings.Repeat("The quick brown fox jumps over the lazy dog. ", 40)
+	chunks, err := chunkContent(text, ingestFormatText, 200, 50)
+	if err != nil {
+		t.Fatalf("chunkContent: %v", err)
+	}
+	if len(chunks) < 2 {
+		t.Fatalf("got %d chunks, want several", len(chunks))
+	}
+	for i, c := range chunks {
+		if n := utf8.RuneCountInString(c); n > 200 {
+			t.Errorf("chunk %d has %d characters, want <= 200", i, n)
+		}
+	}
+	// Consecutive chunks share context.
+	tail := chunks[0][len(chunks[0])-20:]
+	if !strings.Contains(chunks[1], tail) {
+		t.Errorf("chunk 1 does not overlap chunk 0: %q / %q", chunks[0], chunks[1])
+	}
+}
+
+func TestChunkContent_HardSplitsLongTokens(t *testing.T) {
+	chunks, err := chunkContent(strings.Repeat("x", 25), ingestFormatText, 10, 0)
+	if err != nil {
+		t.Fatalf("chunkContent: %v", err)
+	}
+	if got := strings.Join(chunks, "|"); got != "xxxxxxxxxx|xxxxxxxxxx|xxxxx" {
+		t.Errorf("chunks = %s", got)
+	}
+}
+
+func TestChunkContent_MarkdownSplitsOnHeadings(t *testing.T) {
+	doc := "# Sleep\nEight hours.\n\n```sh\n# not a heading\n```\n## Diet\nMore greens.\n"
+	chunks, err := chunkContent(doc, ingestFormatMarkdown, 1000, 0)
+	if err != nil {
+		t.Fatalf("chunkContent: %v", err)
+	}
+	if len(chunks) != 2 || !strings.HasPrefix(chunks[0], "# Sleep") || !strings.Contains(chunks[0], "# not a heading") || !strings.HasPrefix(chunks[1], "## Diet") {
+		t.Errorf("chunks = %q", chunks)
+	}
+}
+
+func TestChunkContent_PDFRejoinsWrappedLines(t *testing.T) {
+	chunks, err := chunkContent("The onboard-\ning protocol\nstarts here.\fPage two.", ingestFormatPDF, 1000, 0)
+	if err != nil {
+		t.Fatalf("chunkContent: %v", err)
+	}
+	if len(chunks) != 1 || chunks[0] != "The onboarding protocol starts here.\n\nPage two." {
+		t.Errorf("chunks = %q", chunks)
+	}
+}
+
+func TestChunkContent_RejectsBadOptions(t *testing.T) {
+	for _, tc := range []struct {
+		format        string
+		size, overlap int
+	}{
+		{"docx", 100, 0},
+		{ingestFormatText, 0, 0},
+		{ingestFormatText, 100, 100},
+	} {
+		if _, err := chunkContent("text", tc.format, tc.size, tc.overlap); err == nil {
+			t.Errorf("chunkContent(%q, %d, %d): expected an error", tc.format, tc.size, tc.overlap)
+		}
+	}
+}
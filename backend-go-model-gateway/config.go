@@ -0,0 +1,774 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend-go-model-gateway/pkg/promptguard"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the gateway's configuration. Each setting is read, in increasing
+// precedence, from its default, from the GATEWAY_CONFIG_FILE (YAML or JSON,
+// keyed by the yaml tags) and from its environment variable (the env tags).
+// Components get their section of it from main; settings without an env tag
+// here (credentials, TLS, tracing, ...) are read from the environment by
+// their package.
+type Config struct {
+	GRPCPort              int    `yaml:"grpc_port" env:"MODEL_GATEWAY_GRPC_PORT"`
+	HTTPPort              int    `yaml:"http_port" env:"MODEL_GATEWAY_HTTP_PORT"`
+	RequestTimeoutSeconds int    `yaml:"request_timeout_seconds" env:"REQUEST_TIMEOUT_SECONDS"`
+	RedisAddr             string `yaml:"redis_addr" env:"REDIS_ADDR"`
+	TLSSource             string `yaml:"tls_source" env:"TLS_SOURCE"`
+	GRPCReflection        bool   `yaml:"grpc_reflection" env:"GRPC_REFLECTION"`
+	REST                  bool   `yaml:"rest" env:"GATEWAY_REST"`
+
+	LLM        LLMConfig        `yaml:"llm"`
+	Embeddings EmbeddingsConfig `yaml:"embeddings"`
+	RAG        RAGConfig        `yaml:"rag"`
+	PII        PIIConfig        `yaml:"pii"`
+	Moderation ModerationConfig `yaml:"moderation"`
+	RateLimit  RateLimitConfig  `yaml:"rate_limit"`
+	Ingest     IngestConfig     `yaml:"ingest"`
+	Vision     VisionConfig     `yaml:"vision"`
+	Prompts    PromptsConfig    `yaml:"prompts"`
+	Tools      ToolsConfig      `yaml:"tools"`
+	Peers      PeersConfig      `yaml:"peers"`
+
+	// file is the GATEWAY_CONFIG_FILE the config was loaded from, if any;
+	// sources records where each setting came from (default, file or env).
+	file    string
+	sources map[string]string
+}
+
+// LLMConfig is the provider part of Config.
+type LLMConfig struct {
+	Provider                   string   `yaml:"provider" env:"LLM_PROVIDER"`
+	Providers                  []string `yaml:"providers" env:"LLM_PROVIDERS"`
+	MaxTokensCap               int      `yaml:"max_tokens_cap" env:"LLM_MAX_TOKENS_CAP"`
+	HealthProbeIntervalSeconds int      `yaml:"health_probe_interval_seconds" env:"LLM_HEALTH_PROBE_INTERVAL_SECONDS"`
+	RetrievalFallback          bool     `yaml:"retrieval_fallback" env:"LLM_RETRIEVAL_FALLBACK"`
+	Race                       bool     `yaml:"race" env:"LLM_RACE"`
+	AllowedModels              []string `yaml:"allowed_models" env:"LLM_ALLOWED_MODELS"`
+	ToolCalling                string   `yaml:"tool_calling" env:"LLM_TOOL_CALLING"`
+	Reasoning                  string   `yaml:"reasoning" env:"LLM_REASONING"`
+	PlanRepairAttempts         int      `yaml:"plan_repair_attempts" env:"LLM_PLAN_REPAIR_ATTEMPTS"`
+	ModelProbeIntervalSeconds  int      `yaml:"model_probe_interval_seconds" env:"LLM_MODEL_PROBE_INTERVAL_SECONDS"`
+	// PriceInputPerMTok and PriceOutputPerMTok are USD per million tokens;
+	// both or neither are set.
+	PriceInputPerMTok  string `yaml:"price_input_per_mtok" env:"LLM_PRICE_INPUT_PER_MTOK"`
+	PriceOutputPerMTok string `yaml:"price_output_per_mtok" env:"LLM_PRICE_OUTPUT_PER_MTOK"`
+
+	Ollama struct {
+		BaseURL string `yaml:"base_url" env:"OLLAMA_BASE_URL"`
+		Model   string `yaml:"model" env:"OLLAMA_MODEL_NAME"`
+	} `yaml:"ollama"`
+	OpenRouter struct {
+		Model string `yaml:"model" env:"OPENROUTER_MODEL_NAME"`
+	} `yaml:"openrouter"`
+	Anthropic struct {
+		BaseURL   string `yaml:"base_url" env:"ANTHROPIC_BASE_URL"`
+		Model     string `yaml:"model" env:"ANTHROPIC_MODEL_NAME"`
+		MaxTokens int    `yaml:"max_tokens" env:"ANTHROPIC_MAX_TOKENS"`
+	} `yaml:"anthropic"`
+	Mock struct {
+		FixturesDir string `yaml:"fixtures_dir" env:"MOCK_FIXTURES_DIR"`
+	} `yaml:"mock"`
+	Record struct {
+		Mode string `yaml:"mode" env:"LLM_RECORD_MODE"`
+		Dir  string `yaml:"dir" env:"LLM_RECORD_DIR"`
+	} `yaml:"record"`
+	Retry RetryConfig `yaml:"retry"`
+	Queue QueueConfig `yaml:"queue"`
+}
+
+// RetryConfig is the provider call retry policy (see retryPolicy).
+type RetryConfig struct {
+	MaxAttempts int     `yaml:"max_attempts" env:"LLM_RETRY_MAX_ATTEMPTS"`
+	BaseDelayMS int     `yaml:"base_delay_ms" env:"LLM_RETRY_BASE_DELAY_MS"`
+	MaxDelayMS  int     `yaml:"max_delay_ms" env:"LLM_RETRY_MAX_DELAY_MS"`
+	BudgetRatio float64 `yaml:"budget_ratio" env:"LLM_RETRY_BUDGET_RATIO"`
+}
+
+// QueueConfig bounds concurrent provider calls (see requestQueue). Zero
+// limits mean unbounded.
+type QueueConfig struct {
+	MaxConcurrent      int `yaml:"max_concurrent" env:"LLM_MAX_CONCURRENT_REQUESTS"`
+	BatchMaxConcurrent int `yaml:"batch_max_concurrent" env:"LLM_BATCH_MAX_CONCURRENT"`
+	MaxDepth           int `yaml:"max_depth" env:"LLM_QUEUE_MAX_DEPTH"`
+	TimeoutSeconds     int `yaml:"timeout_seconds" env:"LLM_QUEUE_TIMEOUT_SECONDS"`
+}
+
+// EmbeddingsConfig selects the query embedder (see newEmbedder).
+type EmbeddingsConfig struct {
+	// Provider defaults to one that follows LLM_PROVIDER.
+	Provider  string `yaml:"provider" env:"EMBEDDINGS_PROVIDER"`
+	BaseURL   string `yaml:"base_url" env:"EMBEDDINGS_BASE_URL"`
+	Model     string `yaml:"model" env:"EMBEDDINGS_MODEL"`
+	HashDims  int    `yaml:"hash_dims" env:"EMBEDDINGS_HASH_DIMS"`
+	CacheSize int    `yaml:"cache_size" env:"EMBEDDINGS_CACHE_SIZE"`
+	// Models maps languages to models, as lang=model pairs.
+	Models string `yaml:"models" env:"EMBEDDINGS_MODELS"`
+}
+
+// RAGConfig is the retrieval pipeline (see initRAGBackend).
+type RAGConfig struct {
+	Backend       string `yaml:"backend" env:"RAG_BACKEND"`
+	RetrievalMode string `yaml:"retrieval_mode" env:"RAG_RETRIEVAL_MODE"`
+	// GRPCAddr is the Memory Service for RAG_BACKEND=memory.
+	GRPCAddr        string   `yaml:"grpc_addr" env:"RAG_GRPC_ADDR"`
+	Tenancy         string   `yaml:"tenancy" env:"RAG_TENANCY"`
+	MinScore        string   `yaml:"min_score" env:"RAG_MIN_SCORE"`
+	DedupSimilarity float64  `yaml:"dedup_similarity" env:"RAG_DEDUP_SIMILARITY"`
+	Injection       string   `yaml:"injection" env:"RAG_INJECTION"`
+	KnowledgeBases  []string `yaml:"knowledge_bases" env:"RAG_KNOWLEDGE_BASES"`
+	KBCatalogPath   string   `yaml:"kb_catalog_path" env:"KB_CATALOG_PATH"`
+
+	TagsField       string `yaml:"tags_field" env:"RAG_TAGS_FIELD"`
+	DocumentIDField string `yaml:"document_id_field" env:"RAG_DOCUMENT_ID_FIELD"`
+	CreatedAtField  string `yaml:"created_at_field" env:"RAG_CREATED_AT_FIELD"`
+	NamespaceField  string `yaml:"namespace_field" env:"RAG_NAMESPACE_FIELD"`
+
+	HybridRRFK       int `yaml:"hybrid_rrf_k" env:"RAG_HYBRID_RRF_K"`
+	HybridCandidates int `yaml:"hybrid_candidates" env:"RAG_HYBRID_CANDIDATES"`
+
+	DefaultLanguage       string  `yaml:"default_language" env:"RAG_DEFAULT_LANGUAGE"`
+	KBLanguages           string  `yaml:"kb_languages" env:"RAG_KB_LANGUAGES"`
+	Multilingual          bool    `yaml:"multilingual" env:"RAG_MULTILINGUAL"`
+	LanguageMinConfidence float64 `yaml:"language_min_confidence" env:"RAG_LANGUAGE_MIN_CONFIDENCE"`
+	QueryTranslation      string  `yaml:"query_translation" env:"RAG_QUERY_TRANSLATION"`
+
+	Cache    RAGCacheConfig `yaml:"cache"`
+	Rerank   RerankConfig   `yaml:"rerank"`
+	Qdrant   QdrantConfig   `yaml:"qdrant"`
+	PGVector PGVectorConfig `yaml:"pgvector"`
+	Weaviate WeaviateConfig `yaml:"weaviate"`
+	Milvus   MilvusConfig   `yaml:"milvus"`
+	Embedded EmbeddedConfig `yaml:"embedded"`
+}
+
+// RAGCacheConfig is the retrieval result cache (see cachingRAGClient).
+type RAGCacheConfig struct {
+	Size       int     `yaml:"size" env:"RAG_CACHE_SIZE"`
+	TTLSeconds int     `yaml:"ttl_seconds" env:"RAG_CACHE_TTL_SECONDS"`
+	Similarity float64 `yaml:"similarity" env:"RAG_CACHE_SIMILARITY"`
+}
+
+// RerankConfig is the reranking stage (see rerankingRAGClient).
+type RerankConfig struct {
+	Mode       string `yaml:"mode" env:"RAG_RERANKER"`
+	Candidates int    `yaml:"candidates" env:"RAG_RERANK_CANDIDATES"`
+	URL        string `yaml:"url" env:"RERANKER_URL"`
+	API        string `yaml:"api" env:"RERANKER_API"`
+	Model      string `yaml:"model" env:"RERANKER_MODEL"`
+	GRPCAddr   string `yaml:"grpc_addr" env:"RERANKER_GRPC_ADDR"`
+}
+
+// QdrantConfig is RAG_BACKEND=qdrant (see QdrantRAGClient).
+type QdrantConfig struct {
+	URL              string `yaml:"url" env:"QDRANT_URL"`
+	Collections      string `yaml:"collections" env:"QDRANT_COLLECTIONS"`
+	CollectionPrefix string `yaml:"collection_prefix" env:"QDRANT_COLLECTION_PREFIX"`
+	VectorName       string `yaml:"vector_name" env:"QDRANT_VECTOR_NAME"`
+	TextField        string `yaml:"text_field" env:"QDRANT_TEXT_FIELD"`
+	SourceField      string `yaml:"source_field" env:"QDRANT_SOURCE_FIELD"`
+	ScoreThreshold   string `yaml:"score_threshold" env:"QDRANT_SCORE_THRESHOLD"`
+	Distance         string `yaml:"distance" env:"QDRANT_DISTANCE"`
+}
+
+// PGVectorConfig is RAG_BACKEND=pgvector (see PGVectorRAGClient).
+type PGVectorConfig struct {
+	MaxConns         int    `yaml:"max_conns" env:"PGVECTOR_MAX_CONNS"`
+	Layout           string `yaml:"layout" env:"PGVECTOR_LAYOUT"`
+	Table            string `yaml:"table" env:"PGVECTOR_TABLE"`
+	TablePrefix      string `yaml:"table_prefix" env:"PGVECTOR_TABLE_PREFIX"`
+	KBColumn         string `yaml:"kb_column" env:"PGVECTOR_KB_COLUMN"`
+	IDColumn         string `yaml:"id_column" env:"PGVECTOR_ID_COLUMN"`
+	TextColumn       string `yaml:"text_column" env:"PGVECTOR_TEXT_COLUMN"`
+	SourceColumn     string `yaml:"source_column" env:"PGVECTOR_SOURCE_COLUMN"`
+	EmbeddingColumn  string `yaml:"embedding_column" env:"PGVECTOR_EMBEDDING_COLUMN"`
+	Distance         string `yaml:"distance" env:"PGVECTOR_DISTANCE"`
+	TextSearchConfig string `yaml:"text_search_config" env:"PGVECTOR_TEXT_SEARCH_CONFIG"`
+}
+
+// WeaviateConfig is RAG_BACKEND=weaviate (see WeaviateRAGClient).
+type WeaviateConfig struct {
+	URL            string `yaml:"url" env:"WEAVIATE_URL"`
+	Classes        string `yaml:"classes" env:"WEAVIATE_CLASSES"`
+	ClassPrefix    string `yaml:"class_prefix" env:"WEAVIATE_CLASS_PREFIX"`
+	TextProperty   string `yaml:"text_property" env:"WEAVIATE_TEXT_PROPERTY"`
+	SourceProperty string `yaml:"source_property" env:"WEAVIATE_SOURCE_PROPERTY"`
+	HybridAlpha    string `yaml:"hybrid_alpha" env:"WEAVIATE_HYBRID_ALPHA"`
+	Vectorizer     string `yaml:"vectorizer" env:"WEAVIATE_VECTORIZER"`
+}
+
+// MilvusConfig is RAG_BACKEND=milvus (see MilvusRAGClient).
+type MilvusConfig struct {
+	URL              string `yaml:"url" env:"MILVUS_URL"`
+	DBName           string `yaml:"db_name" env:"MILVUS_DB_NAME"`
+	Collections      string `yaml:"collections" env:"MILVUS_COLLECTIONS"`
+	CollectionPrefix string `yaml:"collection_prefix" env:"MILVUS_COLLECTION_PREFIX"`
+	IDField          string `yaml:"id_field" env:"MILVUS_ID_FIELD"`
+	VectorField      string `yaml:"vector_field" env:"MILVUS_VECTOR_FIELD"`
+	TextField        string `yaml:"text_field" env:"MILVUS_TEXT_FIELD"`
+	SourceField      string `yaml:"source_field" env:"MILVUS_SOURCE_FIELD"`
+	MetricType       string `yaml:"metric_type" env:"MILVUS_METRIC_TYPE"`
+	SearchParams     string `yaml:"search_params" env:"MILVUS_SEARCH_PARAMS"`
+}
+
+// EmbeddedConfig is RAG_BACKEND=embedded (see EmbeddedRAGClient).
+type EmbeddedConfig struct {
+	Corpus     string `yaml:"corpus" env:"EMBEDDED_RAG_CORPUS"`
+	Embeddings string `yaml:"embeddings" env:"EMBEDDED_EMBEDDINGS"`
+	HashDims   int    `yaml:"hash_dims" env:"EMBEDDED_HASH_DIMS"`
+}
+
+// PIIConfig is prompt PII scrubbing (see piiScrubber).
+type PIIConfig struct {
+	// Scrub lists the providers whose prompts are scrubbed ("off": none).
+	Scrub        string   `yaml:"scrub" env:"PII_SCRUB"`
+	Kinds        []string `yaml:"kinds" env:"PII_SCRUB_KINDS"`
+	PatternsFile string   `yaml:"patterns_file" env:"PII_SCRUB_PATTERNS_FILE"`
+	Dictionary   string   `yaml:"dictionary" env:"PII_SCRUB_DICTIONARY"`
+}
+
+// ModerationConfig is prompt and plan moderation (see moderation).
+type ModerationConfig struct {
+	Mode      string   `yaml:"mode" env:"MODERATION"`
+	Action    string   `yaml:"action" env:"MODERATION_ACTION"`
+	FailOpen  bool     `yaml:"fail_open" env:"MODERATION_FAIL_OPEN"`
+	Blocklist []string `yaml:"blocklist" env:"MODERATION_BLOCKLIST"`
+	BaseURL   string   `yaml:"base_url" env:"MODERATION_BASE_URL"`
+	Model     string   `yaml:"model" env:"MODERATION_MODEL"`
+}
+
+// RateLimitConfig is per-caller rate limiting (see rateLimiter).
+type RateLimitConfig struct {
+	RPS float64 `yaml:"rps" env:"RATE_LIMIT_RPS"`
+	// Burst defaults to RPS rounded up.
+	Burst int `yaml:"burst" env:"RATE_LIMIT_BURST"`
+	// Callers are caller=rps[:burst] entries.
+	Callers []string `yaml:"callers" env:"RATE_LIMIT_CALLERS"`
+}
+
+// IngestConfig is document ingestion (see ingestService).
+type IngestConfig struct {
+	ChunkSize int `yaml:"chunk_size" env:"INGEST_CHUNK_SIZE"`
+	// ChunkOverlap may be 0 and must be below ChunkSize.
+	ChunkOverlap     int     `yaml:"chunk_overlap" env:"INGEST_CHUNK_OVERLAP"`
+	MaxBytes         int     `yaml:"max_bytes" env:"INGEST_MAX_BYTES"`
+	EmbedBatchSize   int     `yaml:"embed_batch_size" env:"INGEST_EMBED_BATCH_SIZE"`
+	EmbedConcurrency int     `yaml:"embed_concurrency" env:"INGEST_EMBED_CONCURRENCY"`
+	EmbedRate        float64 `yaml:"embed_rate" env:"INGEST_EMBED_RATE"`
+	CheckpointDir    string  `yaml:"checkpoint_dir" env:"INGEST_CHECKPOINT_DIR"`
+}
+
+// VisionConfig is image resource handling (see visionFetcher).
+type VisionConfig struct {
+	AllowedHosts  []string `yaml:"allowed_hosts" env:"VISION_ALLOWED_HOSTS"`
+	MaxImageBytes int      `yaml:"max_image_bytes" env:"VISION_MAX_IMAGE_BYTES"`
+	MaxImages     int      `yaml:"max_images" env:"VISION_MAX_IMAGES"`
+}
+
+// PromptsConfig is the system prompt templates (see systemPrompts).
+type PromptsConfig struct {
+	Path    string `yaml:"path" env:"GATEWAY_PROMPTS_PATH"`
+	Version string `yaml:"version" env:"GATEWAY_PROMPT_VERSION"`
+	// ReloadSeconds is how often Path is checked for edits; 0 disables it.
+	ReloadSeconds int `yaml:"reload_seconds" env:"GATEWAY_PROMPTS_RELOAD_SECONDS"`
+}
+
+// ToolsConfig is tool discovery from the sandbox (see newToolCatalog).
+type ToolsConfig struct {
+	SandboxGRPCAddr string `yaml:"sandbox_grpc_addr" env:"RUST_SANDBOX_GRPC_ADDR"`
+	// DiscoveryIntervalSeconds 0 discovers only at startup.
+	DiscoveryIntervalSeconds int `yaml:"discovery_interval_seconds" env:"TOOL_DISCOVERY_INTERVAL_SECONDS"`
+}
+
+// PeersConfig is the mTLS peer allowlist (see peerPolicy).
+type PeersConfig struct {
+	// Allowed applies to every RPC without a list of its own.
+	Allowed []string `yaml:"allowed" env:"MTLS_ALLOWED_PEERS"`
+	// ByRPC replaces Allowed for one RPC, keyed by its name (case-insensitive).
+	// Each entry is also read from MTLS_ALLOWED_PEERS_<RPC>, see loadConfig.
+	ByRPC map[string][]string `yaml:"by_rpc"`
+}
+
+// chain is the failover chain, primary first (see providerChain).
+func (c LLMConfig) chain() ([]llmProvider, error) {
+	return providerChain(c.Providers, c.Provider)
+}
+
+// defaultConfig is the configuration with no file and an empty environment.
+func defaultConfig() *Config {
+	cfg := &Config{
+		GRPCPort:              DEFAULT_GRPC_PORT,
+		HTTPPort:              DEFAULT_HTTP_PORT,
+		RequestTimeoutSeconds: defaultRequestTimeoutSec,
+		TLSSource:             "pem",
+		GRPCReflection:        true,
+	}
+	cfg.LLM.Provider = defaultProvider
+	cfg.LLM.MaxTokensCap = defaultMaxTokensCap
+	cfg.LLM.HealthProbeIntervalSeconds = defaultCredentialProbeIntervalSec
+	cfg.LLM.ToolCalling = toolCallingNative
+	cfg.LLM.Reasoning = reasoningDrop
+	cfg.LLM.PlanRepairAttempts = defaultPlanRepairAttempts
+	cfg.LLM.ModelProbeIntervalSeconds = defaultModelProbeIntervalSec
+	cfg.LLM.Ollama.BaseURL = defaultOllamaBaseURL
+	cfg.LLM.Ollama.Model = "llama3"
+	cfg.LLM.OpenRouter.Model = "mistralai/mistral-7b-instruct:free"
+	cfg.LLM.Anthropic.BaseURL = defaultAnthropicBaseURL
+	cfg.LLM.Anthropic.Model = "claude-3-5-haiku-latest"
+	cfg.LLM.Anthropic.MaxTokens = defaultAnthropicMaxTokens
+	cfg.LLM.Record.Mode = recordOff
+	cfg.LLM.Retry = RetryConfig{MaxAttempts: defaultRetryMaxAttempts, BaseDelayMS: defaultRetryBaseDelayMS, MaxDelayMS: defaultRetryMaxDelayMS, BudgetRatio: defaultRetryBudgetRatio}
+	cfg.LLM.Queue.TimeoutSeconds = defaultQueueTimeoutSec
+
+	cfg.Embeddings.HashDims = 256
+	cfg.Embeddings.CacheSize = 1024
+
+	cfg.RAG = RAGConfig{
+		Backend:               ragBackendMemory,
+		RetrievalMode:         "vector",
+		GRPCAddr:              "localhost:50052",
+		Tenancy:               ragTenancyOff,
+		DedupSimilarity:       0.8,
+		Injection:             promptguard.ModeQuarantine,
+		KnowledgeBases:        slices.Clone(defaultKnowledgeBases),
+		TagsField:             "tags",
+		DocumentIDField:       "document_id",
+		CreatedAtField:        "created_at",
+		NamespaceField:        "namespace",
+		HybridRRFK:            60,
+		HybridCandidates:      3,
+		DefaultLanguage:       "en",
+		LanguageMinConfidence: 0.5,
+		QueryTranslation:      "off",
+		Cache:                 RAGCacheConfig{Size: 512, TTLSeconds: 30, Similarity: 0.97},
+		Rerank:                RerankConfig{Mode: "off", Candidates: 4, API: "cohere"},
+		Qdrant:                QdrantConfig{URL: "http://localhost:6333", TextField: "text", SourceField: "source", Distance: "Cosine"},
+		PGVector: PGVectorConfig{
+			Layout: "label", Table: "rag_documents", KBColumn: "kb", IDColumn: "id", TextColumn: "text",
+			SourceColumn: "source", EmbeddingColumn: "embedding", Distance: "cosine", TextSearchConfig: "simple",
+		},
+		Weaviate: WeaviateConfig{URL: "http://localhost:8080", TextProperty: "text", SourceProperty: "source", Vectorizer: "gateway"},
+		Milvus: MilvusConfig{
+			URL: "http://localhost:19530", IDField: "id", VectorField: "vector", TextField: "text",
+			SourceField: "source", MetricType: "COSINE",
+		},
+		Embedded: EmbeddedConfig{Embeddings: "hash", HashDims: 256},
+	}
+
+	cfg.PII = PIIConfig{Scrub: "off", Kinds: []string{"email", "phone", "national_id"}}
+	cfg.Moderation = ModerationConfig{Mode: "off", Action: "reject", BaseURL: defaultModerationBaseURL, Model: defaultModerationModel}
+	cfg.Ingest = IngestConfig{
+		ChunkSize: 1000, ChunkOverlap: 150, MaxBytes: 10 << 20,
+		EmbedBatchSize: defaultIngestBatchSize, EmbedConcurrency: defaultIngestConcurrency,
+	}
+	cfg.Vision = VisionConfig{MaxImageBytes: defaultVisionMaxImageBytes, MaxImages: defaultVisionMaxImages}
+	cfg.Prompts = PromptsConfig{Version: defaultPromptVersion, ReloadSeconds: defaultPromptsReloadSec}
+	cfg.Tools.DiscoveryIntervalSeconds = defaultToolDiscoveryIntervalSec
+	return cfg
+}
+
+// loadConfig reads the configuration (see Config) and validates it.
+func loadConfig() (*Config, error) {
+	cfg := defaultConfig()
+	cfg.file = os.Getenv("GATEWAY_CONFIG_FILE")
+	if cfg.file != "" {
+		raw, err := os.ReadFile(cfg.file)
+		if err != nil {
+			return nil, fmt.Errorf("GATEWAY_CONFIG_FILE: %w", err)
+		}
+		// YAML is a superset of JSON, so one decoder reads both.
+		dec := yaml.NewDecoder(bytes.NewReader(raw))
+		dec.KnownFields(true)
+		if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("GATEWAY_CONFIG_FILE %s: %w", cfg.file, err)
+		}
+	}
+
+	defaults := configSettings(defaultConfig())
+	cfg.sources = map[string]string{}
+	var errs []error
+	for i, s := range configSettings(cfg) {
+		source := "default"
+		if formatSetting(s.value) != formatSetting(defaults[i].value) {
+			source = "file"
+		}
+		if v := os.Getenv(s.env); v != "" {
+			if err := parseSetting(s.value, v); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", s.env, err))
+			}
+			source = "env"
+		}
+		cfg.sources[s.env] = source
+	}
+	cfg.loadPeersByRPC()
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// loadPeersByRPC overlays the per-RPC allowlists, which have no fixed
+// variable name: MTLS_ALLOWED_PEERS_<RPC> replaces the file's list for <RPC>.
+func (c *Config) loadPeersByRPC() {
+	byRPC := map[string][]string{}
+	for rpc, ids := range c.Peers.ByRPC {
+		name := peersEnvPrefix + "_" + strings.ToUpper(rpc)
+		byRPC[name] = ids
+		c.sources[name] = "file"
+	}
+	for _, kv := range os.Environ() {
+		name, value, _ := strings.Cut(kv, "=")
+		if !strings.HasPrefix(name, peersEnvPrefix+"_") {
+			continue
+		}
+		if ids := splitList(value); len(ids) > 0 {
+			byRPC[name] = ids
+			c.sources[name] = "env"
+		}
+	}
+	if len(byRPC) == 0 {
+		return
+	}
+	c.Peers.ByRPC = map[string][]string{}
+	for name, ids := range byRPC {
+		c.Peers.ByRPC[strings.TrimPrefix(name, peersEnvPrefix+"_")] = ids
+	}
+}
+
+// validate reports every invalid setting, not just the first.
+func (c *Config) validate() error {
+	var errs []error
+	for _, port := range []struct {
+		env  string
+		port int
+	}{{"MODEL_GATEWAY_GRPC_PORT", c.GRPCPort}, {"MODEL_GATEWAY_HTTP_PORT", c.HTTPPort}} {
+		if port.port <= 0 || port.port > 65535 {
+			errs = append(errs, fmt.Errorf("%s: %d is not a port", port.env, port.port))
+		}
+	}
+	if c.GRPCPort == c.HTTPPort {
+		errs = append(errs, fmt.Errorf("MODEL_GATEWAY_GRPC_PORT and MODEL_GATEWAY_HTTP_PORT are both %d", c.GRPCPort))
+	}
+	for _, positive := range []struct {
+		env   string
+		value int
+	}{
+		{"REQUEST_TIMEOUT_SECONDS", c.RequestTimeoutSeconds},
+		{"LLM_MAX_TOKENS_CAP", c.LLM.MaxTokensCap},
+		{"LLM_HEALTH_PROBE_INTERVAL_SECONDS", c.LLM.HealthProbeIntervalSeconds},
+		{"ANTHROPIC_MAX_TOKENS", c.LLM.Anthropic.MaxTokens},
+		{"LLM_QUEUE_TIMEOUT_SECONDS", c.LLM.Queue.TimeoutSeconds},
+		{"EMBEDDINGS_HASH_DIMS", c.Embeddings.HashDims},
+		{"EMBEDDED_HASH_DIMS", c.RAG.Embedded.HashDims},
+		{"RAG_HYBRID_RRF_K", c.RAG.HybridRRFK},
+		{"RAG_HYBRID_CANDIDATES", c.RAG.HybridCandidates},
+		{"RAG_RERANK_CANDIDATES", c.RAG.Rerank.Candidates},
+		{"RAG_CACHE_TTL_SECONDS", c.RAG.Cache.TTLSeconds},
+		{"INGEST_CHUNK_SIZE", c.Ingest.ChunkSize},
+		{"INGEST_MAX_BYTES", c.Ingest.MaxBytes},
+		{"INGEST_EMBED_BATCH_SIZE", c.Ingest.EmbedBatchSize},
+		{"INGEST_EMBED_CONCURRENCY", c.Ingest.EmbedConcurrency},
+		{"VISION_MAX_IMAGE_BYTES", c.Vision.MaxImageBytes},
+		{"VISION_MAX_IMAGES", c.Vision.MaxImages},
+	} {
+		if positive.value <= 0 {
+			errs = append(errs, fmt.Errorf("%s: want a positive number, got %d", positive.env, positive.value))
+		}
+	}
+	for _, nonNegative := range []struct {
+		env   string
+		value int
+	}{
+		{"LLM_PLAN_REPAIR_ATTEMPTS", c.LLM.PlanRepairAttempts},
+		{"LLM_MODEL_PROBE_INTERVAL_SECONDS", c.LLM.ModelProbeIntervalSeconds},
+		{"LLM_RETRY_MAX_ATTEMPTS", c.LLM.Retry.MaxAttempts},
+		{"LLM_RETRY_BASE_DELAY_MS", c.LLM.Retry.BaseDelayMS},
+		{"LLM_RETRY_MAX_DELAY_MS", c.LLM.Retry.MaxDelayMS},
+		{"LLM_MAX_CONCURRENT_REQUESTS", c.LLM.Queue.MaxConcurrent},
+		{"LLM_BATCH_MAX_CONCURRENT", c.LLM.Queue.BatchMaxConcurrent},
+		{"LLM_QUEUE_MAX_DEPTH", c.LLM.Queue.MaxDepth},
+		{"EMBEDDINGS_CACHE_SIZE", c.Embeddings.CacheSize},
+		{"RAG_CACHE_SIZE", c.RAG.Cache.Size},
+		{"PGVECTOR_MAX_CONNS", c.RAG.PGVector.MaxConns},
+		{"RATE_LIMIT_BURST", c.RateLimit.Burst},
+		{"GATEWAY_PROMPTS_RELOAD_SECONDS", c.Prompts.ReloadSeconds},
+		{"TOOL_DISCOVERY_INTERVAL_SECONDS", c.Tools.DiscoveryIntervalSeconds},
+	} {
+		if nonNegative.value < 0 {
+			errs = append(errs, fmt.Errorf("%s: want a non-negative number, got %d", nonNegative.env, nonNegative.value))
+		}
+	}
+	for _, ratio := range []struct {
+		env      string
+		value    float64
+		min, max float64
+		// open excludes min from the range.
+		open bool
+	}{
+		{"LLM_RETRY_BUDGET_RATIO", c.LLM.Retry.BudgetRatio, 0, 1, false},
+		{"RAG_DEDUP_SIMILARITY", c.RAG.DedupSimilarity, 0, 1, false},
+		{"RAG_LANGUAGE_MIN_CONFIDENCE", c.RAG.LanguageMinConfidence, 0, 1, false},
+		{"RAG_CACHE_SIMILARITY", c.RAG.Cache.Similarity, 0, 1, true},
+	} {
+		if ratio.value < ratio.min || ratio.value > ratio.max || (ratio.open && ratio.value == ratio.min) {
+			lower := "["
+			if ratio.open {
+				lower = "("
+			}
+			errs = append(errs, fmt.Errorf("%s: want a number in %s%g, %g], got %g", ratio.env, lower, ratio.min, ratio.max, ratio.value))
+		}
+	}
+	for _, enum := range []struct {
+		env, value string
+		allowed    []string
+	}{
+		{"LLM_TOOL_CALLING", c.LLM.ToolCalling, []string{toolCallingNative, toolCallingJSON}},
+		{"LLM_REASONING", c.LLM.Reasoning, []string{reasoningDrop, reasoningReturn}},
+		{"EMBEDDINGS_PROVIDER", embeddingsProvider(c.Embeddings, c.LLM), []string{"ollama", "openrouter", "openai", "hash"}},
+		{"RAG_BACKEND", c.RAG.Backend, []string{ragBackendMemory, ragBackendQdrant, ragBackendPGVector, ragBackendWeaviate, ragBackendMilvus, ragBackendEmbedded}},
+		{"RAG_RETRIEVAL_MODE", c.RAG.RetrievalMode, []string{"vector", "hybrid"}},
+		{"RAG_QUERY_TRANSLATION", c.RAG.QueryTranslation, []string{"off", "llm"}},
+		{"RAG_RERANKER", c.RAG.Rerank.Mode, []string{"off", "http", "grpc", "llm"}},
+		{"RERANKER_API", c.RAG.Rerank.API, []string{"cohere", "tei"}},
+		{"QDRANT_DISTANCE", c.RAG.Qdrant.Distance, []string{"Cosine", "Dot", "Euclid", "Manhattan"}},
+		{"PGVECTOR_LAYOUT", c.RAG.PGVector.Layout, []string{"label", "table"}},
+		{"PGVECTOR_DISTANCE", c.RAG.PGVector.Distance, []string{"cosine", "l2", "ip"}},
+		{"WEAVIATE_VECTORIZER", c.RAG.Weaviate.Vectorizer, []string{"gateway", "weaviate"}},
+		{"MILVUS_METRIC_TYPE", c.RAG.Milvus.MetricType, []string{"COSINE", "IP", "L2"}},
+		{"EMBEDDED_EMBEDDINGS", c.RAG.Embedded.Embeddings, []string{"hash", "provider"}},
+		{"MODERATION", c.Moderation.Mode, []string{"off", "local", "api"}},
+		{"MODERATION_ACTION", c.Moderation.Action, []string{"reject", "redact"}},
+	} {
+		if !slices.ContainsFunc(enum.allowed, func(a string) bool { return strings.EqualFold(a, strings.TrimSpace(enum.value)) }) {
+			errs = append(errs, fmt.Errorf("unsupported %s=%q (supported: %s)", enum.env, enum.value, strings.Join(enum.allowed, ", ")))
+		}
+	}
+	switch strings.ToLower(c.RAG.Rerank.Mode) {
+	case "http":
+		if c.RAG.Rerank.URL == "" {
+			errs = append(errs, fmt.Errorf("RERANKER_URL is required when RAG_RERANKER=http"))
+		}
+	case "grpc":
+		if c.RAG.Rerank.GRPCAddr == "" {
+			errs = append(errs, fmt.Errorf("RERANKER_GRPC_ADDR is required when RAG_RERANKER=grpc"))
+		}
+	}
+	if c.Ingest.ChunkOverlap < 0 || (c.Ingest.ChunkSize > 0 && c.Ingest.ChunkOverlap >= c.Ingest.ChunkSize) {
+		errs = append(errs, fmt.Errorf("INGEST_CHUNK_OVERLAP: want a number in [0, INGEST_CHUNK_SIZE), got %d", c.Ingest.ChunkOverlap))
+	}
+	if c.Ingest.EmbedRate < 0 {
+		errs = append(errs, fmt.Errorf("INGEST_EMBED_RATE: want a non-negative number, got %g", c.Ingest.EmbedRate))
+	}
+	if strings.EqualFold(c.RAG.Backend, ragBackendEmbedded) && c.RAG.Embedded.Corpus == "" {
+		errs = append(errs, fmt.Errorf("EMBEDDED_RAG_CORPUS is required when RAG_BACKEND=embedded"))
+	}
+	for _, parse := range []func() error{
+		func() error { _, err := usagePrices(c.LLM); return err },
+		func() error { _, err := parseKeyValueList("EMBEDDINGS_MODELS", c.Embeddings.Models); return err },
+		func() error { _, err := ragTenancy(c.RAG); return err },
+		func() error { _, err := ragMinScore(c.RAG); return err },
+		func() error { _, err := ragInjectionMode(c.RAG); return err },
+		func() error { _, err := kbLanguagesFromConfig(c.RAG); return err },
+		func() error {
+			_, err := parseKBMapping("QDRANT_COLLECTIONS", c.RAG.Qdrant.Collections, "collection")
+			return err
+		},
+		func() error {
+			_, err := parseOptionalFloat("QDRANT_SCORE_THRESHOLD", c.RAG.Qdrant.ScoreThreshold)
+			return err
+		},
+		func() error {
+			_, err := parseKBMapping("WEAVIATE_CLASSES", c.RAG.Weaviate.Classes, "Class")
+			return err
+		},
+		func() error {
+			_, err := parseOptionalFloat("WEAVIATE_HYBRID_ALPHA", c.RAG.Weaviate.HybridAlpha)
+			return err
+		},
+		func() error {
+			_, err := parseKBMapping("MILVUS_COLLECTIONS", c.RAG.Milvus.Collections, "collection")
+			return err
+		},
+		func() error { _, err := c.PII.providers(); return err },
+		func() error { _, err := c.PII.builtins(); return err },
+		func() error { _, _, err := c.RateLimit.limits(); return err },
+		func() error { _, err := c.Vision.policy(); return err },
+		func() error { _, err := c.Peers.policy(); return err },
+	} {
+		if err := parse(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	switch strings.ToLower(c.TLSSource) {
+	case "pem", "spiffe":
+	default:
+		errs = append(errs, fmt.Errorf("unsupported TLS_SOURCE %q (supported: pem, spiffe)", c.TLSSource))
+	}
+	switch c.LLM.Record.Mode {
+	case recordOff:
+	case recordRecord, recordReplay, recordReplayOrRecord:
+		if c.LLM.Record.Dir == "" {
+			errs = append(errs, fmt.Errorf("LLM_RECORD_DIR is required with LLM_RECORD_MODE=%s", c.LLM.Record.Mode))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("LLM_RECORD_MODE: want off, record, replay or replay_or_record, got %q", c.LLM.Record.Mode))
+	}
+	if chain, err := c.LLM.chain(); err != nil {
+		errs = append(errs, err)
+	} else {
+		for _, provider := range chain {
+			switch provider {
+			case providerOpenRouter, providerOllama, providerAnthropic, providerMock:
+			default:
+				errs = append(errs, fmt.Errorf("unsupported LLM_PROVIDER=%q (supported: openrouter, ollama, anthropic, mock)", provider))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// effective lists every setting by variable name, with its value and
+// source. Credentials are not settings; they are read through pkg/secrets.
+func (c *Config) effective() map[string]any {
+	out := map[string]any{}
+	for _, s := range configSettings(c) {
+		out[s.env] = map[string]string{"value": formatSetting(s.value), "source": c.sources[s.env]}
+	}
+	for rpc, ids := range c.Peers.ByRPC {
+		name := peersEnvPrefix + "_" + rpc
+		out[name] = map[string]string{"value": strings.Join(ids, ","), "source": c.sources[name]}
+	}
+	return out
+}
+
+// logEffective logs the effective configuration as one line, keys sorted,
+// so two instances' settings can be diffed.
+func (c *Config) logEffective() {
+	settings, _ := json.Marshal(c.effective())
+	log.Printf(
+		`{"timestamp":"%s","level":"info","service":"%s","config_file":%q,"settings":%s,"message":"effective configuration."}`,
+		time.Now().Format(time.RFC3339Nano), SERVICE_NAME, c.file, settings,
+	)
+}
+
+// configSetting is one typed setting of a Config.
+type configSetting struct {
+	env   string
+	value reflect.Value
+}
+
+// configSettings lists c's typed settings in declaration order.
+func configSettings(c *Config) []configSetting {
+	var out []configSetting
+	var walk func(v reflect.Value)
+	walk = func(v reflect.Value) {
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if env := field.Tag.Get("env"); env != "" {
+				out = append(out, configSetting{env: env, value: v.Field(i)})
+			} else if field.Type.Kind() == reflect.Struct && field.IsExported() {
+				walk(v.Field(i))
+			}
+		}
+	}
+	walk(reflect.ValueOf(c).Elem())
+	return out
+}
+
+// formatSetting renders a setting the way its variable is written: booleans
+// as on/off and lists comma-separated.
+func formatSetting(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			return "on"
+		}
+		return "off"
+	case reflect.Int:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 64)
+	case reflect.Slice:
+		return strings.Join(v.Interface().([]string), ",")
+	default:
+		return v.String()
+	}
+}
+
+// parseSetting sets v from its variable's value.
+func parseSetting(v reflect.Value, raw string) error {
+	raw = strings.TrimSpace(raw)
+	switch v.Kind() {
+	case reflect.Bool:
+		switch strings.ToLower(raw) {
+		case "on", "true", "1":
+			v.SetBool(true)
+		case "off", "false", "0":
+			v.SetBool(false)
+		default:
+			return fmt.Errorf("want on or off, got %q", raw)
+		}
+	case reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("want a number, got %q", raw)
+		}
+		v.SetInt(int64(n))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return fmt.Errorf("want a number, got %q", raw)
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		v.Set(reflect.ValueOf(splitList(raw)))
+	default:
+		v.SetString(raw)
+	}
+	return nil
+}
+
+// splitList splits a comma-separated variable into trimmed, non-empty
+// entries (nil when blank).
+func splitList(raw string) []string {
+	var parts []string
+	for _, part := range strings.Split(raw, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
+
+// parseOptionalFloat parses the value v of an optional numeric setting key
+// (nil when unset).
+func parseOptionalFloat(key, v string) (*float64, error) {
+	if v = strings.TrimSpace(v); v == "" {
+		return nil, nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%s: want a number, got %q", key, v)
+	}
+	return &f, nil
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// configEnv clears the variables a config test touches, restoring them
+// afterwards.
+func configEnv(t *testing.T, keys ...string) {
+	t.Helper()
+	for _, key := range append(keys, "GATEWAY_CONFIG_FILE") {
+		t.Setenv(key, "")
+	}
+}
+
+// envConfig loads the configuration from the test's environment.
+func envConfig(t *testing.T) *Config {
+	t.Helper()
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+func writeConfig(t *testing.T, name, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfig_Defaults(t *testing.T) {
+	configEnv(t, "MODEL_GATEWAY_GRPC_PORT", "LLM_PROVIDER", "LLM_PROVIDERS")
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.GRPCPort != DEFAULT_GRPC_PORT || cfg.LLM.Provider != defaultProvider || cfg.REST || cfg.sources["MODEL_GATEWAY_GRPC_PORT"] != "default" {
+		t.Fatalf("defaults = %+v, sources %v", cfg, cfg.sources)
+	}
+}
+
+func TestLoadConfig_FileAndEnvOverrides(t *testing.T) {
+	configEnv(t, "MODEL_GATEWAY_HTTP_PORT", "LLM_PROVIDER", "LLM_PROVIDERS", "OLLAMA_MODEL_NAME", "GATEWAY_REST", "RAG_BACKEND", "RAG_RERANKER", "RERANKER_URL", "RAG_CACHE_SIMILARITY", "RATE_LIMIT_CALLERS")
+	t.Setenv("GATEWAY_CONFIG_FILE", writeConfig(t, "gateway.yaml", `
+http_port: 9005
+rest: on
+llm:
+  providers: [ollama, mock]
+  ollama:
+    model: llama3.1
+rag:
+  backend: qdrant
+  rerank:
+    mode: http
+    url: http://tei:8080/rerank
+  cache:
+    similarity: 0.9
+rate_limit:
+  callers: [eval-runner=0.5:2]
+peers:
+  by_rpc:
+    GetRAGContext: [memory-indexer]
+    GetPlan: [agent-planner]
+`))
+	t.Setenv("MTLS_ALLOWED_PEERS_GETPLAN", "ops")
+	t.Setenv("MODEL_GATEWAY_HTTP_PORT", "9006")
+
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.HTTPPort != 9006 || cfg.sources["MODEL_GATEWAY_HTTP_PORT"] != "env" {
+		t.Fatalf("env should override the file: %d (%s)", cfg.HTTPPort, cfg.sources["MODEL_GATEWAY_HTTP_PORT"])
+	}
+	if !cfg.REST || cfg.LLM.Ollama.Model != "llama3.1" || cfg.sources["OLLAMA_MODEL_NAME"] != "file" {
+		t.Fatalf("file settings = %+v", cfg)
+	}
+	if chain, err := cfg.LLM.chain(); err != nil || len(chain) != 2 || chain[1] != providerMock {
+		t.Fatalf("chain = %v, %v", chain, err)
+	}
+	if cfg.RAG.Backend != ragBackendQdrant || cfg.RAG.Rerank.URL != "http://tei:8080/rerank" || cfg.RAG.Cache.Similarity != 0.9 {
+		t.Fatalf("rag settings = %+v", cfg.RAG)
+	}
+	if _, callers, err := cfg.RateLimit.limits(); err != nil || callers["eval-runner"] != (rateLimit{perSecond: 0.5, burst: 2}) {
+		t.Fatalf("rate limit callers = %v, %v", callers, err)
+	}
+	peers, err := cfg.Peers.policy()
+	if err != nil || !slices.Equal(peers.byMethod["GetRAGContext"], []string{"memory-indexer"}) || !slices.Equal(peers.byMethod["GetPlan"], []string{"ops"}) {
+		t.Fatalf("peer policy = %+v, %v", peers, err)
+	}
+	if got := cfg.effective()["MTLS_ALLOWED_PEERS_GETPLAN"].(map[string]string); got["value"] != "ops" || got["source"] != "env" {
+		t.Fatalf("MTLS_ALLOWED_PEERS_GETPLAN = %v", got)
+	}
+	// File settings are not exported to the environment.
+	if v := os.Getenv("RAG_BACKEND"); v != "" {
+		t.Fatalf("RAG_BACKEND exported as %q", v)
+	}
+	effective := cfg.effective()
+	for key, want := range map[string]string{"OLLAMA_MODEL_NAME": "llama3.1", "RAG_RERANKER": "http", "RAG_CACHE_SIMILARITY": "0.9"} {
+		if got := effective[key].(map[string]string); got["value"] != want || got["source"] != "file" {
+			t.Fatalf("%s = %v, want %s from the file", key, got, want)
+		}
+	}
+	if got := effective["RAG_TENANCY"].(map[string]string); got["value"] != ragTenancyOff || got["source"] != "default" {
+		t.Fatalf("RAG_TENANCY = %v", got)
+	}
+}
+
+func TestLoadConfig_ReloadReplacesFileSettings(t *testing.T) {
+	configEnv(t, "OLLAMA_MODEL_NAME", "RAG_TENANCY")
+	path := writeConfig(t, "gateway.json", `{"llm": {"ollama": {"model": "a"}}, "rag": {"tenancy": "required"}}`)
+	t.Setenv("GATEWAY_CONFIG_FILE", path)
+	if _, err := loadConfig(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"llm": {"ollama": {"model": "b"}}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.LLM.Ollama.Model != "b" {
+		t.Fatalf("reloaded model = %q", cfg.LLM.Ollama.Model)
+	}
+	if cfg.RAG.Tenancy != ragTenancyOff || cfg.sources["RAG_TENANCY"] != "default" {
+		t.Fatalf("RAG_TENANCY dropped from the file is still %q (%s)", cfg.RAG.Tenancy, cfg.sources["RAG_TENANCY"])
+	}
+}
+
+func TestLoadConfig_Validation(t *testing.T) {
+	configEnv(t, "MODEL_GATEWAY_GRPC_PORT", "REQUEST_TIMEOUT_SECONDS", "LLM_PROVIDER", "LLM_PROVIDERS", "GRPC_REFLECTION", "LLM_RECORD_MODE", "LLM_RECORD_DIR",
+		"RAG_BACKEND", "RAG_DEDUP_SIMILARITY", "RAG_RERANKER", "QDRANT_COLLECTIONS", "MODERATION_ACTION", "LLM_RETRY_BUDGET_RATIO",
+		"INGEST_CHUNK_OVERLAP", "VISION_ALLOWED_HOSTS", "TOOL_DISCOVERY_INTERVAL_SECONDS")
+	for name, tc := range map[string]struct {
+		file string
+		env  map[string]string
+		want string
+	}{
+		"unknown key":       {file: "grpc_prot: 1\n", want: "grpc_prot"},
+		"old env map":       {file: "env:\n  RAG_BACKEND: embedded\n", want: "env"},
+		"bad port":          {env: map[string]string{"MODEL_GATEWAY_GRPC_PORT": "70000"}, want: "not a port"},
+		"not a number":      {env: map[string]string{"REQUEST_TIMEOUT_SECONDS": "soon"}, want: "REQUEST_TIMEOUT_SECONDS"},
+		"bad provider":      {env: map[string]string{"LLM_PROVIDERS": "openrouter,gpt"}, want: "gpt"},
+		"bad on or off":     {env: map[string]string{"GRPC_REFLECTION": "maybe"}, want: "GRPC_REFLECTION"},
+		"record no dir":     {env: map[string]string{"LLM_RECORD_MODE": "record"}, want: "LLM_RECORD_DIR"},
+		"bad backend":       {file: "rag:\n  backend: chroma\n", want: "RAG_BACKEND"},
+		"bad ratio":         {env: map[string]string{"RAG_DEDUP_SIMILARITY": "1.5"}, want: "RAG_DEDUP_SIMILARITY"},
+		"not a float":       {env: map[string]string{"LLM_RETRY_BUDGET_RATIO": "lots"}, want: "LLM_RETRY_BUDGET_RATIO"},
+		"http no url":       {env: map[string]string{"RAG_RERANKER": "http"}, want: "RERANKER_URL"},
+		"bad mapping":       {env: map[string]string{"QDRANT_COLLECTIONS": "Domain-KB"}, want: "QDRANT_COLLECTIONS"},
+		"bad action":        {env: map[string]string{"MODERATION_ACTION": "warn"}, want: "MODERATION_ACTION"},
+		"overlap too big":   {file: "ingest:\n  chunk_size: 100\n  chunk_overlap: 100\n", want: "INGEST_CHUNK_OVERLAP"},
+		"bad image host":    {env: map[string]string{"VISION_ALLOWED_HOSTS": "https://images.example.com/"}, want: "VISION_ALLOWED_HOSTS"},
+		"negative interval": {env: map[string]string{"TOOL_DISCOVERY_INTERVAL_SECONDS": "-1"}, want: "TOOL_DISCOVERY_INTERVAL_SECONDS"},
+		"unknown peer RPC":  {env: map[string]string{"MTLS_ALLOWED_PEERS_GETRAGCONTXT": "ops"}, want: "MTLS_ALLOWED_PEERS_GETRAGCONTXT"},
+		"unknown file RPC":  {file: "peers:\n  by_rpc:\n    GetPlans: [ops]\n", want: "MTLS_ALLOWED_PEERS_GETPLANS"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if tc.file != "" {
+				t.Setenv("GATEWAY_CONFIG_FILE", writeConfig(t, "gateway.yaml", tc.file))
+			}
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+			if _, err := loadConfig(); err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("err = %v, want it to mention %q", err, tc.want)
+			}
+		})
+	}
+}
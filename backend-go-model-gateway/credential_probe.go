@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"backend-go-model-gateway/internal/logger"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+const (
+	defaultCredentialProbeIntervalSec = 300
+	credentialProbeTimeout            = 5 * time.Second
+)
+
+// credentialProbe checks the provider key with a 1-token completion, at most
+// once per interval, so a revoked key takes the gateway out of rotation
+// instead of failing every request. It lives on the llmRuntime: a reload
+// with a new key probes again right away.
+type credentialProbe struct {
+	mu        sync.Mutex
+	checkedAt time.Time
+	rejected  error
+}
+
+// credentialsRejected reports the provider's answer to the last probe, probing
+// first when the result is older than interval. Only 401 and 403 count as
+// rejected: rate limits and outages are not fixed by taking replicas out.
+func (r *llmRuntime) credentialsRejected(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 || r.Client == nil {
+		return nil
+	}
+	p := &r.creds
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.checkedAt.IsZero() && time.Since(p.checkedAt) < interval {
+		return p.rejected
+	}
+
+	probeCtx, cancel := context.WithTimeout(ctx, credentialProbeTimeout)
+	defer cancel()
+	_, err := r.Client.CreateChatCompletion(probeCtx, openai.ChatCompletionRequest{
+		Model:     r.Model,
+		Messages:  []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: "ping"}},
+		MaxTokens: 1,
+	})
+	lg := logger.NewContextLogger(ctx)
+	switch {
+	case authFailed(err):
+		if p.rejected == nil {
+			lg.Error("llm_credentials_rejected", "provider", r.Provider, "model", r.Model, "error", err)
+		}
+		p.rejected = err
+	case err != nil:
+		// Inconclusive: keep the last verdict and try again next interval.
+		lg.Warn("llm_credential_probe_failed", "provider", r.Provider, "model", r.Model, "error", err)
+	default:
+		if p.rejected != nil {
+			lg.Info("llm_credentials_accepted", "provider", r.Provider, "model", r.Model)
+		}
+		p.rejected = nil
+	}
+	p.checkedAt = time.Now()
+	return p.rejected
+}
+
+// authFailed reports whether err is the provider refusing the API key.
+func authFailed(err error) bool {
+	var apiErr *openai.APIError
+	var reqErr *openai.RequestError
+	switch {
+	case errors.As(err, &apiErr):
+		return apiErr.HTTPStatusCode == http.StatusUnauthorized || apiErr.HTTPStatusCode == http.StatusForbidden
+	case errors.As(err, &reqErr):
+		return reqErr.HTTPStatusCode == http.StatusUnauthorized || reqErr.HTTPStatusCode == http.StatusForbidden
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestHealthCheck_CredentialProbe(t *testing.T) {
+	var calls atomic.Int32
+	var code atomic.Int32
+	code.Store(http.StatusUnauthorized)
+	llm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		var req openai.ChatCompletionRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if req.MaxTokens != 1 {
+			t.Errorf("probe max_tokens = %d", req.MaxTokens)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if c := int(code.Load()); c != http.StatusOK {
+			w.WriteHeader(c)
+			_, _ = w.Write([]byte(`{"error":{"message":"No auth credentials found","code":401}}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(openai.ChatCompletionResponse{Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Content: "p"}}}})
+	}))
+	defer llm.Close()
+	cfg := openai.DefaultConfig("revoked")
+	cfg.BaseURL = llm.URL
+	runtime := &llmRuntime{Provider: providerOpenRouter, Model: "m", Client: openai.NewClientWithConfig(cfg)}
+	h := &healthServer{gateway: &server{llm: runtime}, probeInterval: time.Minute}
+	check := func() grpc_health_v1.HealthCheckResponse_ServingStatus {
+		t.Helper()
+		resp, err := h.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.GetStatus()
+	}
+
+	// A rejected key fails the check, and the verdict is cached.
+	if got := check(); got != grpc_health_v1.HealthCheckResponse_NOT_SERVING {
+		t.Fatalf("revoked key: %v", got)
+	}
+	code.Store(http.StatusOK)
+	if got := check(); got != grpc_health_v1.HealthCheckResponse_NOT_SERVING || calls.Load() != 1 {
+		t.Fatalf("within the interval: %v after %d probes", got, calls.Load())
+	}
+
+	// Once the interval is over the key is probed again.
+	runtime.creds.checkedAt = time.Now().Add(-2 * time.Minute)
+	if got := check(); got != grpc_health_v1.HealthCheckResponse_SERVING || calls.Load() != 2 {
+		t.Fatalf("restored key: %v after %d probes", got, calls.Load())
+	}
+
+	// Rate limits say nothing about the key.
+	code.Store(http.StatusTooManyRequests)
+	runtime.creds.checkedAt = time.Now().Add(-2 * time.Minute)
+	if got := check(); got != grpc_health_v1.HealthCheckResponse_SERVING {
+		t.Fatalf("rate limited: %v", got)
+	}
+
+	// probeInterval 0 never calls the provider.
+	h.probeInterval = 0
+	runtime.creds = credentialProbe{}
+	code.Store(http.StatusUnauthorized)
+	if got := check(); got != grpc_health_v1.HealthCheckResponse_SERVING || calls.Load() != 3 {
+		t.Fatalf("probe disabled: %v after %d probes", got, calls.Load())
+	}
+}
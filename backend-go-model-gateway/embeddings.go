@@ -0,0 +1,313 @@
+package main
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"backend-go-model-gateway/pkg/secrets"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// Embedder turns query text into a vector for the direct vector-store RAG
+// backends (qdrant, ...). The memory-service backend embeds on the Python side
+// and does not use it.
+type Embedder interface {
+	Embed(ctx context.Context, text string) ([]float32, error)
+}
+
+// batchEmbedder is implemented by embedders that can embed many texts in one
+// round trip; ingestion uses it for document chunks.
+type batchEmbedder interface {
+	EmbedBatch(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// embedAll embeds texts, in one call when e supports batching.
+func embedAll(ctx context.Context, e Embedder, texts []string) ([][]float32, error) {
+	if b, ok := e.(batchEmbedder); ok {
+		return b.EmbedBatch(ctx, texts)
+	}
+	out := make([][]float32, len(texts))
+	for i, text := range texts {
+		vec, err := e.Embed(ctx, text)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = vec
+	}
+	return out, nil
+}
+
+// openAIEmbedder calls any OpenAI-compatible /v1/embeddings endpoint
+// (OpenAI, OpenRouter, Ollama, vLLM, LM Studio, ...).
+type openAIEmbedder struct {
+	client *openai.Client
+	model  string
+}
+
+// embeddingsProvider is EMBEDDINGS_PROVIDER or, unset, the one that follows
+// LLM_PROVIDER (mock uses hash).
+func embeddingsProvider(cfg EmbeddingsConfig, llmCfg LLMConfig) string {
+	if provider := strings.ToLower(cfg.Provider); provider != "" {
+		return provider
+	}
+	switch llmProvider(strings.ToLower(llmCfg.Provider)) {
+	case providerOllama:
+		return "ollama"
+	case providerMock:
+		return "hash"
+	default:
+		return "openrouter"
+	}
+}
+
+// newEmbedder builds the query embedder, wrapped in an LRU cache.
+//
+//   - EMBEDDINGS_PROVIDER (default: follows LLM_PROVIDER; mock uses hash)
+//   - ollama: OLLAMA_BASE_URL + /v1, model nomic-embed-text
+//   - openrouter: OpenRouter with OPENROUTER_API_KEY, model openai/text-embedding-3-small
+//   - openai: any OpenAI-compatible endpoint (EMBEDDINGS_BASE_URL, default api.openai.com)
+//   - hash: the built-in hashed bag-of-words embedder (EMBEDDINGS_HASH_DIMS, default 256)
+//   - EMBEDDINGS_BASE_URL / EMBEDDINGS_MODEL override the provider defaults
+//   - EMBEDDINGS_API_KEY (optional; resolved through pkg/secrets)
+//   - EMBEDDINGS_CACHE_SIZE (default: 1024; 0 disables the cache)
+func newEmbedder(ctx context.Context, store *secrets.Store, cfg EmbeddingsConfig, llmCfg LLMConfig) (Embedder, error) {
+	provider := embeddingsProvider(cfg, llmCfg)
+
+	var (
+		embedder Embedder
+		model    string
+		// client is the API client, reused for EMBEDDINGS_MODELS.
+		client *openai.Client
+	)
+	switch provider {
+	case "hash":
+		dims := cfg.HashDims
+		embedder, model = hashEmbedder{dims: dims}, fmt.Sprintf("hash-%d", dims)
+
+	case "ollama", "openai", "openrouter":
+		apiKey, err := store.Lookup(ctx, "EMBEDDINGS_API_KEY")
+		if err != nil {
+			return nil, err
+		}
+		clientCfg := openai.DefaultConfig(apiKey)
+		clientCfg.HTTPClient = sharedHTTPClient
+		switch provider {
+		case "ollama":
+			clientCfg.BaseURL = normalizeOllamaBaseURL(llmCfg.Ollama.BaseURL)
+			model = "nomic-embed-text"
+		case "openrouter":
+			clientCfg.BaseURL = "https://openrouter.ai/api/v1"
+			model = "openai/text-embedding-3-small"
+			// Reuse the chat key unless a dedicated embeddings key is set.
+			if apiKey == "" {
+				clientCfg.HTTPClient = &http.Client{
+					Transport: &secrets.BearerTransport{Store: store, Name: "OPENROUTER_API_KEY", Base: sharedHTTPClient.Transport},
+				}
+			}
+		default:
+			model = "text-embedding-3-small"
+		}
+		if cfg.BaseURL != "" {
+			clientCfg.BaseURL = cfg.BaseURL
+		}
+		if cfg.Model != "" {
+			model = cfg.Model
+		}
+		client = openai.NewClientWithConfig(clientCfg)
+		embedder = &openAIEmbedder{client: client, model: model}
+
+	default:
+		return nil, fmt.Errorf("unsupported EMBEDDINGS_PROVIDER=%q (supported: ollama, openrouter, openai, hash)", provider)
+	}
+
+	size := cfg.CacheSize
+	if size < 0 {
+		return nil, fmt.Errorf("EMBEDDINGS_CACHE_SIZE: want a non-negative integer, got %d", size)
+	}
+	log.Printf(
+		`{"timestamp":"%s","level":"info","service":"%s","component":"Embedder","provider":%q,"model":%q,"cache_size":%d}`,
+		time.Now().Format(time.RFC3339Nano), SERVICE_NAME, provider, model, size,
+	)
+	if size > 0 {
+		embedder = newCachingEmbedder(embedder, size)
+	}
+
+	models, err := parseKeyValueList("EMBEDDINGS_MODELS", cfg.Models)
+	if err != nil || len(models) == 0 {
+		return embedder, err
+	}
+	if client == nil {
+		return nil, fmt.Errorf("EMBEDDINGS_MODELS needs an API embeddings provider (ollama, openrouter, openai), not %q", provider)
+	}
+	le := &languageEmbedder{models: map[string]Embedder{}, fallback: embedder}
+	for lang, m := range models {
+		var e Embedder = &openAIEmbedder{client: client, model: m}
+		if size > 0 {
+			e = newCachingEmbedder(e, size)
+		}
+		le.models[strings.ToLower(lang)] = e
+	}
+	log.Printf(
+		`{"timestamp":"%s","level":"info","service":"%s","component":"Embedder","provider":%q,"language_models":%q}`,
+		time.Now().Format(time.RFC3339Nano), SERVICE_NAME, provider, cfg.Models,
+	)
+	return le, nil
+}
+
+// parseKeyValueList parses v, a comma-separated list of key=value pairs from
+// environment variable name; it returns nil when the variable is unset.
+func parseKeyValueList(name, v string) (map[string]string, error) {
+	if strings.TrimSpace(v) == "" {
+		return nil, nil
+	}
+	out := map[string]string{}
+	for _, pair := range strings.Split(v, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if !ok || key == "" || value == "" {
+			return nil, fmt.Errorf("%s: want comma-separated key=value pairs, got %q", name, pair)
+		}
+		out[key] = value
+	}
+	return out, nil
+}
+
+type embeddingLanguageKey struct{}
+
+// withEmbeddingLanguage tags ctx with the language of the text being
+// embedded, so a languageEmbedder uses that language's model.
+func withEmbeddingLanguage(ctx context.Context, lang string) context.Context {
+	return context.WithValue(ctx, embeddingLanguageKey{}, lang)
+}
+
+// languageEmbedder embeds with a per-language model (EMBEDDINGS_MODELS), chosen
+// by the language ctx is tagged with (withEmbeddingLanguage), and with the
+// default model for untagged contexts and other languages. A KB must be
+// queried with the model its documents were embedded with, so queries and
+// ingestion both tag the KB's language (RAG_KB_LANGUAGES).
+type languageEmbedder struct {
+	models   map[string]Embedder
+	fallback Embedder
+}
+
+func (e *languageEmbedder) pick(ctx context.Context) Embedder {
+	lang, _ := ctx.Value(embeddingLanguageKey{}).(string)
+	if m, ok := e.models[lang]; ok {
+		return m
+	}
+	return e.fallback
+}
+
+func (e *languageEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	return e.pick(ctx).Embed(ctx, text)
+}
+
+func (e *languageEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	return embedAll(ctx, e.pick(ctx), texts)
+}
+
+func (e *openAIEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	resp, err := e.client.CreateEmbeddings(ctx, openai.EmbeddingRequestStrings{
+		Input: []string{text},
+		Model: openai.EmbeddingModel(e.model),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("embed query: %w", err)
+	}
+	if len(resp.Data) == 0 || len(resp.Data[0].Embedding) == 0 {
+		return nil, fmt.Errorf("embed query: empty embedding from model %q", e.model)
+	}
+	return resp.Data[0].Embedding, nil
+}
+
+func (e *openAIEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	resp, err := e.client.CreateEmbeddings(ctx, openai.EmbeddingRequestStrings{
+		Input: texts,
+		Model: openai.EmbeddingModel(e.model),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("embed batch: %w", err)
+	}
+	if len(resp.Data) != len(texts) {
+		return nil, fmt.Errorf("embed batch: got %d embeddings for %d texts from model %q", len(resp.Data), len(texts), e.model)
+	}
+	out := make([][]float32, len(texts))
+	for _, d := range resp.Data {
+		if d.Index < 0 || d.Index >= len(texts) || len(d.Embedding) == 0 {
+			return nil, fmt.Errorf("embed batch: bad embedding at index %d from model %q", d.Index, e.model)
+		}
+		out[d.Index] = d.Embedding
+	}
+	return out, nil
+}
+
+// cachingEmbedder keeps the embeddings of recently seen query texts in an LRU
+// so repeated prompts (retries, multi-step plans, several KBs) do not pay for
+// another embeddings round trip. Cached vectors are shared; callers must not
+// modify them. Errors are not cached.
+type cachingEmbedder struct {
+	next Embedder
+	size int
+
+	mu    sync.Mutex
+	order *list.List // front = most recently used
+	items map[string]*list.Element
+}
+
+type embeddingCacheEntry struct {
+	text   string
+	vector []float32
+}
+
+func newCachingEmbedder(next Embedder, size int) *cachingEmbedder {
+	return &cachingEmbedder{
+		next:  next,
+		size:  size,
+		order: list.New(),
+		items: make(map[string]*list.Element, size),
+	}
+}
+
+// EmbedBatch bypasses the cache: document chunks are embedded once and would
+// only evict the query embeddings the cache exists for.
+func (c *cachingEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	return embedAll(ctx, c.next, texts)
+}
+
+func (c *cachingEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	c.mu.Lock()
+	if el, ok := c.items[text]; ok {
+		c.order.MoveToFront(el)
+		vec := el.Value.(*embeddingCacheEntry).vector
+		c.mu.Unlock()
+		return vec, nil
+	}
+	c.mu.Unlock()
+
+	vec, err := c.next.Embed(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[text]; ok {
+		// A concurrent miss for the same text got here first.
+		c.order.MoveToFront(el)
+		return vec, nil
+	}
+	c.items[text] = c.order.PushFront(&embeddingCacheEntry{text: text, vector: vec})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*embeddingCacheEntry).text)
+	}
+	return vec, nil
+}
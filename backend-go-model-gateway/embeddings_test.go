@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+)
+
+type countingEmbedder struct {
+	calls atomic.Int32
+}
+
+func (e *countingEmbedder) Embed(_ context.Context, text string) ([]float32, error) {
+	e.calls.Add(1)
+	return []float32{float32(len(text))}, nil
+}
+
+func TestCachingEmbedder_EvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	next := &countingEmbedder{}
+	c := newCachingEmbedder(next, 2)
+
+	for _, q := range []string{"a", "bb", "a", "ccc", "a", "bb"} {
+		vec, err := c.Embed(ctx, q)
+		if err != nil {
+			t.Fatalf("Embed(%q): %v", q, err)
+		}
+		if vec[0] != float32(len(q)) {
+			t.Fatalf("Embed(%q) = %v", q, vec)
+		}
+	}
+	// a, bb miss; a hits; ccc misses and evicts bb (a was used more recently);
+	// a hits; bb misses again.
+	if got := next.calls.Load(); got != 4 {
+		t.Errorf("underlying embedder called %d times, want 4", got)
+	}
+	if c.order.Len() != 2 || c.items["bb"] == nil || c.items["a"] == nil {
+		t.Errorf("unexpected cache contents: %v", c.items)
+	}
+}
+
+func TestNewEmbedder_FollowsLLMProvider(t *testing.T) {
+	t.Setenv("LLM_PROVIDER", "mock")
+	t.Setenv("EMBEDDINGS_CACHE_SIZE", "0")
+	cfg := envConfig(t)
+	e, err := newEmbedder(context.Background(), nil, cfg.Embeddings, cfg.LLM)
+	if err != nil {
+		t.Fatalf("newEmbedder: %v", err)
+	}
+	if _, ok := e.(hashEmbedder); !ok {
+		t.Errorf("mock provider embedder = %T, want hashEmbedder", e)
+	}
+
+	t.Setenv("LLM_PROVIDER", "ollama")
+	t.Setenv("EMBEDDINGS_CACHE_SIZE", "")
+	cfg = envConfig(t)
+	e, err = newEmbedder(context.Background(), nil, cfg.Embeddings, cfg.LLM)
+	if err != nil {
+		t.Fatalf("newEmbedder: %v", err)
+	}
+	ce, ok := e.(*cachingEmbedder)
+	if !ok {
+		t.Fatalf("embedder = %T, want *cachingEmbedder", e)
+	}
+	if oe, ok := ce.next.(*openAIEmbedder); !ok || oe.model != "nomic-embed-text" {
+		t.Errorf("ollama embedder = %#v", ce.next)
+	}
+
+	t.Setenv("EMBEDDINGS_PROVIDER", "cohere")
+	if _, err := loadConfig(); err == nil {
+		t.Error("expected an error for an unsupported provider")
+	}
+}
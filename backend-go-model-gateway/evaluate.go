@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"backend-go-model-gateway/pkg/mockprovider"
+	pb "backend-go-model-gateway/proto/proto"
+	"backend-go-model-gateway/service"
+
+	"github.com/sashabaranov/go-openai"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// evaluateContextChars caps the retrieved context sent to the judge.
+const evaluateContextChars = 8000
+
+// EvaluateAnswer has the configured model grade a final answer for relevance
+// to the prompt and groundedness in the retrieved context (LLM-as-judge).
+// The mock provider answers with mockprovider's word-overlap heuristic.
+func (s *server) EvaluateAnswer(ctx context.Context, in *pb.EvaluateRequest) (*pb.EvaluateResponse, error) {
+	ctx = service.ContextWithTraceIDFromIncomingGRPC(ctx)
+	if strings.TrimSpace(in.GetAnswer()) == "" {
+		return nil, status.Error(codes.InvalidArgument, "answer is required")
+	}
+	llm, _ := s.runtime()
+	if llm == nil {
+		return nil, status.Error(codes.Unavailable, "LLM runtime not initialized")
+	}
+	if llm.Provider == providerMock {
+		return mockprovider.Evaluate(in), nil
+	}
+	if llm.Client == nil {
+		return nil, status.Error(codes.Unavailable, "LLM client not initialized")
+	}
+
+	callCtx, cancel := context.WithTimeout(ctx, s.requestTimeout)
+	defer cancel()
+	// Grading is background work: it queues behind interactive plans.
+	release, err := s.acquireProvider(callCtx, priorityBatch)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	var user strings.Builder
+	fmt.Fprintf(&user, "Prompt:\n%s\n\nAnswer:\n%s\n\nContext:\n", in.GetPrompt(), in.GetAnswer())
+	var passages strings.Builder
+	for _, c := range in.GetContext() {
+		passages.WriteString(c + "\n---\n")
+	}
+	text := []rune(passages.String())
+	if len(text) > evaluateContextChars {
+		text = text[:evaluateContextChars]
+	}
+	if len(text) == 0 {
+		user.WriteString("(none)\n")
+	} else {
+		user.WriteString(string(text))
+	}
+	user.WriteString("\nReturn {\"relevance\": n, \"groundedness\": n, \"rationale\": \"one sentence\"}.")
+
+	resp, err := s.createChatCompletion(callCtx, llm, openai.ChatCompletionRequest{
+		Model: llm.Model,
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: "You grade an assistant's answer from 0 (worst) to 10 (best): relevance is how well it addresses the prompt, groundedness how well the context supports it (10 when it needs no context and makes no factual claims). Return STRICT JSON only."},
+			{Role: openai.ChatMessageRoleUser, Content: user.String()},
+		},
+		Temperature: 0,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Choices) == 0 {
+		return nil, status.Error(codes.Internal, "evaluate: empty LLM response")
+	}
+	var grade struct {
+		Relevance    float64 `json:"relevance"`
+		Groundedness float64 `json:"groundedness"`
+		Rationale    string  `json:"rationale"`
+	}
+	if err := json.Unmarshal([]byte(llmScores.FindString(replyAnswer(resp.Choices[0].Message.Content))), &grade); err != nil {
+		return nil, status.Errorf(codes.Internal, "evaluate: parse LLM grade: %v", err)
+	}
+	relevance, groundedness := unitScore(grade.Relevance), unitScore(grade.Groundedness)
+	return &pb.EvaluateResponse{
+		Relevance:    relevance,
+		Groundedness: groundedness,
+		Score:        (relevance + groundedness) / 2,
+		Rationale:    grade.Rationale,
+		ModelName:    llm.Model,
+	}, nil
+}
+
+// unitScore maps a 0-10 grade to 0-1.
+func unitScore(grade float64) float64 {
+	return min(max(grade/10, 0), 1)
+}
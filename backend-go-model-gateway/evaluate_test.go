@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	pb "backend-go-model-gateway/proto/proto"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestEvaluateAnswer_LLMJudge(t *testing.T) {
+	var sent openai.ChatCompletionRequest
+	llm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&sent)
+		_ = json.NewEncoder(w).Encode(openai.ChatCompletionResponse{
+			Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Role: "assistant", Content: "```json\n{\"relevance\": 8, \"groundedness\": 14, \"rationale\": \"Cites the schedule.\"}\n```"}}},
+		})
+	}))
+	defer llm.Close()
+	cfg := openai.DefaultConfig("")
+	cfg.BaseURL = llm.URL
+
+	s := &server{
+		llm:            &llmRuntime{Provider: providerOllama, Model: "judge", Client: openai.NewClientWithConfig(cfg)},
+		requestTimeout: time.Duration(defaultRequestTimeoutSec) * time.Second,
+	}
+	resp, err := s.EvaluateAnswer(context.Background(), &pb.EvaluateRequest{
+		Prompt:  "When is my dentist appointment?",
+		Answer:  "Tuesday at 9am.",
+		Context: []string{"Dentist: Tuesday 9:00"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Grades are scaled to 0-1 and clamped.
+	if resp.GetRelevance() != 0.8 || resp.GetGroundedness() != 1 || resp.GetScore() != 0.9 || resp.GetRationale() != "Cites the schedule." || resp.GetModelName() != "judge" {
+		t.Fatalf("response = %+v", resp)
+	}
+	if user := sent.Messages[1].Content; !strings.Contains(user, "Dentist: Tuesday 9:00") || !strings.Contains(user, "Tuesday at 9am.") {
+		t.Fatalf("judge prompt = %s", user)
+	}
+
+	if _, err := s.EvaluateAnswer(context.Background(), &pb.EvaluateRequest{Prompt: "x"}); err == nil {
+		t.Fatal("empty answer: want an error")
+	}
+}
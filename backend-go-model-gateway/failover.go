@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// providerChain is the failover chain for names (LLM_PROVIDERS, an ordered
+// list such as "openrouter,ollama,mock"), primary first, or the single
+// provider (LLM_PROVIDER) when names is empty.
+func providerChain(names []string, single string) ([]llmProvider, error) {
+	if len(names) == 0 {
+		return []llmProvider{llmProvider(strings.ToLower(single))}, nil
+	}
+	var chain []llmProvider
+	for _, name := range names {
+		provider := llmProvider(strings.ToLower(strings.TrimSpace(name)))
+		if provider == "" {
+			continue
+		}
+		if slices.Contains(chain, provider) {
+			return nil, fmt.Errorf("LLM_PROVIDERS lists %q twice", provider)
+		}
+		chain = append(chain, provider)
+	}
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("LLM_PROVIDERS=%q names no provider", strings.Join(names, ","))
+	}
+	return chain, nil
+}
+
+// failoverWorthy reports whether a failed GetPlan should move on to the next
+// provider: rate limits (429), server errors (5xx), timeouts and unreachable
+// providers. Other API errors, such as a rejected request, are returned.
+func failoverWorthy(err error) bool {
+	var apiErr *openai.APIError
+	var reqErr *openai.RequestError
+	code := 0
+	switch {
+	case errors.As(err, &apiErr):
+		code = apiErr.HTTPStatusCode
+	case errors.As(err, &reqErr):
+		code = reqErr.HTTPStatusCode
+	default:
+		// Timeouts, connection refused, DNS and TLS failures.
+		return true
+	}
+	return code == http.StatusTooManyRequests || code >= http.StatusInternalServerError
+}
+
+// chain returns the providers a GetPlan tries in order. A preferred provider
+// from LLM_PROVIDERS goes first and the others keep their order; ok is false
+// when preferred is not configured, and the configured order is used.
+func (r *llmRuntime) chain(preferred string) (chain []*llmRuntime, ok bool) {
+	chain = append([]*llmRuntime{r}, r.Fallbacks...)
+	if preferred == "" {
+		return chain, true
+	}
+	i := slices.IndexFunc(chain, func(c *llmRuntime) bool { return strings.EqualFold(string(c.Provider), preferred) })
+	if i < 0 {
+		return chain, false
+	}
+	chosen := chain[i]
+	return append([]*llmRuntime{chosen}, slices.Delete(chain, i, i+1)...), true
+}
+
+// chainNames lists a runtime's provider and its fallbacks, for admin status.
+func (r *llmRuntime) chainNames() []llmProvider {
+	names := []llmProvider{r.Provider}
+	for _, f := range r.Fallbacks {
+		names = append(names, f.Provider)
+	}
+	return names
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	pb "backend-go-model-gateway/proto/proto"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// failoverProvider is a fake OpenAI-compatible provider answering status
+// (200: a plan naming the provider).
+func failoverProvider(t *testing.T, name string, status int, calls *int) *llmRuntime {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*calls++
+		w.Header().Set("Content-Type", "application/json")
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte(`{"error":{"message":"upstream unavailable","type":"server_error"}}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(openai.ChatCompletionResponse{
+			Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Role: "assistant", Content: `{"steps":["from ` + name + `"]}`}}},
+		})
+	}))
+	t.Cleanup(srv.Close)
+	cfg := openai.DefaultConfig("")
+	cfg.BaseURL = srv.URL
+	return &llmRuntime{Provider: llmProvider(name), Model: name + "-model", Client: openai.NewClientWithConfig(cfg), ToolCalling: toolCallingJSON}
+}
+
+func TestGetPlan_Failover(t *testing.T) {
+	var primaryCalls, fallbackCalls int
+	primary := failoverProvider(t, "openrouter", http.StatusServiceUnavailable, &primaryCalls)
+	fallback := failoverProvider(t, "ollama", http.StatusOK, &fallbackCalls)
+	primary.Fallbacks = []*llmRuntime{fallback}
+	s := &server{llm: primary, requestTimeout: time.Duration(defaultRequestTimeoutSec) * time.Second}
+
+	resp, err := s.GetPlan(context.Background(), &pb.PlanRequest{Prompt: "plan", Model: "openai/gpt-4o-mini"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.GetProvider() != "ollama" || resp.GetModelName() != "ollama-model" || primaryCalls != 1 || fallbackCalls != 1 {
+		t.Fatalf("served by %s/%s after %d+%d calls", resp.GetProvider(), resp.GetModelName(), primaryCalls, fallbackCalls)
+	}
+
+	// The last provider's error is returned; a mock at the end always answers.
+	fallback.Client = failoverProvider(t, "ollama", http.StatusBadGateway, &fallbackCalls).Client
+	if _, err := s.GetPlan(context.Background(), &pb.PlanRequest{Prompt: "plan"}); err == nil {
+		t.Fatal("GetPlan succeeded with every provider failing")
+	}
+	primary.Fallbacks = append(primary.Fallbacks, &llmRuntime{Provider: providerMock, Model: "mock"})
+	if resp, err := s.GetPlan(context.Background(), &pb.PlanRequest{Prompt: "plan"}); err != nil || resp.GetProvider() != "mock" {
+		t.Fatalf("mock failover = %v, %v", resp, err)
+	}
+}
+
+func TestGetPlan_NoFailoverOnClientError(t *testing.T) {
+	var primaryCalls, fallbackCalls int
+	primary := failoverProvider(t, "openrouter", http.StatusBadRequest, &primaryCalls)
+	primary.Fallbacks = []*llmRuntime{failoverProvider(t, "ollama", http.StatusOK, &fallbackCalls)}
+	s := &server{llm: primary, requestTimeout: time.Duration(defaultRequestTimeoutSec) * time.Second}
+
+	if _, err := s.GetPlan(context.Background(), &pb.PlanRequest{Prompt: "plan"}); err == nil || fallbackCalls != 0 {
+		t.Fatalf("err = %v after %d fallback calls", err, fallbackCalls)
+	}
+}
+
+func TestFailoverWorthy(t *testing.T) {
+	for err, want := range map[error]bool{
+		&openai.APIError{HTTPStatusCode: http.StatusTooManyRequests}:         true,
+		&openai.APIError{HTTPStatusCode: http.StatusBadGateway}:              true,
+		&openai.RequestError{HTTPStatusCode: http.StatusInternalServerError}: true,
+		&openai.APIError{HTTPStatusCode: http.StatusUnauthorized}:            false,
+		context.DeadlineExceeded:                                             true,
+		errors.New("dial tcp: connection refused"):                           true,
+	} {
+		if got := failoverWorthy(err); got != want {
+			t.Errorf("failoverWorthy(%v) = %v", err, got)
+		}
+	}
+}
+
+func TestProviderChain(t *testing.T) {
+	t.Setenv("LLM_PROVIDER", "ollama")
+	if chain, err := envConfig(t).LLM.chain(); err != nil || len(chain) != 1 || chain[0] != providerOllama {
+		t.Fatalf("LLM_PROVIDER only: %v, %v", chain, err)
+	}
+	t.Setenv("LLM_PROVIDERS", " OpenRouter, ollama ,mock,")
+	if chain, err := envConfig(t).LLM.chain(); err != nil || len(chain) != 3 || chain[0] != providerOpenRouter || chain[2] != providerMock {
+		t.Fatalf("chain = %v, %v", chain, err)
+	}
+	t.Setenv("LLM_PROVIDERS", "ollama,ollama")
+	if _, err := loadConfig(); err == nil {
+		t.Fatal("duplicate provider accepted")
+	}
+
+	t.Setenv("LLM_PROVIDERS", "ollama,mock")
+	llm, err := newLLMClient(context.Background(), nil, envConfig(t).LLM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if llm.Provider != providerOllama || len(llm.Fallbacks) != 1 || llm.Fallbacks[0].Provider != providerMock {
+		t.Fatalf("runtime chain = %v", llm.chainNames())
+	}
+}
+
+func TestGetPlan_PreferredProvider(t *testing.T) {
+	var primaryCalls, ollamaCalls int
+	primary := failoverProvider(t, "openrouter", http.StatusOK, &primaryCalls)
+	ollama := failoverProvider(t, "ollama", http.StatusOK, &ollamaCalls)
+	primary.Fallbacks = []*llmRuntime{ollama}
+	s := &server{llm: primary, requestTimeout: time.Duration(defaultRequestTimeoutSec) * time.Second}
+
+	// The preferred provider serves, with the preferred model.
+	resp, err := s.GetPlan(context.Background(), &pb.PlanRequest{Prompt: "plan", Provider: "ollama", Model: "qwen2.5:0.5b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.GetProvider() != "ollama" || resp.GetModelName() != "qwen2.5:0.5b" || primaryCalls != 0 || ollamaCalls != 1 {
+		t.Fatalf("served by %s/%s after %d+%d calls", resp.GetProvider(), resp.GetModelName(), primaryCalls, ollamaCalls)
+	}
+
+	// When it fails, the rest of the chain still follows.
+	ollama.Client = failoverProvider(t, "ollama", http.StatusServiceUnavailable, &ollamaCalls).Client
+	if resp, err := s.GetPlan(context.Background(), &pb.PlanRequest{Prompt: "plan", Provider: "ollama"}); err != nil || resp.GetProvider() != "openrouter" {
+		t.Fatalf("failover from the preferred provider = %v, %v", resp, err)
+	}
+
+	// An unconfigured provider is ignored.
+	if resp, err := s.GetPlan(context.Background(), &pb.PlanRequest{Prompt: "plan", Provider: "anthropic"}); err != nil || resp.GetProvider() != "openrouter" {
+		t.Fatalf("unconfigured provider = %v, %v", resp, err)
+	}
+	if names := primary.chainNames(); len(names) != 2 || names[0] != providerOpenRouter {
+		t.Fatalf("chain changed: %v", names)
+	}
+}
+
+func TestGetPlan_RetrievalOnlyFallback(t *testing.T) {
+	var calls int
+	primary := failoverProvider(t, "openrouter", http.StatusServiceUnavailable, &calls)
+	s := &server{llm: primary, vectorDB: fakeRAGClient{}, requestTimeout: time.Duration(defaultRequestTimeoutSec) * time.Second}
+	if _, err := s.GetPlan(context.Background(), &pb.PlanRequest{Prompt: "vacation policy"}); err == nil {
+		t.Fatal("GetPlan succeeded with the fallback off")
+	}
+
+	s.retrievalFallback = true
+	resp, err := s.GetPlan(context.Background(), &pb.PlanRequest{Prompt: "vacation policy"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.GetDegraded() || resp.GetModelName() != retrievalOnlyModel || len(resp.GetMatches()) != 1 || resp.GetMatches()[0].GetId() != "fake-1" {
+		t.Fatalf("degraded response = %v", resp)
+	}
+	var plan struct {
+		Degraded bool `json:"degraded"`
+		Matches  []struct {
+			ID string `json:"id"`
+		} `json:"matches"`
+	}
+	if err := json.Unmarshal([]byte(resp.GetPlan()), &plan); err != nil || !plan.Degraded || len(plan.Matches) != 1 || plan.Matches[0].ID != "fake-1" {
+		t.Fatalf("plan = %s (%v)", resp.GetPlan(), err)
+	}
+
+	// A rejected request is the caller's error, not an outage.
+	s.llm = failoverProvider(t, "openrouter", http.StatusBadRequest, &calls)
+	if _, err := s.GetPlan(context.Background(), &pb.PlanRequest{Prompt: "vacation policy"}); err == nil {
+		t.Fatal("GetPlan answered retrieval-only for a client error")
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"math"
+
+	pb "backend-go-model-gateway/proto/proto"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+const (
+	defaultPlanTemperature = 0.2
+	defaultMaxTokensCap    = 4096
+	maxStopSequences       = 4
+)
+
+// generation is a GetPlan or Chat request's sampling parameters after
+// clamping.
+// Zero topP and maxTokens leave the provider's defaults.
+type generation struct {
+	temperature float32
+	topP        float32
+	maxTokens   int
+	stop        []string
+}
+
+// planGeneration reads a GetPlan request's generation parameters, clamped to
+// what providers accept (maxTokensCap <= 0 means defaultMaxTokensCap). It
+// also returns the names of the parameters it had to clamp.
+func planGeneration(in *pb.PlanRequest, maxTokensCap int) (g generation, clamped []string) {
+	return clampGeneration(in.Temperature, in.TopP, in.MaxTokens, in.GetStop(), maxTokensCap)
+}
+
+// chatGeneration is planGeneration for a Chat request.
+func chatGeneration(in *pb.ChatRequest, maxTokensCap int) (g generation, clamped []string) {
+	return clampGeneration(in.Temperature, in.TopP, in.MaxTokens, in.GetStop(), maxTokensCap)
+}
+
+// clampGeneration clamps the parameters a request set (nil: unset).
+func clampGeneration(temperature, topP *float32, maxTokens *int32, stop []string, maxTokensCap int) (g generation, clamped []string) {
+	if maxTokensCap <= 0 {
+		maxTokensCap = defaultMaxTokensCap
+	}
+	g.temperature = defaultPlanTemperature
+	if temperature != nil {
+		g.temperature = clampFloat(*temperature, 0, 2)
+		if g.temperature != *temperature {
+			clamped = append(clamped, "temperature")
+		}
+	}
+	if topP != nil {
+		g.topP = clampFloat(*topP, math.SmallestNonzeroFloat32, 1)
+		if g.topP != *topP {
+			clamped = append(clamped, "top_p")
+		}
+	}
+	if maxTokens != nil {
+		g.maxTokens = min(max(int(*maxTokens), 1), maxTokensCap)
+		if g.maxTokens != int(*maxTokens) {
+			clamped = append(clamped, "max_tokens")
+		}
+	}
+	for _, s := range stop {
+		if s == "" {
+			continue
+		}
+		if len(g.stop) == maxStopSequences {
+			clamped = append(clamped, "stop")
+			break
+		}
+		g.stop = append(g.stop, s)
+	}
+	return g, clamped
+}
+
+// apply sets the parameters on a chat completion request.
+func (g generation) apply(req *openai.ChatCompletionRequest) {
+	req.Temperature = g.temperature
+	// go-openai omits a zero temperature, which providers read as their own
+	// default (often 1); the smallest float is as good as 0.
+	if req.Temperature == 0 {
+		req.Temperature = math.SmallestNonzeroFloat32
+	}
+	req.TopP = g.topP
+	req.MaxTokens = g.maxTokens
+	req.Stop = g.stop
+}
+
+func clampFloat(v, lo, hi float32) float32 {
+	if v != v { // NaN
+		return lo
+	}
+	return min(max(v, lo), hi)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	pb "backend-go-model-gateway/proto/proto"
+
+	"github.com/sashabaranov/go-openai"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestPlanGeneration_Clamps(t *testing.T) {
+	g, clamped := planGeneration(&pb.PlanRequest{}, 0)
+	if g.temperature != defaultPlanTemperature || g.topP != 0 || g.maxTokens != 0 || g.stop != nil || clamped != nil {
+		t.Fatalf("defaults = %+v, clamped %q", g, clamped)
+	}
+
+	in := &pb.PlanRequest{
+		Temperature: proto.Float32(3),
+		TopP:        proto.Float32(-1),
+		MaxTokens:   proto.Int32(100000),
+		Stop:        []string{"a", "", "b", "c", "d", "e"},
+	}
+	g, clamped = planGeneration(in, 2048)
+	if g.temperature != 2 || g.topP != math.SmallestNonzeroFloat32 || g.maxTokens != 2048 || !slices.Equal(g.stop, []string{"a", "b", "c", "d"}) {
+		t.Fatalf("clamped = %+v", g)
+	}
+	if !slices.Equal(clamped, []string{"temperature", "top_p", "max_tokens", "stop"}) {
+		t.Fatalf("clamped params = %q", clamped)
+	}
+
+	g, clamped = planGeneration(&pb.PlanRequest{Temperature: proto.Float32(0), MaxTokens: proto.Int32(256)}, 0)
+	if g.temperature != 0 || g.maxTokens != 256 || clamped != nil {
+		t.Fatalf("in range = %+v, clamped %q", g, clamped)
+	}
+}
+
+func TestGetPlan_SendsGenerationParams(t *testing.T) {
+	var got map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = nil
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(openai.ChatCompletionResponse{
+			Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Role: "assistant", Content: `{"steps":["ok"]}`}}},
+		})
+	}))
+	defer srv.Close()
+	cfg := openai.DefaultConfig("")
+	cfg.BaseURL = srv.URL
+	llm := &llmRuntime{Provider: providerOllama, Model: "m", Client: openai.NewClientWithConfig(cfg), ToolCalling: toolCallingJSON}
+	s := &server{llm: llm, requestTimeout: 5 * time.Second}
+
+	in := &pb.PlanRequest{Prompt: "plan", Temperature: proto.Float32(0), TopP: proto.Float32(0.5), MaxTokens: proto.Int32(64), Stop: []string{"\n\n"}}
+	if _, err := s.GetPlan(context.Background(), in); err != nil {
+		t.Fatal(err)
+	}
+	if got["temperature"] == nil || got["temperature"].(float64) > 1e-6 {
+		t.Fatalf("temperature = %v, want ~0 and present", got["temperature"])
+	}
+	if got["top_p"] != 0.5 || got["max_tokens"] != float64(64) || len(got["stop"].([]any)) != 1 {
+		t.Fatalf("request = %v", got)
+	}
+
+	if _, err := s.GetPlan(context.Background(), &pb.PlanRequest{Prompt: "plan"}); err != nil {
+		t.Fatal(err)
+	}
+	if temp := got["temperature"].(float64); math.Abs(temp-defaultPlanTemperature) > 1e-6 || got["max_tokens"] != nil {
+		t.Fatalf("default request = %v", got)
+	}
+}
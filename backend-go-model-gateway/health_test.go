@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"backend-go-model-gateway/pkg/admin"
+	pb "backend-go-model-gateway/proto/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	grpc_health_v1 "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+)
+
+func TestHealth_NamedServicesWatchAndReflection(t *testing.T) {
+	interval := healthWatchInterval
+	healthWatchInterval = 10 * time.Millisecond
+	t.Cleanup(func() { healthWatchInterval = interval })
+
+	t.Setenv("GATEWAY_ADMIN_API_KEY", "ops")
+	gw := &server{llm: &llmRuntime{Provider: providerMock}}
+	ops := admin.New(admin.Options{KeyName: "GATEWAY_ADMIN_API_KEY", Status: gw.adminStatus})
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gs := grpc.NewServer()
+	grpc_health_v1.RegisterHealthServer(gs, &healthServer{gateway: gw, ops: ops})
+	pb.RegisterModelGatewayServer(gs, gw)
+	reflection.Register(gs)
+	go func() { _ = gs.Serve(lis) }()
+	t.Cleanup(gs.Stop)
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	hc := grpc_health_v1.NewHealthClient(conn)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for _, service := range []string{"", pb.ModelGateway_ServiceDesc.ServiceName} {
+		resp, err := hc.Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: service})
+		if err != nil || resp.GetStatus() != grpc_health_v1.HealthCheckResponse_SERVING {
+			t.Fatalf("Check(%q) = %v, %v", service, resp.GetStatus(), err)
+		}
+	}
+	if _, err := hc.Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: "nope.Service"}); status.Code(err) != codes.NotFound {
+		t.Fatalf("Check(unknown) error = %v, want NotFound", err)
+	}
+
+	watch, err := hc.Watch(ctx, &grpc_health_v1.HealthCheckRequest{Service: pb.ModelGateway_ServiceDesc.ServiceName})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp, err := watch.Recv(); err != nil || resp.GetStatus() != grpc_health_v1.HealthCheckResponse_SERVING {
+		t.Fatalf("first Watch status = %v, %v", resp.GetStatus(), err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/admin/drain", nil)
+	req.Header.Set("Authorization", "Bearer ops")
+	rec := httptest.NewRecorder()
+	ops.Handler().ServeHTTP(rec, req)
+	if !ops.Draining() {
+		t.Fatalf("drain = %d %s", rec.Code, rec.Body)
+	}
+	if resp, err := watch.Recv(); err != nil || resp.GetStatus() != grpc_health_v1.HealthCheckResponse_NOT_SERVING {
+		t.Fatalf("Watch status after drain = %v, %v", resp.GetStatus(), err)
+	}
+
+	unknown, err := hc.Watch(ctx, &grpc_health_v1.HealthCheckRequest{Service: "nope.Service"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp, err := unknown.Recv(); err != nil || resp.GetStatus() != grpc_health_v1.HealthCheckResponse_SERVICE_UNKNOWN {
+		t.Fatalf("Watch(unknown) = %v, %v", resp.GetStatus(), err)
+	}
+
+	refl, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := refl.Send(&reflectionpb.ServerReflectionRequest{MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{}}); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := refl.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found bool
+	for _, s := range resp.GetListServicesResponse().GetService() {
+		found = found || s.GetName() == pb.ModelGateway_ServiceDesc.ServiceName
+	}
+	if !found {
+		t.Fatalf("reflection services = %v, want %s", resp.GetListServicesResponse().GetService(), pb.ModelGateway_ServiceDesc.ServiceName)
+	}
+}
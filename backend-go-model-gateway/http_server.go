@@ -1,18 +1,48 @@
 package main
 
 import (
+	"crypto/subtle"
 	"encoding/json"
+	"log"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
+
+	"backend-go-model-gateway/pkg/admin"
+	"backend-go-model-gateway/pkg/secrets"
 )
 
+// adminRoutes are the gateway's write endpoints. They are served only behind
+// GATEWAY_ADMIN_API_KEY (see requireAdminKey).
+type adminRoutes struct {
+	store *secrets.Store
+	// ingest is nil when the RAG backend does not accept writes.
+	ingest *ingestService
+	kbs    *kbService
+	// debug is nil without a RAG backend.
+	debug *retrievalDebugService
+	// ops serves /admin/* (nil: not mounted). It does its own authentication.
+	ops *admin.Server
+}
+
 // NewHTTPMux wires up the temporary HTTP endpoints for the model gateway.
 //
 // This is intentionally split out from main() so it can be verified via unit/integration
 // tests without booting the full gRPC + LLM stack.
-func NewHTTPMux(vectorClient RAGContextClient) *http.ServeMux {
+func NewHTTPMux(vectorClient RAGContextClient, admin adminRoutes) *http.ServeMux {
 	mux := http.NewServeMux()
 
+	mux.Handle("/api/v1/ingest", requireAdminKey(admin.store, admin.ingest))
+	mux.Handle("/api/v1/retrieval/debug", requireAdminKey(admin.store, admin.debug))
+	if admin.kbs != nil {
+		mux.Handle("/api/v1/kbs", requireAdminKey(admin.store, admin.kbs))
+		mux.Handle("/api/v1/kbs/", requireAdminKey(admin.store, admin.kbs))
+	}
+	if admin.ops != nil {
+		mux.Handle("/admin/", admin.ops.Handler())
+	}
+
 	mux.HandleFunc("/api/v1/vector-test", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
 			w.WriteHeader(http.StatusMethodNotAllowed)
@@ -33,7 +63,8 @@ func NewHTTPMux(vectorClient RAGContextClient) *http.ServeMux {
 			return
 		}
 
-		matches, err := vectorClient.GetContext(r.Context(), VectorQueryRequest{QueryText: q, TopK: k})
+		// kb may repeat; without it the default KB is searched.
+		matches, err := vectorClient.GetContext(r.Context(), VectorQueryRequest{QueryText: q, TopK: k, KnowledgeBases: r.URL.Query()["kb"]})
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			_ = json.NewEncoder(w).Encode(map[string]any{"error": err.Error()})
@@ -44,5 +75,57 @@ func NewHTTPMux(vectorClient RAGContextClient) *http.ServeMux {
 		_ = json.NewEncoder(w).Encode(matches)
 	})
 
+	// Cumulative provider token usage since process start (used by cmd/loadgen
+	// to report per-run token deltas).
+	mux.HandleFunc("/api/v1/usage", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			_ = json.NewEncoder(w).Encode(map[string]any{"error": "method not allowed"})
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(gatewayUsage.snapshot())
+	})
+
 	return mux
 }
+
+// requireAdminKey checks X-API-Key (or Authorization: Bearer) against
+// GATEWAY_ADMIN_API_KEY, resolved through pkg/secrets on every request. If the
+// key is not set, authentication is DISABLED (dev mode only), as for the
+// planner's PAGI_API_KEY.
+func requireAdminKey(store *secrets.Store, next http.Handler) http.Handler {
+	return requireKey(store, "GATEWAY_ADMIN_API_KEY", next)
+}
+
+// requireKey is requireAdminKey for the key named name.
+func requireKey(store *secrets.Store, name string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apiKey, err := store.Lookup(r.Context(), name)
+		if err != nil {
+			// Configured but unreadable: fail closed rather than disabling auth.
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_ = json.NewEncoder(w).Encode(map[string]any{"error": "authentication unavailable"})
+			return
+		}
+		if apiKey == "" {
+			log.Printf(
+				`{"timestamp":"%s","level":"warn","service":"%s","component":"http","path":%q,"message":"%s not set - authentication disabled (INSECURE)"}`,
+				time.Now().Format(time.RFC3339Nano), SERVICE_NAME, r.URL.Path, name,
+			)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		provided := r.Header.Get("X-API-Key")
+		if provided == "" {
+			provided = strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		}
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(apiKey)) != 1 {
+			w.WriteHeader(http.StatusUnauthorized)
+			_ = json.NewEncoder(w).Encode(map[string]any{"error": "unauthorized"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ragIngester is implemented by RAG backends that accept writes. Ingestion
+// talks to the backend directly, not through the hybrid or tenancy wrappers.
+type ragIngester interface {
+	// ReplaceDocument deletes kb's existing chunks of documentID in namespace
+	// and writes chunks in their place, so re-ingesting a shorter version of a
+	// document leaves no stale chunks behind.
+	ReplaceDocument(ctx context.Context, kb, namespace, documentID string, chunks []ingestChunk) error
+}
+
+// ingestChunk is one chunk of an ingested document, ready to be written.
+type ingestChunk struct {
+	ID         string
+	Text       string
+	Source     string
+	DocumentID string
+	Tags       []string
+	CreatedAt  time.Time
+	Namespace  string
+	// Embedding is nil when the backend vectorizes documents itself.
+	Embedding []float32
+}
+
+// ingestChunkID derives a stable UUID for chunk i of a document: re-ingesting
+// overwrites the same IDs, and UUIDs are valid point/object IDs in every
+// backend (Qdrant and Weaviate accept nothing else).
+func ingestChunkID(namespace, kb, documentID string, i int) string {
+	name := fmt.Sprintf("pagi-rag:%s/%s/%s#%d", namespace, kb, documentID, i)
+	return uuid.NewSHA1(uuid.NameSpaceURL, []byte(name)).String()
+}
+
+// ingestService serves POST /api/v1/ingest.
+type ingestService struct {
+	ingester ragIngester
+	// embedder is nil when the backend vectorizes documents itself.
+	embedder Embedder
+	// kbs, when set, restricts writes to catalogued KBs.
+	kbs *kbCatalog
+	// cache is invalidated for the KB a document is written to.
+	cache *cachingRAGClient
+	// languages selects the embedding model for a KB's documents.
+	languages    kbLanguages
+	tenancy      string
+	chunkSize    int
+	chunkOverlap int
+	maxBytes     int64
+	// batchSize chunks go in one embeddings request (<= 0: all of them), up
+	// to concurrency requests at a time (see ingest_batch.go).
+	batchSize   int
+	concurrency int
+	// pacer limits embeddings requests per second (nil-safe: unlimited).
+	pacer *requestPacer
+	// checkpoints let a failed ingestion resume (nil-safe: off).
+	checkpoints *ingestCheckpoints
+}
+
+// newIngestService configures ingestion for the active backend from cfg
+// (INGEST_*). It returns nil when the backend does not accept writes
+// (RAG_BACKEND=memory).
+func newIngestService(b *ragBackend, kbs *kbCatalog, cfg IngestConfig) (*ingestService, error) {
+	if b == nil || b.ingester == nil {
+		return nil, nil
+	}
+	s := &ingestService{
+		ingester:     b.ingester,
+		embedder:     b.embedder,
+		kbs:          kbs,
+		cache:        b.cache,
+		languages:    b.languages,
+		tenancy:      b.tenancy,
+		chunkSize:    cfg.ChunkSize,
+		chunkOverlap: cfg.ChunkOverlap,
+		maxBytes:     int64(cfg.MaxBytes),
+	}
+	if err := s.configureEmbedding(cfg); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+type ingestRequest struct {
+	KB         string    `json:"kb"`
+	Content    string    `json:"content"`
+	Format     string    `json:"format"`
+	Source     string    `json:"source"`
+	DocumentID string    `json:"document_id"`
+	Tags       []string  `json:"tags"`
+	CreatedAt  time.Time `json:"created_at"`
+	// Namespace is the owning tenant; required when RAG_TENANCY=required.
+	Namespace    string `json:"namespace"`
+	ChunkSize    int    `json:"chunk_size"`
+	ChunkOverlap *int   `json:"chunk_overlap"`
+}
+
+type ingestResponse struct {
+	KB         string   `json:"kb"`
+	DocumentID string   `json:"document_id"`
+	Namespace  string   `json:"namespace,omitempty"`
+	Chunks     int      `json:"chunks"`
+	IDs        []string `json:"ids"`
+	// ResumedChunks were embedded by an earlier, failed attempt.
+	ResumedChunks int `json:"resumed_chunks,omitempty"`
+}
+
+// errIngestInvalid marks request errors (HTTP 400) as opposed to backend
+// failures (HTTP 502).
+var errIngestInvalid = errors.New("invalid ingest request")
+
+func (s *ingestService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		_ = json.NewEncoder(w).Encode(map[string]any{"error": "method not allowed"})
+		return
+	}
+	if s == nil {
+		w.WriteHeader(http.StatusNotImplemented)
+		_ = json.NewEncoder(w).Encode(map[string]any{"error": "the active RAG backend does not support ingestion"})
+		return
+	}
+
+	var req ingestRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, s.maxBytes)).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]any{"error": "invalid request body: " + err.Error()})
+		return
+	}
+	resp, err := s.ingest(r.Context(), req)
+	if err != nil {
+		status := http.StatusBadGateway
+		if errors.Is(err, errIngestInvalid) {
+			status = http.StatusBadRequest
+		}
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(map[string]any{"error": err.Error()})
+		return
+	}
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// ingest chunks, embeds and writes one document.
+func (s *ingestService) ingest(ctx context.Context, req ingestRequest) (*ingestResponse, error) {
+	start := time.Now()
+	if strings.TrimSpace(req.Content) == "" {
+		return nil, fmt.Errorf("%w: content is required", errIngestInvalid)
+	}
+	if req.KB == "" {
+		req.KB = defaultRAGKnowledgeBase
+	}
+	if s.kbs != nil && !s.kbs.Has(req.KB) {
+		return nil, fmt.Errorf("%w: unknown knowledge base %q (create it with POST /api/v1/kbs)", errIngestInvalid, req.KB)
+	}
+	switch {
+	case req.Namespace != "" && !tenantIDPattern.MatchString(req.Namespace):
+		return nil, fmt.Errorf("%w: invalid namespace %q", errIngestInvalid, req.Namespace)
+	case req.Namespace == "" && s.tenancy == ragTenancyRequired:
+		return nil, fmt.Errorf("%w: namespace is required when RAG_TENANCY=required", errIngestInvalid)
+	}
+	if req.DocumentID == "" {
+		sum := sha256.Sum256([]byte(req.Content))
+		req.DocumentID = "doc-" + hex.EncodeToString(sum[:8])
+	}
+	if req.Source == "" {
+		req.Source = req.DocumentID
+	}
+	if req.CreatedAt.IsZero() {
+		req.CreatedAt = time.Now().UTC()
+	}
+	size, overlap := s.chunkSize, s.chunkOverlap
+	if req.ChunkSize > 0 {
+		size = req.ChunkSize
+		overlap = min(overlap, size/2)
+	}
+	if req.ChunkOverlap != nil {
+		overlap = *req.ChunkOverlap
+	}
+
+	texts, err := chunkContent(req.Content, strings.ToLower(req.Format), size, overlap)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errIngestInvalid, err)
+	}
+	if len(texts) == 0 {
+		return nil, fmt.Errorf("%w: content produced no chunks", errIngestInvalid)
+	}
+
+	var (
+		vectors    [][]float32
+		resumed    int
+		checkpoint string
+	)
+	if s.embedder != nil {
+		lang := s.languages.of(req.KB)
+		checkpoint = ingestCheckpointKey(req.Namespace, req.KB, req.DocumentID, lang, texts)
+		if vectors, resumed, err = s.embedChunks(withEmbeddingLanguage(ctx, lang), checkpoint, texts); err != nil {
+			return nil, err
+		}
+	}
+	chunks := make([]ingestChunk, len(texts))
+	resp := &ingestResponse{KB: req.KB, DocumentID: req.DocumentID, Namespace: req.Namespace, Chunks: len(texts), IDs: make([]string, len(texts)), ResumedChunks: resumed}
+	for i, text := range texts {
+		chunks[i] = ingestChunk{
+			ID:         ingestChunkID(req.Namespace, req.KB, req.DocumentID, i),
+			Text:       text,
+			Source:     req.Source,
+			DocumentID: req.DocumentID,
+			Tags:       req.Tags,
+			CreatedAt:  req.CreatedAt,
+			Namespace:  req.Namespace,
+		}
+		if vectors != nil {
+			chunks[i].Embedding = vectors[i]
+		}
+		resp.IDs[i] = chunks[i].ID
+	}
+	if err := s.ingester.ReplaceDocument(ctx, req.KB, req.Namespace, req.DocumentID, chunks); err != nil {
+		return nil, err
+	}
+	s.checkpoints.drop(checkpoint)
+	s.cache.Invalidate(req.KB)
+
+	log.Printf(
+		`{"timestamp":"%s","level":"info","service":"%s","component":"Ingest","kb":%q,"document_id":%q,"namespace":%q,"format":%q,"chunks":%d,"resumed_chunks":%d,"latency_ms":%d}`,
+		time.Now().Format(time.RFC3339Nano), SERVICE_NAME, req.KB, req.DocumentID, req.Namespace, req.Format, len(chunks), resumed, time.Since(start).Milliseconds(),
+	)
+	return resp, nil
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultIngestBatchSize   = 64
+	defaultIngestConcurrency = 4
+	// maxMemoryCheckpoints bounds the unfinished documents kept in memory
+	// without INGEST_CHECKPOINT_DIR; the oldest is dropped first.
+	maxMemoryCheckpoints = 32
+)
+
+// configureEmbedding applies the batching settings of cfg to s: chunks per
+// embeddings request, requests in flight per document, requests per second
+// per replica (0: unlimited) and the checkpoint directory, which keeps
+// checkpoints across restarts.
+func (s *ingestService) configureEmbedding(cfg IngestConfig) error {
+	s.batchSize = cfg.EmbedBatchSize
+	s.concurrency = cfg.EmbedConcurrency
+	s.pacer = newRequestPacer(cfg.EmbedRate)
+	s.checkpoints = &ingestCheckpoints{dir: cfg.CheckpointDir}
+	if s.checkpoints.dir != "" {
+		if err := os.MkdirAll(s.checkpoints.dir, 0o755); err != nil {
+			return fmt.Errorf("INGEST_CHECKPOINT_DIR: %w", err)
+		}
+	}
+	return nil
+}
+
+// embedChunks embeds a document's chunks in batches of s.batchSize, up to
+// s.concurrency at a time. Each finished batch is checkpointed under key, so
+// when a batch fails a retry of the same document only embeds what is
+// missing. It returns how many chunks came from a checkpoint.
+func (s *ingestService) embedChunks(ctx context.Context, key string, texts []string) (vectors [][]float32, resumed int, err error) {
+	vectors = make([][]float32, len(texts))
+	for start, batch := range s.checkpoints.load(key) {
+		if start >= 0 && start+len(batch) <= len(texts) {
+			copy(vectors[start:], batch)
+		}
+	}
+
+	size := s.batchSize
+	if size <= 0 {
+		size = len(texts)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+		slots    = make(chan struct{}, max(s.concurrency, 1))
+	)
+	for start := 0; start < len(texts); start += size {
+		end := min(start+size, len(texts))
+		if embedded(vectors[start:end]) {
+			resumed += end - start
+			continue
+		}
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			err := s.pacer.wait(ctx)
+			var batch [][]float32
+			if err == nil {
+				batch, err = embedAll(ctx, s.embedder, texts[start:end])
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = fmt.Errorf("embed chunks %d-%d: %w", start, end-1, err)
+				}
+				cancel()
+				return
+			}
+			copy(vectors[start:], batch)
+			s.checkpoints.save(key, start, batch)
+		}()
+	}
+	wg.Wait()
+	if firstErr == nil {
+		firstErr = ctx.Err()
+	}
+	if firstErr != nil {
+		return nil, 0, firstErr
+	}
+	return vectors, resumed, nil
+}
+
+func embedded(vectors [][]float32) bool {
+	for _, v := range vectors {
+		if v == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// ingestCheckpointKey identifies one version of a document's chunks: a
+// changed document, chunking or embedding language starts from zero.
+func ingestCheckpointKey(namespace, kb, documentID, language string, texts []string) string {
+	h := sha256.New()
+	for _, part := range append([]string{namespace, kb, documentID, language}, texts...) {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// ingestCheckpoints keeps the embedded batches of documents whose ingestion
+// has not finished: in files under dir when it is set, in memory otherwise
+// (nil-safe: no checkpoints). A document's checkpoint is dropped once it is
+// written.
+type ingestCheckpoints struct {
+	dir string
+
+	mu    sync.Mutex
+	mem   map[string]map[int][][]float32
+	order []string
+}
+
+func (c *ingestCheckpoints) load(key string) map[int][][]float32 {
+	if c == nil {
+		return nil
+	}
+	if c.dir == "" {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return maps.Clone(c.mem[key])
+	}
+	entries, _ := os.ReadDir(filepath.Join(c.dir, key))
+	out := map[int][][]float32{}
+	for _, e := range entries {
+		start, err := strconv.Atoi(strings.TrimSuffix(e.Name(), ".json"))
+		if err != nil {
+			continue
+		}
+		b, err := os.ReadFile(filepath.Join(c.dir, key, e.Name()))
+		if err != nil {
+			continue
+		}
+		var batch [][]float32
+		if json.Unmarshal(b, &batch) == nil {
+			out[start] = batch
+		}
+	}
+	return out
+}
+
+// save records the batch of vectors starting at chunk start. Checkpoints are
+// best-effort: a failed write only costs re-embedding on retry.
+func (c *ingestCheckpoints) save(key string, start int, batch [][]float32) {
+	if c == nil {
+		return
+	}
+	if c.dir == "" {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.mem == nil {
+			c.mem = map[string]map[int][][]float32{}
+		}
+		if c.mem[key] == nil {
+			c.order = append(c.order, key)
+			if len(c.order) > maxMemoryCheckpoints {
+				delete(c.mem, c.order[0])
+				c.order = c.order[1:]
+			}
+			c.mem[key] = map[int][][]float32{}
+		}
+		c.mem[key][start] = batch
+		return
+	}
+	b, err := json.Marshal(batch)
+	if err != nil {
+		return
+	}
+	dir := filepath.Join(c.dir, key)
+	if os.MkdirAll(dir, 0o755) != nil {
+		return
+	}
+	// Write then rename, so a crash never leaves a partial batch.
+	tmp := filepath.Join(dir, fmt.Sprintf(".%d.tmp", start))
+	if os.WriteFile(tmp, b, 0o644) == nil {
+		_ = os.Rename(tmp, filepath.Join(dir, fmt.Sprintf("%d.json", start)))
+	}
+}
+
+func (c *ingestCheckpoints) drop(key string) {
+	if c == nil {
+		return
+	}
+	if c.dir != "" {
+		_ = os.RemoveAll(filepath.Join(c.dir, key))
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.mem[key]; !ok {
+		return
+	}
+	delete(c.mem, key)
+	for i, k := range c.order {
+		if k == key {
+			c.order = append(c.order[:i], c.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// requestPacer spaces requests evenly at no more than a rate per second,
+// across all its callers (nil-safe: unlimited).
+type requestPacer struct {
+	interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+// newRequestPacer returns nil for a rate <= 0.
+func newRequestPacer(perSecond float64) *requestPacer {
+	if perSecond <= 0 {
+		return nil
+	}
+	return &requestPacer{interval: time.Duration(float64(time.Second) / perSecond)}
+}
+
+// wait blocks until the caller's turn.
+func (p *requestPacer) wait(ctx context.Context) error {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	at := p.next
+	if now := time.Now(); at.Before(now) {
+		at = now
+	}
+	p.next = at.Add(p.interval)
+	p.mu.Unlock()
+
+	timer := time.NewTimer(time.Until(at))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
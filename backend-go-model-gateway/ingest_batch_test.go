@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// flakyEmbedder embeds with hashEmbedder, failing the batches that contain
+// a text in fail, and records the batch sizes and peak concurrency.
+type flakyEmbedder struct {
+	hashEmbedder
+
+	mu       sync.Mutex
+	fail     map[string]bool
+	batches  []int
+	inFlight int
+	peak     int
+}
+
+func (e *flakyEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	e.mu.Lock()
+	e.batches = append(e.batches, len(texts))
+	e.inFlight++
+	e.peak = max(e.peak, e.inFlight)
+	failed := false
+	for _, t := range texts {
+		failed = failed || e.fail[t]
+	}
+	e.mu.Unlock()
+	time.Sleep(5 * time.Millisecond)
+	e.mu.Lock()
+	e.inFlight--
+	e.mu.Unlock()
+	if failed {
+		return nil, errors.New("embeddings endpoint unavailable")
+	}
+	return embedAll(ctx, e.hashEmbedder, texts)
+}
+
+func (e *flakyEmbedder) embedded() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	n := 0
+	for _, b := range e.batches {
+		n += b
+	}
+	return n
+}
+
+func TestIngest_BatchesAndResumes(t *testing.T) {
+	for _, dir := range []string{"", t.TempDir()} {
+		name := "memory"
+		if dir != "" {
+			name = "dir"
+		}
+		t.Run(name, func(t *testing.T) {
+			ec, err := LoadEmbeddedRAGClient(context.Background(), writeCorpus(t, `{"id":"seed","text":"seed"}`), hashEmbedder{dims: 16})
+			if err != nil {
+				t.Fatalf("LoadEmbeddedRAGClient: %v", err)
+			}
+			var paragraphs []string
+			for i := range 20 {
+				paragraphs = append(paragraphs, fmt.Sprintf("Paragraph %02d about sleep hygiene.", i))
+			}
+			emb := &flakyEmbedder{hashEmbedder: hashEmbedder{dims: 16}, fail: map[string]bool{paragraphs[13]: true}}
+			s := &ingestService{
+				ingester: ec, embedder: emb, chunkSize: 40, maxBytes: 1 << 20,
+				batchSize: 3, concurrency: 2, checkpoints: &ingestCheckpoints{dir: dir},
+			}
+			req := ingestRequest{KB: "Body-KB", DocumentID: "sleep", Content: strings.Join(paragraphs, "\n\n")}
+
+			if _, err := s.ingest(context.Background(), req); err == nil || !strings.Contains(err.Error(), "embed chunks 12-14") {
+				t.Fatalf("first attempt: err = %v", err)
+			}
+			if emb.peak > 2 {
+				t.Fatalf("%d embeddings requests in flight, want at most 2", emb.peak)
+			}
+			for _, n := range emb.batches {
+				if n > 3 {
+					t.Fatalf("batch of %d chunks, want at most 3", n)
+				}
+			}
+
+			firstAttempt := emb.embedded()
+			emb.mu.Lock()
+			emb.fail = nil
+			emb.mu.Unlock()
+			resp, err := s.ingest(context.Background(), req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.Chunks != 20 || resp.ResumedChunks == 0 {
+				t.Fatalf("retry: %d chunks, %d resumed", resp.Chunks, resp.ResumedChunks)
+			}
+			if again := emb.embedded() - firstAttempt; again != resp.Chunks-resp.ResumedChunks {
+				t.Fatalf("retry embedded %d chunks, want the %d not checkpointed", again, resp.Chunks-resp.ResumedChunks)
+			}
+
+			// A finished document starts from zero the next time.
+			resp, err = s.ingest(context.Background(), req)
+			if err != nil || resp.ResumedChunks != 0 {
+				t.Fatalf("re-ingest: resumed %d (%v), want 0", resp.ResumedChunks, err)
+			}
+		})
+	}
+}
+
+func TestRequestPacer(t *testing.T) {
+	p := newRequestPacer(100)
+	start := time.Now()
+	for range 5 {
+		if err := p.wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Fatalf("5 requests at 100/s took %v, want >= 40ms", elapsed)
+	}
+	if newRequestPacer(0) != nil || (*requestPacer)(nil).wait(context.Background()) != nil {
+		t.Fatal("rate 0 should not limit")
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func postIngest(t *testing.T, url, key string, body map[string]any) (*http.Response, map[string]any) {
+	t.Helper()
+	b, _ := json.Marshal(body)
+	req, _ := http.NewRequest(http.MethodPost, url+"/api/v1/ingest", bytes.NewReader(b))
+	if key != "" {
+		req.Header.Set("X-API-Key", key)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST /api/v1/ingest: %v", err)
+	}
+	defer resp.Body.Close()
+	var out map[string]any
+	_ = json.NewDecoder(resp.Body).Decode(&out)
+	return resp, out
+}
+
+func TestIngestEndpoint_ChunksEmbedsAndReplaces(t *testing.T) {
+	t.Setenv("GATEWAY_ADMIN_API_KEY", "admin")
+	ec, err := LoadEmbeddedRAGClient(context.Background(), writeCorpus(t, `{"id":"seed","kb":"Domain-KB","text":"Unrelated seed document"}`), hashEmbedder{dims: 64})
+	if err != nil {
+		t.Fatalf("LoadEmbeddedRAGClient: %v", err)
+	}
+	ingest := &ingestService{ingester: ec, embedder: ec.embedder, chunkSize: 60, chunkOverlap: 10, maxBytes: 1 << 20}
+	srv := httptest.NewServer(NewHTTPMux(ec, adminRoutes{ingest: ingest}))
+	t.Cleanup(srv.Close)
+
+	doc := map[string]any{
+		"kb":          "Body-KB",
+		"format":      "markdown",
+		"document_id": "sleep-guide",
+		"source":      "sleep.md",
+		"tags":        []string{"health"},
+		"content":     "# Sleep\nAdults need seven to nine hours of sleep.\n\n# Naps\nShort naps of twenty minutes restore alertness.\n",
+	}
+	if resp, _ := postIngest(t, srv.URL, "wrong", doc); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("wrong key: got %d, want 401", resp.StatusCode)
+	}
+	resp, out := postIngest(t, srv.URL, "admin", doc)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("ingest: got %d %v", resp.StatusCode, out)
+	}
+	if out["chunks"] != float64(2) || out["document_id"] != "sleep-guide" {
+		t.Fatalf("ingest response = %v", out)
+	}
+
+	matches, err := ec.GetContext(context.Background(), VectorQueryRequest{QueryText: "naps twenty minutes", TopK: 1, KnowledgeBases: []string{"Body-KB"}})
+	if err != nil {
+		t.Fatalf("GetContext: %v", err)
+	}
+	if len(matches) != 1 || !strings.Contains(matches[0].Text, "naps") || matches[0].Source != "sleep.md" {
+		t.Fatalf("matches = %+v", matches)
+	}
+
+	// Re-ingesting a shorter version leaves no stale chunks behind.
+	doc["content"] = "# Sleep\nAdults need seven to nine hours of sleep.\n"
+	if resp, out := postIngest(t, srv.URL, "admin", doc); resp.StatusCode != http.StatusOK || out["chunks"] != float64(1) {
+		t.Fatalf("re-ingest: got %d %v", resp.StatusCode, out)
+	}
+	matches, _ = ec.GetContext(context.Background(), VectorQueryRequest{QueryText: "naps twenty minutes", TopK: 5, KnowledgeBases: []string{"Body-KB"}})
+	if len(matches) != 1 {
+		t.Fatalf("after re-ingest got %d Body-KB chunks, want 1: %+v", len(matches), matches)
+	}
+}
+
+func TestIngestEndpoint_RejectsInvalidRequests(t *testing.T) {
+	ec, err := LoadEmbeddedRAGClient(context.Background(), writeCorpus(t, `{"id":"seed","text":"seed"}`), hashEmbedder{dims: 16})
+	if err != nil {
+		t.Fatalf("LoadEmbeddedRAGClient: %v", err)
+	}
+	ingest := &ingestService{ingester: ec, embedder: ec.embedder, tenancy: ragTenancyRequired, chunkSize: 100, chunkOverlap: 10, maxBytes: 1 << 20}
+	srv := httptest.NewServer(NewHTTPMux(ec, adminRoutes{ingest: ingest}))
+	t.Cleanup(srv.Close)
+
+	for name, body := range map[string]map[string]any{
+		"empty content":     {"content": " ", "namespace": "acme"},
+		"unknown format":    {"content": "x", "format": "docx", "namespace": "acme"},
+		"missing namespace": {"content": "x"},
+		"bad namespace":     {"content": "x", "namespace": "acme\" || true"},
+	} {
+		if resp, out := postIngest(t, srv.URL, "", body); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: got %d %v, want 400", name, resp.StatusCode, out)
+		}
+	}
+
+	noWrites := httptest.NewServer(NewHTTPMux(ec, adminRoutes{}))
+	t.Cleanup(noWrites.Close)
+	if resp, _ := postIngest(t, noWrites.URL, "", map[string]any{"content": "x"}); resp.StatusCode != http.StatusNotImplemented {
+		t.Errorf("without an ingester: got %d, want 501", resp.StatusCode)
+	}
+}
+
+func TestQdrantRAGClient_ReplaceDocument(t *testing.T) {
+	var mu sync.Mutex
+	var calls []string
+	bodies := map[string]map[string]any{}
+	qdrant := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		call := r.Method + " " + r.URL.RequestURI()
+		calls = append(calls, call)
+		bodies[call] = body
+		mu.Unlock()
+		_, _ = w.Write([]byte(`{"result":{"status":"completed"}}`))
+	}))
+	t.Cleanup(qdrant.Close)
+	t.Setenv("QDRANT_URL", qdrant.URL)
+	t.Setenv("QDRANT_COLLECTION_PREFIX", "pagi_")
+
+	c, err := NewQdrantRAGClient(envConfig(t).RAG, nil, fakeEmbedder{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	created := time.Unix(1700000000, 0)
+	err = c.ReplaceDocument(context.Background(), "Domain-KB", "acme", "guide", []ingestChunk{
+		{ID: ingestChunkID("acme", "Domain-KB", "guide", 0), Text: "chunk", Source: "guide.md", DocumentID: "guide", CreatedAt: created, Namespace: "acme", Embedding: []float32{1, 0}},
+	})
+	if err != nil {
+		t.Fatalf("ReplaceDocument: %v", err)
+	}
+
+	const del = "POST /collections/pagi_domain_kb/points/delete?wait=true"
+	const put = "PUT /collections/pagi_domain_kb/points?wait=true"
+	if len(calls) != 2 || calls[0] != del || calls[1] != put {
+		t.Fatalf("calls = %v, want [%s %s]", calls, del, put)
+	}
+	b, _ := json.Marshal(bodies[del])
+	if want := `{"filter":{"must":[{"key":"document_id","match":{"value":"guide"}},{"key":"namespace","match":{"value":"acme"}}]}}`; string(b) != want {
+		t.Errorf("delete body = %s, want %s", b, want)
+	}
+	point := bodies[put]["points"].([]any)[0].(map[string]any)
+	payload := point["payload"].(map[string]any)
+	if payload["created_at"] != float64(1700000000) || payload["namespace"] != "acme" || payload["source"] != "guide.md" {
+		t.Errorf("point = %v", point)
+	}
+}
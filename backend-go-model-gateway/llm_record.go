@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"backend-go-model-gateway/internal/logger"
+	"backend-go-model-gateway/service"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// LLM_RECORD_MODE values.
+const (
+	recordOff            = "off"
+	recordRecord         = "record"
+	recordReplay         = "replay"
+	recordReplayOrRecord = "replay_or_record"
+)
+
+// llmRecorder records provider calls to LLM_RECORD_DIR and serves them back,
+// so planner behavior can be debugged offline and pinned in regression tests.
+// Each exchange is one JSON file named after the provider and a hash of the
+// request, which is what replay looks up: an identical request (same
+// provider, model, messages, parameters and tools) gets the recorded reply.
+// Requests are recorded after PII scrubbing, so the files hold placeholders.
+type llmRecorder struct {
+	mode string
+	dir  string
+}
+
+// llmRecording is one recorded exchange. Exactly one of Response and Error
+// is set.
+type llmRecording struct {
+	Key        string                         `json:"key"`
+	Provider   llmProvider                    `json:"provider"`
+	SessionID  string                         `json:"session_id,omitempty"`
+	RecordedAt time.Time                      `json:"recorded_at"`
+	Request    openai.ChatCompletionRequest   `json:"request"`
+	Response   *openai.ChatCompletionResponse `json:"response,omitempty"`
+	Error      *recordedError                 `json:"error,omitempty"`
+}
+
+// recordedError is a provider's error answer (an *openai.APIError).
+type recordedError struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+}
+
+// newLLMRecorder returns the recorder for mode, or nil when it is off.
+func newLLMRecorder(mode, dir string) (*llmRecorder, error) {
+	switch mode {
+	case "", recordOff:
+		return nil, nil
+	case recordRecord, recordReplayOrRecord:
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("LLM_RECORD_DIR: %w", err)
+		}
+	case recordReplay:
+		if _, err := os.Stat(dir); err != nil {
+			return nil, fmt.Errorf("LLM_RECORD_DIR: %w", err)
+		}
+	}
+	return &llmRecorder{mode: mode, dir: dir}, nil
+}
+
+// recordingKey identifies a request to a provider.
+func recordingKey(provider llmProvider, req openai.ChatCompletionRequest) (string, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(append([]byte(provider+"\n"), body...))
+	return hex.EncodeToString(sum[:12]), nil
+}
+
+func (r *llmRecorder) path(provider llmProvider, key string) string {
+	return filepath.Join(r.dir, fmt.Sprintf("%s-%s.json", provider, key))
+}
+
+// call serves req from a recording or from the provider, depending on the
+// mode. A replay without a recording fails with a 404 *openai.APIError,
+// which is neither retried nor failed over.
+func (r *llmRecorder) call(ctx context.Context, llm *llmRuntime, req openai.ChatCompletionRequest, next func() (openai.ChatCompletionResponse, error)) (openai.ChatCompletionResponse, error) {
+	key, err := recordingKey(llm.Provider, req)
+	if err != nil {
+		return openai.ChatCompletionResponse{}, err
+	}
+	lg := logger.NewContextLogger(ctx)
+	if r.mode == recordReplay || r.mode == recordReplayOrRecord {
+		rec, err := r.load(llm.Provider, key)
+		switch {
+		case err == nil:
+			lg.Info("llm_replayed", "provider", llm.Provider, "model", req.Model, "key", key)
+			if rec.Error != nil {
+				return openai.ChatCompletionResponse{}, &openai.APIError{HTTPStatusCode: rec.Error.Status, Message: rec.Error.Message}
+			}
+			return *rec.Response, nil
+		case !errors.Is(err, os.ErrNotExist):
+			return openai.ChatCompletionResponse{}, err
+		case r.mode == recordReplay:
+			lg.Warn("llm_replay_miss", "provider", llm.Provider, "model", req.Model, "key", key)
+			return openai.ChatCompletionResponse{}, &openai.APIError{
+				HTTPStatusCode: http.StatusNotFound,
+				Message:        fmt.Sprintf("no recording for this %s request (key %s) in LLM_RECORD_DIR", llm.Provider, key),
+			}
+		}
+	}
+
+	resp, err := next()
+	rec := llmRecording{Key: key, Provider: llm.Provider, SessionID: service.SessionIDFromIncomingGRPC(ctx), RecordedAt: time.Now().UTC(), Request: req}
+	var apiErr *openai.APIError
+	switch {
+	case err == nil:
+		rec.Response = &resp
+	case errors.As(err, &apiErr):
+		rec.Error = &recordedError{Status: apiErr.HTTPStatusCode, Message: apiErr.Message}
+	default:
+		// Timeouts and unreachable providers say nothing about the request.
+		return resp, err
+	}
+	if saveErr := r.save(rec); saveErr != nil {
+		lg.Warn("llm_record_failed", "provider", llm.Provider, "key", key, "error", saveErr)
+	} else {
+		lg.Info("llm_recorded", "provider", llm.Provider, "model", req.Model, "key", key)
+	}
+	return resp, err
+}
+
+func (r *llmRecorder) load(provider llmProvider, key string) (*llmRecording, error) {
+	raw, err := os.ReadFile(r.path(provider, key))
+	if err != nil {
+		return nil, err
+	}
+	var rec llmRecording
+	if err := json.Unmarshal(raw, &rec); err != nil {
+		return nil, fmt.Errorf("recording %s: %w", key, err)
+	}
+	if (rec.Response == nil) == (rec.Error == nil) {
+		return nil, fmt.Errorf("recording %s: want a response or an error", key)
+	}
+	return &rec, nil
+}
+
+// save writes rec through a temporary file, so replays never see half of
+// one. A repeated request replaces its recording.
+func (r *llmRecorder) save(rec llmRecording) error {
+	body, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(r.dir, ".recording-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(body, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), r.path(rec.Provider, rec.Key))
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	pb "backend-go-model-gateway/proto/proto"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestLLMRecorder_RecordThenReplay(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "recordings")
+	var calls int
+	llm := failoverProvider(t, "ollama", http.StatusOK, &calls)
+	recorder, err := newLLMRecorder(recordRecord, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &server{llm: llm, recorder: recorder, requestTimeout: time.Duration(defaultRequestTimeoutSec) * time.Second}
+	recorded, err := s.GetPlan(context.Background(), &pb.PlanRequest{Prompt: "plan the release"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "ollama-*.json"))
+	if calls != 1 || len(files) != 1 {
+		t.Fatalf("calls = %d, recordings = %v", calls, files)
+	}
+
+	// Replay never reaches the provider.
+	s.recorder, err = newLLMRecorder(recordReplay, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed, err := s.GetPlan(context.Background(), &pb.PlanRequest{Prompt: "plan the release"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 || replayed.GetPlan() != recorded.GetPlan() {
+		t.Fatalf("calls = %d, replayed plan %q, recorded %q", calls, replayed.GetPlan(), recorded.GetPlan())
+	}
+
+	// A request that was never recorded is a miss, not a provider call.
+	var apiErr *openai.APIError
+	if _, err := s.GetPlan(context.Background(), &pb.PlanRequest{Prompt: "something else"}); !errors.As(err, &apiErr) || apiErr.HTTPStatusCode != http.StatusNotFound || calls != 1 {
+		t.Fatalf("miss: err = %v, calls = %d", err, calls)
+	}
+
+	// replay_or_record fills the gap.
+	s.recorder, _ = newLLMRecorder(recordReplayOrRecord, dir)
+	if _, err := s.GetPlan(context.Background(), &pb.PlanRequest{Prompt: "something else"}); err != nil || calls != 2 {
+		t.Fatalf("replay_or_record: err = %v, calls = %d", err, calls)
+	}
+	if _, err := s.GetPlan(context.Background(), &pb.PlanRequest{Prompt: "something else"}); err != nil || calls != 2 {
+		t.Fatalf("replay_or_record second call: err = %v, calls = %d", err, calls)
+	}
+}
+
+func TestLLMRecorder_RecordsProviderErrors(t *testing.T) {
+	dir := t.TempDir()
+	var calls int
+	llm := failoverProvider(t, "openrouter", http.StatusBadRequest, &calls)
+	s := &server{llm: llm, requestTimeout: time.Duration(defaultRequestTimeoutSec) * time.Second}
+	s.recorder, _ = newLLMRecorder(recordRecord, dir)
+	if _, err := s.GetPlan(context.Background(), &pb.PlanRequest{Prompt: "bad request"}); err == nil {
+		t.Fatal("want the provider's 400")
+	}
+	s.recorder, _ = newLLMRecorder(recordReplay, dir)
+	var apiErr *openai.APIError
+	if _, err := s.GetPlan(context.Background(), &pb.PlanRequest{Prompt: "bad request"}); !errors.As(err, &apiErr) || apiErr.HTTPStatusCode != http.StatusBadRequest || calls != 1 {
+		t.Fatalf("replayed err = %v, calls = %d", err, calls)
+	}
+}
+
+func TestNewLLMRecorder(t *testing.T) {
+	if r, err := newLLMRecorder(recordOff, ""); r != nil || err != nil {
+		t.Fatalf("off = %v, %v", r, err)
+	}
+	if _, err := newLLMRecorder(recordReplay, filepath.Join(t.TempDir(), "missing")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("replay from a missing dir: %v", err)
+	}
+}
@@ -2,8 +2,6 @@ package main
 
 import (
 	"context"
-	"crypto/tls"
-	"crypto/x509"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -11,22 +9,37 @@ import (
 	"net"
 	"net/http"
 	"os"
-	"path/filepath"
-	"strconv"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"backend-go-model-gateway/internal/logger"
+	"backend-go-model-gateway/pkg/admin"
+	"backend-go-model-gateway/pkg/buildinfo"
+	"backend-go-model-gateway/pkg/chaos"
+	"backend-go-model-gateway/pkg/egress"
+	"backend-go-model-gateway/pkg/featureflags"
+	"backend-go-model-gateway/pkg/lifecycle"
+	"backend-go-model-gateway/pkg/mockprovider"
+	"backend-go-model-gateway/pkg/ragfilter"
+	"backend-go-model-gateway/pkg/secrets"
+	"backend-go-model-gateway/pkg/spiffe"
+	"backend-go-model-gateway/pkg/tlsreload"
+	"backend-go-model-gateway/pkg/tools"
 	pb "backend-go-model-gateway/proto/proto" // Reference generated code package
 	"backend-go-model-gateway/service"
 
+	"github.com/go-redis/redis/v8"
 	openai "github.com/sashabaranov/go-openai"
 	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
 	grpc_health_v1 "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 )
 
 //go:generate protoc --go_out=./proto --go_opt=paths=source_relative --go-grpc_out=./proto --go-grpc_opt=paths=source_relative proto/model.proto
@@ -35,7 +48,6 @@ import (
 const DEFAULT_GRPC_PORT = 50051
 const DEFAULT_HTTP_PORT = 8005
 const SERVICE_NAME = "backend-go-model-gateway"
-const VERSION = "1.0.0"
 
 const (
 	defaultProvider          = "openrouter"
@@ -47,9 +59,11 @@ const (
 // pooling and outbound request tracing for all LLM calls.
 //
 // NOTE: request-level timeouts should be enforced via context deadlines.
-var sharedHTTPClient = newSharedHTTPClient()
+var sharedHTTPClient = newSharedHTTPClient(nil)
 
-func newSharedHTTPClient() *http.Client {
+// newSharedHTTPClient builds the shared client; with an egress policy it only
+// connects to allowed destinations.
+func newSharedHTTPClient(policy *egress.Policy) *http.Client {
 	base := &http.Transport{
 		Proxy: http.ProxyFromEnvironment,
 		DialContext: (&net.Dialer{
@@ -65,7 +79,7 @@ func newSharedHTTPClient() *http.Client {
 	}
 
 	return &http.Client{
-		Transport: ClientTraceTransport(base),
+		Transport: ClientTraceTransport(policy.Transport(base)),
 	}
 }
 
@@ -74,15 +88,44 @@ type llmProvider string
 const (
 	providerOpenRouter llmProvider = "openrouter"
 	providerOllama     llmProvider = "ollama"
+	providerAnthropic  llmProvider = "anthropic"
 	// providerMock is a zero-dependency dev mode that returns deterministic JSON
 	// plans (and optionally tool calls) without contacting any external LLM.
 	providerMock llmProvider = "mock"
 )
 
+// flagRateLimitMockFallback controls whether an upstream 429 is answered with
+// the deterministic mock plan (default) or surfaced to the caller.
+const flagRateLimitMockFallback = "rate_limit_mock_fallback"
+
+func init() {
+	featureflags.Register(flagRateLimitMockFallback, true)
+}
+
 type llmRuntime struct {
 	Provider llmProvider
 	Model    string
-	Client   *openai.Client
+	Client   chatCompleter
+	// AllowedModels limits the models a GetPlan may prefer (empty: any).
+	AllowedModels []string
+	// ToolCalling is LLM_TOOL_CALLING: how GetPlan offers tools (see
+	// tool_calling.go). Empty behaves as "json".
+	ToolCalling string
+	// Reasoning is LLM_REASONING: whether GetPlan returns the reasoning
+	// trace it separates from the plan (see reasoning.go).
+	Reasoning string
+	// jsonTools are the models that rejected native tools.
+	jsonTools jsonToolModels
+	// creds caches the health check's credential probe.
+	creds credentialProbe
+	// models caches ListModels' health probes.
+	models modelProbes
+	// Fallbacks are LLM_PROVIDERS after the first, tried in order when this
+	// provider fails with a 429, a 5xx or a timeout (see failover.go).
+	Fallbacks []*llmRuntime
+	// Fixtures are the mock provider's scripted responses (MOCK_FIXTURES_DIR;
+	// nil-safe).
+	Fixtures *mockprovider.Fixtures
 }
 
 // noopRAGClient is a fallback RAG client used when the Memory Service is not
@@ -95,45 +138,24 @@ func (noopRAGClient) GetContext(_ context.Context, _ VectorQueryRequest) ([]Vect
 	return []VectorQueryMatch{}, nil
 }
 
-// --- Tool Definitions (for LLM tool-use prompting) ---
-type ToolDefinition struct {
-	Name        string               `json:"name"`
-	Description string               `json:"description"`
-	Parameters  map[string]ToolParam `json:"parameters"`
-}
-
-type ToolParam struct {
-	Type        string `json:"type"`
-	Description string `json:"description"`
-}
-
-var availableTools = []ToolDefinition{
-	{
-		Name:        "web_search",
-		Description: "Use this tool to find up-to-date information or external knowledge.",
-		Parameters: map[string]ToolParam{
-			"query": {Type: "string", Description: "The search query."},
-		},
-	},
+// chaosRAGClient injects PAGI_CHAOS faults at the RAG boundary.
+type chaosRAGClient struct {
+	next  RAGContextClient
+	chaos *chaos.Injector
 }
 
-func getEnv(key, fallback string) string {
-	if v := os.Getenv(key); v != "" {
-		return v
+func (c chaosRAGClient) GetContext(ctx context.Context, req VectorQueryRequest) ([]VectorQueryMatch, error) {
+	if err := c.chaos.Inject(ctx, chaos.RAG); err != nil {
+		return nil, err
 	}
-	return fallback
-}
-
-func getEnvInt(key string, fallback int) int {
-	v := os.Getenv(key)
-	if v == "" {
-		return fallback
+	matches, err := c.next.GetContext(ctx, req)
+	if err != nil {
+		return nil, err
 	}
-	i, err := strconv.Atoi(v)
-	if err != nil || i <= 0 {
-		return fallback
+	for i := range matches {
+		matches[i].Text, _ = c.chaos.Malform(chaos.RAG, matches[i].Text)
 	}
-	return i
+	return matches, nil
 }
 
 func normalizeOllamaBaseURL(base string) string {
@@ -145,123 +167,401 @@ func normalizeOllamaBaseURL(base string) string {
 	return base + "/v1"
 }
 
-func loadMTLSServerCreds() (credentials.TransportCredentials, bool, error) {
-	serverCertPath := os.Getenv("TLS_SERVER_CERT_PATH")
-	serverKeyPath := os.Getenv("TLS_SERVER_KEY_PATH")
-	caCertPath := os.Getenv("TLS_CA_CERT_PATH")
+// loadMTLSServerCreds builds server mTLS credentials. Each of TLS_SERVER_CERT,
+// TLS_SERVER_KEY and TLS_CA_CERT may be given as a *_PATH file or as a secret
+// reference (see pkg/secrets), e.g. TLS_SERVER_KEY=vault://pki/gateway#key.
+// Rotated material is picked up every TLS_RELOAD_SECONDS without a restart
+// (see pkg/tlsreload). With TLS_SOURCE=spiffe the identity comes from the
+// SPIFFE Workload API instead.
+func loadMTLSServerCreds(ctx context.Context, store *secrets.Store, source string) (credentials.TransportCredentials, bool, error) {
+	switch source = strings.ToLower(source); source {
+	case "pem":
+	case "spiffe":
+		return loadSPIFFEServerCreds(ctx)
+	default:
+		return nil, false, fmt.Errorf("unsupported TLS_SOURCE %q (supported: pem, spiffe)", source)
+	}
+
+	haveCert := store.PEMConfigured("TLS_SERVER_CERT")
+	haveKey := store.PEMConfigured("TLS_SERVER_KEY")
+	haveCA := store.PEMConfigured("TLS_CA_CERT")
 
 	// Allow non-TLS local dev unless explicitly configured.
-	if serverCertPath == "" && serverKeyPath == "" && caCertPath == "" {
+	if !haveCert && !haveKey && !haveCA {
 		return nil, false, nil
 	}
-	if serverCertPath == "" || serverKeyPath == "" || caCertPath == "" {
-		return nil, false, fmt.Errorf("mTLS misconfigured: TLS_SERVER_CERT_PATH, TLS_SERVER_KEY_PATH, TLS_CA_CERT_PATH must all be set")
+	if !haveCert || !haveKey || !haveCA {
+		return nil, false, fmt.Errorf("mTLS misconfigured: TLS_SERVER_CERT_PATH, TLS_SERVER_KEY_PATH, TLS_CA_CERT_PATH (or their secret equivalents) must all be set")
 	}
 
-	serverCert, err := tls.LoadX509KeyPair(serverCertPath, serverKeyPath)
+	reloader, err := tlsreload.New(ctx, tlsreload.Options{
+		Store:    store,
+		Cert:     "TLS_SERVER_CERT",
+		Key:      "TLS_SERVER_KEY",
+		CA:       "TLS_CA_CERT",
+		Interval: tlsreload.IntervalFromEnv(),
+		OnReload: func(e tlsreload.Event) {
+			if e.Err != nil {
+				log.Printf(
+					`{"timestamp":"%s","level":"warn","service":"%s","component":"tls","error":%q,"not_after":"%s","message":"rotated TLS material rejected; serving the current certificate"}`,
+					time.Now().Format(time.RFC3339Nano), SERVICE_NAME, e.Err.Error(), e.NotAfter.Format(time.RFC3339),
+				)
+				return
+			}
+			log.Printf(
+				`{"timestamp":"%s","level":"info","service":"%s","component":"tls","not_after":"%s","message":"TLS certificates reloaded"}`,
+				time.Now().Format(time.RFC3339Nano), SERVICE_NAME, e.NotAfter.Format(time.RFC3339),
+			)
+		},
+	})
 	if err != nil {
-		return nil, false, fmt.Errorf("load server keypair (%s, %s): %w", filepath.Clean(serverCertPath), filepath.Clean(serverKeyPath), err)
+		return nil, false, fmt.Errorf("load server mTLS material: %w", err)
 	}
+	return credentials.NewTLS(reloader.ServerConfig()), true, nil
+}
 
-	caPEM, err := os.ReadFile(caCertPath)
+// loadSPIFFEServerCreds builds server mTLS credentials from the X.509-SVID
+// served on SPIFFE_ENDPOINT_SOCKET. Clients must present an SVID from a
+// trusted domain whose ID SPIFFE_AUTHORIZED_IDS allows (default: any workload
+// in the gateway's own trust domain). The agent rotates the SVID in place.
+func loadSPIFFEServerCreds(ctx context.Context) (credentials.TransportCredentials, bool, error) {
+	opts, err := spiffe.OptionsFromEnv()
 	if err != nil {
-		return nil, false, fmt.Errorf("read CA cert (%s): %w", filepath.Clean(caCertPath), err)
+		return nil, false, err
+	}
+	opts.OnUpdate = func(e spiffe.Event) {
+		if e.Err != nil {
+			log.Printf(
+				`{"timestamp":"%s","level":"warn","service":"%s","component":"tls","spiffe_id":%q,"error":%q,"not_after":"%s","message":"SPIFFE Workload API stream failed; serving the current SVID"}`,
+				time.Now().Format(time.RFC3339Nano), SERVICE_NAME, e.ID, e.Err.Error(), e.NotAfter.Format(time.RFC3339),
+			)
+			return
+		}
+		log.Printf(
+			`{"timestamp":"%s","level":"info","service":"%s","component":"tls","spiffe_id":%q,"not_after":"%s","message":"SPIFFE SVID updated"}`,
+			time.Now().Format(time.RFC3339Nano), SERVICE_NAME, e.ID, e.NotAfter.Format(time.RFC3339),
+		)
 	}
-	caPool := x509.NewCertPool()
-	if ok := caPool.AppendCertsFromPEM(caPEM); !ok {
-		return nil, false, fmt.Errorf("append CA certs from PEM (%s): no certs parsed", filepath.Clean(caCertPath))
+	source, err := spiffe.NewSource(ctx, opts)
+	if err != nil {
+		return nil, false, fmt.Errorf("load server SPIFFE identity: %w", err)
 	}
+	return credentials.NewTLS(source.ServerConfig(spiffe.AuthorizerFromEnv(source))), true, nil
+}
 
-	conf := &tls.Config{
-		MinVersion:   tls.VersionTLS12,
-		Certificates: []tls.Certificate{serverCert},
-		ClientCAs:    caPool,
-		ClientAuth:   tls.RequireAndVerifyClientCert,
-		NextProtos:   []string{"h2"},
+// newLLMClient builds the runtime for LLM_PROVIDER or, when LLM_PROVIDERS is
+// set, for its first provider with the others as fallbacks.
+func newLLMClient(ctx context.Context, store *secrets.Store, cfg LLMConfig) (*llmRuntime, error) {
+	chain, err := cfg.chain()
+	if err != nil {
+		return nil, err
 	}
-
-	return credentials.NewTLS(conf), true, nil
+	primary, err := newLLMRuntime(ctx, store, cfg, chain[0])
+	if err != nil {
+		return nil, err
+	}
+	for _, provider := range chain[1:] {
+		fallback, err := newLLMRuntime(ctx, store, cfg, provider)
+		if err != nil {
+			return nil, fmt.Errorf("LLM_PROVIDERS %s: %w", provider, err)
+		}
+		primary.Fallbacks = append(primary.Fallbacks, fallback)
+	}
+	return primary, nil
 }
 
-func initializeLLMClient() (*llmRuntime, error) {
-	provider := llmProvider(strings.ToLower(getEnv("LLM_PROVIDER", defaultProvider)))
-
+// newLLMRuntime builds the client for one provider.
+func newLLMRuntime(ctx context.Context, store *secrets.Store, cfg LLMConfig, provider llmProvider) (*llmRuntime, error) {
 	// Zero-dependency local/dev mode.
 	if provider == providerMock {
-		return &llmRuntime{Provider: providerMock, Model: "mock", Client: nil}, nil
+		fixtures, err := loadMockFixtures(cfg.Mock.FixturesDir)
+		if err != nil {
+			return nil, err
+		}
+		return &llmRuntime{Provider: providerMock, Model: "mock", Client: nil, Fixtures: fixtures}, nil
 	}
 
 	// Shared OpenAI-compatible client setup (go-openai)
 	switch provider {
 	case providerOllama:
-		ollamaBase := normalizeOllamaBaseURL(getEnv("OLLAMA_BASE_URL", defaultOllamaBaseURL))
-		model := getEnv("OLLAMA_MODEL_NAME", "llama3")
-		cfg := openai.DefaultConfig("")
-		cfg.BaseURL = ollamaBase
-		cfg.HTTPClient = sharedHTTPClient
-		client := openai.NewClientWithConfig(cfg)
-		return &llmRuntime{Provider: providerOllama, Model: model, Client: client}, nil
+		clientCfg := openai.DefaultConfig("")
+		clientCfg.BaseURL = normalizeOllamaBaseURL(cfg.Ollama.BaseURL)
+		clientCfg.HTTPClient = sharedHTTPClient
+		client := openai.NewClientWithConfig(clientCfg)
+		return &llmRuntime{Provider: providerOllama, Model: cfg.Ollama.Model, Client: client, AllowedModels: cfg.AllowedModels, ToolCalling: cfg.ToolCalling, Reasoning: cfg.Reasoning}, nil
 
 	case providerOpenRouter, "":
-		apiKey := os.Getenv("OPENROUTER_API_KEY")
-		if apiKey == "" {
+		// Resolved through pkg/secrets so the key can live in Vault/AWS SM or a
+		// mounted file; fail fast here if it cannot be read at startup.
+		apiKey, err := store.Get(ctx, "OPENROUTER_API_KEY")
+		if errors.Is(err, secrets.ErrNotFound) || (err == nil && apiKey == "") {
 			return nil, fmt.Errorf("OPENROUTER_API_KEY is required when LLM_PROVIDER=openrouter")
+		} else if err != nil {
+			return nil, err
+		}
+		clientCfg := openai.DefaultConfig(apiKey)
+		clientCfg.BaseURL = "https://openrouter.ai/api/v1"
+		// Re-read the key per request (cached by the store) so rotation needs no restart.
+		clientCfg.HTTPClient = &http.Client{
+			Transport: &reasoningFieldTransport{
+				Base: &secrets.BearerTransport{Store: store, Name: "OPENROUTER_API_KEY", Base: sharedHTTPClient.Transport},
+			},
+		}
+		client := openai.NewClientWithConfig(clientCfg)
+		return &llmRuntime{Provider: providerOpenRouter, Model: cfg.OpenRouter.Model, Client: client, AllowedModels: cfg.AllowedModels, ToolCalling: cfg.ToolCalling, Reasoning: cfg.Reasoning}, nil
+
+	case providerAnthropic:
+		apiKey, err := store.Get(ctx, "ANTHROPIC_API_KEY")
+		if errors.Is(err, secrets.ErrNotFound) || (err == nil && apiKey == "") {
+			return nil, fmt.Errorf("ANTHROPIC_API_KEY is required when LLM_PROVIDER=anthropic")
+		} else if err != nil {
+			return nil, err
+		}
+		client := &anthropicClient{
+			baseURL:   cfg.Anthropic.BaseURL,
+			maxTokens: cfg.Anthropic.MaxTokens,
+			http: &http.Client{
+				Transport: &secrets.BearerTransport{Store: store, Name: "ANTHROPIC_API_KEY", Header: "x-api-key", Base: sharedHTTPClient.Transport},
+			},
 		}
-		model := getEnv("OPENROUTER_MODEL_NAME", "mistralai/mistral-7b-instruct:free")
-		cfg := openai.DefaultConfig(apiKey)
-		cfg.BaseURL = "https://openrouter.ai/api/v1"
-		cfg.HTTPClient = sharedHTTPClient
-		client := openai.NewClientWithConfig(cfg)
-		return &llmRuntime{Provider: providerOpenRouter, Model: model, Client: client}, nil
+		return &llmRuntime{Provider: providerAnthropic, Model: cfg.Anthropic.Model, Client: client, AllowedModels: cfg.AllowedModels, ToolCalling: cfg.ToolCalling, Reasoning: cfg.Reasoning}, nil
 
 	default:
-		return nil, fmt.Errorf("unsupported LLM_PROVIDER=%q (supported: openrouter, ollama, mock)", provider)
+		return nil, fmt.Errorf("unsupported LLM_PROVIDER=%q (supported: openrouter, ollama, anthropic, mock)", provider)
 	}
 }
 
 // --- gRPC Server Implementation ---
 type server struct {
 	pb.UnimplementedModelGatewayServer
+	// mu guards llm, pii, prompts and config, which POST
+	// /admin/reload-config replaces.
+	mu  sync.RWMutex
 	llm *llmRuntime
+	// config is the configuration llm was built from (nil in tests).
+	config *Config
 	// vectorDB provides Retrieval-Augmented Generation (RAG) context for prompts.
 	vectorDB RAGContextClient
+	// kbs lists the KBs GetPlan retrieves from (nil-safe: the defaults).
+	kbs *kbCatalog
+	// minScore drops matches scoring below it from the prompt (nil: keep all).
+	minScore *float64
+	// dedupSimilarity drops near-duplicate matches from the prompt (0: off).
+	dedupSimilarity float64
+	// ragInjection is RAG_INJECTION ("": quarantine; see rag_injection.go).
+	ragInjection string
+	// race is LLM_RACE: GetPlan asks the first two providers of the chain
+	// at once (see race.go).
+	race bool
+	// recorder records or replays provider calls (LLM_RECORD_MODE; nil:
+	// off).
+	recorder *llmRecorder
+	// retrievalFallback is LLM_RETRIEVAL_FALLBACK: answer with the retrieved
+	// matches when every provider is down (see retrieval_fallback.go).
+	retrievalFallback bool
 	// Per-request timeout for the LLM call.
 	requestTimeout time.Duration
+	// flags resolves feature flags (nil-safe: env/defaults only).
+	flags *featureflags.Provider
+	// chaos injects faults when PAGI_CHAOS is set (nil-safe: disabled).
+	chaos *chaos.Injector
+	// pii scrubs personal data from prompts sent to the PII_SCRUB providers
+	// (nil-safe: disabled).
+	pii *piiScrubber
+	// prompts are the GetPlan system prompt versions (nil-safe: v1 only).
+	prompts *systemPrompts
+	// queue bounds concurrent provider calls by priority class (nil-safe:
+	// unlimited).
+	queue *requestQueue
+	// rateLimit bounds each caller's request rate (nil-safe: unlimited).
+	rateLimit *rateLimiter
+	// retry retries transient provider failures (nil-safe: one attempt).
+	retry *retryPolicy
+	// planRepairs is how often a GetPlan reply failing its schema is sent
+	// back to the model (0: never).
+	planRepairs int
+	// maxTokensCap bounds a GetPlan request's max_tokens (0:
+	// defaultMaxTokensCap).
+	maxTokensCap int
+	// modelProbeInterval is how long a ListModels health probe is reused
+	// (0: models are not probed).
+	modelProbeInterval time.Duration
+	// vision loads image resources for vision models (nil-safe: images are
+	// ignored).
+	vision *visionFetcher
+	// moderation screens GetPlan prompts and plans (nil-safe: off).
+	moderation *moderation
+	// tools are the tools GetPlan offers, as listed by the sandbox (nil-safe:
+	// tools.Builtin).
+	tools *tools.Catalog
 }
 
-func buildMockPlanResponse(in *pb.PlanRequest, requestStart time.Time) *pb.PlanResponse {
-	// Zero-dependency mock provider: return deterministic strict JSON.
-	// This keeps the stack usable out-of-the-box without any API keys and also
-	// serves as a resilience fallback when upstream LLM providers rate-limit.
-	prompt := strings.TrimSpace(in.GetPrompt())
-	lower := strings.ToLower(prompt)
-
-	// Heuristic: if the user asks for “latest” / “search” / “web”, emit a tool call.
-	if strings.Contains(lower, "search") || strings.Contains(lower, "web") || strings.Contains(lower, "latest") {
-		payload := map[string]any{
-			"model_type": string(providerMock),
-			"prompt":     in.GetPrompt(),
-			"tool": map[string]any{
-				"name": "web_search",
-				"args": map[string]any{"query": prompt},
-			},
+// runtime returns the current LLM runtime and PII scrubber.
+func (s *server) runtime() (*llmRuntime, *piiScrubber) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.llm, s.pii
+}
+
+// systemPrompts returns the current GetPlan system prompt versions.
+func (s *server) systemPrompts() *systemPrompts {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.prompts
+}
+
+// reloadConfig rebuilds the LLM runtime, PII scrubber and system prompts from
+// the environment (POST /admin/reload-config). Requests in progress finish on
+// the old ones.
+func (s *server) reloadConfig(ctx context.Context, store *secrets.Store) (map[string]any, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	llm, err := newLLMClient(ctx, store, cfg.LLM)
+	if err != nil {
+		return nil, err
+	}
+	pii, err := newPIIScrubber(cfg.PII)
+	if err != nil {
+		return nil, err
+	}
+	prompts, err := newSystemPrompts(cfg.Prompts)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.llm, s.pii, s.prompts, s.config = llm, pii, prompts, cfg
+	s.mu.Unlock()
+	cfg.logEffective()
+	return s.adminStatus(ctx), nil
+}
+
+// adminStatus is the gateway's part of GET /admin/status.
+func (s *server) adminStatus(context.Context) map[string]any {
+	llm, pii := s.runtime()
+	out := map[string]any{"pii_scrub": pii != nil}
+	if llm != nil {
+		out["provider"], out["model"] = llm.Provider, llm.Model
+		if len(llm.Fallbacks) > 0 {
+			out["providers"] = llm.chainNames()
 		}
-		b, _ := json.Marshal(payload)
-		return &pb.PlanResponse{Plan: string(b), ModelName: "mock", LatencyMs: time.Since(requestStart).Milliseconds()}
+		if llm.Provider != providerMock {
+			out["tool_calling"], out["reasoning"] = llm.ToolCalling, llm.Reasoning
+		}
+		for _, r := range append([]*llmRuntime{llm}, llm.Fallbacks...) {
+			if r.Fixtures != nil {
+				out["mock_fixtures"] = r.Fixtures.Len()
+			}
+		}
+	}
+	if pii != nil {
+		out["pii_scrub_providers"] = pii.providers
+	}
+	if s.moderation != nil {
+		out["moderation"] = map[string]any{"classifier": s.moderation.name, "redact": s.moderation.redact, "fail_open": s.moderation.failOpen}
+	}
+	if s.queue != nil {
+		out["queue"] = s.queue.status()
 	}
+	if s.rateLimit != nil {
+		out["rate_limit"] = s.rateLimit.status()
+	}
+	if s.retry != nil {
+		out["retry"] = s.retry.status()
+	}
+	if s.retrievalFallback {
+		out["retrieval_fallback"] = true
+	}
+	if s.race {
+		out["race"] = true
+	}
+	if s.recorder != nil {
+		out["llm_record"] = map[string]any{"mode": s.recorder.mode, "dir": s.recorder.dir}
+	}
+	if s.tools != nil {
+		source, refreshed := s.tools.Source()
+		catalog := map[string]any{"source": source, "count": len(s.tools.Definitions())}
+		if !refreshed.IsZero() {
+			catalog["refreshed_at"] = refreshed.UTC().Format(time.RFC3339)
+		}
+		out["tools"] = catalog
+	}
+	if prompts := s.systemPrompts(); prompts != nil {
+		versions := make([]string, 0, len(prompts.versions))
+		for v := range prompts.versions {
+			versions = append(versions, v)
+		}
+		sort.Strings(versions)
+		out["prompt_version"], out["prompt_versions"] = prompts.fallback, versions
+	}
+	s.mu.RLock()
+	cfg := s.config
+	s.mu.RUnlock()
+	if cfg != nil {
+		out["config"] = cfg.effective()
+	}
+	return out
+}
 
-	steps := []string{
-		"Restate the objective in one sentence and identify constraints.",
-		"Propose a minimal 3-step plan with clear inputs/outputs.",
-		"Return the plan as strict JSON for downstream parsing.",
+// createChatCompletion calls the upstream provider, retrying 429s, 5xx and
+// connection errors under the LLM_RETRY_* policy.
+func (s *server) createChatCompletion(ctx context.Context, llm *llmRuntime, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	s.retry.called()
+	for attempt := 1; ; attempt++ {
+		resp, err := s.createChatCompletionOnce(ctx, llm, req)
+		if err == nil {
+			return resp, nil
+		}
+		delay, decision := s.retry.next(ctx, attempt, err)
+		lg := logger.NewContextLogger(ctx)
+		if decision != retryYes {
+			if attempt > 1 || decision == retryNoBudget {
+				lg.Warn("llm_retry_gave_up", "provider", llm.Provider, "attempts", attempt, "reason", decision, "error", err)
+			}
+			return resp, err
+		}
+		lg.Warn("llm_retry", "provider", llm.Provider, "attempt", attempt, "delay_ms", delay.Milliseconds(), "error", err)
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return resp, err
+		}
+	}
+}
+
+// createChatCompletionOnce makes one provider call, applying PAGI_CHAOS
+// faults at the provider boundary. Injected faults carrying a status are
+// surfaced as *openai.APIError so the regular 429/5xx handling is exercised.
+func (s *server) createChatCompletionOnce(ctx context.Context, llm *llmRuntime, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	if err := s.chaos.Inject(ctx, chaos.Provider); err != nil {
+		var fault *chaos.FaultError
+		if errors.As(err, &fault) && fault.Status > 0 {
+			return openai.ChatCompletionResponse{}, &openai.APIError{HTTPStatusCode: fault.Status, Message: fault.Error()}
+		}
+		return openai.ChatCompletionResponse{}, err
+	}
+
+	var resp openai.ChatCompletionResponse
+	var err error
+	if s.recorder != nil {
+		resp, err = s.recorder.call(ctx, llm, req, func() (openai.ChatCompletionResponse, error) {
+			return llm.Client.CreateChatCompletion(ctx, req)
+		})
+	} else {
+		resp, err = llm.Client.CreateChatCompletion(ctx, req)
+	}
+	if err == nil {
+		gatewayUsage.record(resp.Usage)
+	} else {
+		gatewayUsage.fail()
 	}
-	payload := map[string]any{
-		"model_type": string(providerMock),
-		"prompt":     in.GetPrompt(),
-		"steps":      steps,
+	if err == nil && len(resp.Choices) > 0 {
+		resp.Choices[0].Message.Content, _ = s.chaos.Malform(chaos.Provider, resp.Choices[0].Message.Content)
 	}
-	b, _ := json.Marshal(payload)
-	return &pb.PlanResponse{Plan: string(b), ModelName: "mock", LatencyMs: time.Since(requestStart).Milliseconds()}
+	return resp, err
 }
 
 // healthServer implements the standard gRPC Health Checking Protocol.
@@ -271,22 +571,56 @@ func buildMockPlanResponse(in *pb.PlanRequest, requestStart time.Time) *pb.PlanR
 type healthServer struct {
 	grpc_health_v1.UnimplementedHealthServer
 
-	llm       *llmRuntime
+	gateway   *server
 	ragClient *RAGGRPCClient
+	// ops reports NOT_SERVING while the gateway drains (nil-safe).
+	ops *admin.Server
+	// probeInterval is how often the provider key is verified (0: never).
+	probeInterval time.Duration
+}
+
+// healthWatchInterval is how often Watch re-checks the gateway's health.
+var healthWatchInterval = 5 * time.Second
+
+// Check reports the gateway's health. The empty service and the gateway's
+// own service name (modelgateway.ModelGateway) are checked alike; other
+// names are NotFound, as the protocol requires.
+func (h *healthServer) Check(ctx context.Context, in *grpc_health_v1.HealthCheckRequest) (*grpc_health_v1.HealthCheckResponse, error) {
+	if !knownHealthService(in.GetService()) {
+		return nil, status.Errorf(codes.NotFound, "unknown service %q", in.GetService())
+	}
+	return &grpc_health_v1.HealthCheckResponse{Status: h.status(ctx)}, nil
+}
+
+// knownHealthService reports whether Check and Watch answer for service.
+func knownHealthService(service string) bool {
+	return service == "" || service == pb.ModelGateway_ServiceDesc.ServiceName
 }
 
-func (h *healthServer) Check(ctx context.Context, _ *grpc_health_v1.HealthCheckRequest) (*grpc_health_v1.HealthCheckResponse, error) {
+// status runs the readiness checks.
+func (h *healthServer) status(ctx context.Context) grpc_health_v1.HealthCheckResponse_ServingStatus {
+	// A draining replica asks to be taken out of rotation.
+	if h.ops.Draining() {
+		return grpc_health_v1.HealthCheckResponse_NOT_SERVING
+	}
+
+	llm, _ := h.gateway.runtime()
 	// Mock mode is always "serving" (no downstream dependencies).
-	if h.llm != nil && h.llm.Provider == providerMock {
-		return &grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_SERVING}, nil
+	if llm != nil && llm.Provider == providerMock {
+		return grpc_health_v1.HealthCheckResponse_SERVING
 	}
 
 	// 1) LLM client must be initialized.
-	if h.llm == nil || h.llm.Client == nil {
-		return &grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_NOT_SERVING}, nil
+	if llm == nil || llm.Client == nil {
+		return grpc_health_v1.HealthCheckResponse_NOT_SERVING
 	}
 
-	// 2) Memory Service (RAG) should be reachable (best-effort).
+	// 2) The provider must accept the API key (cached probe).
+	if err := llm.credentialsRejected(ctx, h.probeInterval); err != nil {
+		return grpc_health_v1.HealthCheckResponse_NOT_SERVING
+	}
+
+	// 3) Memory Service (RAG) should be reachable (best-effort).
 	// If the memory service exports gRPC health, probe it.
 	if h.ragClient != nil && h.ragClient.conn != nil {
 		probeCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
@@ -294,33 +628,98 @@ func (h *healthServer) Check(ctx context.Context, _ *grpc_health_v1.HealthCheckR
 		hc := grpc_health_v1.NewHealthClient(h.ragClient.conn)
 		resp, err := hc.Check(probeCtx, &grpc_health_v1.HealthCheckRequest{Service: ""})
 		if err != nil || resp.GetStatus() != grpc_health_v1.HealthCheckResponse_SERVING {
-			return &grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_NOT_SERVING}, nil
+			return grpc_health_v1.HealthCheckResponse_NOT_SERVING
 		}
 	}
 
-	return &grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_SERVING}, nil
+	return grpc_health_v1.HealthCheckResponse_SERVING
 }
 
-func (h *healthServer) Watch(_ *grpc_health_v1.HealthCheckRequest, _ grpc_health_v1.Health_WatchServer) error {
-	return status.Error(codes.Unimplemented, "Watch is not implemented")
+// Watch sends the service's status, then each change of it, checking every
+// healthWatchInterval until the caller goes away. An unknown service is
+// reported as SERVICE_UNKNOWN rather than failing, so a watcher started
+// before a service is registered keeps watching.
+func (h *healthServer) Watch(in *grpc_health_v1.HealthCheckRequest, stream grpc_health_v1.Health_WatchServer) error {
+	ctx := stream.Context()
+	ticker := time.NewTicker(healthWatchInterval)
+	defer ticker.Stop()
+	last := grpc_health_v1.HealthCheckResponse_ServingStatus(-1)
+	for {
+		current := grpc_health_v1.HealthCheckResponse_SERVICE_UNKNOWN
+		if knownHealthService(in.GetService()) {
+			current = h.status(ctx)
+		}
+		if current != last {
+			if err := stream.Send(&grpc_health_v1.HealthCheckResponse{Status: current}); err != nil {
+				return err
+			}
+			last = current
+		}
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case <-ticker.C:
+		}
+	}
 }
 
-// GetPlan implements modelgateway.ModelGatewayServer.
+// GetPlan implements modelgateway.ModelGatewayServer. With moderation on, the
+// prompt and earlier turns are screened before planning and the plan before
+// it is returned.
 func (s *server) GetPlan(ctx context.Context, in *pb.PlanRequest) (*pb.PlanResponse, error) {
+	if s.moderation == nil {
+		return s.getPlan(ctx, in)
+	}
+	ctx = service.ContextWithTraceIDFromIncomingGRPC(ctx)
+	prompt, err := s.moderation.screen(ctx, moderationPrompt, in.GetPrompt())
+	if err != nil {
+		return nil, err
+	}
+	if prompt != in.GetPrompt() {
+		in = proto.Clone(in).(*pb.PlanRequest)
+		in.Prompt = prompt
+	}
+	for i, m := range in.GetMessages() {
+		content, err := s.moderation.screen(ctx, moderationPrompt, m.GetContent())
+		if err != nil {
+			return nil, err
+		}
+		if content != m.GetContent() {
+			in = proto.Clone(in).(*pb.PlanRequest)
+			in.Messages[i].Content = content
+		}
+	}
+	resp, err := s.getPlan(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Plan, err = s.moderation.screen(ctx, moderationPlan, resp.GetPlan()); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (s *server) getPlan(ctx context.Context, in *pb.PlanRequest) (*pb.PlanResponse, error) {
 	requestStart := time.Now()
 
 	ctx = service.ContextWithTraceIDFromIncomingGRPC(ctx)
+	sessionID := service.SessionIDFromIncomingGRPC(ctx)
 
-	// Bound the LLM call.
+	// Bound retrieval and the primary provider's call; each failover provider
+	// gets a fresh timeout.
 	callCtx, cancel := context.WithTimeout(ctx, s.requestTimeout)
 	defer cancel()
 
+	// One runtime serves the whole request, even if config is reloaded meanwhile.
+	llm, scrubber := s.runtime()
 	provider := "uninitialized"
 	model := "uninitialized"
-	if s.llm != nil {
-		provider = string(s.llm.Provider)
-		model = s.llm.Model
+	if llm != nil {
+		provider = string(llm.Provider)
+		model, _ = llm.planModel(in.GetModel())
 	}
+	prompts := s.systemPrompts()
+	promptVersion := prompts.version(in.GetPromptVersion())
 
 	lg := logger.NewContextLogger(callCtx)
 	resourceTypes := make([]string, 0, len(in.GetResources()))
@@ -330,41 +729,91 @@ func (s *server) GetPlan(ctx context.Context, in *pb.PlanRequest) (*pb.PlanRespo
 		}
 		resourceTypes = append(resourceTypes, r.GetType())
 	}
+	peerName := ""
+	if id, ok := peerIdentityFromContext(ctx); ok {
+		peerName = id.Name
+	}
 	lg.Info(
 		"GetPlan",
+		"session_id", sessionID,
+		"peer", peerName,
+		"principal", service.PrincipalFromIncomingGRPC(ctx),
 		"provider", provider,
 		"model", model,
+		"persona", in.GetPersona(),
+		"prompt_version", promptVersion,
+		"priority", requestPriority(in.GetPriority()),
 		"prompt", in.GetPrompt(),
+		"messages", len(in.GetMessages()),
 		"resource_count", len(in.GetResources()),
 		"resource_types", resourceTypes,
 	)
 
-	if s.llm == nil {
+	if llm == nil {
 		return nil, fmt.Errorf("LLM runtime not initialized")
 	}
+	if requested := in.GetPromptVersion(); requested != "" && requested != promptVersion {
+		lg.Warn("prompt_version_unknown", "requested", requested, "prompt_version", promptVersion)
+	}
+	turns, err := planTurns(in.GetMessages())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	attempt := planAttempt{in: in, prompts: prompts, promptVersion: promptVersion, scrubber: scrubber, turns: turns, start: requestStart}
 
 	// Zero-dependency mock provider: return deterministic strict JSON.
 	// This keeps docker-compose usable out-of-the-box without any API keys.
-	if s.llm.Provider == providerMock {
-		return buildMockPlanResponse(in, requestStart), nil
-	}
-
-	if s.llm.Client == nil {
-		return nil, fmt.Errorf("LLM client not initialized")
+	if llm.Provider == providerMock {
+		release, err := s.acquireProvider(callCtx, in.GetPriority())
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+		return s.planWith(callCtx, llm, attempt, true)
 	}
 
 	// --- RAG: Retrieve vector context (best-effort; do not fail the request) ---
 	// Default top-k for retrieval; the mock currently returns 2 deterministic items regardless.
 	const topK = 3
-	retrievalPreamble := ""
+	var matches []VectorQueryMatch
 	if s.vectorDB != nil {
 		retrievalStart := time.Now()
-		// Temporary stand-in for a future protobuf field: request all conceptual RAG KBs.
-		kbList := []string{"Domain-KB", "Body-KB", "Soul-KB"}
-		matches, err := s.vectorDB.GetContext(callCtx, VectorQueryRequest{QueryText: in.GetPrompt(), TopK: topK, KnowledgeBases: kbList})
+		// Request every catalogued KB (see the /api/v1/kbs management API).
+		kbList := s.kbs.Names()
+		var err error
+		matches, err = s.vectorDB.GetContext(callCtx, VectorQueryRequest{
+			QueryText:      in.GetPrompt(),
+			TopK:           topK,
+			KnowledgeBases: kbList,
+			Filter:         ragfilter.FromProto(in.GetRagFilter()),
+		})
 		if err != nil {
 			lg.Warn("vector_retrieval_failed", "error", err)
-		} else if len(matches) > 0 {
+		}
+		// Irrelevant snippets mislead the model more than no context at all:
+		// below-threshold matches are dropped, and with none left the prompt
+		// carries no <context> block and the response is flagged ungrounded.
+		if relevant := relevantMatches(matches, s.minScore); len(relevant) < len(matches) {
+			best := matches[0].Score
+			for _, m := range matches {
+				best = max(best, m.Score)
+			}
+			lg.Info("vector_retrieval_below_threshold", "match_count", len(matches), "kept", len(relevant), "best_score", best, "min_score", *s.minScore)
+			matches = relevant
+		}
+		// Multi-KB retrieval often returns the same passage from several KBs.
+		if unique := dedupeMatches(matches, s.dedupSimilarity); len(unique) < len(matches) {
+			lg.Info("vector_retrieval_deduplicated", "match_count", len(matches), "kept", len(unique))
+			matches = unique
+		}
+		// Documents that address the model instead of informing it are kept
+		// out of the prompt.
+		kept, flagged := screenMatches(matches, s.ragInjection)
+		for _, f := range flagged {
+			lg.Warn("rag_injection_detected", "session_id", sessionID, "kb", f.match.KnowledgeBase, "id", f.match.ID, "rules", f.rules, "quarantined", len(kept) < len(matches))
+		}
+		matches = kept
+		if len(matches) > 0 {
 			var contextBuilder strings.Builder
 			contextBuilder.WriteString("The following information is retrieved from the knowledge base:\n")
 			contextBuilder.WriteString("<context>\n")
@@ -374,165 +823,364 @@ func (s *server) GetPlan(ctx context.Context, in *pb.PlanRequest) (*pb.PlanRespo
 				contextBuilder.WriteString(fmt.Sprintf("ID: %s\nText: %s\n---\n", match.ID, match.Text))
 			}
 			contextBuilder.WriteString("</context>\n\n")
-			retrievalPreamble = contextBuilder.String()
+			attempt.retrievalPreamble = contextBuilder.String()
 
 			lg.Info("vector_retrieval_complete", "match_count", len(matches), "latency_ms", time.Since(retrievalStart).Milliseconds())
 		}
 	}
 
-	// --- Tool schema + strict output instructions ---
-	toolsBlob, _ := json.MarshalIndent(availableTools, "", "  ")
-	toolsSection := fmt.Sprintf("<available_tools>\n%s\n</available_tools>\n\n", string(toolsBlob))
-
-	// Prompt the model to return strict JSON so downstream can parse either a plan or a tool call.
-	system := "" +
-		"You are a planning assistant.\n" +
-		"Return STRICT JSON only (no markdown, no prose, no code fences).\n\n" +
-		"TOOL USE:\n" +
-		"- If a tool is necessary, return a STRICT JSON object containing the key 'tool'.\n" +
-		"- The 'tool' object MUST have keys: 'name' (string) and 'args' (object).\n" +
-		"- Example: {\"tool\":{\"name\":\"web_search\",\"args\":{\"query\":\"...\"}}}\n" +
-		"\n" +
-		"PLANNING (no tool needed):\n" +
-		"- Return a STRICT JSON object containing: 'steps' (array of strings).\n" +
-		"\n" +
-		toolsSection
-
-	user := retrievalPreamble + fmt.Sprintf("User prompt: %s", in.GetPrompt())
-
-	resp, err := s.llm.Client.CreateChatCompletion(
-		callCtx,
-		openai.ChatCompletionRequest{
-			Model: s.llm.Model,
-			Messages: []openai.ChatCompletionMessage{
-				{Role: openai.ChatMessageRoleSystem, Content: system},
-				{Role: openai.ChatMessageRoleUser, Content: user},
-			},
-			Temperature: 0.2,
-		},
-	)
+	// --- Provider chain: the primary, then LLM_PROVIDERS' failovers ---
+	chain, providerAllowed := llm.chain(in.GetProvider())
+	if !providerAllowed {
+		lg.Warn("preferred_provider_not_allowed", "persona", in.GetPersona(), "preferred", in.GetProvider(), "provider", provider)
+	}
+
+	// --- Image resources: fetched once, if any model in the chain sees them ---
+	for i, current := range chain {
+		m := current.Model
+		if i == 0 {
+			m, _ = current.planModel(in.GetModel())
+		}
+		if traitsFor(m).vision {
+			attempt.images = s.vision.images(callCtx, in.GetResources())
+			break
+		}
+	}
+
+	// --- Provider capacity: one slot covers the whole failover chain ---
+	release, err := s.acquireProvider(callCtx, in.GetPriority())
 	if err != nil {
+		return nil, err
+	}
+	defer release()
+	for i := 0; i < len(chain); i++ {
+		current := chain[i]
+		attemptCtx := callCtx
+		if i > 0 {
+			var cancelAttempt context.CancelFunc
+			attemptCtx, cancelAttempt = context.WithTimeout(ctx, s.requestTimeout)
+			defer cancelAttempt()
+		}
+		var resp *pb.PlanResponse
+		var err error
+		raced := i == 0 && s.race && len(chain) > 1
+		if raced {
+			// LLM_RACE: the first two providers at once; a failover
+			// continues with the third.
+			resp, current, err = s.racePlan(attemptCtx, chain[0], chain[1], attempt)
+			i = 1
+		} else {
+			resp, err = s.planWith(attemptCtx, current, attempt, i == 0)
+		}
+		if err == nil {
+			if i > 0 && !raced {
+				lg.Info("llm_failover_served", "provider", current.Provider, "model", resp.GetModelName(), "primary", chain[0].Provider)
+			}
+			return resp, nil
+		}
+		if i+1 < len(chain) && ctx.Err() == nil && failoverWorthy(err) {
+			lg.Warn("llm_failover", "provider", current.Provider, "next", chain[i+1].Provider, "error", err)
+			continue
+		}
 		// Resilience: if OpenRouter is rate-limited upstream (429), fall back to the
 		// deterministic mock response so the system remains usable.
-		if s.llm.Provider == providerOpenRouter {
+		if current.Provider == providerOpenRouter && s.flags.Enabled(ctx, flagRateLimitMockFallback, sessionID) {
 			var apiErr *openai.APIError
 			if errors.As(err, &apiErr) && apiErr.HTTPStatusCode == http.StatusTooManyRequests {
-				lg.Warn("llm_rate_limited_falling_back_to_mock", "provider", provider, "model", model, "error", err)
-				return buildMockPlanResponse(in, requestStart), nil
+				lg.Warn("llm_rate_limited_falling_back_to_mock", "provider", current.Provider, "model", current.Model, "error", err)
+				resp := mockprovider.BuildPlanResponse(in, requestStart)
+				resp.PromptVersion = promptVersion
+				resp.Provider = string(providerMock)
+				return resp, nil
 			}
 		}
+		// Degraded mode: with every provider down, what was retrieved is
+		// still worth showing.
+		if s.retrievalFallback && len(matches) > 0 && ctx.Err() == nil && failoverWorthy(err) {
+			lg.Warn("llm_unavailable_retrieval_only", "provider", current.Provider, "match_count", len(matches), "error", err)
+			return retrievalOnlyPlan(in, matches, promptVersion, requestStart), nil
+		}
 		return nil, err
 	}
+	return nil, fmt.Errorf("LLM runtime not initialized")
+}
 
-	content := ""
-	if len(resp.Choices) > 0 {
-		content = resp.Choices[0].Message.Content
-	}
+// planAttempt is what every provider in the failover chain plans from.
+type planAttempt struct {
+	in                *pb.PlanRequest
+	prompts           *systemPrompts
+	promptVersion     string
+	scrubber          *piiScrubber
+	retrievalPreamble string
+	// turns are the request's earlier turns, sent after the user prompt.
+	turns []openai.ChatCompletionMessage
+	// images are the request's image resources as data URLs, for models
+	// with vision (see vision.go).
+	images []string
+	start  time.Time
+	// racing reports a reply that still fails the plan schema after repair
+	// as an *invalidPlanError, so the other racer can win (see race.go).
+	racing bool
+}
 
-	trimmed := strings.TrimSpace(content)
+// planWith asks one provider for the plan. The request's preferred model only
+// applies to the first provider tried; a failover uses its configured model.
+func (s *server) planWith(ctx context.Context, llm *llmRuntime, a planAttempt, first bool) (*pb.PlanResponse, error) {
+	in := a.in
+	lg := logger.NewContextLogger(ctx)
+	provider := string(llm.Provider)
+	model, modelAllowed := llm.Model, true
+	if first {
+		model, modelAllowed = llm.planModel(in.GetModel())
+	}
+	if !modelAllowed {
+		lg.Warn("preferred_model_not_allowed", "persona", in.GetPersona(), "preferred", in.GetModel(), "model", model)
+	}
 
-	// Normalize common LLM output formats into strict JSON:
-	// - raw JSON object
-	// - fenced code block containing JSON
-	// - non-JSON text (fallback wrapper)
-	stripFences := func(s string) string {
-		s = strings.TrimSpace(s)
-		if !strings.HasPrefix(s, "```") {
-			return s
-		}
-		// Drop the first fence line
-		if idx := strings.Index(s, "\n"); idx >= 0 {
-			s = s[idx+1:]
+	if llm.Provider == providerMock {
+		if err := s.chaos.Inject(ctx, chaos.Provider); err != nil {
+			return nil, err
 		}
-		// Drop the trailing fence
-		if end := strings.LastIndex(s, "```"); end >= 0 {
-			s = s[:end]
+		resp, err := mockPlan(ctx, llm, in, a.start)
+		if err != nil {
+			return nil, err
 		}
-		return strings.TrimSpace(s)
+		resp.Plan, _ = s.chaos.Malform(chaos.Provider, resp.Plan)
+		resp.PromptVersion = a.promptVersion
+		resp.Provider = provider
+		return resp, nil
 	}
 
-	normalizeJSON := func(raw string) (string, bool) {
-		candidate := strings.TrimSpace(raw)
-		if !strings.HasPrefix(candidate, "{") {
-			return "", false
-		}
+	if llm.Client == nil {
+		return nil, fmt.Errorf("LLM client not initialized")
+	}
 
-		var obj map[string]any
-		if err := json.Unmarshal([]byte(candidate), &obj); err != nil {
-			return "", false
+	user := a.retrievalPreamble + fmt.Sprintf("User prompt: %s", in.GetPrompt())
+	var images []string
+	if len(a.images) > 0 {
+		if traitsFor(model).vision {
+			images = a.images
+			lg.Info("vision_resources_attached", "provider", provider, "model", model, "images", len(images))
+		} else {
+			lg.Warn("vision_resources_ignored", "provider", provider, "model", model, "images", len(a.images))
 		}
+	}
 
-		// Tool-call path: pass through (but ensure tracing fields exist).
-		if toolObj, ok := obj["tool"].(map[string]any); ok {
-			name, _ := toolObj["name"].(string)
-			if strings.TrimSpace(name) == "" {
-				return "", false
-			}
-			if _, ok := toolObj["args"]; !ok {
-				toolObj["args"] = map[string]any{}
-			}
-			if _, ok := obj["model_type"]; !ok {
-				obj["model_type"] = provider
-			}
-			if _, ok := obj["prompt"]; !ok {
-				obj["prompt"] = in.GetPrompt()
-			}
-			b, _ := json.Marshal(obj)
-			return string(b), true
-		}
+	// Personal data must not reach the provider: it sees placeholders, and the
+	// plan it returns gets the values back.
+	turns := a.turns
+	var pii *piiSession
+	if a.scrubber.appliesTo(llm.Provider) {
+		pii = a.scrubber.session()
+		user = pii.scrub(user)
+		turns = scrubMessages(pii, turns)
+		pii.logScrubbed(ctx, "GetPlan", llm.Provider, model)
+	}
 
-		// Planning path: require a non-empty steps array.
-		stepsAny, ok := obj["steps"].([]any)
-		if !ok || len(stepsAny) == 0 {
-			return "", false
+	// --- Tool schema + strict output instructions ---
+	// A persona may narrow the tools; with none left none are offered.
+	// Natively offered tools go in the request instead of the prompt.
+	tools := offeredTools(s.tools.Definitions(), in.GetAllowedTools())
+	native := len(tools) > 0 && llm.nativeTools(model)
+	gen, clamped := planGeneration(in, s.maxTokensCap)
+	if len(clamped) > 0 {
+		lg.Warn("generation_params_clamped", "provider", provider, "model", model, "params", clamped)
+	}
+	planRequest := func(native bool) (openai.ChatCompletionRequest, error) {
+		system, err := a.prompts.plan(a.promptVersion, provider, in.GetSystemPrompt(), tools, native)
+		if err != nil {
+			return openai.ChatCompletionRequest{}, status.Error(codes.Internal, err.Error())
 		}
-		steps := make([]string, 0, len(stepsAny))
-		for _, v := range stepsAny {
-			if s, ok := v.(string); ok && strings.TrimSpace(s) != "" {
-				steps = append(steps, s)
-			}
+		if pii != nil && pii.scrubbed() {
+			system += piiPlaceholderNote
 		}
-		if len(steps) == 0 {
-			return "", false
+		req := openai.ChatCompletionRequest{
+			Model: model,
+			Messages: append([]openai.ChatCompletionMessage{
+				{Role: openai.ChatMessageRoleSystem, Content: system},
+				withImages(openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, Content: user}, images),
+			}, turns...),
 		}
-		payload := map[string]any{
-			"model_type": provider,
-			"steps":      steps,
-			"prompt":     in.GetPrompt(),
+		gen.apply(&req)
+		if native {
+			req.Tools = openAITools(tools)
 		}
-		b, _ := json.Marshal(payload)
-		return string(b), true
+		return req, nil
 	}
 
-	// 1) Try raw JSON
-	if normalized, ok := normalizeJSON(trimmed); ok {
-		trimmed = normalized
+	req, err := planRequest(native)
+	if err != nil {
+		return nil, err
+	}
+	var resp openai.ChatCompletionResponse
+	// StreamPlan callers get the reply as it is generated. Scrubbed replies
+	// carry placeholders and native tool calls no text, so those arrive whole,
+	// as do recorded and replayed ones.
+	if streamer, ok := llm.Client.(chatStreamer); ok && !native && pii == nil && s.recorder == nil && planDeltasFromContext(ctx) != nil {
+		resp, err = s.streamChatCompletion(ctx, llm, streamer, req, planDeltasFromContext(ctx))
 	} else {
-		// 2) Try fenced JSON
-		fenced := stripFences(trimmed)
-		if normalized, ok := normalizeJSON(fenced); ok {
-			trimmed = normalized
-		} else {
-			// 3) Fallback wrapper
-			fallback := map[string]any{
-				"model_type": provider,
-				"steps":      []string{trimmed},
-				"prompt":     in.GetPrompt(),
+		resp, err = s.createChatCompletion(ctx, llm, req)
+	}
+	if native && toolsUnsupported(err) {
+		// The model lacks function calling: describe the tools in the prompt
+		// and parse the reply, now and for the rest of the runtime.
+		lg.Warn("native_tools_unsupported", "provider", provider, "model", model, "error", err)
+		llm.jsonTools.add(model)
+		native = false
+		if req, err = planRequest(false); err != nil {
+			return nil, err
+		}
+		resp, err = s.createChatCompletion(ctx, llm, req)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	reply := func(resp openai.ChatCompletionResponse) (content, reasoning string) {
+		var msg openai.ChatCompletionMessage
+		if len(resp.Choices) > 0 {
+			msg = resp.Choices[0].Message
+		}
+		// Reasoning models think out loud before the plan, inline or in a
+		// separate field; only the plan is parsed.
+		content, reasoning = splitReasoning(msg.Content)
+		reasoning = joinReasoning(msg.ReasoningContent, reasoning)
+		if reasoning != "" {
+			lg.Info("plan_reasoning_separated", "provider", provider, "model", model, "reasoning_chars", len(reasoning))
+		}
+		if call, ok := toolCallPlan(msg); ok && native {
+			content = call
+		} else if native && len(msg.ToolCalls) > 0 {
+			lg.Warn("native_tool_call_malformed", "provider", provider, "model", model, "tool", msg.ToolCalls[0].Function.Name)
+		}
+		return content, reasoning
+	}
+	content, reasoning := reply(resp)
+	usage := resp.Usage
+
+	// A reply that fails its schema goes back to the model with the problems,
+	// up to LLM_PLAN_REPAIR_ATTEMPTS times; after that it is wrapped as a
+	// single step.
+	valid := true
+	for repair := 1; ; repair++ {
+		problems := planSchemaProblems(content)
+		valid = len(problems) == 0
+		if valid {
+			if repair > 1 {
+				lg.Info("plan_schema_repaired", "provider", provider, "model", model, "repairs", repair-1)
 			}
-			b, _ := json.Marshal(fallback)
-			trimmed = string(b)
+			break
+		}
+		if repair > s.planRepairs {
+			lg.Warn("plan_schema_invalid", "provider", provider, "model", model, "repairs", repair-1, "problems", problems)
+			break
 		}
+		lg.Warn("plan_schema_repair", "provider", provider, "model", model, "attempt", repair, "problems", problems)
+		req.Messages = append(req.Messages,
+			openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: content},
+			openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, Content: planRepairPrompt(problems)},
+		)
+		repaired, err := s.createChatCompletion(ctx, llm, req)
+		if err != nil {
+			lg.Warn("plan_schema_repair_failed", "provider", provider, "model", model, "attempt", repair, "error", err)
+			break
+		}
+		content, reasoning = reply(repaired)
+		usage.PromptTokens += repaired.Usage.PromptTokens
+		usage.CompletionTokens += repaired.Usage.CompletionTokens
+	}
+
+	trimmed := normalizePlanOutput(content, provider, in.GetPrompt())
+	if pii != nil {
+		trimmed = pii.restore(trimmed)
+		reasoning = pii.restore(reasoning)
+	}
+	if llm.Reasoning != reasoningReturn {
+		reasoning = ""
+	}
+
+	latencyMs := time.Since(a.start).Milliseconds()
+	plan := &pb.PlanResponse{
+		Plan:             trimmed,
+		ModelName:        model,
+		LatencyMs:        latencyMs,
+		Ungrounded:       a.retrievalPreamble == "",
+		PromptVersion:    a.promptVersion,
+		Reasoning:        reasoning,
+		Provider:         provider,
+		PromptTokens:     int64(usage.PromptTokens),
+		CompletionTokens: int64(usage.CompletionTokens),
+	}
+	if a.racing && !valid {
+		return nil, &invalidPlanError{resp: plan}
+	}
+	return plan, nil
+}
+
+// GetRAGContext serves the gateway's RAG backend over the memory service's
+// RPC, so planners can retrieve through the gateway (MEMORY_GRPC_ADDR pointed
+// at it) and get RAG_TENANCY enforcement. Distance is reported as 1 - score.
+func (s *server) GetRAGContext(ctx context.Context, in *pb.RAGContextRequest) (*pb.RAGContextResponse, error) {
+	ctx = service.ContextWithTraceIDFromIncomingGRPC(ctx)
+	if s.vectorDB == nil {
+		return nil, status.Error(codes.Unavailable, "RAG backend not initialized")
+	}
+
+	matches, err := s.vectorDB.GetContext(ctx, VectorQueryRequest{
+		QueryText:      in.GetQuery(),
+		TopK:           int(in.GetTopK()),
+		KnowledgeBases: in.GetKnowledgeBases(),
+		Filter:         ragfilter.FromProto(in.GetFilter()),
+	})
+	switch {
+	case errors.Is(err, errTenantRequired):
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	case errors.Is(err, errInvalidTenant):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case err != nil:
+		return nil, status.Error(codes.Unavailable, err.Error())
 	}
 
-	latencyMs := time.Since(requestStart).Milliseconds()
-	return &pb.PlanResponse{
-		Plan:      trimmed,
-		ModelName: s.llm.Model,
-		LatencyMs: latencyMs,
-	}, nil
+	resp := &pb.RAGContextResponse{Matches: make([]*pb.RAGMatch, 0, len(matches))}
+	for _, m := range matches {
+		resp.Matches = append(resp.Matches, &pb.RAGMatch{
+			Id:            m.ID,
+			Text:          m.Text,
+			Distance:      1 - m.Score,
+			KnowledgeBase: m.KnowledgeBase,
+			Source:        m.Source,
+		})
+	}
+	return resp, nil
 }
 
 func main() {
+	// PAGI_CONFIG_FILE overrides the environment; POST /admin/reload-config
+	// re-applies it.
+	if path := admin.OptionsFromEnv().ConfigFile; path != "" {
+		if _, err := admin.LoadEnvFile(path); err != nil {
+			log.Fatalf(
+				`{"timestamp": "%s", "level": "fatal", "service": "%s", "error": %q}`,
+				time.Now().Format(time.RFC3339Nano), SERVICE_NAME, "PAGI_CONFIG_FILE: "+err.Error(),
+			)
+		}
+	}
+
+	// Every server and background worker below runs in this group; SIGINT or
+	// SIGTERM, or any of them exiting, shuts the rest down.
+	group, ctx := lifecycle.New(context.Background(), lifecycle.Options{
+		OnExit: func(component string, err error) {
+			level, msg := "info", ""
+			if err != nil && !errors.Is(err, context.Canceled) {
+				level, msg = "error", err.Error()
+			}
+			log.Printf(
+				`{"timestamp":"%s","level":"%s","service":"%s","component":%q,"error":%q,"message":"component stopped."}`,
+				time.Now().Format(time.RFC3339Nano), level, SERVICE_NAME, component, msg,
+			)
+		},
+	})
+
 	// --- OpenTelemetry tracing (best-effort) ---
 	if tp, err := InitTracer(context.Background()); err != nil {
 		log.Printf(
@@ -543,49 +1191,114 @@ func main() {
 		defer func() { _ = tp.Shutdown(context.Background()) }()
 	}
 
-	// Parse port from environment or flag
-	grpcPortEnv := os.Getenv("MODEL_GATEWAY_GRPC_PORT")
-	port, err := strconv.Atoi(grpcPortEnv)
-	if err != nil || port == 0 {
-		port = DEFAULT_GRPC_PORT
+	// GATEWAY_CONFIG_FILE and the environment; every effective setting is
+	// logged so instances can be compared.
+	cfg, err := loadConfig()
+	if err != nil {
+		log.Fatalf(
+			`{"timestamp": "%s", "level": "fatal", "service": "%s", "error": %q}`,
+			time.Now().Format(time.RFC3339Nano), SERVICE_NAME, err.Error(),
+		)
 	}
+	cfg.logEffective()
+	port := cfg.GRPCPort
 
-	// Initialize Vector DB (RAG) client.
-	//
-	// In bare-metal dev mode the Memory Service may not be ready when the Model
-	// Gateway starts. Don't fail fast here; fall back to a no-op RAG client so the
-	// gateway can still serve mock LLM responses and become healthy.
-	var ragClient *RAGGRPCClient
-	var vectorClient RAGContextClient = noopRAGClient{}
-
-	rigCtx, cancelRAGDial := context.WithTimeout(context.Background(), 2*time.Second)
-	defer cancelRAGDial()
-	if rc, err := NewRAGGRPCClient(rigCtx); err != nil {
+	// Outbound allowlist (PAGI_EGRESS_ALLOW). It covers the shared LLM client
+	// and, through http.DefaultTransport, the vector stores, reranker, Vault
+	// and Consul clients.
+	egressPolicy, err := egress.FromEnv()
+	if err != nil {
+		log.Fatalf(
+			`{"timestamp": "%s", "level": "fatal", "service": "%s", "error": %q}`,
+			time.Now().Format(time.RFC3339Nano), SERVICE_NAME, err.Error(),
+		)
+	}
+	if egressPolicy != nil {
+		sharedHTTPClient = newSharedHTTPClient(egressPolicy)
+		http.DefaultTransport = egressPolicy.Transport(http.DefaultTransport.(*http.Transport))
 		log.Printf(
-			`{"timestamp":"%s","level":"warn","service":"%s","component":"RAGGRPCClient","error":%q,"message":"failed to connect to memory service for RAG; starting with noop RAG client"}`,
+			`{"timestamp":"%s","level":"info","service":"%s","component":"egress","allow":%q,"message":"egress allowlist enabled"}`,
+			time.Now().Format(time.RFC3339Nano), SERVICE_NAME, os.Getenv("PAGI_EGRESS_ALLOW"),
+		)
+	}
+
+	secretStore := secrets.FromEnv()
+
+	// Initialize the RAG backend (RAG_BACKEND: memory service or a direct vector store).
+	rag, err := initRAGBackend(context.Background(), secretStore, cfg)
+	if err != nil {
+		log.Fatalf(
+			`{"timestamp": "%s", "level": "fatal", "service": "%s", "error": %q}`,
 			time.Now().Format(time.RFC3339Nano), SERVICE_NAME, err.Error(),
 		)
-	} else {
-		ragClient = rc
-		vectorClient = rc
-		defer func() { _ = rc.Close() }()
 	}
+	defer rag.Close()
+	vectorClient := rag.client
+	log.Printf(
+		`{"timestamp":"%s","level":"info","service":"%s","component":"rag","rag_backend":%q,"message":"RAG backend initialized"}`,
+		time.Now().Format(time.RFC3339Nano), SERVICE_NAME, rag.name,
+	)
 
-	// Temporary HTTP endpoint for independent testing of vector retrieval.
-	httpPort := getEnvInt("MODEL_GATEWAY_HTTP_PORT", DEFAULT_HTTP_PORT)
-	go func() {
-		srv := &http.Server{Addr: fmt.Sprintf(":%d", httpPort), Handler: NewHTTPMux(vectorClient)}
+	// Chaos / fault-injection mode (PAGI_CHAOS) for resilience testing.
+	chaosInjector, err := chaos.FromEnv()
+	if err != nil {
+		log.Fatalf(
+			`{"timestamp": "%s", "level": "fatal", "service": "%s", "error": %q}`,
+			time.Now().Format(time.RFC3339Nano), SERVICE_NAME, err.Error(),
+		)
+	}
+	if chaosInjector.Enabled() {
+		rules, _ := json.Marshal(chaosInjector.Describe())
 		log.Printf(
-			`{"timestamp":"%s","level":"info","service":"%s","version":"%s","port":%d,"message":"HTTP server listening (temporary vector-test endpoint)."}`,
-			time.Now().Format(time.RFC3339Nano), SERVICE_NAME, VERSION, httpPort,
+			`{"timestamp":"%s","level":"warn","service":"%s","component":"chaos","rules":%s,"message":"PAGI_CHAOS enabled; faults will be injected at provider/RAG boundaries"}`,
+			time.Now().Format(time.RFC3339Nano), SERVICE_NAME, rules,
 		)
-		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			log.Printf(
-				`{"timestamp":"%s","level":"error","service":"%s","error":"http server failed: %v"}`,
-				time.Now().Format(time.RFC3339Nano), SERVICE_NAME, err,
-			)
-		}
-	}()
+		vectorClient = chaosRAGClient{next: vectorClient, chaos: chaosInjector}
+	}
+
+	kbs, err := newKBCatalog(cfg.RAG)
+	if err != nil {
+		log.Fatalf(
+			`{"timestamp": "%s", "level": "fatal", "service": "%s", "error": %q}`,
+			time.Now().Format(time.RFC3339Nano), SERVICE_NAME, err.Error(),
+		)
+	}
+	prices, err := usagePrices(cfg.LLM)
+	if err != nil {
+		log.Fatalf(
+			`{"timestamp": "%s", "level": "fatal", "service": "%s", "error": %q}`,
+			time.Now().Format(time.RFC3339Nano), SERVICE_NAME, err.Error(),
+		)
+	}
+	gatewayUsage.setPrices(prices)
+	minScore, err := ragMinScore(cfg.RAG)
+	if err != nil {
+		log.Fatalf(
+			`{"timestamp": "%s", "level": "fatal", "service": "%s", "error": %q}`,
+			time.Now().Format(time.RFC3339Nano), SERVICE_NAME, err.Error(),
+		)
+	}
+	ragInjection, err := ragInjectionMode(cfg.RAG)
+	if err != nil {
+		log.Fatalf(
+			`{"timestamp": "%s", "level": "fatal", "service": "%s", "error": %q}`,
+			time.Now().Format(time.RFC3339Nano), SERVICE_NAME, err.Error(),
+		)
+	}
+	recorder, err := newLLMRecorder(cfg.LLM.Record.Mode, cfg.LLM.Record.Dir)
+	if err != nil {
+		log.Fatalf(
+			`{"timestamp": "%s", "level": "fatal", "service": "%s", "error": %q}`,
+			time.Now().Format(time.RFC3339Nano), SERVICE_NAME, err.Error(),
+		)
+	}
+	ingest, err := newIngestService(rag, kbs, cfg.Ingest)
+	if err != nil {
+		log.Fatalf(
+			`{"timestamp": "%s", "level": "fatal", "service": "%s", "error": %q}`,
+			time.Now().Format(time.RFC3339Nano), SERVICE_NAME, err.Error(),
+		)
+	}
 
 	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
 	if err != nil {
@@ -595,7 +1308,14 @@ func main() {
 		)
 	}
 
-	llm, err := initializeLLMClient()
+	llm, err := newLLMClient(context.Background(), secretStore, cfg.LLM)
+	if err != nil {
+		log.Fatalf(
+			`{"timestamp": "%s", "level": "fatal", "service": "%s", "error": %q}`,
+			time.Now().Format(time.RFC3339Nano), SERVICE_NAME, err.Error(),
+		)
+	}
+	prompts, err := newSystemPrompts(cfg.Prompts)
 	if err != nil {
 		log.Fatalf(
 			`{"timestamp": "%s", "level": "fatal", "service": "%s", "error": %q}`,
@@ -603,20 +1323,136 @@ func main() {
 		)
 	}
 
-	timeoutSec := getEnvInt("REQUEST_TIMEOUT_SECONDS", defaultRequestTimeoutSec)
+	// Feature flags: env/file always, Redis only when REDIS_ADDR is configured.
+	flagOpts := featureflags.OptionsFromEnv()
+	if redisAddr := cfg.RedisAddr; redisAddr != "" {
+		redisOpts := &redis.Options{Addr: redisAddr}
+		secretStore.ConfigureRedis(redisOpts)
+		rdb := redis.NewClient(redisOpts)
+		pingCtx, cancelPing := context.WithTimeout(context.Background(), 2*time.Second)
+		if err := rdb.Ping(pingCtx).Err(); err != nil {
+			log.Printf(
+				`{"timestamp":"%s","level":"warn","service":"%s","component":"featureflags","redis_addr":%q,"error":%q,"message":"redis unavailable; feature flags will use env/file sources only"}`,
+				time.Now().Format(time.RFC3339Nano), SERVICE_NAME, redisAddr, err.Error(),
+			)
+			_ = rdb.Close()
+		} else {
+			flagOpts.Redis = rdb
+			defer func() { _ = rdb.Close() }()
+		}
+		cancelPing()
+	}
+	flags, err := featureflags.New(flagOpts)
+	if err != nil {
+		log.Fatalf(
+			`{"timestamp": "%s", "level": "fatal", "service": "%s", "error": %q}`,
+			time.Now().Format(time.RFC3339Nano), SERVICE_NAME, err.Error(),
+		)
+	}
+
+	pii, err := newPIIScrubber(cfg.PII)
+	if err != nil {
+		log.Fatalf(
+			`{"timestamp": "%s", "level": "fatal", "service": "%s", "error": %q}`,
+			time.Now().Format(time.RFC3339Nano), SERVICE_NAME, err.Error(),
+		)
+	}
+	if pii != nil {
+		log.Printf(
+			`{"timestamp":"%s","level":"info","service":"%s","component":"pii","providers":%q,"pattern_count":%d,"dictionary":%t,"message":"PII scrubbing enabled for provider prompts."}`,
+			time.Now().Format(time.RFC3339Nano), SERVICE_NAME, fmt.Sprint(pii.providers), len(pii.patterns), pii.terms != nil,
+		)
+	}
+
+	peers, err := cfg.Peers.policy()
+	if err != nil {
+		log.Fatalf(
+			`{"timestamp": "%s", "level": "fatal", "service": "%s", "error": %q}`,
+			time.Now().Format(time.RFC3339Nano), SERVICE_NAME, err.Error(),
+		)
+	}
+	rateLimit, err := rateLimiterFromConfig(cfg.RateLimit)
+	if err != nil {
+		log.Fatalf(
+			`{"timestamp": "%s", "level": "fatal", "service": "%s", "error": %q}`,
+			time.Now().Format(time.RFC3339Nano), SERVICE_NAME, err.Error(),
+		)
+	}
+	vision, err := newVisionFetcher(cfg.Vision)
+	if err != nil {
+		log.Fatalf(
+			`{"timestamp": "%s", "level": "fatal", "service": "%s", "error": %q}`,
+			time.Now().Format(time.RFC3339Nano), SERVICE_NAME, err.Error(),
+		)
+	}
+	moderation, err := newModeration(ctx, secretStore, cfg.Moderation)
+	if err != nil {
+		log.Fatalf(
+			`{"timestamp": "%s", "level": "fatal", "service": "%s", "error": %q}`,
+			time.Now().Format(time.RFC3339Nano), SERVICE_NAME, err.Error(),
+		)
+	}
+	toolCatalog, toolDiscoveryInterval, closeToolDiscovery, err := newToolCatalog(cfg.Tools)
+	if err != nil {
+		log.Fatalf(
+			`{"timestamp": "%s", "level": "fatal", "service": "%s", "error": %q}`,
+			time.Now().Format(time.RFC3339Nano), SERVICE_NAME, err.Error(),
+		)
+	}
+	defer closeToolDiscovery()
+	if toolCatalog != nil {
+		group.Go("tool_discovery", func(ctx context.Context) error {
+			toolCatalog.Run(ctx, toolDiscoveryInterval, logToolRefresh(toolCatalog))
+			<-ctx.Done() // Run returns after one refresh when the interval is 0.
+			return ctx.Err()
+		})
+	}
+	gw := &server{llm: llm, vectorDB: vectorClient, kbs: kbs, minScore: minScore, dedupSimilarity: cfg.RAG.DedupSimilarity, ragInjection: ragInjection, retrievalFallback: cfg.LLM.RetrievalFallback, requestTimeout: time.Duration(cfg.RequestTimeoutSeconds) * time.Second, flags: flags, chaos: chaosInjector, pii: pii, prompts: prompts, queue: requestQueueFromConfig(cfg.LLM.Queue), rateLimit: rateLimit, retry: retryPolicyFromConfig(cfg.LLM.Retry), planRepairs: cfg.LLM.PlanRepairAttempts, maxTokensCap: cfg.LLM.MaxTokensCap, modelProbeInterval: time.Duration(cfg.LLM.ModelProbeIntervalSeconds) * time.Second, vision: vision, moderation: moderation, tools: toolCatalog, config: cfg, recorder: recorder, race: cfg.LLM.Race}
+	// Edited prompt templates are picked up without a restart or reload.
+	go gw.watchSystemPrompts(ctx, time.Duration(cfg.Prompts.ReloadSeconds)*time.Second)
+
+	// Operator API (/admin/status, /admin/drain, /admin/reload-config) on the
+	// HTTP port, behind GATEWAY_ADMIN_API_KEY.
+	adminOpts := admin.OptionsFromEnv()
+	adminOpts.Service, adminOpts.Version = SERVICE_NAME, buildinfo.Version
+	adminOpts.Store, adminOpts.KeyName = secretStore, "GATEWAY_ADMIN_API_KEY"
+	adminOpts.Status = gw.adminStatus
+	adminOpts.Reload = func(ctx context.Context) (map[string]any, error) {
+		cfg, err := gw.reloadConfig(ctx, secretStore)
+		if err == nil {
+			log.Printf(
+				`{"timestamp":"%s","level":"info","service":"%s","component":"admin","provider":%q,"model":%q,"message":"configuration reloaded."}`,
+				time.Now().Format(time.RFC3339Nano), SERVICE_NAME, fmt.Sprint(cfg["provider"]), fmt.Sprint(cfg["model"]),
+			)
+		}
+		return cfg, err
+	}
+	adminOpts.OnDrain = func(draining bool) {
+		log.Printf(
+			`{"timestamp":"%s","level":"info","service":"%s","component":"admin","draining":%t,"message":"drain state changed."}`,
+			time.Now().Format(time.RFC3339Nano), SERVICE_NAME, draining,
+		)
+	}
+	ops := admin.New(adminOpts)
 
-	serverOpts := []grpc.ServerOption{grpc.StatsHandler(otelgrpc.NewServerHandler())}
-	if creds, enabled, err := loadMTLSServerCreds(); err != nil {
+	serverOpts := []grpc.ServerOption{grpc.StatsHandler(otelgrpc.NewServerHandler()), grpc.ChainUnaryInterceptor(ops.UnaryServerInterceptor()), grpc.ChainStreamInterceptor(ops.StreamServerInterceptor())}
+	creds, mtls, err := loadMTLSServerCreds(ctx, secretStore, cfg.TLSSource)
+	if err != nil {
 		log.Fatalf(
 			`{"timestamp": "%s", "level": "fatal", "service": "%s", "error": %q}`,
 			time.Now().Format(time.RFC3339Nano), SERVICE_NAME, err.Error(),
 		)
-	} else if enabled {
-		serverOpts = append(serverOpts, grpc.Creds(creds))
+	} else if mtls {
+		serverOpts = append(serverOpts, grpc.Creds(creds), grpc.ChainUnaryInterceptor(peerAuthUnaryInterceptor(peers)), grpc.ChainStreamInterceptor(peerAuthStreamInterceptor(peers)))
 		log.Printf(
 			`{"timestamp": "%s", "level": "info", "service": "%s", "message": "mTLS enabled for gRPC server."}`,
 			time.Now().Format(time.RFC3339Nano), SERVICE_NAME,
 		)
+	} else if peers != nil {
+		log.Fatalf(
+			`{"timestamp": "%s", "level": "fatal", "service": "%s", "error": "MTLS_ALLOWED_PEERS* is set but mTLS is not enabled; peer identities cannot be checked"}`,
+			time.Now().Format(time.RFC3339Nano), SERVICE_NAME,
+		)
 	} else {
 		log.Printf(
 			`{"timestamp": "%s", "level": "warn", "service": "%s", "message": "mTLS NOT enabled for gRPC server (TLS_* env vars not set); running insecure."}`,
@@ -624,19 +1460,58 @@ func main() {
 		)
 	}
 
+	// After peer authorization, so mTLS callers are limited by identity.
+	if rateLimit != nil {
+		serverOpts = append(serverOpts, grpc.ChainUnaryInterceptor(rateLimit.unaryInterceptor()), grpc.ChainStreamInterceptor(rateLimit.streamInterceptor()))
+	}
 	s := grpc.NewServer(serverOpts...)
-	grpc_health_v1.RegisterHealthServer(s, &healthServer{llm: llm, ragClient: ragClient})
-	pb.RegisterModelGatewayServer(s, &server{llm: llm, vectorDB: vectorClient, requestTimeout: time.Duration(timeoutSec) * time.Second})
+	probeInterval := time.Duration(cfg.LLM.HealthProbeIntervalSeconds) * time.Second
+	grpc_health_v1.RegisterHealthServer(s, &healthServer{gateway: gw, ragClient: rag.memory, ops: ops, probeInterval: probeInterval})
+	pb.RegisterModelGatewayServer(s, gw)
+	// Reflection lets grpcurl and similar tools call the gateway without
+	// proto files.
+	if cfg.GRPCReflection {
+		reflection.Register(s)
+	}
 
+	// HTTP endpoints: ingestion, KB management, retrieval debugging, admin.
+	httpPort := cfg.HTTPPort
+	mux := NewHTTPMux(vectorClient, adminRoutes{store: secretStore, ingest: ingest, kbs: newKBService(kbs, rag), debug: newRetrievalDebugService(rag, kbs, minScore, cfg.RAG.DedupSimilarity, ragInjection), ops: ops})
+	mux.Handle("/version", buildinfo.Handler(SERVICE_NAME, gw.features))
+	handler := ops.Track(mux)
+	if cfg.REST {
+		if err := checkRESTAuth(ctx, secretStore, mtls || peers != nil); err != nil {
+			log.Fatalf(
+				`{"timestamp": "%s", "level": "fatal", "service": "%s", "error": %q}`,
+				time.Now().Format(time.RFC3339Nano), SERVICE_NAME, err.Error(),
+			)
+		}
+		// The RPCs are counted in flight by the interceptor, as on gRPC.
+		rest := http.NewServeMux()
+		rest.Handle("/", handler)
+		rest.Handle("/v1/", requireKey(secretStore, "GATEWAY_REST_API_KEY", newRESTGateway(gw, ops.UnaryServerInterceptor(), rateLimit.unaryInterceptor())))
+		handler = rest
+	}
+	group.HTTPServer("http", &http.Server{Addr: fmt.Sprintf(":%d", httpPort), Handler: handler})
+	log.Printf(
+		`{"timestamp":"%s","level":"info","service":"%s","version":"%s","port":%d,"message":"HTTP server listening (temporary vector-test endpoint)."}`,
+		time.Now().Format(time.RFC3339Nano), SERVICE_NAME, buildinfo.Version, httpPort,
+	)
+
+	group.GRPCServer("grpc", s, lis)
 	log.Printf(
 		`{"timestamp": "%s", "level": "info", "service": "%s", "version": "%s", "port": %d, "provider": %q, "model": %q, "message": "gRPC server listening."}`,
-		time.Now().Format(time.RFC3339Nano), SERVICE_NAME, VERSION, port, llm.Provider, llm.Model,
+		time.Now().Format(time.RFC3339Nano), SERVICE_NAME, buildinfo.Version, port, llm.Provider, llm.Model,
 	)
 
-	if err := s.Serve(lis); err != nil {
+	if err := group.Wait(); err != nil {
 		log.Fatalf(
-			`{"timestamp": "%s", "level": "fatal", "service": "%s", "error": "failed to serve: %v"}`,
-			time.Now().Format(time.RFC3339Nano), SERVICE_NAME, err,
+			`{"timestamp": "%s", "level": "fatal", "service": "%s", "error": %q}`,
+			time.Now().Format(time.RFC3339Nano), SERVICE_NAME, err.Error(),
 		)
 	}
+	log.Printf(
+		`{"timestamp":"%s","level":"info","service":"%s","reason":%q,"message":"shutdown complete."}`,
+		time.Now().Format(time.RFC3339Nano), SERVICE_NAME, group.Cause().Error(),
+	)
 }
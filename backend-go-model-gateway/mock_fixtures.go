@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"backend-go-model-gateway/internal/logger"
+	"backend-go-model-gateway/pkg/mockprovider"
+	pb "backend-go-model-gateway/proto/proto"
+	"backend-go-model-gateway/service"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// loadMockFixtures loads MOCK_FIXTURES_DIR for the mock provider (nil when
+// unset: the built-in heuristics only).
+func loadMockFixtures(dir string) (*mockprovider.Fixtures, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	fixtures, err := mockprovider.LoadFixtures(dir)
+	if err != nil {
+		return nil, fmt.Errorf("MOCK_FIXTURES_DIR: %w", err)
+	}
+	return fixtures, nil
+}
+
+// mockPlan answers a GetPlan under the mock provider: from the fixtures when
+// one matches, otherwise with the built-in plan.
+func mockPlan(ctx context.Context, llm *llmRuntime, in *pb.PlanRequest, requestStart time.Time) (*pb.PlanResponse, error) {
+	resp, fixture, turn, err := llm.Fixtures.Plan(in, service.SessionIDFromIncomingGRPC(ctx), requestStart)
+	if fixture == "" {
+		return mockprovider.BuildPlanResponse(in, requestStart), nil
+	}
+	return resp, mockFixtureServed(ctx, "GetPlan", fixture, turn, err)
+}
+
+// mockChat is mockPlan for Chat.
+func mockChat(ctx context.Context, llm *llmRuntime, in *pb.ChatRequest, requestStart time.Time) (*pb.ChatResponse, error) {
+	resp, fixture, turn, err := llm.Fixtures.Chat(in, service.SessionIDFromIncomingGRPC(ctx), requestStart)
+	if fixture == "" {
+		return mockprovider.Chat(in, requestStart), nil
+	}
+	return resp, mockFixtureServed(ctx, "Chat", fixture, turn, err)
+}
+
+// mockFixtureServed logs a fixture's answer. A scripted failure becomes the
+// *openai.APIError a real provider returns, so retries, failover and the
+// mock fallback see it as one.
+func mockFixtureServed(ctx context.Context, rpc, fixture string, turn int, err error) error {
+	lg := logger.NewContextLogger(ctx)
+	var scripted *mockprovider.Error
+	if errors.As(err, &scripted) {
+		lg.Info("mock_fixture_served", "rpc", rpc, "fixture", fixture, "turn", turn, "status", scripted.Status)
+		return &openai.APIError{HTTPStatusCode: scripted.Status, Message: scripted.Message}
+	}
+	lg.Info("mock_fixture_served", "rpc", rpc, "fixture", fixture, "turn", turn)
+	return err
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	pb "backend-go-model-gateway/proto/proto"
+
+	"github.com/sashabaranov/go-openai"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestMockFixtures_GetPlanAndChat(t *testing.T) {
+	dir := t.TempDir()
+	fixture := `[
+		{"match": "outage", "responses": [{"error": {"status": 503, "message": "down"}}]},
+		{"match": "deploy", "responses": [{"steps": ["scripted step"]}]},
+		{"rpc": "chat", "responses": [{"content": "scripted reply"}]}
+	]`
+	if err := os.WriteFile(filepath.Join(dir, "script.json"), []byte(fixture), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	configEnv(t, "LLM_PROVIDER", "LLM_PROVIDERS", "MOCK_FIXTURES_DIR")
+	t.Setenv("LLM_PROVIDER", "mock")
+	t.Setenv("MOCK_FIXTURES_DIR", dir)
+	llm, err := newLLMClient(context.Background(), nil, envConfig(t).LLM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &server{llm: llm, requestTimeout: time.Duration(defaultRequestTimeoutSec) * time.Second}
+	if got := s.adminStatus(context.Background())["mock_fixtures"]; got != 3 {
+		t.Fatalf("mock_fixtures = %v", got)
+	}
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-session-id", "s1"))
+	resp, err := s.GetPlan(ctx, &pb.PlanRequest{Prompt: "deploy the app"})
+	if err != nil || resp.GetPlan() != `{"model_type":"mock","prompt":"deploy the app","steps":["scripted step"]}` || resp.GetProvider() != "mock" {
+		t.Fatalf("GetPlan = %v, %v", resp, err)
+	}
+	// Used up: the built-in plan.
+	if resp, _ := s.GetPlan(ctx, &pb.PlanRequest{Prompt: "deploy the app"}); resp.GetPlan() == `{"model_type":"mock","prompt":"deploy the app","steps":["scripted step"]}` {
+		t.Fatal("fixture answered twice")
+	}
+
+	var apiErr *openai.APIError
+	if _, err := s.GetPlan(ctx, &pb.PlanRequest{Prompt: "outage"}); !errors.As(err, &apiErr) || apiErr.HTTPStatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("scripted outage err = %v", err)
+	}
+
+	chat, err := s.Chat(ctx, &pb.ChatRequest{Messages: []*pb.ChatMessage{{Role: "user", Content: "hi"}}})
+	if err != nil || chat.GetContent() != "scripted reply" {
+		t.Fatalf("Chat = %v, %v", chat, err)
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	pb "backend-go-model-gateway/proto/proto"
+
+	"github.com/sashabaranov/go-openai"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultModelProbeIntervalSec = 60
+	modelProbeTimeout            = 5 * time.Second
+)
+
+// modelTraits are what a model family accepts. Models are matched by the
+// longest prefix of their name without the vendor ("openai/") or tag (":8b").
+type modelTraits struct {
+	vision        bool
+	contextWindow int32
+}
+
+var knownModelTraits = map[string]modelTraits{
+	"gpt-4o":           {vision: true, contextWindow: 128000},
+	"gpt-4-turbo":      {vision: true, contextWindow: 128000},
+	"gpt-4.1":          {vision: true, contextWindow: 1047576},
+	"gpt-3.5-turbo":    {contextWindow: 16385},
+	"claude-3-5-haiku": {contextWindow: 200000},
+	"claude-3.5-haiku": {contextWindow: 200000},
+	"claude-":          {vision: true, contextWindow: 200000},
+	"gemini":           {vision: true, contextWindow: 1048576},
+	"mistral-7b":       {contextWindow: 32768},
+	"llama3":           {contextWindow: 8192},
+	"llama3.1":         {contextWindow: 131072},
+	"llama3.2":         {contextWindow: 131072},
+	"llama3.3":         {contextWindow: 131072},
+	"llava":            {vision: true, contextWindow: 4096},
+	"qwen2.5":          {contextWindow: 32768},
+}
+
+// traitsFor looks a model up in knownModelTraits; unknown models get zero
+// traits.
+func traitsFor(model string) modelTraits {
+	name := strings.ToLower(model)
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	if i := strings.Index(name, ":"); i >= 0 {
+		name = name[:i]
+	}
+	best := ""
+	for prefix := range knownModelTraits {
+		if strings.HasPrefix(name, prefix) && len(prefix) > len(best) {
+			best = prefix
+		}
+	}
+	return knownModelTraits[best]
+}
+
+// modelProbes caches the last health probe per model. Like credentialProbe it
+// lives on the llmRuntime, so a reload probes again.
+type modelProbes struct {
+	mu     sync.Mutex
+	probes map[string]*modelProbe
+}
+
+type modelProbe struct {
+	mu     sync.Mutex
+	health *pb.ModelHealth
+}
+
+func (m *modelProbes) get(model string) *modelProbe {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.probes == nil {
+		m.probes = map[string]*modelProbe{}
+	}
+	if m.probes[model] == nil {
+		m.probes[model] = &modelProbe{}
+	}
+	return m.probes[model]
+}
+
+// modelHealth returns the model's last probe, probing first with a 1-token
+// completion when it is older than interval. Concurrent callers share one
+// probe.
+func (r *llmRuntime) modelHealth(ctx context.Context, model string, interval time.Duration) *pb.ModelHealth {
+	if interval <= 0 {
+		return &pb.ModelHealth{Status: "unknown"}
+	}
+	if r.Provider == providerMock {
+		return &pb.ModelHealth{Status: "ok", CheckedAtUnix: time.Now().Unix()}
+	}
+	p := r.models.get(model)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.health != nil && time.Since(time.Unix(p.health.GetCheckedAtUnix(), 0)) < interval {
+		return p.health
+	}
+
+	h := &pb.ModelHealth{Status: "ok"}
+	start := time.Now()
+	if r.Client == nil {
+		h.Status, h.Error = "error", "LLM client not initialized"
+	} else {
+		probeCtx, cancel := context.WithTimeout(ctx, modelProbeTimeout)
+		_, err := r.Client.CreateChatCompletion(probeCtx, openai.ChatCompletionRequest{
+			Model:     model,
+			Messages:  []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: "ping"}},
+			MaxTokens: 1,
+		})
+		cancel()
+		if err != nil {
+			h.Status, h.Error = "error", err.Error()
+		}
+	}
+	h.LatencyMs = time.Since(start).Milliseconds()
+	h.CheckedAtUnix = time.Now().Unix()
+	p.health = h
+	return h
+}
+
+// ListModels lists the models GetPlan can route to: each provider's
+// configured model and its LLM_ALLOWED_MODELS, in failover order, with their
+// traits and a health probe at most every LLM_MODEL_PROBE_INTERVAL_SECONDS.
+// Models are probed concurrently.
+func (s *server) ListModels(ctx context.Context, _ *pb.ListModelsRequest) (*pb.ListModelsResponse, error) {
+	llm, _ := s.runtime()
+	if llm == nil {
+		return nil, status.Error(codes.Unavailable, "LLM runtime not initialized")
+	}
+	chain, _ := llm.chain("")
+	resp := &pb.ListModelsResponse{}
+	var runtimes []*llmRuntime
+	for _, r := range chain {
+		models := []string{r.Model}
+		if r.Provider != providerMock {
+			for _, m := range r.AllowedModels {
+				if !slices.Contains(models, m) {
+					models = append(models, m)
+				}
+			}
+		}
+		for _, m := range models {
+			traits := traitsFor(m)
+			resp.Models = append(resp.Models, &pb.ModelInfo{
+				Provider:      string(r.Provider),
+				Model:         m,
+				Primary:       m == r.Model,
+				NativeTools:   r.nativeTools(m),
+				Vision:        traits.vision,
+				ContextWindow: traits.contextWindow,
+			})
+			runtimes = append(runtimes, r)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i, info := range resp.Models {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			info.Health = runtimes[i].modelHealth(ctx, info.GetModel(), s.modelProbeInterval)
+		}()
+	}
+	wg.Wait()
+	return resp, nil
+}
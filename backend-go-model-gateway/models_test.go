@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pb "backend-go-model-gateway/proto/proto"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestListModels(t *testing.T) {
+	var calls atomic.Int32
+	llm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		var req openai.ChatCompletionRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		w.Header().Set("Content-Type", "application/json")
+		if req.Model == "vendor/retired:free" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":{"message":"model not found","code":404}}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(openai.ChatCompletionResponse{Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Content: "p"}}}})
+	}))
+	defer llm.Close()
+	cfg := openai.DefaultConfig("key")
+	cfg.BaseURL = llm.URL
+	s := &server{
+		llm: &llmRuntime{
+			Provider:      providerOpenRouter,
+			Model:         "openai/gpt-4o",
+			Client:        openai.NewClientWithConfig(cfg),
+			AllowedModels: []string{"openai/gpt-4o", "vendor/retired:free"},
+			ToolCalling:   toolCallingNative,
+			Fallbacks:     []*llmRuntime{{Provider: providerMock, Model: "mock"}},
+		},
+		modelProbeInterval: time.Minute,
+	}
+
+	resp, err := s.ListModels(context.Background(), &pb.ListModelsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	models := resp.GetModels()
+	if len(models) != 3 {
+		t.Fatalf("models = %v", models)
+	}
+	gpt, retired, mock := models[0], models[1], models[2]
+	if gpt.GetModel() != "openai/gpt-4o" || !gpt.GetPrimary() || !gpt.GetNativeTools() || !gpt.GetVision() || gpt.GetContextWindow() != 128000 || gpt.GetHealth().GetStatus() != "ok" {
+		t.Fatalf("gpt-4o = %v", gpt)
+	}
+	if retired.GetPrimary() || retired.GetVision() || retired.GetContextWindow() != 0 || retired.GetHealth().GetStatus() != "error" || retired.GetHealth().GetError() == "" {
+		t.Fatalf("retired model = %v", retired)
+	}
+	if mock.GetProvider() != "mock" || mock.GetNativeTools() || mock.GetHealth().GetStatus() != "ok" {
+		t.Fatalf("mock = %v", mock)
+	}
+	if calls.Load() != 2 {
+		t.Fatalf("%d probes, want one per non-mock model", calls.Load())
+	}
+
+	// Probes are reused within the interval.
+	if _, err := s.ListModels(context.Background(), &pb.ListModelsRequest{}); err != nil || calls.Load() != 2 {
+		t.Fatalf("second list probed again (%d probes, %v)", calls.Load(), err)
+	}
+
+	s.modelProbeInterval = 0
+	resp, _ = s.ListModels(context.Background(), &pb.ListModelsRequest{})
+	if got := resp.GetModels()[0].GetHealth().GetStatus(); got != "unknown" {
+		t.Fatalf("status with probing off = %q", got)
+	}
+}
+
+func TestTraitsFor(t *testing.T) {
+	for model, want := range map[string]modelTraits{
+		"openai/gpt-4o-mini":                 {vision: true, contextWindow: 128000},
+		"claude-3-5-haiku-latest":            {contextWindow: 200000},
+		"anthropic/claude-sonnet-4":          {vision: true, contextWindow: 200000},
+		"llama3.1:8b":                        {contextWindow: 131072},
+		"llama3":                             {contextWindow: 8192},
+		"mistralai/mistral-7b-instruct:free": {contextWindow: 32768},
+		"some-new-model":                     {},
+	} {
+		if got := traitsFor(model); got != want {
+			t.Errorf("traitsFor(%q) = %+v, want %+v", model, got, want)
+		}
+	}
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"backend-go-model-gateway/internal/logger"
+	"backend-go-model-gateway/pkg/secrets"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Moderation stages and the ErrorInfo reasons of their rejections.
+const (
+	moderationPrompt = "prompt"
+	moderationPlan   = "plan"
+
+	reasonPromptFlagged = "PROMPT_FLAGGED"
+	reasonPlanFlagged   = "PLAN_FLAGGED"
+	// moderationErrorDomain is the ErrorInfo domain of moderation errors.
+	moderationErrorDomain = "model-gateway.pagi"
+
+	defaultModerationBaseURL = "https://api.openai.com/v1"
+	defaultModerationModel   = "omni-moderation-latest"
+)
+
+// classifier decides whether a text is unsafe.
+type classifier interface {
+	classify(ctx context.Context, text string) (moderationResult, error)
+}
+
+// moderationResult lists the categories a text was flagged for (none: safe).
+// spans are the flagged byte ranges, when the classifier can tell.
+type moderationResult struct {
+	categories []string
+	spans      [][2]int
+}
+
+// moderation screens GetPlan's prompt before the provider sees it and the
+// plan before the planner does (nil-safe: off).
+type moderation struct {
+	name       string
+	classifier classifier
+	// redact masks flagged spans instead of rejecting the text; texts
+	// flagged without spans are still rejected.
+	redact bool
+	// failOpen lets texts through when the classifier fails.
+	failOpen bool
+}
+
+// newModeration returns the moderation cfg describes, or nil when moderation
+// is off.
+//
+//   - MODERATION (default: off) — local (built-in patterns) or api (an
+//     OpenAI-compatible /moderations endpoint)
+//   - MODERATION_ACTION (default: reject) — or redact
+//   - MODERATION_FAIL_OPEN (default: off) — on lets texts through when the
+//     classifier fails
+//   - MODERATION_BLOCKLIST (optional) — extra comma-separated terms, for both
+//     classifiers
+//   - MODERATION_BASE_URL (default: https://api.openai.com/v1),
+//     MODERATION_MODEL (default: omni-moderation-latest) and
+//     MODERATION_API_KEY (via pkg/secrets) — for api
+func newModeration(ctx context.Context, store *secrets.Store, cfg ModerationConfig) (*moderation, error) {
+	m := &moderation{
+		name:     strings.ToLower(strings.TrimSpace(cfg.Mode)),
+		failOpen: cfg.FailOpen,
+	}
+	switch action := strings.ToLower(cfg.Action); action {
+	case "reject":
+	case "redact":
+		m.redact = true
+	default:
+		return nil, fmt.Errorf("MODERATION_ACTION: want reject or redact, got %q", action)
+	}
+	local := &patternClassifier{rules: builtinModerationRules}
+	for _, term := range cfg.Blocklist {
+		if term = strings.TrimSpace(term); term != "" {
+			local.rules = append(local.rules, moderationRule{"blocklist", blocklistPattern(term)})
+		}
+	}
+
+	switch m.name {
+	case "", "off":
+		return nil, nil
+	case "local":
+		m.classifier = local
+	case "api":
+		key, err := store.Lookup(ctx, "MODERATION_API_KEY")
+		if err != nil {
+			return nil, err
+		}
+		api := &apiClassifier{
+			url:   strings.TrimRight(cfg.BaseURL, "/") + "/moderations",
+			model: cfg.Model,
+			key:   key,
+			http:  sharedHTTPClient,
+		}
+		m.classifier = api
+		if len(local.rules) > len(builtinModerationRules) {
+			// The blocklist applies on top of the API.
+			m.classifier = classifiers{&patternClassifier{rules: local.rules[len(builtinModerationRules):]}, api}
+		}
+	default:
+		return nil, fmt.Errorf("MODERATION: want off, local or api, got %q", m.name)
+	}
+	return m, nil
+}
+
+// screen checks text at a stage and returns it, redacted if so configured.
+// A flagged text is rejected with InvalidArgument (prompt) or
+// FailedPrecondition (plan), carrying an ErrorInfo with the categories.
+func (m *moderation) screen(ctx context.Context, stage, text string) (string, error) {
+	if m == nil || strings.TrimSpace(text) == "" {
+		return text, nil
+	}
+	lg := logger.NewContextLogger(ctx)
+	result, err := m.classifier.classify(ctx, text)
+	if err != nil {
+		if m.failOpen {
+			lg.Warn("moderation_failed_open", "stage", stage, "classifier", m.name, "error", err)
+			return text, nil
+		}
+		lg.Warn("moderation_failed", "stage", stage, "classifier", m.name, "error", err)
+		return "", status.Errorf(codes.Unavailable, "moderation unavailable: %v", err)
+	}
+	if len(result.categories) == 0 {
+		return text, nil
+	}
+	if m.redact && len(result.spans) > 0 {
+		lg.Warn("moderation_redacted", "stage", stage, "classifier", m.name, "categories", result.categories, "spans", len(result.spans))
+		return redactSpans(text, result.spans), nil
+	}
+	lg.Warn("moderation_rejected", "stage", stage, "classifier", m.name, "categories", result.categories)
+	code, reason := codes.InvalidArgument, reasonPromptFlagged
+	if stage == moderationPlan {
+		code, reason = codes.FailedPrecondition, reasonPlanFlagged
+	}
+	st, detailErr := status.New(code, fmt.Sprintf("%s flagged by moderation: %s", stage, strings.Join(result.categories, ", "))).WithDetails(&errdetails.ErrorInfo{
+		Reason:   reason,
+		Domain:   moderationErrorDomain,
+		Metadata: map[string]string{"stage": stage, "categories": strings.Join(result.categories, ","), "classifier": m.name},
+	})
+	if detailErr != nil {
+		return "", status.Errorf(code, "%s flagged by moderation: %s", stage, strings.Join(result.categories, ", "))
+	}
+	return "", st.Err()
+}
+
+// redactSpans replaces the (possibly overlapping) spans with [REDACTED].
+func redactSpans(text string, spans [][2]int) string {
+	spans = slices.Clone(spans)
+	sort.Slice(spans, func(i, j int) bool { return spans[i][0] < spans[j][0] })
+	var b strings.Builder
+	last := 0
+	for _, sp := range spans {
+		if sp[0] < last {
+			// Overlaps the span just redacted.
+			last = max(last, sp[1])
+			continue
+		}
+		b.WriteString(text[last:sp[0]])
+		b.WriteString("[REDACTED]")
+		last = sp[1]
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+type moderationRule struct {
+	category string
+	pattern  *regexp.Regexp
+}
+
+// blocklistPattern matches a term as whole words, ignoring case.
+func blocklistPattern(term string) *regexp.Regexp {
+	return regexp.MustCompile(`(?i)\b` + regexp.QuoteMeta(term) + `\b`)
+}
+
+// builtinModerationRules is a conservative local classifier: it catches
+// explicit requests for serious harm, not everything an API would.
+var builtinModerationRules = []moderationRule{
+	{"self-harm", regexp.MustCompile(`(?i)\b(kill|hurt|harm|cut)\s+myself\b`)},
+	{"self-harm", regexp.MustCompile(`(?i)\b(how\s+to\s+(commit\s+)?suicide|suicide\s+(methods?|instructions))\b`)},
+	{"violence", regexp.MustCompile(`(?i)\bhow\s+(to|do\s+i|can\s+i)\s+(make|build|assemble)\s+(a\s+|an\s+)?(pipe\s*bomb|bomb|explosive\s+device|ied)\b`)},
+	{"violence", regexp.MustCompile(`(?i)\b(i\s+(want|am\s+going|'m\s+going)\s+to\s+(kill|murder|shoot|stab))\b`)},
+	{"sexual/minors", regexp.MustCompile(`(?i)\b(child|minor|underage)\s+(porn\w*|sexual\w*|nude\w*)\b`)},
+	{"weapons", regexp.MustCompile(`(?i)\b(synthesi[sz]e|make|produce)\s+(sarin|vx|ricin|anthrax|nerve\s+agent)\b`)},
+}
+
+// patternClassifier flags texts matching its rules, with their spans.
+type patternClassifier struct {
+	rules []moderationRule
+}
+
+func (c *patternClassifier) classify(_ context.Context, text string) (moderationResult, error) {
+	var r moderationResult
+	for _, rule := range c.rules {
+		matches := rule.pattern.FindAllStringIndex(text, -1)
+		if len(matches) == 0 {
+			continue
+		}
+		if !slices.Contains(r.categories, rule.category) {
+			r.categories = append(r.categories, rule.category)
+		}
+		for _, m := range matches {
+			r.spans = append(r.spans, [2]int{m[0], m[1]})
+		}
+	}
+	return r, nil
+}
+
+// apiClassifier calls an OpenAI-compatible POST /moderations, which flags
+// whole texts.
+type apiClassifier struct {
+	url, model, key string
+	http            *http.Client
+}
+
+func (c *apiClassifier) classify(ctx context.Context, text string) (moderationResult, error) {
+	body, _ := json.Marshal(map[string]any{"input": text, "model": c.model})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return moderationResult{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.key != "" {
+		req.Header.Set("Authorization", "Bearer "+c.key)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return moderationResult{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return moderationResult{}, fmt.Errorf("moderations: HTTP %d", resp.StatusCode)
+	}
+	var out struct {
+		Results []struct {
+			Flagged    bool            `json:"flagged"`
+			Categories map[string]bool `json:"categories"`
+		} `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return moderationResult{}, fmt.Errorf("moderations: %w", err)
+	}
+	var r moderationResult
+	for _, res := range out.Results {
+		for category, flagged := range res.Categories {
+			if flagged && !slices.Contains(r.categories, category) {
+				r.categories = append(r.categories, category)
+			}
+		}
+		if res.Flagged && len(r.categories) == 0 {
+			r.categories = append(r.categories, "flagged")
+		}
+	}
+	sort.Strings(r.categories)
+	return r, nil
+}
+
+// classifiers flags what any of them flags. Spans are kept only when every
+// flagging classifier reported them, since redaction must cover all of it.
+type classifiers []classifier
+
+func (cs classifiers) classify(ctx context.Context, text string) (moderationResult, error) {
+	var r moderationResult
+	spansComplete := true
+	for _, c := range cs {
+		res, err := c.classify(ctx, text)
+		if err != nil {
+			return moderationResult{}, err
+		}
+		if len(res.categories) == 0 {
+			continue
+		}
+		for _, category := range res.categories {
+			if !slices.Contains(r.categories, category) {
+				r.categories = append(r.categories, category)
+			}
+		}
+		spansComplete = spansComplete && len(res.spans) > 0
+		r.spans = append(r.spans, res.spans...)
+	}
+	if !spansComplete {
+		r.spans = nil
+	}
+	return r, nil
+}
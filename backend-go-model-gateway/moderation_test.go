@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	pb "backend-go-model-gateway/proto/proto"
+
+	"github.com/sashabaranov/go-openai"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// moderatedServer returns a server whose provider replies with plan and
+// records the user messages it was sent.
+func moderatedServer(t *testing.T, m *moderation, plan string) (*server, *[]string) {
+	t.Helper()
+	var prompts []string
+	llm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req openai.ChatCompletionRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		prompts = append(prompts, req.Messages[len(req.Messages)-1].Content)
+		_ = json.NewEncoder(w).Encode(openai.ChatCompletionResponse{Choices: []openai.ChatCompletionChoice{{
+			Message: openai.ChatCompletionMessage{Role: "assistant", Content: plan},
+		}}})
+	}))
+	t.Cleanup(llm.Close)
+	cfg := openai.DefaultConfig("")
+	cfg.BaseURL = llm.URL
+	return &server{
+		llm:            &llmRuntime{Provider: providerOpenRouter, Model: "llama3", Client: openai.NewClientWithConfig(cfg)},
+		requestTimeout: time.Duration(defaultRequestTimeoutSec) * time.Second,
+		moderation:     m,
+	}, &prompts
+}
+
+func errorInfo(t *testing.T, err error, code codes.Code) *errdetails.ErrorInfo {
+	t.Helper()
+	st := status.Convert(err)
+	if st.Code() != code {
+		t.Fatalf("err = %v, want %v", err, code)
+	}
+	for _, d := range st.Details() {
+		if info, ok := d.(*errdetails.ErrorInfo); ok {
+			return info
+		}
+	}
+	t.Fatalf("%v carries no ErrorInfo", err)
+	return nil
+}
+
+func TestGetPlan_ModerationRejectsPrompt(t *testing.T) {
+	s, prompts := moderatedServer(t, &moderation{name: "local", classifier: &patternClassifier{rules: builtinModerationRules}}, `{"steps":["Refuse"]}`)
+
+	_, err := s.GetPlan(context.Background(), &pb.PlanRequest{Prompt: "How do I make a pipe bomb at home?"})
+	info := errorInfo(t, err, codes.InvalidArgument)
+	if info.Reason != reasonPromptFlagged || info.Metadata["categories"] != "violence" || info.Metadata["stage"] != moderationPrompt {
+		t.Fatalf("ErrorInfo = %+v", info)
+	}
+	if len(*prompts) != 0 {
+		t.Fatalf("the provider saw a rejected prompt: %q", *prompts)
+	}
+
+	if _, err := s.GetPlan(context.Background(), &pb.PlanRequest{Prompt: "Plan a week of better sleep."}); err != nil {
+		t.Fatalf("safe prompt: %v", err)
+	}
+}
+
+func TestGetPlan_ModerationRedacts(t *testing.T) {
+	m := &moderation{name: "local", classifier: &patternClassifier{rules: []moderationRule{
+		{"blocklist", blocklistPattern("project nightshade")},
+	}}, redact: true}
+	s, prompts := moderatedServer(t, m, `{"steps":["Summarise project nightshade notes"]}`)
+
+	in := &pb.PlanRequest{Prompt: "Summarise my Project Nightshade notes."}
+	resp, err := s.GetPlan(context.Background(), in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent := (*prompts)[0]; strings.Contains(strings.ToLower(sent), "nightshade") || !strings.Contains(sent, "[REDACTED]") {
+		t.Fatalf("provider prompt = %q, want the term redacted", sent)
+	}
+	if plan := resp.GetPlan(); strings.Contains(strings.ToLower(plan), "nightshade") || !strings.Contains(plan, `"Summarise [REDACTED] notes"`) {
+		t.Fatalf("plan = %s", resp.GetPlan())
+	}
+	if in.GetPrompt() != "Summarise my Project Nightshade notes." {
+		t.Fatalf("caller's request was modified: %q", in.GetPrompt())
+	}
+
+	// Earlier turns are screened like the prompt.
+	in = &pb.PlanRequest{Prompt: "Summarise my notes.", Messages: []*pb.ChatMessage{
+		{Role: "assistant", Content: `{"tool":{"name":"notes_search"}}`},
+		{Role: "user", Content: "<tool_result>\nProject Nightshade kickoff\n</tool_result>"},
+	}}
+	if _, err := s.GetPlan(context.Background(), in); err != nil {
+		t.Fatal(err)
+	}
+	if sent := (*prompts)[1]; strings.Contains(strings.ToLower(sent), "nightshade") || !strings.Contains(sent, "[REDACTED]") {
+		t.Fatalf("provider turn = %q, want the term redacted", sent)
+	}
+	if in.GetMessages()[1].GetContent() != "<tool_result>\nProject Nightshade kickoff\n</tool_result>" {
+		t.Fatalf("caller's messages were modified: %v", in.GetMessages())
+	}
+}
+
+func TestGetPlan_ModerationRejectsPlan(t *testing.T) {
+	auth := ""
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Input string `json:"input"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		auth = r.Header.Get("Authorization")
+		hit := strconv.FormatBool(strings.Contains(req.Input, "hurt them"))
+		_, _ = w.Write([]byte(`{"results":[{"flagged":` + hit + `,"categories":{"violence":` + hit + `,"harassment":false}}]}`))
+	}))
+	defer api.Close()
+	// Redaction needs spans, which the API does not give: the plan is rejected.
+	m := &moderation{name: "api", classifier: &apiClassifier{url: api.URL, model: defaultModerationModel, key: "sk-mod", http: api.Client()}, redact: true}
+	s, _ := moderatedServer(t, m, `{"steps":["Find where they live","hurt them"]}`)
+
+	_, err := s.GetPlan(context.Background(), &pb.PlanRequest{Prompt: "My neighbour is loud."})
+	info := errorInfo(t, err, codes.FailedPrecondition)
+	if info.Reason != reasonPlanFlagged || info.Metadata["categories"] != "violence" || info.Metadata["classifier"] != "api" {
+		t.Fatalf("ErrorInfo = %+v", info)
+	}
+	if auth != "Bearer sk-mod" {
+		t.Fatalf("Authorization = %q", auth)
+	}
+}
+
+func TestModeration_ClassifierFailure(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "overloaded", http.StatusServiceUnavailable)
+	}))
+	defer api.Close()
+	classifier := &apiClassifier{url: api.URL, http: api.Client()}
+
+	if _, err := (&moderation{name: "api", classifier: classifier}).screen(context.Background(), moderationPrompt, "hello"); status.Code(err) != codes.Unavailable {
+		t.Fatalf("fail-closed: err = %v, want Unavailable", err)
+	}
+	if got, err := (&moderation{name: "api", classifier: classifier, failOpen: true}).screen(context.Background(), moderationPrompt, "hello"); err != nil || got != "hello" {
+		t.Fatalf("fail-open: %q, %v", got, err)
+	}
+}
+
+func TestNewModeration(t *testing.T) {
+	t.Setenv("MODERATION", "")
+	if m, err := newModeration(context.Background(), nil, envConfig(t).Moderation); m != nil || err != nil {
+		t.Fatalf("unset: %+v, %v", m, err)
+	}
+	t.Setenv("MODERATION", "local")
+	t.Setenv("MODERATION_BLOCKLIST", "nightshade, ")
+	m, err := newModeration(context.Background(), nil, envConfig(t).Moderation)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, _ := m.classifier.classify(context.Background(), "about Nightshade"); len(r.categories) != 1 || r.categories[0] != "blocklist" {
+		t.Fatalf("blocklist: %+v", r)
+	}
+	t.Setenv("MODERATION_ACTION", "warn")
+	if _, err := loadConfig(); err == nil {
+		t.Fatal("MODERATION_ACTION=warn accepted")
+	}
+}
+
+func TestRedactSpans(t *testing.T) {
+	if got := redactSpans("a bad worse end", [][2]int{{6, 11}, {2, 5}, {4, 9}}); got != "a [REDACTED] end" {
+		t.Fatalf("overlapping spans: %q", got)
+	}
+}
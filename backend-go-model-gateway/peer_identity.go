@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+
+	"backend-go-model-gateway/internal/logger"
+	"backend-go-model-gateway/pkg/spiffe"
+	pb "backend-go-model-gateway/proto/proto"
+	"backend-go-model-gateway/service"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// peerIdentity is who an mTLS client authenticated as: the names in the
+// certificate it presented, which the TLS handshake already verified.
+type peerIdentity struct {
+	// Name is the primary identity: the SPIFFE ID, else the first DNS SAN,
+	// else the subject CN.
+	Name       string
+	SPIFFEID   string
+	DNSNames   []string
+	CommonName string
+}
+
+// names are every identity an allowlist entry may match.
+func (p peerIdentity) names() []string {
+	names := append([]string{}, p.DNSNames...)
+	if p.SPIFFEID != "" {
+		names = append(names, p.SPIFFEID)
+	}
+	if p.CommonName != "" {
+		names = append(names, p.CommonName)
+	}
+	return names
+}
+
+var errNoPeerCertificate = errors.New("no verified client certificate")
+
+// peerIdentityFromGRPC reads the client certificate of the connection an
+// incoming RPC arrived on.
+func peerIdentityFromGRPC(ctx context.Context) (peerIdentity, error) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return peerIdentity{}, errNoPeerCertificate
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.PeerCertificates) == 0 {
+		return peerIdentity{}, errNoPeerCertificate
+	}
+	cert := info.State.PeerCertificates[0]
+	id := peerIdentity{DNSNames: cert.DNSNames, CommonName: cert.Subject.CommonName}
+	id.SPIFFEID, _ = spiffe.IDFromCert(cert)
+	switch {
+	case id.SPIFFEID != "":
+		id.Name = id.SPIFFEID
+	case len(id.DNSNames) > 0:
+		id.Name = id.DNSNames[0]
+	default:
+		id.Name = id.CommonName
+	}
+	if id.Name == "" {
+		return peerIdentity{}, errors.New("client certificate has no SAN or CN")
+	}
+	return id, nil
+}
+
+type peerIdentityKey struct{}
+
+// peerIdentityFromContext returns the identity attached by the peer
+// authorization interceptor; ok is false without mTLS.
+func peerIdentityFromContext(ctx context.Context) (peerIdentity, bool) {
+	id, ok := ctx.Value(peerIdentityKey{}).(peerIdentity)
+	return id, ok
+}
+
+// peerPolicy is the per-RPC allowlist of peer identities. An entry matches a
+// DNS SAN, SPIFFE ID or CN of the client certificate; a SPIFFE trust domain
+// (spiffe://example.org) matches every workload in it, and * matches any
+// verified certificate.
+type peerPolicy struct {
+	// all applies to RPCs without their own list; empty allows any peer.
+	all      []string
+	byMethod map[string][]string
+}
+
+// peerPolicyInherits maps RPCs to the RPC whose list they use when they have
+// none of their own: StreamPlan returns the same plans as GetPlan.
+var peerPolicyInherits = map[string]string{"StreamPlan": "GetPlan"}
+
+// peersEnvPrefix names the allowlist variables: MTLS_ALLOWED_PEERS and
+// MTLS_ALLOWED_PEERS_<RPC>.
+const peersEnvPrefix = "MTLS_ALLOWED_PEERS"
+
+// policy is the allowlist of c: Allowed for every RPC and ByRPC for one RPC
+// each, named case-insensitively (GETRAGCONTEXT, or a streaming RPC such as
+// STREAMPLAN). It is nil when neither is set; every unknown RPC name is
+// reported.
+func (c PeersConfig) policy() (*peerPolicy, error) {
+	if len(c.Allowed) == 0 && len(c.ByRPC) == 0 {
+		return nil, nil
+	}
+	methods := map[string]string{}
+	for _, m := range pb.ModelGateway_ServiceDesc.Methods {
+		methods[strings.ToUpper(m.MethodName)] = m.MethodName
+	}
+	for _, st := range pb.ModelGateway_ServiceDesc.Streams {
+		methods[strings.ToUpper(st.StreamName)] = st.StreamName
+	}
+
+	p := peerPolicy{all: c.Allowed}
+	var errs []error
+	for _, rpc := range slices.Sorted(maps.Keys(c.ByRPC)) {
+		method, ok := methods[strings.ToUpper(rpc)]
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s_%s: no ModelGateway RPC named %q", peersEnvPrefix, strings.ToUpper(rpc), rpc))
+			continue
+		}
+		if p.byMethod == nil {
+			p.byMethod = map[string][]string{}
+		}
+		p.byMethod[method] = c.ByRPC[rpc]
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return &p, nil
+}
+
+// allows reports whether id may call method (e.g. GetPlan). A nil policy
+// allows every verified peer.
+func (p *peerPolicy) allows(method string, id peerIdentity) bool {
+	if p == nil {
+		return true
+	}
+	allowed, ok := p.byMethod[method]
+	if !ok {
+		allowed, ok = p.byMethod[peerPolicyInherits[method]]
+	}
+	if !ok {
+		allowed = p.all
+	}
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, entry := range allowed {
+		if entry == "*" || slices.Contains(id.names(), entry) ||
+			(id.SPIFFEID != "" && entry == spiffe.TrustDomain(id.SPIFFEID)) {
+			return true
+		}
+	}
+	return false
+}
+
+// peerAuthUnaryInterceptor identifies the mTLS client of every RPC, logs it,
+// attaches it to the context (peerIdentityFromContext) and rejects peers the
+// policy does not allow for that RPC with PermissionDenied. A valid
+// certificate is therefore not enough on its own once a policy is set.
+//
+// Health checks are exempt so probes keep working under any policy.
+func peerAuthUnaryInterceptor(policy *peerPolicy) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := authorizePeer(ctx, policy, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// peerAuthStreamInterceptor is peerAuthUnaryInterceptor for streaming RPCs.
+func peerAuthStreamInterceptor(policy *peerPolicy) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authorizePeer(ss.Context(), policy, info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &peerStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// peerStream is a server stream whose context carries the peer identity.
+type peerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *peerStream) Context() context.Context { return s.ctx }
+
+// authorizePeer checks the caller of fullMethod against the policy and
+// returns ctx with its identity attached.
+func authorizePeer(ctx context.Context, policy *peerPolicy, fullMethod string) (context.Context, error) {
+	if strings.HasPrefix(fullMethod, "/grpc.health.v1.Health/") {
+		return ctx, nil
+	}
+	lg := logger.NewContextLogger(service.ContextWithTraceIDFromIncomingGRPC(ctx))
+	id, err := peerIdentityFromGRPC(ctx)
+	if err != nil {
+		lg.Warn("grpc_peer_unauthenticated", "method", fullMethod, "error", err.Error())
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	method := path.Base(fullMethod)
+	if !policy.allows(method, id) {
+		lg.Warn("grpc_peer_denied", "method", fullMethod, "peer", id.Name, "dns_names", id.DNSNames, "common_name", id.CommonName)
+		return nil, status.Errorf(codes.PermissionDenied, "peer %q is not allowed to call %s", id.Name, method)
+	}
+	lg.Info("grpc_peer", "method", fullMethod, "peer", id.Name)
+	return context.WithValue(ctx, peerIdentityKey{}, id), nil
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/url"
+	"strings"
+	"testing"
+
+	pb "backend-go-model-gateway/proto/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// withPeerCert returns ctx as an RPC from a client that presented cert.
+func withPeerCert(cert *x509.Certificate) context.Context {
+	return peer.NewContext(context.Background(), &peer.Peer{AuthInfo: credentials.TLSInfo{
+		State: tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}},
+	}})
+}
+
+func TestPeerAuthUnaryInterceptor(t *testing.T) {
+	t.Setenv("MTLS_ALLOWED_PEERS", "agent-planner,spiffe://pagi.test")
+	t.Setenv("MTLS_ALLOWED_PEERS_GETRAGCONTEXT", "agent-planner,memory-indexer")
+	policy, err := envConfig(t).Peers.policy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	intercept := peerAuthUnaryInterceptor(policy)
+
+	planner := &x509.Certificate{Subject: pkix.Name{CommonName: "planner"}, DNSNames: []string{"agent-planner"}}
+	indexer := &x509.Certificate{Subject: pkix.Name{CommonName: "memory-indexer"}}
+	workload := &x509.Certificate{URIs: []*url.URL{{Scheme: "spiffe", Host: "pagi.test", Path: "/sa/ops"}}}
+
+	for _, tc := range []struct {
+		name   string
+		ctx    context.Context
+		method string
+		want   codes.Code
+		peer   string
+	}{
+		{"DNS SAN on the default list", withPeerCert(planner), pb.ModelGateway_GetPlan_FullMethodName, codes.OK, "agent-planner"},
+		{"trust domain on the default list", withPeerCert(workload), pb.ModelGateway_GetPlan_FullMethodName, codes.OK, "spiffe://pagi.test/sa/ops"},
+		{"CN not on the default list", withPeerCert(indexer), pb.ModelGateway_GetPlan_FullMethodName, codes.PermissionDenied, ""},
+		{"CN on the RPC's list", withPeerCert(indexer), pb.ModelGateway_GetRAGContext_FullMethodName, codes.OK, "memory-indexer"},
+		{"RPC list replaces the default", withPeerCert(workload), pb.ModelGateway_GetRAGContext_FullMethodName, codes.PermissionDenied, ""},
+		{"no client certificate", context.Background(), pb.ModelGateway_GetPlan_FullMethodName, codes.Unauthenticated, ""},
+		{"health checks are exempt", context.Background(), "/grpc.health.v1.Health/Check", codes.OK, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var got string
+			_, err := intercept(tc.ctx, nil, &grpc.UnaryServerInfo{FullMethod: tc.method}, func(ctx context.Context, _ any) (any, error) {
+				if id, ok := peerIdentityFromContext(ctx); ok {
+					got = id.Name
+				}
+				return nil, nil
+			})
+			if status.Code(err) != tc.want {
+				t.Fatalf("code = %v (%v), want %v", status.Code(err), err, tc.want)
+			}
+			if got != tc.peer {
+				t.Fatalf("peer in context = %q, want %q", got, tc.peer)
+			}
+		})
+	}
+}
+
+type ctxStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s ctxStream) Context() context.Context { return s.ctx }
+
+func TestPeerAuthStreamInterceptor(t *testing.T) {
+	t.Setenv("MTLS_ALLOWED_PEERS", "agent-planner")
+	policy, err := envConfig(t).Peers.policy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	intercept := peerAuthStreamInterceptor(policy)
+	info := &grpc.StreamServerInfo{FullMethod: pb.ModelGateway_StreamPlan_FullMethodName, IsServerStream: true}
+
+	var got string
+	err = intercept(nil, ctxStream{ctx: withPeerCert(&x509.Certificate{DNSNames: []string{"agent-planner"}})}, info, func(_ any, ss grpc.ServerStream) error {
+		if id, ok := peerIdentityFromContext(ss.Context()); ok {
+			got = id.Name
+		}
+		return nil
+	})
+	if err != nil || got != "agent-planner" {
+		t.Fatalf("allowed peer: err = %v, peer in context = %q", err, got)
+	}
+
+	err = intercept(nil, ctxStream{ctx: withPeerCert(&x509.Certificate{Subject: pkix.Name{CommonName: "memory-indexer"}})}, info, func(any, grpc.ServerStream) error {
+		t.Fatal("handler ran for a denied peer")
+		return nil
+	})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("denied peer: code = %v (%v)", status.Code(err), err)
+	}
+}
+
+func TestPeersConfigPolicy(t *testing.T) {
+	if p, err := envConfig(t).Peers.policy(); p != nil || err != nil {
+		t.Fatalf("unset = %+v, %v; want no policy", p, err)
+	}
+	if !(*peerPolicy)(nil).allows("GetPlan", peerIdentity{Name: "anyone"}) {
+		t.Fatal("no policy must allow every verified peer")
+	}
+
+	// Only GetPlan is restricted; other RPCs stay open.
+	t.Setenv("MTLS_ALLOWED_PEERS_GETPLAN", "agent-planner")
+	p, err := envConfig(t).Peers.policy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.allows("GetPlan", peerIdentity{Name: "ops", CommonName: "ops"}) || !p.allows("GetRAGContext", peerIdentity{Name: "ops", CommonName: "ops"}) {
+		t.Fatalf("policy = %+v, want only GetPlan restricted", p)
+	}
+
+	// StreamPlan returns the same plans, so it follows GetPlan's list.
+	if p.allows("StreamPlan", peerIdentity{Name: "ops", CommonName: "ops"}) || !p.allows("StreamPlan", peerIdentity{Name: "agent-planner", DNSNames: []string{"agent-planner"}}) {
+		t.Fatal("a GetPlan-only restriction must apply to StreamPlan")
+	}
+	// Streaming RPCs can have their own list.
+	t.Setenv("MTLS_ALLOWED_PEERS_STREAMPLAN", "ops")
+	if p, err = envConfig(t).Peers.policy(); err != nil {
+		t.Fatal(err)
+	}
+	if !p.allows("StreamPlan", peerIdentity{Name: "ops", CommonName: "ops"}) || p.allows("GetPlan", peerIdentity{Name: "ops", CommonName: "ops"}) {
+		t.Fatalf("policy = %+v, want StreamPlan's own list to replace GetPlan's", p)
+	}
+
+	// A misspelled RPC fails loading the config, so startup.
+	t.Setenv("MTLS_ALLOWED_PEERS_GETPLANS", "agent-planner")
+	if _, err := loadConfig(); err == nil || !strings.Contains(err.Error(), "MTLS_ALLOWED_PEERS_GETPLANS") {
+		t.Fatalf("err = %v, want an error for an unknown RPC", err)
+	}
+}
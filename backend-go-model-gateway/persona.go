@@ -0,0 +1,36 @@
+package main
+
+import (
+	"slices"
+
+	"backend-go-model-gateway/pkg/tools"
+)
+
+// planModel returns the model for a GetPlan: the request's preferred model
+// when it is allowed, otherwise the configured one. ok is false when a
+// preferred model was refused. The mock provider always answers as "mock".
+func (r *llmRuntime) planModel(preferred string) (model string, ok bool) {
+	if preferred == "" || preferred == r.Model || r.Provider == providerMock {
+		return r.Model, true
+	}
+	if len(r.AllowedModels) > 0 && !slices.Contains(r.AllowedModels, preferred) {
+		return r.Model, false
+	}
+	return preferred, true
+}
+
+// offeredTools returns the tools a GetPlan may offer the model: every tool
+// in defs when allowed is empty, otherwise the named ones ("none" matches no
+// tool).
+func offeredTools(defs []tools.Definition, allowed []string) []tools.Definition {
+	if len(allowed) == 0 {
+		return defs
+	}
+	var offered []tools.Definition
+	for _, t := range defs {
+		if slices.Contains(allowed, t.Name) {
+			offered = append(offered, t)
+		}
+	}
+	return offered
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"backend-go-model-gateway/pkg/tools"
+	pb "backend-go-model-gateway/proto/proto"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestGetPlan_AppliesPersona(t *testing.T) {
+	var sent openai.ChatCompletionRequest
+	llm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&sent)
+		_ = json.NewEncoder(w).Encode(openai.ChatCompletionResponse{
+			Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Role: "assistant", Content: `{"steps":["ok"]}`}}},
+		})
+	}))
+	defer llm.Close()
+	cfg := openai.DefaultConfig("")
+	cfg.BaseURL = llm.URL
+
+	s := &server{
+		llm:            &llmRuntime{Provider: providerOpenRouter, Model: "default-model", Client: openai.NewClientWithConfig(cfg), AllowedModels: []string{"work-model"}},
+		requestTimeout: time.Duration(defaultRequestTimeoutSec) * time.Second,
+	}
+	resp, err := s.GetPlan(context.Background(), &pb.PlanRequest{
+		Prompt:       "plan my week",
+		Persona:      "work-assistant",
+		SystemPrompt: "You are Sam's work assistant.",
+		Model:        "work-model",
+		AllowedTools: []string{"none"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent.Model != "work-model" || resp.GetModelName() != "work-model" {
+		t.Fatalf("model = %q (reported %q), want work-model", sent.Model, resp.GetModelName())
+	}
+	system := sent.Messages[0].Content
+	if !strings.HasPrefix(system, "You are Sam's work assistant.") || strings.Contains(system, "<available_tools>") {
+		t.Fatalf("system message = %s", system)
+	}
+
+	// A model outside LLM_ALLOWED_MODELS falls back to the configured one.
+	if _, err := s.GetPlan(context.Background(), &pb.PlanRequest{Prompt: "plan my week", Model: "expensive-model"}); err != nil {
+		t.Fatal(err)
+	}
+	if sent.Model != "default-model" || !strings.Contains(sent.Messages[0].Content, `"web_search"`) {
+		t.Fatalf("model = %q, tools offered: %v", sent.Model, strings.Contains(sent.Messages[0].Content, "web_search"))
+	}
+}
+
+func TestOfferedTools(t *testing.T) {
+	if got := offeredTools(tools.Builtin, nil); len(got) != len(tools.Builtin) {
+		t.Fatalf("no restriction offers %d tools", len(got))
+	}
+	if got := offeredTools(tools.Builtin, []string{"web_search"}); len(got) != 1 || got[0].Name != "web_search" {
+		t.Fatalf("web_search only = %+v", got)
+	}
+	if got := offeredTools(tools.Builtin, []string{"none"}); len(got) != 0 {
+		t.Fatalf("none = %+v", got)
+	}
+}
+
+func TestAllowedModels(t *testing.T) {
+	t.Setenv("LLM_ALLOWED_MODELS", " a , b,,")
+	if got := envConfig(t).LLM.AllowedModels; len(got) != 2 || got[0] != "a" || got[1] != "b" {
+		t.Fatalf("allowed = %q", got)
+	}
+	r := &llmRuntime{Provider: providerOllama, Model: "llama3"}
+	if m, ok := r.planModel("mistral"); m != "mistral" || !ok {
+		t.Fatalf("no allow-list: %q %v", m, ok)
+	}
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"backend-go-model-gateway/internal/logger"
+	"backend-go-model-gateway/service"
+)
+
+// piiPattern finds one kind of personal data. valid, when set, rejects
+// regex matches that are not really of that kind.
+type piiPattern struct {
+	kind  string
+	re    *regexp.Regexp
+	valid func(match string) bool
+}
+
+// builtinPIIPatterns are the PII_SCRUB_KINDS. national_id covers US social
+// security numbers and UK national insurance numbers; other schemes go in
+// PII_SCRUB_PATTERNS_FILE. Patterns run in this order, so an SSN is not
+// mistaken for a phone number.
+var builtinPIIPatterns = []piiPattern{
+	{kind: "email", re: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`)},
+	{kind: "national_id", re: regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`)},
+	{kind: "national_id", re: regexp.MustCompile(`\b[A-CEGHJ-PR-TW-Z][A-CEGHJ-NPR-TW-Z] ?\d{2} ?\d{2} ?\d{2} ?[A-D]\b`)},
+	{kind: "phone", re: regexp.MustCompile(`(?:\+|\(|\b)\d[\d ().-]{6,}\d\b`), valid: plausiblePhone},
+}
+
+// isoDate matches the start of an ISO 8601 date or timestamp, which the phone
+// pattern would otherwise pick up.
+var isoDate = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}`)
+
+// plausiblePhone accepts 9 to 15 digits (8 with a leading +), the range of
+// E.164 numbers with an area code, and rejects dates.
+func plausiblePhone(s string) bool {
+	digits := 0
+	for _, r := range s {
+		if unicode.IsDigit(r) {
+			digits++
+		}
+	}
+	least := 9
+	if strings.HasPrefix(s, "+") {
+		least = 8
+	}
+	return digits >= least && digits <= 15 && !isoDate.MatchString(s)
+}
+
+// piiScrubber replaces personal data in prompts with placeholders such as
+// [EMAIL_1] before they leave for an external provider, and puts the values
+// back into the plan that comes back. A nil scrubber scrubs nothing.
+type piiScrubber struct {
+	providers []llmProvider
+	patterns  []piiPattern
+	// terms matches PII_SCRUB_DICTIONARY entries (nil: no dictionary).
+	terms *regexp.Regexp
+}
+
+// providers parses PII_SCRUB, the providers whose prompts are scrubbed, e.g.
+// "openrouter" ("off": none).
+func (c PIIConfig) providers() ([]llmProvider, error) {
+	v := strings.ToLower(strings.TrimSpace(c.Scrub))
+	if v == "off" || v == "" {
+		return nil, nil
+	}
+	var providers []llmProvider
+	for _, name := range strings.Split(v, ",") {
+		switch p := llmProvider(strings.TrimSpace(name)); p {
+		case providerOpenRouter, providerOllama, providerAnthropic:
+			providers = append(providers, p)
+		case "":
+		default:
+			return nil, fmt.Errorf("PII_SCRUB: unsupported provider %q (supported: openrouter, ollama, anthropic, or off)", name)
+		}
+	}
+	return providers, nil
+}
+
+// builtins parses PII_SCRUB_KINDS into built-in patterns, in built-in order
+// whatever order the kinds were listed in.
+func (c PIIConfig) builtins() ([]piiPattern, error) {
+	var patterns []piiPattern
+	for _, kind := range c.Kinds {
+		kind = strings.ToLower(strings.TrimSpace(kind))
+		if kind == "" {
+			continue
+		}
+		found := false
+		for _, p := range builtinPIIPatterns {
+			if p.kind == kind {
+				patterns = append(patterns, p)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("PII_SCRUB_KINDS: unknown kind %q (supported: email, phone, national_id)", kind)
+		}
+	}
+	sort.SliceStable(patterns, func(i, j int) bool {
+		return builtinPIIIndex(patterns[i]) < builtinPIIIndex(patterns[j])
+	})
+	return patterns, nil
+}
+
+// newPIIScrubber builds the scrubber for cfg (nil when PII_SCRUB is off):
+//   - PII_SCRUB: providers whose prompts are scrubbed
+//   - PII_SCRUB_KINDS: built-in patterns (default: email,phone,national_id)
+//   - PII_SCRUB_PATTERNS_FILE: extra patterns, one "kind regex" per line
+//   - PII_SCRUB_DICTIONARY: known personal terms (names, addresses), one per
+//     line, matched case-insensitively as whole words
+func newPIIScrubber(cfg PIIConfig) (*piiScrubber, error) {
+	providers, err := cfg.providers()
+	if err != nil || providers == nil {
+		return nil, err
+	}
+	patterns, err := cfg.builtins()
+	if err != nil {
+		return nil, err
+	}
+	s := &piiScrubber{providers: providers, patterns: patterns}
+
+	if path := cfg.PatternsFile; path != "" {
+		lines, err := readPIILines(path)
+		if err != nil {
+			return nil, fmt.Errorf("PII_SCRUB_PATTERNS_FILE: %w", err)
+		}
+		for _, line := range lines {
+			kind, expr, ok := strings.Cut(line, " ")
+			expr = strings.TrimSpace(expr)
+			if !ok || expr == "" || !isPIIKind(kind) {
+				return nil, fmt.Errorf("PII_SCRUB_PATTERNS_FILE: want \"kind regex\" with a kind of letters, digits and _, got %q", line)
+			}
+			re, err := regexp.Compile(expr)
+			if err != nil {
+				return nil, fmt.Errorf("PII_SCRUB_PATTERNS_FILE: kind %s: %w", kind, err)
+			}
+			s.patterns = append(s.patterns, piiPattern{kind: strings.ToLower(kind), re: re})
+		}
+	}
+
+	if path := cfg.Dictionary; path != "" {
+		terms, err := readPIILines(path)
+		if err != nil {
+			return nil, fmt.Errorf("PII_SCRUB_DICTIONARY: %w", err)
+		}
+		s.terms = dictionaryRegexp(terms)
+	}
+	return s, nil
+}
+
+func builtinPIIIndex(p piiPattern) int {
+	for i, b := range builtinPIIPatterns {
+		if b.re == p.re {
+			return i
+		}
+	}
+	return len(builtinPIIPatterns)
+}
+
+func isPIIKind(s string) bool {
+	return s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	}) < 0
+}
+
+// readPIILines returns the non-blank lines of path that are not # comments.
+func readPIILines(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var lines []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if line := strings.TrimSpace(sc.Text()); line != "" && !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	return lines, sc.Err()
+}
+
+// dictionaryRegexp matches any of terms case-insensitively, longest first so
+// "Jane Doe" wins over "Jane". Terms starting or ending in a letter or digit
+// only match there as whole words.
+func dictionaryRegexp(terms []string) *regexp.Regexp {
+	if len(terms) == 0 {
+		return nil
+	}
+	terms = slices.Clone(terms)
+	sort.SliceStable(terms, func(i, j int) bool { return len(terms[i]) > len(terms[j]) })
+	alts := make([]string, len(terms))
+	for i, t := range terms {
+		alt := regexp.QuoteMeta(t)
+		if r := []rune(t); isWordRune(r[0]) {
+			alt = `\b` + alt
+		}
+		if r := []rune(t); isWordRune(r[len(r)-1]) {
+			alt += `\b`
+		}
+		alts[i] = alt
+	}
+	return regexp.MustCompile(`(?i)(?:` + strings.Join(alts, "|") + `)`)
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+// appliesTo reports whether prompts for provider are scrubbed.
+func (s *piiScrubber) appliesTo(provider llmProvider) bool {
+	return s != nil && slices.Contains(s.providers, provider)
+}
+
+// piiSession scrubs the prompts of one request. The same value gets the same
+// placeholder in every message, so the model can refer to it consistently.
+type piiSession struct {
+	scrubber *piiScrubber
+	// placeholders maps a lower-cased value to its placeholder.
+	placeholders map[string]string
+	// values maps a placeholder back to the value as first seen.
+	values map[string]string
+	counts map[string]int
+}
+
+func (s *piiScrubber) session() *piiSession {
+	return &piiSession{
+		scrubber:     s,
+		placeholders: map[string]string{},
+		values:       map[string]string{},
+		counts:       map[string]int{},
+	}
+}
+
+// scrub returns text with personal data replaced by placeholders.
+func (p *piiSession) scrub(text string) string {
+	for _, pat := range p.scrubber.patterns {
+		text = pat.re.ReplaceAllStringFunc(text, func(m string) string {
+			if pat.valid != nil && !pat.valid(m) {
+				return m
+			}
+			return p.placeholder(pat.kind, m)
+		})
+	}
+	if p.scrubber.terms != nil {
+		text = p.scrubber.terms.ReplaceAllStringFunc(text, func(m string) string {
+			return p.placeholder("term", m)
+		})
+	}
+	return text
+}
+
+func (p *piiSession) placeholder(kind, value string) string {
+	key := strings.ToLower(value)
+	if ph, ok := p.placeholders[key]; ok {
+		return ph
+	}
+	p.counts[kind]++
+	ph := "[" + strings.ToUpper(kind) + "_" + strconv.Itoa(p.counts[kind]) + "]"
+	p.placeholders[key] = ph
+	p.values[ph] = value
+	return ph
+}
+
+// piiPlaceholderNote is appended to the system prompt of a scrubbed request.
+const piiPlaceholderNote = "Placeholders such as [EMAIL_1] stand for redacted values; copy them verbatim wherever the value is needed.\n"
+
+// scrubbed reports whether any value was replaced.
+func (p *piiSession) scrubbed() bool {
+	return len(p.values) > 0
+}
+
+// logScrubbed records a redaction event for audit: which request sent how
+// many distinct values of each kind to which provider, never the values.
+func (p *piiSession) logScrubbed(ctx context.Context, rpc string, provider llmProvider, model string) {
+	if p == nil || !p.scrubbed() {
+		return
+	}
+	logger.NewContextLogger(ctx).Info("pii_scrubbed",
+		"rpc", rpc,
+		"session_id", service.SessionIDFromIncomingGRPC(ctx),
+		"principal", service.PrincipalFromIncomingGRPC(ctx),
+		"provider", provider,
+		"model", model,
+		"counts", p.counts,
+	)
+}
+
+// restore puts the original values back into a normalized (JSON) plan. The
+// values are JSON-escaped, since placeholders only appear inside strings.
+func (p *piiSession) restore(plan string) string {
+	if len(p.values) == 0 {
+		return plan
+	}
+	pairs := make([]string, 0, 2*len(p.values))
+	for ph, v := range p.values {
+		b, _ := json.Marshal(v)
+		pairs = append(pairs, ph, string(b[1:len(b)-1]))
+	}
+	return strings.NewReplacer(pairs...).Replace(plan)
+}
+
+// restoreText puts the original values back into plain text, such as a Chat
+// reply.
+func (p *piiSession) restoreText(text string) string {
+	if len(p.values) == 0 {
+		return text
+	}
+	pairs := make([]string, 0, 2*len(p.values))
+	for ph, v := range p.values {
+		pairs = append(pairs, ph, v)
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	pb "backend-go-model-gateway/proto/proto"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestPIISession_ScrubAndRestore(t *testing.T) {
+	dir := t.TempDir()
+	dict := filepath.Join(dir, "dictionary.txt")
+	if err := os.WriteFile(dict, []byte("# the twin\nJane Doe\nJane\n42 Elm Street\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	patterns := filepath.Join(dir, "patterns.txt")
+	if err := os.WriteFile(patterns, []byte(`passport \b[A-Z]\d{8}\b`+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PII_SCRUB", "openrouter")
+	t.Setenv("PII_SCRUB_DICTIONARY", dict)
+	t.Setenv("PII_SCRUB_PATTERNS_FILE", patterns)
+	s, err := newPIIScrubber(envConfig(t).PII)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.appliesTo(providerOpenRouter) || s.appliesTo(providerOllama) {
+		t.Fatalf("providers = %v, want openrouter only", s.providers)
+	}
+
+	sess := s.session()
+	got := sess.scrub("Jane Doe (jane.doe@example.com, +44 20 7946 0958, SSN 123-45-6789, NI AB 12 34 56 C, " +
+		"passport X12345678) lives at 42 elm street. Email JANE.DOE@example.com. Jane was born 1990-04-01 and paid 1,250.")
+	want := "[TERM_1] ([EMAIL_1], [PHONE_1], SSN [NATIONAL_ID_1], NI [NATIONAL_ID_2], " +
+		"passport [PASSPORT_1]) lives at [TERM_2]. Email [EMAIL_1]. [TERM_3] was born 1990-04-01 and paid 1,250."
+	if got != want {
+		t.Fatalf("scrub:\n got %s\nwant %s", got, want)
+	}
+
+	plan := `{"steps":["Email [EMAIL_1] about [TERM_1]","Call [PHONE_1]","Keep [UNKNOWN_1]"]}`
+	restored := sess.restore(plan)
+	var obj struct{ Steps []string }
+	if err := json.Unmarshal([]byte(restored), &obj); err != nil {
+		t.Fatalf("restored plan is not JSON: %v: %s", err, restored)
+	}
+	if obj.Steps[0] != "Email jane.doe@example.com about Jane Doe" || obj.Steps[1] != "Call +44 20 7946 0958" || obj.Steps[2] != "Keep [UNKNOWN_1]" {
+		t.Fatalf("restored steps = %q", obj.Steps)
+	}
+
+	// Values are JSON-escaped on the way back in.
+	quoted := (&piiScrubber{terms: dictionaryRegexp([]string{`Bob "B" Smith`})}).session()
+	if got := quoted.restore(`{"steps":["` + quoted.scrub(`ask Bob "B" Smith`) + `"]}`); !json.Valid([]byte(got)) || !strings.Contains(got, `Bob \"B\" Smith`) {
+		t.Fatalf("restore with quotes = %s", got)
+	}
+}
+
+func TestNewPIIScrubber(t *testing.T) {
+	if s, err := newPIIScrubber(envConfig(t).PII); s != nil || err != nil {
+		t.Fatalf("unset = %+v, %v; want no scrubber", s, err)
+	}
+	if (*piiScrubber)(nil).appliesTo(providerOpenRouter) {
+		t.Fatal("nil scrubber applies")
+	}
+	for env, value := range map[string]string{
+		"PII_SCRUB":               "openai",
+		"PII_SCRUB_KINDS":         "email,passport",
+		"PII_SCRUB_PATTERNS_FILE": filepath.Join(t.TempDir(), "missing"),
+	} {
+		t.Run(env, func(t *testing.T) {
+			t.Setenv("PII_SCRUB", "openrouter")
+			t.Setenv(env, value)
+			cfg, err := loadConfig()
+			if err == nil {
+				_, err = newPIIScrubber(cfg.PII)
+			}
+			if err == nil {
+				t.Fatalf("%s=%s: want an error", env, value)
+			}
+		})
+	}
+
+	t.Setenv("PII_SCRUB", "openrouter")
+	t.Setenv("PII_SCRUB_KINDS", "email")
+	s, err := newPIIScrubber(envConfig(t).PII)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.session().scrub("a@b.io 555-867-5309"); got != "[EMAIL_1] 555-867-5309" {
+		t.Fatalf("email only = %q", got)
+	}
+}
+
+func TestGetPlan_ScrubsPII(t *testing.T) {
+	var sent []openai.ChatCompletionMessage
+	llm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req openai.ChatCompletionRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		sent = req.Messages
+		_ = json.NewEncoder(w).Encode(openai.ChatCompletionResponse{
+			Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Role: "assistant", Content: `{"steps":["Reply to [EMAIL_1]"]}`}}},
+		})
+	}))
+	defer llm.Close()
+	cfg := openai.DefaultConfig("")
+	cfg.BaseURL = llm.URL
+
+	s := &server{
+		llm:            &llmRuntime{Provider: providerOpenRouter, Model: "m", Client: openai.NewClientWithConfig(cfg)},
+		vectorDB:       planRAG{{ID: "contact-1", KnowledgeBase: "Social-KB", Text: "Sam's number is (555) 867-5309.", Score: 0.9}},
+		requestTimeout: time.Duration(defaultRequestTimeoutSec) * time.Second,
+		pii:            &piiScrubber{providers: []llmProvider{providerOpenRouter}, patterns: builtinPIIPatterns},
+	}
+	resp, err := s.GetPlan(context.Background(), &pb.PlanRequest{Prompt: "answer sam@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range sent {
+		if strings.Contains(m.Content, "sam@example.com") || strings.Contains(m.Content, "867-5309") {
+			t.Fatalf("%s message leaked PII: %s", m.Role, m.Content)
+		}
+	}
+	if user := sent[len(sent)-1].Content; !strings.Contains(user, "[PHONE_1]") || !strings.Contains(user, "User prompt: answer [EMAIL_1]") {
+		t.Fatalf("user message = %s", user)
+	}
+	if !strings.Contains(resp.GetPlan(), "Reply to sam@example.com") {
+		t.Fatalf("plan = %s, want the email restored", resp.GetPlan())
+	}
+}
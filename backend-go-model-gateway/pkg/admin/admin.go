@@ -0,0 +1,346 @@
+// Package admin is the operator API shared by the Go services:
+//
+//	GET    /admin/status          version, uptime, drain state, in-flight work, last reload
+//	POST   /admin/drain           start draining (?wait=30s waits for in-flight work)
+//	DELETE /admin/drain           stop draining
+//	POST   /admin/reload-config   re-read PAGI_CONFIG_FILE and secrets, rebuild reloadable config
+//
+// A draining service keeps serving what it receives but reports itself not
+// ready, so load balancers and orchestrators move traffic away; once in-flight
+// work reaches zero the replica can be stopped.
+//
+// PAGI_CONFIG_FILE is an env file (KEY=VALUE per line) applied over the
+// process environment at startup and on every reload, e.g. a mounted
+// ConfigMap. Removing a line does not unset the variable.
+//
+// Every request needs the service's admin key (X-API-Key or Authorization:
+// Bearer), resolved through pkg/secrets. Without a key the admin API answers
+// 503: unlike the dev-mode fallbacks elsewhere, drain and reload are
+// disruptive enough to never run unauthenticated.
+package admin
+
+import (
+	"bufio"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"backend-go-model-gateway/pkg/secrets"
+
+	"google.golang.org/grpc"
+)
+
+// Options configures a Server.
+type Options struct {
+	Service string
+	Version string
+
+	// Store resolves KeyName (nil: plain environment variables).
+	Store *secrets.Store
+	// KeyName is the secret holding the admin key, e.g. GATEWAY_ADMIN_API_KEY.
+	KeyName string
+
+	// ConfigFile is applied to the environment before Reload (see the package
+	// doc); empty skips it.
+	ConfigFile string
+
+	// Status adds service-specific fields to GET /admin/status.
+	Status func(ctx context.Context) map[string]any
+	// Reload rebuilds the service's reloadable configuration from the
+	// environment and returns a summary of it. On error the service must keep
+	// its running configuration. Nil only re-reads ConfigFile and secrets.
+	Reload func(ctx context.Context) (map[string]any, error)
+	// OnDrain is called when draining starts (true) or stops (false).
+	OnDrain func(draining bool)
+}
+
+// OptionsFromEnv reads PAGI_CONFIG_FILE.
+func OptionsFromEnv() Options {
+	return Options{ConfigFile: strings.TrimSpace(os.Getenv("PAGI_CONFIG_FILE"))}
+}
+
+// Server tracks drain state and in-flight work and serves the admin API. A
+// nil *Server never drains and tracks nothing.
+type Server struct {
+	opts    Options
+	started time.Time
+
+	draining atomic.Bool
+	inFlight atomic.Int64
+
+	mu          sync.Mutex
+	reloads     int
+	lastReload  time.Time
+	reloadError string
+}
+
+// New returns a Server for opts.
+func New(opts Options) *Server {
+	return &Server{opts: opts, started: time.Now()}
+}
+
+// Draining reports whether the service is draining.
+func (s *Server) Draining() bool {
+	return s != nil && s.draining.Load()
+}
+
+// InFlight returns the number of requests or jobs currently in progress.
+func (s *Server) InFlight() int64 {
+	if s == nil {
+		return 0
+	}
+	return s.inFlight.Load()
+}
+
+// Begin counts a unit of work as in flight until the returned func is called.
+func (s *Server) Begin() (end func()) {
+	if s == nil {
+		return func() {}
+	}
+	s.inFlight.Add(1)
+	return func() { s.inFlight.Add(-1) }
+}
+
+// Track counts HTTP requests as in flight. Admin requests are not counted.
+func (s *Server) Track(next http.Handler) http.Handler {
+	if s == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/admin/") {
+			defer s.Begin()()
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// UnaryServerInterceptor counts gRPC calls as in flight. Health checks are not
+// counted.
+func (s *Server) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !strings.HasPrefix(info.FullMethod, "/grpc.health.v1.Health/") {
+			defer s.Begin()()
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor is UnaryServerInterceptor for streaming RPCs.
+func (s *Server) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !strings.HasPrefix(info.FullMethod, "/grpc.health.v1.Health/") {
+			defer s.Begin()()
+		}
+		return handler(srv, ss)
+	}
+}
+
+// Handler serves the /admin/ routes.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/admin/status", s.handleStatus)
+	mux.HandleFunc("/admin/drain", s.handleDrain)
+	mux.HandleFunc("/admin/reload-config", s.handleReload)
+	return s.authenticate(mux)
+}
+
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, err := s.opts.Store.Lookup(r.Context(), s.opts.KeyName)
+		if err != nil {
+			writeJSON(w, http.StatusServiceUnavailable, map[string]any{"error": "authentication unavailable"})
+			return
+		}
+		if key == "" {
+			writeJSON(w, http.StatusServiceUnavailable, map[string]any{"error": fmt.Sprintf("admin API disabled: %s is not set", s.opts.KeyName)})
+			return
+		}
+		provided := r.Header.Get("X-API-Key")
+		if provided == "" {
+			provided = strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		}
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(key)) != 1 {
+			writeJSON(w, http.StatusUnauthorized, map[string]any{"error": "unauthorized"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]any{"error": "method not allowed"})
+		return
+	}
+	out := map[string]any{
+		"service":        s.opts.Service,
+		"started_at":     s.started.UTC(),
+		"uptime_seconds": int64(time.Since(s.started).Seconds()),
+		"draining":       s.Draining(),
+		"in_flight":      s.InFlight(),
+	}
+	if s.opts.Version != "" {
+		out["version"] = s.opts.Version
+	}
+	s.mu.Lock()
+	out["reloads"] = s.reloads
+	if !s.lastReload.IsZero() {
+		out["last_reload"] = s.lastReload.UTC()
+	}
+	if s.reloadError != "" {
+		out["last_reload_error"] = s.reloadError
+	}
+	s.mu.Unlock()
+	if s.opts.Status != nil {
+		out["details"] = s.opts.Status(r.Context())
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) handleDrain(w http.ResponseWriter, r *http.Request) {
+	var drain bool
+	switch r.Method {
+	case http.MethodPost:
+		drain = true
+	case http.MethodDelete:
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]any{"error": "method not allowed"})
+		return
+	}
+	var wait time.Duration
+	if v := r.URL.Query().Get("wait"); v != "" && drain {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "wait must be a duration such as 30s"})
+			return
+		}
+		wait = d
+	}
+
+	if s.draining.Swap(drain) != drain && s.opts.OnDrain != nil {
+		s.opts.OnDrain(drain)
+	}
+	idle := s.waitIdle(r.Context(), wait)
+	writeJSON(w, http.StatusOK, map[string]any{"draining": drain, "in_flight": s.InFlight(), "idle": idle})
+}
+
+// waitIdle waits up to d for in-flight work to finish and reports whether it
+// did.
+func (s *Server) waitIdle(ctx context.Context, d time.Duration) bool {
+	deadline := time.Now().Add(d)
+	for s.InFlight() > 0 && time.Now().Before(deadline) {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+	return s.InFlight() == 0
+}
+
+func (s *Server) handleReload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]any{"error": "method not allowed"})
+		return
+	}
+	changed, config, err := s.reload(r.Context())
+	s.mu.Lock()
+	s.lastReload = time.Now()
+	s.reloadError = ""
+	if err != nil {
+		s.reloadError = err.Error()
+	} else {
+		s.reloads++
+	}
+	s.mu.Unlock()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error(), "changed": changed})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"reloaded": true, "changed": changed, "config": config})
+}
+
+func (s *Server) reload(ctx context.Context) ([]string, map[string]any, error) {
+	changed := []string{}
+	if s.opts.ConfigFile != "" {
+		var err error
+		if changed, err = LoadEnvFile(s.opts.ConfigFile); err != nil {
+			return changed, nil, err
+		}
+	}
+	s.opts.Store.Expire()
+	if s.opts.Reload == nil {
+		return changed, nil, nil
+	}
+	config, err := s.opts.Reload(ctx)
+	return changed, config, err
+}
+
+// LoadEnvFile sets the variables in an env file (KEY=VALUE lines; blank lines,
+// # comments, "export " prefixes and surrounding quotes are allowed) and
+// returns the names whose value changed. Nothing is set if the file is
+// malformed.
+func LoadEnvFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	type kv struct{ key, value string }
+	var vars []kv
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("%s:%d: want KEY=VALUE", path, n)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			if value[0] == '"' {
+				if unquoted, err := strconv.Unquote(value); err == nil {
+					value = unquoted
+				} else {
+					value = value[1 : len(value)-1]
+				}
+			} else {
+				value = value[1 : len(value)-1]
+			}
+		}
+		vars = append(vars, kv{key, value})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	changed := []string{}
+	for _, v := range vars {
+		if old, set := os.LookupEnv(v.key); set && old == v.value {
+			continue
+		}
+		if err := os.Setenv(v.key, v.value); err != nil {
+			return changed, err
+		}
+		changed = append(changed, v.key)
+	}
+	return changed, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
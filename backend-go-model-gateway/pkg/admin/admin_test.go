@@ -0,0 +1,135 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"backend-go-model-gateway/pkg/secrets"
+)
+
+func do(t *testing.T, h http.Handler, method, path, key string) (int, map[string]any) {
+	t.Helper()
+	req := httptest.NewRequest(method, path, nil)
+	if key != "" {
+		req.Header.Set("X-API-Key", key)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	var out map[string]any
+	_ = json.NewDecoder(rec.Body).Decode(&out)
+	return rec.Code, out
+}
+
+func TestServer_AuthAndDrain(t *testing.T) {
+	env := map[string]string{}
+	var drains []bool
+	s := New(Options{
+		Service: "svc",
+		Version: "1.2.3",
+		Store:   secrets.New(secrets.Options{Env: func(k string) string { return env[k] }}),
+		KeyName: "SVC_ADMIN_API_KEY",
+		Status:  func(context.Context) map[string]any { return map[string]any{"provider": "mock"} },
+		OnDrain: func(d bool) { drains = append(drains, d) },
+	})
+	h := s.Handler()
+
+	if code, out := do(t, h, http.MethodGet, "/admin/status", ""); code != http.StatusServiceUnavailable || !strings.Contains(out["error"].(string), "SVC_ADMIN_API_KEY") {
+		t.Fatalf("no key configured = %d %v", code, out)
+	}
+	env["SVC_ADMIN_API_KEY"] = "ops"
+	if code, _ := do(t, h, http.MethodGet, "/admin/status", "wrong"); code != http.StatusUnauthorized {
+		t.Fatalf("wrong key = %d", code)
+	}
+
+	// A request in flight keeps the drain from going idle until it ends.
+	app := s.Track(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.InFlight() != 1 {
+			t.Errorf("in flight during request = %d", s.InFlight())
+		}
+	}))
+	app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/plan", nil))
+	end := s.Begin()
+
+	code, out := do(t, h, http.MethodPost, "/admin/drain?wait=10ms", "ops")
+	if code != http.StatusOK || out["draining"] != true || out["idle"] != false || out["in_flight"] != float64(1) || !s.Draining() {
+		t.Fatalf("drain = %d %v", code, out)
+	}
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		end()
+	}()
+	if _, out := do(t, h, http.MethodPost, "/admin/drain?wait=5s", "ops"); out["idle"] != true {
+		t.Fatalf("drain with wait = %v", out)
+	}
+	code, out = do(t, h, http.MethodGet, "/admin/status", "ops")
+	if code != http.StatusOK || out["version"] != "1.2.3" || out["draining"] != true || out["details"].(map[string]any)["provider"] != "mock" {
+		t.Fatalf("status = %d %v", code, out)
+	}
+	if _, out := do(t, h, http.MethodDelete, "/admin/drain", "ops"); out["draining"] != false || s.Draining() {
+		t.Fatalf("undrain = %v", out)
+	}
+	if len(drains) != 2 || !drains[0] || drains[1] {
+		t.Fatalf("OnDrain calls = %v, want [true false]", drains)
+	}
+	if code, _ := do(t, h, http.MethodPost, "/admin/drain?wait=soon", "ops"); code != http.StatusBadRequest {
+		t.Fatalf("bad wait = %d", code)
+	}
+	if (*Server)(nil).Draining() {
+		t.Fatal("nil server drains")
+	}
+}
+
+func TestServer_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pagi.env")
+	write := func(s string) {
+		if err := os.WriteFile(path, []byte(s), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("# provider\nexport ADMIN_TEST_MODEL=\"model-a\"\nADMIN_TEST_TIMEOUT='30'\n")
+	t.Setenv("ADMIN_TEST_MODEL", "")
+	t.Setenv("ADMIN_TEST_TIMEOUT", "30")
+	t.Setenv("ADMIN_TEST_KEY", "ops")
+
+	fail := false
+	s := New(Options{
+		KeyName:    "ADMIN_TEST_KEY",
+		ConfigFile: path,
+		Reload: func(context.Context) (map[string]any, error) {
+			if fail {
+				return nil, errors.New("bad provider")
+			}
+			return map[string]any{"model": os.Getenv("ADMIN_TEST_MODEL")}, nil
+		},
+	})
+	h := s.Handler()
+
+	code, out := do(t, h, http.MethodPost, "/admin/reload-config", "ops")
+	if code != http.StatusOK || out["config"].(map[string]any)["model"] != "model-a" {
+		t.Fatalf("reload = %d %v", code, out)
+	}
+	if changed := out["changed"].([]any); len(changed) != 1 || changed[0] != "ADMIN_TEST_MODEL" {
+		t.Fatalf("changed = %v, want only ADMIN_TEST_MODEL", changed)
+	}
+
+	fail = true
+	if code, out := do(t, h, http.MethodPost, "/admin/reload-config", "ops"); code != http.StatusInternalServerError || out["error"] != "bad provider" {
+		t.Fatalf("failed reload = %d %v", code, out)
+	}
+	if _, out := do(t, h, http.MethodGet, "/admin/status", "ops"); out["reloads"] != float64(1) || out["last_reload_error"] != "bad provider" {
+		t.Fatalf("status after failed reload = %v", out)
+	}
+
+	write("ADMIN_TEST_MODEL=model-b\nnot a pair\n")
+	if _, err := LoadEnvFile(path); err == nil || os.Getenv("ADMIN_TEST_MODEL") != "model-a" {
+		t.Fatalf("malformed file: %v, model %q; want an error and nothing set", err, os.Getenv("ADMIN_TEST_MODEL"))
+	}
+}
@@ -0,0 +1,87 @@
+// Package answereval scores a final answer against its prompt and retrieved
+// context without a model. It is the planner's "heuristic" evaluation mode and
+// the mock provider's EvaluateAnswer.
+//
+// Both scores are word overlaps over distinctive words (four letters or
+// more): relevance is the share of the prompt's words the answer uses, and
+// groundedness the share of the answer's words found in the context. Answers
+// are often plan JSON, so JSON keys such as "steps" are ignored, and so are
+// common words ("what", "your").
+package answereval
+
+import (
+	"strings"
+	"unicode"
+
+	pb "backend-go-model-gateway/proto/proto"
+)
+
+// Scores are 0 (worst) to 1 (best).
+type Scores struct {
+	Relevance float64 `json:"relevance"`
+	// Groundedness is 1 when there is no context to check against.
+	Groundedness float64 `json:"groundedness"`
+	Score        float64 `json:"score"`
+}
+
+// ignored are the JSON keys of plans and tool calls, which are not content,
+// and common words that say nothing about the topic.
+var ignored = map[string]bool{
+	"steps": true, "tool": true, "name": true, "args": true, "query": true, "model_type": true, "prompt": true, "scratchpad": true,
+	"what": true, "when": true, "where": true, "which": true, "while": true, "with": true, "your": true, "yours": true,
+	"this": true, "that": true, "these": true, "those": true, "there": true, "their": true, "them": true, "they": true,
+	"have": true, "from": true, "will": true, "would": true, "should": true, "could": true, "about": true, "into": true,
+	"does": true, "been": true, "were": true, "some": true, "also": true, "just": true, "than": true, "then": true,
+}
+
+// Heuristic scores answer by word overlap.
+func Heuristic(prompt, answer string, context []string) Scores {
+	answerWords := words(answer)
+	inAnswer := map[string]bool{}
+	for _, w := range answerWords {
+		inAnswer[w] = true
+	}
+
+	s := Scores{Relevance: 1, Groundedness: 1}
+	if promptWords := words(prompt); len(promptWords) > 0 {
+		s.Relevance = share(promptWords, inAnswer)
+	}
+	if len(context) > 0 && len(answerWords) > 0 {
+		inContext := map[string]bool{}
+		for _, w := range words(strings.Join(context, "\n")) {
+			inContext[w] = true
+		}
+		s.Groundedness = share(answerWords, inContext)
+	}
+	s.Score = (s.Relevance + s.Groundedness) / 2
+	return s
+}
+
+// Response converts s to an EvaluateAnswer response.
+func (s Scores) Response(rationale, model string) *pb.EvaluateResponse {
+	return &pb.EvaluateResponse{Relevance: s.Relevance, Groundedness: s.Groundedness, Score: s.Score, Rationale: rationale, ModelName: model}
+}
+
+func share(words []string, in map[string]bool) float64 {
+	found := 0
+	for _, w := range words {
+		if in[w] {
+			found++
+		}
+	}
+	return float64(found) / float64(len(words))
+}
+
+func words(text string) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, w := range strings.FieldsFunc(strings.ToLower(text), func(c rune) bool {
+		return !unicode.IsLetter(c) && !unicode.IsNumber(c)
+	}) {
+		if len([]rune(w)) >= 4 && !seen[w] && !ignored[w] {
+			seen[w] = true
+			out = append(out, w)
+		}
+	}
+	return out
+}
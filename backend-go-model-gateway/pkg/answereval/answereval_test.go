@@ -0,0 +1,22 @@
+package answereval
+
+import "testing"
+
+func TestHeuristic(t *testing.T) {
+	context := []string{"Sam's dentist appointment is on Tuesday morning at the Elm Street clinic."}
+
+	good := Heuristic("When is my dentist appointment?", `{"steps":["Your dentist appointment is Tuesday morning."]}`, context)
+	if good.Relevance != 1 || good.Groundedness != 1 || good.Score != 1 {
+		t.Fatalf("grounded answer = %+v", good)
+	}
+
+	off := Heuristic("When is my dentist appointment?", `{"steps":["Bananas contain potassium."]}`, context)
+	if off.Relevance != 0 || off.Groundedness != 0 {
+		t.Fatalf("unrelated answer = %+v", off)
+	}
+
+	// Without context, groundedness cannot be checked.
+	if s := Heuristic("When is my dentist appointment?", "Your appointment is Friday.", nil); s.Groundedness != 1 || s.Relevance != 0.5 || s.Score != 0.75 {
+		t.Fatalf("no context = %+v", s)
+	}
+}
@@ -0,0 +1,124 @@
+// Package buildinfo describes the running binary the same way in every Go
+// service: its version, the commit and time it was built from, its Go version
+// and the features it has enabled. Services serve it on GET /version, and the
+// gateway on its GetVersion RPC.
+//
+// Release builds set the version, commit and time with -ldflags:
+//
+//	go build -ldflags "-X backend-go-model-gateway/pkg/buildinfo.Version=1.2.0 \
+//	    -X backend-go-model-gateway/pkg/buildinfo.GitSHA=$(git rev-parse HEAD) \
+//	    -X backend-go-model-gateway/pkg/buildinfo.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// Without them, the commit and time come from the VCS stamp go build embeds
+// when it builds inside a git checkout, if there is one.
+package buildinfo
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"sync"
+)
+
+// Set with -ldflags -X (see the package doc).
+var (
+	Version   = "1.0.0"
+	GitSHA    string
+	BuildTime string
+)
+
+// Info is what GET /version returns.
+type Info struct {
+	Service   string `json:"service"`
+	Version   string `json:"version"`
+	GitSHA    string `json:"git_sha,omitempty"`
+	BuildTime string `json:"build_time,omitempty"`
+	GoVersion string `json:"go_version"`
+	// Features are the optional features the service has enabled, sorted.
+	Features []string `json:"features"`
+}
+
+// Get returns the build info of service with its enabled features.
+func Get(service string, features []string) Info {
+	sha, at := vcs()
+	features = append([]string{}, features...)
+	sort.Strings(features)
+	return Info{
+		Service:   service,
+		Version:   Version,
+		GitSHA:    sha,
+		BuildTime: at,
+		GoVersion: runtime.Version(),
+		Features:  features,
+	}
+}
+
+// Enabled returns the names set to true, e.g. of a feature flag snapshot.
+func Enabled(m map[string]bool) []string {
+	out := []string{}
+	for name, on := range m {
+		if on {
+			out = append(out, name)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Handler serves GET /version as plain JSON. features is called per request,
+// so features switched on by a config reload show up (nil: none).
+func Handler(service string, features func(context.Context) []string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			_ = json.NewEncoder(w).Encode(map[string]any{"error": "method not allowed"})
+			return
+		}
+		var enabled []string
+		if features != nil {
+			enabled = features(r.Context())
+		}
+		_ = json.NewEncoder(w).Encode(Get(service, enabled))
+	})
+}
+
+var vcsOnce = sync.OnceValues(func() (sha, at string) {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "", ""
+	}
+	modified := false
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			sha = s.Value
+		case "vcs.time":
+			at = s.Value
+		case "vcs.modified":
+			modified = s.Value == "true"
+		}
+	}
+	if modified && sha != "" {
+		sha += "-dirty"
+	}
+	return sha, at
+})
+
+// vcs returns the ldflags commit and time, falling back to the VCS stamp.
+func vcs() (sha, at string) {
+	sha, at = GitSHA, BuildTime
+	if sha == "" || at == "" {
+		stampSHA, stampAt := vcsOnce()
+		if sha == "" {
+			sha = stampSHA
+		}
+		if at == "" {
+			at = stampAt
+		}
+	}
+	return sha, at
+}
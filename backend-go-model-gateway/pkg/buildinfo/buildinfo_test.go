@@ -0,0 +1,39 @@
+package buildinfo
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	defer func(v, sha, at string) { Version, GitSHA, BuildTime = v, sha, at }(Version, GitSHA, BuildTime)
+	Version, GitSHA, BuildTime = "1.2.0", "abc123", "2026-10-01T12:00:00Z"
+
+	rec := httptest.NewRecorder()
+	Handler("svc", func(context.Context) []string { return []string{"rag", "moderation"} }).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/version", nil))
+	var got Info
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	want := Info{Service: "svc", Version: "1.2.0", GitSHA: "abc123", BuildTime: "2026-10-01T12:00:00Z", GoVersion: runtime.Version(), Features: []string{"moderation", "rag"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("GET /version = %+v, want %+v", got, want)
+	}
+
+	rec = httptest.NewRecorder()
+	Handler("svc", nil).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/version", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("POST /version = %d", rec.Code)
+	}
+}
+
+func TestEnabled(t *testing.T) {
+	if got := Enabled(map[string]bool{"b": true, "a": true, "off": false}); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Fatalf("Enabled = %v", got)
+	}
+}
@@ -0,0 +1,246 @@
+// Package chaos implements an opt-in fault-injection mode (PAGI_CHAOS) used to
+// exercise circuit breakers and fallbacks in CI and staging.
+//
+// PAGI_CHAOS holds a spec of per-boundary rules:
+//
+//	PAGI_CHAOS="provider:latency=500ms,error=0.1,status=429;rag:error=0.5;tool:malformed=0.2;redis:error=1"
+//
+// Supported keys per boundary:
+//
+//	latency   fixed delay added before the call (Go duration)
+//	jitter    additional random delay in [0, jitter)
+//	error     probability [0,1] that the call fails with ErrInjected
+//	malformed probability [0,1] that a successful payload is corrupted
+//	status    HTTP-style status attached to injected errors (e.g. 429, 503)
+//
+// The boundary "*" applies to every boundary without its own rule. An empty,
+// "0", "false" or "off" value disables chaos entirely. PAGI_CHAOS_SEED makes
+// the random decisions reproducible.
+package chaos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Boundary identifies an integration point where faults can be injected.
+type Boundary string
+
+const (
+	Provider Boundary = "provider"
+	RAG      Boundary = "rag"
+	Tool     Boundary = "tool"
+	Redis    Boundary = "redis"
+)
+
+// ErrInjected is the sentinel wrapped by every injected failure.
+var ErrInjected = errors.New("chaos: injected fault")
+
+// Rule describes the faults injected at a single boundary.
+type Rule struct {
+	Latency       time.Duration
+	Jitter        time.Duration
+	ErrorRate     float64
+	MalformedRate float64
+	Status        int
+}
+
+// FaultError is returned by Inject when a failure is injected.
+type FaultError struct {
+	Boundary Boundary
+	Status   int
+}
+
+func (e *FaultError) Error() string {
+	if e.Status > 0 {
+		return fmt.Sprintf("chaos: injected fault at %s boundary (status %d)", e.Boundary, e.Status)
+	}
+	return fmt.Sprintf("chaos: injected fault at %s boundary", e.Boundary)
+}
+
+func (e *FaultError) Unwrap() error { return ErrInjected }
+
+// Injector applies rules. A nil *Injector is valid and never injects anything.
+type Injector struct {
+	rules map[Boundary]Rule
+
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+// FromEnv parses PAGI_CHAOS (and PAGI_CHAOS_SEED). It returns nil when chaos
+// mode is disabled.
+func FromEnv() (*Injector, error) {
+	spec := strings.TrimSpace(os.Getenv("PAGI_CHAOS"))
+	seed := time.Now().UnixNano()
+	if v := os.Getenv("PAGI_CHAOS_SEED"); v != "" {
+		parsed, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid PAGI_CHAOS_SEED %q: %w", v, err)
+		}
+		seed = parsed
+	}
+	return Parse(spec, seed)
+}
+
+// Parse builds an Injector from a spec string. It returns nil for a disabled spec.
+func Parse(spec string, seed int64) (*Injector, error) {
+	switch strings.ToLower(strings.TrimSpace(spec)) {
+	case "", "0", "false", "off", "no":
+		return nil, nil
+	}
+
+	rules := map[Boundary]Rule{}
+	for _, section := range strings.Split(spec, ";") {
+		section = strings.TrimSpace(section)
+		if section == "" {
+			continue
+		}
+		name, body, ok := strings.Cut(section, ":")
+		if !ok {
+			return nil, fmt.Errorf("chaos spec %q: expected <boundary>:<key>=<value>,...", section)
+		}
+		var rule Rule
+		for _, kv := range strings.Split(body, ",") {
+			kv = strings.TrimSpace(kv)
+			if kv == "" {
+				continue
+			}
+			key, value, ok := strings.Cut(kv, "=")
+			if !ok {
+				return nil, fmt.Errorf("chaos spec %q: expected key=value, got %q", section, kv)
+			}
+			if err := rule.set(strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)); err != nil {
+				return nil, fmt.Errorf("chaos spec %q: %w", section, err)
+			}
+		}
+		rules[Boundary(strings.ToLower(strings.TrimSpace(name)))] = rule
+	}
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	return &Injector{rules: rules, rnd: rand.New(rand.NewSource(seed))}, nil
+}
+
+func (r *Rule) set(key, value string) error {
+	var err error
+	switch key {
+	case "latency":
+		r.Latency, err = time.ParseDuration(value)
+	case "jitter":
+		r.Jitter, err = time.ParseDuration(value)
+	case "error":
+		r.ErrorRate, err = parseRate(value)
+	case "malformed":
+		r.MalformedRate, err = parseRate(value)
+	case "status":
+		r.Status, err = strconv.Atoi(value)
+	default:
+		return fmt.Errorf("unknown key %q", key)
+	}
+	if err != nil {
+		return fmt.Errorf("%s=%q: %w", key, value, err)
+	}
+	return nil
+}
+
+func parseRate(v string) (float64, error) {
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, err
+	}
+	if f < 0 || f > 1 {
+		return 0, fmt.Errorf("rate must be within [0,1]")
+	}
+	return f, nil
+}
+
+// Enabled reports whether any rule is configured.
+func (i *Injector) Enabled() bool {
+	return i != nil && len(i.rules) > 0
+}
+
+// Describe returns a compact, loggable representation of the active rules.
+func (i *Injector) Describe() map[string]string {
+	out := map[string]string{}
+	if i == nil {
+		return out
+	}
+	for b, r := range i.rules {
+		out[string(b)] = fmt.Sprintf("latency=%s jitter=%s error=%.2f malformed=%.2f status=%d",
+			r.Latency, r.Jitter, r.ErrorRate, r.MalformedRate, r.Status)
+	}
+	return out
+}
+
+func (i *Injector) rule(b Boundary) (Rule, bool) {
+	if i == nil {
+		return Rule{}, false
+	}
+	if r, ok := i.rules[b]; ok {
+		return r, true
+	}
+	r, ok := i.rules["*"]
+	return r, ok
+}
+
+func (i *Injector) float() float64 {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.rnd.Float64()
+}
+
+// Inject applies the boundary's latency and possibly returns an injected
+// *FaultError. It honors ctx cancellation while sleeping.
+func (i *Injector) Inject(ctx context.Context, b Boundary) error {
+	r, ok := i.rule(b)
+	if !ok {
+		return nil
+	}
+
+	delay := r.Latency
+	if r.Jitter > 0 {
+		delay += time.Duration(i.float() * float64(r.Jitter))
+	}
+	if delay > 0 {
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+
+	if r.ErrorRate > 0 && i.float() < r.ErrorRate {
+		return &FaultError{Boundary: b, Status: r.Status}
+	}
+	return nil
+}
+
+// Malform corrupts payload with the boundary's malformed rate, returning the
+// (possibly unchanged) payload and whether it was corrupted.
+func (i *Injector) Malform(b Boundary, payload string) (string, bool) {
+	r, ok := i.rule(b)
+	if !ok || r.MalformedRate <= 0 || i.float() >= r.MalformedRate {
+		return payload, false
+	}
+
+	switch int(i.float() * 3) {
+	case 0:
+		// Truncated mid-document (common with dropped connections / token limits).
+		return payload[:len(payload)/2], true
+	case 1:
+		// Chatty model that ignores "STRICT JSON only".
+		return "Sure! Here is what you asked for:\n" + payload + "\nLet me know if you need anything else.", true
+	default:
+		return "{\"chaos\": <<not json>>", true
+	}
+}
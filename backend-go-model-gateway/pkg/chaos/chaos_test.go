@@ -0,0 +1,65 @@
+package chaos
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParse_DisabledSpecsReturnNil(t *testing.T) {
+	for _, spec := range []string{"", "off", "0", "false"} {
+		inj, err := Parse(spec, 1)
+		if err != nil || inj != nil {
+			t.Fatalf("Parse(%q) = %v, %v; want nil, nil", spec, inj, err)
+		}
+		if inj.Enabled() {
+			t.Fatalf("nil injector must report disabled")
+		}
+	}
+}
+
+func TestParse_RejectsInvalidRules(t *testing.T) {
+	for _, spec := range []string{"provider", "provider:error=2", "rag:latency=soon", "tool:bogus=1"} {
+		if _, err := Parse(spec, 1); err == nil {
+			t.Fatalf("Parse(%q): expected error", spec)
+		}
+	}
+}
+
+func TestInject_AlwaysFailingBoundaryCarriesStatus(t *testing.T) {
+	inj, err := Parse("provider:error=1,status=429;rag:latency=1ms", 42)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	err = inj.Inject(context.Background(), Provider)
+	var fault *FaultError
+	if !errors.As(err, &fault) || fault.Status != 429 || !errors.Is(err, ErrInjected) {
+		t.Fatalf("expected injected 429 fault, got %v", err)
+	}
+
+	start := time.Now()
+	if err := inj.Inject(context.Background(), RAG); err != nil {
+		t.Fatalf("rag boundary should only add latency, got %v", err)
+	}
+	if time.Since(start) < time.Millisecond {
+		t.Fatalf("expected injected latency")
+	}
+
+	if err := inj.Inject(context.Background(), Tool); err != nil {
+		t.Fatalf("unconfigured boundary must not inject, got %v", err)
+	}
+}
+
+func TestMalform_CorruptsPayload(t *testing.T) {
+	inj, err := Parse("*:malformed=1", 7)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	in := `{"steps":["a","b"]}`
+	out, ok := inj.Malform(Tool, in)
+	if !ok || out == in {
+		t.Fatalf("expected payload to be corrupted, got %q", out)
+	}
+}
@@ -0,0 +1,236 @@
+// Package client is a typed Go client for the agent planner's HTTP API and
+// the model gateway, for services and tools that would otherwise hand-roll
+// requests against them:
+//
+//	planner := client.NewPlanner("http://localhost:8585", client.Options{APIKey: key})
+//	resp, err := planner.Plan(ctx, client.PlanRequest{Prompt: "what is on my calendar today?", SessionID: "s1"})
+//
+// Every call sends the context's trace ID (WithTraceID) as X-Trace-ID, and
+// HTTP calls are traced with otelhttp. Failed calls return an *Error.
+//
+// Calls that change nothing, or that can safely run twice (GET, DELETE and
+// the gateway's GetPlan), are retried on network errors, 429, 502, 503 and
+// 504 (gRPC Unavailable and ResourceExhausted), with exponential backoff.
+// POST /plan and the other writes are not: a retried run would run twice.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"backend-go-model-gateway/pkg/envelope"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// TraceHeader carries the trace ID on HTTP requests; gRPC calls send it as
+// x-trace-id metadata.
+const TraceHeader = "X-Trace-ID"
+
+// Defaults for Options.
+const (
+	DefaultRetries = 2
+	DefaultBackoff = 200 * time.Millisecond
+)
+
+// Options configures a client. The zero value is usable.
+type Options struct {
+	// APIKey is sent as X-API-Key (the planner's PAGI_API_KEY).
+	APIKey string
+	// HTTPClient sends the requests. Its transport is wrapped with otelhttp;
+	// the default has no timeout, so calls are bounded by their context.
+	HTTPClient *http.Client
+	// Retries is how often a retryable call is retried (default
+	// DefaultRetries; negative disables retries).
+	Retries int
+	// Backoff is the wait before the first retry, doubled for each next one
+	// (default DefaultBackoff).
+	Backoff time.Duration
+}
+
+func (o Options) withDefaults() Options {
+	if o.Retries == 0 {
+		o.Retries = DefaultRetries
+	}
+	o.Retries = max(o.Retries, 0)
+	if o.Backoff <= 0 {
+		o.Backoff = DefaultBackoff
+	}
+	base := o.HTTPClient
+	if base == nil {
+		base = &http.Client{}
+	}
+	traced := *base
+	traced.Transport = otelhttp.NewTransport(base.Transport)
+	if base.Transport == nil {
+		traced.Transport = otelhttp.NewTransport(http.DefaultTransport)
+	}
+	o.HTTPClient = &traced
+	return o
+}
+
+// Error is a failed call: an HTTP status other than 2xx, with the message
+// and code of the service's error envelope when it sent one.
+type Error struct {
+	Method     string
+	URL        string
+	StatusCode int
+	// Code is the envelope's error.code, e.g. "not_found".
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("%s %s: %s (HTTP %d)", e.Method, e.URL, e.Message, e.StatusCode)
+	}
+	return fmt.Sprintf("%s %s: HTTP %d", e.Method, e.URL, e.StatusCode)
+}
+
+// IsNotFound reports whether err is a 404 *Error.
+func IsNotFound(err error) bool {
+	var e *Error
+	return errors.As(err, &e) && e.StatusCode == http.StatusNotFound
+}
+
+type traceIDKey struct{}
+
+// WithTraceID returns ctx carrying the trace ID sent with each call.
+func WithTraceID(ctx context.Context, traceID string) context.Context {
+	return context.WithValue(ctx, traceIDKey{}, traceID)
+}
+
+// TraceID returns the trace ID set with WithTraceID, or "".
+func TraceID(ctx context.Context) string {
+	id, _ := ctx.Value(traceIDKey{}).(string)
+	return id
+}
+
+// httpClient is what the planner and gateway clients share for HTTP calls.
+type httpClient struct {
+	baseURL string
+	opts    Options
+}
+
+func newHTTPClient(baseURL string, opts Options) httpClient {
+	return httpClient{baseURL: strings.TrimRight(baseURL, "/"), opts: opts.withDefaults()}
+}
+
+// retryableStatus reports whether an idempotent call is worth retrying.
+func retryableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// do sends a request to path and decodes the response into out (when
+// non-nil), unwrapping the envelope (see pkg/envelope). A *[]byte out
+// receives the raw body, for endpoints that only use JSON for errors. body is
+// encoded as JSON, except for a json.RawMessage, which is sent as is.
+func (c httpClient) do(ctx context.Context, method, path string, body, out any) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if raw, ok := body.(json.RawMessage); ok {
+			payload = raw
+		} else if payload, err = json.Marshal(body); err != nil {
+			return fmt.Errorf("encode request: %w", err)
+		}
+	}
+	url := c.baseURL + path
+	attempts := 1
+	if method == http.MethodGet || method == http.MethodDelete {
+		attempts += c.opts.Retries
+	}
+	wait := c.opts.Backoff
+	for attempt := 1; ; attempt++ {
+		raw, status, err := c.send(ctx, method, url, payload)
+		retry := (err != nil && ctx.Err() == nil) || (err == nil && retryableStatus(status))
+		if !retry || attempt >= attempts {
+			if err != nil {
+				return err
+			}
+			return decode(method, url, status, raw, out)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+}
+
+// send makes one attempt.
+func (c httpClient) send(ctx context.Context, method, url string, payload []byte) ([]byte, int, error) {
+	var reader io.Reader
+	if payload != nil {
+		reader = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return nil, 0, err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.opts.APIKey != "" {
+		req.Header.Set("X-API-Key", c.opts.APIKey)
+	}
+	if id := TraceID(ctx); id != "" {
+		req.Header.Set(TraceHeader, id)
+	}
+	resp, err := c.opts.HTTPClient.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, fmt.Errorf("read response: %w", err)
+	}
+	return raw, resp.StatusCode, nil
+}
+
+func decode(method, url string, status int, raw []byte, out any) error {
+	data, apiErr, enveloped := envelope.Unwrap(raw)
+	if status >= 300 {
+		e := &Error{Method: method, URL: url, StatusCode: status}
+		if apiErr != nil {
+			e.Code, e.Message = apiErr.Code, apiErr.Message
+		} else {
+			var legacy struct {
+				Error string `json:"error"`
+			}
+			if json.Unmarshal(raw, &legacy) == nil && legacy.Error != "" {
+				e.Message = legacy.Error
+			} else {
+				e.Message = strings.TrimSpace(string(raw))
+			}
+		}
+		return e
+	}
+	if out == nil {
+		return nil
+	}
+	if b, ok := out.(*[]byte); ok {
+		*b = raw
+		return nil
+	}
+	if enveloped {
+		raw = data
+	}
+	if err := json.Unmarshal(raw, out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
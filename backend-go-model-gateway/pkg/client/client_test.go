@@ -0,0 +1,138 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"backend-go-model-gateway/pkg/envelope"
+	pb "backend-go-model-gateway/proto/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestPlanner_PlanAndErrors(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.Header.Get("X-API-Key") != "k" || r.Header.Get(TraceHeader) != "trace-1" {
+			envelope.WriteError(w, r, http.StatusUnauthorized, "missing key or trace")
+			return
+		}
+		var req PlanRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if req.Prompt == "fail" {
+			envelope.WriteError(w, r, http.StatusServiceUnavailable, "planner busy")
+			return
+		}
+		envelope.WriteData(w, r, http.StatusOK, map[string]string{"result": "plan for " + req.SessionID})
+	}))
+	defer srv.Close()
+	p := NewPlanner(srv.URL+"/", Options{APIKey: "k", Backoff: time.Millisecond})
+	ctx := WithTraceID(context.Background(), "trace-1")
+
+	resp, err := p.Plan(ctx, PlanRequest{Prompt: "hi", SessionID: "s1"})
+	if err != nil || resp.Result != "plan for s1" {
+		t.Fatalf("Plan = %+v, %v", resp, err)
+	}
+
+	// A failed run is not retried: it may have done work.
+	calls = 0
+	_, err = p.Plan(ctx, PlanRequest{Prompt: "fail"})
+	var e *Error
+	if !errors.As(err, &e) || e.StatusCode != http.StatusServiceUnavailable || e.Code != "service_unavailable" || e.Message != "planner busy" || calls != 1 {
+		t.Fatalf("Plan error = %v after %d calls", err, calls)
+	}
+}
+
+func TestPlanner_RetriesReads(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		switch {
+		case r.URL.Path == "/sessions/missing/data":
+			envelope.WriteError(w, r, http.StatusNotFound, "unknown session")
+		case calls < 3:
+			envelope.WriteError(w, r, http.StatusBadGateway, "audit db busy")
+		default:
+			if r.URL.Query().Get("session_id") != "s1" || r.URL.Query().Get("limit") != "5" {
+				envelope.WriteError(w, r, http.StatusBadRequest, "bad query "+r.URL.RawQuery)
+				return
+			}
+			envelope.WriteData(w, r, http.StatusOK, map[string]any{"entries": []AuditEntry{{ID: 7, SessionID: "s1", EventType: "PLAN_END"}}, "count": 1})
+		}
+	}))
+	defer srv.Close()
+	p := NewPlanner(srv.URL, Options{Backoff: time.Millisecond})
+
+	entries, err := p.Audit(context.Background(), AuditQuery{SessionID: "s1", Limit: 5})
+	if err != nil || len(entries) != 1 || entries[0].ID != 7 || calls != 3 {
+		t.Fatalf("Audit = %+v, %v after %d calls", entries, err, calls)
+	}
+
+	calls = 10
+	if _, err := p.ForgetSession(context.Background(), "missing"); !IsNotFound(err) || calls != 11 {
+		t.Fatalf("ForgetSession error = %v after %d calls", err, calls-10)
+	}
+}
+
+type fakeGateway struct {
+	pb.UnimplementedModelGatewayServer
+	failures int
+	md       metadata.MD
+}
+
+func (f *fakeGateway) GetPlan(ctx context.Context, in *pb.PlanRequest) (*pb.PlanResponse, error) {
+	if f.failures > 0 {
+		f.failures--
+		return nil, status.Error(codes.Unavailable, "no provider")
+	}
+	f.md, _ = metadata.FromIncomingContext(ctx)
+	return &pb.PlanResponse{Plan: "{}", ModelName: "mock"}, nil
+}
+
+func TestGateway_GetPlanRetriesAndTags(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake := &fakeGateway{failures: 2}
+	gs := grpc.NewServer()
+	pb.RegisterModelGatewayServer(gs, fake)
+	go func() { _ = gs.Serve(lis) }()
+	defer gs.Stop()
+
+	conn, err := DialGateway(lis.Addr().String(), insecure.NewCredentials())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	g := NewGateway(conn, "", Options{Backoff: time.Millisecond})
+
+	ctx, cancel := context.WithTimeout(WithTraceID(context.Background(), "trace-2"), 5*time.Second)
+	defer cancel()
+	resp, err := g.GetPlan(ctx, "s1", &pb.PlanRequest{Prompt: "hi"})
+	if err != nil || resp.GetModelName() != "mock" {
+		t.Fatalf("GetPlan = %v, %v", resp, err)
+	}
+	if got := fake.md.Get("x-session-id"); len(got) != 1 || got[0] != "s1" {
+		t.Fatalf("x-session-id = %v", got)
+	}
+	if got := fake.md.Get("x-trace-id"); len(got) != 1 || got[0] != "trace-2" {
+		t.Fatalf("x-trace-id = %v", got)
+	}
+
+	fake.failures = DefaultRetries + 1
+	if _, err := g.GetPlan(ctx, "", &pb.PlanRequest{Prompt: "hi"}); status.Code(err) != codes.Unavailable {
+		t.Fatalf("GetPlan after retries = %v", err)
+	}
+}
@@ -0,0 +1,107 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	pb "backend-go-model-gateway/proto/proto"
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Gateway calls the model gateway: GetPlan over gRPC and the vector-test
+// endpoint over HTTP.
+type Gateway struct {
+	rpc  pb.ModelGatewayClient
+	http httpClient
+	opts Options
+}
+
+// NewGateway returns a client using conn for gRPC (a *grpc.ClientConn or a
+// grpcpool.Pool; see DialGateway) and httpURL, e.g. "http://localhost:8005",
+// for HTTP. conn may be nil when only HTTP is used. Options.APIKey is not
+// sent to the gateway.
+func NewGateway(conn grpc.ClientConnInterface, httpURL string, opts Options) *Gateway {
+	opts.APIKey = ""
+	h := newHTTPClient(httpURL, opts)
+	return &Gateway{rpc: pb.NewModelGatewayClient(conn), http: h, opts: h.opts}
+}
+
+// DialGateway connects to the gateway's gRPC address with creds, traced with
+// otelgrpc. The caller closes the connection.
+func DialGateway(target string, creds credentials.TransportCredentials) (*grpc.ClientConn, error) {
+	return grpc.NewClient(target, grpc.WithTransportCredentials(creds), grpc.WithStatsHandler(otelgrpc.NewClientHandler()))
+}
+
+// GetPlan asks the gateway for one plan, on behalf of sessionID when it is
+// set. Unavailable and ResourceExhausted errors are retried.
+func (g *Gateway) GetPlan(ctx context.Context, sessionID string, req *pb.PlanRequest) (*pb.PlanResponse, error) {
+	md := metadata.MD{}
+	if sessionID != "" {
+		md.Set("x-session-id", sessionID)
+	}
+	if id := TraceID(ctx); id != "" {
+		md.Set("x-trace-id", id)
+	}
+	ctx = metadata.NewOutgoingContext(ctx, metadata.Join(metadataFrom(ctx), md))
+	wait := g.opts.Backoff
+	for attempt := 0; ; attempt++ {
+		resp, err := g.rpc.GetPlan(ctx, req)
+		code := status.Code(err)
+		if err == nil || attempt >= g.opts.Retries || (code != codes.Unavailable && code != codes.ResourceExhausted) {
+			return resp, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+}
+
+func metadataFrom(ctx context.Context) metadata.MD {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	return md
+}
+
+// VectorMatch is a retrieval result of VectorTest.
+type VectorMatch struct {
+	ID            string  `json:"id"`
+	Score         float64 `json:"score"`
+	Text          string  `json:"text"`
+	Source        string  `json:"source"`
+	KnowledgeBase string  `json:"knowledge_base"`
+}
+
+// VectorQuery is a VectorTest query. K and KB are optional.
+type VectorQuery struct {
+	Query string
+	K     int
+	KB    string
+}
+
+// VectorTest runs a retrieval query against the gateway's RAG backend (GET
+// /api/v1/vector-test), returning the top K matches.
+func (g *Gateway) VectorTest(ctx context.Context, q VectorQuery) ([]VectorMatch, error) {
+	v := url.Values{"query": {q.Query}}
+	if q.K > 0 {
+		v.Set("k", strconv.Itoa(q.K))
+	}
+	if q.KB != "" {
+		v.Set("kb", q.KB)
+	}
+	var matches []VectorMatch
+	if err := g.http.do(ctx, http.MethodGet, "/api/v1/vector-test?"+v.Encode(), nil, &matches); err != nil {
+		return nil, err
+	}
+	return matches, nil
+}
@@ -0,0 +1,284 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"backend-go-model-gateway/pkg/ragfilter"
+)
+
+// Planner calls the agent planner's HTTP API.
+type Planner struct {
+	http httpClient
+}
+
+// NewPlanner returns a client for the planner at baseURL, e.g.
+// "http://localhost:8585".
+func NewPlanner(baseURL string, opts Options) *Planner {
+	return &Planner{http: newHTTPClient(baseURL, opts)}
+}
+
+// Resource is a multi-modal input of a plan request.
+type Resource struct {
+	Type string `json:"type"`
+	URI  string `json:"uri"`
+}
+
+// PlanRequest is the body of POST /plan.
+type PlanRequest struct {
+	Prompt    string            `json:"prompt"`
+	SessionID string            `json:"session_id"`
+	Resources []Resource        `json:"resources,omitempty"`
+	RAGFilter *ragfilter.Filter `json:"rag_filter,omitempty"`
+	Persona   string            `json:"persona,omitempty"`
+	Tags      []string          `json:"tags,omitempty"`
+	// Priority is "interactive" (default) or "batch".
+	Priority string `json:"priority,omitempty"`
+}
+
+// PlanResponse is the result of POST /plan. Degraded is the planner's turn
+// latency report, when a turn went over its budget.
+type PlanResponse struct {
+	Result   string          `json:"result"`
+	Degraded json.RawMessage `json:"degraded,omitempty"`
+}
+
+// Plan runs the agent loop for a prompt. It is not retried.
+func (p *Planner) Plan(ctx context.Context, req PlanRequest) (*PlanResponse, error) {
+	var resp PlanResponse
+	if err := p.http.do(ctx, http.MethodPost, "/plan", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// AuditEntry is one audit log row.
+type AuditEntry struct {
+	ID        int64           `json:"id"`
+	TraceID   string          `json:"trace_id"`
+	SessionID string          `json:"session_id"`
+	Principal string          `json:"principal,omitempty"`
+	Timestamp time.Time       `json:"timestamp"`
+	EventType string          `json:"event_type"`
+	Data      json.RawMessage `json:"data,omitempty"`
+	Erased    bool            `json:"erased,omitempty"`
+}
+
+// AuditQuery narrows Audit. Zero-valued fields are ignored.
+type AuditQuery struct {
+	SessionID string
+	TraceID   string
+	EventType string
+	Principal string
+	Since     time.Time
+	Until     time.Time
+	Limit     int
+}
+
+// Audit queries the audit log (GET /audit).
+func (p *Planner) Audit(ctx context.Context, q AuditQuery) ([]AuditEntry, error) {
+	v := url.Values{}
+	for name, value := range map[string]string{"session_id": q.SessionID, "trace_id": q.TraceID, "event_type": q.EventType, "principal": q.Principal} {
+		if value != "" {
+			v.Set(name, value)
+		}
+	}
+	setTime(v, "since", q.Since)
+	setTime(v, "until", q.Until)
+	if q.Limit > 0 {
+		v.Set("limit", strconv.Itoa(q.Limit))
+	}
+	var resp struct {
+		Entries []AuditEntry `json:"entries"`
+	}
+	if err := p.http.do(ctx, http.MethodGet, "/audit?"+v.Encode(), nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Entries, nil
+}
+
+// AuditStats is the planner's overview of the audit log over a window.
+type AuditStats struct {
+	Since  time.Time `json:"since"`
+	Until  time.Time `json:"until"`
+	Runs   RunStats  `json:"runs"`
+	PerDay []struct {
+		Date string `json:"date"`
+		RunStats
+	} `json:"per_day"`
+	Tools []struct {
+		Tool   string `json:"tool"`
+		Calls  int64  `json:"calls"`
+		Errors int64  `json:"errors"`
+	} `json:"tools"`
+	FailureReasons []struct {
+		Reason string `json:"reason"`
+		Count  int64  `json:"count"`
+	} `json:"failure_reasons"`
+}
+
+// RunStats counts agent runs and their turns.
+type RunStats struct {
+	Total    int64   `json:"total"`
+	Failed   int64   `json:"failed"`
+	MaxTurns int64   `json:"max_turns"`
+	AvgTurns float64 `json:"avg_turns"`
+}
+
+// AuditStats aggregates the audit log over [since, until) (GET
+// /audit/stats). Zero times use the planner's defaults: the last 30 days.
+func (p *Planner) AuditStats(ctx context.Context, since, until time.Time) (*AuditStats, error) {
+	v := url.Values{}
+	setTime(v, "since", since)
+	setTime(v, "until", until)
+	var resp AuditStats
+	if err := p.http.do(ctx, http.MethodGet, "/audit/stats?"+v.Encode(), nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// BundleRequest selects the rows of a compliance bundle.
+type BundleRequest struct {
+	SessionID string     `json:"session_id,omitempty"`
+	Since     *time.Time `json:"since,omitempty"`
+	Until     *time.Time `json:"until,omitempty"`
+}
+
+// AuditBundle downloads a signed compliance bundle, a zip archive (POST
+// /audit/bundle).
+func (p *Planner) AuditBundle(ctx context.Context, req BundleRequest) ([]byte, error) {
+	var zipped []byte
+	if err := p.http.do(ctx, http.MethodPost, "/audit/bundle", req, &zipped); err != nil {
+		return nil, err
+	}
+	return zipped, nil
+}
+
+// SessionSummary is a session found by Sessions.
+type SessionSummary struct {
+	SessionID string    `json:"session_id"`
+	Tags      []string  `json:"tags"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+	Events    int       `json:"events"`
+}
+
+// SessionQuery narrows Sessions. Zero-valued fields are ignored.
+type SessionQuery struct {
+	// Tags must all be set on a session.
+	Tags  []string
+	Since time.Time
+	Until time.Time
+	Limit int
+}
+
+// Sessions lists sessions by tag and activity, most recent first (GET
+// /sessions).
+func (p *Planner) Sessions(ctx context.Context, q SessionQuery) ([]SessionSummary, error) {
+	v := url.Values{"tag": q.Tags}
+	setTime(v, "since", q.Since)
+	setTime(v, "until", q.Until)
+	if q.Limit > 0 {
+		v.Set("limit", strconv.Itoa(q.Limit))
+	}
+	var resp struct {
+		Sessions []SessionSummary `json:"sessions"`
+	}
+	if err := p.http.do(ctx, http.MethodGet, "/sessions?"+v.Encode(), nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Sessions, nil
+}
+
+// SessionTags returns a session's tags.
+func (p *Planner) SessionTags(ctx context.Context, sessionID string) ([]string, error) {
+	return p.tags(ctx, http.MethodGet, sessionTagsPath(sessionID), nil)
+}
+
+// AddSessionTags tags a session and returns all its tags.
+func (p *Planner) AddSessionTags(ctx context.Context, sessionID string, tags ...string) ([]string, error) {
+	return p.tags(ctx, http.MethodPost, sessionTagsPath(sessionID), map[string][]string{"tags": tags})
+}
+
+// RemoveSessionTag removes a tag and returns the session's remaining tags.
+func (p *Planner) RemoveSessionTag(ctx context.Context, sessionID, tag string) ([]string, error) {
+	return p.tags(ctx, http.MethodDelete, sessionTagsPath(sessionID)+"/"+url.PathEscape(tag), nil)
+}
+
+func sessionTagsPath(sessionID string) string {
+	return "/sessions/" + url.PathEscape(sessionID) + "/tags"
+}
+
+func (p *Planner) tags(ctx context.Context, method, path string, body any) ([]string, error) {
+	var resp struct {
+		Tags []string `json:"tags"`
+	}
+	if err := p.http.do(ctx, method, path, body, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Tags, nil
+}
+
+// ExportSession downloads a session archive (GET /sessions/{id}/export).
+func (p *Planner) ExportSession(ctx context.Context, sessionID string) ([]byte, error) {
+	var archive []byte
+	if err := p.http.do(ctx, http.MethodGet, "/sessions/"+url.PathEscape(sessionID)+"/export", nil, &archive); err != nil {
+		return nil, err
+	}
+	return archive, nil
+}
+
+// ImportResult is what ImportSession recreated.
+type ImportResult struct {
+	SessionID string `json:"session_id"`
+	From      string `json:"from"`
+	History   int    `json:"history"`
+	Playbooks int    `json:"playbooks"`
+	AuditRows int    `json:"audit_rows"`
+}
+
+// ImportSession recreates a session from an archive (POST /sessions/import),
+// under as when it is set.
+func (p *Planner) ImportSession(ctx context.Context, archive []byte, as string) (*ImportResult, error) {
+	path := "/sessions/import"
+	if as != "" {
+		path += "?session_id=" + url.QueryEscape(as)
+	}
+	var resp ImportResult
+	if err := p.http.do(ctx, http.MethodPost, path, json.RawMessage(archive), &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// DeletionReceipt is the planner's signed record of a forgotten session.
+type DeletionReceipt struct {
+	Version     int              `json:"version"`
+	SessionID   string           `json:"session_id"`
+	DeletedAt   time.Time        `json:"deleted_at"`
+	RequestedBy string           `json:"requested_by,omitempty"`
+	Deleted     map[string]int64 `json:"deleted"`
+	KeyErased   bool             `json:"key_erased"`
+	PublicKey   string           `json:"public_key"`
+	Signature   string           `json:"signature,omitempty"`
+}
+
+// ForgetSession deletes a session everywhere (DELETE /sessions/{id}/data).
+func (p *Planner) ForgetSession(ctx context.Context, sessionID string) (*DeletionReceipt, error) {
+	var receipt DeletionReceipt
+	if err := p.http.do(ctx, http.MethodDelete, "/sessions/"+url.PathEscape(sessionID)+"/data", nil, &receipt); err != nil {
+		return nil, err
+	}
+	return &receipt, nil
+}
+
+func setTime(v url.Values, name string, t time.Time) {
+	if !t.IsZero() {
+		v.Set(name, t.UTC().Format(time.RFC3339))
+	}
+}
@@ -0,0 +1,91 @@
+// Package discovery adds optional service discovery for gRPC downstreams.
+//
+// Address env vars (MODEL_GATEWAY_ADDR, MEMORY_GRPC_ADDR, RAG_GRPC_ADDR, ...)
+// keep accepting a static host:port. Importing this package additionally
+// registers two gRPC resolver schemes so the same vars can name a service:
+//
+//	dnssrv:///_grpc._tcp.model-gateway.internal   DNS SRV lookup
+//	consul:///model-gateway                       Consul health API (passing instances)
+//	consul:///model-gateway?tag=grpc&dc=dc1       with tag / datacenter filters
+//
+// Both resolvers re-resolve every PAGI_DISCOVERY_REFRESH_SECONDS (default 30)
+// and whenever gRPC reports a connection failure. Dialing through DialOptions
+// enables round_robin load-balancing, so every discovered replica receives
+// traffic; the built-in dns:/// scheme benefits from the same balancing when
+// a name resolves to several A records.
+//
+// Consul is reached at CONSUL_HTTP_ADDR (default 127.0.0.1:8500) with the
+// optional CONSUL_HTTP_TOKEN.
+package discovery
+
+import (
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+const (
+	SchemeSRV    = "dnssrv"
+	SchemeConsul = "consul"
+
+	defaultRefresh = 30 * time.Second
+)
+
+// serviceConfig enables client-side round-robin across resolved addresses.
+const serviceConfig = `{"loadBalancingConfig":[{"round_robin":{}}]}`
+
+// DialOptions returns the options every discovery-aware dial should include.
+// They are harmless for static host:port targets (a single address).
+func DialOptions() []grpc.DialOption {
+	return []grpc.DialOption{grpc.WithDefaultServiceConfig(serviceConfig)}
+}
+
+// IsDiscoveryTarget reports whether target uses one of the schemes registered
+// by this package.
+func IsDiscoveryTarget(target string) bool {
+	return strings.HasPrefix(target, SchemeSRV+":") || strings.HasPrefix(target, SchemeConsul+":")
+}
+
+// ServerName returns the host name a TLS client should verify for target.
+//
+// For host:port targets this is the host. For discovery targets it is the
+// logical service name (the Consul service, or the SRV owner name with the
+// _service._proto labels stripped), which is what certificates for a
+// replicated service are expected to carry as SAN.
+func ServerName(target string) string {
+	if u, err := url.Parse(target); err == nil && u.Scheme != "" && u.Opaque == "" && strings.HasPrefix(target, u.Scheme+"://") {
+		name := strings.TrimPrefix(u.Path, "/")
+		if name == "" {
+			name = u.Host
+		}
+		if u.Scheme == SchemeSRV {
+			labels := strings.Split(name, ".")
+			for len(labels) > 1 && strings.HasPrefix(labels[0], "_") {
+				labels = labels[1:]
+			}
+			name = strings.Join(labels, ".")
+		}
+		if host, _, err := net.SplitHostPort(name); err == nil {
+			return host
+		}
+		return name
+	}
+	if host, _, err := net.SplitHostPort(target); err == nil {
+		return host
+	}
+	return target
+}
+
+func refreshInterval() time.Duration {
+	if v := os.Getenv("PAGI_DISCOVERY_REFRESH_SECONDS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return time.Duration(n) * time.Second
+		}
+	}
+	return defaultRefresh
+}
@@ -0,0 +1,104 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/peer"
+)
+
+func TestServerName(t *testing.T) {
+	cases := map[string]string{
+		"model-gateway:50051":                             "model-gateway",
+		"consul:///model-gateway":                         "model-gateway",
+		"consul:///model-gateway?tag=grpc":                "model-gateway",
+		"dnssrv:///_grpc._tcp.model-gateway.internal":     "model-gateway.internal",
+		"dns:///model-gateway.svc.cluster.local:50051":    "model-gateway.svc.cluster.local",
+		"consul://ignored-authority/memory-service?dc=eu": "memory-service",
+	}
+	for in, want := range cases {
+		if got := ServerName(in); got != want {
+			t.Errorf("ServerName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+// countingHealth records the local address each Check was served on.
+type countingHealth struct {
+	*health.Server
+	hits chan string
+}
+
+func (c countingHealth) Check(ctx context.Context, req *grpc_health_v1.HealthCheckRequest) (*grpc_health_v1.HealthCheckResponse, error) {
+	if p, ok := peer.FromContext(ctx); ok {
+		c.hits <- p.LocalAddr.String()
+	}
+	return c.Server.Check(ctx, req)
+}
+
+func startReplica(t *testing.T, hits chan string) int {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	s := grpc.NewServer()
+	grpc_health_v1.RegisterHealthServer(s, countingHealth{Server: health.NewServer(), hits: hits})
+	go func() { _ = s.Serve(lis) }()
+	t.Cleanup(s.Stop)
+	return lis.Addr().(*net.TCPAddr).Port
+}
+
+func TestConsulResolverRoundRobin(t *testing.T) {
+	hits := make(chan string, 64)
+	ports := []int{startReplica(t, hits), startReplica(t, hits)}
+
+	consul := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/health/service/model-gateway" || r.URL.Query().Get("passing") != "true" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `[{"Node":{"Address":"127.0.0.1"},"Service":{"Address":"","Port":%d}},`+
+			`{"Node":{"Address":"10.0.0.9"},"Service":{"Address":"127.0.0.1","Port":%d}}]`, ports[0], ports[1])
+	}))
+	t.Cleanup(consul.Close)
+	t.Setenv("CONSUL_HTTP_ADDR", consul.URL)
+
+	opts := append(DialOptions(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.NewClient("consul:///model-gateway", opts...)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	hc := grpc_health_v1.NewHealthClient(conn)
+	for i := 0; i < 10; i++ {
+		if _, err := hc.Check(ctx, &grpc_health_v1.HealthCheckRequest{}); err != nil {
+			t.Fatalf("Check #%d: %v", i, err)
+		}
+	}
+
+	seen := map[string]int{}
+	for i := 0; i < 10; i++ {
+		seen[<-hits]++
+	}
+	for _, p := range ports {
+		addr := net.JoinHostPort("127.0.0.1", strconv.Itoa(p))
+		if seen[addr] == 0 {
+			t.Fatalf("replica %s received no traffic; distribution=%v", addr, seen)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/resolver"
+)
+
+func init() {
+	resolver.Register(&builder{scheme: SchemeSRV, newLookup: newSRVLookup})
+	resolver.Register(&builder{scheme: SchemeConsul, newLookup: newConsulLookup})
+}
+
+// lookupFunc returns the current set of host:port addresses for a target.
+type lookupFunc func(ctx context.Context) ([]string, error)
+
+type builder struct {
+	scheme    string
+	newLookup func(target resolver.Target) (lookupFunc, error)
+}
+
+func (b *builder) Scheme() string { return b.scheme }
+
+func (b *builder) Build(target resolver.Target, cc resolver.ClientConn, _ resolver.BuildOptions) (resolver.Resolver, error) {
+	lookup, err := b.newLookup(target)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &pollingResolver{
+		cc:       cc,
+		lookup:   lookup,
+		interval: refreshInterval(),
+		ctx:      ctx,
+		cancel:   cancel,
+		resolveC: make(chan struct{}, 1),
+	}
+	r.wg.Add(1)
+	go r.run()
+	r.ResolveNow(resolver.ResolveNowOptions{})
+	return r, nil
+}
+
+// pollingResolver periodically re-runs lookup and pushes the result to gRPC.
+type pollingResolver struct {
+	cc       resolver.ClientConn
+	lookup   lookupFunc
+	interval time.Duration
+
+	ctx      context.Context
+	cancel   context.CancelFunc
+	resolveC chan struct{}
+	wg       sync.WaitGroup
+}
+
+func (r *pollingResolver) ResolveNow(resolver.ResolveNowOptions) {
+	select {
+	case r.resolveC <- struct{}{}:
+	default:
+	}
+}
+
+func (r *pollingResolver) Close() {
+	r.cancel()
+	r.wg.Wait()
+}
+
+func (r *pollingResolver) run() {
+	defer r.wg.Done()
+	t := time.NewTicker(r.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-r.resolveC:
+		case <-t.C:
+		}
+		r.resolve()
+	}
+}
+
+func (r *pollingResolver) resolve() {
+	ctx, cancel := context.WithTimeout(r.ctx, 5*time.Second)
+	defer cancel()
+	addrs, err := r.lookup(ctx)
+	if err != nil {
+		if r.ctx.Err() == nil {
+			r.cc.ReportError(err)
+		}
+		return
+	}
+	if len(addrs) == 0 {
+		r.cc.ReportError(errors.New("discovery: no healthy instances"))
+		return
+	}
+	state := resolver.State{Addresses: make([]resolver.Address, 0, len(addrs))}
+	for _, a := range addrs {
+		state.Addresses = append(state.Addresses, resolver.Address{Addr: a})
+	}
+	_ = r.cc.UpdateState(state)
+}
+
+// targetName returns the service/record name from a resolver target
+// (the path of scheme:///name, or the host of scheme://name).
+func targetName(target resolver.Target) string {
+	name := strings.TrimPrefix(target.URL.Path, "/")
+	if name == "" {
+		name = target.URL.Host
+	}
+	return name
+}
+
+func newSRVLookup(target resolver.Target) (lookupFunc, error) {
+	name := targetName(target)
+	if name == "" {
+		return nil, fmt.Errorf("discovery: empty SRV name in %q", target.URL.String())
+	}
+	res := net.DefaultResolver
+	return func(ctx context.Context) ([]string, error) {
+		_, srvs, err := res.LookupSRV(ctx, "", "", name)
+		if err != nil {
+			return nil, fmt.Errorf("discovery: SRV %s: %w", name, err)
+		}
+		// Keep only the best (lowest) priority; gRPC round_robin ignores weights.
+		sort.Slice(srvs, func(i, j int) bool { return srvs[i].Priority < srvs[j].Priority })
+		out := make([]string, 0, len(srvs))
+		for _, s := range srvs {
+			if s.Priority != srvs[0].Priority {
+				break
+			}
+			out = append(out, net.JoinHostPort(strings.TrimSuffix(s.Target, "."), strconv.Itoa(int(s.Port))))
+		}
+		return out, nil
+	}, nil
+}
+
+func newConsulLookup(target resolver.Target) (lookupFunc, error) {
+	service := targetName(target)
+	if service == "" {
+		return nil, fmt.Errorf("discovery: empty Consul service in %q", target.URL.String())
+	}
+	base := os.Getenv("CONSUL_HTTP_ADDR")
+	if base == "" {
+		base = "127.0.0.1:8500"
+	}
+	if !strings.Contains(base, "://") {
+		base = "http://" + base
+	}
+	q := target.URL.Query()
+	params := url.Values{"passing": {"true"}}
+	if tag := q.Get("tag"); tag != "" {
+		params.Set("tag", tag)
+	}
+	if dc := q.Get("dc"); dc != "" {
+		params.Set("dc", dc)
+	}
+	endpoint := strings.TrimRight(base, "/") + "/v1/health/service/" + url.PathEscape(service) + "?" + params.Encode()
+	token := os.Getenv("CONSUL_HTTP_TOKEN")
+	client := &http.Client{Timeout: 5 * time.Second}
+
+	return func(ctx context.Context) ([]string, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+		if err != nil {
+			return nil, err
+		}
+		if token != "" {
+			req.Header.Set("X-Consul-Token", token)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("discovery: consul %s: %w", service, err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("discovery: consul %s: HTTP %d", service, resp.StatusCode)
+		}
+		var entries []struct {
+			Node struct {
+				Address string `json:"Address"`
+			} `json:"Node"`
+			Service struct {
+				Address string `json:"Address"`
+				Port    int    `json:"Port"`
+			} `json:"Service"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+			return nil, fmt.Errorf("discovery: consul %s: decode: %w", service, err)
+		}
+		out := make([]string, 0, len(entries))
+		for _, e := range entries {
+			host := e.Service.Address
+			if host == "" {
+				host = e.Node.Address
+			}
+			if host == "" || e.Service.Port == 0 {
+				continue
+			}
+			out = append(out, net.JoinHostPort(host, strconv.Itoa(e.Service.Port)))
+		}
+		return out, nil
+	}, nil
+}
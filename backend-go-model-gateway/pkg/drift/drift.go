@@ -0,0 +1,91 @@
+// Package drift compares the settings services must agree on, so config skew
+// between them is logged at startup instead of surfacing as runtime failures:
+// a KB the planner asks for that the gateway does not serve, a caller that
+// gives up before its callee answers, notifications published on a channel
+// nobody reads, or a trace ID sent under a header the callee ignores.
+//
+// Each service reports its Settings (the gateway in GetCapabilities, the
+// planner on GET /capabilities) and checks the services it calls with Check.
+package drift
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Settings are one service's side of the shared configuration. Empty fields
+// are unknown and not compared.
+type Settings struct {
+	Service string `json:"service"`
+	// KnowledgeBases are the KBs the service retrieves from (planner) or
+	// serves (gateway).
+	KnowledgeBases []string `json:"knowledge_bases,omitempty"`
+	// TimeoutSeconds is the longest the service takes to answer one request.
+	TimeoutSeconds int `json:"timeout_seconds,omitempty"`
+	// NotificationsChannel is the Redis channel notifications go through.
+	NotificationsChannel string `json:"notifications_channel,omitempty"`
+	// TraceHeader carries the request's trace ID to and from the service.
+	TraceHeader string `json:"trace_header,omitempty"`
+}
+
+// Mismatch is one setting two services disagree on.
+type Mismatch struct {
+	Setting string
+	Local   string
+	Remote  string
+	// Problem says what goes wrong at runtime.
+	Problem string
+}
+
+func (m Mismatch) String() string {
+	return fmt.Sprintf("%s: %s (local %s, remote %s)", m.Setting, m.Problem, m.Local, m.Remote)
+}
+
+// Check compares a caller's settings with those of a service it calls.
+// callTimeout is how long the caller waits for the callee (0: not compared).
+func Check(caller Settings, callTimeout time.Duration, callee Settings) []Mismatch {
+	var out []Mismatch
+	if len(caller.KnowledgeBases) > 0 && len(callee.KnowledgeBases) > 0 {
+		var missing []string
+		for _, kb := range caller.KnowledgeBases {
+			if !slices.Contains(callee.KnowledgeBases, kb) {
+				missing = append(missing, kb)
+			}
+		}
+		if len(missing) > 0 {
+			out = append(out, Mismatch{
+				Setting: "knowledge_bases",
+				Local:   strings.Join(caller.KnowledgeBases, ","),
+				Remote:  strings.Join(callee.KnowledgeBases, ","),
+				Problem: fmt.Sprintf("%s does not serve %s", callee.Service, strings.Join(missing, ",")),
+			})
+		}
+	}
+	if calleeTimeout := time.Duration(callee.TimeoutSeconds) * time.Second; callTimeout > 0 && calleeTimeout > 0 && callTimeout < calleeTimeout {
+		out = append(out, Mismatch{
+			Setting: "timeout",
+			Local:   callTimeout.String(),
+			Remote:  calleeTimeout.String(),
+			Problem: fmt.Sprintf("%s gives up before %s times out", caller.Service, callee.Service),
+		})
+	}
+	if caller.NotificationsChannel != "" && callee.NotificationsChannel != "" && caller.NotificationsChannel != callee.NotificationsChannel {
+		out = append(out, Mismatch{
+			Setting: "notifications_channel",
+			Local:   caller.NotificationsChannel,
+			Remote:  callee.NotificationsChannel,
+			Problem: "notifications are published on a channel the other side does not use",
+		})
+	}
+	if caller.TraceHeader != "" && callee.TraceHeader != "" && !strings.EqualFold(caller.TraceHeader, callee.TraceHeader) {
+		out = append(out, Mismatch{
+			Setting: "trace_header",
+			Local:   caller.TraceHeader,
+			Remote:  callee.TraceHeader,
+			Problem: "trace IDs are not propagated between the services",
+		})
+	}
+	return out
+}
@@ -0,0 +1,40 @@
+package drift
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCheck(t *testing.T) {
+	planner := Settings{Service: "agent-planner", KnowledgeBases: []string{"Mind-KB", "Domain-KB"}, NotificationsChannel: "pagi_notifications", TraceHeader: "X-Trace-ID"}
+	gateway := Settings{Service: "model-gateway", KnowledgeBases: []string{"Domain-KB", "Mind-KB", "Body-KB"}, TimeoutSeconds: 30, TraceHeader: "x-trace-id"}
+	if got := Check(planner, time.Minute, gateway); len(got) != 0 {
+		t.Fatalf("agreeing settings: %v", got)
+	}
+
+	gateway.KnowledgeBases = []string{"Domain-KB"}
+	gateway.TraceHeader = "X-Request-Id"
+	got := Check(planner, 10*time.Second, gateway)
+	want := []string{
+		"knowledge_bases: model-gateway does not serve Mind-KB (local Mind-KB,Domain-KB, remote Domain-KB)",
+		"timeout: agent-planner gives up before model-gateway times out (local 10s, remote 30s)",
+		"trace_header: trace IDs are not propagated between the services (local X-Trace-ID, remote X-Request-Id)",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("mismatches = %v", got)
+	}
+	for i := range want {
+		if got[i].String() != want[i] {
+			t.Errorf("mismatch %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+
+	notifier := Settings{Service: "notification-service", NotificationsChannel: "alerts"}
+	if got := Check(planner, 0, notifier); len(got) != 1 || got[0].Setting != "notifications_channel" {
+		t.Fatalf("channel mismatch = %v", got)
+	}
+	// Unknown settings are not compared.
+	if got := Check(planner, time.Second, Settings{Service: "bff"}); len(got) != 0 {
+		t.Fatalf("unknown settings: %v", got)
+	}
+}
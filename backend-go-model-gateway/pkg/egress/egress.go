@@ -0,0 +1,218 @@
+// Package egress enforces an outbound allowlist (hosts, CIDRs, URL schemes),
+// so a prompt-injected tool argument or resource URI cannot make a service
+// call arbitrary internal endpoints (SSRF).
+//
+// PAGI_EGRESS_ALLOW lists what may be reached, comma-separated:
+//
+//	api.openrouter.ai        exact host name
+//	*.svc.cluster.local      any subdomain
+//	10.0.0.0/8, 127.0.0.1    address ranges and single addresses
+//
+// Names are matched as written. A name that is not listed is resolved, and
+// the connection is allowed only when every address it resolves to is in a
+// listed range; the checked address is dialed, so DNS cannot be rebound
+// between check and connect. Everything else is refused. Unset, there is no
+// policy and every destination is allowed.
+//
+// PAGI_EGRESS_SCHEMES (default http,https) limits the schemes of URIs checked
+// with CheckURL, such as resource URIs handed to tools.
+package egress
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+)
+
+// ErrDenied is returned for destinations the policy does not allow.
+var ErrDenied = errors.New("egress denied")
+
+// Policy is an outbound allowlist. A nil Policy allows everything.
+type Policy struct {
+	hosts    []string // exact names
+	suffixes []string // ".example.com" for *.example.com
+	prefixes []netip.Prefix
+	schemes  []string
+
+	// lookup resolves names that are not listed; net.DefaultResolver by default.
+	lookup func(ctx context.Context, host string) ([]netip.Addr, error)
+}
+
+// FromEnv reads PAGI_EGRESS_ALLOW and PAGI_EGRESS_SCHEMES. It returns nil
+// when PAGI_EGRESS_ALLOW is unset.
+func FromEnv() (*Policy, error) {
+	allow := strings.TrimSpace(os.Getenv("PAGI_EGRESS_ALLOW"))
+	if allow == "" {
+		return nil, nil
+	}
+	schemes := os.Getenv("PAGI_EGRESS_SCHEMES")
+	if strings.TrimSpace(schemes) == "" {
+		schemes = "http,https"
+	}
+	return New(strings.Split(allow, ","), strings.Split(schemes, ","))
+}
+
+// New builds a policy from allowlist entries (see the package doc) and the
+// schemes CheckURL accepts.
+func New(allow, schemes []string) (*Policy, error) {
+	p := &Policy{lookup: func(ctx context.Context, host string) ([]netip.Addr, error) {
+		return net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	}}
+	for _, entry := range allow {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		switch {
+		case entry == "":
+		case strings.Contains(entry, "/"):
+			prefix, err := netip.ParsePrefix(entry)
+			if err != nil {
+				return nil, fmt.Errorf("PAGI_EGRESS_ALLOW: bad CIDR %q: %w", entry, err)
+			}
+			p.prefixes = append(p.prefixes, prefix.Masked())
+		case strings.HasPrefix(entry, "*."):
+			p.suffixes = append(p.suffixes, entry[1:])
+		default:
+			if addr, err := netip.ParseAddr(strings.Trim(entry, "[]")); err == nil {
+				p.prefixes = append(p.prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+				continue
+			}
+			if strings.ContainsAny(entry, ":*") {
+				return nil, fmt.Errorf("PAGI_EGRESS_ALLOW: bad entry %q (want a host name, *.domain, an IP or a CIDR)", entry)
+			}
+			p.hosts = append(p.hosts, entry)
+		}
+	}
+	for _, s := range schemes {
+		if s = strings.ToLower(strings.TrimSpace(s)); s != "" {
+			p.schemes = append(p.schemes, s)
+		}
+	}
+	return p, nil
+}
+
+// nameAllowed reports whether host is listed by name.
+func (p *Policy) nameAllowed(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if slices.Contains(p.hosts, host) {
+		return true
+	}
+	for _, suffix := range p.suffixes {
+		if strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Policy) addrAllowed(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range p.prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve checks host and returns the addresses to connect to, or nil when
+// host is listed by name and may be dialed as is.
+func (p *Policy) resolve(ctx context.Context, host string) ([]netip.Addr, error) {
+	if addr, err := netip.ParseAddr(strings.Trim(host, "[]")); err == nil {
+		if !p.addrAllowed(addr) {
+			return nil, fmt.Errorf("%w: %s is not in PAGI_EGRESS_ALLOW", ErrDenied, addr)
+		}
+		return []netip.Addr{addr}, nil
+	}
+	if p.nameAllowed(host) {
+		return nil, nil
+	}
+	if len(p.prefixes) == 0 {
+		return nil, fmt.Errorf("%w: host %q is not in PAGI_EGRESS_ALLOW", ErrDenied, host)
+	}
+	addrs, err := p.lookup(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("%w: host %q does not resolve", ErrDenied, host)
+	}
+	for _, addr := range addrs {
+		if !p.addrAllowed(addr) {
+			return nil, fmt.Errorf("%w: host %q resolves to %s, which is not in PAGI_EGRESS_ALLOW", ErrDenied, host, addr)
+		}
+	}
+	return addrs, nil
+}
+
+// CheckURL checks a URI's scheme and host against the policy, e.g. a
+// resource URI before it is passed to something that will fetch it.
+func (p *Policy) CheckURL(ctx context.Context, raw string) error {
+	if p == nil {
+		return nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("%w: invalid URI %q", ErrDenied, raw)
+	}
+	if !slices.Contains(p.schemes, strings.ToLower(u.Scheme)) {
+		return fmt.Errorf("%w: scheme %q is not in PAGI_EGRESS_SCHEMES", ErrDenied, u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return fmt.Errorf("%w: URI %q has no host", ErrDenied, raw)
+	}
+	_, err = p.resolve(ctx, u.Hostname())
+	return err
+}
+
+// DialContext wraps dial so it only connects to allowed destinations.
+func (p *Policy) DialContext(dial func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	if p == nil {
+		return dial
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		addrs, err := p.resolve(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		if addrs == nil {
+			return dial(ctx, network, addr)
+		}
+		var firstErr error
+		for _, a := range addrs {
+			conn, err := dial(ctx, network, net.JoinHostPort(a.String(), port))
+			if err == nil {
+				return conn, nil
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+		return nil, firstErr
+	}
+}
+
+// Transport returns a clone of base that only connects to allowed
+// destinations. With a proxy configured, the proxy is the destination that
+// is checked.
+func (p *Policy) Transport(base *http.Transport) *http.Transport {
+	t := base.Clone()
+	if p == nil {
+		return t
+	}
+	dial := t.DialContext
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	t.DialContext = p.DialContext(dial)
+	return t
+}
@@ -0,0 +1,80 @@
+package egress
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func newPolicy(t *testing.T, allow ...string) *Policy {
+	t.Helper()
+	p, err := New(allow, []string{"http", "https"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.lookup = func(_ context.Context, host string) ([]netip.Addr, error) {
+		return map[string][]netip.Addr{
+			"memory.internal": {netip.MustParseAddr("10.1.2.3")},
+			"rebound.example": {netip.MustParseAddr("10.1.2.4"), netip.MustParseAddr("169.254.169.254")},
+		}[host], nil
+	}
+	return p
+}
+
+func TestPolicy_CheckURL(t *testing.T) {
+	p := newPolicy(t, "api.openrouter.ai", "*.svc.cluster.local", "10.0.0.0/8", "::1")
+	for raw, allowed := range map[string]bool{
+		"https://api.openrouter.ai/v1/chat":           true,
+		"https://API.OpenRouter.ai./v1":               true,
+		"http://qdrant.rag.svc.cluster.local:6333/":   true,
+		"http://svc.cluster.local/":                   false,
+		"http://memory.internal:8003/memory/store":    true, // resolves into 10/8
+		"http://rebound.example/":                     false,
+		"http://169.254.169.254/latest/meta-data":     false,
+		"http://[::1]:8080/":                          true,
+		"http://10.9.9.9/":                            true,
+		"file:///etc/passwd":                          false,
+		"gopher://api.openrouter.ai/":                 false,
+		"https://evil.example/?u=api.openrouter.ai":   false,
+		"https://api.openrouter.ai.evil.example/":     false,
+		"http://unknown.example/":                     false,
+		"https://user@api.openrouter.ai@evil.example": false,
+	} {
+		err := p.CheckURL(context.Background(), raw)
+		if (err == nil) != allowed {
+			t.Errorf("CheckURL(%q) = %v, want allowed=%t", raw, err, allowed)
+		}
+		if err != nil && !errors.Is(err, ErrDenied) {
+			t.Errorf("CheckURL(%q) = %v, want ErrDenied", raw, err)
+		}
+	}
+	if err := (*Policy)(nil).CheckURL(context.Background(), "file:///etc/passwd"); err != nil {
+		t.Fatalf("nil policy: %v", err)
+	}
+	if _, err := New([]string{"10.0.0.0/33"}, nil); err == nil {
+		t.Fatal("want an error for a bad CIDR")
+	}
+}
+
+func TestPolicy_Transport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer srv.Close()
+
+	get := func(p *Policy) error {
+		client := &http.Client{Transport: p.Transport(http.DefaultTransport.(*http.Transport))}
+		resp, err := client.Get(srv.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+	if err := get(newPolicy(t, "127.0.0.0/8")); err != nil {
+		t.Fatalf("allowed range: %v", err)
+	}
+	if err := get(newPolicy(t, "api.openrouter.ai")); !errors.Is(err, ErrDenied) {
+		t.Fatalf("unlisted address: %v, want ErrDenied", err)
+	}
+}
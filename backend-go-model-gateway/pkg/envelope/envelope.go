@@ -0,0 +1,115 @@
+// Package envelope is the JSON response shape of the planner and the BFF
+// HTTP APIs:
+//
+//	{"data": ..., "error": null, "meta": {"trace_id": "...", "latency_ms": 12, "version": "1.0.0"}}
+//	{"data": null, "error": {"code": "bad_request", "message": "..."}, "meta": {...}}
+//
+// Every endpoint answers the same way, so a generated client decodes data or
+// error once instead of per endpoint. The HTTP status is unchanged; error.code
+// is derived from it. File downloads (zip bundles, session archives) and
+// streams are not wrapped.
+//
+// A service calls NewContext once per request, before any handler can
+// answer, so meta carries the request's trace ID and the time since it
+// arrived.
+package envelope
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Envelope is a response body.
+type Envelope struct {
+	Data  any    `json:"data"`
+	Error *Error `json:"error"`
+	Meta  Meta   `json:"meta"`
+}
+
+// Error describes a failed request.
+type Error struct {
+	// Code is the snake_case HTTP status text, e.g. "service_unavailable".
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string { return e.Message }
+
+// Meta describes the request and the service that answered it.
+type Meta struct {
+	TraceID   string `json:"trace_id,omitempty"`
+	LatencyMS int64  `json:"latency_ms"`
+	Version   string `json:"version,omitempty"`
+}
+
+type requestKey struct{}
+
+type request struct {
+	traceID, version string
+	start            time.Time
+}
+
+// NewContext returns ctx with the request's trace ID, the service version and
+// the current time as its start, for the meta of responses written with it.
+func NewContext(ctx context.Context, traceID, version string) context.Context {
+	return context.WithValue(ctx, requestKey{}, request{traceID: traceID, version: version, start: time.Now()})
+}
+
+// Write writes an envelope with data and, when e is non-nil, an error.
+func Write(w http.ResponseWriter, r *http.Request, status int, data any, e *Error) {
+	var meta Meta
+	if req, ok := r.Context().Value(requestKey{}).(request); ok {
+		meta = Meta{TraceID: req.traceID, LatencyMS: time.Since(req.start).Milliseconds(), Version: req.version}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(Envelope{Data: data, Error: e, Meta: meta})
+}
+
+// WriteData writes a successful response.
+func WriteData(w http.ResponseWriter, r *http.Request, status int, data any) {
+	Write(w, r, status, data, nil)
+}
+
+// WriteError writes a failed response with no data.
+func WriteError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	Write(w, r, status, nil, &Error{Code: Code(status), Message: message})
+}
+
+// Code is error.code for an HTTP status: its status text in snake_case, or
+// "error" for statuses without one.
+func Code(status int) string {
+	text := http.StatusText(status)
+	if text == "" {
+		return "error"
+	}
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == ' ' || r == '-':
+			return '_'
+		case r >= 'A' && r <= 'Z':
+			return r + ('a' - 'A')
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			return r
+		}
+		return -1
+	}, text)
+}
+
+// Unwrap splits a response body into its data and error. ok is false when
+// raw is not an envelope, such as a body from a service that does not use
+// one.
+func Unwrap(raw []byte) (data json.RawMessage, e *Error, ok bool) {
+	var env struct {
+		Data  json.RawMessage  `json:"data"`
+		Error *Error           `json:"error"`
+		Meta  *json.RawMessage `json:"meta"`
+	}
+	if err := json.Unmarshal(raw, &env); err != nil || env.Meta == nil {
+		return nil, nil, false
+	}
+	return env.Data, env.Error, true
+}
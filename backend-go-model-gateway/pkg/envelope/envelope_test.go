@@ -0,0 +1,68 @@
+package envelope
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/plan", nil)
+	req = req.WithContext(NewContext(req.Context(), "trace-1", "1.2.3"))
+
+	rec := httptest.NewRecorder()
+	WriteData(rec, req, http.StatusCreated, map[string]string{"result": "ok"})
+	if rec.Code != http.StatusCreated || rec.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("status %d, headers %v", rec.Code, rec.Header())
+	}
+	var got map[string]json.RawMessage
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if string(got["data"]) != `{"result":"ok"}` || string(got["error"]) != "null" {
+		t.Fatalf("body = %s", rec.Body)
+	}
+	var meta Meta
+	if err := json.Unmarshal(got["meta"], &meta); err != nil || meta.TraceID != "trace-1" || meta.Version != "1.2.3" || meta.LatencyMS < 0 {
+		t.Fatalf("meta = %s (%v)", got["meta"], err)
+	}
+
+	rec = httptest.NewRecorder()
+	WriteError(rec, req, http.StatusServiceUnavailable, "draining")
+	data, e, ok := Unwrap(rec.Body.Bytes())
+	if !ok || string(data) != "null" || e == nil || e.Code != "service_unavailable" || e.Message != "draining" {
+		t.Fatalf("Unwrap(%s) = %s, %+v, %v", rec.Body, data, e, ok)
+	}
+}
+
+func TestWrite_NoContext(t *testing.T) {
+	rec := httptest.NewRecorder()
+	WriteData(rec, httptest.NewRequest(http.MethodGet, "/health", nil), http.StatusOK, "ok")
+	if _, _, ok := Unwrap(rec.Body.Bytes()); !ok {
+		t.Fatalf("body = %s", rec.Body)
+	}
+}
+
+func TestCode(t *testing.T) {
+	for status, want := range map[int]string{
+		http.StatusBadRequest:           "bad_request",
+		http.StatusUnauthorized:         "unauthorized",
+		http.StatusTooManyRequests:      "too_many_requests",
+		http.StatusRequestURITooLong:    "request_uri_too_long",
+		http.StatusNonAuthoritativeInfo: "non_authoritative_information",
+		599:                             "error",
+	} {
+		if got := Code(status); got != want {
+			t.Errorf("Code(%d) = %q, want %q", status, got, want)
+		}
+	}
+}
+
+func TestUnwrap_NotAnEnvelope(t *testing.T) {
+	for _, raw := range []string{`{"error":"unauthorized"}`, `[1,2]`, `not json`} {
+		if _, _, ok := Unwrap([]byte(raw)); ok {
+			t.Errorf("Unwrap(%s) ok", raw)
+		}
+	}
+}
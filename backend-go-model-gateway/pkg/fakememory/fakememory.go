@@ -0,0 +1,535 @@
+// Package fakememory is an in-process stand-in for the Python Memory Service,
+// intended for planner and gateway integration tests.
+//
+// It serves both surfaces the Go services depend on:
+//
+//   - gRPC: ModelGateway.GetRAGContext (plus grpc.health.v1)
+//   - HTTP: GET /memory/latest, POST /memory/store, POST /memory/playbook,
+//     POST /memory/feedback, DELETE /memory/session
+//
+// Documents and session history can be seeded up front, and every write is
+// recorded so tests can assert on what the planner persisted. Writes carrying
+// a delta_id already stored are answered 409 and not applied, as the Python
+// service does.
+package fakememory
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"backend-go-model-gateway/pkg/httpsign"
+	"backend-go-model-gateway/pkg/ragfilter"
+	pb "backend-go-model-gateway/proto/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	grpc_health_v1 "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// MindKB is the knowledge base playbooks are written to.
+const MindKB = "Mind-KB"
+
+// Document is a seedable RAG document.
+type Document struct {
+	ID     string
+	Text   string
+	Source string
+	// Tags and CreatedAt are matched by RAGContextRequest.filter.
+	Tags      []string
+	CreatedAt time.Time
+}
+
+// Message is a single session-history entry as exchanged over /memory/*.
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// StoreRequest is a decoded POST /memory/store body.
+type StoreRequest struct {
+	SessionID   string         `json:"session_id"`
+	DeltaID     string         `json:"delta_id"`
+	History     []Message      `json:"history"`
+	Prompt      string         `json:"prompt"`
+	LLMResponse map[string]any `json:"llm_response"`
+	// Raw keeps every field of the original payload (including ones this
+	// fixture does not model) for assertions.
+	Raw map[string]any `json:"-"`
+}
+
+// Playbook is a decoded POST /memory/playbook body.
+type Playbook struct {
+	ID              string              `json:"playbook_id"`
+	SessionID       string              `json:"session_id"`
+	DeltaID         string              `json:"delta_id"`
+	Prompt          string              `json:"prompt"`
+	HistorySequence []map[string]string `json:"history_sequence"`
+}
+
+// Feedback is a decoded POST /memory/feedback body.
+type Feedback struct {
+	SessionID string `json:"session_id"`
+	Prompt    string `json:"prompt"`
+	Matches   []struct {
+		ID            string  `json:"id"`
+		KnowledgeBase string  `json:"knowledge_base"`
+		Cited         bool    `json:"cited"`
+		Overlap       float64 `json:"overlap"`
+		Used          bool    `json:"used"`
+	} `json:"matches"`
+}
+
+// Server is the fake memory service. Use New + Start (or StartT in tests).
+type Server struct {
+	pb.UnimplementedModelGatewayServer
+
+	mu        sync.Mutex
+	docs      map[string][]Document
+	history   map[string][]Message
+	stores    []StoreRequest
+	playbooks []Playbook
+	feedback  []Feedback
+	ragCalls  []*pb.RAGContextRequest
+	forgotten []string
+
+	deltas     map[string]bool
+	duplicates int
+	lost       int
+	lagging    bool
+	pending    map[string][]Message
+
+	grpcServer *grpc.Server
+	listener   net.Listener
+	httpServer *httptest.Server
+
+	// GRPCAddr is the host:port of the gRPC listener once started.
+	GRPCAddr string
+	// HTTPURL is the base URL of the HTTP API once started.
+	HTTPURL string
+
+	// Verifier, when set, rejects /memory/* writes that are not HMAC-signed
+	// (see pkg/httpsign), as the Memory Service does with PAGI_HMAC_KEY set.
+	// Reads stay open.
+	Verifier *httpsign.Verifier
+}
+
+// New returns an empty, unstarted fake.
+func New() *Server {
+	return &Server{
+		docs:    map[string][]Document{},
+		history: map[string][]Message{},
+		deltas:  map[string]bool{},
+	}
+}
+
+// StartT starts a fake on ephemeral ports and closes it when the test ends.
+func StartT(t testing.TB) *Server {
+	t.Helper()
+	s := New()
+	if err := s.Start(); err != nil {
+		t.Fatalf("start fake memory service: %v", err)
+	}
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Start listens on 127.0.0.1 ephemeral ports for gRPC and HTTP.
+func (s *Server) Start() error {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return fmt.Errorf("listen grpc: %w", err)
+	}
+	s.listener = lis
+	s.GRPCAddr = lis.Addr().String()
+
+	s.grpcServer = grpc.NewServer()
+	pb.RegisterModelGatewayServer(s.grpcServer, s)
+	hs := health.NewServer()
+	hs.SetServingStatus("", grpc_health_v1.HealthCheckResponse_SERVING)
+	grpc_health_v1.RegisterHealthServer(s.grpcServer, hs)
+	go func() { _ = s.grpcServer.Serve(lis) }()
+
+	s.httpServer = httptest.NewServer(s.Handler())
+	s.HTTPURL = s.httpServer.URL
+	return nil
+}
+
+// Close stops both listeners.
+func (s *Server) Close() {
+	if s.grpcServer != nil {
+		s.grpcServer.Stop()
+	}
+	if s.httpServer != nil {
+		s.httpServer.Close()
+	}
+}
+
+// Seed adds documents to a knowledge base.
+func (s *Server) Seed(kb string, docs ...Document) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.docs[kb] = append(s.docs[kb], docs...)
+}
+
+// SeedHistory appends messages to a session's history.
+func (s *Server) SeedHistory(sessionID string, msgs ...Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.history[sessionID] = append(s.history[sessionID], msgs...)
+}
+
+// History returns a copy of a session's history.
+func (s *Server) History(sessionID string) []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.history[sessionID]...)
+}
+
+// LoseResponses makes the next n writes apply but answer 503, like writes
+// whose response never reached the caller.
+func (s *Server) LoseResponses(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lost = n
+}
+
+// LagWrites makes /memory/store writes invisible to GET /memory/latest while
+// on, like an eventually consistent replica; turning it off shows them.
+func (s *Server) LagWrites(on bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lagging = on
+	if !on {
+		for id, msgs := range s.pending {
+			s.history[id] = append(s.history[id], msgs...)
+		}
+		s.pending = nil
+	}
+}
+
+// Duplicates returns how many writes were dropped for a known delta_id.
+func (s *Server) Duplicates() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.duplicates
+}
+
+// Stores returns every POST /memory/store applied so far.
+func (s *Server) Stores() []StoreRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]StoreRequest(nil), s.stores...)
+}
+
+// Playbooks returns every POST /memory/playbook applied so far.
+func (s *Server) Playbooks() []Playbook {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Playbook(nil), s.playbooks...)
+}
+
+// Feedback returns every POST /memory/feedback received so far.
+func (s *Server) Feedback() []Feedback {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Feedback(nil), s.feedback...)
+}
+
+// Forgotten returns the session IDs of every DELETE /memory/session so far.
+func (s *Server) Forgotten() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.forgotten...)
+}
+
+// RAGRequests returns every GetRAGContext request received so far.
+func (s *Server) RAGRequests() []*pb.RAGContextRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*pb.RAGContextRequest(nil), s.ragCalls...)
+}
+
+// GetRAGContext ranks seeded documents per KB by token overlap with the query
+// and returns up to top_k matches per KB (mirroring the Python service).
+// Documents failing the request's metadata filter are skipped.
+func (s *Server) GetRAGContext(_ context.Context, req *pb.RAGContextRequest) (*pb.RAGContextResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ragCalls = append(s.ragCalls, req)
+
+	topK := int(req.GetTopK())
+	if topK <= 0 {
+		topK = 1
+	}
+	kbs := req.GetKnowledgeBases()
+	if len(kbs) == 0 {
+		kbs = []string{"Body-KB"}
+	}
+
+	query := tokenize(req.GetQuery())
+	filter := ragfilter.FromProto(req.GetFilter())
+	resp := &pb.RAGContextResponse{}
+	for _, kb := range kbs {
+		type scored struct {
+			doc      Document
+			distance float64
+		}
+		ranked := make([]scored, 0, len(s.docs[kb]))
+		for _, d := range s.docs[kb] {
+			if !filter.Match(ragfilter.Metadata{ID: d.ID, Source: d.Source, Tags: d.Tags, CreatedAt: d.CreatedAt}) {
+				continue
+			}
+			ranked = append(ranked, scored{doc: d, distance: distance(query, tokenize(d.Text))})
+		}
+		sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].distance < ranked[j].distance })
+		if len(ranked) > topK {
+			ranked = ranked[:topK]
+		}
+		for _, r := range ranked {
+			source := r.doc.Source
+			if source == "" {
+				source = "fake"
+			}
+			resp.Matches = append(resp.Matches, &pb.RAGMatch{
+				Id:            r.doc.ID,
+				Text:          r.doc.Text,
+				Distance:      r.distance,
+				KnowledgeBase: kb,
+				Source:        source,
+			})
+		}
+	}
+	return resp, nil
+}
+
+// Handler returns the HTTP API (useful to mount without Start).
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/health", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"service": "fake-memory", "status": "ok"})
+	})
+
+	mux.HandleFunc("/memory/latest", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]any{"error": "method not allowed"})
+			return
+		}
+		sessionID := r.URL.Query().Get("session_id")
+		msgs := s.History(sessionID)
+		if msgs == nil {
+			msgs = []Message{}
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"session_id": sessionID, "messages": msgs})
+	})
+
+	mux.HandleFunc("/memory/store", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]any{"error": "method not allowed"})
+			return
+		}
+		var raw map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&raw); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+			return
+		}
+		b, _ := json.Marshal(raw)
+		var req StoreRequest
+		_ = json.Unmarshal(b, &req)
+		req.Raw = raw
+
+		s.mu.Lock()
+		if s.duplicate(req.DeltaID) {
+			s.mu.Unlock()
+			writeJSON(w, http.StatusConflict, map[string]any{"status": "duplicate", "session_id": req.SessionID, "delta_id": req.DeltaID})
+			return
+		}
+		s.stores = append(s.stores, req)
+		if s.lagging {
+			if s.pending == nil {
+				s.pending = map[string][]Message{}
+			}
+			s.pending[req.SessionID] = append(s.pending[req.SessionID], req.History...)
+		} else {
+			s.history[req.SessionID] = append(s.history[req.SessionID], req.History...)
+		}
+		lost := s.loseResponse()
+		s.mu.Unlock()
+
+		if lost {
+			writeJSON(w, http.StatusServiceUnavailable, map[string]any{"error": "response lost"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"status": "ok", "session_id": req.SessionID, "turns": len(req.History)})
+	})
+
+	mux.HandleFunc("/memory/playbook", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]any{"error": "method not allowed"})
+			return
+		}
+		var pbk Playbook
+		if err := json.NewDecoder(r.Body).Decode(&pbk); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+			return
+		}
+		text := summarizePlaybook(pbk)
+		sum := sha256.Sum256([]byte(text))
+		pbk.ID = hex.EncodeToString(sum[:])
+
+		s.mu.Lock()
+		if s.duplicate(pbk.DeltaID) {
+			s.mu.Unlock()
+			writeJSON(w, http.StatusConflict, map[string]any{"status": "duplicate", "playbook_id": pbk.ID, "delta_id": pbk.DeltaID})
+			return
+		}
+		s.playbooks = append(s.playbooks, pbk)
+		s.docs[MindKB] = append(s.docs[MindKB], Document{ID: pbk.ID, Text: text, Source: "playbook"})
+		lost := s.loseResponse()
+		s.mu.Unlock()
+
+		if lost {
+			writeJSON(w, http.StatusServiceUnavailable, map[string]any{"error": "response lost"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"status": "ok", "playbook_id": pbk.ID})
+	})
+
+	mux.HandleFunc("/memory/feedback", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]any{"error": "method not allowed"})
+			return
+		}
+		var fb Feedback
+		if err := json.NewDecoder(r.Body).Decode(&fb); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+			return
+		}
+
+		s.mu.Lock()
+		s.feedback = append(s.feedback, fb)
+		s.mu.Unlock()
+
+		writeJSON(w, http.StatusOK, map[string]any{"status": "ok", "updated": len(fb.Matches)})
+	})
+
+	mux.HandleFunc("/memory/session", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]any{"error": "method not allowed"})
+			return
+		}
+		sessionID := r.URL.Query().Get("session_id")
+		if sessionID == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "session_id is required"})
+			return
+		}
+
+		s.mu.Lock()
+		s.forgotten = append(s.forgotten, sessionID)
+		messages := len(s.history[sessionID]) + len(s.pending[sessionID])
+		delete(s.history, sessionID)
+		delete(s.pending, sessionID)
+		dropped := map[string]bool{}
+		kept := s.playbooks[:0]
+		for _, pbk := range s.playbooks {
+			if pbk.SessionID == sessionID {
+				dropped[pbk.ID] = true
+				continue
+			}
+			kept = append(kept, pbk)
+		}
+		s.playbooks = kept
+		docs := s.docs[MindKB][:0]
+		for _, d := range s.docs[MindKB] {
+			if !dropped[d.ID] {
+				docs = append(docs, d)
+			}
+		}
+		s.docs[MindKB] = docs
+		s.mu.Unlock()
+
+		writeJSON(w, http.StatusOK, map[string]any{"status": "ok", "session_id": sessionID, "messages": messages, "playbooks": len(dropped)})
+	})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.Verifier != nil && strings.HasPrefix(r.URL.Path, "/memory/") && r.Method != http.MethodGet && r.Method != http.MethodHead {
+			s.Verifier.Middleware(mux).ServeHTTP(w, r)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// duplicate records a write's delta ID and reports whether it was already
+// stored; writes without one are never duplicates. s.mu must be held.
+func (s *Server) duplicate(deltaID string) bool {
+	if deltaID == "" {
+		return false
+	}
+	if s.deltas[deltaID] {
+		s.duplicates++
+		return true
+	}
+	s.deltas[deltaID] = true
+	return false
+}
+
+// loseResponse consumes one LoseResponses write. s.mu must be held.
+func (s *Server) loseResponse() bool {
+	if s.lost == 0 {
+		return false
+	}
+	s.lost--
+	return true
+}
+
+func summarizePlaybook(p Playbook) string {
+	var b strings.Builder
+	b.WriteString("Playbook for: " + p.Prompt + "\n")
+	for _, step := range p.HistorySequence {
+		b.WriteString(step["role"] + ": " + step["content"] + "\n")
+	}
+	return b.String()
+}
+
+func tokenize(s string) map[string]struct{} {
+	out := map[string]struct{}{}
+	for _, f := range strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9')
+	}) {
+		out[f] = struct{}{}
+	}
+	return out
+}
+
+// distance is 1 - (shared tokens / query tokens): 0 for a perfect match, 1 for
+// no overlap. It mirrors Chroma's "lower is better" distance semantics.
+func distance(query, doc map[string]struct{}) float64 {
+	if len(query) == 0 {
+		return 1
+	}
+	shared := 0
+	for tok := range query {
+		if _, ok := doc[tok]; ok {
+			shared++
+		}
+	}
+	return 1 - float64(shared)/float64(len(query))
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
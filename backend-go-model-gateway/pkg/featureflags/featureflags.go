@@ -0,0 +1,366 @@
+// Package featureflags provides a lightweight feature-flag provider shared by
+// the Go services (Model Gateway, Agent Planner).
+//
+// Flags are resolved in the following order (first match wins):
+//
+//  1. per-session overrides (in-process, then the Redis session hash)
+//  2. global values from Redis (hash PAGI_FLAGS_REDIS_KEY)
+//  3. a JSON flag file (PAGI_FLAGS_FILE), re-read when its mtime changes
+//  4. environment variables (PAGI_FLAG_<NAME>, e.g. PAGI_FLAG_PLAYBOOK_REUSE)
+//  5. the compiled-in default
+//
+// A flag value is either a boolean ("true"/"false", "on"/"off", "1"/"0") or a
+// rollout percentage ("25%"). Percentages enable the flag for a stable subset
+// of sessions so features can be turned on gradually without redeploys.
+package featureflags
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Well-known flag names. Services may register additional flags via Register.
+const (
+	// ReflectionTurns enables an extra self-critique turn before the final answer.
+	ReflectionTurns = "reflection_turns"
+	// PlaybookReuse enables Mind-KB playbook retrieval and storage in the planner.
+	PlaybookReuse = "playbook_reuse"
+	// Streaming enables streaming responses where supported.
+	Streaming = "streaming"
+	// ShadowRouting mirrors requests to a candidate provider without using its output.
+	ShadowRouting = "shadow_routing"
+)
+
+const (
+	defaultRedisKey        = "pagi:flags"
+	defaultRefreshInterval = 5 * time.Second
+	redisLookupTimeout     = 100 * time.Millisecond
+)
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]bool{
+		ReflectionTurns: false,
+		PlaybookReuse:   true,
+		Streaming:       false,
+		ShadowRouting:   false,
+	}
+)
+
+// Register declares a flag and its compiled-in default. Registering an existing
+// flag overwrites its default.
+func Register(name string, defaultValue bool) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[normalizeName(name)] = defaultValue
+}
+
+// Names returns all registered flag names in sorted order.
+func Names() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func defaultFor(name string) bool {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return registry[name]
+}
+
+// Options configures a Provider.
+type Options struct {
+	// FilePath is an optional JSON file of {"flag_name": "true" | false | "25%"}.
+	FilePath string
+	// Redis is an optional client used for global and per-session values.
+	Redis *redis.Client
+	// RedisKey is the hash holding global values. Per-session overrides live in
+	// "<RedisKey>:session:<session_id>".
+	RedisKey string
+	// RefreshInterval bounds how often the file and Redis global hash are re-read.
+	RefreshInterval time.Duration
+}
+
+// OptionsFromEnv reads PAGI_FLAGS_FILE, PAGI_FLAGS_REDIS_KEY and
+// PAGI_FLAGS_REFRESH_SECONDS. The Redis client must be supplied by the caller.
+func OptionsFromEnv() Options {
+	opts := Options{
+		FilePath: strings.TrimSpace(os.Getenv("PAGI_FLAGS_FILE")),
+		RedisKey: strings.TrimSpace(os.Getenv("PAGI_FLAGS_REDIS_KEY")),
+	}
+	if v := os.Getenv("PAGI_FLAGS_REFRESH_SECONDS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			opts.RefreshInterval = time.Duration(n) * time.Second
+		}
+	}
+	return opts
+}
+
+// Provider resolves flag values from the configured sources.
+//
+// A nil *Provider is valid and resolves every flag to its env value or default.
+type Provider struct {
+	opts Options
+
+	mu               sync.RWMutex
+	fileValues       map[string]string
+	fileModTime      time.Time
+	redisValues      map[string]string
+	lastRefresh      time.Time
+	sessionOverrides map[string]map[string]bool
+}
+
+// New constructs a Provider and performs an initial load of the flag file.
+func New(opts Options) (*Provider, error) {
+	if opts.RedisKey == "" {
+		opts.RedisKey = defaultRedisKey
+	}
+	if opts.RefreshInterval <= 0 {
+		opts.RefreshInterval = defaultRefreshInterval
+	}
+	p := &Provider{
+		opts:             opts,
+		sessionOverrides: map[string]map[string]bool{},
+	}
+	if err := p.reloadFile(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Enabled reports whether the named flag is on for the given session.
+// sessionID may be empty for global (non-session) decisions.
+func (p *Provider) Enabled(ctx context.Context, name, sessionID string) bool {
+	name = normalizeName(name)
+	if p == nil {
+		if raw, ok := envValue(name); ok {
+			if on, ok := evaluate(name, raw, sessionID); ok {
+				return on
+			}
+		}
+		return defaultFor(name)
+	}
+
+	if sessionID != "" {
+		p.mu.RLock()
+		on, ok := p.sessionOverrides[sessionID][name]
+		p.mu.RUnlock()
+		if ok {
+			return on
+		}
+		if raw, ok := p.redisSessionValue(ctx, name, sessionID); ok {
+			if on, ok := evaluate(name, raw, sessionID); ok {
+				return on
+			}
+		}
+	}
+
+	p.maybeRefresh(ctx)
+
+	p.mu.RLock()
+	redisRaw, inRedis := p.redisValues[name]
+	fileRaw, inFile := p.fileValues[name]
+	p.mu.RUnlock()
+
+	if inRedis {
+		if on, ok := evaluate(name, redisRaw, sessionID); ok {
+			return on
+		}
+	}
+	if inFile {
+		if on, ok := evaluate(name, fileRaw, sessionID); ok {
+			return on
+		}
+	}
+	if raw, ok := envValue(name); ok {
+		if on, ok := evaluate(name, raw, sessionID); ok {
+			return on
+		}
+	}
+	return defaultFor(name)
+}
+
+// Snapshot returns the effective value of every registered flag for a session.
+func (p *Provider) Snapshot(ctx context.Context, sessionID string) map[string]bool {
+	out := make(map[string]bool)
+	for _, name := range Names() {
+		out[name] = p.Enabled(ctx, name, sessionID)
+	}
+	return out
+}
+
+// SetSessionOverride pins a flag for a single session in-process. It takes
+// precedence over every other source.
+func (p *Provider) SetSessionOverride(sessionID, name string, enabled bool) {
+	if p == nil || sessionID == "" {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	m, ok := p.sessionOverrides[sessionID]
+	if !ok {
+		m = map[string]bool{}
+		p.sessionOverrides[sessionID] = m
+	}
+	m[normalizeName(name)] = enabled
+}
+
+// ClearSessionOverrides drops all in-process overrides for a session.
+func (p *Provider) ClearSessionOverrides(sessionID string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.sessionOverrides, sessionID)
+}
+
+func (p *Provider) maybeRefresh(ctx context.Context) {
+	p.mu.RLock()
+	due := time.Since(p.lastRefresh) >= p.opts.RefreshInterval
+	p.mu.RUnlock()
+	if !due {
+		return
+	}
+
+	p.mu.Lock()
+	// Another goroutine may have refreshed while we waited for the lock.
+	if time.Since(p.lastRefresh) < p.opts.RefreshInterval {
+		p.mu.Unlock()
+		return
+	}
+	p.lastRefresh = time.Now()
+	p.mu.Unlock()
+
+	// Best-effort: keep serving the previous values on failure.
+	_ = p.reloadFile()
+
+	if p.opts.Redis == nil {
+		return
+	}
+	lookupCtx, cancel := context.WithTimeout(ctx, redisLookupTimeout)
+	defer cancel()
+	values, err := p.opts.Redis.HGetAll(lookupCtx, p.opts.RedisKey).Result()
+	if err != nil {
+		return
+	}
+	normalized := make(map[string]string, len(values))
+	for k, v := range values {
+		normalized[normalizeName(k)] = v
+	}
+	p.mu.Lock()
+	p.redisValues = normalized
+	p.mu.Unlock()
+}
+
+func (p *Provider) reloadFile() error {
+	if p.opts.FilePath == "" {
+		return nil
+	}
+	info, err := os.Stat(p.opts.FilePath)
+	if err != nil {
+		return fmt.Errorf("stat flags file %s: %w", p.opts.FilePath, err)
+	}
+
+	p.mu.RLock()
+	unchanged := p.fileValues != nil && info.ModTime().Equal(p.fileModTime)
+	p.mu.RUnlock()
+	if unchanged {
+		return nil
+	}
+
+	b, err := os.ReadFile(p.opts.FilePath)
+	if err != nil {
+		return fmt.Errorf("read flags file %s: %w", p.opts.FilePath, err)
+	}
+	var raw map[string]any
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return fmt.Errorf("parse flags file %s: %w", p.opts.FilePath, err)
+	}
+	values := make(map[string]string, len(raw))
+	for k, v := range raw {
+		values[normalizeName(k)] = fmt.Sprint(v)
+	}
+
+	p.mu.Lock()
+	p.fileValues = values
+	p.fileModTime = info.ModTime()
+	p.mu.Unlock()
+	return nil
+}
+
+func (p *Provider) redisSessionValue(ctx context.Context, name, sessionID string) (string, bool) {
+	if p.opts.Redis == nil {
+		return "", false
+	}
+	lookupCtx, cancel := context.WithTimeout(ctx, redisLookupTimeout)
+	defer cancel()
+	// redis.Nil (no override) and transport errors both fall through to the
+	// global sources.
+	v, err := p.opts.Redis.HGet(lookupCtx, p.opts.RedisKey+":session:"+sessionID, name).Result()
+	if err != nil {
+		return "", false
+	}
+	return v, true
+}
+
+func envValue(name string) (string, bool) {
+	v, ok := os.LookupEnv("PAGI_FLAG_" + strings.ToUpper(name))
+	if !ok || strings.TrimSpace(v) == "" {
+		return "", false
+	}
+	return v, true
+}
+
+// evaluate interprets a raw flag value. The boolean result is only meaningful
+// when ok is true.
+func evaluate(name, raw, sessionID string) (enabled bool, ok bool) {
+	v := strings.ToLower(strings.TrimSpace(raw))
+	switch v {
+	case "1", "true", "on", "yes", "enabled":
+		return true, true
+	case "0", "false", "off", "no", "disabled":
+		return false, true
+	}
+	if strings.HasSuffix(v, "%") {
+		pct, err := strconv.ParseFloat(strings.TrimSuffix(v, "%"), 64)
+		if err != nil {
+			return false, false
+		}
+		return inRollout(name, sessionID, pct), true
+	}
+	return false, false
+}
+
+// inRollout deterministically buckets a session into [0, 100).
+// Requests without a session are only enabled at 100%.
+func inRollout(name, sessionID string, pct float64) bool {
+	if pct >= 100 {
+		return true
+	}
+	if pct <= 0 || sessionID == "" {
+		return false
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(name + ":" + sessionID))
+	return float64(h.Sum32()%10000)/100 < pct
+}
+
+func normalizeName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
@@ -0,0 +1,73 @@
+package featureflags
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEnabled_PrecedenceSessionOverFileOverEnv(t *testing.T) {
+	t.Setenv("PAGI_FLAG_STREAMING", "true")
+
+	path := filepath.Join(t.TempDir(), "flags.json")
+	if err := os.WriteFile(path, []byte(`{"streaming": false}`), 0o600); err != nil {
+		t.Fatalf("write flags file: %v", err)
+	}
+
+	p, err := New(Options{FilePath: path})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	ctx := context.Background()
+
+	if p.Enabled(ctx, Streaming, "s1") {
+		t.Fatalf("expected file value (false) to win over env (true)")
+	}
+
+	p.SetSessionOverride("s1", Streaming, true)
+	if !p.Enabled(ctx, Streaming, "s1") {
+		t.Fatalf("expected session override to win")
+	}
+	if p.Enabled(ctx, Streaming, "s2") {
+		t.Fatalf("override must not leak to other sessions")
+	}
+}
+
+func TestEnabled_NilProviderUsesEnvAndDefaults(t *testing.T) {
+	var p *Provider
+	ctx := context.Background()
+
+	if !p.Enabled(ctx, PlaybookReuse, "") {
+		t.Fatalf("expected compiled-in default true for %s", PlaybookReuse)
+	}
+	t.Setenv("PAGI_FLAG_PLAYBOOK_REUSE", "off")
+	if p.Enabled(ctx, PlaybookReuse, "") {
+		t.Fatalf("expected env value to disable %s", PlaybookReuse)
+	}
+}
+
+func TestEvaluate_PercentageRolloutIsStable(t *testing.T) {
+	enabled := 0
+	for i := 0; i < 1000; i++ {
+		session := "session-" + string(rune('a'+i%26)) + string(rune('a'+i/26))
+		first, ok := evaluate(Streaming, "30%", session)
+		if !ok {
+			t.Fatalf("expected percentage value to parse")
+		}
+		second, _ := evaluate(Streaming, "30%", session)
+		if first != second {
+			t.Fatalf("rollout must be deterministic for session %q", session)
+		}
+		if first {
+			enabled++
+		}
+	}
+	if enabled < 200 || enabled > 400 {
+		t.Fatalf("expected roughly 30%% of sessions enabled, got %d/1000", enabled)
+	}
+
+	if on, _ := evaluate(Streaming, "50%", ""); on {
+		t.Fatalf("partial rollouts must not enable requests without a session")
+	}
+}
@@ -0,0 +1,184 @@
+// Package grpcpool spreads a client's RPCs over several connections to the
+// same target.
+//
+// One *grpc.ClientConn to a static host:port is a single TCP connection: when
+// it stalls (a half-open socket, a saturated HTTP/2 stream limit, a replica
+// behind the load balancer that stopped answering) every RPC on it suffers. A
+// Pool keeps Size connections and picks them round-robin, preferring READY
+// connections and skipping ones in TRANSIENT_FAILURE, so one bad connection
+// only loses its share of traffic until it reconnects. Each connection still
+// load-balances across resolved replicas (see pkg/discovery).
+//
+// A Pool implements grpc.ClientConnInterface, so generated clients take it in
+// place of a *grpc.ClientConn.
+package grpcpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+)
+
+// DefaultSize is the number of connections when PAGI_GRPC_POOL_SIZE is unset.
+const DefaultSize = 2
+
+// Options configures a Pool.
+type Options struct {
+	// Size is the number of connections (minimum 1).
+	Size int
+	// WaitForReady makes RPCs wait for a connection to become ready, up to
+	// their deadline, instead of failing fast while it (re)connects.
+	WaitForReady bool
+	// OnStateChange is called whenever connection index changes state.
+	OnStateChange func(index int, from, to connectivity.State)
+}
+
+// OptionsFromEnv reads PAGI_GRPC_POOL_SIZE (default 2) and
+// PAGI_GRPC_WAIT_FOR_READY (default on).
+func OptionsFromEnv() Options {
+	opts := Options{Size: DefaultSize, WaitForReady: true}
+	if n, err := strconv.Atoi(os.Getenv("PAGI_GRPC_POOL_SIZE")); err == nil && n > 0 {
+		opts.Size = n
+	}
+	if strings.EqualFold(os.Getenv("PAGI_GRPC_WAIT_FOR_READY"), "off") {
+		opts.WaitForReady = false
+	}
+	return opts
+}
+
+// Pool is a fixed set of connections to one target.
+type Pool struct {
+	target string
+	opts   Options
+	conns  []*grpc.ClientConn
+	next   atomic.Uint32
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// Dial opens opts.Size connections to target with dial and starts connecting
+// them. The Pool's watchers stop on Close, not when ctx is done.
+func Dial(ctx context.Context, target string, opts Options, dial func(ctx context.Context, target string) (*grpc.ClientConn, error)) (*Pool, error) {
+	if opts.Size < 1 {
+		opts.Size = 1
+	}
+	p := &Pool{target: target, opts: opts}
+	for i := 0; i < opts.Size; i++ {
+		conn, err := dial(ctx, target)
+		if err != nil {
+			_ = p.Close()
+			return nil, fmt.Errorf("connection %d of %d: %w", i+1, opts.Size, err)
+		}
+		p.conns = append(p.conns, conn)
+	}
+
+	watchCtx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	for i, conn := range p.conns {
+		// Connect now: an idle connection is only picked when none is ready,
+		// so without this the pool would sit on its first connection.
+		conn.Connect()
+		if opts.OnStateChange != nil {
+			p.wg.Add(1)
+			go p.watch(watchCtx, i, conn)
+		}
+	}
+	return p, nil
+}
+
+func (p *Pool) watch(ctx context.Context, index int, conn *grpc.ClientConn) {
+	defer p.wg.Done()
+	state := conn.GetState()
+	for conn.WaitForStateChange(ctx, state) {
+		next := conn.GetState()
+		p.opts.OnStateChange(index, state, next)
+		state = next
+	}
+}
+
+// Target returns the target the Pool dialed.
+func (p *Pool) Target() string { return p.target }
+
+// States returns each connection's current connectivity state.
+func (p *Pool) States() []string {
+	states := make([]string, len(p.conns))
+	for i, conn := range p.conns {
+		states[i] = conn.GetState().String()
+	}
+	return states
+}
+
+// stater is the part of *grpc.ClientConn pick needs.
+type stater interface {
+	GetState() connectivity.State
+}
+
+// pick returns the index of the next READY connection after start, else the
+// next one not in TRANSIENT_FAILURE, else start itself.
+func pick[S stater](conns []S, start int) int {
+	n := len(conns)
+	fallback := -1
+	for k := 0; k < n; k++ {
+		i := (start + k) % n
+		switch conns[i].GetState() {
+		case connectivity.Ready:
+			return i
+		case connectivity.TransientFailure, connectivity.Shutdown:
+		default:
+			if fallback < 0 {
+				fallback = i
+			}
+		}
+	}
+	if fallback >= 0 {
+		return fallback
+	}
+	return start % n
+}
+
+func (p *Pool) conn() *grpc.ClientConn {
+	return p.conns[pick(p.conns, int(p.next.Add(1)-1))]
+}
+
+func (p *Pool) callOptions(opts []grpc.CallOption) []grpc.CallOption {
+	if !p.opts.WaitForReady {
+		return opts
+	}
+	// Prepended, so a caller's own grpc.WaitForReady(false) still wins.
+	return append([]grpc.CallOption{grpc.WaitForReady(true)}, opts...)
+}
+
+// Invoke implements grpc.ClientConnInterface.
+func (p *Pool) Invoke(ctx context.Context, method string, args, reply any, opts ...grpc.CallOption) error {
+	return p.conn().Invoke(ctx, method, args, reply, p.callOptions(opts)...)
+}
+
+// NewStream implements grpc.ClientConnInterface.
+func (p *Pool) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return p.conn().NewStream(ctx, desc, method, p.callOptions(opts)...)
+}
+
+// Close closes every connection. It is safe on a nil Pool.
+func (p *Pool) Close() error {
+	if p == nil {
+		return nil
+	}
+	if p.cancel != nil {
+		p.cancel()
+	}
+	var errs []error
+	for _, conn := range p.conns {
+		errs = append(errs, conn.Close())
+	}
+	p.wg.Wait()
+	return errors.Join(errs...)
+}
@@ -0,0 +1,135 @@
+package grpcpool
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/peer"
+)
+
+// peerHealth records the client address (one per TCP connection) of each Check.
+type peerHealth struct {
+	*health.Server
+	mu    sync.Mutex
+	peers map[string]int
+}
+
+func (h *peerHealth) Check(ctx context.Context, req *grpc_health_v1.HealthCheckRequest) (*grpc_health_v1.HealthCheckResponse, error) {
+	if p, ok := peer.FromContext(ctx); ok {
+		h.mu.Lock()
+		h.peers[p.Addr.String()]++
+		h.mu.Unlock()
+	}
+	return h.Server.Check(ctx, req)
+}
+
+func dialInsecure(ctx context.Context, target string) (*grpc.ClientConn, error) {
+	return grpc.DialContext(ctx, target, grpc.WithTransportCredentials(insecure.NewCredentials()))
+}
+
+func TestPool_SpreadsRPCsOverConnections(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hs := &peerHealth{Server: health.NewServer(), peers: map[string]int{}}
+	srv := grpc.NewServer()
+	grpc_health_v1.RegisterHealthServer(srv, hs)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	var mu sync.Mutex
+	var transitions []connectivity.State
+	pool, err := Dial(context.Background(), lis.Addr().String(), Options{
+		Size:         3,
+		WaitForReady: true,
+		OnStateChange: func(_ int, _, to connectivity.State) {
+			mu.Lock()
+			transitions = append(transitions, to)
+			mu.Unlock()
+		},
+	}, dialInsecure)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = pool.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	// Wait-for-ready lets the first RPCs ride out the connections' startup.
+	client := grpc_health_v1.NewHealthClient(pool)
+	for i := 0; i < 30; i++ {
+		if _, err := client.Check(ctx, &grpc_health_v1.HealthCheckRequest{}); err != nil {
+			t.Fatalf("Check #%d: %v", i, err)
+		}
+	}
+
+	hs.mu.Lock()
+	used := len(hs.peers)
+	hs.mu.Unlock()
+	if used != 3 {
+		t.Fatalf("RPCs used %d connections, want 3", used)
+	}
+	// Watchers report asynchronously; give them a moment.
+	readyCount := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		n := 0
+		for _, s := range transitions {
+			if s == connectivity.Ready {
+				n++
+			}
+		}
+		return n
+	}
+	for deadline := time.Now().Add(2 * time.Second); readyCount() < 3 && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := readyCount(); n != 3 {
+		t.Fatalf("%d READY state changes, want one per connection", n)
+	}
+}
+
+type fakeConn connectivity.State
+
+func (f fakeConn) GetState() connectivity.State { return connectivity.State(f) }
+
+func TestPick_SkipsBrokenConnections(t *testing.T) {
+	ready, idle, broken := fakeConn(connectivity.Ready), fakeConn(connectivity.Idle), fakeConn(connectivity.TransientFailure)
+	for _, tc := range []struct {
+		conns []fakeConn
+		start int
+		want  int
+	}{
+		{[]fakeConn{ready, ready, ready}, 4, 1},
+		{[]fakeConn{ready, broken, ready}, 1, 2},
+		{[]fakeConn{idle, broken, ready}, 0, 2},
+		{[]fakeConn{broken, idle, broken}, 2, 1},
+		{[]fakeConn{broken, broken}, 1, 1},
+	} {
+		if got := pick(tc.conns, tc.start); got != tc.want {
+			t.Errorf("pick(%v, %d) = %d, want %d", tc.conns, tc.start, got, tc.want)
+		}
+	}
+}
+
+func TestOptionsFromEnv(t *testing.T) {
+	t.Setenv("PAGI_GRPC_POOL_SIZE", "")
+	t.Setenv("PAGI_GRPC_WAIT_FOR_READY", "")
+	if got := OptionsFromEnv(); got.Size != DefaultSize || !got.WaitForReady {
+		t.Fatalf("defaults = %+v", got)
+	}
+	t.Setenv("PAGI_GRPC_POOL_SIZE", "4")
+	t.Setenv("PAGI_GRPC_WAIT_FOR_READY", "off")
+	if got := OptionsFromEnv(); got.Size != 4 || got.WaitForReady {
+		t.Fatalf("from env = %+v", got)
+	}
+}
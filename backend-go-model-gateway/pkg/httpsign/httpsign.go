@@ -0,0 +1,217 @@
+// Package httpsign signs and verifies internal HTTP requests with a shared
+// HMAC key, for hops that cannot easily use mTLS (the Memory Service HTTP
+// API, webhook callbacks). On a flat network it stops anyone who can reach
+// the port from spoofing writes.
+//
+// A signed request carries two headers:
+//
+//	X-Pagi-Timestamp: 1760518800
+//	X-Pagi-Signature: v1=<hex HMAC-SHA256>
+//
+// The MAC covers the version, the timestamp, the method, the request URI
+// (path and query) and the SHA-256 of the body, each on its own line:
+//
+//	v1\n<timestamp>\n<METHOD>\n<request-uri>\n<hex sha256(body)>
+//
+// Verifiers reject requests whose timestamp is more than MaxSkew away from
+// their clock, which bounds how long a captured request can be replayed.
+//
+// The key is the PAGI_HMAC_KEY secret (any pkg/secrets form). During a
+// rotation, verifiers also accept PAGI_HMAC_KEY_PREVIOUS; roll the new key to
+// verifiers first, then to signers.
+package httpsign
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend-go-model-gateway/pkg/secrets"
+)
+
+const (
+	TimestampHeader = "X-Pagi-Timestamp"
+	SignatureHeader = "X-Pagi-Signature"
+
+	// KeyName and PreviousKeyName are the secrets signers and verifiers read.
+	KeyName         = "PAGI_HMAC_KEY"
+	PreviousKeyName = "PAGI_HMAC_KEY_PREVIOUS"
+
+	// DefaultMaxSkew is how far a request's timestamp may be from the
+	// verifier's clock.
+	DefaultMaxSkew = 5 * time.Minute
+
+	version = "v1"
+)
+
+var (
+	ErrUnsigned     = errors.New("httpsign: request is not signed")
+	ErrExpired      = errors.New("httpsign: request timestamp outside the allowed window")
+	ErrBadSignature = errors.New("httpsign: signature mismatch")
+)
+
+// Sign sets the timestamp and signature headers on req. The body is read and
+// replaced, so it can still be sent.
+func Sign(req *http.Request, key []byte, now time.Time) error {
+	body, err := readBody(req)
+	if err != nil {
+		return err
+	}
+	ts := strconv.FormatInt(now.Unix(), 10)
+	req.Header.Set(TimestampHeader, ts)
+	req.Header.Set(SignatureHeader, version+"="+hex.EncodeToString(mac(key, ts, req.Method, req.URL.RequestURI(), body)))
+	return nil
+}
+
+// Verify checks req's signature against each of keys and its timestamp
+// against now. The body is read and replaced, so handlers can still read it.
+func Verify(req *http.Request, keys [][]byte, maxSkew time.Duration, now time.Time) error {
+	ts := req.Header.Get(TimestampHeader)
+	sig := req.Header.Get(SignatureHeader)
+	if ts == "" || sig == "" {
+		return ErrUnsigned
+	}
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: bad timestamp %q", ErrUnsigned, ts)
+	}
+	if skew := now.Sub(time.Unix(unix, 0)); skew > maxSkew || skew < -maxSkew {
+		return ErrExpired
+	}
+	got, ok := strings.CutPrefix(sig, version+"=")
+	if !ok {
+		return fmt.Errorf("%w: unsupported signature version", ErrBadSignature)
+	}
+	want, err := hex.DecodeString(got)
+	if err != nil {
+		return ErrBadSignature
+	}
+	body, err := readBody(req)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if hmac.Equal(want, mac(key, ts, req.Method, req.URL.RequestURI(), body)) {
+			return nil
+		}
+	}
+	return ErrBadSignature
+}
+
+func mac(key []byte, ts, method, uri string, body []byte) []byte {
+	sum := sha256.Sum256(body)
+	h := hmac.New(sha256.New, key)
+	fmt.Fprintf(h, "%s\n%s\n%s\n%s\n%s", version, ts, strings.ToUpper(method), uri, hex.EncodeToString(sum[:]))
+	return h.Sum(nil)
+}
+
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("httpsign: read body: %w", err)
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// Transport signs every request with the current value of the Name secret
+// (default PAGI_HMAC_KEY), so a client keeps working across key rotation.
+// Requests go out unsigned while the secret is unset. Base defaults to
+// http.DefaultTransport.
+type Transport struct {
+	Store *secrets.Store
+	Name  string
+	Base  http.RoundTripper
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	name := t.Name
+	if name == "" {
+		name = KeyName
+	}
+	key, err := t.Store.Lookup(req.Context(), name)
+	if err != nil {
+		return nil, err
+	}
+	if key == "" {
+		return base.RoundTrip(req)
+	}
+	r := req.Clone(req.Context())
+	if err := Sign(r, []byte(key), time.Now()); err != nil {
+		return nil, err
+	}
+	return base.RoundTrip(r)
+}
+
+// Verifier rejects requests that are not signed with PAGI_HMAC_KEY or
+// PAGI_HMAC_KEY_PREVIOUS.
+type Verifier struct {
+	Store *secrets.Store
+	// MaxSkew defaults to DefaultMaxSkew.
+	MaxSkew time.Duration
+}
+
+// Configured reports whether a key is set, i.e. whether verification is on.
+func (v *Verifier) Configured() bool {
+	return v.Store.Configured(KeyName)
+}
+
+// Verify checks req against the current keys.
+func (v *Verifier) Verify(req *http.Request) error {
+	keys, err := v.keys(req.Context())
+	if err != nil {
+		return err
+	}
+	skew := v.MaxSkew
+	if skew <= 0 {
+		skew = DefaultMaxSkew
+	}
+	return Verify(req, keys, skew, time.Now())
+}
+
+func (v *Verifier) keys(ctx context.Context) ([][]byte, error) {
+	var keys [][]byte
+	for _, name := range []string{KeyName, PreviousKeyName} {
+		key, err := v.Store.Lookup(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		if key != "" {
+			keys = append(keys, []byte(key))
+		}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("httpsign: %s is not set", KeyName)
+	}
+	return keys, nil
+}
+
+// Middleware answers 401 to requests that fail Verify.
+func (v *Verifier) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := v.Verify(r); err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = fmt.Fprintf(w, `{"error":%q}`, err.Error())
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
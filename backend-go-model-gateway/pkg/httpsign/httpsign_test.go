@@ -0,0 +1,116 @@
+package httpsign
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"backend-go-model-gateway/pkg/secrets"
+)
+
+func newRequest(body string) *http.Request {
+	return httptest.NewRequest(http.MethodPost, "http://memory:8003/memory/store?x=1", strings.NewReader(body))
+}
+
+func TestSignVerify(t *testing.T) {
+	key := []byte("k1")
+	now := time.Unix(1_760_518_800, 0)
+	req := newRequest(`{"session_id":"s1"}`)
+	if err := Sign(req, key, now); err != nil {
+		t.Fatal(err)
+	}
+	if err := Verify(req, [][]byte{[]byte("old"), key}, time.Minute, now.Add(30*time.Second)); err != nil {
+		t.Fatalf("Verify = %v", err)
+	}
+	if b, _ := io.ReadAll(req.Body); string(b) != `{"session_id":"s1"}` {
+		t.Fatalf("body after Verify = %q", b)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		tamper func(r *http.Request)
+		at     time.Time
+		want   error
+	}{
+		{"body changed", func(r *http.Request) { r.Body = io.NopCloser(strings.NewReader(`{"session_id":"s2"}`)) }, now, ErrBadSignature},
+		{"path changed", func(r *http.Request) { r.URL.Path = "/memory/playbook" }, now, ErrBadSignature},
+		{"method changed", func(r *http.Request) { r.Method = http.MethodPut }, now, ErrBadSignature},
+		{"replayed late", func(*http.Request) {}, now.Add(2 * time.Minute), ErrExpired},
+		{"unsigned", func(r *http.Request) { r.Header.Del(SignatureHeader) }, now, ErrUnsigned},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := newRequest(`{"session_id":"s1"}`)
+			if err := Sign(r, key, now); err != nil {
+				t.Fatal(err)
+			}
+			tc.tamper(r)
+			if err := Verify(r, [][]byte{key}, time.Minute, tc.at); !errors.Is(err, tc.want) {
+				t.Fatalf("Verify = %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
+
+// TestSignVectors pins signatures that backend-python-memory's
+// test_request_signing.py verifies, so the Go and Python sides agree.
+func TestSignVectors(t *testing.T) {
+	for _, tc := range []struct {
+		method, url, body, want string
+	}{
+		{http.MethodPost, "http://memory:8003/memory/store?session_id=s%201&x=1", `{"session_id":"s 1","history":[]}`, "v1=906f2d1ac0d1543666cbadf555c1a6bb8fc85124171e70d40c04402cf16d2c0f"},
+		{http.MethodDelete, "http://memory:8003/memory/session?session_id=abc", "", "v1=80a70cc50bc8029fcecef1b53fc0063ab9e89fbe422e62649c50ea7fa7a0a1e2"},
+	} {
+		req := httptest.NewRequest(tc.method, tc.url, strings.NewReader(tc.body))
+		if err := Sign(req, []byte("test-key"), time.Unix(1700000000, 0)); err != nil {
+			t.Fatal(err)
+		}
+		if got := req.Header.Get("X-Pagi-Signature"); got != tc.want {
+			t.Errorf("%s %s: signature = %s, want %s", tc.method, tc.url, got, tc.want)
+		}
+	}
+}
+
+func TestTransportAndMiddleware(t *testing.T) {
+	env := map[string]string{KeyName: "new", PreviousKeyName: "old"}
+	verifier := &Verifier{Store: secrets.New(secrets.Options{Env: func(k string) string { return env[k] }})}
+	var got string
+	srv := httptest.NewServer(verifier.Middleware(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		got = string(b)
+	})))
+	defer srv.Close()
+
+	post := func(key string) int {
+		t.Helper()
+		store := secrets.New(secrets.Options{Env: func(k string) string {
+			if k == KeyName {
+				return key
+			}
+			return ""
+		}})
+		client := &http.Client{Transport: &Transport{Store: store}}
+		resp, err := client.Post(srv.URL+"/memory/store", "application/json", strings.NewReader(`{"a":1}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if code := post("new"); code != http.StatusOK || got != `{"a":1}` {
+		t.Fatalf("signed with the current key: %d, body %q", code, got)
+	}
+	if code := post("old"); code != http.StatusOK {
+		t.Fatalf("signed with the previous key: %d, want 200 during rotation", code)
+	}
+	if code := post("other"); code != http.StatusUnauthorized {
+		t.Fatalf("signed with a wrong key: %d, want 401", code)
+	}
+	if code := post(""); code != http.StatusUnauthorized {
+		t.Fatalf("unsigned: %d, want 401", code)
+	}
+}
@@ -0,0 +1,209 @@
+// Package jsonschema validates decoded JSON against a JSON Schema. It covers
+// the subset the gateway's output schemas use: type, properties, required,
+// additionalProperties, items, minItems, maxItems, minLength, maxLength and
+// enum. Other keywords, such as $schema, title and description, are ignored.
+//
+// Problems are worded for a model to correct its output, e.g.
+// "/steps/0: must be a string".
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Schema is a parsed JSON Schema.
+type Schema struct {
+	Type                 Types              `json:"type,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Additional        `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+}
+
+// Types is the "type" keyword: one type name or a list of them.
+type Types []string
+
+// UnmarshalJSON accepts "string" as well as ["string", "null"].
+func (t *Types) UnmarshalJSON(b []byte) error {
+	var one string
+	if err := json.Unmarshal(b, &one); err == nil {
+		*t = Types{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(b, &many); err != nil {
+		return fmt.Errorf("type: want a string or an array of strings")
+	}
+	*t = many
+	return nil
+}
+
+// Additional is the "additionalProperties" keyword: false forbids properties
+// not listed in "properties", and a schema constrains them.
+type Additional struct {
+	Forbidden bool
+	Schema    *Schema
+}
+
+// UnmarshalJSON accepts a boolean or a schema.
+func (a *Additional) UnmarshalJSON(b []byte) error {
+	var allowed bool
+	if err := json.Unmarshal(b, &allowed); err == nil {
+		a.Forbidden = !allowed
+		return nil
+	}
+	return json.Unmarshal(b, &a.Schema)
+}
+
+// Parse parses a schema document.
+func Parse(doc []byte) (*Schema, error) {
+	var s Schema
+	if err := json.Unmarshal(doc, &s); err != nil {
+		return nil, fmt.Errorf("parse schema: %w", err)
+	}
+	return &s, nil
+}
+
+// MustParse is Parse for schemas compiled into the binary.
+func MustParse(doc string) *Schema {
+	s, err := Parse([]byte(doc))
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// Validate checks v, a value decoded by encoding/json into any, and returns
+// its problems; none means v is valid.
+func (s *Schema) Validate(v any) []string {
+	var problems []string
+	s.validate("", v, &problems)
+	return problems
+}
+
+func (s *Schema) validate(path string, v any, problems *[]string) {
+	if s == nil {
+		return
+	}
+	at := path
+	if at == "" {
+		at = "/"
+	}
+	report := func(format string, args ...any) {
+		*problems = append(*problems, at+": "+fmt.Sprintf(format, args...))
+	}
+
+	if len(s.Type) > 0 && !slices.ContainsFunc(s.Type, func(t string) bool { return hasType(v, t) }) {
+		report("must be %s, got %s", article(strings.Join(s.Type, " or ")), typeOf(v))
+		return
+	}
+	if len(s.Enum) > 0 && !slices.ContainsFunc(s.Enum, func(e any) bool { return reflect.DeepEqual(e, v) }) {
+		b, _ := json.Marshal(s.Enum)
+		report("must be one of %s", b)
+	}
+
+	switch v := v.(type) {
+	case string:
+		n := utf8.RuneCountInString(v)
+		if s.MinLength != nil && n < *s.MinLength {
+			if *s.MinLength == 1 {
+				report("must not be empty")
+			} else {
+				report("must be at least %d characters", *s.MinLength)
+			}
+		}
+		if s.MaxLength != nil && n > *s.MaxLength {
+			report("must be at most %d characters", *s.MaxLength)
+		}
+	case []any:
+		if s.MinItems != nil && len(v) < *s.MinItems {
+			if *s.MinItems == 1 {
+				report("must not be empty")
+			} else {
+				report("must have at least %d items", *s.MinItems)
+			}
+		}
+		if s.MaxItems != nil && len(v) > *s.MaxItems {
+			report("must have at most %d items", *s.MaxItems)
+		}
+		for i, item := range v {
+			s.Items.validate(fmt.Sprintf("%s/%d", path, i), item, problems)
+		}
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				report("missing required property %q", name)
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if prop, ok := s.Properties[name]; ok {
+				prop.validate(path+"/"+name, v[name], problems)
+				continue
+			}
+			if a := s.AdditionalProperties; a != nil {
+				if a.Forbidden {
+					report("unexpected property %q", name)
+				} else {
+					a.Schema.validate(path+"/"+name, v[name], problems)
+				}
+			}
+		}
+	}
+}
+
+// hasType reports whether v is of JSON schema type t.
+func hasType(v any, t string) bool {
+	switch t {
+	case "integer":
+		f, ok := v.(float64)
+		return ok && f == math.Trunc(f)
+	case "number":
+		_, ok := v.(float64)
+		return ok
+	default:
+		return typeOf(v) == t
+	}
+}
+
+func typeOf(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+func article(t string) string {
+	if t != "" && strings.ContainsAny(t[:1], "aeiou") {
+		return "an " + t
+	}
+	return "a " + t
+}
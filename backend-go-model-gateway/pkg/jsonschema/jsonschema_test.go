@@ -0,0 +1,52 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	s := MustParse(`{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"type": "object",
+		"required": ["name", "tags"],
+		"properties": {
+			"name": {"type": "string", "minLength": 1},
+			"tags": {"type": "array", "minItems": 1, "items": {"type": "string"}},
+			"count": {"type": ["integer", "null"]},
+			"mode": {"enum": ["fast", "slow"]}
+		},
+		"additionalProperties": false
+	}`)
+
+	cases := []struct {
+		doc  string
+		want []string
+	}{
+		{`{"name":"a","tags":["x"],"count":2,"mode":"fast"}`, nil},
+		{`{"name":"a","tags":["x"],"count":null}`, nil},
+		{`[]`, []string{"/: must be an object, got array"}},
+		{`{"tags":[]}`, []string{`/: missing required property "name"`, "/tags: must not be empty"}},
+		{`{"name":"","tags":["x",3],"count":1.5,"mode":"medium","extra":true}`, []string{
+			"/count: must be an integer or null, got number",
+			`/: unexpected property "extra"`,
+			`/mode: must be one of ["fast","slow"]`,
+			"/name: must not be empty",
+			"/tags/1: must be a string, got number",
+		}},
+	}
+	for _, c := range cases {
+		var v any
+		if err := json.Unmarshal([]byte(c.doc), &v); err != nil {
+			t.Fatal(err)
+		}
+		if got := s.Validate(v); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s:\n got %q\nwant %q", c.doc, got, c.want)
+		}
+	}
+
+	if _, err := Parse([]byte(`{"type": 3}`)); err == nil {
+		t.Fatal("Parse accepted a numeric type")
+	}
+}
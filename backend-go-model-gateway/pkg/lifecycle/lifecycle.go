@@ -0,0 +1,213 @@
+// Package lifecycle owns the long-lived goroutines of a service: servers,
+// consumers and background workers run as named components of a Group.
+//
+// The Group's context is cancelled on SIGINT/SIGTERM, by Stop, or as soon as
+// any component returns, so one failing server takes the service down instead
+// of leaving it half alive. Components must return once the context is done;
+// Wait gives them ShutdownTimeout and then reports the ones still running
+// rather than hanging on (or silently leaking) them.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// DefaultShutdownTimeout is used when Options.ShutdownTimeout is zero.
+const DefaultShutdownTimeout = 10 * time.Second
+
+// ErrShutdownTimeout is returned by Wait when components outlive the shutdown
+// timeout.
+var ErrShutdownTimeout = errors.New("components did not stop within the shutdown timeout")
+
+// errStopped is the cancellation cause for Stop; signals use ErrSignal.
+var errStopped = errors.New("stopped")
+
+// ErrSignal is the context cause when a shutdown signal arrived.
+var ErrSignal = errors.New("shutdown signal received")
+
+// Options configures a Group.
+type Options struct {
+	// ShutdownTimeout is how long servers get to finish in-flight requests and
+	// Wait gives the remaining components once shutdown starts.
+	ShutdownTimeout time.Duration
+	// Signals that start shutdown (default SIGINT, SIGTERM; empty slice: none).
+	Signals []os.Signal
+	// OnExit is called as each component returns, with its error if any.
+	OnExit func(component string, err error)
+}
+
+// Group runs components until the first one exits or shutdown is requested.
+type Group struct {
+	opts   Options
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	wg     sync.WaitGroup
+
+	mu      sync.Mutex
+	err     error
+	running map[string]int
+
+	stopSignals func()
+}
+
+// New returns a Group and its context, which is done once shutdown starts.
+func New(parent context.Context, opts Options) (*Group, context.Context) {
+	if opts.ShutdownTimeout <= 0 {
+		opts.ShutdownTimeout = DefaultShutdownTimeout
+	}
+	if opts.Signals == nil {
+		opts.Signals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+	ctx, cancel := context.WithCancelCause(parent)
+	g := &Group{opts: opts, ctx: ctx, cancel: cancel, running: map[string]int{}, stopSignals: func() {}}
+
+	if len(opts.Signals) > 0 {
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, opts.Signals...)
+		done := make(chan struct{})
+		go func() {
+			select {
+			case s := <-sig:
+				cancel(fmt.Errorf("%w: %s", ErrSignal, s))
+			case <-done:
+			}
+		}()
+		var once sync.Once
+		g.stopSignals = func() {
+			once.Do(func() {
+				signal.Stop(sig)
+				close(done)
+			})
+		}
+	}
+	return g, ctx
+}
+
+// Go runs a component. run must return once ctx is done. If it returns
+// earlier, with or without an error, the whole Group shuts down. Returning
+// ctx's context.Canceled is not an error.
+func (g *Group) Go(name string, run func(ctx context.Context) error) {
+	g.mu.Lock()
+	g.running[name]++
+	g.mu.Unlock()
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		err := run(g.ctx)
+		g.mu.Lock()
+		if g.running[name]--; g.running[name] == 0 {
+			delete(g.running, name)
+		}
+		if err != nil && !errors.Is(err, context.Canceled) && g.err == nil {
+			g.err = fmt.Errorf("%s: %w", name, err)
+		}
+		g.mu.Unlock()
+		if g.opts.OnExit != nil {
+			g.opts.OnExit(name, err)
+		}
+		g.cancel(fmt.Errorf("%s exited", name))
+	}()
+}
+
+// HTTPServer runs srv as a component; on shutdown it stops accepting
+// connections and waits up to ShutdownTimeout for active requests.
+func (g *Group) HTTPServer(name string, srv *http.Server) {
+	g.Go(name, func(ctx context.Context) error {
+		errc := make(chan error, 1)
+		go func() { errc <- srv.ListenAndServe() }()
+		select {
+		case err := <-errc:
+			return err
+		case <-ctx.Done():
+		}
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), g.opts.ShutdownTimeout)
+		defer cancel()
+		err := srv.Shutdown(shutdownCtx)
+		if serveErr := <-errc; !errors.Is(serveErr, http.ErrServerClosed) && err == nil {
+			err = serveErr
+		}
+		return err
+	})
+}
+
+// GRPCServer serves srv on lis as a component; on shutdown it stops
+// gracefully, forcing the remaining RPCs closed after ShutdownTimeout.
+func (g *Group) GRPCServer(name string, srv *grpc.Server, lis net.Listener) {
+	g.Go(name, func(ctx context.Context) error {
+		errc := make(chan error, 1)
+		go func() { errc <- srv.Serve(lis) }()
+		select {
+		case err := <-errc:
+			return err
+		case <-ctx.Done():
+		}
+		stopped := make(chan struct{})
+		go func() {
+			srv.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-time.After(g.opts.ShutdownTimeout):
+			srv.Stop()
+			<-stopped
+		}
+		return <-errc
+	})
+}
+
+// Stop starts shutdown.
+func (g *Group) Stop() {
+	g.cancel(errStopped)
+}
+
+// Wait blocks until shutdown starts and every component has returned, or the
+// shutdown timeout passes. It returns the first component error, or
+// ErrShutdownTimeout naming the components still running.
+func (g *Group) Wait() error {
+	defer g.stopSignals()
+	<-g.ctx.Done()
+
+	done := make(chan struct{})
+	go func() {
+		g.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(g.opts.ShutdownTimeout):
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		names := make([]string, 0, len(g.running))
+		for name := range g.running {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("%w: %s", ErrShutdownTimeout, strings.Join(names, ", "))
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.err
+}
+
+// Cause reports why shutdown started, e.g. ErrSignal or "<component> exited";
+// nil while the Group is running.
+func (g *Group) Cause() error {
+	if g.ctx.Err() == nil {
+		return nil
+	}
+	return context.Cause(g.ctx)
+}
@@ -0,0 +1,72 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+func TestGroup_FirstExitStopsEverything(t *testing.T) {
+	var mu sync.Mutex
+	var exits []string
+	g, _ := New(context.Background(), Options{Signals: []os.Signal{}, OnExit: func(name string, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		exits = append(exits, name)
+	}})
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.GRPCServer("grpc", grpc.NewServer(), lis)
+	g.HTTPServer("http", &http.Server{Addr: "127.0.0.1:0", Handler: http.NotFoundHandler()})
+	g.Go("worker", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	boom := errors.New("consumer lost its connection")
+	g.Go("consumer", func(context.Context) error { return boom })
+
+	if err := g.Wait(); !errors.Is(err, boom) || !strings.HasPrefix(err.Error(), "consumer: ") {
+		t.Fatalf("Wait = %v, want the consumer's error", err)
+	}
+	if len(exits) != 4 {
+		t.Fatalf("exits = %v, want every component", exits)
+	}
+	if cause := g.Cause(); cause == nil || !strings.Contains(cause.Error(), "consumer exited") {
+		t.Fatalf("Cause = %v", cause)
+	}
+}
+
+func TestGroup_StopAndStuckComponents(t *testing.T) {
+	g, ctx := New(context.Background(), Options{Signals: []os.Signal{}})
+	g.Go("worker", func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+	g.Stop()
+	if err := g.Wait(); err != nil || ctx.Err() == nil {
+		t.Fatalf("Wait after Stop = %v (ctx %v)", err, ctx.Err())
+	}
+
+	g, _ = New(context.Background(), Options{Signals: []os.Signal{}, ShutdownTimeout: 20 * time.Millisecond})
+	release := make(chan struct{})
+	defer close(release)
+	g.Go("leaky", func(context.Context) error {
+		<-release // ignores ctx
+		return nil
+	})
+	g.Stop()
+	if err := g.Wait(); !errors.Is(err, ErrShutdownTimeout) || !strings.Contains(err.Error(), "leaky") {
+		t.Fatalf("Wait with a stuck component = %v", err)
+	}
+}
@@ -0,0 +1,280 @@
+package mockprovider
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	pb "backend-go-model-gateway/proto/proto"
+)
+
+// Fixtures are scripted mock responses, loaded from a directory of JSON
+// files. Each file holds one Fixture or an array of them. Fixtures are tried
+// in file name order, then in order within a file; the first whose RPC and
+// Match fit the request and that has a response left answers it. Requests no
+// fixture answers get the built-in heuristics.
+//
+// A fixture's responses are served in order, counted per session, so a
+// multi-turn tool flow replays the same way for every session.
+type Fixtures struct {
+	fixtures []*Fixture
+
+	mu     sync.Mutex
+	served map[servedKey]int
+}
+
+type servedKey struct {
+	fixture int
+	session string
+}
+
+// Fixture is one scripted exchange.
+type Fixture struct {
+	// Name identifies the fixture in logs (default: the file name, with
+	// the index for arrays).
+	Name string `json:"name"`
+	// RPC is "plan" (GetPlan, the default) or "chat".
+	RPC string `json:"rpc"`
+	// Match is a regular expression on the prompt (GetPlan) or the last
+	// user message (Chat). Empty matches every request.
+	Match string `json:"match"`
+	// Responses are served one per matching request.
+	Responses []Response `json:"responses"`
+	// Repeat keeps serving the last response once the others are used up;
+	// otherwise the fixture stops matching.
+	Repeat bool `json:"repeat"`
+
+	match *regexp.Regexp
+}
+
+// Response is one scripted reply. Exactly one field is set: Plan, Tool or
+// Steps for GetPlan, Content for Chat, or Error for either.
+type Response struct {
+	// Plan is returned verbatim as the plan.
+	Plan json.RawMessage `json:"plan,omitempty"`
+	// Tool is a tool call, as the built-in web_search heuristic returns.
+	Tool *ToolCall `json:"tool,omitempty"`
+	// Steps is a final plan with these steps.
+	Steps []string `json:"steps,omitempty"`
+	// Content is a Chat reply.
+	Content string `json:"content,omitempty"`
+	// Error fails the request as a provider would.
+	Error *Error `json:"error,omitempty"`
+}
+
+// ToolCall is a scripted tool call.
+type ToolCall struct {
+	Name string         `json:"name"`
+	Args map[string]any `json:"args"`
+}
+
+// Error is a scripted provider failure: an HTTP status, such as 429 or 503,
+// and a message. The gateway treats it like the real provider's error.
+type Error struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("mock fixture: status %d: %s", e.Status, e.Message)
+}
+
+// LoadFixtures reads every *.json file in dir.
+func LoadFixtures(dir string) (*Fixtures, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		if _, err := os.Stat(dir); err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(paths)
+	f := &Fixtures{served: map[servedKey]int{}}
+	for _, path := range paths {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var fixtures []*Fixture
+		if raw = bytes.TrimSpace(raw); len(raw) > 0 && raw[0] == '[' {
+			err = json.Unmarshal(raw, &fixtures)
+		} else {
+			fixtures = []*Fixture{{}}
+			err = json.Unmarshal(raw, fixtures[0])
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		base := filepath.Base(path)
+		for i, fixture := range fixtures {
+			if fixture.Name == "" {
+				fixture.Name = base
+				if len(fixtures) > 1 {
+					fixture.Name = fmt.Sprintf("%s[%d]", base, i)
+				}
+			}
+			if err := fixture.compile(); err != nil {
+				return nil, fmt.Errorf("%s: fixture %q: %w", path, fixture.Name, err)
+			}
+			f.fixtures = append(f.fixtures, fixture)
+		}
+	}
+	return f, nil
+}
+
+func (x *Fixture) compile() error {
+	switch x.RPC {
+	case "":
+		x.RPC = "plan"
+	case "plan", "chat":
+	default:
+		return fmt.Errorf("rpc must be plan or chat, got %q", x.RPC)
+	}
+	re, err := regexp.Compile(x.Match)
+	if err != nil {
+		return fmt.Errorf("match: %w", err)
+	}
+	x.match = re
+	if len(x.Responses) == 0 {
+		return errors.New("no responses")
+	}
+	for i, r := range x.Responses {
+		set := 0
+		for _, ok := range []bool{len(r.Plan) > 0, r.Tool != nil, len(r.Steps) > 0, r.Content != "", r.Error != nil} {
+			if ok {
+				set++
+			}
+		}
+		switch {
+		case set != 1:
+			return fmt.Errorf("response %d: want exactly one of plan, tool, steps, content or error", i)
+		case r.Error != nil && (r.Error.Status < 400 || r.Error.Status > 599):
+			return fmt.Errorf("response %d: error status %d is not a 4xx or 5xx", i, r.Error.Status)
+		case x.RPC == "chat" && r.Content == "" && r.Error == nil:
+			return fmt.Errorf("response %d: chat fixtures answer with content or error", i)
+		case x.RPC == "plan" && r.Content != "":
+			return fmt.Errorf("response %d: plan fixtures answer with plan, tool, steps or error", i)
+		case len(r.Plan) > 0 && !json.Valid(r.Plan):
+			return fmt.Errorf("response %d: plan is not JSON", i)
+		case r.Tool != nil && r.Tool.Name == "":
+			return fmt.Errorf("response %d: tool needs a name", i)
+		}
+	}
+	return nil
+}
+
+// Len is the number of fixtures loaded.
+func (f *Fixtures) Len() int {
+	if f == nil {
+		return 0
+	}
+	return len(f.fixtures)
+}
+
+// next picks the response for a request, or returns ok false.
+func (f *Fixtures) next(rpc, text, session string) (r Response, name string, turn int, ok bool) {
+	if f == nil {
+		return Response{}, "", 0, false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, fixture := range f.fixtures {
+		if fixture.RPC != rpc || !fixture.match.MatchString(text) {
+			continue
+		}
+		key := servedKey{fixture: i, session: session}
+		n := f.served[key]
+		if n >= len(fixture.Responses) {
+			if !fixture.Repeat {
+				continue
+			}
+			n = len(fixture.Responses) - 1
+		}
+		f.served[key]++
+		return fixture.Responses[n], fixture.Name, n + 1, true
+	}
+	return Response{}, "", 0, false
+}
+
+// Plan answers a GetPlan from the fixtures. fixture is the name of the one
+// that answered, with turn its 1-based position; it is empty when none
+// applies and the caller should use BuildPlanResponse. A scripted failure is
+// returned as an *Error.
+func (f *Fixtures) Plan(in *pb.PlanRequest, session string, requestStart time.Time) (resp *pb.PlanResponse, fixture string, turn int, err error) {
+	r, fixture, turn, ok := f.next("plan", in.GetPrompt(), session)
+	if !ok {
+		return nil, "", 0, nil
+	}
+	switch {
+	case r.Error != nil:
+		return nil, fixture, turn, r.Error
+	case r.Tool != nil:
+		return toolPlan(in, r.Tool.Name, r.Tool.Args, requestStart), fixture, turn, nil
+	case len(r.Steps) > 0:
+		return stepsPlan(in, r.Steps, requestStart), fixture, turn, nil
+	default:
+		var plan bytes.Buffer
+		if err := json.Compact(&plan, r.Plan); err != nil {
+			return nil, fixture, turn, err
+		}
+		return &pb.PlanResponse{Plan: plan.String(), ModelName: ModelName, LatencyMs: time.Since(requestStart).Milliseconds()}, fixture, turn, nil
+	}
+}
+
+// Chat answers a Chat request from the fixtures, like Plan.
+func (f *Fixtures) Chat(in *pb.ChatRequest, session string, requestStart time.Time) (resp *pb.ChatResponse, fixture string, turn int, err error) {
+	r, fixture, turn, ok := f.next("chat", lastUserText(in), session)
+	if !ok {
+		return nil, "", 0, nil
+	}
+	if r.Error != nil {
+		return nil, fixture, turn, r.Error
+	}
+	return &pb.ChatResponse{
+		Content:      r.Content,
+		Model:        ModelName,
+		FinishReason: "stop",
+		LatencyMs:    time.Since(requestStart).Milliseconds(),
+	}, fixture, turn, nil
+}
+
+// Reset forgets which responses were served, so every session starts over.
+func (f *Fixtures) Reset() {
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.served = map[servedKey]int{}
+}
+
+// lastUserText is the text of the last user message.
+func lastUserText(in *pb.ChatRequest) string {
+	last := ""
+	for _, m := range in.GetMessages() {
+		if m.GetRole() != "user" {
+			continue
+		}
+		last = m.GetContent()
+		if len(m.GetParts()) > 0 {
+			var texts []string
+			for _, p := range m.GetParts() {
+				if p.GetType() == "text" {
+					texts = append(texts, p.GetText())
+				}
+			}
+			last = strings.Join(texts, " ")
+		}
+	}
+	return last
+}
@@ -0,0 +1,101 @@
+package mockprovider
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	pb "backend-go-model-gateway/proto/proto"
+)
+
+func writeFixtures(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, body := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestFixtures_SequencePerSession(t *testing.T) {
+	f, err := LoadFixtures(writeFixtures(t, map[string]string{
+		"10_tools.json": `{"match": "(?i)weather", "responses": [
+			{"tool": {"name": "web_search", "args": {"query": "weather"}}},
+			{"plan": {"model_type": "mock", "answer": "sunny"}}
+		]}`,
+		"20_chat.json": `[{"rpc": "chat", "responses": [{"content": "scripted"}], "repeat": true}]`,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Len() != 2 {
+		t.Fatalf("Len = %d", f.Len())
+	}
+	in := &pb.PlanRequest{Prompt: "What's the weather?"}
+	for _, session := range []string{"a", "b"} {
+		resp, fixture, turn, err := f.Plan(in, session, time.Now())
+		if err != nil || fixture != "10_tools.json" || turn != 1 || !strings.Contains(resp.GetPlan(), `"web_search"`) {
+			t.Fatalf("%s turn 1 = %v, %q, %d, %v", session, resp, fixture, turn, err)
+		}
+		resp, _, turn, _ = f.Plan(in, session, time.Now())
+		if turn != 2 || resp.GetPlan() != `{"model_type":"mock","answer":"sunny"}` {
+			t.Fatalf("%s turn 2 = %q (%d)", session, resp.GetPlan(), turn)
+		}
+		// Used up without repeat: the built-in heuristics take over.
+		if _, fixture, _, _ := f.Plan(in, session, time.Now()); fixture != "" {
+			t.Fatalf("%s: exhausted fixture %q still answered", session, fixture)
+		}
+	}
+	if _, fixture, _, _ := f.Plan(&pb.PlanRequest{Prompt: "hello"}, "a", time.Now()); fixture != "" {
+		t.Fatalf("unmatched prompt answered by %q", fixture)
+	}
+
+	chat := &pb.ChatRequest{Messages: []*pb.ChatMessage{{Role: "user", Content: "hi"}}}
+	for range 3 {
+		if resp, _, _, _ := f.Chat(chat, "a", time.Now()); resp.GetContent() != "scripted" {
+			t.Fatalf("chat = %v", resp)
+		}
+	}
+
+	f.Reset()
+	if _, _, turn, _ := f.Plan(in, "a", time.Now()); turn != 1 {
+		t.Fatalf("after Reset turn = %d", turn)
+	}
+}
+
+func TestFixtures_ScriptedError(t *testing.T) {
+	f, err := LoadFixtures(writeFixtures(t, map[string]string{
+		"outage.json": `{"responses": [{"error": {"status": 503, "message": "down"}}]}`,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, fixture, _, err := f.Plan(&pb.PlanRequest{Prompt: "x"}, "", time.Now())
+	var scripted *Error
+	if fixture != "outage.json" || !errors.As(err, &scripted) || scripted.Status != 503 {
+		t.Fatalf("fixture %q, err %v", fixture, err)
+	}
+}
+
+func TestLoadFixtures_Invalid(t *testing.T) {
+	for name, body := range map[string]string{
+		"no responses":   `{"match": "x"}`,
+		"two kinds":      `{"responses": [{"steps": ["a"], "content": "b"}]}`,
+		"chat with plan": `{"rpc": "chat", "responses": [{"steps": ["a"]}]}`,
+		"bad regexp":     `{"match": "(", "responses": [{"steps": ["a"]}]}`,
+		"bad status":     `{"responses": [{"error": {"status": 200}}]}`,
+		"bad rpc":        `{"rpc": "embed", "responses": [{"steps": ["a"]}]}`,
+	} {
+		if _, err := LoadFixtures(writeFixtures(t, map[string]string{"f.json": body})); err == nil {
+			t.Errorf("%s: loaded", name)
+		}
+	}
+	if _, err := LoadFixtures(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("missing directory loaded")
+	}
+}
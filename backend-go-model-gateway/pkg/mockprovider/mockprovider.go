@@ -0,0 +1,143 @@
+// Package mockprovider implements the gateway's zero-dependency "mock" LLM
+// provider. It is shared by the gateway binary and in-process test harnesses.
+package mockprovider
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"strings"
+	"time"
+
+	"backend-go-model-gateway/pkg/answereval"
+	pb "backend-go-model-gateway/proto/proto"
+)
+
+// ModelName is reported in PlanResponse.model_name and the plan's model_type.
+const ModelName = "mock"
+
+// BuildPlanResponse returns a deterministic strict-JSON plan for the request.
+//
+// This keeps the stack usable out-of-the-box without any API keys and also
+// serves as a resilience fallback when upstream LLM providers rate-limit.
+func BuildPlanResponse(in *pb.PlanRequest, requestStart time.Time) *pb.PlanResponse {
+	prompt := strings.TrimSpace(in.GetPrompt())
+	lower := strings.ToLower(prompt)
+
+	// Once the planner has fed a tool result back, finish with a plan instead of
+	// calling the tool again so multi-turn loops terminate.
+	hasToolResult := strings.Contains(lower, "<tool_result>")
+	for _, m := range in.GetMessages() {
+		if m.GetRole() == "user" && strings.Contains(m.GetContent(), "<tool_result>") {
+			hasToolResult = true
+		}
+	}
+	// A persona may leave web_search out of allowed_tools.
+	canSearch := len(in.GetAllowedTools()) == 0 || slices.Contains(in.GetAllowedTools(), "web_search")
+
+	// Heuristic: if the user asks for “latest” / “search” / “web”, emit a tool call.
+	if !hasToolResult && canSearch && (strings.Contains(lower, "search") || strings.Contains(lower, "web") || strings.Contains(lower, "latest")) {
+		return toolPlan(in, "web_search", map[string]any{"query": prompt}, requestStart)
+	}
+
+	steps := []string{
+		"Restate the objective in one sentence and identify constraints.",
+		"Propose a minimal 3-step plan with clear inputs/outputs.",
+		"Return the plan as strict JSON for downstream parsing.",
+	}
+	if hasToolResult {
+		steps = []string{
+			"Review the tool result provided in <tool_result>.",
+			"Summarize the relevant findings for the user.",
+			"Return the final answer as strict JSON for downstream parsing.",
+		}
+	}
+	return stepsPlan(in, steps, requestStart)
+}
+
+// toolPlan is a plan calling one tool.
+func toolPlan(in *pb.PlanRequest, name string, args map[string]any, requestStart time.Time) *pb.PlanResponse {
+	if args == nil {
+		args = map[string]any{}
+	}
+	payload := map[string]any{
+		"model_type": ModelName,
+		"prompt":     in.GetPrompt(),
+		"tool": map[string]any{
+			"name": name,
+			"args": args,
+		},
+	}
+	b, _ := json.Marshal(payload)
+	return &pb.PlanResponse{Plan: string(b), ModelName: ModelName, LatencyMs: time.Since(requestStart).Milliseconds()}
+}
+
+// stepsPlan is a final plan with the given steps.
+func stepsPlan(in *pb.PlanRequest, steps []string, requestStart time.Time) *pb.PlanResponse {
+	payload := map[string]any{
+		"model_type": ModelName,
+		"prompt":     in.GetPrompt(),
+		"steps":      steps,
+	}
+	b, _ := json.Marshal(payload)
+	return &pb.PlanResponse{Plan: string(b), ModelName: ModelName, LatencyMs: time.Since(requestStart).Milliseconds()}
+}
+
+// ExecuteTool runs a tool against canned data, for planners whose gateway is
+// in mock mode and which may have no sandbox to call. web_search returns
+// the same results for the same query; other tools answer "unknown_tool",
+// as the sandbox does.
+func ExecuteTool(in *pb.ToolRequest) *pb.ToolResponse {
+	args := map[string]any{}
+	_ = json.Unmarshal([]byte(in.GetArgsJson()), &args)
+	if in.GetToolName() != "web_search" {
+		b, _ := json.MarshalIndent(map[string]any{"message": "Unknown tool", "tool_name": in.GetToolName(), "echo": args}, "", "  ")
+		return &pb.ToolResponse{Status: "unknown_tool", Stdout: string(b)}
+	}
+	query, _ := args["query"].(string)
+	query = strings.TrimSpace(query)
+	// The same query always gets the same URLs.
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(query))
+	id := fmt.Sprintf("%08x", h.Sum32())
+	b, _ := json.MarshalIndent(map[string]any{
+		"query": query,
+		"results": []map[string]string{
+			{
+				"title":   "Mock search result",
+				"url":     "https://example.com/mock/" + id,
+				"snippet": "Canned result from the mock provider; no web request was made.",
+			},
+			{
+				"title":   "Second mock search result",
+				"url":     "https://example.org/mock/" + id + "/2",
+				"snippet": "Another canned result, so multi-result handling can be demoed.",
+			},
+		},
+	}, "", "  ")
+	return &pb.ToolResponse{Status: "ok", Stdout: string(b)}
+}
+
+// Evaluate grades an answer with answereval's word-overlap heuristic.
+func Evaluate(in *pb.EvaluateRequest) *pb.EvaluateResponse {
+	return answereval.Heuristic(in.GetPrompt(), in.GetAnswer(), in.GetContext()).Response("word-overlap heuristic", ModelName)
+}
+
+// mockChatEcho caps the user text a mock chat reply repeats.
+const mockChatEcho = 200
+
+// Chat answers a chat request with the last user message's text (the first
+// 200 characters), so callers of the Chat RPC can be run without a provider.
+func Chat(in *pb.ChatRequest, requestStart time.Time) *pb.ChatResponse {
+	echo := []rune(strings.TrimSpace(lastUserText(in)))
+	if len(echo) > mockChatEcho {
+		echo = echo[:mockChatEcho]
+	}
+	return &pb.ChatResponse{
+		Content:      "Mock reply: " + string(echo),
+		Model:        ModelName,
+		FinishReason: "stop",
+		LatencyMs:    time.Since(requestStart).Milliseconds(),
+	}
+}
@@ -0,0 +1,91 @@
+// Package promptguard spots prompt-injection attempts in retrieved text:
+// documents that address the model instead of informing it. RAG snippets are
+// pasted into prompts verbatim, so a poisoned document can otherwise override
+// the system prompt or smuggle in a tool call.
+//
+// Scan is pattern based and deliberately conservative: it looks for
+// instruction overrides, role hijacks, prompt-exfiltration requests, tool-call
+// JSON in the planner's or OpenAI's format, and tags that would close or open
+// the prompt's own sections. Both the planner and the gateway use it to
+// quarantine snippets before building a prompt.
+package promptguard
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Modes of a scan, as set by AGENT_RAG_INJECTION and RAG_INJECTION.
+const (
+	// ModeQuarantine drops flagged snippets from the prompt.
+	ModeQuarantine = "quarantine"
+	// ModeFlag only reports them.
+	ModeFlag = "flag"
+	// ModeOff skips scanning.
+	ModeOff = "off"
+)
+
+// ParseMode validates a mode setting; "" is ModeQuarantine.
+func ParseMode(s string) (string, bool) {
+	switch m := strings.ToLower(strings.TrimSpace(s)); m {
+	case "", ModeQuarantine:
+		return ModeQuarantine, true
+	case ModeFlag, ModeOff:
+		return m, true
+	}
+	return "", false
+}
+
+type rule struct {
+	name    string
+	pattern *regexp.Regexp
+}
+
+var rules = []rule{
+	{"instruction_override", regexp.MustCompile(`(?i)\b(ignore|disregard|forget|override)\s+(all\s+|any\s+)?(the\s+|your\s+)?(previous|prior|above|earlier|preceding|system)\s+(instructions?|prompts?|rules|directions|messages?)\b`)},
+	{"instruction_override", regexp.MustCompile(`(?i)\bnew\s+(system\s+)?instructions?\s*:`)},
+	{"role_hijack", regexp.MustCompile(`(?i)\byou\s+are\s+now\s+(a|an|in|the)\b|\bact\s+as\s+(an?\s+)?(unrestricted|jailbroken|dan)\b|\bdeveloper\s+mode\s+(enabled|on)\b`)},
+	{"role_hijack", regexp.MustCompile(`(?im)^\s*(system|assistant)(\s+prompt)?\s*:\s*(you\s+(are|must|will|should)|ignore|from\s+now\s+on)\b`)},
+	{"prompt_exfiltration", regexp.MustCompile(`(?i)\b(reveal|print|repeat|output|show)\s+(me\s+)?(your|the)\s+(system\s+prompt|hidden\s+instructions|initial\s+instructions)\b`)},
+	{"tool_call_json", regexp.MustCompile(`(?is)\{\s*"tool"\s*:\s*\{\s*"name"\s*:`)},
+	{"tool_call_json", regexp.MustCompile(`(?is)"(tool_calls|function_call)"\s*:\s*[\[{]`)},
+	{"prompt_markup", regexp.MustCompile(`(?i)</?\s*(context|system|plan|tool_result|instructions)\s*>|<\|(im_start|im_end|system|endoftext)\|>|\[/?INST\]`)},
+}
+
+// PlaybookKB holds the playbooks the planner learns from its own runs. They
+// record the tool calls of those runs, so tool-call JSON is expected there.
+const PlaybookKB = "Mind-KB"
+
+// ScanMatch is Scan for a snippet retrieved from kb: tool_call_json is not
+// reported for PlaybookKB.
+func ScanMatch(kb, text string) []string {
+	hits := Scan(text)
+	if kb != PlaybookKB {
+		return hits
+	}
+	out := hits[:0]
+	for _, h := range hits {
+		if h != "tool_call_json" {
+			out = append(out, h)
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+// Scan returns the names of the rules text matches, in rule order without
+// repeats; none means the text looks clean.
+func Scan(text string) []string {
+	var hits []string
+	for _, r := range rules {
+		if len(hits) > 0 && hits[len(hits)-1] == r.name {
+			continue
+		}
+		if r.pattern.MatchString(text) {
+			hits = append(hits, r.name)
+		}
+	}
+	return hits
+}
@@ -0,0 +1,131 @@
+// Package ragfilter defines the metadata filters that scope RAG retrieval
+// (source, tags, document ID, creation date range) and converts them to and
+// from the RAGFilter protobuf message.
+//
+// Set fields are ANDed: a document matches when its source is one of Sources,
+// it carries every tag in Tags, its ID is one of DocumentIDs, and it was
+// created inside [CreatedAfter, CreatedBefore). Empty fields do not filter.
+//
+// Backends translate a Filter into their native query language; Match is the
+// reference semantics for in-process stores and fakes.
+package ragfilter
+
+import (
+	"slices"
+	"time"
+
+	pb "backend-go-model-gateway/proto/proto"
+)
+
+// Filter is a structured metadata filter. The JSON form is what HTTP callers
+// (the planner's /plan endpoint) accept.
+type Filter struct {
+	Sources       []string  `json:"sources,omitempty"`
+	Tags          []string  `json:"tags,omitempty"`
+	DocumentIDs   []string  `json:"document_ids,omitempty"`
+	CreatedAfter  time.Time `json:"created_after,omitzero"`
+	CreatedBefore time.Time `json:"created_before,omitzero"`
+	// WithinDays is a relative lower bound ("from the last 30 days"), resolved
+	// against the current time by Resolve.
+	WithinDays int `json:"within_days,omitempty"`
+}
+
+// Metadata is what Match needs to know about a document.
+type Metadata struct {
+	ID        string
+	Source    string
+	Tags      []string
+	CreatedAt time.Time
+}
+
+// IsZero reports whether f filters nothing. A nil filter is zero.
+func (f *Filter) IsZero() bool {
+	return f == nil || (len(f.Sources) == 0 && len(f.Tags) == 0 && len(f.DocumentIDs) == 0 &&
+		f.CreatedAfter.IsZero() && f.CreatedBefore.IsZero() && f.WithinDays <= 0)
+}
+
+// Resolve returns a copy of f with WithinDays folded into CreatedAfter (the
+// later of the two bounds wins). It returns nil for a zero filter.
+func (f *Filter) Resolve(now time.Time) *Filter {
+	if f.IsZero() {
+		return nil
+	}
+	out := *f
+	if out.WithinDays > 0 {
+		after := now.AddDate(0, 0, -out.WithinDays)
+		if after.After(out.CreatedAfter) {
+			out.CreatedAfter = after
+		}
+		out.WithinDays = 0
+	}
+	return &out
+}
+
+// Match reports whether a document with metadata m passes f. A document with
+// no creation time fails any date bound.
+func (f *Filter) Match(m Metadata) bool {
+	if f.IsZero() {
+		return true
+	}
+	if len(f.Sources) > 0 && !slices.Contains(f.Sources, m.Source) {
+		return false
+	}
+	if len(f.DocumentIDs) > 0 && !slices.Contains(f.DocumentIDs, m.ID) {
+		return false
+	}
+	for _, tag := range f.Tags {
+		if !slices.Contains(m.Tags, tag) {
+			return false
+		}
+	}
+	if !f.CreatedAfter.IsZero() && (m.CreatedAt.IsZero() || m.CreatedAt.Before(f.CreatedAfter)) {
+		return false
+	}
+	if !f.CreatedBefore.IsZero() && (m.CreatedAt.IsZero() || !m.CreatedAt.Before(f.CreatedBefore)) {
+		return false
+	}
+	return true
+}
+
+// FromProto converts the wire form. It returns nil for a nil or empty message.
+func FromProto(p *pb.RAGFilter) *Filter {
+	if p == nil {
+		return nil
+	}
+	f := &Filter{
+		Sources:     p.GetSources(),
+		Tags:        p.GetTags(),
+		DocumentIDs: p.GetDocumentIds(),
+	}
+	if s := p.GetCreatedAfterUnix(); s != 0 {
+		f.CreatedAfter = time.Unix(s, 0).UTC()
+	}
+	if s := p.GetCreatedBeforeUnix(); s != 0 {
+		f.CreatedBefore = time.Unix(s, 0).UTC()
+	}
+	if f.IsZero() {
+		return nil
+	}
+	return f
+}
+
+// Proto converts f to the wire form, resolving WithinDays against now. It
+// returns nil for a zero filter.
+func (f *Filter) Proto(now time.Time) *pb.RAGFilter {
+	r := f.Resolve(now)
+	if r == nil {
+		return nil
+	}
+	p := &pb.RAGFilter{
+		Sources:     r.Sources,
+		Tags:        r.Tags,
+		DocumentIds: r.DocumentIDs,
+	}
+	if !r.CreatedAfter.IsZero() {
+		p.CreatedAfterUnix = r.CreatedAfter.Unix()
+	}
+	if !r.CreatedBefore.IsZero() {
+		p.CreatedBeforeUnix = r.CreatedBefore.Unix()
+	}
+	return p
+}
@@ -0,0 +1,67 @@
+package ragfilter
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	pb "backend-go-model-gateway/proto/proto"
+)
+
+func TestFilterMatch(t *testing.T) {
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	doc := Metadata{ID: "doc-1", Source: "journal", Tags: []string{"health", "sleep"}, CreatedAt: now.AddDate(0, 0, -3)}
+
+	cases := []struct {
+		name   string
+		filter *Filter
+		want   bool
+	}{
+		{"nil", nil, true},
+		{"source", &Filter{Sources: []string{"wiki", "journal"}}, true},
+		{"other source", &Filter{Sources: []string{"wiki"}}, false},
+		{"all tags", &Filter{Tags: []string{"health", "sleep"}}, true},
+		{"missing tag", &Filter{Tags: []string{"health", "diet"}}, false},
+		{"document id", &Filter{DocumentIDs: []string{"doc-1"}}, true},
+		{"within range", &Filter{CreatedAfter: now.AddDate(0, 0, -30), CreatedBefore: now}, true},
+		{"before range", &Filter{CreatedAfter: now.AddDate(0, 0, -1)}, false},
+		{"upper bound is exclusive", &Filter{CreatedBefore: doc.CreatedAt}, false},
+		{"combined", &Filter{Sources: []string{"journal"}, Tags: []string{"health"}, DocumentIDs: []string{"doc-2"}}, false},
+	}
+	for _, tc := range cases {
+		if got := tc.filter.Match(doc); got != tc.want {
+			t.Errorf("%s: Match = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+
+	if (&Filter{CreatedAfter: now}).Match(Metadata{ID: "undated"}) {
+		t.Error("an undated document must fail a date bound")
+	}
+}
+
+func TestFilterWithinDaysAndProto(t *testing.T) {
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	var f Filter
+	if err := json.Unmarshal([]byte(`{"tags":["health"],"within_days":30,"created_before":"2026-10-10T00:00:00Z"}`), &f); err != nil {
+		t.Fatal(err)
+	}
+
+	p := f.Proto(now)
+	if got, want := p.GetCreatedAfterUnix(), now.AddDate(0, 0, -30).Unix(); got != want {
+		t.Errorf("created_after_unix = %d, want %d", got, want)
+	}
+	back := FromProto(p)
+	if back.Tags[0] != "health" || !back.CreatedBefore.Equal(time.Date(2026, 10, 10, 0, 0, 0, 0, time.UTC)) || back.WithinDays != 0 {
+		t.Errorf("round trip = %+v", back)
+	}
+
+	// An explicit later lower bound wins over within_days.
+	later := now.AddDate(0, 0, -2)
+	if r := (&Filter{CreatedAfter: later, WithinDays: 30}).Resolve(now); !r.CreatedAfter.Equal(later) {
+		t.Errorf("Resolve kept %v, want %v", r.CreatedAfter, later)
+	}
+
+	if (&Filter{}).Proto(now) != nil || FromProto(&pb.RAGFilter{}) != nil {
+		t.Error("empty filters should convert to nil")
+	}
+}
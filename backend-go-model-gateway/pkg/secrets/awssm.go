@@ -0,0 +1,65 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+)
+
+// awsFetcher reads AWS Secrets Manager secrets.
+//
+// Reference format: <secret-id>[#<json-key>]. The secret id may be a name or a
+// full ARN. Without a key the whole SecretString is returned; with a key the
+// SecretString is parsed as a JSON object and that field returned.
+type awsFetcher struct {
+	once   sync.Once
+	client *secretsmanager.Client
+	err    error
+}
+
+func (a *awsFetcher) init(ctx context.Context) error {
+	a.once.Do(func() {
+		cfg, err := config.LoadDefaultConfig(ctx)
+		if err != nil {
+			a.err = fmt.Errorf("awssm: load AWS config: %w", err)
+			return
+		}
+		a.client = secretsmanager.NewFromConfig(cfg)
+	})
+	return a.err
+}
+
+func (a *awsFetcher) fetch(ctx context.Context, ref string) (string, error) {
+	if err := a.init(ctx); err != nil {
+		return "", err
+	}
+	id, key, _ := strings.Cut(ref, "#")
+	if id == "" {
+		return "", fmt.Errorf("awssm: empty secret id in %q", ref)
+	}
+
+	out, err := a.client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{SecretId: aws.String(id)})
+	if err != nil {
+		return "", fmt.Errorf("awssm: %s: %w", id, err)
+	}
+	val := aws.ToString(out.SecretString)
+	if key == "" {
+		return val, nil
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(val), &fields); err != nil {
+		return "", fmt.Errorf("awssm: %s is not a JSON object (needed for #%s): %w", id, key, err)
+	}
+	s, ok := fields[key].(string)
+	if !ok {
+		return "", fmt.Errorf("awssm: key %q not found in %s", key, id)
+	}
+	return s, nil
+}
@@ -0,0 +1,107 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// PEM returns PEM material for name. The legacy NAME_PATH variable (e.g.
+// TLS_CA_CERT_PATH) wins when set; otherwise NAME / NAME_FILE are resolved
+// like any other secret (so TLS_CA_CERT=vault://pki/pagi#ca works).
+func (s *Store) PEM(ctx context.Context, name string) ([]byte, error) {
+	env := os.Getenv
+	if s != nil {
+		env = s.env
+	}
+	if path := env(name + "_PATH"); path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read %s (%s): %w", name, path, err)
+		}
+		return b, nil
+	}
+	v, err := s.Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(v), nil
+}
+
+// PEMConfigured reports whether name is available as NAME_PATH, NAME or NAME_FILE.
+func (s *Store) PEMConfigured(name string) bool {
+	env := os.Getenv
+	if s != nil {
+		env = s.env
+	}
+	return env(name+"_PATH") != "" || s.Configured(name)
+}
+
+// ConfigureRedis authenticates every new connection in opts with the current
+// REDIS_USERNAME / REDIS_PASSWORD secrets, so a rotated password is used as
+// soon as the pool dials again. It is a no-op when REDIS_PASSWORD is unset.
+func (s *Store) ConfigureRedis(opts *redis.Options) {
+	if opts == nil || !s.Configured("REDIS_PASSWORD") {
+		return
+	}
+	next := opts.OnConnect
+	opts.OnConnect = func(ctx context.Context, cn *redis.Conn) error {
+		password, err := s.Get(ctx, "REDIS_PASSWORD")
+		if err != nil {
+			return err
+		}
+		username, err := s.Lookup(ctx, "REDIS_USERNAME")
+		if err != nil {
+			return err
+		}
+		if username != "" {
+			err = cn.AuthACL(ctx, username, password).Err()
+		} else {
+			err = cn.Auth(ctx, password).Err()
+		}
+		if err != nil {
+			return fmt.Errorf("redis auth: %w", err)
+		}
+		if next != nil {
+			return next(ctx, cn)
+		}
+		return nil
+	}
+}
+
+// BearerTransport sets "Authorization: Bearer <secret>" on every request from
+// the current value of name, so an HTTP client keeps working across key
+// rotation. Base defaults to http.DefaultTransport.
+type BearerTransport struct {
+	Store *Store
+	Name  string
+	Base  http.RoundTripper
+	// Header, when set, carries the bare secret instead of Authorization
+	// (e.g. "x-api-key").
+	Header string
+}
+
+func (t *BearerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.Store.Get(req.Context(), t.Name)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if token == "" {
+		return base.RoundTrip(req)
+	}
+	r := req.Clone(req.Context())
+	if t.Header != "" {
+		r.Header.Set(t.Header, token)
+	} else {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	return base.RoundTrip(r)
+}
@@ -0,0 +1,209 @@
+// Package secrets resolves credentials (OPENROUTER_API_KEY, PAGI_API_KEY,
+// REDIS_PASSWORD, TLS material, ...) from secret stores instead of plaintext
+// environment variables. It is shared by the gateway and the planner.
+//
+// For a secret NAME the Store looks at, in order:
+//
+//	NAME_FILE=/run/secrets/name                  file contents (Docker/K8s secret mounts)
+//	NAME=vault://secret/pagi#openrouter_api_key  Vault KV (v2 by default; ?kv=1 for v1)
+//	NAME=awssm://pagi/prod#OPENROUTER_API_KEY    AWS Secrets Manager (name or ARN; #key selects a JSON field)
+//	NAME=file:///run/secrets/name                file contents
+//	NAME=sk-or-...                               the literal value (previous behaviour)
+//
+// Values fetched from a store or file are cached and re-fetched after
+// PAGI_SECRETS_REFRESH_SECONDS (default 300), so rotated credentials are picked
+// up without a restart. If a refresh fails the last good value keeps being
+// served.
+//
+// Vault is reached at VAULT_ADDR with VAULT_TOKEN (or VAULT_TOKEN_FILE) and the
+// optional VAULT_NAMESPACE. AWS uses the default SDK credential chain
+// (env, shared config, IRSA, instance role).
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned when a secret is not configured at all.
+var ErrNotFound = errors.New("secrets: not configured")
+
+const defaultRefresh = 5 * time.Minute
+
+// fetcher resolves a reference (the part after "scheme://") for one backend.
+type fetcher interface {
+	fetch(ctx context.Context, ref string) (string, error)
+}
+
+// Options configures a Store.
+type Options struct {
+	// Refresh is how long a fetched value is served before it is re-fetched.
+	Refresh time.Duration
+	// Env looks up configuration; defaults to os.Getenv (overridable in tests).
+	Env func(string) string
+}
+
+// OptionsFromEnv reads PAGI_SECRETS_REFRESH_SECONDS.
+func OptionsFromEnv() Options {
+	opts := Options{Refresh: defaultRefresh}
+	if v := os.Getenv("PAGI_SECRETS_REFRESH_SECONDS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			opts.Refresh = time.Duration(n) * time.Second
+		}
+	}
+	return opts
+}
+
+type cacheEntry struct {
+	value     string
+	fetchedAt time.Time
+}
+
+// Store resolves and caches secrets. The zero value is not usable; use New.
+// A nil *Store falls back to plain environment variables.
+type Store struct {
+	refresh time.Duration
+	env     func(string) string
+
+	mu       sync.Mutex
+	cache    map[string]cacheEntry
+	fetchers map[string]fetcher
+}
+
+// New returns a Store with the file, Vault and AWS Secrets Manager backends.
+func New(opts Options) *Store {
+	if opts.Refresh <= 0 {
+		opts.Refresh = defaultRefresh
+	}
+	if opts.Env == nil {
+		opts.Env = os.Getenv
+	}
+	s := &Store{
+		refresh: opts.Refresh,
+		env:     opts.Env,
+		cache:   make(map[string]cacheEntry),
+	}
+	s.fetchers = map[string]fetcher{
+		"file":  fileFetcher{},
+		"vault": newVaultFetcher(opts.Env),
+		"awssm": &awsFetcher{},
+	}
+	return s
+}
+
+// FromEnv is shorthand for New(OptionsFromEnv()).
+func FromEnv() *Store {
+	return New(OptionsFromEnv())
+}
+
+// Get returns the current value of secret name, or ErrNotFound when neither
+// NAME nor NAME_FILE is set.
+func (s *Store) Get(ctx context.Context, name string) (string, error) {
+	if s == nil {
+		if v := os.Getenv(name); v != "" {
+			return v, nil
+		}
+		return "", ErrNotFound
+	}
+
+	scheme, ref := "", ""
+	if path := strings.TrimSpace(s.env(name + "_FILE")); path != "" {
+		scheme, ref = "file", path
+	} else {
+		raw := s.env(name)
+		if raw == "" {
+			return "", ErrNotFound
+		}
+		var ok bool
+		scheme, ref, ok = parseRef(raw)
+		if !ok {
+			return raw, nil
+		}
+	}
+
+	key := scheme + "://" + ref
+	s.mu.Lock()
+	cached, hit := s.cache[key]
+	s.mu.Unlock()
+	if hit && time.Since(cached.fetchedAt) < s.refresh {
+		return cached.value, nil
+	}
+
+	v, err := s.fetchers[scheme].fetch(ctx, ref)
+	if err != nil {
+		if hit {
+			// Keep serving the last good value; the backend may be briefly down.
+			return cached.value, nil
+		}
+		return "", fmt.Errorf("secret %s: %w", name, err)
+	}
+	s.mu.Lock()
+	s.cache[key] = cacheEntry{value: v, fetchedAt: time.Now()}
+	s.mu.Unlock()
+	return v, nil
+}
+
+// Lookup is Get for optional secrets: it returns "" when the secret is unset.
+// Fetch errors are still returned.
+func (s *Store) Lookup(ctx context.Context, name string) (string, error) {
+	v, err := s.Get(ctx, name)
+	if errors.Is(err, ErrNotFound) {
+		return "", nil
+	}
+	return v, err
+}
+
+// Expire marks every cached value stale, so the next Get re-fetches it, e.g.
+// when an operator reloads configuration after rotating a credential. As with
+// a scheduled refresh, the last good value is served if the fetch fails.
+func (s *Store) Expire() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, entry := range s.cache {
+		entry.fetchedAt = time.Time{}
+		s.cache[key] = entry
+	}
+}
+
+// Configured reports whether name (or name_FILE) is set, without fetching.
+func (s *Store) Configured(name string) bool {
+	env := os.Getenv
+	if s != nil {
+		env = s.env
+	}
+	return env(name) != "" || strings.TrimSpace(env(name+"_FILE")) != ""
+}
+
+// parseRef splits "scheme://ref" for the supported backends.
+func parseRef(raw string) (scheme, ref string, ok bool) {
+	for _, sch := range []string{"file", "vault", "awssm"} {
+		if rest, found := strings.CutPrefix(raw, sch+"://"); found {
+			return sch, rest, true
+		}
+	}
+	return "", "", false
+}
+
+type fileFetcher struct{}
+
+func (fileFetcher) fetch(_ context.Context, path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	// Mounted secrets commonly end with a newline; PEM blocks keep theirs.
+	v := string(b)
+	if !strings.Contains(v, "-----BEGIN ") {
+		v = strings.TrimRight(v, "\r\n")
+	}
+	return v, nil
+}
@@ -0,0 +1,133 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func mapEnv(m map[string]string) func(string) string {
+	return func(k string) string { return m[k] }
+}
+
+func TestGetSources(t *testing.T) {
+	dir := t.TempDir()
+	mounted := filepath.Join(dir, "api_key")
+	if err := os.WriteFile(mounted, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	s := New(Options{Env: mapEnv(map[string]string{
+		"LITERAL":      "plain-value",
+		"MOUNTED_FILE": mounted,
+		"MOUNTED":      "ignored-when-file-set",
+		"REF":          "file://" + mounted,
+	})})
+	ctx := context.Background()
+
+	for name, want := range map[string]string{"LITERAL": "plain-value", "MOUNTED": "from-file", "REF": "from-file"} {
+		got, err := s.Get(ctx, name)
+		if err != nil || got != want {
+			t.Errorf("Get(%s) = %q, %v; want %q", name, got, err, want)
+		}
+	}
+	if _, err := s.Get(ctx, "MISSING"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get(MISSING) err = %v, want ErrNotFound", err)
+	}
+	if v, err := s.Lookup(ctx, "MISSING"); v != "" || err != nil {
+		t.Errorf("Lookup(MISSING) = %q, %v", v, err)
+	}
+
+	// A rewritten file is served from the cache until Expire.
+	if err := os.WriteFile(mounted, []byte("rotated\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := s.Get(ctx, "MOUNTED"); got != "from-file" {
+		t.Errorf("cached Get(MOUNTED) = %q, want from-file", got)
+	}
+	s.Expire()
+	if got, _ := s.Get(ctx, "MOUNTED"); got != "rotated" {
+		t.Errorf("Get(MOUNTED) after Expire = %q, want rotated", got)
+	}
+}
+
+func TestVaultRefreshAndStaleFallback(t *testing.T) {
+	value := "v1"
+	up := true
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root" || r.URL.Path != "/v1/secret/data/pagi" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		if !up {
+			http.Error(w, "sealed", http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":{"data":{"openrouter_api_key":"` + value + `"}}}`))
+	}))
+	t.Cleanup(vault.Close)
+
+	s := New(Options{Refresh: 10 * time.Millisecond, Env: mapEnv(map[string]string{
+		"VAULT_ADDR":         vault.URL,
+		"VAULT_TOKEN":        "root",
+		"OPENROUTER_API_KEY": "vault://secret/pagi#openrouter_api_key",
+	})})
+	ctx := context.Background()
+
+	if got, err := s.Get(ctx, "OPENROUTER_API_KEY"); err != nil || got != "v1" {
+		t.Fatalf("first Get = %q, %v", got, err)
+	}
+
+	value = "v2"
+	time.Sleep(20 * time.Millisecond)
+	if got, _ := s.Get(ctx, "OPENROUTER_API_KEY"); got != "v2" {
+		t.Fatalf("after rotation Get = %q, want v2", got)
+	}
+
+	up = false
+	time.Sleep(20 * time.Millisecond)
+	if got, err := s.Get(ctx, "OPENROUTER_API_KEY"); err != nil || got != "v2" {
+		t.Fatalf("with vault down Get = %q, %v; want stale v2", got, err)
+	}
+}
+
+func TestBearerTransport(t *testing.T) {
+	var seen string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = r.Header.Get("Authorization")
+	}))
+	t.Cleanup(srv.Close)
+
+	s := New(Options{Env: mapEnv(map[string]string{"API_KEY": "k-123"})})
+	client := &http.Client{Transport: &BearerTransport{Store: s, Name: "API_KEY"}}
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req.Header.Set("Authorization", "Bearer stale")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if seen != "Bearer k-123" {
+		t.Fatalf("Authorization = %q", seen)
+	}
+
+	var apiKey string
+	srv2 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen, apiKey = r.Header.Get("Authorization"), r.Header.Get("X-Api-Key")
+	}))
+	t.Cleanup(srv2.Close)
+	client = &http.Client{Transport: &BearerTransport{Store: s, Name: "API_KEY", Header: "x-api-key"}}
+	resp, err = client.Get(srv2.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if apiKey != "k-123" || seen != "" {
+		t.Fatalf("x-api-key = %q, Authorization = %q", apiKey, seen)
+	}
+}
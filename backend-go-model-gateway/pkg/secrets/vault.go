@@ -0,0 +1,103 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// vaultFetcher reads Vault KV secrets over the HTTP API.
+//
+// Reference format: <mount>/<path>#<key>[?kv=1]. KV v2 is assumed, so
+// "secret/pagi#api_key" reads GET /v1/secret/data/pagi and returns
+// data.data.api_key.
+type vaultFetcher struct {
+	env    func(string) string
+	client *http.Client
+}
+
+func newVaultFetcher(env func(string) string) *vaultFetcher {
+	return &vaultFetcher{env: env, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (v *vaultFetcher) token() (string, error) {
+	if t := v.env("VAULT_TOKEN"); t != "" {
+		return t, nil
+	}
+	if path := v.env("VAULT_TOKEN_FILE"); path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("vault token file: %w", err)
+		}
+		return strings.TrimSpace(string(b)), nil
+	}
+	return "", errors.New("vault: VAULT_TOKEN or VAULT_TOKEN_FILE is required")
+}
+
+func (v *vaultFetcher) fetch(ctx context.Context, ref string) (string, error) {
+	addr := strings.TrimRight(v.env("VAULT_ADDR"), "/")
+	if addr == "" {
+		return "", errors.New("vault: VAULT_ADDR is not set")
+	}
+	token, err := v.token()
+	if err != nil {
+		return "", err
+	}
+
+	kvVersion := "2"
+	if base, query, ok := strings.Cut(ref, "?"); ok {
+		ref = base
+		if strings.Contains(query, "kv=1") {
+			kvVersion = "1"
+		}
+	}
+	path, key, _ := strings.Cut(ref, "#")
+	path = strings.Trim(path, "/")
+	mount, rest, ok := strings.Cut(path, "/")
+	if !ok || rest == "" || key == "" {
+		return "", fmt.Errorf("vault: reference %q must look like <mount>/<path>#<key>", ref)
+	}
+	apiPath := mount + "/" + rest
+	if kvVersion == "2" {
+		apiPath = mount + "/data/" + rest
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, addr+"/v1/"+apiPath, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if ns := v.env("VAULT_NAMESPACE"); ns != "" {
+		req.Header.Set("X-Vault-Namespace", ns)
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("vault: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault: GET %s: HTTP %d", apiPath, resp.StatusCode)
+	}
+
+	var body struct {
+		Data map[string]any `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("vault: decode %s: %w", apiPath, err)
+	}
+	data := body.Data
+	if kvVersion == "2" {
+		inner, _ := body.Data["data"].(map[string]any)
+		data = inner
+	}
+	val, ok := data[key].(string)
+	if !ok {
+		return "", fmt.Errorf("vault: key %q not found at %s", key, path)
+	}
+	return val, nil
+}
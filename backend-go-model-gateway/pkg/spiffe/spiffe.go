@@ -0,0 +1,366 @@
+// Package spiffe obtains mTLS identities from a SPIFFE Workload API endpoint
+// (a SPIRE agent socket) instead of static PEM files, for zero-trust clusters.
+//
+// A Source streams the workload's X.509-SVID (certificate chain and key) and
+// the trust bundles from the Workload API; the agent pushes a new SVID before
+// the current one expires, so rotation needs no restart or file watching.
+// Peers are authenticated by their SPIFFE ID (the spiffe:// URI SAN of their
+// certificate), verified against the bundle of the peer's trust domain, and
+// then authorized by an Authorizer. Host names play no part.
+//
+// Services opt in with TLS_SOURCE=spiffe and SPIFFE_ENDPOINT_SOCKET (e.g.
+// unix:///run/spire/sockets/agent.sock); SPIFFE_AUTHORIZED_IDS lists the
+// peers allowed to connect (see AuthorizerFromEnv).
+package spiffe
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"backend-go-model-gateway/pkg/spiffe/workloadpb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+)
+
+// headerKey is the metadata every Workload API call must carry, so the agent
+// can tell API clients from SSRF-style requests.
+const headerKey = "workload.spiffe.io"
+
+// firstSVIDTimeout bounds how long NewSource waits for the first SVID, e.g.
+// while the agent is still attesting the workload.
+const firstSVIDTimeout = 30 * time.Second
+
+// Options configures a Source.
+type Options struct {
+	// Addr is the Workload API endpoint: unix:///path/to/agent.sock or
+	// tcp://host:port.
+	Addr string
+	// OnUpdate, when set, is called after every SVID update and every
+	// stream failure.
+	OnUpdate func(Event)
+}
+
+// Event describes an SVID update or a Workload API failure.
+type Event struct {
+	// ID and NotAfter describe the SVID being served.
+	ID       string
+	NotAfter time.Time
+	// Err is set when the stream to the Workload API failed; the current
+	// SVID keeps being served while the Source reconnects.
+	Err error
+}
+
+// OptionsFromEnv reads SPIFFE_ENDPOINT_SOCKET.
+func OptionsFromEnv() (Options, error) {
+	addr := strings.TrimSpace(os.Getenv("SPIFFE_ENDPOINT_SOCKET"))
+	if addr == "" {
+		return Options{}, errors.New("SPIFFE_ENDPOINT_SOCKET is required when TLS_SOURCE=spiffe")
+	}
+	return Options{Addr: addr}, nil
+}
+
+type svid struct {
+	id       string
+	cert     tls.Certificate
+	notAfter time.Time
+	// bundles holds the CA pool of the SVID's own trust domain and of every
+	// federated one, keyed by trust domain ID (spiffe://example.org).
+	bundles map[string]*x509.CertPool
+}
+
+// Source keeps the workload's current SVID and trust bundles. It is safe for
+// concurrent use.
+type Source struct {
+	opts   Options
+	conn   *grpc.ClientConn
+	cancel context.CancelFunc
+	done   chan struct{}
+	cur    atomic.Pointer[svid]
+}
+
+// NewSource connects to the Workload API and waits for the first SVID. The
+// stream runs until Close.
+func NewSource(ctx context.Context, opts Options) (*Source, error) {
+	target := opts.Addr
+	switch {
+	case strings.HasPrefix(target, "unix://"):
+	case strings.HasPrefix(target, "tcp://"):
+		target = strings.TrimPrefix(target, "tcp://")
+	case strings.HasPrefix(target, "/"):
+		target = "unix://" + target
+	default:
+		return nil, fmt.Errorf("SPIFFE_ENDPOINT_SOCKET: want unix:///path or tcp://host:port, got %q", opts.Addr)
+	}
+	conn, err := grpc.NewClient(target, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("workload API: %w", err)
+	}
+
+	watchCtx, cancel := context.WithCancel(context.Background())
+	s := &Source{opts: opts, conn: conn, cancel: cancel, done: make(chan struct{})}
+	first := make(chan struct{})
+	go s.watch(watchCtx, first)
+
+	wait, stop := context.WithTimeout(ctx, firstSVIDTimeout)
+	defer stop()
+	select {
+	case <-first:
+		return s, nil
+	case <-wait.Done():
+		s.Close()
+		return nil, fmt.Errorf("workload API %s: no X.509-SVID received: %w", opts.Addr, wait.Err())
+	}
+}
+
+// Close stops the stream.
+func (s *Source) Close() {
+	if s == nil {
+		return
+	}
+	s.cancel()
+	<-s.done
+	_ = s.conn.Close()
+}
+
+// ID is the workload's own SPIFFE ID.
+func (s *Source) ID() string {
+	return s.cur.Load().id
+}
+
+// NotAfter is the expiry of the SVID being served.
+func (s *Source) NotAfter() time.Time {
+	return s.cur.Load().notAfter
+}
+
+// watch streams SVID updates, reconnecting with backoff, until ctx ends. first
+// is closed once the first SVID is in place.
+func (s *Source) watch(ctx context.Context, first chan struct{}) {
+	defer close(s.done)
+	client := workloadpb.NewSpiffeWorkloadAPIClient(s.conn)
+	backoff := time.Second
+	for ctx.Err() == nil {
+		err := s.stream(metadata.AppendToOutgoingContext(ctx, headerKey, "true"), client, first, &backoff)
+		if ctx.Err() != nil {
+			return
+		}
+		if cur := s.cur.Load(); cur != nil {
+			s.notify(Event{ID: cur.id, NotAfter: cur.notAfter, Err: err})
+		} else {
+			s.notify(Event{Err: err})
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, 30*time.Second)
+	}
+}
+
+func (s *Source) stream(ctx context.Context, client workloadpb.SpiffeWorkloadAPIClient, first chan struct{}, backoff *time.Duration) error {
+	stream, err := client.FetchX509SVID(ctx, &workloadpb.X509SVIDRequest{})
+	if err != nil {
+		return err
+	}
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		next, err := parseResponse(resp)
+		if err != nil {
+			return err
+		}
+		*backoff = time.Second
+		wasEmpty := s.cur.Swap(next) == nil
+		s.notify(Event{ID: next.id, NotAfter: next.notAfter})
+		if wasEmpty {
+			close(first)
+		}
+	}
+}
+
+func (s *Source) notify(e Event) {
+	if s.opts.OnUpdate != nil {
+		s.opts.OnUpdate(e)
+	}
+}
+
+// parseResponse takes the default (first) SVID and the bundles from resp.
+func parseResponse(resp *workloadpb.X509SVIDResponse) (*svid, error) {
+	if len(resp.GetSvids()) == 0 {
+		return nil, errors.New("workload API: response has no SVIDs")
+	}
+	pb := resp.GetSvids()[0]
+	chain, err := x509.ParseCertificates(pb.GetX509Svid())
+	if err != nil || len(chain) == 0 {
+		return nil, fmt.Errorf("workload API: parse SVID %s: %v", pb.GetSpiffeId(), err)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(pb.GetX509SvidKey())
+	if err != nil {
+		return nil, fmt.Errorf("workload API: parse SVID key %s: %w", pb.GetSpiffeId(), err)
+	}
+	id, err := IDFromCert(chain[0])
+	if err != nil {
+		return nil, fmt.Errorf("workload API: %w", err)
+	}
+
+	out := &svid{id: id, notAfter: chain[0].NotAfter, bundles: map[string]*x509.CertPool{}}
+	out.cert = tls.Certificate{PrivateKey: key, Leaf: chain[0]}
+	for _, c := range chain {
+		out.cert.Certificate = append(out.cert.Certificate, c.Raw)
+	}
+	if out.bundles[TrustDomain(id)], err = parseBundle(pb.GetBundle()); err != nil {
+		return nil, fmt.Errorf("workload API: bundle for %s: %w", TrustDomain(id), err)
+	}
+	for td, der := range resp.GetFederatedBundles() {
+		if out.bundles[td], err = parseBundle(der); err != nil {
+			return nil, fmt.Errorf("workload API: federated bundle for %s: %w", td, err)
+		}
+	}
+	return out, nil
+}
+
+func parseBundle(der []byte) (*x509.CertPool, error) {
+	certs, err := x509.ParseCertificates(der)
+	if err != nil {
+		return nil, err
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("empty bundle")
+	}
+	pool := x509.NewCertPool()
+	for _, c := range certs {
+		pool.AddCert(c)
+	}
+	return pool, nil
+}
+
+// IDFromCert returns the SPIFFE ID of an X.509-SVID: its one spiffe:// URI SAN.
+func IDFromCert(cert *x509.Certificate) (string, error) {
+	var ids []string
+	for _, u := range cert.URIs {
+		if u.Scheme == "spiffe" {
+			ids = append(ids, u.String())
+		}
+	}
+	if len(ids) != 1 {
+		return "", fmt.Errorf("certificate %q has %d SPIFFE IDs, want 1", cert.Subject.CommonName, len(ids))
+	}
+	return ids[0], nil
+}
+
+// TrustDomain returns the trust domain ID of a SPIFFE ID:
+// spiffe://example.org/ns/prod/sa/planner → spiffe://example.org.
+func TrustDomain(id string) string {
+	u, err := url.Parse(id)
+	if err != nil || u.Scheme != "spiffe" {
+		return ""
+	}
+	return "spiffe://" + u.Host
+}
+
+// Authorizer decides whether a peer with an authenticated SPIFFE ID may
+// connect; a nil error allows it.
+type Authorizer func(id string) error
+
+// AuthorizeIDs allows peers whose ID is listed, or whose trust domain is
+// listed (spiffe://example.org allows every workload there).
+func AuthorizeIDs(allowed ...string) Authorizer {
+	return func(id string) error {
+		if slices.Contains(allowed, id) || slices.Contains(allowed, TrustDomain(id)) {
+			return nil
+		}
+		return fmt.Errorf("spiffe: peer %s is not authorized", id)
+	}
+}
+
+// AuthorizerFromEnv reads SPIFFE_AUTHORIZED_IDS, comma-separated SPIFFE IDs or
+// trust domain IDs (see AuthorizeIDs). Unset, it allows any workload in the
+// source's own trust domain.
+func AuthorizerFromEnv(s *Source) Authorizer {
+	var allowed []string
+	for _, id := range strings.Split(os.Getenv("SPIFFE_AUTHORIZED_IDS"), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			allowed = append(allowed, id)
+		}
+	}
+	if len(allowed) == 0 {
+		allowed = []string{TrustDomain(s.ID())}
+	}
+	return AuthorizeIDs(allowed...)
+}
+
+// ServerConfig returns a server config that serves the current SVID and
+// requires clients to present an SVID that verifies against their trust
+// domain's bundle and passes authorize.
+func (s *Source) ServerConfig(authorize Authorizer) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		NextProtos: []string{"h2"},
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return &s.cur.Load().cert, nil
+		},
+		// Chains are verified against the current bundles in VerifyConnection,
+		// since ClientCAs would fix them at startup.
+		ClientAuth: tls.RequireAnyClientCert,
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			return s.verifyPeer(cs.PeerCertificates, authorize)
+		},
+	}
+}
+
+// ClientConfig returns a client config that presents the current SVID and
+// requires the server's SVID to verify against its trust domain's bundle and
+// pass authorize. The server's host name is not checked: SPIFFE IDs replace
+// it.
+func (s *Source) ClientConfig(authorize Authorizer) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		NextProtos: []string{"h2"},
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return &s.cur.Load().cert, nil
+		},
+		InsecureSkipVerify: true,
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			return s.verifyPeer(cs.PeerCertificates, authorize)
+		},
+	}
+}
+
+func (s *Source) verifyPeer(certs []*x509.Certificate, authorize Authorizer) error {
+	if len(certs) == 0 {
+		return errors.New("spiffe: peer presented no certificate")
+	}
+	id, err := IDFromCert(certs[0])
+	if err != nil {
+		return fmt.Errorf("spiffe: %w", err)
+	}
+	roots, ok := s.cur.Load().bundles[TrustDomain(id)]
+	if !ok {
+		return fmt.Errorf("spiffe: no trust bundle for peer %s", id)
+	}
+	opts := x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: x509.NewCertPool(),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}
+	for _, c := range certs[1:] {
+		opts.Intermediates.AddCert(c)
+	}
+	if _, err := certs[0].Verify(opts); err != nil {
+		return fmt.Errorf("spiffe: verify peer %s: %w", id, err)
+	}
+	return authorize(id)
+}
@@ -0,0 +1,222 @@
+package spiffe
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"backend-go-model-gateway/pkg/spiffe/workloadpb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// authority is a trust domain's CA.
+type authority struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newAuthority(t *testing.T, td string) *authority {
+	t.Helper()
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: td},
+		URIs:                  []*url.URL{{Scheme: "spiffe", Host: td}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &authority{cert: cert, key: key}
+}
+
+// svid issues an X.509-SVID for id, as the agent would hand it out.
+func (a *authority) svid(t *testing.T, id string, serial int64) *workloadpb.X509SVID {
+	t.Helper()
+	u, _ := url.Parse(id)
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		URIs:         []*url.URL{u},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Duration(serial) * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, a.cert, &key.PublicKey, a.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, _ := x509.MarshalPKCS8PrivateKey(key)
+	return &workloadpb.X509SVID{SpiffeId: id, X509Svid: der, X509SvidKey: keyDER, Bundle: a.cert.Raw}
+}
+
+// fakeAgent is a Workload API serving the SVIDs sent on updates to every
+// stream.
+type fakeAgent struct {
+	workloadpb.UnimplementedSpiffeWorkloadAPIServer
+	updates chan *workloadpb.X509SVIDResponse
+}
+
+func (f *fakeAgent) FetchX509SVID(_ *workloadpb.X509SVIDRequest, stream workloadpb.SpiffeWorkloadAPI_FetchX509SVIDServer) error {
+	md, _ := metadata.FromIncomingContext(stream.Context())
+	if v := md.Get(headerKey); len(v) != 1 || v[0] != "true" {
+		return status.Error(codes.InvalidArgument, "security header missing from request")
+	}
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case resp := <-f.updates:
+			if err := stream.Send(resp); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// startAgent serves a fake Workload API on a unix socket and returns a Source
+// connected to it, after the agent sent first.
+func startAgent(t *testing.T, first *workloadpb.X509SVID) (*Source, *fakeAgent) {
+	t.Helper()
+	// Unix socket paths are limited to ~100 bytes, too short for t.TempDir.
+	dir, err := os.MkdirTemp("", "spiffe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	sock := filepath.Join(dir, "agent.sock")
+	lis, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	agent := &fakeAgent{updates: make(chan *workloadpb.X509SVIDResponse, 4)}
+	srv := grpc.NewServer()
+	workloadpb.RegisterSpiffeWorkloadAPIServer(srv, agent)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	agent.updates <- &workloadpb.X509SVIDResponse{Svids: []*workloadpb.X509SVID{first}}
+	src, err := NewSource(context.Background(), Options{Addr: "unix://" + sock})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(src.Close)
+	return src, agent
+}
+
+// handshake connects client to server over a pipe and returns the serial of
+// the certificate the client saw, or the first handshake error. In TLS 1.3 the
+// client finishes before the server checks its certificate, so the client
+// keeps reading to take the server's tickets or alert.
+func handshake(server, client *tls.Config) (int64, error) {
+	sc, cc := net.Pipe()
+	defer sc.Close()
+	defer cc.Close()
+	srv := tls.Server(sc, server)
+	cli := tls.Client(cc, client)
+	errc := make(chan error, 1)
+	go func() { errc <- srv.Handshake() }()
+	if err := cli.Handshake(); err != nil {
+		sc.Close()
+		<-errc
+		return 0, err
+	}
+	go func() { _, _ = io.Copy(io.Discard, cli) }()
+	if err := <-errc; err != nil {
+		return 0, err
+	}
+	return cli.ConnectionState().PeerCertificates[0].SerialNumber.Int64(), nil
+}
+
+const (
+	gatewayID = "spiffe://pagi.test/ns/prod/sa/model-gateway"
+	plannerID = "spiffe://pagi.test/ns/prod/sa/agent-planner"
+)
+
+func TestSource_MutualAuthAndRotation(t *testing.T) {
+	ca := newAuthority(t, "pagi.test")
+	gateway, agent := startAgent(t, ca.svid(t, gatewayID, 1))
+	planner, _ := startAgent(t, ca.svid(t, plannerID, 1))
+	if gateway.ID() != gatewayID {
+		t.Fatalf("ID = %q, want %q", gateway.ID(), gatewayID)
+	}
+
+	server := gateway.ServerConfig(AuthorizeIDs(plannerID))
+	client := planner.ClientConfig(AuthorizeIDs(gatewayID))
+	if serial, err := handshake(server, client); err != nil || serial != 1 {
+		t.Fatalf("handshake = %d, %v; want serial 1", serial, err)
+	}
+
+	// The agent pushes a rotated SVID; new handshakes use it.
+	agent.updates <- &workloadpb.X509SVIDResponse{Svids: []*workloadpb.X509SVID{ca.svid(t, gatewayID, 2)}}
+	deadline := time.Now().Add(5 * time.Second)
+	for gateway.NotAfter().Before(time.Now().Add(90*time.Minute)) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if serial, err := handshake(server, client); err != nil || serial != 2 {
+		t.Fatalf("handshake after rotation = %d, %v; want serial 2", serial, err)
+	}
+}
+
+func TestSource_RejectsUnauthorizedAndForeignPeers(t *testing.T) {
+	ca := newAuthority(t, "pagi.test")
+	gateway, _ := startAgent(t, ca.svid(t, gatewayID, 1))
+	planner, _ := startAgent(t, ca.svid(t, plannerID, 1))
+
+	// Authenticated, but not on the gateway's list.
+	server := gateway.ServerConfig(AuthorizeIDs("spiffe://pagi.test/ns/prod/sa/other"))
+	if _, err := handshake(server, planner.ClientConfig(AuthorizeIDs(gatewayID))); err == nil {
+		t.Fatal("want the gateway to reject an unauthorized planner")
+	}
+
+	// Authorized by trust domain, but signed by a CA outside the bundle.
+	intruder, _ := startAgent(t, newAuthority(t, "pagi.test").svid(t, plannerID, 1))
+	server = gateway.ServerConfig(AuthorizeIDs("spiffe://pagi.test"))
+	if _, err := handshake(server, intruder.ClientConfig(AuthorizeIDs(gatewayID))); err == nil {
+		t.Fatal("want the gateway to reject an SVID its bundle does not verify")
+	}
+	if _, err := handshake(server, planner.ClientConfig(AuthorizeIDs(gatewayID))); err != nil {
+		t.Fatalf("trust domain authorization: %v", err)
+	}
+}
+
+func TestAuthorizeIDs(t *testing.T) {
+	authorize := AuthorizeIDs(plannerID, "spiffe://partner.test")
+	for id, want := range map[string]bool{
+		plannerID:                          true,
+		"spiffe://partner.test/sa/ingest":  true,
+		gatewayID:                          false,
+		"spiffe://partner.test.evil/sa/x":  false,
+		"spiffe://pagi.test/ns/prod/sa/ag": false,
+	} {
+		if err := authorize(id); (err == nil) != want {
+			t.Errorf("authorize(%q) = %v, want allowed=%t", id, err, want)
+		}
+	}
+	if err := AuthorizeIDs()(plannerID); err == nil {
+		t.Error("an empty list must allow nobody")
+	}
+}
@@ -0,0 +1,285 @@
+// The X.509 subset of the SPIFFE Workload API
+// (https://github.com/spiffe/spiffe/blob/main/standards/SPIFFE_Workload_API.md).
+// The upstream definition has no package: the service is /SpiffeWorkloadAPI.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.10
+// 	protoc        v6.33.2
+// source: workload.proto
+
+package workloadpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type X509SVIDRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *X509SVIDRequest) Reset() {
+	*x = X509SVIDRequest{}
+	mi := &file_workload_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *X509SVIDRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*X509SVIDRequest) ProtoMessage() {}
+
+func (x *X509SVIDRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_workload_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use X509SVIDRequest.ProtoReflect.Descriptor instead.
+func (*X509SVIDRequest) Descriptor() ([]byte, []int) {
+	return file_workload_proto_rawDescGZIP(), []int{0}
+}
+
+type X509SVIDResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The workload's SVIDs; the first is the default identity.
+	Svids []*X509SVID `protobuf:"bytes,1,rep,name=svids,proto3" json:"svids,omitempty"`
+	// ASN.1 DER certificate revocation lists.
+	Crl [][]byte `protobuf:"bytes,2,rep,name=crl,proto3" json:"crl,omitempty"`
+	// CA certificate bundles of federated trust domains, keyed by trust domain
+	// ID (spiffe://example.org), as concatenated ASN.1 DER certificates.
+	FederatedBundles map[string][]byte `protobuf:"bytes,3,rep,name=federated_bundles,json=federatedBundles,proto3" json:"federated_bundles,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *X509SVIDResponse) Reset() {
+	*x = X509SVIDResponse{}
+	mi := &file_workload_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *X509SVIDResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*X509SVIDResponse) ProtoMessage() {}
+
+func (x *X509SVIDResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_workload_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use X509SVIDResponse.ProtoReflect.Descriptor instead.
+func (*X509SVIDResponse) Descriptor() ([]byte, []int) {
+	return file_workload_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *X509SVIDResponse) GetSvids() []*X509SVID {
+	if x != nil {
+		return x.Svids
+	}
+	return nil
+}
+
+func (x *X509SVIDResponse) GetCrl() [][]byte {
+	if x != nil {
+		return x.Crl
+	}
+	return nil
+}
+
+func (x *X509SVIDResponse) GetFederatedBundles() map[string][]byte {
+	if x != nil {
+		return x.FederatedBundles
+	}
+	return nil
+}
+
+type X509SVID struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The SPIFFE ID of the SVID.
+	SpiffeId string `protobuf:"bytes,1,opt,name=spiffe_id,json=spiffeId,proto3" json:"spiffe_id,omitempty"`
+	// The certificate chain, leaf first, as concatenated ASN.1 DER certificates.
+	X509Svid []byte `protobuf:"bytes,2,opt,name=x509_svid,json=x509Svid,proto3" json:"x509_svid,omitempty"`
+	// The private key, as ASN.1 DER PKCS#8.
+	X509SvidKey []byte `protobuf:"bytes,3,opt,name=x509_svid_key,json=x509SvidKey,proto3" json:"x509_svid_key,omitempty"`
+	// The CA bundle of the SVID's trust domain, as concatenated ASN.1 DER
+	// certificates.
+	Bundle []byte `protobuf:"bytes,4,opt,name=bundle,proto3" json:"bundle,omitempty"`
+	// An operator-specified string that tells SVIDs apart.
+	Hint          string `protobuf:"bytes,5,opt,name=hint,proto3" json:"hint,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *X509SVID) Reset() {
+	*x = X509SVID{}
+	mi := &file_workload_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *X509SVID) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*X509SVID) ProtoMessage() {}
+
+func (x *X509SVID) ProtoReflect() protoreflect.Message {
+	mi := &file_workload_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use X509SVID.ProtoReflect.Descriptor instead.
+func (*X509SVID) Descriptor() ([]byte, []int) {
+	return file_workload_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *X509SVID) GetSpiffeId() string {
+	if x != nil {
+		return x.SpiffeId
+	}
+	return ""
+}
+
+func (x *X509SVID) GetX509Svid() []byte {
+	if x != nil {
+		return x.X509Svid
+	}
+	return nil
+}
+
+func (x *X509SVID) GetX509SvidKey() []byte {
+	if x != nil {
+		return x.X509SvidKey
+	}
+	return nil
+}
+
+func (x *X509SVID) GetBundle() []byte {
+	if x != nil {
+		return x.Bundle
+	}
+	return nil
+}
+
+func (x *X509SVID) GetHint() string {
+	if x != nil {
+		return x.Hint
+	}
+	return ""
+}
+
+var File_workload_proto protoreflect.FileDescriptor
+
+const file_workload_proto_rawDesc = "" +
+	"\n" +
+	"\x0eworkload.proto\"\x11\n" +
+	"\x0fX509SVIDRequest\"\xe0\x01\n" +
+	"\x10X509SVIDResponse\x12\x1f\n" +
+	"\x05svids\x18\x01 \x03(\v2\t.X509SVIDR\x05svids\x12\x10\n" +
+	"\x03crl\x18\x02 \x03(\fR\x03crl\x12T\n" +
+	"\x11federated_bundles\x18\x03 \x03(\v2'.X509SVIDResponse.FederatedBundlesEntryR\x10federatedBundles\x1aC\n" +
+	"\x15FederatedBundlesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\fR\x05value:\x028\x01\"\x94\x01\n" +
+	"\bX509SVID\x12\x1b\n" +
+	"\tspiffe_id\x18\x01 \x01(\tR\bspiffeId\x12\x1b\n" +
+	"\tx509_svid\x18\x02 \x01(\fR\bx509Svid\x12\"\n" +
+	"\rx509_svid_key\x18\x03 \x01(\fR\vx509SvidKey\x12\x16\n" +
+	"\x06bundle\x18\x04 \x01(\fR\x06bundle\x12\x12\n" +
+	"\x04hint\x18\x05 \x01(\tR\x04hint2K\n" +
+	"\x11SpiffeWorkloadAPI\x126\n" +
+	"\rFetchX509SVID\x12\x10.X509SVIDRequest\x1a\x11.X509SVIDResponse0\x01B0Z.backend-go-model-gateway/pkg/spiffe/workloadpbb\x06proto3"
+
+var (
+	file_workload_proto_rawDescOnce sync.Once
+	file_workload_proto_rawDescData []byte
+)
+
+func file_workload_proto_rawDescGZIP() []byte {
+	file_workload_proto_rawDescOnce.Do(func() {
+		file_workload_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_workload_proto_rawDesc), len(file_workload_proto_rawDesc)))
+	})
+	return file_workload_proto_rawDescData
+}
+
+var file_workload_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_workload_proto_goTypes = []any{
+	(*X509SVIDRequest)(nil),  // 0: X509SVIDRequest
+	(*X509SVIDResponse)(nil), // 1: X509SVIDResponse
+	(*X509SVID)(nil),         // 2: X509SVID
+	nil,                      // 3: X509SVIDResponse.FederatedBundlesEntry
+}
+var file_workload_proto_depIdxs = []int32{
+	2, // 0: X509SVIDResponse.svids:type_name -> X509SVID
+	3, // 1: X509SVIDResponse.federated_bundles:type_name -> X509SVIDResponse.FederatedBundlesEntry
+	0, // 2: SpiffeWorkloadAPI.FetchX509SVID:input_type -> X509SVIDRequest
+	1, // 3: SpiffeWorkloadAPI.FetchX509SVID:output_type -> X509SVIDResponse
+	3, // [3:4] is the sub-list for method output_type
+	2, // [2:3] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
+}
+
+func init() { file_workload_proto_init() }
+func file_workload_proto_init() {
+	if File_workload_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_workload_proto_rawDesc), len(file_workload_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   4,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_workload_proto_goTypes,
+		DependencyIndexes: file_workload_proto_depIdxs,
+		MessageInfos:      file_workload_proto_msgTypes,
+	}.Build()
+	File_workload_proto = out.File
+	file_workload_proto_goTypes = nil
+	file_workload_proto_depIdxs = nil
+}
@@ -0,0 +1,247 @@
+// Package tlsreload serves mTLS material that can rotate without a restart,
+// for short-lived certificates issued by cert-manager, Vault PKI and the like.
+//
+// A Reloader resolves a certificate, its key and a CA bundle through
+// pkg/secrets (NAME_PATH files, or any secret reference) and re-reads them
+// from the TLS handshake callbacks at most every Interval. New material is
+// swapped in when it changed; established connections keep the material they
+// were made with. Material that does not load (a half-written file pair, a
+// key that does not match its certificate) is rejected and the current
+// material keeps being served.
+//
+// NAME_PATH files are read on every check. Secret references are re-fetched
+// only after PAGI_SECRETS_REFRESH_SECONDS, so set that at or below the
+// certificate lifetime when certificates come from a store.
+package tlsreload
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"backend-go-model-gateway/pkg/secrets"
+)
+
+// DefaultInterval is how often material is re-checked unless
+// TLS_RELOAD_SECONDS says otherwise.
+const DefaultInterval = 30 * time.Second
+
+// IntervalFromEnv reads TLS_RELOAD_SECONDS (default 30); 0 disables reloading.
+func IntervalFromEnv() time.Duration {
+	v := os.Getenv("TLS_RELOAD_SECONDS")
+	if v == "" {
+		return DefaultInterval
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return DefaultInterval
+	}
+	return time.Duration(n) * time.Second
+}
+
+// Options configures a Reloader.
+type Options struct {
+	Store *secrets.Store
+	// Cert, Key and CA are the secret names of the PEM certificate chain, its
+	// private key and the CA bundle that verifies peers, e.g. TLS_SERVER_CERT.
+	Cert, Key, CA string
+	// Interval is the minimum time between checks; 0 never reloads.
+	Interval time.Duration
+	// OnReload, when set, is called after a check that swapped in new
+	// material or failed.
+	OnReload func(Event)
+}
+
+// Event describes the outcome of a reload check.
+type Event struct {
+	// NotAfter is the expiry of the certificate being served.
+	NotAfter time.Time
+	// Err is set when new material was rejected.
+	Err error
+}
+
+type material struct {
+	cert     tls.Certificate
+	pool     *x509.CertPool
+	digest   [sha256.Size]byte
+	notAfter time.Time
+}
+
+// Reloader holds the current mTLS material. It is safe for concurrent use.
+type Reloader struct {
+	opts Options
+	now  func() time.Time
+
+	cur     atomic.Pointer[material]
+	mu      sync.Mutex // serializes reloads
+	checked atomic.Int64
+}
+
+// New loads the initial material, which must be valid.
+func New(ctx context.Context, opts Options) (*Reloader, error) {
+	r := &Reloader{opts: opts, now: time.Now}
+	m, err := r.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r.cur.Store(m)
+	r.checked.Store(r.now().UnixNano())
+	return r, nil
+}
+
+// NotAfter is the expiry of the certificate being served.
+func (r *Reloader) NotAfter() time.Time {
+	return r.cur.Load().notAfter
+}
+
+// Reload re-reads the material now and reports whether it changed. On error
+// the current material is kept.
+func (r *Reloader) Reload(ctx context.Context) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.reloadLocked(ctx)
+}
+
+func (r *Reloader) reloadLocked(ctx context.Context) (bool, error) {
+	r.checked.Store(r.now().UnixNano())
+
+	m, err := r.load(ctx)
+	if err != nil {
+		r.notify(Event{NotAfter: r.NotAfter(), Err: err})
+		return false, err
+	}
+	if m.digest == r.cur.Load().digest {
+		return false, nil
+	}
+	r.cur.Store(m)
+	r.notify(Event{NotAfter: m.notAfter})
+	return true, nil
+}
+
+// current returns the material for a handshake, reloading first when a check
+// is due. Concurrent handshakes do not wait for a reload in progress.
+func (r *Reloader) current(ctx context.Context) *material {
+	if r.due() && r.mu.TryLock() {
+		if r.due() {
+			_, _ = r.reloadLocked(ctx)
+		}
+		r.mu.Unlock()
+	}
+	return r.cur.Load()
+}
+
+func (r *Reloader) due() bool {
+	return r.opts.Interval > 0 && r.now().Sub(time.Unix(0, r.checked.Load())) >= r.opts.Interval
+}
+
+func (r *Reloader) notify(e Event) {
+	if r.opts.OnReload != nil {
+		r.opts.OnReload(e)
+	}
+}
+
+func (r *Reloader) load(ctx context.Context) (*material, error) {
+	certPEM, err := r.opts.Store.PEM(ctx, r.opts.Cert)
+	if err != nil {
+		return nil, err
+	}
+	keyPEM, err := r.opts.Store.PEM(ctx, r.opts.Key)
+	if err != nil {
+		return nil, err
+	}
+	caPEM, err := r.opts.Store.PEM(ctx, r.opts.CA)
+	if err != nil {
+		return nil, err
+	}
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("load keypair (%s, %s): %w", r.opts.Cert, r.opts.Key, err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("append CA certs from PEM (%s): no certs parsed", r.opts.CA)
+	}
+
+	h := sha256.New()
+	for _, b := range [][]byte{certPEM, keyPEM, caPEM} {
+		h.Write(b)
+	}
+	leaf := cert.Leaf
+	if leaf == nil {
+		if leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", r.opts.Cert, err)
+		}
+	}
+	m := &material{cert: cert, pool: pool, notAfter: leaf.NotAfter}
+	copy(m.digest[:], h.Sum(nil))
+	return m, nil
+}
+
+// ServerConfig returns a server config that requires and verifies client
+// certificates, serving the current certificate and verifying against the
+// current CA bundle on every handshake.
+func (r *Reloader) ServerConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		NextProtos: []string{"h2"},
+		GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			m := r.current(hello.Context())
+			return &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{m.cert},
+				ClientCAs:    m.pool,
+				ClientAuth:   tls.RequireAndVerifyClientCert,
+				NextProtos:   []string{"h2"},
+			}, nil
+		},
+	}
+}
+
+// ClientConfig returns a client config that presents the current certificate
+// and verifies the server against serverName and the current CA bundle.
+//
+// The standard verification reads a fixed RootCAs pool, so it is replaced:
+// InsecureSkipVerify turns it off and VerifyConnection performs the same
+// chain and hostname checks against the current pool.
+func (r *Reloader) ClientConfig(serverName string) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: serverName,
+		NextProtos: []string{"h2"},
+		GetClientCertificate: func(info *tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return &r.current(info.Context()).cert, nil
+		},
+		InsecureSkipVerify: true,
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			return verifyServer(cs, r.current(context.Background()).pool)
+		},
+	}
+}
+
+// verifyServer checks the server's chain against roots and its certificate
+// against the requested server name, as crypto/tls does by default.
+func verifyServer(cs tls.ConnectionState, roots *x509.CertPool) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("tls: server presented no certificate")
+	}
+	opts := x509.VerifyOptions{
+		Roots:         roots,
+		DNSName:       cs.ServerName,
+		Intermediates: x509.NewCertPool(),
+	}
+	for _, c := range cs.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(c)
+	}
+	_, err := cs.PeerCertificates[0].Verify(opts)
+	return err
+}
@@ -0,0 +1,175 @@
+package tlsreload
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"backend-go-model-gateway/pkg/secrets"
+)
+
+// issue writes a fresh CA and a server and client certificate it signed to
+// dir, as TLS_SERVER_*, TLS_CLIENT_* and TLS_CA_CERT files.
+func issue(t *testing.T, dir string, serial int64) {
+	t.Helper()
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(serial),
+		Subject:               pkix.Name{CommonName: "pagi-test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, _ := x509.ParseCertificate(caDER)
+	write(t, dir, "ca.pem", "CERTIFICATE", caDER)
+
+	for _, leaf := range []struct {
+		name  string
+		usage x509.ExtKeyUsage
+	}{{"server", x509.ExtKeyUsageServerAuth}, {"client", x509.ExtKeyUsageClientAuth}} {
+		key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		tmpl := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: leaf.name},
+			DNSNames:     []string{"model-gateway"},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Duration(serial) * time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{leaf.usage},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, &key.PublicKey, caKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keyDER, _ := x509.MarshalECPrivateKey(key)
+		write(t, dir, leaf.name+".pem", "CERTIFICATE", der)
+		write(t, dir, leaf.name+"-key.pem", "EC PRIVATE KEY", keyDER)
+	}
+}
+
+func write(t *testing.T, dir, name, typ string, der []byte) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newReloaders(t *testing.T, dir string) (server, client *Reloader) {
+	t.Helper()
+	paths := map[string]string{
+		"TLS_SERVER_CERT_PATH": filepath.Join(dir, "server.pem"),
+		"TLS_SERVER_KEY_PATH":  filepath.Join(dir, "server-key.pem"),
+		"TLS_CLIENT_CERT_PATH": filepath.Join(dir, "client.pem"),
+		"TLS_CLIENT_KEY_PATH":  filepath.Join(dir, "client-key.pem"),
+		"TLS_CA_CERT_PATH":     filepath.Join(dir, "ca.pem"),
+	}
+	store := secrets.New(secrets.Options{Env: func(k string) string { return paths[k] }})
+	var err error
+	if server, err = New(context.Background(), Options{Store: store, Cert: "TLS_SERVER_CERT", Key: "TLS_SERVER_KEY", CA: "TLS_CA_CERT", Interval: time.Minute}); err != nil {
+		t.Fatal(err)
+	}
+	if client, err = New(context.Background(), Options{Store: store, Cert: "TLS_CLIENT_CERT", Key: "TLS_CLIENT_KEY", CA: "TLS_CA_CERT", Interval: time.Minute}); err != nil {
+		t.Fatal(err)
+	}
+	return server, client
+}
+
+// handshake connects a client to a server over a pipe and returns the serial
+// of the certificate each side saw from the other.
+func handshake(t *testing.T, server, client *Reloader) (serverSerial, clientSerial int64) {
+	t.Helper()
+	sc, cc := net.Pipe()
+	defer sc.Close()
+	defer cc.Close()
+	srv := tls.Server(sc, server.ServerConfig())
+	cli := tls.Client(cc, client.ClientConfig("model-gateway"))
+	errc := make(chan error, 1)
+	go func() { errc <- srv.Handshake() }()
+	if err := cli.Handshake(); err != nil {
+		t.Fatalf("client handshake: %v", err)
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("server handshake: %v", err)
+	}
+	return cli.ConnectionState().PeerCertificates[0].SerialNumber.Int64(),
+		srv.ConnectionState().PeerCertificates[0].SerialNumber.Int64()
+}
+
+func TestReloader_PicksUpRotatedCertificates(t *testing.T) {
+	dir := t.TempDir()
+	issue(t, dir, 1)
+	server, client := newReloaders(t, dir)
+	if s, c := handshake(t, server, client); s != 1 || c != 1 {
+		t.Fatalf("serials = %d, %d, want 1, 1", s, c)
+	}
+
+	// Rotate everything, CA included. Nothing changes until a check is due.
+	issue(t, dir, 2)
+	if s, _ := handshake(t, server, client); s != 1 {
+		t.Fatalf("server serial = %d before the interval elapsed, want 1", s)
+	}
+	later := time.Now().Add(2 * time.Minute)
+	server.now = func() time.Time { return later }
+	client.now = func() time.Time { return later }
+	if s, c := handshake(t, server, client); s != 2 || c != 2 {
+		t.Fatalf("serials = %d, %d after rotation, want 2, 2", s, c)
+	}
+	if got := server.NotAfter(); got.Before(time.Now().Add(90 * time.Minute)) {
+		t.Fatalf("NotAfter = %v, want the rotated certificate's", got)
+	}
+}
+
+func TestReloader_KeepsCurrentMaterialOnBadRotation(t *testing.T) {
+	dir := t.TempDir()
+	issue(t, dir, 1)
+	var events []Event
+	server, client := newReloaders(t, dir)
+	server.opts.OnReload = func(e Event) { events = append(events, e) }
+
+	// A half-written rotation: the certificate is new, the key is not yet.
+	old, _ := os.ReadFile(filepath.Join(dir, "server-key.pem"))
+	issue(t, dir, 2)
+	if err := os.WriteFile(filepath.Join(dir, "server-key.pem"), old, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if changed, err := server.Reload(context.Background()); changed || err == nil {
+		t.Fatalf("Reload = %t, %v; want an error", changed, err)
+	}
+	if len(events) != 1 || events[0].Err == nil {
+		t.Fatalf("events = %+v, want one failure", events)
+	}
+	if changed, err := server.Reload(context.Background()); changed || err == nil {
+		t.Fatalf("second Reload = %t, %v; want an error", changed, err)
+	}
+	if server.cur.Load().cert.Leaf.SerialNumber.Int64() != 1 {
+		t.Fatal("rejected material replaced the current certificate")
+	}
+	if _, err := client.Reload(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// The client now trusts only the new CA, so the old server certificate fails.
+	sc, cc := net.Pipe()
+	defer sc.Close()
+	defer cc.Close()
+	go func() { _ = tls.Server(sc, server.ServerConfig()).Handshake() }()
+	if err := tls.Client(cc, client.ClientConfig("model-gateway")).Handshake(); err == nil {
+		t.Fatal("want the client to reject a certificate from the old CA")
+	}
+}
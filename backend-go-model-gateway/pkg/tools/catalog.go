@@ -0,0 +1,132 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	pb "backend-go-model-gateway/proto/proto"
+)
+
+// Catalog holds the tool definitions in effect. It starts with Builtin and
+// switches to the sandbox's own list (ToolService.ListTools) once a refresh
+// succeeds, so tools added to or removed from the sandbox are offered and
+// accepted without a redeploy. A failed refresh keeps the last good list. A
+// nil *Catalog is Builtin.
+type Catalog struct {
+	client pb.ToolServiceClient
+
+	mu        sync.RWMutex
+	defs      []Definition
+	source    string
+	refreshed time.Time
+}
+
+// Sources of a catalog's definitions.
+const (
+	SourceBuiltin = "builtin"
+	SourceSandbox = "sandbox"
+)
+
+// NewCatalog returns a catalog serving Builtin until Refresh succeeds.
+func NewCatalog(client pb.ToolServiceClient) *Catalog {
+	return &Catalog{client: client, defs: Builtin, source: SourceBuiltin}
+}
+
+// Definitions returns the tools in effect. The slice is shared; do not
+// modify it.
+func (c *Catalog) Definitions() []Definition {
+	if c == nil {
+		return Builtin
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.defs
+}
+
+// Source reports where the definitions came from (SourceBuiltin or
+// SourceSandbox) and when they were last refreshed from the sandbox.
+func (c *Catalog) Source() (string, time.Time) {
+	if c == nil {
+		return SourceBuiltin, time.Time{}
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.source, c.refreshed
+}
+
+// Refresh replaces the definitions with the sandbox's and reports whether
+// they changed. An empty list is refused: a sandbox that lost its tools
+// should not take them away from running services.
+func (c *Catalog) Refresh(ctx context.Context) (changed bool, err error) {
+	resp, err := c.client.ListTools(ctx, &pb.ListToolsRequest{})
+	if err != nil {
+		return false, err
+	}
+	if len(resp.GetTools()) == 0 {
+		return false, errors.New("sandbox listed no tools; keeping the current ones")
+	}
+	defs := make([]Definition, 0, len(resp.GetTools()))
+	for _, t := range resp.GetTools() {
+		if t.GetName() == "" {
+			continue
+		}
+		defs = append(defs, FromProto(t))
+	}
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Name < defs[j].Name })
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	changed = !reflect.DeepEqual(defs, c.defs)
+	c.defs, c.source, c.refreshed = defs, SourceSandbox, time.Now()
+	return changed, nil
+}
+
+// Run calls Refresh now and then every interval (only once when interval is
+// 0) until ctx is done, passing each outcome to report (for logging).
+func (c *Catalog) Run(ctx context.Context, interval time.Duration, report func(changed bool, err error)) {
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		refreshCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		changed, err := c.Refresh(refreshCtx)
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+		report(changed, err)
+		if tick == nil {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick:
+		}
+	}
+}
+
+// FromProto converts a ListTools definition.
+func FromProto(t *pb.ToolDefinition) Definition {
+	d := Definition{Name: t.GetName(), Description: t.GetDescription(), Parameters: map[string]Param{}}
+	for name, p := range t.GetParameters() {
+		d.Parameters[name] = Param{Type: p.GetType(), Description: p.GetDescription()}
+	}
+	return d
+}
+
+// ToProto converts a definition for ListTools.
+func ToProto(d Definition) *pb.ToolDefinition {
+	t := &pb.ToolDefinition{Name: d.Name, Description: d.Description, Parameters: map[string]*pb.ToolParameter{}}
+	for name, p := range d.Parameters {
+		t.Parameters[name] = &pb.ToolParameter{Type: p.Type, Description: p.Description}
+	}
+	return t
+}
@@ -0,0 +1,63 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	pb "backend-go-model-gateway/proto/proto"
+
+	"google.golang.org/grpc"
+)
+
+type fakeToolService struct {
+	pb.ToolServiceClient
+	tools []*pb.ToolDefinition
+	err   error
+}
+
+func (f *fakeToolService) ListTools(context.Context, *pb.ListToolsRequest, ...grpc.CallOption) (*pb.ListToolsResponse, error) {
+	return &pb.ListToolsResponse{Tools: f.tools}, f.err
+}
+
+func TestCatalog_Refresh(t *testing.T) {
+	sandbox := &fakeToolService{err: errors.New("unavailable")}
+	c := NewCatalog(sandbox)
+	if _, err := c.Refresh(context.Background()); err == nil {
+		t.Fatal("refresh against a failing sandbox succeeded")
+	}
+	if source, _ := c.Source(); source != SourceBuiltin || len(c.Definitions()) != len(Builtin) {
+		t.Fatalf("source %s, %d tools; want the builtin ones", source, len(c.Definitions()))
+	}
+
+	sandbox.err = nil
+	sandbox.tools = []*pb.ToolDefinition{
+		{Name: "web_search", Description: "Search.", Parameters: map[string]*pb.ToolParameter{"query": {Type: "string"}}},
+		ToProto(Definition{Name: "calendar_lookup", Description: "Events.", Parameters: map[string]Param{"day": {Type: "string"}}}),
+	}
+	changed, err := c.Refresh(context.Background())
+	if err != nil || !changed {
+		t.Fatalf("Refresh = %v, %v", changed, err)
+	}
+	defs := c.Definitions()
+	if len(defs) != 2 || defs[0].Name != "calendar_lookup" || defs[1].Parameters["query"].Type != "string" {
+		t.Fatalf("definitions = %+v", defs)
+	}
+	if err := Validate(defs, "calendar_lookup", map[string]any{"day": "monday"}); err != nil {
+		t.Fatalf("discovered tool rejected: %v", err)
+	}
+	if changed, _ := c.Refresh(context.Background()); changed {
+		t.Fatal("unchanged list reported as changed")
+	}
+
+	// An empty list keeps the last good one.
+	sandbox.tools = nil
+	if _, err := c.Refresh(context.Background()); err == nil || len(c.Definitions()) != 2 {
+		t.Fatalf("empty list: %v, %d tools", err, len(c.Definitions()))
+	}
+
+	var nilCatalog *Catalog
+	if len(nilCatalog.Definitions()) != len(Builtin) {
+		t.Fatal("nil catalog is not Builtin")
+	}
+}
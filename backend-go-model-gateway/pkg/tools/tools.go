@@ -0,0 +1,154 @@
+// Package tools is the registry of tools the model may call: their names,
+// descriptions and parameter schemas. The gateway offers them to the model,
+// and the planner validates the calls it parses against them before the
+// sandbox runs anything.
+package tools
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Definition describes a tool. The JSON form is what the gateway lists in the
+// system prompt.
+type Definition struct {
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	Parameters  map[string]Param `json:"parameters"`
+}
+
+// Param is one argument of a tool. Type is a JSON schema type: string,
+// number, integer, boolean, object or array. Every parameter is required.
+type Param struct {
+	Type        string `json:"type"`
+	Description string `json:"description"`
+}
+
+// Builtin lists the tools the sandbox implements.
+var Builtin = []Definition{
+	{
+		Name:        "web_search",
+		Description: "Use this tool to find up-to-date information or external knowledge.",
+		Parameters: map[string]Param{
+			"query": {Type: "string", Description: "The search query."},
+		},
+	},
+}
+
+// Lookup returns the definition named name.
+func Lookup(defs []Definition, name string) (Definition, bool) {
+	for _, d := range defs {
+		if d.Name == name {
+			return d, true
+		}
+	}
+	return Definition{}, false
+}
+
+// ValidationError is a tool call that does not match its definition. Problems
+// has one entry per missing, unknown or mistyped argument, worded for the
+// model to correct the call.
+type ValidationError struct {
+	Tool     string
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid call to tool %q: %s", e.Tool, strings.Join(e.Problems, "; "))
+}
+
+// Validate checks a call against defs: the tool must be defined, every
+// parameter given with its type, and no other argument passed. It returns a
+// *ValidationError, or nil for a valid call.
+func Validate(defs []Definition, name string, args map[string]any) error {
+	def, ok := Lookup(defs, name)
+	if !ok {
+		names := make([]string, 0, len(defs))
+		for _, d := range defs {
+			names = append(names, d.Name)
+		}
+		sort.Strings(names)
+		return &ValidationError{Tool: name, Problems: []string{fmt.Sprintf("unknown tool; available tools: %s", strings.Join(names, ", "))}}
+	}
+
+	var problems []string
+	params := make([]string, 0, len(def.Parameters))
+	for p := range def.Parameters {
+		params = append(params, p)
+	}
+	sort.Strings(params)
+	for _, p := range params {
+		want := def.Parameters[p].Type
+		v, ok := args[p]
+		switch {
+		case !ok:
+			problems = append(problems, fmt.Sprintf("missing required argument %q (%s)", p, want))
+		case !hasType(v, want):
+			problems = append(problems, fmt.Sprintf("argument %q: want %s, got %s", p, want, typeOf(v)))
+		}
+	}
+	var extra []string
+	for a := range args {
+		if _, ok := def.Parameters[a]; !ok {
+			extra = append(extra, a)
+		}
+	}
+	sort.Strings(extra)
+	for _, a := range extra {
+		problems = append(problems, fmt.Sprintf("unknown argument %q; expected %s", a, strings.Join(params, ", ")))
+	}
+	if len(problems) > 0 {
+		return &ValidationError{Tool: name, Problems: problems}
+	}
+	return nil
+}
+
+// hasType reports whether a decoded JSON value has schema type want. Unknown
+// types accept any value.
+func hasType(v any, want string) bool {
+	switch want {
+	case "string":
+		s, ok := v.(string)
+		return ok && strings.TrimSpace(s) != ""
+	case "number":
+		_, ok := v.(float64)
+		return ok
+	case "integer":
+		f, ok := v.(float64)
+		return ok && f == math.Trunc(f)
+	case "boolean":
+		_, ok := v.(bool)
+		return ok
+	case "object":
+		_, ok := v.(map[string]any)
+		return ok
+	case "array":
+		_, ok := v.([]any)
+		return ok
+	}
+	return true
+}
+
+// typeOf names a decoded JSON value's type for error messages.
+func typeOf(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		if strings.TrimSpace(v) == "" {
+			return "an empty string"
+		}
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	}
+	return fmt.Sprintf("%T", v)
+}
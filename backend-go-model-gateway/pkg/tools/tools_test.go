@@ -0,0 +1,52 @@
+package tools
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	defs := append([]Definition{{
+		Name: "fetch",
+		Parameters: map[string]Param{
+			"url":     {Type: "string"},
+			"retries": {Type: "integer"},
+			"headers": {Type: "object"},
+			"follow":  {Type: "boolean"},
+		},
+	}}, Builtin...)
+	args := func(raw string) map[string]any {
+		var m map[string]any
+		if err := json.Unmarshal([]byte(raw), &m); err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+
+	if err := Validate(defs, "web_search", args(`{"query":"lisbon weather"}`)); err != nil {
+		t.Fatalf("valid call: %v", err)
+	}
+	if err := Validate(defs, "fetch", args(`{"url":"https://example.com","retries":2,"headers":{},"follow":true}`)); err != nil {
+		t.Fatalf("valid call: %v", err)
+	}
+
+	for name, tc := range map[string]struct {
+		tool, args string
+		problems   []string
+	}{
+		"unknown tool":  {"shell", `{}`, []string{"unknown tool; available tools: fetch, web_search"}},
+		"missing":       {"web_search", `{}`, []string{`missing required argument "query" (string)`}},
+		"empty string":  {"web_search", `{"query":"  "}`, []string{`argument "query": want string, got an empty string`}},
+		"renamed":       {"web_search", `{"q":"x"}`, []string{`missing required argument "query" (string)`, `unknown argument "q"; expected query`}},
+		"wrong types":   {"fetch", `{"url":7,"retries":1.5,"headers":[],"follow":"yes"}`, []string{`argument "follow": want boolean, got string`, `argument "headers": want object, got array`, `argument "retries": want integer, got number`, `argument "url": want string, got number`}},
+		"null argument": {"web_search", `{"query":null}`, []string{`argument "query": want string, got null`}},
+	} {
+		err := Validate(defs, tc.tool, args(tc.args))
+		var verr *ValidationError
+		if !errors.As(err, &verr) || verr.Tool != tc.tool || strings.Join(verr.Problems, "|") != strings.Join(tc.problems, "|") {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
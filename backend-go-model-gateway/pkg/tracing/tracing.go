@@ -0,0 +1,196 @@
+// Package tracing reads the OTLP trace exporter and sampler settings shared by
+// the gateway and the planner. It uses the standard OpenTelemetry variable
+// names:
+//
+//	OTEL_EXPORTER_OTLP_ENDPOINT           host:port, or http(s)://host:port (default localhost:4317)
+//	OTEL_EXPORTER_OTLP_TIMEOUT            per-export timeout, milliseconds or a duration (default 10s)
+//	OTEL_EXPORTER_OTLP_INSECURE           true/false; see below
+//	OTEL_EXPORTER_OTLP_CERTIFICATE        CA bundle that verifies the collector
+//	OTEL_EXPORTER_OTLP_CLIENT_CERTIFICATE client certificate for mTLS
+//	OTEL_EXPORTER_OTLP_CLIENT_KEY         its private key
+//	OTEL_TRACES_SAMPLER                   always_on, always_off, traceidratio, parentbased_always_on
+//	                                      (default), parentbased_always_off, parentbased_traceidratio
+//	OTEL_TRACES_SAMPLER_ARG               sampling ratio for the *traceidratio samplers (default 1)
+//
+// The connection is plaintext unless TLS is asked for: an https:// endpoint,
+// OTEL_EXPORTER_OTLP_INSECURE=false, or a CA or client certificate. Asking
+// for both is an error rather than a silent downgrade.
+package tracing
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+const (
+	defaultEndpoint = "localhost:4317"
+	defaultTimeout  = 10 * time.Second
+)
+
+// Config is the exporter and sampler configuration.
+type Config struct {
+	Endpoint string
+	Timeout  time.Duration
+	// TLS is nil for a plaintext connection.
+	TLS *tls.Config
+
+	SamplerName  string
+	SamplerRatio float64
+	Sampler      sdktrace.Sampler
+}
+
+// FromEnv reads the variables listed in the package doc.
+func FromEnv() (Config, error) {
+	c := Config{Endpoint: defaultEndpoint, Timeout: defaultTimeout}
+
+	scheme := ""
+	if v := strings.TrimSpace(os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")); v != "" {
+		c.Endpoint = v
+		if s, rest, ok := strings.Cut(v, "://"); ok {
+			scheme, c.Endpoint = strings.ToLower(s), strings.TrimSuffix(rest, "/")
+			if scheme != "http" && scheme != "https" {
+				return Config{}, fmt.Errorf("OTEL_EXPORTER_OTLP_ENDPOINT: unsupported scheme %q", s)
+			}
+		}
+	}
+
+	if v := strings.TrimSpace(os.Getenv("OTEL_EXPORTER_OTLP_TIMEOUT")); v != "" {
+		d, err := parseTimeout(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("OTEL_EXPORTER_OTLP_TIMEOUT: %w", err)
+		}
+		c.Timeout = d
+	}
+
+	tlsConfig, err := tlsFromEnv(scheme)
+	if err != nil {
+		return Config{}, err
+	}
+	c.TLS = tlsConfig
+
+	c.SamplerName = strings.ToLower(strings.TrimSpace(os.Getenv("OTEL_TRACES_SAMPLER")))
+	if c.SamplerName == "" {
+		c.SamplerName = "parentbased_always_on"
+	}
+	c.SamplerRatio = 1
+	if v := strings.TrimSpace(os.Getenv("OTEL_TRACES_SAMPLER_ARG")); v != "" {
+		r, err := strconv.ParseFloat(v, 64)
+		if err != nil || r < 0 || r > 1 {
+			return Config{}, fmt.Errorf("OTEL_TRACES_SAMPLER_ARG: want a ratio between 0 and 1, got %q", v)
+		}
+		c.SamplerRatio = r
+	}
+	if c.Sampler, err = sampler(c.SamplerName, c.SamplerRatio); err != nil {
+		return Config{}, err
+	}
+	return c, nil
+}
+
+// ExporterOptions configures an otlptracegrpc exporter for c.
+func (c Config) ExporterOptions() []otlptracegrpc.Option {
+	opts := []otlptracegrpc.Option{
+		otlptracegrpc.WithEndpoint(c.Endpoint),
+		otlptracegrpc.WithTimeout(c.Timeout),
+	}
+	if c.TLS == nil {
+		return append(opts, otlptracegrpc.WithTLSCredentials(insecure.NewCredentials()))
+	}
+	return append(opts, otlptracegrpc.WithTLSCredentials(credentials.NewTLS(c.TLS)))
+}
+
+// String summarizes c for startup logs.
+func (c Config) String() string {
+	s := fmt.Sprintf("endpoint=%s tls=%t timeout=%s sampler=%s", c.Endpoint, c.TLS != nil, c.Timeout, c.SamplerName)
+	if strings.HasSuffix(c.SamplerName, "traceidratio") {
+		s += fmt.Sprintf(" ratio=%g", c.SamplerRatio)
+	}
+	return s
+}
+
+func sampler(name string, ratio float64) (sdktrace.Sampler, error) {
+	switch name {
+	case "always_on":
+		return sdktrace.AlwaysSample(), nil
+	case "always_off":
+		return sdktrace.NeverSample(), nil
+	case "traceidratio":
+		return sdktrace.TraceIDRatioBased(ratio), nil
+	case "parentbased_always_on":
+		return sdktrace.ParentBased(sdktrace.AlwaysSample()), nil
+	case "parentbased_always_off":
+		return sdktrace.ParentBased(sdktrace.NeverSample()), nil
+	case "parentbased_traceidratio":
+		return sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio)), nil
+	}
+	return nil, fmt.Errorf("OTEL_TRACES_SAMPLER: unsupported sampler %q", name)
+}
+
+// parseTimeout accepts the spec's milliseconds or a Go duration such as 5s.
+func parseTimeout(v string) (time.Duration, error) {
+	d, err := time.ParseDuration(v)
+	if ms, msErr := strconv.Atoi(v); msErr == nil {
+		d, err = time.Duration(ms)*time.Millisecond, nil
+	}
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("want a positive number of milliseconds or a duration, got %q", v)
+	}
+	return d, nil
+}
+
+func tlsFromEnv(scheme string) (*tls.Config, error) {
+	caFile := strings.TrimSpace(os.Getenv("OTEL_EXPORTER_OTLP_CERTIFICATE"))
+	certFile := strings.TrimSpace(os.Getenv("OTEL_EXPORTER_OTLP_CLIENT_CERTIFICATE"))
+	keyFile := strings.TrimSpace(os.Getenv("OTEL_EXPORTER_OTLP_CLIENT_KEY"))
+
+	wantTLS := scheme == "https" || caFile != "" || certFile != "" || keyFile != ""
+	if v := strings.TrimSpace(os.Getenv("OTEL_EXPORTER_OTLP_INSECURE")); v != "" {
+		plaintext, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("OTEL_EXPORTER_OTLP_INSECURE: want true or false, got %q", v)
+		}
+		if plaintext && wantTLS {
+			return nil, errors.New("OTEL_EXPORTER_OTLP_INSECURE=true conflicts with an https endpoint or OTLP certificates")
+		}
+		wantTLS = !plaintext
+	}
+	if scheme == "http" && wantTLS {
+		return nil, errors.New("OTEL_EXPORTER_OTLP_ENDPOINT: http:// conflicts with OTLP TLS settings")
+	}
+	if !wantTLS {
+		return nil, nil
+	}
+
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("OTEL_EXPORTER_OTLP_CERTIFICATE: %w", err)
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("OTEL_EXPORTER_OTLP_CERTIFICATE %s: no PEM certificates", caFile)
+		}
+	}
+	if (certFile == "") != (keyFile == "") {
+		return nil, errors.New("OTEL_EXPORTER_OTLP_CLIENT_CERTIFICATE and OTEL_EXPORTER_OTLP_CLIENT_KEY must be set together")
+	}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("OTLP client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
@@ -0,0 +1,112 @@
+package tracing
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFromEnv_Defaults(t *testing.T) {
+	for _, k := range []string{"OTEL_EXPORTER_OTLP_ENDPOINT", "OTEL_EXPORTER_OTLP_TIMEOUT", "OTEL_EXPORTER_OTLP_INSECURE", "OTEL_TRACES_SAMPLER"} {
+		t.Setenv(k, "")
+	}
+	c, err := FromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Endpoint != "localhost:4317" || c.TLS != nil || c.Timeout != 10*time.Second || c.SamplerName != "parentbased_always_on" {
+		t.Fatalf("defaults = %s", c)
+	}
+}
+
+func TestFromEnv_Sampler(t *testing.T) {
+	t.Setenv("OTEL_TRACES_SAMPLER", "parentbased_traceidratio")
+	t.Setenv("OTEL_TRACES_SAMPLER_ARG", "0.1")
+	t.Setenv("OTEL_EXPORTER_OTLP_TIMEOUT", "2500")
+	c, err := FromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(c.Sampler.Description(), "TraceIDRatioBased{0.1}") || c.Timeout != 2500*time.Millisecond {
+		t.Fatalf("config = %s, sampler %s", c, c.Sampler.Description())
+	}
+	if got := c.String(); !strings.Contains(got, "ratio=0.1") {
+		t.Fatalf("String() = %q", got)
+	}
+
+	for name, env := range map[string][2]string{
+		"unknown sampler": {"OTEL_TRACES_SAMPLER", "sometimes"},
+		"ratio above 1":   {"OTEL_TRACES_SAMPLER_ARG", "1.5"},
+		"bad timeout":     {"OTEL_EXPORTER_OTLP_TIMEOUT", "soon"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(env[0], env[1])
+			if _, err := FromEnv(); err == nil || !strings.Contains(err.Error(), env[0]) {
+				t.Fatalf("err = %v, want one naming %s", err, env[0])
+			}
+		})
+	}
+}
+
+func TestFromEnv_TLS(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "https://collector.example:4317/")
+	c, err := FromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Endpoint != "collector.example:4317" || c.TLS == nil || c.TLS.RootCAs != nil {
+		t.Fatalf("https endpoint = %s", c)
+	}
+
+	ca := writeCert(t)
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "collector:4317")
+	t.Setenv("OTEL_EXPORTER_OTLP_CERTIFICATE", ca)
+	if c, err = FromEnv(); err != nil || c.TLS == nil || c.TLS.RootCAs == nil {
+		t.Fatalf("CA file: %v, %s", err, c)
+	}
+
+	// Plaintext with TLS material configured is a mistake, not a downgrade.
+	t.Setenv("OTEL_EXPORTER_OTLP_INSECURE", "true")
+	if _, err := FromEnv(); err == nil {
+		t.Fatal("want an error for insecure with a CA certificate")
+	}
+	t.Setenv("OTEL_EXPORTER_OTLP_INSECURE", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_CLIENT_CERTIFICATE", ca)
+	if _, err := FromEnv(); err == nil || !strings.Contains(err.Error(), "set together") {
+		t.Fatalf("client cert without key: %v", err)
+	}
+}
+
+func writeCert(t *testing.T) string {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "otel-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
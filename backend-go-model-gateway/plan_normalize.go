@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// normalizePlanOutput turns raw LLM output into the strict JSON plan payload the
+// Agent Planner expects. It accepts, in order of preference:
+//   - a raw JSON object (tool call or {"steps": [...]})
+//   - a fenced code block containing such an object
+//   - anything else, wrapped as a single-step plan
+//
+// This runs on every GetPlan response, so it is kept free of per-call closures
+// and covered by BenchmarkNormalizePlanOutput.
+func normalizePlanOutput(content, provider, prompt string) string {
+	trimmed := strings.TrimSpace(content)
+
+	// 1) Try raw JSON
+	if normalized, ok := normalizePlanJSON(trimmed, provider, prompt); ok {
+		return normalized
+	}
+	// 2) Try fenced JSON
+	if normalized, ok := normalizePlanJSON(stripCodeFences(trimmed), provider, prompt); ok {
+		return normalized
+	}
+	// 3) Fallback wrapper
+	fallback := map[string]any{
+		"model_type": provider,
+		"steps":      []string{trimmed},
+		"prompt":     prompt,
+	}
+	b, _ := json.Marshal(fallback)
+	return string(b)
+}
+
+func stripCodeFences(s string) string {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "```") {
+		return s
+	}
+	// Drop the first fence line
+	if idx := strings.Index(s, "\n"); idx >= 0 {
+		s = s[idx+1:]
+	}
+	// Drop the trailing fence
+	if end := strings.LastIndex(s, "```"); end >= 0 {
+		s = s[:end]
+	}
+	return strings.TrimSpace(s)
+}
+
+func normalizePlanJSON(raw, provider, prompt string) (string, bool) {
+	candidate := strings.TrimSpace(raw)
+	if !strings.HasPrefix(candidate, "{") {
+		return "", false
+	}
+
+	var obj map[string]any
+	if err := json.Unmarshal([]byte(candidate), &obj); err != nil {
+		return "", false
+	}
+
+	// Tool-call path: pass through (but ensure tracing fields exist).
+	if toolObj, ok := obj["tool"].(map[string]any); ok {
+		name, _ := toolObj["name"].(string)
+		if strings.TrimSpace(name) == "" {
+			return "", false
+		}
+		if _, ok := toolObj["args"]; !ok {
+			toolObj["args"] = map[string]any{}
+		}
+		if _, ok := obj["model_type"]; !ok {
+			obj["model_type"] = provider
+		}
+		if _, ok := obj["prompt"]; !ok {
+			obj["prompt"] = prompt
+		}
+		b, _ := json.Marshal(obj)
+		return string(b), true
+	}
+
+	// Planning path: require a non-empty steps array.
+	stepsAny, ok := obj["steps"].([]any)
+	if !ok || len(stepsAny) == 0 {
+		return "", false
+	}
+	steps := make([]string, 0, len(stepsAny))
+	for _, v := range stepsAny {
+		if s, ok := v.(string); ok && strings.TrimSpace(s) != "" {
+			steps = append(steps, s)
+		}
+	}
+	if len(steps) == 0 {
+		return "", false
+	}
+	payload := map[string]any{
+		"model_type": provider,
+		"steps":      steps,
+		"prompt":     prompt,
+	}
+	b, _ := json.Marshal(payload)
+	return string(b), true
+}
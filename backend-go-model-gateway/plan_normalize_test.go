@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestNormalizePlanOutput(t *testing.T) {
+	cases := []struct {
+		name      string
+		in        string
+		wantTool  string
+		wantSteps []string
+	}{
+		{name: "raw steps", in: `{"steps":["a","  ","b"]}`, wantSteps: []string{"a", "b"}},
+		{name: "fenced tool", in: "```json\n{\"tool\":{\"name\":\"web_search\"}}\n```", wantTool: "web_search"},
+		{name: "prose", in: "  just do it  ", wantSteps: []string{"just do it"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out struct {
+				ModelType string   `json:"model_type"`
+				Prompt    string   `json:"prompt"`
+				Steps     []string `json:"steps"`
+				Tool      *struct {
+					Name string         `json:"name"`
+					Args map[string]any `json:"args"`
+				} `json:"tool"`
+			}
+			if err := json.Unmarshal([]byte(normalizePlanOutput(tc.in, "openrouter", "p")), &out); err != nil {
+				t.Fatalf("output is not JSON: %v", err)
+			}
+			if out.ModelType != "openrouter" || out.Prompt != "p" {
+				t.Fatalf("missing tracing fields: %+v", out)
+			}
+			if tc.wantTool != "" {
+				if out.Tool == nil || out.Tool.Name != tc.wantTool || out.Tool.Args == nil {
+					t.Fatalf("tool = %+v, want %q with args", out.Tool, tc.wantTool)
+				}
+				return
+			}
+			if len(out.Steps) != len(tc.wantSteps) {
+				t.Fatalf("steps = %v, want %v", out.Steps, tc.wantSteps)
+			}
+			for i := range out.Steps {
+				if out.Steps[i] != tc.wantSteps[i] {
+					t.Fatalf("steps = %v, want %v", out.Steps, tc.wantSteps)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkNormalizePlanOutput(b *testing.B) {
+	inputs := map[string]string{
+		"raw":    `{"steps":["Check the calendar","Summarize today's meetings","Draft a reply to Alice"]}`,
+		"fenced": "```json\n{\"tool\":{\"name\":\"web_search\",\"args\":{\"query\":\"weather in Lisbon\"}}}\n```",
+		"prose":  "Sure! First I would check your calendar, then summarize the meetings for today.",
+	}
+	for name, in := range inputs {
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_ = normalizePlanOutput(in, "openrouter", "what is on my calendar today?")
+			}
+		})
+	}
+}
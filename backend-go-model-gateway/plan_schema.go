@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"backend-go-model-gateway/pkg/jsonschema"
+)
+
+const defaultPlanRepairAttempts = 2
+
+// toolCallSchema and stepsSchema are the two shapes a GetPlan reply may take,
+// as the system prompt describes them. Other top-level keys (model_type,
+// prompt) are allowed; normalizePlanOutput fills them in.
+var (
+	toolCallSchema = jsonschema.MustParse(`{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"title": "Tool call",
+		"type": "object",
+		"required": ["tool"],
+		"properties": {
+			"tool": {
+				"type": "object",
+				"required": ["name", "args"],
+				"properties": {
+					"name": {"type": "string", "minLength": 1},
+					"args": {"type": "object"}
+				},
+				"additionalProperties": false
+			},
+			"scratchpad": {"type": "array", "items": {"type": "string"}}
+		}
+	}`)
+	stepsSchema = jsonschema.MustParse(`{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"title": "Plan",
+		"type": "object",
+		"required": ["steps"],
+		"properties": {
+			"steps": {"type": "array", "minItems": 1, "items": {"type": "string", "minLength": 1}},
+			"scratchpad": {"type": "array", "items": {"type": "string"}}
+		}
+	}`)
+)
+
+// planSchemaProblems validates a GetPlan reply, with or without code fences,
+// against the tool-call schema when it has a "tool" key and the steps schema
+// otherwise. It returns nil for a valid reply.
+func planSchemaProblems(content string) []string {
+	raw := stripCodeFences(content)
+	var v any
+	if err := json.Unmarshal([]byte(raw), &v); err != nil {
+		return []string{fmt.Sprintf("reply is not valid JSON: %v", err)}
+	}
+	obj, ok := v.(map[string]any)
+	if !ok {
+		return []string{"reply must be a JSON object"}
+	}
+	if _, ok := obj["tool"]; ok {
+		return toolCallSchema.Validate(obj)
+	}
+	return stepsSchema.Validate(obj)
+}
+
+// planRepairPrompt asks the model to fix a reply that failed its schema.
+func planRepairPrompt(problems []string) string {
+	return "Your reply does not match the required JSON format:\n- " + strings.Join(problems, "\n- ") +
+		"\nReply again with only the corrected JSON object: either {\"tool\":{\"name\":\"...\",\"args\":{...}}} or {\"steps\":[\"...\"]}."
+}
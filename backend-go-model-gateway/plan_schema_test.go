@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	pb "backend-go-model-gateway/proto/proto"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestPlanSchemaProblems(t *testing.T) {
+	cases := map[string]string{
+		`{"steps":["Check the calendar"],"scratchpad":["dentist on Tuesday"]}`:          "",
+		"```json\n{\"tool\":{\"name\":\"web_search\",\"args\":{\"query\":\"x\"}}}\n```": "",
+		`{"steps":[]}`:                                    "/steps: must not be empty",
+		`{"steps":["ok", 2]}`:                             "/steps/1: must be a string, got number",
+		`{"tool":{"name":"web_search"}}`:                  `/tool: missing required property "args"`,
+		`{"tool":{"name":"x","args":{},"why":"because"}}`: `/tool: unexpected property "why"`,
+		`{"plan":"do it"}`:                                `/: missing required property "steps"`,
+		`["step"]`:                                        "reply must be a JSON object",
+		`Sure! Here is your plan.`:                        "reply is not valid JSON",
+	}
+	for content, want := range cases {
+		problems := planSchemaProblems(content)
+		if want == "" {
+			if len(problems) > 0 {
+				t.Errorf("%s: unexpected problems %q", content, problems)
+			}
+			continue
+		}
+		if len(problems) == 0 || !strings.HasPrefix(problems[0], want) {
+			t.Errorf("%s: problems %q, want %q first", content, problems, want)
+		}
+	}
+}
+
+func TestGetPlan_RepairsInvalidPlan(t *testing.T) {
+	replies := []string{`{"steps":"check the calendar"}`, `{"steps":["Check the calendar"]}`}
+	var requests []openai.ChatCompletionRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req openai.ChatCompletionRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		requests = append(requests, req)
+		content := replies[min(len(requests), len(replies))-1]
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(openai.ChatCompletionResponse{
+			Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Role: "assistant", Content: content}}},
+		})
+	}))
+	defer srv.Close()
+	cfg := openai.DefaultConfig("")
+	cfg.BaseURL = srv.URL
+	llm := &llmRuntime{Provider: providerOllama, Model: "m", Client: openai.NewClientWithConfig(cfg), ToolCalling: toolCallingJSON}
+	s := &server{llm: llm, requestTimeout: 5 * time.Second, planRepairs: 1}
+
+	resp, err := s.GetPlan(context.Background(), &pb.PlanRequest{Prompt: "what is on today?"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 2 || !strings.Contains(resp.GetPlan(), `"steps":["Check the calendar"]`) {
+		t.Fatalf("%d calls, plan %s", len(requests), resp.GetPlan())
+	}
+	repair := requests[1].Messages
+	if n := len(repair); n != 4 || repair[2].Content != replies[0] || !strings.Contains(repair[3].Content, "/steps: must be an array, got string") {
+		t.Fatalf("repair messages = %+v", repair)
+	}
+
+	// Still invalid after the last repair: wrapped as a single step.
+	replies = []string{`{"steps":"check the calendar"}`}
+	requests = nil
+	resp, err = s.GetPlan(context.Background(), &pb.PlanRequest{Prompt: "what is on today?"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 2 || !strings.Contains(resp.GetPlan(), `"steps":["{\"steps\":\"check the calendar\"}"]`) {
+		t.Fatalf("%d calls, plan %s", len(requests), resp.GetPlan())
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"backend-go-model-gateway/internal/logger"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Priority classes for provider capacity (PlanRequest.priority).
+const (
+	priorityInteractive = "interactive"
+	priorityBatch       = "batch"
+)
+
+const defaultQueueTimeoutSec = 30
+
+// requestPriority maps PlanRequest.priority to a class; anything but "batch"
+// is interactive, so older callers keep their place in line.
+func requestPriority(p string) string {
+	if strings.EqualFold(strings.TrimSpace(p), priorityBatch) {
+		return priorityBatch
+	}
+	return priorityInteractive
+}
+
+// requestQueue bounds concurrent provider calls with two priority classes.
+// A freed slot always goes to the oldest waiting interactive request before
+// any batch one, and batch requests never hold more than batchMax slots, so
+// a backlog of evaluation jobs cannot starve chat of provider capacity.
+//
+// A nil *requestQueue admits everything (LLM_MAX_CONCURRENT_REQUESTS unset).
+type requestQueue struct {
+	capacity int
+	batchMax int
+	// maxDepth bounds the requests waiting in both classes together; one
+	// more is rejected at once (0: unbounded).
+	maxDepth int
+	timeout  time.Duration
+
+	mu      sync.Mutex
+	running map[string]int
+	waiting map[string][]chan struct{}
+}
+
+// newRequestQueue returns a queue for capacity concurrent calls, batchMax of
+// which may be batch (clamped to 1..capacity-1 so one slot stays free for
+// interactive requests when capacity allows), and at most maxDepth waiting
+// ones. capacity <= 0 returns nil.
+func newRequestQueue(capacity, batchMax, maxDepth int, timeout time.Duration) *requestQueue {
+	if capacity <= 0 {
+		return nil
+	}
+	if batchMax <= 0 || batchMax >= capacity {
+		batchMax = max(1, capacity-1)
+	}
+	return &requestQueue{
+		capacity: capacity,
+		batchMax: batchMax,
+		maxDepth: max(0, maxDepth),
+		timeout:  timeout,
+		running:  map[string]int{},
+		waiting:  map[string][]chan struct{}{},
+	}
+}
+
+// requestQueueFromConfig returns the queue cfg describes.
+func requestQueueFromConfig(cfg QueueConfig) *requestQueue {
+	return newRequestQueue(
+		cfg.MaxConcurrent,
+		cfg.BatchMaxConcurrent,
+		cfg.MaxDepth,
+		time.Duration(cfg.TimeoutSeconds)*time.Second,
+	)
+}
+
+// acquire waits for a slot in class and returns the func that frees it. It
+// fails with ResourceExhausted when the queue is full or after the queue
+// timeout, or with ctx's error.
+func (q *requestQueue) acquire(ctx context.Context, class string) (func(), error) {
+	if q == nil {
+		return func() {}, nil
+	}
+	q.mu.Lock()
+	if q.admitsLocked(class) && len(q.waiting[class]) == 0 && (class == priorityInteractive || len(q.waiting[priorityInteractive]) == 0) {
+		q.running[class]++
+		q.mu.Unlock()
+		return q.releaser(class), nil
+	}
+	if waiting := len(q.waiting[priorityInteractive]) + len(q.waiting[priorityBatch]); q.maxDepth > 0 && waiting >= q.maxDepth {
+		q.mu.Unlock()
+		return nil, status.Error(codes.ResourceExhausted, fmt.Sprintf("gateway at capacity: %d requests running and %d queued", q.capacity, waiting))
+	}
+	ready := make(chan struct{})
+	q.waiting[class] = append(q.waiting[class], ready)
+	q.mu.Unlock()
+
+	timer := time.NewTimer(q.timeout)
+	defer timer.Stop()
+	var err error
+	select {
+	case <-ready:
+		return q.releaser(class), nil
+	case <-timer.C:
+		err = status.Error(codes.ResourceExhausted, fmt.Sprintf("gateway at capacity: %s request queued longer than %s", class, q.timeout))
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for i, w := range q.waiting[class] {
+		if w == ready {
+			q.waiting[class] = append(q.waiting[class][:i], q.waiting[class][i+1:]...)
+			return nil, err
+		}
+	}
+	// Granted a slot while giving up: hand it on.
+	q.running[class]--
+	q.dispatchLocked()
+	return nil, err
+}
+
+// releaser frees a class slot once, however often it is called.
+func (q *requestQueue) releaser(class string) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			q.mu.Lock()
+			defer q.mu.Unlock()
+			q.running[class]--
+			q.dispatchLocked()
+		})
+	}
+}
+
+// admitsLocked reports whether a class request could start now.
+func (q *requestQueue) admitsLocked(class string) bool {
+	if q.running[priorityInteractive]+q.running[priorityBatch] >= q.capacity {
+		return false
+	}
+	return class == priorityInteractive || q.running[priorityBatch] < q.batchMax
+}
+
+// dispatchLocked hands free slots to waiters, interactive first.
+func (q *requestQueue) dispatchLocked() {
+	for _, class := range []string{priorityInteractive, priorityBatch} {
+		for len(q.waiting[class]) > 0 && q.admitsLocked(class) {
+			q.running[class]++
+			close(q.waiting[class][0])
+			q.waiting[class] = q.waiting[class][1:]
+		}
+	}
+}
+
+// status is the queue's part of GET /admin/status.
+func (q *requestQueue) status() map[string]any {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return map[string]any{
+		"capacity":  q.capacity,
+		"batch_max": q.batchMax,
+		"max_depth": q.maxDepth,
+		"running": map[string]int{
+			priorityInteractive: q.running[priorityInteractive],
+			priorityBatch:       q.running[priorityBatch],
+		},
+		"waiting": map[string]int{
+			priorityInteractive: len(q.waiting[priorityInteractive]),
+			priorityBatch:       len(q.waiting[priorityBatch]),
+		},
+	}
+}
+
+// acquireProvider waits for provider capacity for a request of the given
+// PlanRequest.priority, logging long waits and rejections.
+func (s *server) acquireProvider(ctx context.Context, priority string) (func(), error) {
+	class := requestPriority(priority)
+	start := time.Now()
+	release, err := s.queue.acquire(ctx, class)
+	lg := logger.NewContextLogger(ctx)
+	if err != nil {
+		lg.Warn("llm_queue_rejected", "priority", class, "wait_ms", time.Since(start).Milliseconds(), "error", err)
+		return nil, err
+	}
+	if wait := time.Since(start); wait >= 100*time.Millisecond {
+		lg.Info("llm_queue_wait", "priority", class, "wait_ms", wait.Milliseconds())
+	}
+	return release, nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRequestQueue_InteractiveFirst(t *testing.T) {
+	q := newRequestQueue(2, 0, 0, time.Second)
+	if q.batchMax != 1 {
+		t.Fatalf("batchMax = %d, want 1", q.batchMax)
+	}
+	ctx := context.Background()
+
+	// Batch may hold one of the two slots, never both.
+	releaseBatch, err := q.acquire(ctx, priorityBatch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	releaseChat, err := q.acquire(ctx, priorityInteractive)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	order := make(chan string, 2)
+	for _, class := range []string{priorityBatch, priorityInteractive} {
+		go func() {
+			release, err := q.acquire(ctx, class)
+			if err != nil {
+				order <- "error: " + err.Error()
+				return
+			}
+			order <- class
+			release()
+		}()
+		// The batch request queues first.
+		waitFor(t, func() bool { return q.status()["waiting"].(map[string]int)[class] == 1 })
+	}
+
+	// A freed batch slot goes to the later interactive request.
+	releaseBatch()
+	if got := <-order; got != priorityInteractive {
+		t.Fatalf("first served = %q, want interactive", got)
+	}
+	if got := <-order; got != priorityBatch {
+		t.Fatalf("second served = %q, want batch", got)
+	}
+	releaseChat()
+	releaseChat() // idempotent
+	if st := q.status(); st["running"].(map[string]int)[priorityInteractive] != 0 || st["running"].(map[string]int)[priorityBatch] != 0 {
+		t.Fatalf("status after release = %v", st)
+	}
+}
+
+func TestRequestQueue_Timeout(t *testing.T) {
+	q := newRequestQueue(1, 0, 0, 20*time.Millisecond)
+	release, err := q.acquire(context.Background(), priorityInteractive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+	if _, err := q.acquire(context.Background(), priorityBatch); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("queued past timeout: err = %v, want ResourceExhausted", err)
+	}
+	if n := q.status()["waiting"].(map[string]int)[priorityBatch]; n != 0 {
+		t.Fatalf("batch waiting = %d after timeout", n)
+	}
+
+	// Unset capacity admits everything.
+	var unlimited *requestQueue
+	if _, err := unlimited.acquire(context.Background(), priorityBatch); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRequestQueue_MaxDepth(t *testing.T) {
+	q := newRequestQueue(1, 0, 1, time.Second)
+	release, err := q.acquire(context.Background(), priorityInteractive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	queued := make(chan error, 1)
+	go func() {
+		release, err := q.acquire(context.Background(), priorityInteractive)
+		if err == nil {
+			release()
+		}
+		queued <- err
+	}()
+	waitFor(t, func() bool { return q.status()["waiting"].(map[string]int)[priorityInteractive] == 1 })
+
+	// The queue is full: rejected without waiting out the timeout.
+	start := time.Now()
+	if _, err := q.acquire(context.Background(), priorityBatch); status.Code(err) != codes.ResourceExhausted || time.Since(start) > 100*time.Millisecond {
+		t.Fatalf("full queue: err = %v after %v", err, time.Since(start))
+	}
+	release()
+	if err := <-queued; err != nil {
+		t.Fatalf("queued request: %v", err)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"backend-go-model-gateway/pkg/tools"
+)
+
+// defaultPromptVersion names the built-in GetPlan system prompt.
+const defaultPromptVersion = "v1"
+
+// defaultSystemPrompt is version v1 of the GetPlan system prompt. .Tools is
+// the <available_tools> section, empty when no tool is offered or the tools
+// are offered natively (.NativeTools).
+//
+//go:embed prompts/v1.tmpl
+var defaultSystemPrompt string
+
+// systemPrompts are the GetPlan system prompt versions: v1 plus those in
+// GATEWAY_PROMPTS_PATH. Planners ask for one per request
+// (PlanRequest.prompt_version); fallback serves requests that ask for none or
+// for one this gateway does not have. A version may have variants for single
+// providers, which replace it for requests served by that provider. A nil
+// *systemPrompts serves v1 only.
+type systemPrompts struct {
+	versions map[string]*template.Template
+	// variants are keyed by version and provider, e.g. "v2/anthropic".
+	variants map[string]*template.Template
+	fallback string
+	// cfg is what p was loaded from and fingerprint is cfg.Path's when it
+	// was read (see watchSystemPrompts).
+	cfg         PromptsConfig
+	fingerprint string
+}
+
+// systemPromptData is what system prompt templates render.
+type systemPromptData struct {
+	Tools       string
+	NativeTools bool
+	// Provider serves the request, e.g. "ollama".
+	Provider string
+}
+
+// newSystemPrompts loads cfg.Path (GATEWAY_PROMPTS_PATH) with cfg.Version
+// (GATEWAY_PROMPT_VERSION) as the fallback. The path is either a JSON object of templates by version,
+// where a version may instead map providers to templates ("default" for the
+// rest), or a directory of <version>.tmpl files with <provider>/<version>.tmpl
+// variants.
+func newSystemPrompts(cfg PromptsConfig) (*systemPrompts, error) {
+	v1, err := parseSystemPrompt(defaultPromptVersion, defaultSystemPrompt)
+	if err != nil {
+		return nil, err
+	}
+	p := &systemPrompts{
+		versions: map[string]*template.Template{defaultPromptVersion: v1},
+		variants: map[string]*template.Template{},
+		fallback: cfg.Version,
+		cfg:      cfg,
+	}
+	if path := cfg.Path; path != "" {
+		// Taken before reading, so an edit made meanwhile is loaded again.
+		if p.fingerprint, err = promptsFingerprint(path); err != nil {
+			return nil, fmt.Errorf("GATEWAY_PROMPTS_PATH %s: %w", path, err)
+		}
+		texts, err := readSystemPrompts(path)
+		if err != nil {
+			return nil, fmt.Errorf("GATEWAY_PROMPTS_PATH %s: %w", path, err)
+		}
+		for key, text := range texts {
+			version, provider, _ := strings.Cut(key, "/")
+			if version == defaultPromptVersion && provider == "" {
+				return nil, fmt.Errorf("GATEWAY_PROMPTS_PATH %s: %s is built in", path, defaultPromptVersion)
+			}
+			t, err := parseSystemPrompt(key, text)
+			if err != nil {
+				return nil, fmt.Errorf("GATEWAY_PROMPTS_PATH %s: %w", path, err)
+			}
+			if provider == "" {
+				p.versions[version] = t
+			} else {
+				p.variants[key] = t
+			}
+		}
+		for key := range p.variants {
+			if version, _, _ := strings.Cut(key, "/"); p.versions[version] == nil {
+				return nil, fmt.Errorf("GATEWAY_PROMPTS_PATH %s: %s has no template for other providers", path, key)
+			}
+		}
+	}
+	if p.versions[p.fallback] == nil {
+		return nil, fmt.Errorf("GATEWAY_PROMPT_VERSION %q: no such prompt version", p.fallback)
+	}
+	return p, nil
+}
+
+// readSystemPrompts reads the templates at path by version, and by
+// "version/provider" for provider variants.
+func readSystemPrompts(path string) (map[string]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	texts := map[string]string{}
+	if info.IsDir() {
+		files, err := systemPromptFiles(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, rel := range files {
+			b, err := os.ReadFile(filepath.Join(path, rel))
+			if err != nil {
+				return nil, err
+			}
+			key := strings.TrimSuffix(filepath.ToSlash(rel), ".tmpl")
+			if provider, version, ok := strings.Cut(key, "/"); ok {
+				key = version + "/" + provider
+			}
+			texts[key] = string(b)
+		}
+		return texts, nil
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return nil, err
+	}
+	for version, v := range raw {
+		var text string
+		if err := json.Unmarshal(v, &text); err == nil {
+			texts[version] = text
+			continue
+		}
+		var byProvider map[string]string
+		if err := json.Unmarshal(v, &byProvider); err != nil {
+			return nil, fmt.Errorf("prompt version %q: want a template or an object of templates by provider", version)
+		}
+		for provider, text := range byProvider {
+			if provider == "default" {
+				texts[version] = text
+			} else {
+				texts[version+"/"+provider] = text
+			}
+		}
+	}
+	return texts, nil
+}
+
+// systemPromptFiles lists the <version>.tmpl and <provider>/<version>.tmpl
+// files of a prompts directory, relative to it.
+func systemPromptFiles(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		if d.IsDir() {
+			// Skip hidden entries, such as the ..data links of mounted
+			// ConfigMaps, and anything deeper than a provider directory.
+			if rel != "." && (strings.HasPrefix(d.Name(), ".") || strings.Contains(rel, string(filepath.Separator))) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(path) == ".tmpl" && !strings.HasPrefix(d.Name(), ".") {
+			files = append(files, rel)
+		}
+		return nil
+	})
+	sort.Strings(files)
+	return files, err
+}
+
+func parseSystemPrompt(version, text string) (*template.Template, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, fmt.Errorf("prompt version %q is empty", version)
+	}
+	t, err := template.New(version).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("prompt version %q: %w", version, err)
+	}
+	// Fields the template names but systemPromptData lacks only fail when
+	// rendered; catch them at load instead of on every GetPlan.
+	if err := t.Execute(io.Discard, systemPromptData{}); err != nil {
+		return nil, fmt.Errorf("prompt version %q: %w", version, err)
+	}
+	return t, nil
+}
+
+// version returns the version a request asking for requested is served with.
+func (p *systemPrompts) version(requested string) string {
+	if p == nil {
+		return defaultPromptVersion
+	}
+	if p.versions[requested] != nil {
+		return requested
+	}
+	return p.fallback
+}
+
+// render returns the system prompt of version (see version) for
+// data.Provider, using the provider's variant when there is one.
+func (p *systemPrompts) render(version string, data systemPromptData) (string, error) {
+	t := defaultSystemPromptTemplate
+	if p != nil {
+		t = p.versions[version]
+		if variant := p.variants[version+"/"+data.Provider]; variant != nil {
+			t = variant
+		}
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("system prompt %q: %w", version, err)
+	}
+	return b.String(), nil
+}
+
+// names lists the loaded versions and provider variants, for logs.
+func (p *systemPrompts) names() []string {
+	if p == nil {
+		return []string{defaultPromptVersion}
+	}
+	names := make([]string, 0, len(p.versions)+len(p.variants))
+	for name := range p.versions {
+		names = append(names, name)
+	}
+	for name := range p.variants {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// plan builds a GetPlan system prompt for provider: the persona's prompt, if
+// any, then the version's instructions. Tools are listed in the prompt unless
+// they are offered natively.
+func (p *systemPrompts) plan(version, provider, persona string, defs []tools.Definition, native bool) (string, error) {
+	data := systemPromptData{NativeTools: native, Provider: provider}
+	if len(defs) > 0 && !native {
+		toolsBlob, _ := json.MarshalIndent(defs, "", "  ")
+		data.Tools = fmt.Sprintf("<available_tools>\n%s\n</available_tools>\n\n", string(toolsBlob))
+	}
+	system, err := p.render(version, data)
+	if err != nil {
+		return "", err
+	}
+	if persona = strings.TrimSpace(persona); persona != "" {
+		system = persona + "\n\n" + system
+	}
+	return system, nil
+}
+
+var defaultSystemPromptTemplate = template.Must(parseSystemPrompt(defaultPromptVersion, defaultSystemPrompt))
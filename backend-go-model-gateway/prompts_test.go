@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	pb "backend-go-model-gateway/proto/proto"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestGetPlan_PromptVersions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prompts.json")
+	if err := os.WriteFile(path, []byte(`{"v2": "You are a terse planner. Reply with JSON.\n{{.Tools}}"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GATEWAY_PROMPTS_PATH", path)
+	prompts, err := newSystemPrompts(envConfig(t).Prompts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sent openai.ChatCompletionRequest
+	llm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&sent)
+		_ = json.NewEncoder(w).Encode(openai.ChatCompletionResponse{
+			Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Role: "assistant", Content: `{"steps":["ok"]}`}}},
+		})
+	}))
+	defer llm.Close()
+	cfg := openai.DefaultConfig("")
+	cfg.BaseURL = llm.URL
+	s := &server{
+		llm:            &llmRuntime{Provider: providerOllama, Model: "m", Client: openai.NewClientWithConfig(cfg)},
+		requestTimeout: time.Duration(defaultRequestTimeoutSec) * time.Second,
+		prompts:        prompts,
+	}
+
+	for _, tc := range []struct{ requested, want, prefix string }{
+		{"v2", "v2", "You are a terse planner."},
+		{"", "v1", "You are a planning assistant."},
+		// Versions this gateway lacks get the default rather than an error.
+		{"v9", "v1", "You are a planning assistant."},
+	} {
+		resp, err := s.GetPlan(context.Background(), &pb.PlanRequest{Prompt: "plan my week", PromptVersion: tc.requested})
+		if err != nil {
+			t.Fatal(err)
+		}
+		system := sent.Messages[0].Content
+		if resp.GetPromptVersion() != tc.want || !strings.HasPrefix(system, tc.prefix) || !strings.Contains(system, "<available_tools>") {
+			t.Fatalf("requested %q: version %q, system message = %s", tc.requested, resp.GetPromptVersion(), system)
+		}
+	}
+}
+
+func TestNewSystemPrompts(t *testing.T) {
+	var nilPrompts *systemPrompts
+	v1, err := nilPrompts.render(nilPrompts.version("v2"), systemPromptData{})
+	if err != nil || !strings.Contains(v1, "WORKING MEMORY") {
+		t.Fatalf("nil prompts render v1: %q, %v", v1, err)
+	}
+
+	t.Setenv("GATEWAY_PROMPT_VERSION", "v2")
+	if _, err := newSystemPrompts(envConfig(t).Prompts); err == nil {
+		t.Fatal("unknown GATEWAY_PROMPT_VERSION accepted")
+	}
+
+	path := filepath.Join(t.TempDir(), "prompts.json")
+	if err := os.WriteFile(path, []byte(`{"v2": "{{.Missing}}"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GATEWAY_PROMPTS_PATH", path)
+	if _, err := newSystemPrompts(envConfig(t).Prompts); err == nil || !strings.Contains(err.Error(), "Missing") {
+		t.Fatalf("template naming an unknown field: %v", err)
+	}
+}
+
+func TestNewSystemPrompts_ProviderVariants(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prompts.json")
+	if err := os.WriteFile(path, []byte(`{"v2": {"default": "Plan in JSON. {{.Provider}}", "anthropic": "Claude: plan in JSON."}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GATEWAY_PROMPTS_PATH", path)
+	prompts, err := newSystemPrompts(envConfig(t).Prompts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for provider, want := range map[string]string{"ollama": "Plan in JSON. ollama", "anthropic": "Claude: plan in JSON."} {
+		if got, err := prompts.plan("v2", provider, "", nil, false); err != nil || got != want {
+			t.Errorf("%s: %q, %v", provider, got, err)
+		}
+	}
+
+	// Directory layout: <version>.tmpl, and <provider>/<version>.tmpl, which
+	// may also vary the built-in v1.
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "v2.tmpl"), "Directory v2")
+	writeFile(t, filepath.Join(dir, "ollama", "v1.tmpl"), "Small model: JSON only.")
+	writeFile(t, filepath.Join(dir, "README.md"), "not a template")
+	t.Setenv("GATEWAY_PROMPTS_PATH", dir)
+	if prompts, err = newSystemPrompts(envConfig(t).Prompts); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(prompts.names(), ","); got != "v1,v1/ollama,v2" {
+		t.Fatalf("names = %s", got)
+	}
+	if got, _ := prompts.plan("v1", "ollama", "", nil, false); got != "Small model: JSON only." {
+		t.Fatalf("v1 for ollama = %q", got)
+	}
+	if got, _ := prompts.plan("v1", "openrouter", "", nil, false); !strings.HasPrefix(got, "You are a planning assistant.") {
+		t.Fatalf("v1 for openrouter = %q", got)
+	}
+
+	// A variant needs a template for the other providers.
+	writeFile(t, filepath.Join(dir, "anthropic", "v3.tmpl"), "Only for Claude")
+	if _, err := newSystemPrompts(envConfig(t).Prompts); err == nil || !strings.Contains(err.Error(), "v3/anthropic") {
+		t.Fatalf("variant without a default: %v", err)
+	}
+}
+
+func TestWatchSystemPrompts(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "v2.tmpl"), "First draft")
+	t.Setenv("GATEWAY_PROMPTS_PATH", dir)
+	prompts, err := newSystemPrompts(envConfig(t).Prompts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &server{prompts: prompts}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.watchSystemPrompts(ctx, 10*time.Millisecond)
+
+	render := func() string {
+		got, _ := s.systemPrompts().plan("v2", "ollama", "", nil, false)
+		return got
+	}
+	waitForPrompt := func(want string) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for render() != want && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		if got := render(); got != want {
+			t.Fatalf("v2 = %q, want %q", got, want)
+		}
+	}
+
+	writeFile(t, filepath.Join(dir, "v2.tmpl"), "Second draft, longer")
+	waitForPrompt("Second draft, longer")
+
+	// A broken edit keeps the prompts that loaded last.
+	writeFile(t, filepath.Join(dir, "v2.tmpl"), "{{.Missing}}")
+	time.Sleep(50 * time.Millisecond)
+	if got := render(); got != "Second draft, longer" {
+		t.Fatalf("after a broken edit: %q", got)
+	}
+	writeFile(t, filepath.Join(dir, "v2.tmpl"), "Third draft")
+	waitForPrompt("Third draft")
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// defaultPromptsReloadSec is how often GATEWAY_PROMPTS_PATH is checked.
+const defaultPromptsReloadSec = 5
+
+// promptsFingerprint changes when a template under path is written, added or
+// removed ("" without a path).
+func promptsFingerprint(path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return fmt.Sprintf("%d/%d", info.ModTime().UnixNano(), info.Size()), nil
+	}
+	files, err := systemPromptFiles(path)
+	if err != nil {
+		return "", err
+	}
+	fp := ""
+	for _, rel := range files {
+		info, err := os.Stat(filepath.Join(path, rel))
+		if err != nil {
+			return "", err
+		}
+		fp += fmt.Sprintf("%s:%d/%d;", rel, info.ModTime().UnixNano(), info.Size())
+	}
+	return fp, nil
+}
+
+// watchSystemPrompts reloads the system prompts whenever the templates under
+// their GATEWAY_PROMPTS_PATH change, checking every interval until ctx ends. A
+// change that does not load is logged and the current prompts stay.
+func (s *server) watchSystemPrompts(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	// rejected is the fingerprint of templates that failed to load, so they
+	// are not retried until they change again.
+	var rejected string
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		current := s.systemPrompts()
+		fp, err := promptsFingerprint(current.loadedConfig().Path)
+		if err != nil || fp == current.loadedFingerprint() || fp == rejected {
+			// A path that cannot be read is reported by the reload that
+			// follows its return, or by POST /admin/reload-config.
+			continue
+		}
+		if err := s.reloadSystemPrompts(current.loadedConfig()); err != nil {
+			rejected = fp
+			log.Printf(
+				`{"timestamp":"%s","level":"warn","service":"%s","component":"prompts","error":%q,"message":"changed system prompts rejected; serving the current ones"}`,
+				time.Now().Format(time.RFC3339Nano), SERVICE_NAME, err.Error(),
+			)
+			continue
+		}
+		log.Printf(
+			`{"timestamp":"%s","level":"info","service":"%s","component":"prompts","versions":%q,"message":"system prompts reloaded"}`,
+			time.Now().Format(time.RFC3339Nano), SERVICE_NAME, fmt.Sprint(s.systemPrompts().names()),
+		)
+	}
+}
+
+// loadedFingerprint is the fingerprint of the templates p was loaded from.
+func (p *systemPrompts) loadedFingerprint() string {
+	if p == nil {
+		return ""
+	}
+	return p.fingerprint
+}
+
+// loadedConfig is the configuration p was loaded from.
+func (p *systemPrompts) loadedConfig() PromptsConfig {
+	if p == nil {
+		return PromptsConfig{Version: defaultPromptVersion}
+	}
+	return p.cfg
+}
+
+// reloadSystemPrompts replaces the system prompts with those cfg names.
+// Requests in progress finish on the old ones.
+func (s *server) reloadSystemPrompts(cfg PromptsConfig) error {
+	prompts, err := newSystemPrompts(cfg)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.prompts = prompts
+	s.mu.Unlock()
+	return nil
+}
@@ -78,9 +78,31 @@ func (x *Resource) GetUri() string {
 }
 
 type PlanRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Prompt        string                 `protobuf:"bytes,1,opt,name=prompt,proto3" json:"prompt,omitempty"`
-	Resources     []*Resource            `protobuf:"bytes,2,rep,name=resources,proto3" json:"resources,omitempty"` // Optional multi-modal inputs.
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Prompt    string                 `protobuf:"bytes,1,opt,name=prompt,proto3" json:"prompt,omitempty"`
+	Resources []*Resource            `protobuf:"bytes,2,rep,name=resources,proto3" json:"resources,omitempty"`                  // Optional multi-modal inputs.
+	RagFilter *RAGFilter             `protobuf:"bytes,3,opt,name=rag_filter,json=ragFilter,proto3" json:"rag_filter,omitempty"` // Optional scope for the gateway's own retrieval.
+	// Persona settings chosen by the planner. All optional.
+	Persona       string   `protobuf:"bytes,4,opt,name=persona,proto3" json:"persona,omitempty"`                                  // Persona name, for logs.
+	SystemPrompt  string   `protobuf:"bytes,5,opt,name=system_prompt,json=systemPrompt,proto3" json:"system_prompt,omitempty"`    // Placed before the gateway's own instructions.
+	Model         string   `protobuf:"bytes,6,opt,name=model,proto3" json:"model,omitempty"`                                      // Preferred model (see LLM_ALLOWED_MODELS).
+	AllowedTools  []string `protobuf:"bytes,7,rep,name=allowed_tools,json=allowedTools,proto3" json:"allowed_tools,omitempty"`    // Tools offered to the model; empty offers all.
+	PromptVersion string   `protobuf:"bytes,8,opt,name=prompt_version,json=promptVersion,proto3" json:"prompt_version,omitempty"` // System prompt version; unknown or empty uses the default.
+	Provider      string   `protobuf:"bytes,9,opt,name=provider,proto3" json:"provider,omitempty"`                                // Preferred provider, one of LLM_PROVIDERS; empty uses the first.
+	// priority is "interactive" (default) or "batch". Batch requests wait
+	// behind interactive ones for provider capacity (LLM_MAX_CONCURRENT_REQUESTS).
+	Priority string `protobuf:"bytes,10,opt,name=priority,proto3" json:"priority,omitempty"`
+	// Generation parameters. Unset uses the gateway's defaults (temperature 0.2);
+	// values out of range are clamped: temperature to [0, 2], top_p to (0, 1],
+	// max_tokens to LLM_MAX_TOKENS_CAP and stop to its first 4 non-empty entries.
+	Temperature *float32 `protobuf:"fixed32,11,opt,name=temperature,proto3,oneof" json:"temperature,omitempty"`
+	MaxTokens   *int32   `protobuf:"varint,12,opt,name=max_tokens,json=maxTokens,proto3,oneof" json:"max_tokens,omitempty"`
+	TopP        *float32 `protobuf:"fixed32,13,opt,name=top_p,json=topP,proto3,oneof" json:"top_p,omitempty"`
+	Stop        []string `protobuf:"bytes,14,rep,name=stop,proto3" json:"stop,omitempty"`
+	// messages are the run's earlier turns, sent after prompt, oldest first:
+	// "assistant" for a plan the model returned and "user" for what answered
+	// it (a tool result or error). Text only; other roles are rejected.
+	Messages      []*ChatMessage `protobuf:"bytes,15,rep,name=messages,proto3" json:"messages,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -129,11 +151,119 @@ func (x *PlanRequest) GetResources() []*Resource {
 	return nil
 }
 
+func (x *PlanRequest) GetRagFilter() *RAGFilter {
+	if x != nil {
+		return x.RagFilter
+	}
+	return nil
+}
+
+func (x *PlanRequest) GetPersona() string {
+	if x != nil {
+		return x.Persona
+	}
+	return ""
+}
+
+func (x *PlanRequest) GetSystemPrompt() string {
+	if x != nil {
+		return x.SystemPrompt
+	}
+	return ""
+}
+
+func (x *PlanRequest) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *PlanRequest) GetAllowedTools() []string {
+	if x != nil {
+		return x.AllowedTools
+	}
+	return nil
+}
+
+func (x *PlanRequest) GetPromptVersion() string {
+	if x != nil {
+		return x.PromptVersion
+	}
+	return ""
+}
+
+func (x *PlanRequest) GetProvider() string {
+	if x != nil {
+		return x.Provider
+	}
+	return ""
+}
+
+func (x *PlanRequest) GetPriority() string {
+	if x != nil {
+		return x.Priority
+	}
+	return ""
+}
+
+func (x *PlanRequest) GetTemperature() float32 {
+	if x != nil && x.Temperature != nil {
+		return *x.Temperature
+	}
+	return 0
+}
+
+func (x *PlanRequest) GetMaxTokens() int32 {
+	if x != nil && x.MaxTokens != nil {
+		return *x.MaxTokens
+	}
+	return 0
+}
+
+func (x *PlanRequest) GetTopP() float32 {
+	if x != nil && x.TopP != nil {
+		return *x.TopP
+	}
+	return 0
+}
+
+func (x *PlanRequest) GetStop() []string {
+	if x != nil {
+		return x.Stop
+	}
+	return nil
+}
+
+func (x *PlanRequest) GetMessages() []*ChatMessage {
+	if x != nil {
+		return x.Messages
+	}
+	return nil
+}
+
 type PlanResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Plan          string                 `protobuf:"bytes,1,opt,name=plan,proto3" json:"plan,omitempty"`
-	ModelName     string                 `protobuf:"bytes,2,opt,name=model_name,json=modelName,proto3" json:"model_name,omitempty"`
-	LatencyMs     int64                  `protobuf:"varint,3,opt,name=latency_ms,json=latencyMs,proto3" json:"latency_ms,omitempty"`
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Plan      string                 `protobuf:"bytes,1,opt,name=plan,proto3" json:"plan,omitempty"`
+	ModelName string                 `protobuf:"bytes,2,opt,name=model_name,json=modelName,proto3" json:"model_name,omitempty"`
+	LatencyMs int64                  `protobuf:"varint,3,opt,name=latency_ms,json=latencyMs,proto3" json:"latency_ms,omitempty"`
+	// ungrounded is set when the plan was generated without retrieved context:
+	// retrieval failed or found nothing scoring at least RAG_MIN_SCORE.
+	Ungrounded    bool   `protobuf:"varint,4,opt,name=ungrounded,proto3" json:"ungrounded,omitempty"`
+	PromptVersion string `protobuf:"bytes,5,opt,name=prompt_version,json=promptVersion,proto3" json:"prompt_version,omitempty"` // System prompt version the plan was generated with.
+	// reasoning is the trace a reasoning model produced before the plan, kept
+	// out of plan. Only set with LLM_REASONING=return.
+	Reasoning string `protobuf:"bytes,6,opt,name=reasoning,proto3" json:"reasoning,omitempty"`
+	// provider served the plan: the primary or an LLM_PROVIDERS failover.
+	Provider string `protobuf:"bytes,7,opt,name=provider,proto3" json:"provider,omitempty"`
+	// Provider token usage of the plan, schema repairs included; 0 when the
+	// provider does not report it.
+	PromptTokens     int64 `protobuf:"varint,8,opt,name=prompt_tokens,json=promptTokens,proto3" json:"prompt_tokens,omitempty"`
+	CompletionTokens int64 `protobuf:"varint,9,opt,name=completion_tokens,json=completionTokens,proto3" json:"completion_tokens,omitempty"`
+	// degraded is set when no provider could serve the request and the plan is
+	// a retrieval-only answer built from matches (LLM_RETRIEVAL_FALLBACK=on).
+	Degraded      bool        `protobuf:"varint,10,opt,name=degraded,proto3" json:"degraded,omitempty"`
+	Matches       []*RAGMatch `protobuf:"bytes,11,rep,name=matches,proto3" json:"matches,omitempty"` // The retrieved passages; set when degraded.
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -189,18 +319,209 @@ func (x *PlanResponse) GetLatencyMs() int64 {
 	return 0
 }
 
+func (x *PlanResponse) GetUngrounded() bool {
+	if x != nil {
+		return x.Ungrounded
+	}
+	return false
+}
+
+func (x *PlanResponse) GetPromptVersion() string {
+	if x != nil {
+		return x.PromptVersion
+	}
+	return ""
+}
+
+func (x *PlanResponse) GetReasoning() string {
+	if x != nil {
+		return x.Reasoning
+	}
+	return ""
+}
+
+func (x *PlanResponse) GetProvider() string {
+	if x != nil {
+		return x.Provider
+	}
+	return ""
+}
+
+func (x *PlanResponse) GetPromptTokens() int64 {
+	if x != nil {
+		return x.PromptTokens
+	}
+	return 0
+}
+
+func (x *PlanResponse) GetCompletionTokens() int64 {
+	if x != nil {
+		return x.CompletionTokens
+	}
+	return 0
+}
+
+func (x *PlanResponse) GetDegraded() bool {
+	if x != nil {
+		return x.Degraded
+	}
+	return false
+}
+
+func (x *PlanResponse) GetMatches() []*RAGMatch {
+	if x != nil {
+		return x.Matches
+	}
+	return nil
+}
+
+// PlanChunk is one StreamPlan message. Deltas carry the model's reply as it
+// is generated; the last message carries the plan GetPlan would return. Only
+// final is authoritative: schema repairs, normalization and PII restoration
+// happen after the deltas, and a failover starts the deltas over.
+type PlanChunk struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Delta         string                 `protobuf:"bytes,1,opt,name=delta,proto3" json:"delta,omitempty"`
+	Final         *PlanResponse          `protobuf:"bytes,2,opt,name=final,proto3" json:"final,omitempty"` // Set on the last message only.
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PlanChunk) Reset() {
+	*x = PlanChunk{}
+	mi := &file_proto_model_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PlanChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PlanChunk) ProtoMessage() {}
+
+func (x *PlanChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_model_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PlanChunk.ProtoReflect.Descriptor instead.
+func (*PlanChunk) Descriptor() ([]byte, []int) {
+	return file_proto_model_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *PlanChunk) GetDelta() string {
+	if x != nil {
+		return x.Delta
+	}
+	return ""
+}
+
+func (x *PlanChunk) GetFinal() *PlanResponse {
+	if x != nil {
+		return x.Final
+	}
+	return nil
+}
+
+// RAGFilter scopes retrieval by document metadata. Unset fields do not filter;
+// set fields are ANDed together.
+type RAGFilter struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	Sources           []string               `protobuf:"bytes,1,rep,name=sources,proto3" json:"sources,omitempty"`                                                 // document source is any of these
+	Tags              []string               `protobuf:"bytes,2,rep,name=tags,proto3" json:"tags,omitempty"`                                                       // document carries all of these tags
+	DocumentIds       []string               `protobuf:"bytes,3,rep,name=document_ids,json=documentIds,proto3" json:"document_ids,omitempty"`                      // document ID is any of these
+	CreatedAfterUnix  int64                  `protobuf:"varint,4,opt,name=created_after_unix,json=createdAfterUnix,proto3" json:"created_after_unix,omitempty"`    // created at or after (unix seconds); 0 = unbounded
+	CreatedBeforeUnix int64                  `protobuf:"varint,5,opt,name=created_before_unix,json=createdBeforeUnix,proto3" json:"created_before_unix,omitempty"` // created before (unix seconds); 0 = unbounded
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *RAGFilter) Reset() {
+	*x = RAGFilter{}
+	mi := &file_proto_model_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RAGFilter) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RAGFilter) ProtoMessage() {}
+
+func (x *RAGFilter) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_model_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RAGFilter.ProtoReflect.Descriptor instead.
+func (*RAGFilter) Descriptor() ([]byte, []int) {
+	return file_proto_model_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *RAGFilter) GetSources() []string {
+	if x != nil {
+		return x.Sources
+	}
+	return nil
+}
+
+func (x *RAGFilter) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *RAGFilter) GetDocumentIds() []string {
+	if x != nil {
+		return x.DocumentIds
+	}
+	return nil
+}
+
+func (x *RAGFilter) GetCreatedAfterUnix() int64 {
+	if x != nil {
+		return x.CreatedAfterUnix
+	}
+	return 0
+}
+
+func (x *RAGFilter) GetCreatedBeforeUnix() int64 {
+	if x != nil {
+		return x.CreatedBeforeUnix
+	}
+	return 0
+}
+
 type RAGContextRequest struct {
 	state          protoimpl.MessageState `protogen:"open.v1"`
 	Query          string                 `protobuf:"bytes,1,opt,name=query,proto3" json:"query,omitempty"`
 	TopK           int32                  `protobuf:"varint,2,opt,name=top_k,json=topK,proto3" json:"top_k,omitempty"`
 	KnowledgeBases []string               `protobuf:"bytes,3,rep,name=knowledge_bases,json=knowledgeBases,proto3" json:"knowledge_bases,omitempty"`
+	Filter         *RAGFilter             `protobuf:"bytes,4,opt,name=filter,proto3" json:"filter,omitempty"`
 	unknownFields  protoimpl.UnknownFields
 	sizeCache      protoimpl.SizeCache
 }
 
 func (x *RAGContextRequest) Reset() {
 	*x = RAGContextRequest{}
-	mi := &file_proto_model_proto_msgTypes[3]
+	mi := &file_proto_model_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -212,7 +533,7 @@ func (x *RAGContextRequest) String() string {
 func (*RAGContextRequest) ProtoMessage() {}
 
 func (x *RAGContextRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_model_proto_msgTypes[3]
+	mi := &file_proto_model_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -225,7 +546,7 @@ func (x *RAGContextRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RAGContextRequest.ProtoReflect.Descriptor instead.
 func (*RAGContextRequest) Descriptor() ([]byte, []int) {
-	return file_proto_model_proto_rawDescGZIP(), []int{3}
+	return file_proto_model_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *RAGContextRequest) GetQuery() string {
@@ -249,6 +570,13 @@ func (x *RAGContextRequest) GetKnowledgeBases() []string {
 	return nil
 }
 
+func (x *RAGContextRequest) GetFilter() *RAGFilter {
+	if x != nil {
+		return x.Filter
+	}
+	return nil
+}
+
 type RAGMatch struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
@@ -262,7 +590,7 @@ type RAGMatch struct {
 
 func (x *RAGMatch) Reset() {
 	*x = RAGMatch{}
-	mi := &file_proto_model_proto_msgTypes[4]
+	mi := &file_proto_model_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -274,7 +602,7 @@ func (x *RAGMatch) String() string {
 func (*RAGMatch) ProtoMessage() {}
 
 func (x *RAGMatch) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_model_proto_msgTypes[4]
+	mi := &file_proto_model_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -287,7 +615,7 @@ func (x *RAGMatch) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RAGMatch.ProtoReflect.Descriptor instead.
 func (*RAGMatch) Descriptor() ([]byte, []int) {
-	return file_proto_model_proto_rawDescGZIP(), []int{4}
+	return file_proto_model_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *RAGMatch) GetId() string {
@@ -334,7 +662,7 @@ type RAGContextResponse struct {
 
 func (x *RAGContextResponse) Reset() {
 	*x = RAGContextResponse{}
-	mi := &file_proto_model_proto_msgTypes[5]
+	mi := &file_proto_model_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -346,7 +674,7 @@ func (x *RAGContextResponse) String() string {
 func (*RAGContextResponse) ProtoMessage() {}
 
 func (x *RAGContextResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_model_proto_msgTypes[5]
+	mi := &file_proto_model_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -359,7 +687,7 @@ func (x *RAGContextResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RAGContextResponse.ProtoReflect.Descriptor instead.
 func (*RAGContextResponse) Descriptor() ([]byte, []int) {
-	return file_proto_model_proto_rawDescGZIP(), []int{5}
+	return file_proto_model_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *RAGContextResponse) GetMatches() []*RAGMatch {
@@ -391,7 +719,7 @@ type ToolRequest struct {
 
 func (x *ToolRequest) Reset() {
 	*x = ToolRequest{}
-	mi := &file_proto_model_proto_msgTypes[6]
+	mi := &file_proto_model_proto_msgTypes[8]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -403,7 +731,7 @@ func (x *ToolRequest) String() string {
 func (*ToolRequest) ProtoMessage() {}
 
 func (x *ToolRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_model_proto_msgTypes[6]
+	mi := &file_proto_model_proto_msgTypes[8]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -416,7 +744,7 @@ func (x *ToolRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ToolRequest.ProtoReflect.Descriptor instead.
 func (*ToolRequest) Descriptor() ([]byte, []int) {
-	return file_proto_model_proto_rawDescGZIP(), []int{6}
+	return file_proto_model_proto_rawDescGZIP(), []int{8}
 }
 
 func (x *ToolRequest) GetToolName() string {
@@ -472,7 +800,7 @@ type ToolResponse struct {
 
 func (x *ToolResponse) Reset() {
 	*x = ToolResponse{}
-	mi := &file_proto_model_proto_msgTypes[7]
+	mi := &file_proto_model_proto_msgTypes[9]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -484,7 +812,7 @@ func (x *ToolResponse) String() string {
 func (*ToolResponse) ProtoMessage() {}
 
 func (x *ToolResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_model_proto_msgTypes[7]
+	mi := &file_proto_model_proto_msgTypes[9]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -497,7 +825,7 @@ func (x *ToolResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ToolResponse.ProtoReflect.Descriptor instead.
 func (*ToolResponse) Descriptor() ([]byte, []int) {
-	return file_proto_model_proto_rawDescGZIP(), []int{7}
+	return file_proto_model_proto_rawDescGZIP(), []int{9}
 }
 
 func (x *ToolResponse) GetStatus() string {
@@ -521,51 +849,1460 @@ func (x *ToolResponse) GetStderr() string {
 	return ""
 }
 
-var File_proto_model_proto protoreflect.FileDescriptor
+type ListToolsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
 
-const file_proto_model_proto_rawDesc = "" +
-	"\n" +
-	"\x11proto/model.proto\x12\fmodelgateway\"0\n" +
-	"\bResource\x12\x12\n" +
-	"\x04type\x18\x01 \x01(\tR\x04type\x12\x10\n" +
-	"\x03uri\x18\x02 \x01(\tR\x03uri\"[\n" +
-	"\vPlanRequest\x12\x16\n" +
-	"\x06prompt\x18\x01 \x01(\tR\x06prompt\x124\n" +
-	"\tresources\x18\x02 \x03(\v2\x16.modelgateway.ResourceR\tresources\"`\n" +
-	"\fPlanResponse\x12\x12\n" +
-	"\x04plan\x18\x01 \x01(\tR\x04plan\x12\x1d\n" +
-	"\n" +
-	"model_name\x18\x02 \x01(\tR\tmodelName\x12\x1d\n" +
-	"\n" +
-	"latency_ms\x18\x03 \x01(\x03R\tlatencyMs\"g\n" +
-	"\x11RAGContextRequest\x12\x14\n" +
-	"\x05query\x18\x01 \x01(\tR\x05query\x12\x13\n" +
-	"\x05top_k\x18\x02 \x01(\x05R\x04topK\x12'\n" +
-	"\x0fknowledge_bases\x18\x03 \x03(\tR\x0eknowledgeBases\"\x89\x01\n" +
-	"\bRAGMatch\x12\x0e\n" +
-	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
-	"\x04text\x18\x02 \x01(\tR\x04text\x12\x1a\n" +
-	"\bdistance\x18\x03 \x01(\x01R\bdistance\x12%\n" +
-	"\x0eknowledge_base\x18\x04 \x01(\tR\rknowledgeBase\x12\x16\n" +
-	"\x06source\x18\x05 \x01(\tR\x06source\"F\n" +
-	"\x12RAGContextResponse\x120\n" +
-	"\amatches\x18\x01 \x03(\v2\x16.modelgateway.RAGMatchR\amatches\"\xf1\x01\n" +
-	"\vToolRequest\x12\x1b\n" +
-	"\ttool_name\x18\x01 \x01(\tR\btoolName\x12\x1b\n" +
-	"\targs_json\x18\x02 \x01(\tR\bargsJson\x123\n" +
-	"\x15execution_environment\x18\x03 \x01(\tR\x14executionEnvironment\x12\"\n" +
-	"\rcpu_limit_mhz\x18\x04 \x01(\x05R\vcpuLimitMhz\x12&\n" +
-	"\x0fmemory_limit_mb\x18\x05 \x01(\x05R\rmemoryLimitMb\x12'\n" +
-	"\x0ftimeout_seconds\x18\x06 \x01(\x05R\x0etimeoutSeconds\"V\n" +
+func (x *ListToolsRequest) Reset() {
+	*x = ListToolsRequest{}
+	mi := &file_proto_model_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListToolsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListToolsRequest) ProtoMessage() {}
+
+func (x *ListToolsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_model_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListToolsRequest.ProtoReflect.Descriptor instead.
+func (*ListToolsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_model_proto_rawDescGZIP(), []int{10}
+}
+
+type ListToolsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Tools         []*ToolDefinition      `protobuf:"bytes,1,rep,name=tools,proto3" json:"tools,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListToolsResponse) Reset() {
+	*x = ListToolsResponse{}
+	mi := &file_proto_model_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListToolsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListToolsResponse) ProtoMessage() {}
+
+func (x *ListToolsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_model_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListToolsResponse.ProtoReflect.Descriptor instead.
+func (*ListToolsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_model_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *ListToolsResponse) GetTools() []*ToolDefinition {
+	if x != nil {
+		return x.Tools
+	}
+	return nil
+}
+
+// ToolDefinition mirrors pkg/tools.Definition.
+type ToolDefinition struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	Name        string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Description string                 `protobuf:"bytes,2,opt,name=description,proto3" json:"description,omitempty"`
+	// Every parameter is required.
+	Parameters    map[string]*ToolParameter `protobuf:"bytes,3,rep,name=parameters,proto3" json:"parameters,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ToolDefinition) Reset() {
+	*x = ToolDefinition{}
+	mi := &file_proto_model_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ToolDefinition) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ToolDefinition) ProtoMessage() {}
+
+func (x *ToolDefinition) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_model_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ToolDefinition.ProtoReflect.Descriptor instead.
+func (*ToolDefinition) Descriptor() ([]byte, []int) {
+	return file_proto_model_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *ToolDefinition) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ToolDefinition) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *ToolDefinition) GetParameters() map[string]*ToolParameter {
+	if x != nil {
+		return x.Parameters
+	}
+	return nil
+}
+
+type ToolParameter struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Type          string                 `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"` // JSON schema type: string, number, integer, boolean, object or array
+	Description   string                 `protobuf:"bytes,2,opt,name=description,proto3" json:"description,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ToolParameter) Reset() {
+	*x = ToolParameter{}
+	mi := &file_proto_model_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ToolParameter) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ToolParameter) ProtoMessage() {}
+
+func (x *ToolParameter) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_model_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ToolParameter.ProtoReflect.Descriptor instead.
+func (*ToolParameter) Descriptor() ([]byte, []int) {
+	return file_proto_model_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *ToolParameter) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *ToolParameter) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+type RerankRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Query         string                 `protobuf:"bytes,1,opt,name=query,proto3" json:"query,omitempty"`
+	Passages      []string               `protobuf:"bytes,2,rep,name=passages,proto3" json:"passages,omitempty"`
+	Model         string                 `protobuf:"bytes,3,opt,name=model,proto3" json:"model,omitempty"` // optional; the service's default otherwise
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RerankRequest) Reset() {
+	*x = RerankRequest{}
+	mi := &file_proto_model_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RerankRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RerankRequest) ProtoMessage() {}
+
+func (x *RerankRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_model_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RerankRequest.ProtoReflect.Descriptor instead.
+func (*RerankRequest) Descriptor() ([]byte, []int) {
+	return file_proto_model_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *RerankRequest) GetQuery() string {
+	if x != nil {
+		return x.Query
+	}
+	return ""
+}
+
+func (x *RerankRequest) GetPassages() []string {
+	if x != nil {
+		return x.Passages
+	}
+	return nil
+}
+
+func (x *RerankRequest) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+// RerankResponse scores every passage, in request order; higher is more relevant.
+type RerankResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Scores        []float64              `protobuf:"fixed64,1,rep,packed,name=scores,proto3" json:"scores,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RerankResponse) Reset() {
+	*x = RerankResponse{}
+	mi := &file_proto_model_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RerankResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RerankResponse) ProtoMessage() {}
+
+func (x *RerankResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_model_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RerankResponse.ProtoReflect.Descriptor instead.
+func (*RerankResponse) Descriptor() ([]byte, []int) {
+	return file_proto_model_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *RerankResponse) GetScores() []float64 {
+	if x != nil {
+		return x.Scores
+	}
+	return nil
+}
+
+// EvaluateRequest asks the gateway's model to judge a planner's final answer
+// (LLM-as-judge).
+type EvaluateRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Prompt        string                 `protobuf:"bytes,1,opt,name=prompt,proto3" json:"prompt,omitempty"`
+	Answer        string                 `protobuf:"bytes,2,opt,name=answer,proto3" json:"answer,omitempty"`
+	Context       []string               `protobuf:"bytes,3,rep,name=context,proto3" json:"context,omitempty"` // Retrieved passages the answer should rest on.
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EvaluateRequest) Reset() {
+	*x = EvaluateRequest{}
+	mi := &file_proto_model_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EvaluateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EvaluateRequest) ProtoMessage() {}
+
+func (x *EvaluateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_model_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EvaluateRequest.ProtoReflect.Descriptor instead.
+func (*EvaluateRequest) Descriptor() ([]byte, []int) {
+	return file_proto_model_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *EvaluateRequest) GetPrompt() string {
+	if x != nil {
+		return x.Prompt
+	}
+	return ""
+}
+
+func (x *EvaluateRequest) GetAnswer() string {
+	if x != nil {
+		return x.Answer
+	}
+	return ""
+}
+
+func (x *EvaluateRequest) GetContext() []string {
+	if x != nil {
+		return x.Context
+	}
+	return nil
+}
+
+// Scores are 0 (worst) to 1 (best).
+type EvaluateResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Relevance     float64                `protobuf:"fixed64,1,opt,name=relevance,proto3" json:"relevance,omitempty"`       // Does the answer address the prompt?
+	Groundedness  float64                `protobuf:"fixed64,2,opt,name=groundedness,proto3" json:"groundedness,omitempty"` // Is it supported by the context?
+	Score         float64                `protobuf:"fixed64,3,opt,name=score,proto3" json:"score,omitempty"`               // Overall.
+	Rationale     string                 `protobuf:"bytes,4,opt,name=rationale,proto3" json:"rationale,omitempty"`
+	ModelName     string                 `protobuf:"bytes,5,opt,name=model_name,json=modelName,proto3" json:"model_name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EvaluateResponse) Reset() {
+	*x = EvaluateResponse{}
+	mi := &file_proto_model_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EvaluateResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EvaluateResponse) ProtoMessage() {}
+
+func (x *EvaluateResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_model_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EvaluateResponse.ProtoReflect.Descriptor instead.
+func (*EvaluateResponse) Descriptor() ([]byte, []int) {
+	return file_proto_model_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *EvaluateResponse) GetRelevance() float64 {
+	if x != nil {
+		return x.Relevance
+	}
+	return 0
+}
+
+func (x *EvaluateResponse) GetGroundedness() float64 {
+	if x != nil {
+		return x.Groundedness
+	}
+	return 0
+}
+
+func (x *EvaluateResponse) GetScore() float64 {
+	if x != nil {
+		return x.Score
+	}
+	return 0
+}
+
+func (x *EvaluateResponse) GetRationale() string {
+	if x != nil {
+		return x.Rationale
+	}
+	return ""
+}
+
+func (x *EvaluateResponse) GetModelName() string {
+	if x != nil {
+		return x.ModelName
+	}
+	return ""
+}
+
+type CapabilitiesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CapabilitiesRequest) Reset() {
+	*x = CapabilitiesRequest{}
+	mi := &file_proto_model_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CapabilitiesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CapabilitiesRequest) ProtoMessage() {}
+
+func (x *CapabilitiesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_model_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CapabilitiesRequest.ProtoReflect.Descriptor instead.
+func (*CapabilitiesRequest) Descriptor() ([]byte, []int) {
+	return file_proto_model_proto_rawDescGZIP(), []int{18}
+}
+
+// CapabilitiesResponse describes the gateway's current configuration, so
+// callers can adapt to it (e.g. run mock tools against a mock provider).
+type CapabilitiesResponse struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Provider       string                 `protobuf:"bytes,1,opt,name=provider,proto3" json:"provider,omitempty"`                                   // Primary provider, e.g. "openrouter" or "mock".
+	Providers      []string               `protobuf:"bytes,2,rep,name=providers,proto3" json:"providers,omitempty"`                                 // The LLM_PROVIDERS failover chain, primary first.
+	Mock           bool                   `protobuf:"varint,3,opt,name=mock,proto3" json:"mock,omitempty"`                                          // Plans come from the deterministic mock provider.
+	Version        string                 `protobuf:"bytes,4,opt,name=version,proto3" json:"version,omitempty"`                                     // Gateway version.
+	Model          string                 `protobuf:"bytes,5,opt,name=model,proto3" json:"model,omitempty"`                                         // Primary provider's configured model.
+	KnowledgeBases []string               `protobuf:"bytes,6,rep,name=knowledge_bases,json=knowledgeBases,proto3" json:"knowledge_bases,omitempty"` // KBs GetPlan retrieves from.
+	// Settings callers check theirs against (see pkg/drift).
+	TimeoutSeconds int32  `protobuf:"varint,7,opt,name=timeout_seconds,json=timeoutSeconds,proto3" json:"timeout_seconds,omitempty"` // Longest a GetPlan takes: REQUEST_TIMEOUT_SECONDS per provider in the chain.
+	TraceHeader    string `protobuf:"bytes,8,opt,name=trace_header,json=traceHeader,proto3" json:"trace_header,omitempty"`           // Metadata key trace IDs are read from.
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *CapabilitiesResponse) Reset() {
+	*x = CapabilitiesResponse{}
+	mi := &file_proto_model_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CapabilitiesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CapabilitiesResponse) ProtoMessage() {}
+
+func (x *CapabilitiesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_model_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CapabilitiesResponse.ProtoReflect.Descriptor instead.
+func (*CapabilitiesResponse) Descriptor() ([]byte, []int) {
+	return file_proto_model_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *CapabilitiesResponse) GetProvider() string {
+	if x != nil {
+		return x.Provider
+	}
+	return ""
+}
+
+func (x *CapabilitiesResponse) GetProviders() []string {
+	if x != nil {
+		return x.Providers
+	}
+	return nil
+}
+
+func (x *CapabilitiesResponse) GetMock() bool {
+	if x != nil {
+		return x.Mock
+	}
+	return false
+}
+
+func (x *CapabilitiesResponse) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *CapabilitiesResponse) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *CapabilitiesResponse) GetKnowledgeBases() []string {
+	if x != nil {
+		return x.KnowledgeBases
+	}
+	return nil
+}
+
+func (x *CapabilitiesResponse) GetTimeoutSeconds() int32 {
+	if x != nil {
+		return x.TimeoutSeconds
+	}
+	return 0
+}
+
+func (x *CapabilitiesResponse) GetTraceHeader() string {
+	if x != nil {
+		return x.TraceHeader
+	}
+	return ""
+}
+
+type VersionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *VersionRequest) Reset() {
+	*x = VersionRequest{}
+	mi := &file_proto_model_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VersionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VersionRequest) ProtoMessage() {}
+
+func (x *VersionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_model_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VersionRequest.ProtoReflect.Descriptor instead.
+func (*VersionRequest) Descriptor() ([]byte, []int) {
+	return file_proto_model_proto_rawDescGZIP(), []int{20}
+}
+
+// VersionResponse is the gateway's build info, as on GET /version (see
+// pkg/buildinfo).
+type VersionResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Service       string                 `protobuf:"bytes,1,opt,name=service,proto3" json:"service,omitempty"`
+	Version       string                 `protobuf:"bytes,2,opt,name=version,proto3" json:"version,omitempty"`
+	GitSha        string                 `protobuf:"bytes,3,opt,name=git_sha,json=gitSha,proto3" json:"git_sha,omitempty"`          // Empty when unknown.
+	BuildTime     string                 `protobuf:"bytes,4,opt,name=build_time,json=buildTime,proto3" json:"build_time,omitempty"` // RFC 3339; empty when unknown.
+	GoVersion     string                 `protobuf:"bytes,5,opt,name=go_version,json=goVersion,proto3" json:"go_version,omitempty"`
+	Features      []string               `protobuf:"bytes,6,rep,name=features,proto3" json:"features,omitempty"` // Enabled optional features, sorted.
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *VersionResponse) Reset() {
+	*x = VersionResponse{}
+	mi := &file_proto_model_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VersionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VersionResponse) ProtoMessage() {}
+
+func (x *VersionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_model_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VersionResponse.ProtoReflect.Descriptor instead.
+func (*VersionResponse) Descriptor() ([]byte, []int) {
+	return file_proto_model_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *VersionResponse) GetService() string {
+	if x != nil {
+		return x.Service
+	}
+	return ""
+}
+
+func (x *VersionResponse) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *VersionResponse) GetGitSha() string {
+	if x != nil {
+		return x.GitSha
+	}
+	return ""
+}
+
+func (x *VersionResponse) GetBuildTime() string {
+	if x != nil {
+		return x.BuildTime
+	}
+	return ""
+}
+
+func (x *VersionResponse) GetGoVersion() string {
+	if x != nil {
+		return x.GoVersion
+	}
+	return ""
+}
+
+func (x *VersionResponse) GetFeatures() []string {
+	if x != nil {
+		return x.Features
+	}
+	return nil
+}
+
+type ListModelsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListModelsRequest) Reset() {
+	*x = ListModelsRequest{}
+	mi := &file_proto_model_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListModelsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListModelsRequest) ProtoMessage() {}
+
+func (x *ListModelsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_model_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListModelsRequest.ProtoReflect.Descriptor instead.
+func (*ListModelsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_model_proto_rawDescGZIP(), []int{22}
+}
+
+// ListModelsResponse is the catalog of models GetPlan can route to, in
+// failover order.
+type ListModelsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Models        []*ModelInfo           `protobuf:"bytes,1,rep,name=models,proto3" json:"models,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListModelsResponse) Reset() {
+	*x = ListModelsResponse{}
+	mi := &file_proto_model_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListModelsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListModelsResponse) ProtoMessage() {}
+
+func (x *ListModelsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_model_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListModelsResponse.ProtoReflect.Descriptor instead.
+func (*ListModelsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_model_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *ListModelsResponse) GetModels() []*ModelInfo {
+	if x != nil {
+		return x.Models
+	}
+	return nil
+}
+
+type ModelInfo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Provider      string                 `protobuf:"bytes,1,opt,name=provider,proto3" json:"provider,omitempty"`
+	Model         string                 `protobuf:"bytes,2,opt,name=model,proto3" json:"model,omitempty"`
+	Primary       bool                   `protobuf:"varint,3,opt,name=primary,proto3" json:"primary,omitempty"`                                  // The provider's configured model; others are LLM_ALLOWED_MODELS.
+	NativeTools   bool                   `protobuf:"varint,4,opt,name=native_tools,json=nativeTools,proto3" json:"native_tools,omitempty"`       // GetPlan offers tools through the API, not the JSON convention.
+	Vision        bool                   `protobuf:"varint,5,opt,name=vision,proto3" json:"vision,omitempty"`                                    // Accepts image input.
+	ContextWindow int32                  `protobuf:"varint,6,opt,name=context_window,json=contextWindow,proto3" json:"context_window,omitempty"` // In tokens; 0 when unknown.
+	Health        *ModelHealth           `protobuf:"bytes,7,opt,name=health,proto3" json:"health,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ModelInfo) Reset() {
+	*x = ModelInfo{}
+	mi := &file_proto_model_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ModelInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ModelInfo) ProtoMessage() {}
+
+func (x *ModelInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_model_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ModelInfo.ProtoReflect.Descriptor instead.
+func (*ModelInfo) Descriptor() ([]byte, []int) {
+	return file_proto_model_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *ModelInfo) GetProvider() string {
+	if x != nil {
+		return x.Provider
+	}
+	return ""
+}
+
+func (x *ModelInfo) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *ModelInfo) GetPrimary() bool {
+	if x != nil {
+		return x.Primary
+	}
+	return false
+}
+
+func (x *ModelInfo) GetNativeTools() bool {
+	if x != nil {
+		return x.NativeTools
+	}
+	return false
+}
+
+func (x *ModelInfo) GetVision() bool {
+	if x != nil {
+		return x.Vision
+	}
+	return false
+}
+
+func (x *ModelInfo) GetContextWindow() int32 {
+	if x != nil {
+		return x.ContextWindow
+	}
+	return 0
+}
+
+func (x *ModelInfo) GetHealth() *ModelHealth {
+	if x != nil {
+		return x.Health
+	}
+	return nil
+}
+
+// ModelHealth is the result of the model's last 1-token probe.
+type ModelHealth struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Status        string                 `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"` // "ok", "error" or "unknown" (probing is off).
+	LatencyMs     int64                  `protobuf:"varint,2,opt,name=latency_ms,json=latencyMs,proto3" json:"latency_ms,omitempty"`
+	Error         string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	CheckedAtUnix int64                  `protobuf:"varint,4,opt,name=checked_at_unix,json=checkedAtUnix,proto3" json:"checked_at_unix,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ModelHealth) Reset() {
+	*x = ModelHealth{}
+	mi := &file_proto_model_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ModelHealth) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ModelHealth) ProtoMessage() {}
+
+func (x *ModelHealth) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_model_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ModelHealth.ProtoReflect.Descriptor instead.
+func (*ModelHealth) Descriptor() ([]byte, []int) {
+	return file_proto_model_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *ModelHealth) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *ModelHealth) GetLatencyMs() int64 {
+	if x != nil {
+		return x.LatencyMs
+	}
+	return 0
+}
+
+func (x *ModelHealth) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *ModelHealth) GetCheckedAtUnix() int64 {
+	if x != nil {
+		return x.CheckedAtUnix
+	}
+	return 0
+}
+
+// ChatRequest is a general-purpose chat completion, for summaries,
+// classification and the like. Unlike GetPlan, nothing is added to the
+// messages: no system prompt, retrieved context or tools.
+type ChatRequest struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Messages []*ChatMessage         `protobuf:"bytes,1,rep,name=messages,proto3" json:"messages,omitempty"`
+	Provider string                 `protobuf:"bytes,2,opt,name=provider,proto3" json:"provider,omitempty"` // Preferred LLM_PROVIDERS entry; empty keeps the configured order.
+	Model    string                 `protobuf:"bytes,3,opt,name=model,proto3" json:"model,omitempty"`       // Preferred model, subject to LLM_ALLOWED_MODELS.
+	// Generation parameters, clamped as for PlanRequest.
+	Temperature   *float32 `protobuf:"fixed32,4,opt,name=temperature,proto3,oneof" json:"temperature,omitempty"`
+	MaxTokens     *int32   `protobuf:"varint,5,opt,name=max_tokens,json=maxTokens,proto3,oneof" json:"max_tokens,omitempty"`
+	TopP          *float32 `protobuf:"fixed32,6,opt,name=top_p,json=topP,proto3,oneof" json:"top_p,omitempty"`
+	Stop          []string `protobuf:"bytes,7,rep,name=stop,proto3" json:"stop,omitempty"`
+	Priority      string   `protobuf:"bytes,8,opt,name=priority,proto3" json:"priority,omitempty"` // "interactive" (default) or "batch".
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ChatRequest) Reset() {
+	*x = ChatRequest{}
+	mi := &file_proto_model_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChatRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChatRequest) ProtoMessage() {}
+
+func (x *ChatRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_model_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChatRequest.ProtoReflect.Descriptor instead.
+func (*ChatRequest) Descriptor() ([]byte, []int) {
+	return file_proto_model_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *ChatRequest) GetMessages() []*ChatMessage {
+	if x != nil {
+		return x.Messages
+	}
+	return nil
+}
+
+func (x *ChatRequest) GetProvider() string {
+	if x != nil {
+		return x.Provider
+	}
+	return ""
+}
+
+func (x *ChatRequest) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *ChatRequest) GetTemperature() float32 {
+	if x != nil && x.Temperature != nil {
+		return *x.Temperature
+	}
+	return 0
+}
+
+func (x *ChatRequest) GetMaxTokens() int32 {
+	if x != nil && x.MaxTokens != nil {
+		return *x.MaxTokens
+	}
+	return 0
+}
+
+func (x *ChatRequest) GetTopP() float32 {
+	if x != nil && x.TopP != nil {
+		return *x.TopP
+	}
+	return 0
+}
+
+func (x *ChatRequest) GetStop() []string {
+	if x != nil {
+		return x.Stop
+	}
+	return nil
+}
+
+func (x *ChatRequest) GetPriority() string {
+	if x != nil {
+		return x.Priority
+	}
+	return ""
+}
+
+type ChatMessage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Role          string                 `protobuf:"bytes,1,opt,name=role,proto3" json:"role,omitempty"`       // "system", "user" or "assistant".
+	Content       string                 `protobuf:"bytes,2,opt,name=content,proto3" json:"content,omitempty"` // Plain text; ignored when parts are set.
+	Parts         []*ChatContentPart     `protobuf:"bytes,3,rep,name=parts,proto3" json:"parts,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ChatMessage) Reset() {
+	*x = ChatMessage{}
+	mi := &file_proto_model_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChatMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChatMessage) ProtoMessage() {}
+
+func (x *ChatMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_model_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChatMessage.ProtoReflect.Descriptor instead.
+func (*ChatMessage) Descriptor() ([]byte, []int) {
+	return file_proto_model_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *ChatMessage) GetRole() string {
+	if x != nil {
+		return x.Role
+	}
+	return ""
+}
+
+func (x *ChatMessage) GetContent() string {
+	if x != nil {
+		return x.Content
+	}
+	return ""
+}
+
+func (x *ChatMessage) GetParts() []*ChatContentPart {
+	if x != nil {
+		return x.Parts
+	}
+	return nil
+}
+
+type ChatContentPart struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Type          string                 `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"` // "text" or "image_url".
+	Text          string                 `protobuf:"bytes,2,opt,name=text,proto3" json:"text,omitempty"`
+	ImageUrl      string                 `protobuf:"bytes,3,opt,name=image_url,json=imageUrl,proto3" json:"image_url,omitempty"` // An https or data: URL.
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ChatContentPart) Reset() {
+	*x = ChatContentPart{}
+	mi := &file_proto_model_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChatContentPart) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChatContentPart) ProtoMessage() {}
+
+func (x *ChatContentPart) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_model_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChatContentPart.ProtoReflect.Descriptor instead.
+func (*ChatContentPart) Descriptor() ([]byte, []int) {
+	return file_proto_model_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *ChatContentPart) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *ChatContentPart) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *ChatContentPart) GetImageUrl() string {
+	if x != nil {
+		return x.ImageUrl
+	}
+	return ""
+}
+
+type ChatResponse struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Content          string                 `protobuf:"bytes,1,opt,name=content,proto3" json:"content,omitempty"`
+	Provider         string                 `protobuf:"bytes,2,opt,name=provider,proto3" json:"provider,omitempty"` // The provider that answered: the primary or a failover.
+	Model            string                 `protobuf:"bytes,3,opt,name=model,proto3" json:"model,omitempty"`
+	FinishReason     string                 `protobuf:"bytes,4,opt,name=finish_reason,json=finishReason,proto3" json:"finish_reason,omitempty"` // "stop", "length", ...
+	PromptTokens     int32                  `protobuf:"varint,5,opt,name=prompt_tokens,json=promptTokens,proto3" json:"prompt_tokens,omitempty"`
+	CompletionTokens int32                  `protobuf:"varint,6,opt,name=completion_tokens,json=completionTokens,proto3" json:"completion_tokens,omitempty"`
+	LatencyMs        int64                  `protobuf:"varint,7,opt,name=latency_ms,json=latencyMs,proto3" json:"latency_ms,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *ChatResponse) Reset() {
+	*x = ChatResponse{}
+	mi := &file_proto_model_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChatResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChatResponse) ProtoMessage() {}
+
+func (x *ChatResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_model_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChatResponse.ProtoReflect.Descriptor instead.
+func (*ChatResponse) Descriptor() ([]byte, []int) {
+	return file_proto_model_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *ChatResponse) GetContent() string {
+	if x != nil {
+		return x.Content
+	}
+	return ""
+}
+
+func (x *ChatResponse) GetProvider() string {
+	if x != nil {
+		return x.Provider
+	}
+	return ""
+}
+
+func (x *ChatResponse) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *ChatResponse) GetFinishReason() string {
+	if x != nil {
+		return x.FinishReason
+	}
+	return ""
+}
+
+func (x *ChatResponse) GetPromptTokens() int32 {
+	if x != nil {
+		return x.PromptTokens
+	}
+	return 0
+}
+
+func (x *ChatResponse) GetCompletionTokens() int32 {
+	if x != nil {
+		return x.CompletionTokens
+	}
+	return 0
+}
+
+func (x *ChatResponse) GetLatencyMs() int64 {
+	if x != nil {
+		return x.LatencyMs
+	}
+	return 0
+}
+
+var File_proto_model_proto protoreflect.FileDescriptor
+
+const file_proto_model_proto_rawDesc = "" +
+	"\n" +
+	"\x11proto/model.proto\x12\fmodelgateway\"0\n" +
+	"\bResource\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12\x10\n" +
+	"\x03uri\x18\x02 \x01(\tR\x03uri\"\xc5\x04\n" +
+	"\vPlanRequest\x12\x16\n" +
+	"\x06prompt\x18\x01 \x01(\tR\x06prompt\x124\n" +
+	"\tresources\x18\x02 \x03(\v2\x16.modelgateway.ResourceR\tresources\x126\n" +
+	"\n" +
+	"rag_filter\x18\x03 \x01(\v2\x17.modelgateway.RAGFilterR\tragFilter\x12\x18\n" +
+	"\apersona\x18\x04 \x01(\tR\apersona\x12#\n" +
+	"\rsystem_prompt\x18\x05 \x01(\tR\fsystemPrompt\x12\x14\n" +
+	"\x05model\x18\x06 \x01(\tR\x05model\x12#\n" +
+	"\rallowed_tools\x18\a \x03(\tR\fallowedTools\x12%\n" +
+	"\x0eprompt_version\x18\b \x01(\tR\rpromptVersion\x12\x1a\n" +
+	"\bprovider\x18\t \x01(\tR\bprovider\x12\x1a\n" +
+	"\bpriority\x18\n" +
+	" \x01(\tR\bpriority\x12%\n" +
+	"\vtemperature\x18\v \x01(\x02H\x00R\vtemperature\x88\x01\x01\x12\"\n" +
+	"\n" +
+	"max_tokens\x18\f \x01(\x05H\x01R\tmaxTokens\x88\x01\x01\x12\x18\n" +
+	"\x05top_p\x18\r \x01(\x02H\x02R\x04topP\x88\x01\x01\x12\x12\n" +
+	"\x04stop\x18\x0e \x03(\tR\x04stop\x125\n" +
+	"\bmessages\x18\x0f \x03(\v2\x19.modelgateway.ChatMessageR\bmessagesB\x0e\n" +
+	"\f_temperatureB\r\n" +
+	"\v_max_tokensB\b\n" +
+	"\x06_top_p\"\x81\x03\n" +
+	"\fPlanResponse\x12\x12\n" +
+	"\x04plan\x18\x01 \x01(\tR\x04plan\x12\x1d\n" +
+	"\n" +
+	"model_name\x18\x02 \x01(\tR\tmodelName\x12\x1d\n" +
+	"\n" +
+	"latency_ms\x18\x03 \x01(\x03R\tlatencyMs\x12\x1e\n" +
+	"\n" +
+	"ungrounded\x18\x04 \x01(\bR\n" +
+	"ungrounded\x12%\n" +
+	"\x0eprompt_version\x18\x05 \x01(\tR\rpromptVersion\x12\x1c\n" +
+	"\treasoning\x18\x06 \x01(\tR\treasoning\x12\x1a\n" +
+	"\bprovider\x18\a \x01(\tR\bprovider\x12#\n" +
+	"\rprompt_tokens\x18\b \x01(\x03R\fpromptTokens\x12+\n" +
+	"\x11completion_tokens\x18\t \x01(\x03R\x10completionTokens\x12\x1a\n" +
+	"\bdegraded\x18\n" +
+	" \x01(\bR\bdegraded\x120\n" +
+	"\amatches\x18\v \x03(\v2\x16.modelgateway.RAGMatchR\amatches\"S\n" +
+	"\tPlanChunk\x12\x14\n" +
+	"\x05delta\x18\x01 \x01(\tR\x05delta\x120\n" +
+	"\x05final\x18\x02 \x01(\v2\x1a.modelgateway.PlanResponseR\x05final\"\xba\x01\n" +
+	"\tRAGFilter\x12\x18\n" +
+	"\asources\x18\x01 \x03(\tR\asources\x12\x12\n" +
+	"\x04tags\x18\x02 \x03(\tR\x04tags\x12!\n" +
+	"\fdocument_ids\x18\x03 \x03(\tR\vdocumentIds\x12,\n" +
+	"\x12created_after_unix\x18\x04 \x01(\x03R\x10createdAfterUnix\x12.\n" +
+	"\x13created_before_unix\x18\x05 \x01(\x03R\x11createdBeforeUnix\"\x98\x01\n" +
+	"\x11RAGContextRequest\x12\x14\n" +
+	"\x05query\x18\x01 \x01(\tR\x05query\x12\x13\n" +
+	"\x05top_k\x18\x02 \x01(\x05R\x04topK\x12'\n" +
+	"\x0fknowledge_bases\x18\x03 \x03(\tR\x0eknowledgeBases\x12/\n" +
+	"\x06filter\x18\x04 \x01(\v2\x17.modelgateway.RAGFilterR\x06filter\"\x89\x01\n" +
+	"\bRAGMatch\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04text\x18\x02 \x01(\tR\x04text\x12\x1a\n" +
+	"\bdistance\x18\x03 \x01(\x01R\bdistance\x12%\n" +
+	"\x0eknowledge_base\x18\x04 \x01(\tR\rknowledgeBase\x12\x16\n" +
+	"\x06source\x18\x05 \x01(\tR\x06source\"F\n" +
+	"\x12RAGContextResponse\x120\n" +
+	"\amatches\x18\x01 \x03(\v2\x16.modelgateway.RAGMatchR\amatches\"\xf1\x01\n" +
+	"\vToolRequest\x12\x1b\n" +
+	"\ttool_name\x18\x01 \x01(\tR\btoolName\x12\x1b\n" +
+	"\targs_json\x18\x02 \x01(\tR\bargsJson\x123\n" +
+	"\x15execution_environment\x18\x03 \x01(\tR\x14executionEnvironment\x12\"\n" +
+	"\rcpu_limit_mhz\x18\x04 \x01(\x05R\vcpuLimitMhz\x12&\n" +
+	"\x0fmemory_limit_mb\x18\x05 \x01(\x05R\rmemoryLimitMb\x12'\n" +
+	"\x0ftimeout_seconds\x18\x06 \x01(\x05R\x0etimeoutSeconds\"V\n" +
 	"\fToolResponse\x12\x16\n" +
 	"\x06status\x18\x01 \x01(\tR\x06status\x12\x16\n" +
 	"\x06stdout\x18\x02 \x01(\tR\x06stdout\x12\x16\n" +
-	"\x06stderr\x18\x03 \x01(\tR\x06stderr2\xa4\x01\n" +
+	"\x06stderr\x18\x03 \x01(\tR\x06stderr\"\x12\n" +
+	"\x10ListToolsRequest\"G\n" +
+	"\x11ListToolsResponse\x122\n" +
+	"\x05tools\x18\x01 \x03(\v2\x1c.modelgateway.ToolDefinitionR\x05tools\"\xf0\x01\n" +
+	"\x0eToolDefinition\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12 \n" +
+	"\vdescription\x18\x02 \x01(\tR\vdescription\x12L\n" +
+	"\n" +
+	"parameters\x18\x03 \x03(\v2,.modelgateway.ToolDefinition.ParametersEntryR\n" +
+	"parameters\x1aZ\n" +
+	"\x0fParametersEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x121\n" +
+	"\x05value\x18\x02 \x01(\v2\x1b.modelgateway.ToolParameterR\x05value:\x028\x01\"E\n" +
+	"\rToolParameter\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12 \n" +
+	"\vdescription\x18\x02 \x01(\tR\vdescription\"W\n" +
+	"\rRerankRequest\x12\x14\n" +
+	"\x05query\x18\x01 \x01(\tR\x05query\x12\x1a\n" +
+	"\bpassages\x18\x02 \x03(\tR\bpassages\x12\x14\n" +
+	"\x05model\x18\x03 \x01(\tR\x05model\"(\n" +
+	"\x0eRerankResponse\x12\x16\n" +
+	"\x06scores\x18\x01 \x03(\x01R\x06scores\"[\n" +
+	"\x0fEvaluateRequest\x12\x16\n" +
+	"\x06prompt\x18\x01 \x01(\tR\x06prompt\x12\x16\n" +
+	"\x06answer\x18\x02 \x01(\tR\x06answer\x12\x18\n" +
+	"\acontext\x18\x03 \x03(\tR\acontext\"\xa7\x01\n" +
+	"\x10EvaluateResponse\x12\x1c\n" +
+	"\trelevance\x18\x01 \x01(\x01R\trelevance\x12\"\n" +
+	"\fgroundedness\x18\x02 \x01(\x01R\fgroundedness\x12\x14\n" +
+	"\x05score\x18\x03 \x01(\x01R\x05score\x12\x1c\n" +
+	"\trationale\x18\x04 \x01(\tR\trationale\x12\x1d\n" +
+	"\n" +
+	"model_name\x18\x05 \x01(\tR\tmodelName\"\x15\n" +
+	"\x13CapabilitiesRequest\"\x89\x02\n" +
+	"\x14CapabilitiesResponse\x12\x1a\n" +
+	"\bprovider\x18\x01 \x01(\tR\bprovider\x12\x1c\n" +
+	"\tproviders\x18\x02 \x03(\tR\tproviders\x12\x12\n" +
+	"\x04mock\x18\x03 \x01(\bR\x04mock\x12\x18\n" +
+	"\aversion\x18\x04 \x01(\tR\aversion\x12\x14\n" +
+	"\x05model\x18\x05 \x01(\tR\x05model\x12'\n" +
+	"\x0fknowledge_bases\x18\x06 \x03(\tR\x0eknowledgeBases\x12'\n" +
+	"\x0ftimeout_seconds\x18\a \x01(\x05R\x0etimeoutSeconds\x12!\n" +
+	"\ftrace_header\x18\b \x01(\tR\vtraceHeader\"\x10\n" +
+	"\x0eVersionRequest\"\xb8\x01\n" +
+	"\x0fVersionResponse\x12\x18\n" +
+	"\aservice\x18\x01 \x01(\tR\aservice\x12\x18\n" +
+	"\aversion\x18\x02 \x01(\tR\aversion\x12\x17\n" +
+	"\agit_sha\x18\x03 \x01(\tR\x06gitSha\x12\x1d\n" +
+	"\n" +
+	"build_time\x18\x04 \x01(\tR\tbuildTime\x12\x1d\n" +
+	"\n" +
+	"go_version\x18\x05 \x01(\tR\tgoVersion\x12\x1a\n" +
+	"\bfeatures\x18\x06 \x03(\tR\bfeatures\"\x13\n" +
+	"\x11ListModelsRequest\"E\n" +
+	"\x12ListModelsResponse\x12/\n" +
+	"\x06models\x18\x01 \x03(\v2\x17.modelgateway.ModelInfoR\x06models\"\xec\x01\n" +
+	"\tModelInfo\x12\x1a\n" +
+	"\bprovider\x18\x01 \x01(\tR\bprovider\x12\x14\n" +
+	"\x05model\x18\x02 \x01(\tR\x05model\x12\x18\n" +
+	"\aprimary\x18\x03 \x01(\bR\aprimary\x12!\n" +
+	"\fnative_tools\x18\x04 \x01(\bR\vnativeTools\x12\x16\n" +
+	"\x06vision\x18\x05 \x01(\bR\x06vision\x12%\n" +
+	"\x0econtext_window\x18\x06 \x01(\x05R\rcontextWindow\x121\n" +
+	"\x06health\x18\a \x01(\v2\x19.modelgateway.ModelHealthR\x06health\"\x82\x01\n" +
+	"\vModelHealth\x12\x16\n" +
+	"\x06status\x18\x01 \x01(\tR\x06status\x12\x1d\n" +
+	"\n" +
+	"latency_ms\x18\x02 \x01(\x03R\tlatencyMs\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\x12&\n" +
+	"\x0fchecked_at_unix\x18\x04 \x01(\x03R\rcheckedAtUnix\"\xb4\x02\n" +
+	"\vChatRequest\x125\n" +
+	"\bmessages\x18\x01 \x03(\v2\x19.modelgateway.ChatMessageR\bmessages\x12\x1a\n" +
+	"\bprovider\x18\x02 \x01(\tR\bprovider\x12\x14\n" +
+	"\x05model\x18\x03 \x01(\tR\x05model\x12%\n" +
+	"\vtemperature\x18\x04 \x01(\x02H\x00R\vtemperature\x88\x01\x01\x12\"\n" +
+	"\n" +
+	"max_tokens\x18\x05 \x01(\x05H\x01R\tmaxTokens\x88\x01\x01\x12\x18\n" +
+	"\x05top_p\x18\x06 \x01(\x02H\x02R\x04topP\x88\x01\x01\x12\x12\n" +
+	"\x04stop\x18\a \x03(\tR\x04stop\x12\x1a\n" +
+	"\bpriority\x18\b \x01(\tR\bpriorityB\x0e\n" +
+	"\f_temperatureB\r\n" +
+	"\v_max_tokensB\b\n" +
+	"\x06_top_p\"p\n" +
+	"\vChatMessage\x12\x12\n" +
+	"\x04role\x18\x01 \x01(\tR\x04role\x12\x18\n" +
+	"\acontent\x18\x02 \x01(\tR\acontent\x123\n" +
+	"\x05parts\x18\x03 \x03(\v2\x1d.modelgateway.ChatContentPartR\x05parts\"V\n" +
+	"\x0fChatContentPart\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12\x12\n" +
+	"\x04text\x18\x02 \x01(\tR\x04text\x12\x1b\n" +
+	"\timage_url\x18\x03 \x01(\tR\bimageUrl\"\xf0\x01\n" +
+	"\fChatResponse\x12\x18\n" +
+	"\acontent\x18\x01 \x01(\tR\acontent\x12\x1a\n" +
+	"\bprovider\x18\x02 \x01(\tR\bprovider\x12\x14\n" +
+	"\x05model\x18\x03 \x01(\tR\x05model\x12#\n" +
+	"\rfinish_reason\x18\x04 \x01(\tR\ffinishReason\x12#\n" +
+	"\rprompt_tokens\x18\x05 \x01(\x05R\fpromptTokens\x12+\n" +
+	"\x11completion_tokens\x18\x06 \x01(\x05R\x10completionTokens\x12\x1d\n" +
+	"\n" +
+	"latency_ms\x18\a \x01(\x03R\tlatencyMs2\xee\x04\n" +
 	"\fModelGateway\x12@\n" +
-	"\aGetPlan\x12\x19.modelgateway.PlanRequest\x1a\x1a.modelgateway.PlanResponse\x12R\n" +
-	"\rGetRAGContext\x12\x1f.modelgateway.RAGContextRequest\x1a .modelgateway.RAGContextResponse2S\n" +
+	"\aGetPlan\x12\x19.modelgateway.PlanRequest\x1a\x1a.modelgateway.PlanResponse\x12B\n" +
+	"\n" +
+	"StreamPlan\x12\x19.modelgateway.PlanRequest\x1a\x17.modelgateway.PlanChunk0\x01\x12R\n" +
+	"\rGetRAGContext\x12\x1f.modelgateway.RAGContextRequest\x1a .modelgateway.RAGContextResponse\x12O\n" +
+	"\x0eEvaluateAnswer\x12\x1d.modelgateway.EvaluateRequest\x1a\x1e.modelgateway.EvaluateResponse\x12X\n" +
+	"\x0fGetCapabilities\x12!.modelgateway.CapabilitiesRequest\x1a\".modelgateway.CapabilitiesResponse\x12O\n" +
+	"\n" +
+	"ListModels\x12\x1f.modelgateway.ListModelsRequest\x1a .modelgateway.ListModelsResponse\x12=\n" +
+	"\x04Chat\x12\x19.modelgateway.ChatRequest\x1a\x1a.modelgateway.ChatResponse\x12I\n" +
+	"\n" +
+	"GetVersion\x12\x1c.modelgateway.VersionRequest\x1a\x1d.modelgateway.VersionResponse2\xa1\x01\n" +
 	"\vToolService\x12D\n" +
-	"\vExecuteTool\x12\x19.modelgateway.ToolRequest\x1a\x1a.modelgateway.ToolResponseB&Z$backend-go-model-gateway/proto;protob\x06proto3"
+	"\vExecuteTool\x12\x19.modelgateway.ToolRequest\x1a\x1a.modelgateway.ToolResponse\x12L\n" +
+	"\tListTools\x12\x1e.modelgateway.ListToolsRequest\x1a\x1f.modelgateway.ListToolsResponse2O\n" +
+	"\bReranker\x12C\n" +
+	"\x06Rerank\x12\x1b.modelgateway.RerankRequest\x1a\x1c.modelgateway.RerankResponseB&Z$backend-go-model-gateway/proto;protob\x06proto3"
 
 var (
 	file_proto_model_proto_rawDescOnce sync.Once
@@ -579,31 +2316,82 @@ func file_proto_model_proto_rawDescGZIP() []byte {
 	return file_proto_model_proto_rawDescData
 }
 
-var file_proto_model_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
+var file_proto_model_proto_msgTypes = make([]protoimpl.MessageInfo, 31)
 var file_proto_model_proto_goTypes = []any{
-	(*Resource)(nil),           // 0: modelgateway.Resource
-	(*PlanRequest)(nil),        // 1: modelgateway.PlanRequest
-	(*PlanResponse)(nil),       // 2: modelgateway.PlanResponse
-	(*RAGContextRequest)(nil),  // 3: modelgateway.RAGContextRequest
-	(*RAGMatch)(nil),           // 4: modelgateway.RAGMatch
-	(*RAGContextResponse)(nil), // 5: modelgateway.RAGContextResponse
-	(*ToolRequest)(nil),        // 6: modelgateway.ToolRequest
-	(*ToolResponse)(nil),       // 7: modelgateway.ToolResponse
+	(*Resource)(nil),             // 0: modelgateway.Resource
+	(*PlanRequest)(nil),          // 1: modelgateway.PlanRequest
+	(*PlanResponse)(nil),         // 2: modelgateway.PlanResponse
+	(*PlanChunk)(nil),            // 3: modelgateway.PlanChunk
+	(*RAGFilter)(nil),            // 4: modelgateway.RAGFilter
+	(*RAGContextRequest)(nil),    // 5: modelgateway.RAGContextRequest
+	(*RAGMatch)(nil),             // 6: modelgateway.RAGMatch
+	(*RAGContextResponse)(nil),   // 7: modelgateway.RAGContextResponse
+	(*ToolRequest)(nil),          // 8: modelgateway.ToolRequest
+	(*ToolResponse)(nil),         // 9: modelgateway.ToolResponse
+	(*ListToolsRequest)(nil),     // 10: modelgateway.ListToolsRequest
+	(*ListToolsResponse)(nil),    // 11: modelgateway.ListToolsResponse
+	(*ToolDefinition)(nil),       // 12: modelgateway.ToolDefinition
+	(*ToolParameter)(nil),        // 13: modelgateway.ToolParameter
+	(*RerankRequest)(nil),        // 14: modelgateway.RerankRequest
+	(*RerankResponse)(nil),       // 15: modelgateway.RerankResponse
+	(*EvaluateRequest)(nil),      // 16: modelgateway.EvaluateRequest
+	(*EvaluateResponse)(nil),     // 17: modelgateway.EvaluateResponse
+	(*CapabilitiesRequest)(nil),  // 18: modelgateway.CapabilitiesRequest
+	(*CapabilitiesResponse)(nil), // 19: modelgateway.CapabilitiesResponse
+	(*VersionRequest)(nil),       // 20: modelgateway.VersionRequest
+	(*VersionResponse)(nil),      // 21: modelgateway.VersionResponse
+	(*ListModelsRequest)(nil),    // 22: modelgateway.ListModelsRequest
+	(*ListModelsResponse)(nil),   // 23: modelgateway.ListModelsResponse
+	(*ModelInfo)(nil),            // 24: modelgateway.ModelInfo
+	(*ModelHealth)(nil),          // 25: modelgateway.ModelHealth
+	(*ChatRequest)(nil),          // 26: modelgateway.ChatRequest
+	(*ChatMessage)(nil),          // 27: modelgateway.ChatMessage
+	(*ChatContentPart)(nil),      // 28: modelgateway.ChatContentPart
+	(*ChatResponse)(nil),         // 29: modelgateway.ChatResponse
+	nil,                          // 30: modelgateway.ToolDefinition.ParametersEntry
 }
 var file_proto_model_proto_depIdxs = []int32{
-	0, // 0: modelgateway.PlanRequest.resources:type_name -> modelgateway.Resource
-	4, // 1: modelgateway.RAGContextResponse.matches:type_name -> modelgateway.RAGMatch
-	1, // 2: modelgateway.ModelGateway.GetPlan:input_type -> modelgateway.PlanRequest
-	3, // 3: modelgateway.ModelGateway.GetRAGContext:input_type -> modelgateway.RAGContextRequest
-	6, // 4: modelgateway.ToolService.ExecuteTool:input_type -> modelgateway.ToolRequest
-	2, // 5: modelgateway.ModelGateway.GetPlan:output_type -> modelgateway.PlanResponse
-	5, // 6: modelgateway.ModelGateway.GetRAGContext:output_type -> modelgateway.RAGContextResponse
-	7, // 7: modelgateway.ToolService.ExecuteTool:output_type -> modelgateway.ToolResponse
-	5, // [5:8] is the sub-list for method output_type
-	2, // [2:5] is the sub-list for method input_type
-	2, // [2:2] is the sub-list for extension type_name
-	2, // [2:2] is the sub-list for extension extendee
-	0, // [0:2] is the sub-list for field type_name
+	0,  // 0: modelgateway.PlanRequest.resources:type_name -> modelgateway.Resource
+	4,  // 1: modelgateway.PlanRequest.rag_filter:type_name -> modelgateway.RAGFilter
+	27, // 2: modelgateway.PlanRequest.messages:type_name -> modelgateway.ChatMessage
+	6,  // 3: modelgateway.PlanResponse.matches:type_name -> modelgateway.RAGMatch
+	2,  // 4: modelgateway.PlanChunk.final:type_name -> modelgateway.PlanResponse
+	4,  // 5: modelgateway.RAGContextRequest.filter:type_name -> modelgateway.RAGFilter
+	6,  // 6: modelgateway.RAGContextResponse.matches:type_name -> modelgateway.RAGMatch
+	12, // 7: modelgateway.ListToolsResponse.tools:type_name -> modelgateway.ToolDefinition
+	30, // 8: modelgateway.ToolDefinition.parameters:type_name -> modelgateway.ToolDefinition.ParametersEntry
+	24, // 9: modelgateway.ListModelsResponse.models:type_name -> modelgateway.ModelInfo
+	25, // 10: modelgateway.ModelInfo.health:type_name -> modelgateway.ModelHealth
+	27, // 11: modelgateway.ChatRequest.messages:type_name -> modelgateway.ChatMessage
+	28, // 12: modelgateway.ChatMessage.parts:type_name -> modelgateway.ChatContentPart
+	13, // 13: modelgateway.ToolDefinition.ParametersEntry.value:type_name -> modelgateway.ToolParameter
+	1,  // 14: modelgateway.ModelGateway.GetPlan:input_type -> modelgateway.PlanRequest
+	1,  // 15: modelgateway.ModelGateway.StreamPlan:input_type -> modelgateway.PlanRequest
+	5,  // 16: modelgateway.ModelGateway.GetRAGContext:input_type -> modelgateway.RAGContextRequest
+	16, // 17: modelgateway.ModelGateway.EvaluateAnswer:input_type -> modelgateway.EvaluateRequest
+	18, // 18: modelgateway.ModelGateway.GetCapabilities:input_type -> modelgateway.CapabilitiesRequest
+	22, // 19: modelgateway.ModelGateway.ListModels:input_type -> modelgateway.ListModelsRequest
+	26, // 20: modelgateway.ModelGateway.Chat:input_type -> modelgateway.ChatRequest
+	20, // 21: modelgateway.ModelGateway.GetVersion:input_type -> modelgateway.VersionRequest
+	8,  // 22: modelgateway.ToolService.ExecuteTool:input_type -> modelgateway.ToolRequest
+	10, // 23: modelgateway.ToolService.ListTools:input_type -> modelgateway.ListToolsRequest
+	14, // 24: modelgateway.Reranker.Rerank:input_type -> modelgateway.RerankRequest
+	2,  // 25: modelgateway.ModelGateway.GetPlan:output_type -> modelgateway.PlanResponse
+	3,  // 26: modelgateway.ModelGateway.StreamPlan:output_type -> modelgateway.PlanChunk
+	7,  // 27: modelgateway.ModelGateway.GetRAGContext:output_type -> modelgateway.RAGContextResponse
+	17, // 28: modelgateway.ModelGateway.EvaluateAnswer:output_type -> modelgateway.EvaluateResponse
+	19, // 29: modelgateway.ModelGateway.GetCapabilities:output_type -> modelgateway.CapabilitiesResponse
+	23, // 30: modelgateway.ModelGateway.ListModels:output_type -> modelgateway.ListModelsResponse
+	29, // 31: modelgateway.ModelGateway.Chat:output_type -> modelgateway.ChatResponse
+	21, // 32: modelgateway.ModelGateway.GetVersion:output_type -> modelgateway.VersionResponse
+	9,  // 33: modelgateway.ToolService.ExecuteTool:output_type -> modelgateway.ToolResponse
+	11, // 34: modelgateway.ToolService.ListTools:output_type -> modelgateway.ListToolsResponse
+	15, // 35: modelgateway.Reranker.Rerank:output_type -> modelgateway.RerankResponse
+	25, // [25:36] is the sub-list for method output_type
+	14, // [14:25] is the sub-list for method input_type
+	14, // [14:14] is the sub-list for extension type_name
+	14, // [14:14] is the sub-list for extension extendee
+	0,  // [0:14] is the sub-list for field type_name
 }
 
 func init() { file_proto_model_proto_init() }
@@ -611,15 +2399,17 @@ func file_proto_model_proto_init() {
 	if File_proto_model_proto != nil {
 		return
 	}
+	file_proto_model_proto_msgTypes[1].OneofWrappers = []any{}
+	file_proto_model_proto_msgTypes[26].OneofWrappers = []any{}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_model_proto_rawDesc), len(file_proto_model_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   8,
+			NumMessages:   31,
 			NumExtensions: 0,
-			NumServices:   2,
+			NumServices:   3,
 		},
 		GoTypes:           file_proto_model_proto_goTypes,
 		DependencyIndexes: file_proto_model_proto_depIdxs,
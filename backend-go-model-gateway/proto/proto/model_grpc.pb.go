@@ -19,8 +19,14 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	ModelGateway_GetPlan_FullMethodName       = "/modelgateway.ModelGateway/GetPlan"
-	ModelGateway_GetRAGContext_FullMethodName = "/modelgateway.ModelGateway/GetRAGContext"
+	ModelGateway_GetPlan_FullMethodName         = "/modelgateway.ModelGateway/GetPlan"
+	ModelGateway_StreamPlan_FullMethodName      = "/modelgateway.ModelGateway/StreamPlan"
+	ModelGateway_GetRAGContext_FullMethodName   = "/modelgateway.ModelGateway/GetRAGContext"
+	ModelGateway_EvaluateAnswer_FullMethodName  = "/modelgateway.ModelGateway/EvaluateAnswer"
+	ModelGateway_GetCapabilities_FullMethodName = "/modelgateway.ModelGateway/GetCapabilities"
+	ModelGateway_ListModels_FullMethodName      = "/modelgateway.ModelGateway/ListModels"
+	ModelGateway_Chat_FullMethodName            = "/modelgateway.ModelGateway/Chat"
+	ModelGateway_GetVersion_FullMethodName      = "/modelgateway.ModelGateway/GetVersion"
 )
 
 // ModelGatewayClient is the client API for ModelGateway service.
@@ -28,7 +34,13 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type ModelGatewayClient interface {
 	GetPlan(ctx context.Context, in *PlanRequest, opts ...grpc.CallOption) (*PlanResponse, error)
+	StreamPlan(ctx context.Context, in *PlanRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[PlanChunk], error)
 	GetRAGContext(ctx context.Context, in *RAGContextRequest, opts ...grpc.CallOption) (*RAGContextResponse, error)
+	EvaluateAnswer(ctx context.Context, in *EvaluateRequest, opts ...grpc.CallOption) (*EvaluateResponse, error)
+	GetCapabilities(ctx context.Context, in *CapabilitiesRequest, opts ...grpc.CallOption) (*CapabilitiesResponse, error)
+	ListModels(ctx context.Context, in *ListModelsRequest, opts ...grpc.CallOption) (*ListModelsResponse, error)
+	Chat(ctx context.Context, in *ChatRequest, opts ...grpc.CallOption) (*ChatResponse, error)
+	GetVersion(ctx context.Context, in *VersionRequest, opts ...grpc.CallOption) (*VersionResponse, error)
 }
 
 type modelGatewayClient struct {
@@ -49,6 +61,25 @@ func (c *modelGatewayClient) GetPlan(ctx context.Context, in *PlanRequest, opts
 	return out, nil
 }
 
+func (c *modelGatewayClient) StreamPlan(ctx context.Context, in *PlanRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[PlanChunk], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ModelGateway_ServiceDesc.Streams[0], ModelGateway_StreamPlan_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[PlanRequest, PlanChunk]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ModelGateway_StreamPlanClient = grpc.ServerStreamingClient[PlanChunk]
+
 func (c *modelGatewayClient) GetRAGContext(ctx context.Context, in *RAGContextRequest, opts ...grpc.CallOption) (*RAGContextResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(RAGContextResponse)
@@ -59,12 +90,68 @@ func (c *modelGatewayClient) GetRAGContext(ctx context.Context, in *RAGContextRe
 	return out, nil
 }
 
+func (c *modelGatewayClient) EvaluateAnswer(ctx context.Context, in *EvaluateRequest, opts ...grpc.CallOption) (*EvaluateResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(EvaluateResponse)
+	err := c.cc.Invoke(ctx, ModelGateway_EvaluateAnswer_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *modelGatewayClient) GetCapabilities(ctx context.Context, in *CapabilitiesRequest, opts ...grpc.CallOption) (*CapabilitiesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CapabilitiesResponse)
+	err := c.cc.Invoke(ctx, ModelGateway_GetCapabilities_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *modelGatewayClient) ListModels(ctx context.Context, in *ListModelsRequest, opts ...grpc.CallOption) (*ListModelsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListModelsResponse)
+	err := c.cc.Invoke(ctx, ModelGateway_ListModels_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *modelGatewayClient) Chat(ctx context.Context, in *ChatRequest, opts ...grpc.CallOption) (*ChatResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ChatResponse)
+	err := c.cc.Invoke(ctx, ModelGateway_Chat_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *modelGatewayClient) GetVersion(ctx context.Context, in *VersionRequest, opts ...grpc.CallOption) (*VersionResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(VersionResponse)
+	err := c.cc.Invoke(ctx, ModelGateway_GetVersion_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ModelGatewayServer is the server API for ModelGateway service.
 // All implementations must embed UnimplementedModelGatewayServer
 // for forward compatibility.
 type ModelGatewayServer interface {
 	GetPlan(context.Context, *PlanRequest) (*PlanResponse, error)
+	StreamPlan(*PlanRequest, grpc.ServerStreamingServer[PlanChunk]) error
 	GetRAGContext(context.Context, *RAGContextRequest) (*RAGContextResponse, error)
+	EvaluateAnswer(context.Context, *EvaluateRequest) (*EvaluateResponse, error)
+	GetCapabilities(context.Context, *CapabilitiesRequest) (*CapabilitiesResponse, error)
+	ListModels(context.Context, *ListModelsRequest) (*ListModelsResponse, error)
+	Chat(context.Context, *ChatRequest) (*ChatResponse, error)
+	GetVersion(context.Context, *VersionRequest) (*VersionResponse, error)
 	mustEmbedUnimplementedModelGatewayServer()
 }
 
@@ -78,9 +165,27 @@ type UnimplementedModelGatewayServer struct{}
 func (UnimplementedModelGatewayServer) GetPlan(context.Context, *PlanRequest) (*PlanResponse, error) {
 	return nil, status.Error(codes.Unimplemented, "method GetPlan not implemented")
 }
+func (UnimplementedModelGatewayServer) StreamPlan(*PlanRequest, grpc.ServerStreamingServer[PlanChunk]) error {
+	return status.Error(codes.Unimplemented, "method StreamPlan not implemented")
+}
 func (UnimplementedModelGatewayServer) GetRAGContext(context.Context, *RAGContextRequest) (*RAGContextResponse, error) {
 	return nil, status.Error(codes.Unimplemented, "method GetRAGContext not implemented")
 }
+func (UnimplementedModelGatewayServer) EvaluateAnswer(context.Context, *EvaluateRequest) (*EvaluateResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method EvaluateAnswer not implemented")
+}
+func (UnimplementedModelGatewayServer) GetCapabilities(context.Context, *CapabilitiesRequest) (*CapabilitiesResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetCapabilities not implemented")
+}
+func (UnimplementedModelGatewayServer) ListModels(context.Context, *ListModelsRequest) (*ListModelsResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListModels not implemented")
+}
+func (UnimplementedModelGatewayServer) Chat(context.Context, *ChatRequest) (*ChatResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Chat not implemented")
+}
+func (UnimplementedModelGatewayServer) GetVersion(context.Context, *VersionRequest) (*VersionResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetVersion not implemented")
+}
 func (UnimplementedModelGatewayServer) mustEmbedUnimplementedModelGatewayServer() {}
 func (UnimplementedModelGatewayServer) testEmbeddedByValue()                      {}
 
@@ -120,6 +225,17 @@ func _ModelGateway_GetPlan_Handler(srv interface{}, ctx context.Context, dec fun
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ModelGateway_StreamPlan_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(PlanRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ModelGatewayServer).StreamPlan(m, &grpc.GenericServerStream[PlanRequest, PlanChunk]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ModelGateway_StreamPlanServer = grpc.ServerStreamingServer[PlanChunk]
+
 func _ModelGateway_GetRAGContext_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(RAGContextRequest)
 	if err := dec(in); err != nil {
@@ -138,6 +254,96 @@ func _ModelGateway_GetRAGContext_Handler(srv interface{}, ctx context.Context, d
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ModelGateway_EvaluateAnswer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EvaluateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ModelGatewayServer).EvaluateAnswer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ModelGateway_EvaluateAnswer_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ModelGatewayServer).EvaluateAnswer(ctx, req.(*EvaluateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ModelGateway_GetCapabilities_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CapabilitiesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ModelGatewayServer).GetCapabilities(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ModelGateway_GetCapabilities_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ModelGatewayServer).GetCapabilities(ctx, req.(*CapabilitiesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ModelGateway_ListModels_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListModelsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ModelGatewayServer).ListModels(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ModelGateway_ListModels_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ModelGatewayServer).ListModels(ctx, req.(*ListModelsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ModelGateway_Chat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ChatRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ModelGatewayServer).Chat(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ModelGateway_Chat_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ModelGatewayServer).Chat(ctx, req.(*ChatRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ModelGateway_GetVersion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VersionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ModelGatewayServer).GetVersion(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ModelGateway_GetVersion_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ModelGatewayServer).GetVersion(ctx, req.(*VersionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // ModelGateway_ServiceDesc is the grpc.ServiceDesc for ModelGateway service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -153,13 +359,40 @@ var ModelGateway_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetRAGContext",
 			Handler:    _ModelGateway_GetRAGContext_Handler,
 		},
+		{
+			MethodName: "EvaluateAnswer",
+			Handler:    _ModelGateway_EvaluateAnswer_Handler,
+		},
+		{
+			MethodName: "GetCapabilities",
+			Handler:    _ModelGateway_GetCapabilities_Handler,
+		},
+		{
+			MethodName: "ListModels",
+			Handler:    _ModelGateway_ListModels_Handler,
+		},
+		{
+			MethodName: "Chat",
+			Handler:    _ModelGateway_Chat_Handler,
+		},
+		{
+			MethodName: "GetVersion",
+			Handler:    _ModelGateway_GetVersion_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamPlan",
+			Handler:       _ModelGateway_StreamPlan_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/model.proto",
 }
 
 const (
 	ToolService_ExecuteTool_FullMethodName = "/modelgateway.ToolService/ExecuteTool"
+	ToolService_ListTools_FullMethodName   = "/modelgateway.ToolService/ListTools"
 )
 
 // ToolServiceClient is the client API for ToolService service.
@@ -170,6 +403,9 @@ const (
 // over low-latency gRPC.
 type ToolServiceClient interface {
 	ExecuteTool(ctx context.Context, in *ToolRequest, opts ...grpc.CallOption) (*ToolResponse, error)
+	// ListTools returns the tools the sandbox currently implements, so the
+	// gateway and the planner offer and accept live definitions.
+	ListTools(ctx context.Context, in *ListToolsRequest, opts ...grpc.CallOption) (*ListToolsResponse, error)
 }
 
 type toolServiceClient struct {
@@ -190,6 +426,16 @@ func (c *toolServiceClient) ExecuteTool(ctx context.Context, in *ToolRequest, op
 	return out, nil
 }
 
+func (c *toolServiceClient) ListTools(ctx context.Context, in *ListToolsRequest, opts ...grpc.CallOption) (*ListToolsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListToolsResponse)
+	err := c.cc.Invoke(ctx, ToolService_ListTools_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ToolServiceServer is the server API for ToolService service.
 // All implementations must embed UnimplementedToolServiceServer
 // for forward compatibility.
@@ -198,6 +444,9 @@ func (c *toolServiceClient) ExecuteTool(ctx context.Context, in *ToolRequest, op
 // over low-latency gRPC.
 type ToolServiceServer interface {
 	ExecuteTool(context.Context, *ToolRequest) (*ToolResponse, error)
+	// ListTools returns the tools the sandbox currently implements, so the
+	// gateway and the planner offer and accept live definitions.
+	ListTools(context.Context, *ListToolsRequest) (*ListToolsResponse, error)
 	mustEmbedUnimplementedToolServiceServer()
 }
 
@@ -211,6 +460,9 @@ type UnimplementedToolServiceServer struct{}
 func (UnimplementedToolServiceServer) ExecuteTool(context.Context, *ToolRequest) (*ToolResponse, error) {
 	return nil, status.Error(codes.Unimplemented, "method ExecuteTool not implemented")
 }
+func (UnimplementedToolServiceServer) ListTools(context.Context, *ListToolsRequest) (*ListToolsResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListTools not implemented")
+}
 func (UnimplementedToolServiceServer) mustEmbedUnimplementedToolServiceServer() {}
 func (UnimplementedToolServiceServer) testEmbeddedByValue()                     {}
 
@@ -250,6 +502,24 @@ func _ToolService_ExecuteTool_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ToolService_ListTools_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListToolsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ToolServiceServer).ListTools(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ToolService_ListTools_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ToolServiceServer).ListTools(ctx, req.(*ListToolsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // ToolService_ServiceDesc is the grpc.ServiceDesc for ToolService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -261,6 +531,118 @@ var ToolService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ExecuteTool",
 			Handler:    _ToolService_ExecuteTool_Handler,
 		},
+		{
+			MethodName: "ListTools",
+			Handler:    _ToolService_ListTools_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "proto/model.proto",
+}
+
+const (
+	Reranker_Rerank_FullMethodName = "/modelgateway.Reranker/Rerank"
+)
+
+// RerankerClient is the client API for Reranker service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Reranker is implemented by an optional cross-encoder service the gateway
+// calls between retrieval and prompt assembly (RAG_RERANKER=grpc).
+type RerankerClient interface {
+	Rerank(ctx context.Context, in *RerankRequest, opts ...grpc.CallOption) (*RerankResponse, error)
+}
+
+type rerankerClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewRerankerClient(cc grpc.ClientConnInterface) RerankerClient {
+	return &rerankerClient{cc}
+}
+
+func (c *rerankerClient) Rerank(ctx context.Context, in *RerankRequest, opts ...grpc.CallOption) (*RerankResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RerankResponse)
+	err := c.cc.Invoke(ctx, Reranker_Rerank_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RerankerServer is the server API for Reranker service.
+// All implementations must embed UnimplementedRerankerServer
+// for forward compatibility.
+//
+// Reranker is implemented by an optional cross-encoder service the gateway
+// calls between retrieval and prompt assembly (RAG_RERANKER=grpc).
+type RerankerServer interface {
+	Rerank(context.Context, *RerankRequest) (*RerankResponse, error)
+	mustEmbedUnimplementedRerankerServer()
+}
+
+// UnimplementedRerankerServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedRerankerServer struct{}
+
+func (UnimplementedRerankerServer) Rerank(context.Context, *RerankRequest) (*RerankResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Rerank not implemented")
+}
+func (UnimplementedRerankerServer) mustEmbedUnimplementedRerankerServer() {}
+func (UnimplementedRerankerServer) testEmbeddedByValue()                  {}
+
+// UnsafeRerankerServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to RerankerServer will
+// result in compilation errors.
+type UnsafeRerankerServer interface {
+	mustEmbedUnimplementedRerankerServer()
+}
+
+func RegisterRerankerServer(s grpc.ServiceRegistrar, srv RerankerServer) {
+	// If the following call panics, it indicates UnimplementedRerankerServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Reranker_ServiceDesc, srv)
+}
+
+func _Reranker_Rerank_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RerankRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RerankerServer).Rerank(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Reranker_Rerank_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RerankerServer).Rerank(ctx, req.(*RerankRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Reranker_ServiceDesc is the grpc.ServiceDesc for Reranker service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Reranker_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "modelgateway.Reranker",
+	HandlerType: (*RerankerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Rerank",
+			Handler:    _Reranker_Rerank_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/model.proto",
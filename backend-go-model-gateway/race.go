@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"backend-go-model-gateway/internal/logger"
+	pb "backend-go-model-gateway/proto/proto"
+)
+
+// invalidPlanError carries a racer's plan that still failed its schema after
+// repair. It only wins the race when the other provider has nothing better.
+type invalidPlanError struct {
+	resp *pb.PlanResponse
+}
+
+func (e *invalidPlanError) Error() string {
+	return "plan failed its schema after repair"
+}
+
+// racePlan asks a and b for the plan at the same time (LLM_RACE) and returns
+// the first valid one, cancelling the other call. served is the provider
+// whose plan is returned. When both fail, the error is a's and served is a,
+// so failover and the fallbacks treat the pair like the primary failing.
+// Neither racer streams deltas: StreamPlan callers get the final chunk.
+func (s *server) racePlan(ctx context.Context, a, b *llmRuntime, attempt planAttempt) (resp *pb.PlanResponse, served *llmRuntime, err error) {
+	lg := logger.NewContextLogger(ctx)
+	raceCtx, cancel := context.WithCancel(contextWithPlanDeltas(ctx, nil))
+	defer cancel()
+	attempt.racing = true
+	start := time.Now()
+
+	type result struct {
+		llm  *llmRuntime
+		resp *pb.PlanResponse
+		err  error
+	}
+	results := make(chan result, 2)
+	for i, llm := range []*llmRuntime{a, b} {
+		go func() {
+			resp, err := s.planWith(raceCtx, llm, attempt, i == 0)
+			results <- result{llm: llm, resp: resp, err: err}
+		}()
+	}
+
+	var invalid *result
+	var errA error
+	for range 2 {
+		r := <-results
+		other := b
+		if r.llm == b {
+			other = a
+		}
+		var invalidErr *invalidPlanError
+		switch {
+		case r.err == nil:
+			lg.Info("llm_race_won", "provider", r.llm.Provider, "model", r.resp.GetModelName(), "other", other.Provider, "latency_ms", time.Since(start).Milliseconds())
+			return r.resp, r.llm, nil
+		case errors.As(r.err, &invalidErr):
+			lg.Warn("llm_race_invalid_plan", "provider", r.llm.Provider, "other", other.Provider)
+			if invalid == nil {
+				invalid = &result{llm: r.llm, resp: invalidErr.resp}
+			}
+		default:
+			lg.Warn("llm_race_failed", "provider", r.llm.Provider, "error", r.err)
+			if r.llm == a {
+				errA = r.err
+			}
+		}
+	}
+	if invalid != nil {
+		lg.Info("llm_race_won", "provider", invalid.llm.Provider, "model", invalid.resp.GetModelName(), "invalid_plan", true, "latency_ms", time.Since(start).Milliseconds())
+		return invalid.resp, invalid.llm, nil
+	}
+	return nil, a, errA
+}
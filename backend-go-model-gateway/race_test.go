@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pb "backend-go-model-gateway/proto/proto"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// racer is a provider answering reply after delay; cancelled counts the
+// calls given up on by the gateway.
+func racer(t *testing.T, name string, delay time.Duration, status int, reply string, cancelled *atomic.Int32) *llmRuntime {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The server only notices a dropped connection once the body is read.
+		_, _ = io.Copy(io.Discard, r.Body)
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			if cancelled != nil {
+				cancelled.Add(1)
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte(`{"error":{"message":"upstream unavailable","type":"server_error"}}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(openai.ChatCompletionResponse{
+			Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Role: "assistant", Content: reply}}},
+		})
+	}))
+	t.Cleanup(srv.Close)
+	cfg := openai.DefaultConfig("")
+	cfg.BaseURL = srv.URL
+	return &llmRuntime{Provider: llmProvider(name), Model: name + "-model", Client: openai.NewClientWithConfig(cfg), ToolCalling: toolCallingJSON}
+}
+
+func raceServer(chain ...*llmRuntime) *server {
+	chain[0].Fallbacks = chain[1:]
+	return &server{llm: chain[0], race: true, requestTimeout: 5 * time.Second}
+}
+
+func TestGetPlan_RaceFasterProviderWins(t *testing.T) {
+	var cancelled atomic.Int32
+	s := raceServer(
+		racer(t, "ollama", 2*time.Second, http.StatusOK, `{"steps":["from ollama"]}`, &cancelled),
+		racer(t, "openrouter", 0, http.StatusOK, `{"steps":["from openrouter"]}`, nil),
+	)
+	start := time.Now()
+	resp, err := s.GetPlan(context.Background(), &pb.PlanRequest{Prompt: "race"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.GetProvider() != "openrouter" || !strings.Contains(resp.GetPlan(), "from openrouter") || time.Since(start) > time.Second {
+		t.Fatalf("resp = %v after %v", resp, time.Since(start))
+	}
+	// The slower call is cancelled rather than left running.
+	deadline := time.Now().Add(time.Second)
+	for cancelled.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if cancelled.Load() != 1 {
+		t.Fatal("losing provider call was not cancelled")
+	}
+}
+
+func TestGetPlan_RaceSkipsFailuresAndInvalidPlans(t *testing.T) {
+	// A failure does not end the race.
+	s := raceServer(
+		racer(t, "ollama", 0, http.StatusServiceUnavailable, "", nil),
+		racer(t, "openrouter", 50*time.Millisecond, http.StatusOK, `{"steps":["from openrouter"]}`, nil),
+	)
+	if resp, err := s.GetPlan(context.Background(), &pb.PlanRequest{Prompt: "race"}); err != nil || resp.GetProvider() != "openrouter" {
+		t.Fatalf("after a failed racer: %v, %v", resp, err)
+	}
+
+	// Nor does a reply that fails the plan schema.
+	s = raceServer(
+		racer(t, "ollama", 0, http.StatusOK, `not a plan`, nil),
+		racer(t, "openrouter", 50*time.Millisecond, http.StatusOK, `{"steps":["from openrouter"]}`, nil),
+	)
+	if resp, err := s.GetPlan(context.Background(), &pb.PlanRequest{Prompt: "race"}); err != nil || resp.GetProvider() != "openrouter" {
+		t.Fatalf("after an invalid plan: %v, %v", resp, err)
+	}
+
+	// Unless the other one has nothing better.
+	s = raceServer(
+		racer(t, "ollama", 0, http.StatusOK, `not a plan`, nil),
+		racer(t, "openrouter", 0, http.StatusServiceUnavailable, "", nil),
+	)
+	if resp, err := s.GetPlan(context.Background(), &pb.PlanRequest{Prompt: "race"}); err != nil || resp.GetProvider() != "ollama" {
+		t.Fatalf("only an invalid plan: %v, %v", resp, err)
+	}
+}
+
+func TestGetPlan_RaceThenFailover(t *testing.T) {
+	s := raceServer(
+		racer(t, "ollama", 0, http.StatusServiceUnavailable, "", nil),
+		racer(t, "openrouter", 0, http.StatusServiceUnavailable, "", nil),
+		racer(t, "anthropic", 0, http.StatusOK, `{"steps":["from the third"]}`, nil),
+	)
+	// Anthropic's client is OpenAI-shaped here; only the order matters.
+	resp, err := s.GetPlan(context.Background(), &pb.PlanRequest{Prompt: "race"})
+	if err != nil || resp.GetProvider() != "anthropic" {
+		t.Fatalf("failover after the race: %v, %v", resp, err)
+	}
+}
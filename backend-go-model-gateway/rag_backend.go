@@ -0,0 +1,354 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"backend-go-model-gateway/pkg/secrets"
+)
+
+// defaultRAGKnowledgeBase is queried when a request names no KBs, matching the
+// Python memory service.
+const defaultRAGKnowledgeBase = "Body-KB"
+
+const (
+	ragBackendMemory   = "memory"
+	ragBackendQdrant   = "qdrant"
+	ragBackendPGVector = "pgvector"
+	ragBackendWeaviate = "weaviate"
+	ragBackendMilvus   = "milvus"
+	ragBackendEmbedded = "embedded"
+)
+
+// ragBackend is the retrieval backend selected by RAG_BACKEND.
+type ragBackend struct {
+	name   string
+	client RAGContextClient
+	// ingester is the unwrapped backend when it accepts writes, and embedder
+	// the model its documents are embedded with (nil when the backend
+	// vectorizes itself).
+	ingester ragIngester
+	embedder Embedder
+	// kbAdmin is the unwrapped backend when it can manage KB storage.
+	kbAdmin ragKBAdmin
+	// retriever is the unwrapped backend, lexical the same backend when it
+	// supports keyword search, mode the configured RAG_RETRIEVAL_MODE and
+	// rerank the reranking stage (nil when off). The retrieval debug endpoint
+	// runs these stages one by one.
+	retriever RAGContextClient
+	lexical   lexicalSearcher
+	mode      string
+	rerank    *rerankingRAGClient
+	// cfg is the configuration the stages were built from.
+	cfg RAGConfig
+	// languages tags KBs with their documents' language; multilingual is nil
+	// when RAG_MULTILINGUAL is off.
+	languages    kbLanguages
+	multilingual *multilingualRAGClient
+	// cache is nil when RAG_CACHE_SIZE=0; writes invalidate it.
+	cache   *cachingRAGClient
+	tenancy string
+	// memory is set only for the memory-service backend; the gRPC health
+	// server probes its connection.
+	memory *RAGGRPCClient
+	close  func()
+}
+
+func (b *ragBackend) Close() {
+	if b != nil && b.close != nil {
+		b.close()
+	}
+}
+
+// initRAGBackend selects the retrieval backend from cfg.RAG.Backend
+// (RAG_BACKEND):
+//
+//   - memory (default): the Python memory service over gRPC (RAG_GRPC_ADDR)
+//   - qdrant: Qdrant directly, embedding queries in the gateway
+//   - pgvector: Postgres + pgvector, embedding queries in the gateway
+//   - weaviate: Weaviate's GraphQL API, optionally with hybrid search
+//   - milvus: Milvus over its RESTful API, embedding queries in the gateway
+//   - embedded: an in-process store over a JSONL corpus, for dependency-free demos
+//
+// A misconfigured direct backend is an error. An unreachable memory service is
+// not: in bare-metal dev mode it may not be ready when the gateway starts, so
+// the gateway falls back to a no-op client and still becomes healthy.
+//
+// RAG_RETRIEVAL_MODE=hybrid then fuses keyword and vector rankings for backends
+// that support keyword search (see hybridRAGClient), RAG_RERANKER reorders
+// candidates with a reranker (see rerankingRAGClient), RAG_MULTILINGUAL=on
+// routes queries by language (see multilingualRAGClient), results are cached
+// briefly (see cachingRAGClient), and RAG_TENANCY=required scopes every
+// request to the caller's tenant (see tenantRAGClient).
+func initRAGBackend(ctx context.Context, store *secrets.Store, cfg *Config) (*ragBackend, error) {
+	name := strings.ToLower(strings.TrimSpace(cfg.RAG.Backend))
+	tenancy, err := ragTenancy(cfg.RAG)
+	if err != nil {
+		return nil, err
+	}
+	// The memory service has no namespace dimension, so it cannot enforce
+	// isolation; refuse to start rather than serve unscoped results.
+	if tenancy == ragTenancyRequired && (name == ragBackendMemory || name == "") {
+		return nil, fmt.Errorf("RAG_TENANCY=required is not supported by RAG_BACKEND=memory; use a direct backend")
+	}
+	b, err := newRAGBackend(ctx, store, cfg, name)
+	if err != nil {
+		return nil, err
+	}
+	b.cfg = cfg.RAG
+	b.ingester, _ = b.client.(ragIngester)
+	b.kbAdmin, _ = b.client.(ragKBAdmin)
+	b.lexical, _ = b.client.(lexicalSearcher)
+	b.retriever = b.client
+	b.tenancy = tenancy
+
+	switch b.mode = strings.ToLower(cfg.RAG.RetrievalMode); b.mode {
+	case "vector":
+	case "hybrid":
+		if b.lexical == nil {
+			log.Printf(
+				`{"timestamp":"%s","level":"warn","service":"%s","component":"RAGBackend","rag_backend":%q,"message":"RAG_RETRIEVAL_MODE=hybrid is not supported by this backend; using vector retrieval"}`,
+				time.Now().Format(time.RFC3339Nano), SERVICE_NAME, b.name,
+			)
+			b.mode = "vector"
+			break
+		}
+		b.client = newHybridRAGClient(cfg.RAG, b.client, b.lexical)
+	default:
+		b.Close()
+		return nil, fmt.Errorf("unsupported RAG_RETRIEVAL_MODE=%q (supported: vector, hybrid)", b.mode)
+	}
+
+	rerank, err := newRerankingRAGClient(ctx, store, cfg.RAG.Rerank, cfg.LLM, b.client)
+	if err != nil {
+		b.Close()
+		return nil, err
+	}
+	if rerank != nil {
+		b.client = rerank
+		b.rerank = rerank
+		closeBackend := b.close
+		b.close = func() {
+			rerank.Close()
+			if closeBackend != nil {
+				closeBackend()
+			}
+		}
+	}
+
+	if b.languages, err = kbLanguagesFromConfig(cfg.RAG); err != nil {
+		b.Close()
+		return nil, err
+	}
+	if b.multilingual, err = newMultilingualRAGClient(ctx, store, cfg.RAG, cfg.LLM, b.client, b.languages); err != nil {
+		b.Close()
+		return nil, err
+	}
+	if b.multilingual != nil {
+		b.client = b.multilingual
+	}
+	// Per-language models only work when queries are routed by language, and
+	// the embedded store embeds its corpus with the default model at load.
+	if _, ok := b.embedder.(*languageEmbedder); ok && (b.multilingual == nil || b.name == ragBackendEmbedded) {
+		b.Close()
+		return nil, fmt.Errorf("EMBEDDINGS_MODELS needs RAG_MULTILINGUAL=on and a backend other than embedded")
+	}
+
+	if b.cache = newCachingRAGClient(cfg.RAG.Cache, b.client, b.embedder); b.cache != nil {
+		b.client = b.cache
+	}
+
+	if tenancy == ragTenancyRequired {
+		b.client = tenantRAGClient{next: b.client}
+		log.Printf(
+			`{"timestamp":"%s","level":"info","service":"%s","component":"RAGBackend","rag_backend":%q,"namespace_field":%q,"message":"RAG tenancy enforced; requests are scoped to the caller's tenant"}`,
+			time.Now().Format(time.RFC3339Nano), SERVICE_NAME, b.name, cfg.RAG.NamespaceField,
+		)
+	}
+	return b, nil
+}
+
+func newRAGBackend(ctx context.Context, store *secrets.Store, cfg *Config, name string) (*ragBackend, error) {
+	switch name {
+	case ragBackendQdrant:
+		embedder, err := newEmbedder(ctx, store, cfg.Embeddings, cfg.LLM)
+		if err != nil {
+			return nil, err
+		}
+		qc, err := NewQdrantRAGClient(cfg.RAG, store, embedder)
+		if err != nil {
+			return nil, err
+		}
+		return &ragBackend{name: name, client: qc, embedder: embedder}, nil
+
+	case ragBackendPGVector:
+		embedder, err := newEmbedder(ctx, store, cfg.Embeddings, cfg.LLM)
+		if err != nil {
+			return nil, err
+		}
+		pc, err := NewPGVectorRAGClient(ctx, cfg.RAG, store, embedder)
+		if err != nil {
+			return nil, err
+		}
+		return &ragBackend{name: name, client: pc, embedder: embedder, close: pc.Close}, nil
+
+	case ragBackendWeaviate:
+		var embedder Embedder
+		// Skip the embedder when Weaviate vectorizes queries itself.
+		if !strings.EqualFold(cfg.RAG.Weaviate.Vectorizer, "weaviate") {
+			var err error
+			if embedder, err = newEmbedder(ctx, store, cfg.Embeddings, cfg.LLM); err != nil {
+				return nil, err
+			}
+		}
+		wc, err := NewWeaviateRAGClient(cfg.RAG, store, embedder)
+		if err != nil {
+			return nil, err
+		}
+		return &ragBackend{name: name, client: wc, embedder: embedder}, nil
+
+	case ragBackendMilvus:
+		embedder, err := newEmbedder(ctx, store, cfg.Embeddings, cfg.LLM)
+		if err != nil {
+			return nil, err
+		}
+		mc, err := NewMilvusRAGClient(cfg.RAG, store, embedder)
+		if err != nil {
+			return nil, err
+		}
+		return &ragBackend{name: name, client: mc, embedder: embedder}, nil
+
+	case ragBackendEmbedded:
+		ec, err := NewEmbeddedRAGClient(ctx, store, cfg)
+		if err != nil {
+			return nil, err
+		}
+		return &ragBackend{name: name, client: ec, embedder: ec.embedder}, nil
+
+	case ragBackendMemory, "":
+		dialCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+		defer cancel()
+		rc, err := NewRAGGRPCClient(dialCtx, cfg.RAG.GRPCAddr)
+		if err != nil {
+			log.Printf(
+				`{"timestamp":"%s","level":"warn","service":"%s","component":"RAGGRPCClient","error":%q,"message":"failed to connect to memory service for RAG; starting with noop RAG client"}`,
+				time.Now().Format(time.RFC3339Nano), SERVICE_NAME, err.Error(),
+			)
+			return &ragBackend{name: ragBackendMemory, client: noopRAGClient{}}, nil
+		}
+		return &ragBackend{name: ragBackendMemory, client: rc, memory: rc, close: func() { _ = rc.Close() }}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported RAG_BACKEND=%q (supported: memory, qdrant, pgvector, weaviate, milvus, embedded)", name)
+	}
+}
+
+// ragMetadataFields names the document metadata fields (payload keys,
+// properties or columns) that metadata filters apply to in the direct
+// backends. The source field stays per backend (QDRANT_SOURCE_FIELD, ...).
+type ragMetadataFields struct {
+	tags       string
+	documentID string
+	createdAt  string
+	namespace  string
+}
+
+// ragMetadataFieldsFromConfig takes RAG_TAGS_FIELD, RAG_DOCUMENT_ID_FIELD,
+// RAG_CREATED_AT_FIELD and RAG_NAMESPACE_FIELD (default: tags / document_id /
+// created_at / namespace). created_at holds unix seconds, except in pgvector
+// where it is a timestamptz column; the embedded store uses fixed JSONL keys
+// instead.
+func ragMetadataFieldsFromConfig(cfg RAGConfig) ragMetadataFields {
+	return ragMetadataFields{
+		tags:       cfg.TagsField,
+		documentID: cfg.DocumentIDField,
+		createdAt:  cfg.CreatedAtField,
+		namespace:  cfg.NamespaceField,
+	}
+}
+
+// parseKBMapping parses an explicit "KB=name,KB=name" mapping, the value m
+// of setting key. what names the mapped thing in error messages (collection,
+// Class, ...).
+func parseKBMapping(key, m, what string) (map[string]string, error) {
+	mapping := map[string]string{}
+	if m == "" {
+		return mapping, nil
+	}
+	for _, pair := range strings.Split(m, ",") {
+		kb, name, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || kb == "" || name == "" {
+			return nil, fmt.Errorf("%s: invalid entry %q (want KB=%s)", key, pair, what)
+		}
+		mapping[kb] = name
+	}
+	return mapping, nil
+}
+
+// searchKBs runs search for every KB concurrently and concatenates the results
+// in request order (the memory service's layout). A KB whose search fails is
+// logged and skipped; only when every KB fails is an error returned.
+func searchKBs(ctx context.Context, component string, kbs []string, search func(ctx context.Context, kb string) ([]VectorQueryMatch, error)) ([]VectorQueryMatch, error) {
+	perKB := make([][]VectorQueryMatch, len(kbs))
+	errs := make([]error, len(kbs))
+	var wg sync.WaitGroup
+	for i, kb := range kbs {
+		wg.Add(1)
+		go func(i int, kb string) {
+			defer wg.Done()
+			perKB[i], errs[i] = search(ctx, kb)
+		}(i, kb)
+	}
+	wg.Wait()
+
+	var matches []VectorQueryMatch
+	failed := 0
+	for i, kb := range kbs {
+		if errs[i] != nil {
+			failed++
+			log.Printf(
+				`{"timestamp":"%s","level":"warn","service":"%s","component":%q,"knowledge_base":%q,"error":%q}`,
+				time.Now().Format(time.RFC3339Nano), SERVICE_NAME, component, kb, errs[i].Error(),
+			)
+			continue
+		}
+		matches = append(matches, perKB[i]...)
+	}
+	if failed > 0 && failed == len(kbs) {
+		return nil, fmt.Errorf("all %d knowledge base searches failed: %w", failed, errs[0])
+	}
+	if matches == nil {
+		matches = []VectorQueryMatch{}
+	}
+	return matches, nil
+}
+
+// jsonID renders a record id that a backend returns as either a JSON number
+// (Qdrant point ids, Milvus INT64 keys) or a string (UUIDs, VARCHAR keys).
+func jsonID(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	return strings.TrimSpace(string(raw))
+}
+
+// httpStatusError is a non-2xx response from a backend's HTTP API.
+type httpStatusError struct {
+	code int
+	body string
+}
+
+func (e *httpStatusError) Error() string { return fmt.Sprintf("HTTP %d: %s", e.code, e.body) }
+
+// isHTTPStatus reports whether err is an HTTP response with the given status.
+func isHTTPStatus(err error, code int) bool {
+	var se *httpStatusError
+	return errors.As(err, &se) && se.code == code
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// cachingRAGClient caches retrieval results for a short time. The agent loop
+// re-asks near-identical questions within a session ("what is my run
+// schedule?" then "what's my running schedule"), so a lookup matches not only
+// the same query text but any cached query whose embedding is at least
+// similarity-close, within the same scope (KB set, top_k, namespace, filter).
+//
+// It sits inside tenantRAGClient so the namespace is part of the scope, and
+// ingestion and KB deletion invalidate the KBs they touch. Other gateway
+// replicas see writes only once their entries expire.
+type cachingRAGClient struct {
+	next RAGContextClient
+	// embedder detects near-duplicate queries; with nil, only the same
+	// normalized query text hits.
+	embedder   Embedder
+	ttl        time.Duration
+	size       int
+	similarity float64
+	now        func() time.Time
+
+	mu sync.Mutex
+	// entries is in insertion order; the oldest is evicted first.
+	entries []*ragCacheEntry
+}
+
+type ragCacheEntry struct {
+	scope   string
+	kbs     []string
+	query   string
+	vector  []float32
+	norm    float64
+	matches []VectorQueryMatch
+	expires time.Time
+}
+
+// newCachingRAGClient wraps next, or returns nil when caching is off.
+// embedder should be the backend's own (cached) query embedder so detecting
+// near-duplicates costs no extra embeddings call.
+//
+//   - RAG_CACHE_SIZE (default: 512) — cached results; 0 disables the cache
+//   - RAG_CACHE_TTL_SECONDS (default: 30)
+//   - RAG_CACHE_SIMILARITY (default: 0.97) — cosine similarity at which two
+//     queries count as the same; 1 requires the same text
+func newCachingRAGClient(cfg RAGCacheConfig, next RAGContextClient, embedder Embedder) *cachingRAGClient {
+	if cfg.Size <= 0 {
+		return nil
+	}
+	return &cachingRAGClient{
+		next:       next,
+		embedder:   embedder,
+		ttl:        time.Duration(cfg.TTLSeconds) * time.Second,
+		size:       cfg.Size,
+		similarity: cfg.Similarity,
+		now:        time.Now,
+	}
+}
+
+func (c *cachingRAGClient) GetContext(ctx context.Context, req VectorQueryRequest) ([]VectorQueryMatch, error) {
+	if req.TopK <= 0 {
+		req.TopK = 2
+	}
+	if len(req.KnowledgeBases) == 0 {
+		req.KnowledgeBases = []string{defaultRAGKnowledgeBase}
+	}
+	scope := ragCacheScope(req)
+	query := normalizeCacheQuery(req.QueryText)
+
+	var vec []float32
+	if c.embedder != nil && c.similarity < 1 {
+		// Best-effort: without a vector the lookup falls back to exact text.
+		vec, _ = c.embedder.Embed(ctx, req.QueryText)
+	}
+	norm := vectorNorm(vec)
+
+	if matches, similarity, ok := c.lookup(scope, query, vec, norm); ok {
+		log.Printf(
+			`{"timestamp":"%s","level":"info","service":"%s","component":"RAGCache","method":"GetContext","cache":"hit","similarity":%.4f,"query_text":%q,"match_count":%d}`,
+			time.Now().Format(time.RFC3339Nano), SERVICE_NAME, similarity, req.QueryText, len(matches),
+		)
+		return matches, nil
+	}
+
+	matches, err := c.next.GetContext(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	c.store(&ragCacheEntry{
+		scope:   scope,
+		kbs:     slices.Clone(req.KnowledgeBases),
+		query:   query,
+		vector:  vec,
+		norm:    norm,
+		matches: slices.Clone(matches),
+		expires: c.now().Add(c.ttl),
+	})
+	return matches, nil
+}
+
+// lookup returns a copy of the best live entry in scope: the same query text,
+// else the most similar query at or above c.similarity.
+func (c *cachingRAGClient) lookup(scope, query string, vec []float32, norm float64) ([]VectorQueryMatch, float64, bool) {
+	now := c.now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var best *ragCacheEntry
+	bestSim := 0.0
+	for _, e := range c.entries {
+		if e.scope != scope || !now.Before(e.expires) {
+			continue
+		}
+		if e.query == query {
+			best, bestSim = e, 1
+			break
+		}
+		if vec == nil || len(e.vector) != len(vec) {
+			continue
+		}
+		if sim := cosineSimilarity(vec, e.vector, norm, e.norm); sim >= c.similarity && sim > bestSim {
+			best, bestSim = e, sim
+		}
+	}
+	if best == nil {
+		return nil, 0, false
+	}
+	return slices.Clone(best.matches), bestSim, true
+}
+
+func (c *cachingRAGClient) store(e *ragCacheEntry) {
+	now := c.now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = slices.DeleteFunc(c.entries, func(old *ragCacheEntry) bool {
+		return !now.Before(old.expires) || (old.scope == e.scope && old.query == e.query)
+	})
+	if len(c.entries) >= c.size {
+		c.entries = slices.Delete(c.entries, 0, len(c.entries)-c.size+1)
+	}
+	c.entries = append(c.entries, e)
+}
+
+// Invalidate drops every cached result that includes kb, so documents written
+// through this gateway are visible to the next retrieval. It is nil-safe.
+func (c *cachingRAGClient) Invalidate(kb string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = slices.DeleteFunc(c.entries, func(e *ragCacheEntry) bool { return slices.Contains(e.kbs, kb) })
+}
+
+// ragCacheScope identifies the requests whose results are interchangeable
+// apart from the query text. KB order is kept: it orders the results.
+func ragCacheScope(req VectorQueryRequest) string {
+	filter, _ := json.Marshal(req.Filter)
+	return strings.Join(req.KnowledgeBases, ",") + "|" + strconv.Itoa(req.TopK) + "|" + req.Namespace + "|" + string(filter)
+}
+
+// normalizeCacheQuery folds case and whitespace so trivially different texts
+// count as the same query.
+func normalizeCacheQuery(q string) string {
+	return strings.Join(strings.Fields(strings.ToLower(q)), " ")
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"backend-go-model-gateway/pkg/ragfilter"
+)
+
+type countingRAG struct{ calls int }
+
+func (r *countingRAG) GetContext(_ context.Context, req VectorQueryRequest) ([]VectorQueryMatch, error) {
+	r.calls++
+	return []VectorQueryMatch{{ID: req.QueryText, KnowledgeBase: req.KnowledgeBases[0]}}, nil
+}
+
+func TestCachingRAGClient_HitsNearDuplicateQueries(t *testing.T) {
+	t.Setenv("RAG_CACHE_SIMILARITY", "0.9")
+	next := &countingRAG{}
+	c := newCachingRAGClient(envConfig(t).RAG.Cache, next, hashEmbedder{dims: 256})
+	now := time.Unix(1000, 0)
+	c.now = func() time.Time { return now }
+	ctx := context.Background()
+	get := func(q string, kbs ...string) []VectorQueryMatch {
+		t.Helper()
+		m, err := c.GetContext(ctx, VectorQueryRequest{QueryText: q, TopK: 2, KnowledgeBases: kbs})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+
+	get("what is my weekly run schedule", "Body-KB")
+	get("  What is my weekly run schedule ", "Body-KB")
+	if m := get("what is my weekly run schedule please", "Body-KB"); next.calls != 1 || m[0].ID != "what is my weekly run schedule" {
+		t.Fatalf("near-duplicates: %d backend calls (want 1), got %+v", next.calls, m)
+	}
+
+	// Different scope, unrelated query: misses.
+	get("what is my weekly run schedule", "Domain-KB")
+	get("summarize my quarterly finances", "Body-KB")
+	if next.calls != 3 {
+		t.Fatalf("got %d backend calls, want 3", next.calls)
+	}
+	if _, err := c.GetContext(ctx, VectorQueryRequest{QueryText: "what is my weekly run schedule", TopK: 2, KnowledgeBases: []string{"Body-KB"}, Filter: &ragfilter.Filter{Tags: []string{"health"}}}); err != nil || next.calls != 4 {
+		t.Fatalf("a filtered request must not reuse unfiltered results (calls=%d, err=%v)", next.calls, err)
+	}
+
+	c.Invalidate("Body-KB")
+	get("what is my weekly run schedule", "Body-KB")
+	get("what is my weekly run schedule", "Domain-KB")
+	if next.calls != 5 {
+		t.Fatalf("after invalidating Body-KB: %d backend calls, want 5", next.calls)
+	}
+
+	now = now.Add(c.ttl)
+	get("what is my weekly run schedule", "Domain-KB")
+	if next.calls != 6 {
+		t.Fatalf("after the TTL: %d backend calls, want 6", next.calls)
+	}
+}
+
+func TestCachingRAGClient_EvictsOldest(t *testing.T) {
+	t.Setenv("RAG_CACHE_SIZE", "2")
+	t.Setenv("RAG_CACHE_SIMILARITY", "1")
+	next := &countingRAG{}
+	c := newCachingRAGClient(envConfig(t).RAG.Cache, next, nil)
+	for _, q := range []string{"a", "b", "c", "b", "a"} {
+		if _, err := c.GetContext(context.Background(), VectorQueryRequest{QueryText: q}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// "b" was still cached; "a" had been evicted by "c".
+	if next.calls != 4 {
+		t.Errorf("got %d backend calls, want 4", next.calls)
+	}
+
+	t.Setenv("RAG_CACHE_SIZE", "0")
+	if c := newCachingRAGClient(envConfig(t).RAG.Cache, next, nil); c != nil {
+		t.Errorf("RAG_CACHE_SIZE=0: got %v; want the cache disabled", c)
+	}
+}
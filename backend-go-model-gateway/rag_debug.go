@@ -0,0 +1,298 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"backend-go-model-gateway/pkg/promptguard"
+	"backend-go-model-gateway/pkg/ragfilter"
+)
+
+var errDebugInvalid = errors.New("invalid retrieval debug request")
+
+// retrievalDebugService serves POST /api/v1/retrieval/debug. It runs the
+// retrieval pipeline one stage at a time (retrieve, rerank, min-score
+// threshold, dedup, injection screening) with per-request overrides of the configured settings,
+// and reports every stage's matches and latency. Backend raw scores
+// (distances, similarities, keyword ranks) are returned next to the
+// normalized scores, so thresholds and rerankers can be tuned without code
+// edits or restarts.
+//
+// The result cache and chaos injection are bypassed: every request hits the
+// backend.
+type retrievalDebugService struct {
+	backend *ragBackend
+	kbs     *kbCatalog
+	// minScore and dedupSimilarity are GetPlan's settings, the defaults for
+	// requests that do not override them.
+	minScore        *float64
+	dedupSimilarity float64
+	// injection is RAG_INJECTION.
+	injection string
+}
+
+// newRetrievalDebugService returns nil (the endpoint answers 501) without a
+// backend.
+func newRetrievalDebugService(b *ragBackend, kbs *kbCatalog, minScore *float64, dedupSimilarity float64, injection string) *retrievalDebugService {
+	if b == nil || b.retriever == nil {
+		return nil
+	}
+	return &retrievalDebugService{backend: b, kbs: kbs, minScore: minScore, dedupSimilarity: dedupSimilarity, injection: injection}
+}
+
+type retrievalDebugRequest struct {
+	Query          string            `json:"query"`
+	KnowledgeBases []string          `json:"knowledge_bases"`
+	TopK           int               `json:"top_k"`
+	Filter         *ragfilter.Filter `json:"filter"`
+	// Namespace scopes retrieval to one tenant; required when
+	// RAG_TENANCY=required.
+	Namespace string `json:"namespace"`
+	// Mode is vector or hybrid (default: RAG_RETRIEVAL_MODE). Hybrid works
+	// with any backend that supports keyword search, even when not configured.
+	Mode string `json:"mode"`
+	// Rerank toggles the configured reranker (default: on when RAG_RERANKER
+	// is set).
+	Rerank *bool `json:"rerank"`
+	// MinScore and DedupSimilarity override RAG_MIN_SCORE and
+	// RAG_DEDUP_SIMILARITY.
+	MinScore        *float64 `json:"min_score"`
+	DedupSimilarity *float64 `json:"dedup_similarity"`
+}
+
+// retrievalDebugStage is one pipeline stage: the matches it passed on and,
+// for filtering stages, the ones it dropped.
+type retrievalDebugStage struct {
+	Name      string             `json:"name"`
+	LatencyMS float64            `json:"latency_ms"`
+	Matches   []VectorQueryMatch `json:"matches"`
+	Dropped   []VectorQueryMatch `json:"dropped,omitempty"`
+	// Flagged lists the matches the injection stage flagged, with the
+	// rules they broke; with RAG_INJECTION=flag they are not dropped.
+	Flagged []retrievalDebugFlag `json:"flagged,omitempty"`
+	// Error is set when the stage failed and the pipeline fell back, as it
+	// does in production (a failing reranker keeps the retrieval order).
+	Error string `json:"error,omitempty"`
+}
+
+type retrievalDebugFlag struct {
+	ID            string   `json:"id"`
+	KnowledgeBase string   `json:"knowledge_base"`
+	Rules         []string `json:"rules"`
+}
+
+type retrievalDebugResponse struct {
+	Query           string   `json:"query"`
+	KnowledgeBases  []string `json:"knowledge_bases"`
+	TopK            int      `json:"top_k"`
+	Backend         string   `json:"backend"`
+	Mode            string   `json:"mode"`
+	Reranker        string   `json:"reranker,omitempty"`
+	MinScore        *float64 `json:"min_score,omitempty"`
+	DedupSimilarity float64  `json:"dedup_similarity"`
+	// Language is the query's detected language when RAG_MULTILINGUAL=on.
+	// The stages search the requested KBs with the query as given, without
+	// language routing or translation.
+	Language           string                `json:"language,omitempty"`
+	LanguageConfidence float64               `json:"language_confidence,omitempty"`
+	Stages             []retrievalDebugStage `json:"stages"`
+	// Matches are what GetPlan would put in the prompt.
+	Matches   []VectorQueryMatch `json:"matches"`
+	LatencyMS float64            `json:"latency_ms"`
+}
+
+func (s *retrievalDebugService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		_ = json.NewEncoder(w).Encode(map[string]any{"error": "method not allowed"})
+		return
+	}
+	if s == nil {
+		w.WriteHeader(http.StatusNotImplemented)
+		_ = json.NewEncoder(w).Encode(map[string]any{"error": "no RAG backend configured"})
+		return
+	}
+
+	var req retrievalDebugRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]any{"error": "invalid request body: " + err.Error()})
+		return
+	}
+	resp, err := s.debug(r.Context(), req)
+	if err != nil {
+		status := http.StatusBadGateway
+		if errors.Is(err, errDebugInvalid) {
+			status = http.StatusBadRequest
+		}
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(map[string]any{"error": err.Error()})
+		return
+	}
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// debug validates req against the active backend and runs the pipeline.
+func (s *retrievalDebugService) debug(ctx context.Context, req retrievalDebugRequest) (*retrievalDebugResponse, error) {
+	start := time.Now()
+	b := s.backend
+	resp := &retrievalDebugResponse{
+		Query:           req.Query,
+		KnowledgeBases:  req.KnowledgeBases,
+		TopK:            req.TopK,
+		Backend:         b.name,
+		Mode:            strings.ToLower(req.Mode),
+		MinScore:        s.minScore,
+		DedupSimilarity: s.dedupSimilarity,
+	}
+	if resp.TopK <= 0 {
+		resp.TopK = 2
+	}
+	if len(resp.KnowledgeBases) == 0 {
+		resp.KnowledgeBases = []string{defaultRAGKnowledgeBase}
+	}
+	if resp.Mode == "" {
+		resp.Mode = b.mode
+	}
+	rerank := b.rerank != nil
+	if req.Rerank != nil {
+		rerank = *req.Rerank
+	}
+	if req.MinScore != nil {
+		resp.MinScore = req.MinScore
+	}
+	if req.DedupSimilarity != nil {
+		resp.DedupSimilarity = *req.DedupSimilarity
+	}
+
+	switch {
+	case strings.TrimSpace(req.Query) == "":
+		return nil, fmt.Errorf("%w: query is required", errDebugInvalid)
+	case resp.Mode != "vector" && resp.Mode != "hybrid":
+		return nil, fmt.Errorf("%w: unsupported mode %q (supported: vector, hybrid)", errDebugInvalid, resp.Mode)
+	case resp.Mode == "hybrid" && b.lexical == nil:
+		return nil, fmt.Errorf("%w: RAG_BACKEND=%s does not support keyword search, so hybrid mode is unavailable", errDebugInvalid, b.name)
+	case rerank && b.rerank == nil:
+		return nil, fmt.Errorf("%w: rerank requested but RAG_RERANKER is off", errDebugInvalid)
+	case resp.DedupSimilarity < 0 || resp.DedupSimilarity > 1:
+		return nil, fmt.Errorf("%w: dedup_similarity must be in [0, 1]", errDebugInvalid)
+	case req.Namespace != "" && !tenantIDPattern.MatchString(req.Namespace):
+		return nil, fmt.Errorf("%w: invalid namespace %q", errDebugInvalid, req.Namespace)
+	case req.Namespace == "" && b.tenancy == ragTenancyRequired:
+		return nil, fmt.Errorf("%w: namespace is required when RAG_TENANCY=required", errDebugInvalid)
+	}
+	if s.kbs != nil {
+		for _, kb := range resp.KnowledgeBases {
+			if !s.kbs.Has(kb) {
+				return nil, fmt.Errorf("%w: unknown knowledge base %q", errDebugInvalid, kb)
+			}
+		}
+	}
+	if rerank {
+		resp.Reranker = b.rerank.kind
+	}
+	if b.multilingual != nil {
+		resp.Language, resp.LanguageConfidence = detectLanguage(normalizeQuery(req.Query))
+	}
+
+	// Retrieve. With reranking on, fetch the reranker's candidate pool, as
+	// rerankingRAGClient does.
+	vreq := VectorQueryRequest{
+		QueryText:      req.Query,
+		TopK:           resp.TopK,
+		KnowledgeBases: resp.KnowledgeBases,
+		Filter:         req.Filter.Resolve(time.Now()),
+		Namespace:      req.Namespace,
+	}
+	if rerank {
+		vreq.TopK *= b.rerank.candidates
+	}
+	var retriever RAGContextClient = b.retriever
+	if resp.Mode == "hybrid" {
+		retriever = newHybridRAGClient(b.cfg, b.retriever, b.lexical)
+	}
+	stageStart := time.Now()
+	matches, err := retriever.GetContext(ctx, vreq)
+	if err != nil {
+		return nil, fmt.Errorf("%s retrieval: %w", resp.Mode, err)
+	}
+	resp.Stages = append(resp.Stages, retrievalDebugStage{Name: "retrieve", LatencyMS: msSince(stageStart), Matches: matches})
+
+	if rerank {
+		stageStart = time.Now()
+		stage := retrievalDebugStage{Name: "rerank"}
+		reranked := matches
+		if len(matches) > 0 {
+			if reranked, err = b.rerank.rerank(ctx, req.Query, matches); err != nil {
+				stage.Error = err.Error()
+				reranked = matches
+			}
+		}
+		matches = topKPerKB(reranked, resp.KnowledgeBases, resp.TopK)
+		stage.LatencyMS = msSince(stageStart)
+		stage.Matches = matches
+		resp.Stages = append(resp.Stages, stage)
+	}
+
+	if resp.MinScore != nil {
+		stageStart = time.Now()
+		kept := relevantMatches(matches, resp.MinScore)
+		resp.Stages = append(resp.Stages, retrievalDebugStage{Name: "min_score", LatencyMS: msSince(stageStart), Matches: kept, Dropped: droppedMatches(matches, kept)})
+		matches = kept
+	}
+
+	if resp.DedupSimilarity > 0 {
+		stageStart = time.Now()
+		unique := dedupeMatches(matches, resp.DedupSimilarity)
+		resp.Stages = append(resp.Stages, retrievalDebugStage{Name: "dedup", LatencyMS: msSince(stageStart), Matches: unique, Dropped: droppedMatches(matches, unique)})
+		matches = unique
+	}
+
+	if s.injection != promptguard.ModeOff {
+		stageStart = time.Now()
+		kept, flagged := screenMatches(matches, s.injection)
+		stage := retrievalDebugStage{Name: "injection", Matches: kept, Dropped: droppedMatches(matches, kept)}
+		for _, f := range flagged {
+			stage.Flagged = append(stage.Flagged, retrievalDebugFlag{ID: f.match.ID, KnowledgeBase: f.match.KnowledgeBase, Rules: f.rules})
+		}
+		stage.LatencyMS = msSince(stageStart)
+		resp.Stages = append(resp.Stages, stage)
+		matches = kept
+	}
+
+	resp.Matches = matches
+	resp.LatencyMS = msSince(start)
+	log.Printf(
+		`{"timestamp":"%s","level":"info","service":"%s","component":"RetrievalDebug","query_text":%q,"mode":%q,"reranker":%q,"match_count":%d,"latency_ms":%.3f}`,
+		time.Now().Format(time.RFC3339Nano), SERVICE_NAME, req.Query, resp.Mode, resp.Reranker, len(matches), resp.LatencyMS,
+	)
+	return resp, nil
+}
+
+// droppedMatches returns the matches of all that kept (an order-preserving
+// subset of all) does not contain.
+func droppedMatches(all, kept []VectorQueryMatch) []VectorQueryMatch {
+	var dropped []VectorQueryMatch
+	j := 0
+	for _, m := range all {
+		if j < len(kept) && kept[j].KnowledgeBase == m.KnowledgeBase && kept[j].ID == m.ID {
+			j++
+			continue
+		}
+		dropped = append(dropped, m)
+	}
+	return dropped
+}
+
+// msSince is the time since start in milliseconds, at microsecond resolution:
+// in-process stages often take well under a millisecond.
+func msSince(start time.Time) float64 {
+	return float64(time.Since(start).Microseconds()) / 1000
+}
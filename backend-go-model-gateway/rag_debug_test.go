@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"backend-go-model-gateway/pkg/promptguard"
+)
+
+func TestRetrievalDebugEndpoint(t *testing.T) {
+	path := writeCorpus(t,
+		`{"id":"d1","kb":"Domain-KB","text":"Ask the user for clarification when a request is ambiguous"}`,
+		`{"id":"d2","kb":"Domain-KB","text":"Ask the user for clarification when a request is ambiguous."}`,
+		`{"id":"d3","kb":"Domain-KB","text":"Break complex requests into sub-tasks and ask for help"}`,
+		`{"id":"d4","kb":"Domain-KB","text":"Sleep eight hours"}`,
+	)
+	ec, err := LoadEmbeddedRAGClient(context.Background(), path, hashEmbedder{dims: 256})
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend := &ragBackend{
+		name:      ragBackendEmbedded,
+		retriever: ec,
+		lexical:   ec,
+		mode:      "vector",
+		rerank:    &rerankingRAGClient{reranker: stubReranker{}, kind: "stub", candidates: 2},
+		cfg:       defaultConfig().RAG,
+	}
+	debug := newRetrievalDebugService(backend, nil, nil, 0.8, promptguard.ModeOff)
+	srv := httptest.NewServer(NewHTTPMux(fakeRAGClient{}, adminRoutes{debug: debug}))
+	t.Cleanup(srv.Close)
+
+	post := func(body string) (*http.Response, retrievalDebugResponse) {
+		t.Helper()
+		resp, err := http.Post(srv.URL+"/api/v1/retrieval/debug", "application/json", bytes.NewBufferString(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var out retrievalDebugResponse
+		if resp.StatusCode == http.StatusOK {
+			if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return resp, out
+	}
+
+	// Reranking is on by default: 2 * 2 candidates, reordered by text length,
+	// then cut to top_k. d1 and d2 are near-duplicates; the threshold drops
+	// the short d4 first.
+	resp, out := post(`{"query": "the request is ambiguous, ask for clarification", "knowledge_bases": ["Domain-KB"], "top_k": 3, "min_score": 20}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d", resp.StatusCode)
+	}
+	var names []string
+	for _, s := range out.Stages {
+		names = append(names, s.Name)
+	}
+	if len(names) != 4 || names[0] != "retrieve" || names[1] != "rerank" || names[2] != "min_score" || names[3] != "dedup" {
+		t.Fatalf("stages = %v", names)
+	}
+	retrieved := out.Stages[0].Matches
+	if len(retrieved) != 4 || retrieved[0].RawScore != retrieved[0].Score || retrieved[0].Score <= 0 {
+		t.Fatalf("retrieve stage = %+v, want all 4 candidates with raw scores", retrieved)
+	}
+	if got := out.Stages[1].Matches; len(got) != 3 || got[0].ID != "d2" || got[0].Score != 59 || got[0].RawScore == got[0].Score {
+		t.Fatalf("rerank stage = %+v, want d2 first with its reranker score and raw similarity", got)
+	}
+	if len(out.Stages[3].Dropped) != 1 || out.Stages[3].Dropped[0].ID != "d1" {
+		t.Fatalf("dedup dropped %+v, want d1", out.Stages[3].Dropped)
+	}
+	if len(out.Matches) != 2 || out.Matches[0].ID != "d2" || out.Matches[1].ID != "d3" || out.Reranker != "stub" {
+		t.Fatalf("final matches = %+v", out.Matches)
+	}
+
+	// Overrides: hybrid retrieval, no reranking, dedup off.
+	_, out = post(`{"query": "clarification", "knowledge_bases": ["Domain-KB"], "mode": "hybrid", "rerank": false, "dedup_similarity": 0}`)
+	if out.Mode != "hybrid" || len(out.Stages) != 1 || len(out.Matches) != 2 {
+		t.Fatalf("got %+v, want one hybrid retrieve stage with 2 matches", out)
+	}
+
+	for _, body := range []string{
+		`{}`,
+		`{"query": "q", "mode": "keyword"}`,
+		`{"query": "q", "dedup_similarity": 2}`,
+		`{"query": "q", "namespace": "not a tenant!"}`,
+	} {
+		if resp, _ := post(body); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400", body, resp.StatusCode)
+		}
+	}
+
+	// Without a backend the endpoint is not implemented.
+	noRAG := httptest.NewServer(NewHTTPMux(fakeRAGClient{}, adminRoutes{}))
+	t.Cleanup(noRAG.Close)
+	resp, err = http.Post(noRAG.URL+"/api/v1/retrieval/debug", "application/json", bytes.NewBufferString(`{"query": "q"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotImplemented {
+		t.Fatalf("status %d, want 501", resp.StatusCode)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"hash/fnv"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	// dedupShingleWords is the shingle length in words.
+	dedupShingleWords = 3
+	// dedupMinHashes is the MinHash signature length; the Jaccard estimate's
+	// standard error is about 1/sqrt(64) = 0.125 at 0.5, less near 0 and 1.
+	dedupMinHashes = 64
+)
+
+// dedupeMatches drops matches whose text near-duplicates a better-scored
+// match, typically the same passage indexed in several KBs. Survivors keep
+// their order. similarity <= 0 keeps every match.
+func dedupeMatches(matches []VectorQueryMatch, similarity float64) []VectorQueryMatch {
+	if similarity <= 0 || len(matches) < 2 {
+		return matches
+	}
+	sigs := make([][]uint64, len(matches))
+	for i, m := range matches {
+		sigs[i] = minHashSignature(m.Text)
+	}
+	byScore := make([]int, len(matches))
+	for i := range byScore {
+		byScore[i] = i
+	}
+	sort.SliceStable(byScore, func(a, b int) bool { return matches[byScore[a]].Score > matches[byScore[b]].Score })
+
+	keep := make([]bool, len(matches))
+	var kept []int
+	for _, i := range byScore {
+		dup := false
+		for _, j := range kept {
+			if minHashSimilarity(sigs[i], sigs[j]) >= similarity {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			keep[i] = true
+			kept = append(kept, i)
+		}
+	}
+
+	out := make([]VectorQueryMatch, 0, len(kept))
+	for i, m := range matches {
+		if keep[i] {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// minHashSignature returns the MinHash signature of text's word shingles.
+// Case and punctuation are ignored; texts shorter than a shingle form one.
+func minHashSignature(text string) []uint64 {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	n := max(len(words)-dedupShingleWords+1, 1)
+	sig := make([]uint64, dedupMinHashes)
+	for i := range sig {
+		sig[i] = math.MaxUint64
+	}
+	for i := range n {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(strings.Join(words[i:min(i+dedupShingleWords, len(words))], " ")))
+		base := h.Sum64()
+		for k := range sig {
+			sig[k] = min(sig[k], mix64(base^uint64(k+1)*0x9e3779b97f4a7c15))
+		}
+	}
+	return sig
+}
+
+// minHashSimilarity estimates the Jaccard similarity of two shingle sets from
+// their signatures.
+func minHashSimilarity(a, b []uint64) float64 {
+	same := 0
+	for i := range a {
+		if a[i] == b[i] {
+			same++
+		}
+	}
+	return float64(same) / float64(len(a))
+}
+
+// mix64 is the splitmix64 finalizer, deriving independent hash functions
+// from one base hash.
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
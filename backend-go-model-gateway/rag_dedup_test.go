@@ -0,0 +1,46 @@
+package main
+
+import "testing"
+
+func TestDedupeMatches(t *testing.T) {
+	passage := "New users complete the onboarding checklist, verify their email address and set up two-factor authentication before their first session."
+	matches := []VectorQueryMatch{
+		{ID: "domain-1", KnowledgeBase: "Domain-KB", Text: passage, Score: 0.71},
+		{ID: "domain-2", KnowledgeBase: "Domain-KB", Text: "Sessions time out after thirty minutes of inactivity.", Score: 0.64},
+		// The same passage re-indexed with different punctuation and casing.
+		{ID: "mind-1", KnowledgeBase: "Mind-KB", Text: "NEW USERS complete the onboarding checklist; verify their email address, and set up two-factor authentication before their first session!", Score: 0.83},
+		{ID: "body-1", KnowledgeBase: "Body-KB", Text: "New users should sleep eight hours before their first session.", Score: 0.5},
+	}
+
+	got := dedupeMatches(matches, 0.8)
+	var ids []string
+	for _, m := range got {
+		ids = append(ids, m.ID)
+	}
+	// The better-scored copy survives, and order is preserved.
+	if len(ids) != 3 || ids[0] != "domain-2" || ids[1] != "mind-1" || ids[2] != "body-1" {
+		t.Fatalf("got %v, want [domain-2 mind-1 body-1]", ids)
+	}
+
+	if got := dedupeMatches(matches, 0); len(got) != len(matches) {
+		t.Fatalf("similarity 0 must keep every match, got %d", len(got))
+	}
+}
+
+func TestMinHashSimilarity(t *testing.T) {
+	a := minHashSignature("the quick brown fox jumps over the lazy dog near the river bank")
+	b := minHashSignature("the quick brown fox jumps over the lazy dog near the river")
+	c := minHashSignature("quarterly revenue grew twelve percent on strong subscription sales")
+	if sim := minHashSimilarity(a, a); sim != 1 {
+		t.Fatalf("identical texts: similarity %v, want 1", sim)
+	}
+	if sim := minHashSimilarity(a, b); sim < 0.6 {
+		t.Fatalf("overlapping texts: similarity %v, want >= 0.6", sim)
+	}
+	if sim := minHashSimilarity(a, c); sim > 0.2 {
+		t.Fatalf("unrelated texts: similarity %v, want <= 0.2", sim)
+	}
+	if sim := minHashSimilarity(minHashSignature("ok"), minHashSignature("OK!")); sim != 1 {
+		t.Fatalf("short texts differing in case and punctuation: similarity %v, want 1", sim)
+	}
+}
@@ -0,0 +1,359 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"math"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"backend-go-model-gateway/pkg/ragfilter"
+	"backend-go-model-gateway/pkg/secrets"
+)
+
+// EmbeddedRAGClient implements RAGContextClient with an in-process vector store
+// (RAG_BACKEND=embedded): a JSONL corpus is loaded at boot and searched by
+// brute-force cosine similarity. It is meant for demos and local development
+// (pairs well with LLM_PROVIDER=mock), not for large corpora. Documents
+// ingested through /api/v1/ingest are held in memory only and are lost on
+// restart.
+type EmbeddedRAGClient struct {
+	embedder Embedder
+
+	mu   sync.RWMutex
+	docs []embeddedDoc
+	// keywords backs KeywordSearch for RAG_RETRIEVAL_MODE=hybrid.
+	keywords *bm25Index
+}
+
+// embeddedDoc is one corpus line. Embedding is optional in the file; documents
+// without one are embedded at load time.
+type embeddedDoc struct {
+	ID        string    `json:"id"`
+	KB        string    `json:"kb"`
+	Text      string    `json:"text"`
+	Source    string    `json:"source"`
+	Embedding []float32 `json:"embedding,omitempty"`
+
+	// Metadata for filters. DocumentID groups the chunks of one document and
+	// defaults to ID; Namespace is the owning tenant under RAG_TENANCY=required.
+	DocumentID string    `json:"document_id,omitempty"`
+	Tags       []string  `json:"tags,omitempty"`
+	CreatedAt  time.Time `json:"created_at,omitzero"`
+	Namespace  string    `json:"namespace,omitempty"`
+
+	norm float64
+}
+
+// visible reports whether d is in namespace ns ("" when tenancy is off) and
+// passes f.
+func (d *embeddedDoc) visible(ns string, f *ragfilter.Filter) bool {
+	return (ns == "" || d.Namespace == ns) && f.Match(d.metadata())
+}
+
+func (d *embeddedDoc) metadata() ragfilter.Metadata {
+	id := d.DocumentID
+	if id == "" {
+		id = d.ID
+	}
+	return ragfilter.Metadata{ID: id, Source: d.Source, Tags: d.Tags, CreatedAt: d.CreatedAt}
+}
+
+// NewEmbeddedRAGClient loads the corpus cfg.RAG.Embedded names.
+//
+//   - EMBEDDED_RAG_CORPUS (required) — JSONL, one {"id","kb","text","source","embedding"?} per line,
+//     optionally with "document_id", "tags" and "created_at" (RFC 3339) for metadata filters
+//     and "namespace" for RAG_TENANCY=required
+//   - EMBEDDED_EMBEDDINGS (default: hash) — hash: built-in hashed bag-of-words,
+//     no external service; provider: the EMBEDDINGS_* endpoint
+//   - EMBEDDED_HASH_DIMS (default: 256)
+func NewEmbeddedRAGClient(ctx context.Context, store *secrets.Store, cfg *Config) (*EmbeddedRAGClient, error) {
+	ec := cfg.RAG.Embedded
+	path := ec.Corpus
+	if path == "" {
+		return nil, fmt.Errorf("EMBEDDED_RAG_CORPUS is required when RAG_BACKEND=embedded")
+	}
+
+	var embedder Embedder
+	switch v := strings.ToLower(ec.Embeddings); v {
+	case "hash":
+		embedder = hashEmbedder{dims: ec.HashDims}
+	case "provider":
+		var err error
+		if embedder, err = newEmbedder(ctx, store, cfg.Embeddings, cfg.LLM); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported EMBEDDED_EMBEDDINGS=%q (supported: hash, provider)", v)
+	}
+
+	c, err := LoadEmbeddedRAGClient(ctx, path, embedder)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf(
+		`{"timestamp":"%s","level":"info","service":"%s","component":"EmbeddedRAGClient","corpus":%q,"documents":%d}`,
+		time.Now().Format(time.RFC3339Nano), SERVICE_NAME, path, len(c.docs),
+	)
+	return c, nil
+}
+
+// LoadEmbeddedRAGClient reads a JSONL corpus and embeds any documents that do
+// not carry a precomputed embedding. All embeddings must share one dimension.
+func LoadEmbeddedRAGClient(ctx context.Context, path string, embedder Embedder) (*EmbeddedRAGClient, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("embedded corpus: %w", err)
+	}
+	defer f.Close()
+
+	c := &EmbeddedRAGClient{embedder: embedder}
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	line := 0
+	dims := 0
+	for sc.Scan() {
+		line++
+		raw := strings.TrimSpace(sc.Text())
+		if raw == "" || strings.HasPrefix(raw, "#") {
+			continue
+		}
+		var d embeddedDoc
+		if err := json.Unmarshal([]byte(raw), &d); err != nil {
+			return nil, fmt.Errorf("embedded corpus %s:%d: %w", path, line, err)
+		}
+		if d.Text == "" {
+			return nil, fmt.Errorf("embedded corpus %s:%d: empty text", path, line)
+		}
+		if d.KB == "" {
+			d.KB = defaultRAGKnowledgeBase
+		}
+		if d.ID == "" {
+			d.ID = fmt.Sprintf("%s-%d", d.KB, line)
+		}
+		if d.Source == "" {
+			d.Source = "embedded"
+		}
+		if len(d.Embedding) == 0 {
+			if d.Embedding, err = embedder.Embed(ctx, d.Text); err != nil {
+				return nil, fmt.Errorf("embedded corpus %s:%d: %w", path, line, err)
+			}
+		}
+		if dims == 0 {
+			dims = len(d.Embedding)
+		} else if len(d.Embedding) != dims {
+			return nil, fmt.Errorf("embedded corpus %s:%d: embedding has %d dimensions, want %d", path, line, len(d.Embedding), dims)
+		}
+		d.norm = vectorNorm(d.Embedding)
+		c.docs = append(c.docs, d)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("embedded corpus %s: %w", path, err)
+	}
+	c.reindex()
+	return c, nil
+}
+
+// reindex rebuilds the keyword index from c.docs. Callers hold c.mu for
+// writing (or own c exclusively).
+func (c *EmbeddedRAGClient) reindex() {
+	texts := make([]string, len(c.docs))
+	for i, d := range c.docs {
+		texts[i] = d.Text
+	}
+	docs := c.docs
+	c.keywords = newBM25Index(texts, func(i int) string { return docs[i].KB })
+}
+
+// ReplaceDocument swaps the document's chunks in memory and rebuilds the
+// keyword index. Chunks must match the corpus's embedding dimension.
+func (c *EmbeddedRAGClient) ReplaceDocument(_ context.Context, kb, namespace, documentID string, chunks []ingestChunk) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	kept := make([]embeddedDoc, 0, len(c.docs)+len(chunks))
+	dims := 0
+	for _, d := range c.docs {
+		if dims == 0 {
+			dims = len(d.Embedding)
+		}
+		if d.KB == kb && d.Namespace == namespace && d.metadata().ID == documentID {
+			continue
+		}
+		kept = append(kept, d)
+	}
+	for _, ch := range chunks {
+		if dims != 0 && len(ch.Embedding) != dims {
+			return fmt.Errorf("embedded: chunk %s has %d dimensions, want %d", ch.ID, len(ch.Embedding), dims)
+		}
+		kept = append(kept, embeddedDoc{
+			ID:         ch.ID,
+			KB:         kb,
+			Text:       ch.Text,
+			Source:     ch.Source,
+			Embedding:  ch.Embedding,
+			DocumentID: ch.DocumentID,
+			Tags:       ch.Tags,
+			CreatedAt:  ch.CreatedAt,
+			Namespace:  ch.Namespace,
+			norm:       vectorNorm(ch.Embedding),
+		})
+	}
+	c.docs = kept
+	c.reindex()
+	return nil
+}
+
+// CreateKB is a no-op: KBs are document labels in the embedded store.
+func (c *EmbeddedRAGClient) CreateKB(context.Context, string, int) error { return nil }
+
+// DropKB removes kb's documents from memory.
+func (c *EmbeddedRAGClient) DropKB(_ context.Context, kb string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.docs = slices.DeleteFunc(slices.Clone(c.docs), func(d embeddedDoc) bool { return d.KB == kb })
+	c.reindex()
+	return nil
+}
+
+func (c *EmbeddedRAGClient) KBStats(_ context.Context, kb string) (kbStats, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var st kbStats
+	for _, d := range c.docs {
+		if d.KB != kb {
+			continue
+		}
+		st.Documents++
+		if d.CreatedAt.After(st.LastUpdated) {
+			st.LastUpdated = d.CreatedAt
+		}
+	}
+	return st, nil
+}
+
+func (c *EmbeddedRAGClient) GetContext(ctx context.Context, req VectorQueryRequest) ([]VectorQueryMatch, error) {
+	if req.TopK <= 0 {
+		req.TopK = 2
+	}
+	kbs := req.KnowledgeBases
+	if len(kbs) == 0 {
+		kbs = []string{defaultRAGKnowledgeBase}
+	}
+
+	vec, err := c.embedder.Embed(ctx, req.QueryText)
+	if err != nil {
+		return nil, err
+	}
+	qnorm := vectorNorm(vec)
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	matches := make([]VectorQueryMatch, 0, len(kbs)*req.TopK)
+	for _, kb := range kbs {
+		var hits []VectorQueryMatch
+		for i := range c.docs {
+			d := &c.docs[i]
+			if d.KB != kb || len(d.Embedding) != len(vec) || !d.visible(req.Namespace, req.Filter) {
+				continue
+			}
+			similarity := cosineSimilarity(vec, d.Embedding, qnorm, d.norm)
+			hits = append(hits, VectorQueryMatch{
+				ID:            d.ID,
+				Score:         similarity,
+				RawScore:      similarity,
+				Text:          d.Text,
+				Source:        d.Source,
+				KnowledgeBase: kb,
+			})
+		}
+		sort.SliceStable(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+		if len(hits) > req.TopK {
+			hits = hits[:req.TopK]
+		}
+		matches = append(matches, hits...)
+	}
+
+	log.Printf(
+		`{"timestamp":"%s","level":"info","service":"%s","component":"EmbeddedRAGClient","method":"GetContext","documents":%d,"query_text":%q,"top_k":%d,"match_count":%d}`,
+		time.Now().Format(time.RFC3339Nano), SERVICE_NAME, len(c.docs), req.QueryText, req.TopK, len(matches),
+	)
+	return matches, nil
+}
+
+// KeywordSearch ranks the corpus with BM25 over the in-process inverted index.
+func (c *EmbeddedRAGClient) KeywordSearch(_ context.Context, req VectorQueryRequest) ([]VectorQueryMatch, error) {
+	if req.TopK <= 0 {
+		req.TopK = 2
+	}
+	kbs := req.KnowledgeBases
+	if len(kbs) == 0 {
+		kbs = []string{defaultRAGKnowledgeBase}
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	matches := make([]VectorQueryMatch, 0, len(kbs)*req.TopK)
+	for _, kb := range kbs {
+		allow := func(i int) bool { return c.docs[i].visible(req.Namespace, req.Filter) }
+		for _, hit := range c.keywords.search(kb, req.QueryText, req.TopK, allow) {
+			d := c.docs[hit.doc]
+			matches = append(matches, VectorQueryMatch{
+				ID:            d.ID,
+				Score:         hit.score,
+				RawScore:      hit.score,
+				Text:          d.Text,
+				Source:        d.Source,
+				KnowledgeBase: kb,
+			})
+		}
+	}
+	return matches, nil
+}
+
+func vectorNorm(v []float32) float64 {
+	var sum float64
+	for _, f := range v {
+		sum += float64(f) * float64(f)
+	}
+	return math.Sqrt(sum)
+}
+
+func cosineSimilarity(a, b []float32, normA, normB float64) float64 {
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	var dot float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+	}
+	return dot / (normA * normB)
+}
+
+// hashEmbedder is a dependency-free embedder: lower-cased word tokens are
+// hashed into a fixed number of buckets (the "hashing trick"). It captures
+// lexical overlap only, which is enough for demos over a small corpus.
+type hashEmbedder struct {
+	dims int
+}
+
+func (e hashEmbedder) Embed(_ context.Context, text string) ([]float32, error) {
+	v := make([]float32, e.dims)
+	for _, tok := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(tok))
+		v[h.Sum32()%uint32(e.dims)]++
+	}
+	return v, nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"backend-go-model-gateway/pkg/ragfilter"
+)
+
+func writeCorpus(t *testing.T, lines ...string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "corpus.jsonl")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestEmbeddedRAGClient_RanksByCosinePerKB(t *testing.T) {
+	path := writeCorpus(t,
+		`# demo corpus`,
+		`{"id":"d1","kb":"Domain-KB","text":"Always use web search for current facts","source":"rules.txt"}`,
+		`{"id":"d2","kb":"Domain-KB","text":"Ask the user for clarification when a request is ambiguous"}`,
+		`{"id":"d3","kb":"Domain-KB","text":"Break complex requests into sub-tasks"}`,
+		`{"id":"b1","text":"Sleep eight hours and drink water"}`,
+	)
+	c, err := LoadEmbeddedRAGClient(context.Background(), path, hashEmbedder{dims: 256})
+	if err != nil {
+		t.Fatalf("LoadEmbeddedRAGClient: %v", err)
+	}
+
+	matches, err := c.GetContext(context.Background(), VectorQueryRequest{
+		QueryText:      "the request is ambiguous, ask for clarification",
+		TopK:           1,
+		KnowledgeBases: []string{"Domain-KB", "Body-KB", "Soul-KB"},
+	})
+	if err != nil {
+		t.Fatalf("GetContext: %v", err)
+	}
+	if len(matches) != 2 {
+		t.Fatalf("got %d matches, want 2 (one per non-empty KB): %+v", len(matches), matches)
+	}
+	if matches[0].ID != "d2" || matches[0].KnowledgeBase != "Domain-KB" || matches[0].Source != "embedded" {
+		t.Errorf("top Domain-KB match = %+v, want d2", matches[0])
+	}
+	// A line without "kb" lands in the default KB.
+	if matches[1].ID != "b1" || matches[1].KnowledgeBase != "Body-KB" {
+		t.Errorf("Body-KB match = %+v, want b1", matches[1])
+	}
+	if matches[0].Score <= matches[1].Score {
+		t.Errorf("expected the lexically overlapping doc to score higher: %+v", matches)
+	}
+}
+
+func TestEmbeddedRAGClient_PrecomputedEmbeddings(t *testing.T) {
+	path := writeCorpus(t,
+		`{"id":"x","kb":"Body-KB","text":"x","embedding":[1,0,0]}`,
+		`{"id":"y","kb":"Body-KB","text":"y","embedding":[0,1,0]}`,
+	)
+	// fakeEmbedder maps "ab" to [2,1,0], closer to x than to y.
+	c, err := LoadEmbeddedRAGClient(context.Background(), path, fakeEmbedder{})
+	if err != nil {
+		t.Fatalf("LoadEmbeddedRAGClient: %v", err)
+	}
+	matches, err := c.GetContext(context.Background(), VectorQueryRequest{QueryText: "ab", TopK: 2})
+	if err != nil {
+		t.Fatalf("GetContext: %v", err)
+	}
+	if len(matches) != 2 || matches[0].ID != "x" || matches[1].ID != "y" {
+		t.Fatalf("unexpected ranking: %+v", matches)
+	}
+
+	bad := writeCorpus(t,
+		`{"text":"x","embedding":[1,0,0]}`,
+		`{"text":"y","embedding":[0,1]}`,
+	)
+	if _, err := LoadEmbeddedRAGClient(context.Background(), bad, fakeEmbedder{}); err == nil || !strings.Contains(err.Error(), "dimensions") {
+		t.Fatalf("expected a dimension mismatch error, got %v", err)
+	}
+}
+
+func TestEmbeddedRAGClient_MetadataFilter(t *testing.T) {
+	path := writeCorpus(t,
+		`{"id":"a","text":"sleep schedule notes","source":"journal","tags":["sleep"],"created_at":"2026-09-01T00:00:00Z"}`,
+		`{"id":"b","text":"sleep study summary","source":"papers","tags":["sleep","research"],"created_at":"2026-10-01T00:00:00Z"}`,
+		`{"id":"c","text":"sleep tracker export","source":"journal"}`,
+	)
+	c, err := LoadEmbeddedRAGClient(context.Background(), path, hashEmbedder{dims: 64})
+	if err != nil {
+		t.Fatalf("LoadEmbeddedRAGClient: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		filter *ragfilter.Filter
+		want   []string
+	}{
+		{"source", &ragfilter.Filter{Sources: []string{"journal"}}, []string{"a", "c"}},
+		{"tags", &ragfilter.Filter{Tags: []string{"sleep", "research"}}, []string{"b"}},
+		{"date", &ragfilter.Filter{CreatedAfter: time.Date(2026, 9, 15, 0, 0, 0, 0, time.UTC)}, []string{"b"}},
+	} {
+		req := VectorQueryRequest{QueryText: "sleep", TopK: 5, Filter: tc.filter}
+		vec, err := c.GetContext(context.Background(), req)
+		if err != nil {
+			t.Fatalf("%s: GetContext: %v", tc.name, err)
+		}
+		kw, err := c.KeywordSearch(context.Background(), req)
+		if err != nil {
+			t.Fatalf("%s: KeywordSearch: %v", tc.name, err)
+		}
+		for side, matches := range map[string][]VectorQueryMatch{"vector": vec, "keyword": kw} {
+			var ids []string
+			for _, m := range matches {
+				ids = append(ids, m.ID)
+			}
+			slices.Sort(ids)
+			if !slices.Equal(ids, tc.want) {
+				t.Errorf("%s (%s): got %v, want %v", tc.name, side, ids, tc.want)
+			}
+		}
+	}
+}
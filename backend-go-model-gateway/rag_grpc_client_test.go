@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"backend-go-model-gateway/pkg/fakememory"
+)
+
+func TestRAGGRPCClient_AgainstFakeMemoryService(t *testing.T) {
+	mem := fakememory.StartT(t)
+	mem.Seed("Body-KB",
+		fakememory.Document{ID: "body-1", Text: "Morning run schedule: 6am on weekdays"},
+		fakememory.Document{ID: "body-2", Text: "Grocery list for the week"},
+	)
+	mem.Seed("Soul-KB", fakememory.Document{ID: "soul-1", Text: "Values: honesty and curiosity"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client, err := NewRAGGRPCClient(ctx, mem.GRPCAddr)
+	if err != nil {
+		t.Fatalf("NewRAGGRPCClient: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+
+	matches, err := client.GetContext(ctx, VectorQueryRequest{
+		QueryText:      "what is my run schedule",
+		TopK:           1,
+		KnowledgeBases: []string{"Body-KB", "Soul-KB"},
+	})
+	if err != nil {
+		t.Fatalf("GetContext: %v", err)
+	}
+	if len(matches) != 2 {
+		t.Fatalf("expected top-1 per KB (2 matches), got %d: %#v", len(matches), matches)
+	}
+	if matches[0].ID != "body-1" || matches[0].KnowledgeBase != "Body-KB" {
+		t.Fatalf("expected best Body-KB match first, got %#v", matches[0])
+	}
+	if matches[0].Score <= matches[1].Score {
+		t.Fatalf("expected lexical match to score higher: %#v", matches)
+	}
+
+	reqs := mem.RAGRequests()
+	if len(reqs) != 1 || reqs[0].GetTopK() != 1 {
+		t.Fatalf("unexpected recorded RAG requests: %v", reqs)
+	}
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"context"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// lexicalSearcher is implemented by RAG backends that can also rank documents
+// by keyword relevance (BM25 or full-text search). Results follow the
+// GetContext layout: best-first, grouped by KB in request order.
+type lexicalSearcher interface {
+	KeywordSearch(ctx context.Context, req VectorQueryRequest) ([]VectorQueryMatch, error)
+}
+
+// hybridRAGClient runs vector and keyword retrieval side by side and merges
+// the two rankings with reciprocal-rank fusion (RRF). Keyword ranking catches
+// exact identifiers and error codes that embeddings tend to blur.
+//
+// Match scores are the fused RRF scores, not similarities.
+type hybridRAGClient struct {
+	vector  RAGContextClient
+	lexical lexicalSearcher
+	// rrfK damps the weight of top ranks; 60 is the value from the RRF paper.
+	rrfK int
+	// candidates multiplies top_k for each side before fusion.
+	candidates int
+}
+
+// newHybridRAGClient wraps a backend for RAG_RETRIEVAL_MODE=hybrid.
+//
+//   - RAG_HYBRID_RRF_K (default: 60)
+//   - RAG_HYBRID_CANDIDATES (default: 3) — each side fetches top_k * this
+func newHybridRAGClient(cfg RAGConfig, vector RAGContextClient, lexical lexicalSearcher) *hybridRAGClient {
+	return &hybridRAGClient{
+		vector:     vector,
+		lexical:    lexical,
+		rrfK:       cfg.HybridRRFK,
+		candidates: cfg.HybridCandidates,
+	}
+}
+
+func (c *hybridRAGClient) GetContext(ctx context.Context, req VectorQueryRequest) ([]VectorQueryMatch, error) {
+	if req.TopK <= 0 {
+		req.TopK = 2
+	}
+	if len(req.KnowledgeBases) == 0 {
+		req.KnowledgeBases = []string{defaultRAGKnowledgeBase}
+	}
+	wide := req
+	wide.TopK = req.TopK * c.candidates
+
+	type result struct {
+		matches []VectorQueryMatch
+		err     error
+	}
+	lexCh := make(chan result, 1)
+	go func() {
+		m, err := c.lexical.KeywordSearch(ctx, wide)
+		lexCh <- result{m, err}
+	}()
+	vec, vecErr := c.vector.GetContext(ctx, wide)
+	lex := <-lexCh
+
+	// One side failing degrades to the other rather than failing the request.
+	switch {
+	case vecErr != nil && lex.err != nil:
+		return nil, vecErr
+	case vecErr != nil || lex.err != nil:
+		side, err := "vector", vecErr
+		if lex.err != nil {
+			side, err = "keyword", lex.err
+		}
+		log.Printf(
+			`{"timestamp":"%s","level":"warn","service":"%s","component":"HybridRAGClient","failed_side":%q,"error":%q}`,
+			time.Now().Format(time.RFC3339Nano), SERVICE_NAME, side, err.Error(),
+		)
+	}
+
+	matches := fuseRRF(req.KnowledgeBases, req.TopK, c.rrfK, vec, lex.matches)
+	log.Printf(
+		`{"timestamp":"%s","level":"info","service":"%s","component":"HybridRAGClient","method":"GetContext","query_text":%q,"top_k":%d,"vector_candidates":%d,"keyword_candidates":%d,"match_count":%d}`,
+		time.Now().Format(time.RFC3339Nano), SERVICE_NAME, req.QueryText, req.TopK, len(vec), len(lex.matches), len(matches),
+	)
+	return matches, nil
+}
+
+// fuseRRF merges best-first ranked lists per KB: each match scores
+// sum(1 / (k + rank)) over the lists it appears in (rank is 1-based within
+// its KB), and the top topK per KB are kept in request KB order.
+func fuseRRF(kbs []string, topK, k int, lists ...[]VectorQueryMatch) []VectorQueryMatch {
+	out := make([]VectorQueryMatch, 0, len(kbs)*topK)
+	for _, kb := range kbs {
+		fused := map[string]*VectorQueryMatch{}
+		var order []string
+		for _, list := range lists {
+			rank := 0
+			for _, m := range list {
+				if m.KnowledgeBase != kb {
+					continue
+				}
+				rank++
+				f, ok := fused[m.ID]
+				if !ok {
+					m := m
+					m.Score = 0
+					f = &m
+					fused[m.ID] = f
+					order = append(order, m.ID)
+				}
+				f.Score += 1 / float64(k+rank)
+			}
+		}
+		merged := make([]VectorQueryMatch, 0, len(order))
+		for _, id := range order {
+			merged = append(merged, *fused[id])
+		}
+		sort.SliceStable(merged, func(i, j int) bool { return merged[i].Score > merged[j].Score })
+		if len(merged) > topK {
+			merged = merged[:topK]
+		}
+		out = append(out, merged...)
+	}
+	return out
+}
+
+// bm25Index is an in-process inverted index with Okapi BM25 scoring, one
+// shard per KB so document frequencies are not skewed across KBs.
+type bm25Index struct {
+	shards map[string]*bm25Shard
+}
+
+type bm25Shard struct {
+	docs     []int // indices into the caller's document slice
+	lengths  []int
+	avgLen   float64
+	postings map[string][]bm25Posting
+}
+
+type bm25Posting struct {
+	doc int // index into shard.docs
+	tf  int
+}
+
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// newBM25Index indexes texts; kbOf(i) names the KB of texts[i].
+func newBM25Index(texts []string, kbOf func(i int) string) *bm25Index {
+	idx := &bm25Index{shards: map[string]*bm25Shard{}}
+	for i, text := range texts {
+		kb := kbOf(i)
+		shard := idx.shards[kb]
+		if shard == nil {
+			shard = &bm25Shard{postings: map[string][]bm25Posting{}}
+			idx.shards[kb] = shard
+		}
+		terms := lexicalTokens(text)
+		tf := map[string]int{}
+		for _, t := range terms {
+			tf[t]++
+		}
+		local := len(shard.docs)
+		shard.docs = append(shard.docs, i)
+		shard.lengths = append(shard.lengths, len(terms))
+		for t, n := range tf {
+			shard.postings[t] = append(shard.postings[t], bm25Posting{doc: local, tf: n})
+		}
+	}
+	for _, shard := range idx.shards {
+		total := 0
+		for _, l := range shard.lengths {
+			total += l
+		}
+		if len(shard.lengths) > 0 {
+			shard.avgLen = float64(total) / float64(len(shard.lengths))
+		}
+	}
+	return idx
+}
+
+type bm25Hit struct {
+	doc   int // index into the caller's document slice
+	score float64
+}
+
+// search returns up to k documents of kb that share a term with query,
+// best-first. allow, if non-nil, drops documents (by caller index) before
+// ranking.
+func (idx *bm25Index) search(kb, query string, k int, allow func(doc int) bool) []bm25Hit {
+	shard := idx.shards[kb]
+	if shard == nil {
+		return nil
+	}
+	n := float64(len(shard.docs))
+	scores := map[int]float64{}
+	seen := map[string]bool{}
+	for _, term := range lexicalTokens(query) {
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+		postings := shard.postings[term]
+		if len(postings) == 0 {
+			continue
+		}
+		df := float64(len(postings))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		for _, p := range postings {
+			tf := float64(p.tf)
+			norm := 1 - bm25B + bm25B*float64(shard.lengths[p.doc])/shard.avgLen
+			scores[p.doc] += idf * tf * (bm25K1 + 1) / (tf + bm25K1*norm)
+		}
+	}
+
+	hits := make([]bm25Hit, 0, len(scores))
+	for local, score := range scores {
+		if allow != nil && !allow(shard.docs[local]) {
+			continue
+		}
+		hits = append(hits, bm25Hit{doc: shard.docs[local], score: score})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].score != hits[j].score {
+			return hits[i].score > hits[j].score
+		}
+		return hits[i].doc < hits[j].doc
+	})
+	if len(hits) > k {
+		hits = hits[:k]
+	}
+	return hits
+}
+
+// lexicalTokens lower-cases text and splits it into words. Identifiers joined
+// by _ - . : / (ERR_CONN_RESET, E-1042, pagi.gateway) are kept whole as well as
+// split into their parts, so both exact codes and their pieces match.
+func lexicalTokens(text string) []string {
+	isJoiner := func(r rune) bool { return strings.ContainsRune("_-.:/", r) }
+	var out []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !isJoiner(r)
+	}) {
+		word = strings.TrimFunc(word, isJoiner)
+		if word == "" {
+			continue
+		}
+		out = append(out, word)
+		if strings.IndexFunc(word, isJoiner) >= 0 {
+			for _, part := range strings.FieldsFunc(word, isJoiner) {
+				out = append(out, part)
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestLexicalTokens_KeepsIdentifiersWhole(t *testing.T) {
+	got := lexicalTokens("Retry on ERR_CONN_RESET (see pagi.gateway).")
+	want := []string{"retry", "on", "err_conn_reset", "err", "conn", "reset", "see", "pagi.gateway", "pagi", "gateway"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("lexicalTokens = %q, want %q", got, want)
+	}
+}
+
+func TestBM25Index_RanksExactIdentifierFirst(t *testing.T) {
+	texts := []string{
+		"Error E-1041 means the sandbox timed out",
+		"Error E-1042 means the tool is not registered",
+		"Generic error handling guidance for tools",
+		"E-1042 in Soul-KB",
+	}
+	kbs := []string{"Domain-KB", "Domain-KB", "Domain-KB", "Soul-KB"}
+	idx := newBM25Index(texts, func(i int) string { return kbs[i] })
+
+	hits := idx.search("Domain-KB", "what does e-1042 mean?", 3, nil)
+	if len(hits) == 0 || hits[0].doc != 1 {
+		t.Fatalf("hits = %+v, want doc 1 first", hits)
+	}
+	for _, h := range hits {
+		if kbs[h.doc] != "Domain-KB" {
+			t.Errorf("hit %d from %s leaked into Domain-KB results", h.doc, kbs[h.doc])
+		}
+	}
+	if hits := idx.search("Body-KB", "e-1042", 3, nil); len(hits) != 0 {
+		t.Errorf("unknown KB returned hits: %+v", hits)
+	}
+}
+
+func TestFuseRRF(t *testing.T) {
+	m := func(id, kb string) VectorQueryMatch {
+		return VectorQueryMatch{ID: id, KnowledgeBase: kb, Text: id}
+	}
+	vector := []VectorQueryMatch{m("a", "Domain-KB"), m("b", "Domain-KB"), m("c", "Domain-KB"), m("x", "Body-KB")}
+	keyword := []VectorQueryMatch{m("c", "Domain-KB"), m("b", "Domain-KB"), m("y", "Body-KB")}
+
+	got := fuseRRF([]string{"Domain-KB", "Body-KB"}, 2, 60, vector, keyword)
+	var ids []string
+	for _, g := range got {
+		ids = append(ids, g.KnowledgeBase+"/"+g.ID)
+	}
+	// c: 1/63+1/61 edges out b: 2/62; a (1/61) appears in one list only. In
+	// Body-KB x and y tie; x was seen first.
+	want := []string{"Domain-KB/c", "Domain-KB/b", "Body-KB/x", "Body-KB/y"}
+	if !reflect.DeepEqual(ids, want) {
+		t.Fatalf("fused = %v, want %v", ids, want)
+	}
+	if want := 1.0/63 + 1.0/61; got[0].Score != want {
+		t.Errorf("fused score = %v, want %v", got[0].Score, want)
+	}
+}
+
+type stubRAG struct {
+	matches []VectorQueryMatch
+	err     error
+	gotTopK int
+}
+
+func (s *stubRAG) GetContext(_ context.Context, req VectorQueryRequest) ([]VectorQueryMatch, error) {
+	s.gotTopK = req.TopK
+	return s.matches, s.err
+}
+
+func (s *stubRAG) KeywordSearch(ctx context.Context, req VectorQueryRequest) ([]VectorQueryMatch, error) {
+	return s.GetContext(ctx, req)
+}
+
+func TestHybridRAGClient_WidensAndDegrades(t *testing.T) {
+	vector := &stubRAG{matches: []VectorQueryMatch{{ID: "v", KnowledgeBase: "Body-KB"}}}
+	keyword := &stubRAG{err: errors.New("fts index missing")}
+	c := newHybridRAGClient(defaultConfig().RAG, vector, keyword)
+
+	got, err := c.GetContext(context.Background(), VectorQueryRequest{QueryText: "q", TopK: 2})
+	if err != nil {
+		t.Fatalf("GetContext: %v", err)
+	}
+	if len(got) != 1 || got[0].ID != "v" {
+		t.Fatalf("expected vector results when keyword search fails, got %+v", got)
+	}
+	if vector.gotTopK != 6 || keyword.gotTopK != 6 {
+		t.Errorf("candidate top_k = %d/%d, want 6", vector.gotTopK, keyword.gotTopK)
+	}
+
+	vector.err = errors.New("down")
+	if _, err := c.GetContext(context.Background(), VectorQueryRequest{QueryText: "q"}); err == nil {
+		t.Fatal("expected an error when both sides fail")
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+
+	"backend-go-model-gateway/pkg/promptguard"
+)
+
+// ragInjectionMode parses RAG_INJECTION: quarantine (default) drops matches
+// that look like prompt injection from GetPlan's prompt, flag only logs them,
+// off skips the scan.
+func ragInjectionMode(cfg RAGConfig) (string, error) {
+	v := cfg.Injection
+	mode, ok := promptguard.ParseMode(v)
+	if !ok {
+		return "", fmt.Errorf("RAG_INJECTION: want quarantine, flag or off, got %q", v)
+	}
+	return mode, nil
+}
+
+// flaggedMatch is a match promptguard flagged, with the rules it broke.
+type flaggedMatch struct {
+	match VectorQueryMatch
+	rules []string
+}
+
+// screenMatches scans matches for prompt injection (pkg/promptguard). It
+// returns the matches to put in the prompt, which leave out the flagged ones
+// in quarantine mode ("" included), and the flagged ones.
+func screenMatches(matches []VectorQueryMatch, mode string) ([]VectorQueryMatch, []flaggedMatch) {
+	if mode == promptguard.ModeOff {
+		return matches, nil
+	}
+	var flagged []flaggedMatch
+	kept := make([]VectorQueryMatch, 0, len(matches))
+	for _, m := range matches {
+		if rules := promptguard.ScanMatch(m.KnowledgeBase, m.Text); len(rules) > 0 {
+			flagged = append(flagged, flaggedMatch{match: m, rules: rules})
+			if mode != promptguard.ModeFlag {
+				continue
+			}
+		}
+		kept = append(kept, m)
+	}
+	return kept, flagged
+}
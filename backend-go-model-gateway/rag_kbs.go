@@ -0,0 +1,314 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ragKBAdmin is implemented by RAG backends that can manage knowledge-base
+// storage (a collection, class or table per KB, or a KB label).
+type ragKBAdmin interface {
+	// CreateKB creates kb's storage for dims-dimensional embeddings unless it
+	// already exists, so pre-existing collections can be registered.
+	CreateKB(ctx context.Context, kb string, dims int) error
+	// DropKB deletes kb's storage and every document in it.
+	DropKB(ctx context.Context, kb string) error
+	KBStats(ctx context.Context, kb string) (kbStats, error)
+}
+
+// kbStats describes a KB's contents. Documents counts stored records (chunks).
+// LastUpdated is the newest created_at, zero when the backend cannot tell.
+type kbStats struct {
+	Documents   int64     `json:"documents"`
+	LastUpdated time.Time `json:"last_updated,omitzero"`
+}
+
+// kbNamePattern keeps KB names valid once derived into collection, class and
+// table names in every backend.
+var kbNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]{0,62}$`)
+
+// kbCatalog is the set of KBs the gateway knows about: GetPlan retrieves from
+// all of them, and ingestion only writes to them. It is seeded from
+// RAG_KNOWLEDGE_BASES and, when KB_CATALOG_PATH is set, persisted there so
+// KBs created through the API survive restarts.
+type kbCatalog struct {
+	mu    sync.RWMutex
+	path  string
+	names []string
+}
+
+// defaultKnowledgeBases are the conceptual KBs GetPlan has always searched.
+// Mind-KB (planner playbooks) is retrieved by the planner itself.
+var defaultKnowledgeBases = []string{"Domain-KB", "Body-KB", "Soul-KB"}
+
+// newKBCatalog loads the catalog.
+//
+//   - RAG_KNOWLEDGE_BASES (default: Domain-KB,Body-KB,Soul-KB) — initial KBs
+//   - KB_CATALOG_PATH (optional) — JSON file; once written it takes precedence
+//     over RAG_KNOWLEDGE_BASES
+func newKBCatalog(cfg RAGConfig) (*kbCatalog, error) {
+	c := &kbCatalog{path: cfg.KBCatalogPath, names: slices.Clone(cfg.KnowledgeBases)}
+	if len(c.names) == 0 {
+		c.names = slices.Clone(defaultKnowledgeBases)
+	}
+
+	if c.path != "" {
+		b, err := os.ReadFile(c.path)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+		case err != nil:
+			return nil, fmt.Errorf("KB_CATALOG_PATH: %w", err)
+		default:
+			var file struct {
+				KnowledgeBases []string `json:"knowledge_bases"`
+			}
+			if err := json.Unmarshal(b, &file); err != nil {
+				return nil, fmt.Errorf("KB_CATALOG_PATH %s: %w", c.path, err)
+			}
+			c.names = file.KnowledgeBases
+		}
+	}
+	for _, kb := range c.names {
+		if !kbNamePattern.MatchString(kb) {
+			return nil, fmt.Errorf("invalid knowledge base name %q", kb)
+		}
+	}
+	return c, nil
+}
+
+// Names returns the catalogued KBs in creation order. A nil catalog holds the
+// defaults.
+func (c *kbCatalog) Names() []string {
+	if c == nil {
+		return slices.Clone(defaultKnowledgeBases)
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return slices.Clone(c.names)
+}
+
+func (c *kbCatalog) Has(kb string) bool {
+	return slices.Contains(c.Names(), kb)
+}
+
+// update applies fn to the names and persists the result.
+func (c *kbCatalog) update(fn func([]string) []string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	names := fn(slices.Clone(c.names))
+	if c.path != "" {
+		b, err := json.MarshalIndent(map[string]any{"knowledge_bases": names}, "", "  ")
+		if err != nil {
+			return err
+		}
+		// Write-then-rename so a crash never leaves a truncated catalog.
+		tmp, err := os.CreateTemp(filepath.Dir(c.path), ".kb-catalog-*")
+		if err != nil {
+			return fmt.Errorf("save KB catalog: %w", err)
+		}
+		defer os.Remove(tmp.Name())
+		if _, err := tmp.Write(append(b, '\n')); err != nil {
+			tmp.Close()
+			return fmt.Errorf("save KB catalog: %w", err)
+		}
+		if err := tmp.Close(); err != nil {
+			return fmt.Errorf("save KB catalog: %w", err)
+		}
+		if err := os.Rename(tmp.Name(), c.path); err != nil {
+			return fmt.Errorf("save KB catalog: %w", err)
+		}
+	}
+	c.names = names
+	return nil
+}
+
+func (c *kbCatalog) Add(kb string) error {
+	return c.update(func(names []string) []string {
+		if slices.Contains(names, kb) {
+			return names
+		}
+		return append(names, kb)
+	})
+}
+
+func (c *kbCatalog) Remove(kb string) error {
+	return c.update(func(names []string) []string {
+		return slices.DeleteFunc(names, func(n string) bool { return n == kb })
+	})
+}
+
+// kbService serves the knowledge-base management API:
+//
+//	GET    /api/v1/kbs         list KBs with their stats
+//	POST   /api/v1/kbs         create a KB: {"name": "...", "dimensions": 768}
+//	GET    /api/v1/kbs/{name}  describe one KB
+//	DELETE /api/v1/kbs/{name}  delete a KB and its documents
+type kbService struct {
+	catalog *kbCatalog
+	// admin is nil when the backend cannot manage KBs (RAG_BACKEND=memory);
+	// the catalog is then read-only and KBs carry no stats.
+	admin ragKBAdmin
+	// embedder measures the embedding dimension when a create request does
+	// not give one. It is nil when the backend vectorizes documents itself.
+	embedder Embedder
+	// cache is invalidated for a deleted KB.
+	cache *cachingRAGClient
+}
+
+// newKBService wires the catalog to the active backend.
+func newKBService(catalog *kbCatalog, b *ragBackend) *kbService {
+	s := &kbService{catalog: catalog}
+	if b != nil {
+		s.admin = b.kbAdmin
+		s.embedder = b.embedder
+		s.cache = b.cache
+	}
+	return s
+}
+
+type kbInfo struct {
+	Name  string   `json:"name"`
+	Stats *kbStats `json:"stats,omitempty"`
+	// Error reports why stats are missing, e.g. a collection that was
+	// deleted behind the gateway's back.
+	Error string `json:"error,omitempty"`
+}
+
+type kbCreateRequest struct {
+	Name string `json:"name"`
+	// Dimensions defaults to the configured embedder's output size.
+	Dimensions int `json:"dimensions"`
+}
+
+func (s *kbService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/kbs"), "/")
+
+	switch {
+	case name == "" && r.Method == http.MethodGet:
+		names := s.catalog.Names()
+		infos := make([]kbInfo, len(names))
+		for i, kb := range names {
+			infos[i] = s.describe(r.Context(), kb)
+		}
+		_ = json.NewEncoder(w).Encode(infos)
+
+	case name == "" && r.Method == http.MethodPost:
+		var req kbCreateRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&req); err != nil {
+			writeKBError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+			return
+		}
+		s.create(w, r.Context(), req)
+
+	case name != "" && r.Method == http.MethodGet:
+		if !s.catalog.Has(name) {
+			writeKBError(w, http.StatusNotFound, fmt.Sprintf("unknown knowledge base %q", name))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(s.describe(r.Context(), name))
+
+	case name != "" && r.Method == http.MethodDelete:
+		s.delete(w, r.Context(), name)
+
+	default:
+		writeKBError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (s *kbService) describe(ctx context.Context, kb string) kbInfo {
+	info := kbInfo{Name: kb}
+	if s.admin == nil {
+		return info
+	}
+	stats, err := s.admin.KBStats(ctx, kb)
+	if err != nil {
+		info.Error = err.Error()
+		return info
+	}
+	info.Stats = &stats
+	return info
+}
+
+func (s *kbService) create(w http.ResponseWriter, ctx context.Context, req kbCreateRequest) {
+	if s.admin == nil {
+		writeKBError(w, http.StatusNotImplemented, "the active RAG backend does not support knowledge-base management")
+		return
+	}
+	if !kbNamePattern.MatchString(req.Name) {
+		writeKBError(w, http.StatusBadRequest, fmt.Sprintf("invalid knowledge base name %q (letters, digits, '-' and '_', starting with a letter)", req.Name))
+		return
+	}
+	if s.catalog.Has(req.Name) {
+		writeKBError(w, http.StatusConflict, fmt.Sprintf("knowledge base %q already exists", req.Name))
+		return
+	}
+	if req.Dimensions < 0 {
+		writeKBError(w, http.StatusBadRequest, "dimensions must be positive")
+		return
+	}
+	if req.Dimensions == 0 && s.embedder != nil {
+		probe, err := s.embedder.Embed(ctx, "dimension probe")
+		if err != nil {
+			writeKBError(w, http.StatusBadGateway, "measure embedding dimensions: "+err.Error())
+			return
+		}
+		req.Dimensions = len(probe)
+	}
+
+	if err := s.admin.CreateKB(ctx, req.Name, req.Dimensions); err != nil {
+		writeKBError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	if err := s.catalog.Add(req.Name); err != nil {
+		writeKBError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	log.Printf(
+		`{"timestamp":"%s","level":"info","service":"%s","component":"KnowledgeBases","action":"create","knowledge_base":%q,"dimensions":%d}`,
+		time.Now().Format(time.RFC3339Nano), SERVICE_NAME, req.Name, req.Dimensions,
+	)
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(s.describe(ctx, req.Name))
+}
+
+func (s *kbService) delete(w http.ResponseWriter, ctx context.Context, kb string) {
+	if s.admin == nil {
+		writeKBError(w, http.StatusNotImplemented, "the active RAG backend does not support knowledge-base management")
+		return
+	}
+	if !s.catalog.Has(kb) {
+		writeKBError(w, http.StatusNotFound, fmt.Sprintf("unknown knowledge base %q", kb))
+		return
+	}
+	if err := s.admin.DropKB(ctx, kb); err != nil {
+		writeKBError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	s.cache.Invalidate(kb)
+	if err := s.catalog.Remove(kb); err != nil {
+		writeKBError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	log.Printf(
+		`{"timestamp":"%s","level":"info","service":"%s","component":"KnowledgeBases","action":"delete","knowledge_base":%q}`,
+		time.Now().Format(time.RFC3339Nano), SERVICE_NAME, kb,
+	)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeKBError(w http.ResponseWriter, status int, msg string) {
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]any{"error": msg})
+}
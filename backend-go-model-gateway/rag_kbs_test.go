@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestKBCatalog_PersistsChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kbs.json")
+	t.Setenv("KB_CATALOG_PATH", path)
+	t.Setenv("RAG_KNOWLEDGE_BASES", "Domain-KB, Body-KB")
+
+	c, err := newKBCatalog(envConfig(t).RAG)
+	if err != nil {
+		t.Fatalf("newKBCatalog: %v", err)
+	}
+	if err := c.Add("Legal-KB"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Remove("Domain-KB"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The saved catalog takes precedence over RAG_KNOWLEDGE_BASES.
+	reloaded, err := newKBCatalog(envConfig(t).RAG)
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if got := reloaded.Names(); !slices.Equal(got, []string{"Body-KB", "Legal-KB"}) {
+		t.Errorf("reloaded names = %v", got)
+	}
+
+	t.Setenv("KB_CATALOG_PATH", "")
+	t.Setenv("RAG_KNOWLEDGE_BASES", "Body KB")
+	if _, err := newKBCatalog(envConfig(t).RAG); err == nil {
+		t.Error("expected an invalid KB name to be rejected")
+	}
+	if got := (*kbCatalog)(nil).Names(); !slices.Equal(got, defaultKnowledgeBases) {
+		t.Errorf("nil catalog names = %v", got)
+	}
+}
+
+func kbRequest(t *testing.T, method, url string, body any) (int, []byte) {
+	t.Helper()
+	var r io.Reader = http.NoBody
+	if body != nil {
+		b, _ := json.Marshal(body)
+		r = bytes.NewReader(b)
+	}
+	req, _ := http.NewRequest(method, url, r)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, url, err)
+	}
+	defer resp.Body.Close()
+	var buf bytes.Buffer
+	_, _ = buf.ReadFrom(resp.Body)
+	return resp.StatusCode, buf.Bytes()
+}
+
+func TestKBService_Lifecycle(t *testing.T) {
+	ec, err := LoadEmbeddedRAGClient(context.Background(), writeCorpus(t,
+		`{"id":"b1","kb":"Body-KB","text":"Sleep eight hours","created_at":"2026-01-02T00:00:00Z"}`,
+		`{"id":"b2","kb":"Body-KB","text":"Drink water","created_at":"2026-03-04T00:00:00Z"}`,
+	), hashEmbedder{dims: 32})
+	if err != nil {
+		t.Fatalf("LoadEmbeddedRAGClient: %v", err)
+	}
+	backend := &ragBackend{name: ragBackendEmbedded, client: ec, ingester: ec, kbAdmin: ec, embedder: ec.embedder}
+	catalog := &kbCatalog{names: []string{"Body-KB"}}
+	ingest := &ingestService{ingester: ec, embedder: ec.embedder, kbs: catalog, chunkSize: 200, chunkOverlap: 20, maxBytes: 1 << 20}
+	srv := httptest.NewServer(NewHTTPMux(ec, adminRoutes{ingest: ingest, kbs: newKBService(catalog, backend)}))
+	t.Cleanup(srv.Close)
+
+	code, body := kbRequest(t, http.MethodGet, srv.URL+"/api/v1/kbs", nil)
+	if code != http.StatusOK || !strings.Contains(string(body), `"name":"Body-KB","stats":{"documents":2,"last_updated":"2026-03-04T00:00:00Z"}`) {
+		t.Fatalf("list: %d %s", code, body)
+	}
+
+	// Ingestion only writes to catalogued KBs.
+	doc := map[string]any{"kb": "Legal-KB", "content": "Contracts renew yearly."}
+	if code, body := kbRequest(t, http.MethodPost, srv.URL+"/api/v1/ingest", doc); code != http.StatusBadRequest {
+		t.Fatalf("ingest into unknown KB: %d %s", code, body)
+	}
+	if code, body := kbRequest(t, http.MethodPost, srv.URL+"/api/v1/kbs", map[string]any{"name": "Legal-KB"}); code != http.StatusCreated {
+		t.Fatalf("create: %d %s", code, body)
+	}
+	if code, _ := kbRequest(t, http.MethodPost, srv.URL+"/api/v1/kbs", map[string]any{"name": "Legal-KB"}); code != http.StatusConflict {
+		t.Errorf("duplicate create: got %d, want 409", code)
+	}
+	if code, _ := kbRequest(t, http.MethodPost, srv.URL+"/api/v1/kbs", map[string]any{"name": "legal kb"}); code != http.StatusBadRequest {
+		t.Errorf("invalid name: got %d, want 400", code)
+	}
+	if code, body := kbRequest(t, http.MethodPost, srv.URL+"/api/v1/ingest", doc); code != http.StatusOK {
+		t.Fatalf("ingest: %d %s", code, body)
+	}
+
+	code, body = kbRequest(t, http.MethodGet, srv.URL+"/api/v1/kbs/Legal-KB", nil)
+	var info kbInfo
+	_ = json.Unmarshal(body, &info)
+	if code != http.StatusOK || info.Stats == nil || info.Stats.Documents != 1 || info.Stats.LastUpdated.IsZero() {
+		t.Fatalf("describe: %d %s", code, body)
+	}
+
+	if code, body := kbRequest(t, http.MethodDelete, srv.URL+"/api/v1/kbs/Legal-KB", nil); code != http.StatusNoContent {
+		t.Fatalf("delete: %d %s", code, body)
+	}
+	if code, _ := kbRequest(t, http.MethodGet, srv.URL+"/api/v1/kbs/Legal-KB", nil); code != http.StatusNotFound {
+		t.Errorf("describe after delete: got %d, want 404", code)
+	}
+	if st, _ := ec.KBStats(context.Background(), "Legal-KB"); st.Documents != 0 {
+		t.Errorf("Legal-KB still holds %d documents after delete", st.Documents)
+	}
+	if got := catalog.Names(); !slices.Equal(got, []string{"Body-KB"}) {
+		t.Errorf("catalog = %v", got)
+	}
+}
+
+func TestKBService_MemoryBackendIsReadOnly(t *testing.T) {
+	srv := httptest.NewServer(NewHTTPMux(fakeRAGClient{}, adminRoutes{kbs: newKBService(nil, &ragBackend{name: ragBackendMemory})}))
+	t.Cleanup(srv.Close)
+
+	code, body := kbRequest(t, http.MethodGet, srv.URL+"/api/v1/kbs", nil)
+	if code != http.StatusOK || string(body) != `[{"name":"Domain-KB"},{"name":"Body-KB"},{"name":"Soul-KB"}]`+"\n" {
+		t.Errorf("list: %d %s", code, body)
+	}
+	if code, _ := kbRequest(t, http.MethodPost, srv.URL+"/api/v1/kbs", map[string]any{"name": "Legal-KB"}); code != http.StatusNotImplemented {
+		t.Errorf("create: got %d, want 501", code)
+	}
+}
+
+func TestQdrantRAGClient_CreateKB(t *testing.T) {
+	var mu sync.Mutex
+	var calls []string
+	var created map[string]any
+	qdrant := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		switch {
+		case r.Method == http.MethodGet:
+			http.Error(w, `{"status":{"error":"Not found"}}`, http.StatusNotFound)
+		case r.URL.Path == "/collections/pagi_legal_kb" && r.Method == http.MethodPut:
+			_ = json.NewDecoder(r.Body).Decode(&created)
+			_, _ = w.Write([]byte(`{"result":true}`))
+		default:
+			_, _ = w.Write([]byte(`{"result":{}}`))
+		}
+	}))
+	t.Cleanup(qdrant.Close)
+	t.Setenv("QDRANT_URL", qdrant.URL)
+	t.Setenv("QDRANT_COLLECTION_PREFIX", "pagi_")
+
+	c, err := NewQdrantRAGClient(envConfig(t).RAG, nil, fakeEmbedder{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.CreateKB(context.Background(), "Legal-KB", 3); err != nil {
+		t.Fatalf("CreateKB: %v", err)
+	}
+	b, _ := json.Marshal(created)
+	if string(b) != `{"vectors":{"distance":"Cosine","size":3}}` {
+		t.Errorf("create body = %s", b)
+	}
+	// GET, PUT, then one payload index per metadata field.
+	if len(calls) != 7 {
+		t.Errorf("calls = %v", calls)
+	}
+}
@@ -0,0 +1,343 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode"
+
+	"backend-go-model-gateway/internal/logger"
+	"backend-go-model-gateway/pkg/secrets"
+
+	"github.com/sashabaranov/go-openai"
+	"golang.org/x/text/unicode/norm"
+)
+
+// kbLanguages tags KBs with the language of their documents (RAG_KB_LANGUAGES).
+// Untagged KBs are in the default language.
+type kbLanguages struct {
+	byKB     map[string]string
+	fallback string
+}
+
+// kbLanguagesFromConfig reads the KB language tags.
+//
+//   - RAG_DEFAULT_LANGUAGE (default: en) — the language of untagged KBs
+//   - RAG_KB_LANGUAGES (optional) — comma-separated kb=lang pairs, e.g.
+//     Domain-KB-es=es,Body-KB-es=es; lang is an ISO 639-1 code
+func kbLanguagesFromConfig(cfg RAGConfig) (kbLanguages, error) {
+	l := kbLanguages{fallback: strings.ToLower(cfg.DefaultLanguage)}
+	tags, err := parseKeyValueList("RAG_KB_LANGUAGES", cfg.KBLanguages)
+	if err != nil {
+		return l, err
+	}
+	l.byKB = make(map[string]string, len(tags))
+	for kb, lang := range tags {
+		l.byKB[kb] = strings.ToLower(lang)
+	}
+	return l, nil
+}
+
+// of returns the language of kb's documents.
+func (l kbLanguages) of(kb string) string {
+	if lang, ok := l.byKB[kb]; ok {
+		return lang
+	}
+	return l.fallback
+}
+
+// variant returns kb's copy in lang: the KB named "<kb>-<lang>", when it is
+// tagged lang.
+func (l kbLanguages) variant(kb, lang string) (string, bool) {
+	v := kb + "-" + lang
+	return v, l.byKB[v] == lang
+}
+
+// queryTranslator translates a search query into another language.
+type queryTranslator interface {
+	Translate(ctx context.Context, text, from, to string) (string, error)
+}
+
+// multilingualRAGClient makes non-English (more generally, non-KB-language)
+// prompts retrieve sensibly. It normalizes the query (Unicode NFKC, collapsed
+// whitespace) and detects its language, then for each requested KB:
+//
+//   - when the KB has a variant in the query's language ("<kb>-<lang>", see
+//     kbLanguages), it searches the variant with the query as is;
+//   - otherwise, with translation on, it searches the KB with the query
+//     translated into the KB's language;
+//   - otherwise it searches the KB with the query as is.
+//
+// KBs are searched with their language's embedding model (EMBEDDINGS_MODELS).
+// Matches from a variant are reported under the requested KB, so results keep
+// the GetContext layout.
+type multilingualRAGClient struct {
+	next      RAGContextClient
+	languages kbLanguages
+	// minConfidence is the detection confidence below which the query is
+	// taken to be in the default language.
+	minConfidence float64
+	// translator is nil when translation is off.
+	translator queryTranslator
+}
+
+// newMultilingualRAGClient wraps next for RAG_MULTILINGUAL=on, or returns nil
+// when it is off.
+//
+//   - RAG_MULTILINGUAL (default: off) — on or off
+//   - RAG_LANGUAGE_MIN_CONFIDENCE (default: 0.5) — detection confidence needed
+//     to treat a query as non-default-language
+//   - RAG_QUERY_TRANSLATION (default: off) — off, or llm: the gateway's own
+//     LLM_PROVIDER (llmCfg) translates queries for KBs without a variant
+func newMultilingualRAGClient(ctx context.Context, store *secrets.Store, cfg RAGConfig, llmCfg LLMConfig, next RAGContextClient, languages kbLanguages) (*multilingualRAGClient, error) {
+	if !cfg.Multilingual {
+		return nil, nil
+	}
+	if cfg.LanguageMinConfidence < 0 || cfg.LanguageMinConfidence > 1 {
+		return nil, fmt.Errorf("RAG_LANGUAGE_MIN_CONFIDENCE: want a number in [0, 1], got %g", cfg.LanguageMinConfidence)
+	}
+	c := &multilingualRAGClient{next: next, languages: languages, minConfidence: cfg.LanguageMinConfidence}
+
+	switch mode := strings.ToLower(cfg.QueryTranslation); mode {
+	case "off", "":
+	case "llm":
+		llm, err := newLLMClient(ctx, store, llmCfg)
+		if err != nil {
+			return nil, fmt.Errorf("RAG_QUERY_TRANSLATION=llm: %w", err)
+		}
+		if llm.Client == nil {
+			return nil, fmt.Errorf("RAG_QUERY_TRANSLATION=llm needs a real LLM_PROVIDER, not %q", llm.Provider)
+		}
+		c.translator = llmTranslator{client: llm.Client, model: llm.Model}
+	default:
+		return nil, fmt.Errorf("unsupported RAG_QUERY_TRANSLATION=%q (supported: off, llm)", mode)
+	}
+	return c, nil
+}
+
+// kbRoute is how one requested KB is searched.
+type kbRoute struct {
+	kb, target, lang, query string
+}
+
+func (c *multilingualRAGClient) GetContext(ctx context.Context, req VectorQueryRequest) ([]VectorQueryMatch, error) {
+	if len(req.KnowledgeBases) == 0 {
+		req.KnowledgeBases = []string{defaultRAGKnowledgeBase}
+	}
+	query := normalizeQuery(req.QueryText)
+	lang, confidence := detectLanguage(query)
+	if lang == "" || confidence < c.minConfidence {
+		lang = c.languages.fallback
+	}
+
+	routes := make([]kbRoute, len(req.KnowledgeBases))
+	translations := map[string]string{}
+	var translateErr error
+	for i, kb := range req.KnowledgeBases {
+		r := kbRoute{kb: kb, target: kb, lang: c.languages.of(kb), query: query}
+		if v, ok := c.languages.variant(kb, lang); ok && r.lang != lang {
+			r.target, r.lang = v, lang
+		} else if r.lang != lang && c.translator != nil && translateErr == nil {
+			t, ok := translations[r.lang]
+			if !ok {
+				if t, translateErr = c.translator.Translate(ctx, query, lang, r.lang); translateErr == nil {
+					t = normalizeQuery(t)
+					translations[r.lang] = t
+				}
+			}
+			if translateErr == nil && t != "" {
+				r.query = t
+			}
+		}
+		routes[i] = r
+	}
+
+	var routed []string
+	for _, r := range routes {
+		if r.target != r.kb || r.query != query {
+			routed = append(routed, fmt.Sprintf("%s→%s(%s)", r.kb, r.target, r.lang))
+		}
+	}
+	lg := logger.NewContextLogger(ctx)
+	lg.Info("rag_language_detected", "language", lang, "confidence", confidence, "translated", len(translations), "routes", routed)
+	if translateErr != nil {
+		lg.Warn("rag_query_translation_failed", "language", lang, "error", translateErr.Error())
+	}
+
+	// One downstream call per (language, query): backends embed the query
+	// once per call, with the language's model.
+	type group struct {
+		lang, query string
+	}
+	var order []group
+	targets := map[group][]string{}
+	for _, r := range routes {
+		g := group{r.lang, r.query}
+		if _, ok := targets[g]; !ok {
+			order = append(order, g)
+		}
+		targets[g] = append(targets[g], r.target)
+	}
+	requested := map[string]string{}
+	for _, r := range routes {
+		requested[r.target] = r.kb
+	}
+
+	byKB := map[string][]VectorQueryMatch{}
+	for _, g := range order {
+		sub := req
+		sub.QueryText = g.query
+		sub.KnowledgeBases = targets[g]
+		matches, err := c.next.GetContext(withEmbeddingLanguage(ctx, g.lang), sub)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range matches {
+			if kb, ok := requested[m.KnowledgeBase]; ok {
+				m.KnowledgeBase = kb
+			}
+			byKB[m.KnowledgeBase] = append(byKB[m.KnowledgeBase], m)
+		}
+	}
+	out := make([]VectorQueryMatch, 0, len(req.KnowledgeBases)*max(req.TopK, 1))
+	for _, kb := range req.KnowledgeBases {
+		out = append(out, byKB[kb]...)
+	}
+	return out, nil
+}
+
+// normalizeQuery folds compatibility characters (full-width letters and
+// digits, ligatures) with NFKC and collapses whitespace.
+func normalizeQuery(text string) string {
+	return strings.Join(strings.Fields(norm.NFKC.String(text)), " ")
+}
+
+// languageStopwords are frequent short words of Latin-script languages; a
+// query's language is the one whose words it uses most.
+var languageStopwords = map[string][]string{
+	"en": {"the", "and", "is", "are", "what", "how", "of", "to", "in", "for", "my", "with", "should", "do", "does", "you", "this", "that", "it", "when"},
+	"es": {"el", "la", "los", "las", "de", "que", "y", "es", "en", "por", "para", "con", "mi", "cómo", "qué", "un", "una", "del", "debo", "cuándo"},
+	"fr": {"le", "la", "les", "de", "des", "et", "est", "que", "pour", "dans", "un", "une", "mon", "comment", "quel", "du", "avec", "je", "ce", "quand"},
+	"de": {"der", "die", "das", "und", "ist", "nicht", "ich", "mein", "wie", "was", "für", "mit", "ein", "eine", "zu", "den", "auf", "sind", "soll", "wann"},
+	"it": {"il", "lo", "la", "di", "che", "e", "è", "per", "con", "mio", "come", "cosa", "un", "una", "del", "sono", "non", "gli", "devo", "quando"},
+	"pt": {"o", "a", "os", "as", "de", "que", "e", "é", "para", "com", "meu", "como", "um", "uma", "do", "da", "não", "em", "devo", "quando"},
+	"nl": {"de", "het", "een", "en", "is", "van", "ik", "mijn", "hoe", "wat", "niet", "voor", "met", "op", "zijn", "dat", "moet", "wanneer"},
+}
+
+// stopwordLanguages indexes languageStopwords by word.
+var stopwordLanguages = func() map[string][]string {
+	idx := map[string][]string{}
+	for lang, words := range languageStopwords {
+		for _, w := range words {
+			idx[w] = append(idx[w], lang)
+		}
+	}
+	return idx
+}()
+
+// scriptLanguages maps non-Latin scripts to the language a query written in
+// them is taken to be in.
+var scriptLanguages = []struct {
+	script *unicode.RangeTable
+	lang   string
+}{
+	{unicode.Hangul, "ko"},
+	{unicode.Hiragana, "ja"},
+	{unicode.Katakana, "ja"},
+	{unicode.Han, "zh"},
+	{unicode.Cyrillic, "ru"},
+	{unicode.Arabic, "ar"},
+	{unicode.Devanagari, "hi"},
+	{unicode.Greek, "el"},
+	{unicode.Hebrew, "he"},
+	{unicode.Thai, "th"},
+}
+
+// detectLanguage guesses the ISO 639-1 language of a short text, with a
+// confidence in [0, 1]. Text mostly in a non-Latin script is classified by
+// script (Han with any kana is Japanese); Latin text by its stopwords. It
+// returns "" when there is no evidence either way.
+func detectLanguage(text string) (string, float64) {
+	letters := 0
+	byScript := map[string]int{}
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		for _, s := range scriptLanguages {
+			if unicode.Is(s.script, r) {
+				byScript[s.lang]++
+				break
+			}
+		}
+	}
+	if letters == 0 {
+		return "", 0
+	}
+	if byScript["zh"] > 0 && byScript["ja"] > 0 {
+		byScript["ja"] += byScript["zh"]
+		delete(byScript, "zh")
+	}
+	best, bestN, scripted := "", 0, 0
+	for lang, n := range byScript {
+		scripted += n
+		if n > bestN || (n == bestN && lang < best) {
+			best, bestN = lang, n
+		}
+	}
+	if scripted*2 > letters {
+		return best, float64(bestN) / float64(letters)
+	}
+
+	hits := map[string]int{}
+	total := 0
+	for _, w := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !unicode.IsLetter(r) }) {
+		for _, lang := range stopwordLanguages[w] {
+			hits[lang]++
+			total++
+		}
+	}
+	best, bestN = "", 0
+	for lang, n := range hits {
+		if n > bestN || (n == bestN && lang < best) {
+			best, bestN = lang, n
+		}
+	}
+	if total == 0 {
+		return "", 0
+	}
+	return best, float64(bestN) / float64(total)
+}
+
+// llmTranslator translates queries with the gateway's chat model.
+type llmTranslator struct {
+	client chatCompleter
+	model  string
+}
+
+func (t llmTranslator) Translate(ctx context.Context, text, from, to string) (string, error) {
+	start := time.Now()
+	resp, err := t.client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model: t.model,
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: fmt.Sprintf("Translate the user's search query from language %q into language %q (ISO 639-1 codes). Keep names, identifiers and numbers unchanged. Reply with the translation only.", from, to)},
+			{Role: openai.ChatMessageRoleUser, Content: text},
+		},
+		Temperature: 0,
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Choices) == 0 || replyAnswer(resp.Choices[0].Message.Content) == "" {
+		return "", fmt.Errorf("translate: empty LLM response")
+	}
+	out := strings.TrimSpace(replyAnswer(resp.Choices[0].Message.Content))
+	log.Printf(
+		`{"timestamp":"%s","level":"info","service":"%s","component":"MultilingualRAGClient","method":"Translate","from":%q,"to":%q,"query_text":%q,"translation":%q,"latency_ms":%d}`,
+		time.Now().Format(time.RFC3339Nano), SERVICE_NAME, from, to, text, out, time.Since(start).Milliseconds(),
+	)
+	return out, nil
+}
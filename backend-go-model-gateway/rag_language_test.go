@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	for _, tc := range []struct {
+		text string
+		want string
+	}{
+		{"How should I plan my week?", "en"},
+		{"¿Cómo debo planificar mi semana?", "es"},
+		{"Comment dois-je organiser ma semaine pour le travail ?", "fr"},
+		{"Wie soll ich meine Woche planen und was ist wichtig?", "de"},
+		{"今週の予定はどうすればいいですか", "ja"},
+		{"我这周应该怎么安排", "zh"},
+		{"Как мне спланировать неделю?", "ru"},
+		{"12345 ???", ""},
+	} {
+		got, confidence := detectLanguage(tc.text)
+		if got != tc.want {
+			t.Errorf("detectLanguage(%q) = %q (%.2f), want %q", tc.text, got, confidence, tc.want)
+		}
+		if got != "" && (confidence <= 0 || confidence > 1) {
+			t.Errorf("detectLanguage(%q) confidence = %.2f", tc.text, confidence)
+		}
+	}
+}
+
+// languageRecordingRAG records each call's query, KBs and embedding language
+// and returns one match per KB.
+type languageRecordingRAG struct {
+	calls [][3]any
+}
+
+func (r *languageRecordingRAG) GetContext(ctx context.Context, req VectorQueryRequest) ([]VectorQueryMatch, error) {
+	lang, _ := ctx.Value(embeddingLanguageKey{}).(string)
+	r.calls = append(r.calls, [3]any{lang, req.QueryText, req.KnowledgeBases})
+	var out []VectorQueryMatch
+	for _, kb := range req.KnowledgeBases {
+		out = append(out, VectorQueryMatch{ID: kb + "-1", KnowledgeBase: kb})
+	}
+	return out, nil
+}
+
+type stubTranslator struct{ err error }
+
+func (s stubTranslator) Translate(_ context.Context, text, from, to string) (string, error) {
+	return "[" + from + ">" + to + "] " + text, s.err
+}
+
+func TestMultilingualRAGClient_RoutesByLanguage(t *testing.T) {
+	next := &languageRecordingRAG{}
+	c := &multilingualRAGClient{
+		next:       next,
+		languages:  kbLanguages{fallback: "en", byKB: map[string]string{"Domain-KB-es": "es"}},
+		translator: stubTranslator{},
+	}
+
+	// Domain-KB has a Spanish variant; Body-KB gets a translated query. The
+	// full-width digits are normalized.
+	got, err := c.GetContext(context.Background(), VectorQueryRequest{
+		QueryText:      "¿Cómo  debo entrenar para  una carrera de ４２ km?",
+		TopK:           1,
+		KnowledgeBases: []string{"Domain-KB", "Body-KB"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][3]any{
+		{"es", "¿Cómo debo entrenar para una carrera de 42 km?", []string{"Domain-KB-es"}},
+		{"en", "[es>en] ¿Cómo debo entrenar para una carrera de 42 km?", []string{"Body-KB"}},
+	}
+	if !reflect.DeepEqual(next.calls, want) {
+		t.Fatalf("calls = %v, want %v", next.calls, want)
+	}
+	if len(got) != 2 || got[0].KnowledgeBase != "Domain-KB" || got[0].ID != "Domain-KB-es-1" || got[1].KnowledgeBase != "Body-KB" {
+		t.Fatalf("matches = %+v, want the variant's match reported under Domain-KB", got)
+	}
+
+	// English queries go through unchanged in one call; a failing translator
+	// falls back to the original query.
+	next.calls = nil
+	c.translator = stubTranslator{err: errors.New("down")}
+	if _, err := c.GetContext(context.Background(), VectorQueryRequest{QueryText: "How do I train for a race?", KnowledgeBases: []string{"Domain-KB", "Body-KB"}}); err != nil {
+		t.Fatal(err)
+	}
+	if len(next.calls) != 1 || next.calls[0][0] != "en" || next.calls[0][1] != "How do I train for a race?" {
+		t.Fatalf("calls = %v, want one English call", next.calls)
+	}
+	next.calls = nil
+	if _, err := c.GetContext(context.Background(), VectorQueryRequest{QueryText: "¿Qué es una carrera?", KnowledgeBases: []string{"Body-KB"}}); err != nil {
+		t.Fatal(err)
+	}
+	if len(next.calls) != 1 || next.calls[0][1] != "¿Qué es una carrera?" {
+		t.Fatalf("calls = %v, want the untranslated query", next.calls)
+	}
+}
+
+func TestLanguageEmbedder_PicksModelByContext(t *testing.T) {
+	e := &languageEmbedder{
+		models:   map[string]Embedder{"es": hashEmbedder{dims: 8}},
+		fallback: hashEmbedder{dims: 4},
+	}
+	es, err := e.Embed(withEmbeddingLanguage(context.Background(), "es"), "hola")
+	if err != nil {
+		t.Fatal(err)
+	}
+	en, err := e.Embed(context.Background(), "hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(es) != 8 || len(en) != 4 {
+		t.Fatalf("got %d and %d dims, want 8 (es model) and 4 (default)", len(es), len(en))
+	}
+}
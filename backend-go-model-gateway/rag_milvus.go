@@ -0,0 +1,376 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"backend-go-model-gateway/pkg/ragfilter"
+	"backend-go-model-gateway/pkg/secrets"
+)
+
+// MilvusRAGClient implements RAGContextClient against Milvus's RESTful v2 API
+// (RAG_BACKEND=milvus), one collection per knowledge base. It avoids the Milvus
+// gRPC SDK so the gateway keeps a single gRPC stack.
+type MilvusRAGClient struct {
+	baseURL    string
+	store      *secrets.Store
+	embedder   Embedder
+	httpClient *http.Client
+
+	dbName       string
+	collections  map[string]string
+	prefix       string
+	idField      string
+	vectorField  string
+	textField    string
+	sourceField  string
+	fields       ragMetadataFields
+	metric       milvusMetric
+	searchParams map[string]any
+}
+
+// milvusMetric maps a Milvus metric type to a higher-is-better score. Milvus
+// returns similarity for COSINE and IP and a distance for L2.
+type milvusMetric struct {
+	name  string
+	score func(distance float64) float64
+}
+
+var milvusMetrics = map[string]milvusMetric{
+	"COSINE": {name: "COSINE", score: func(d float64) float64 { return d }},
+	"IP":     {name: "IP", score: func(d float64) float64 { return d }},
+	"L2":     {name: "L2", score: func(d float64) float64 { return 1 / (1 + d) }},
+}
+
+// NewMilvusRAGClient configures a MilvusRAGClient from cfg.Milvus.
+//
+//   - MILVUS_URL (default: http://localhost:19530)
+//   - MILVUS_TOKEN (optional; "user:password" or a Zilliz API key, resolved through pkg/secrets)
+//   - MILVUS_DB_NAME (optional; default database otherwise)
+//   - MILVUS_COLLECTIONS explicit KB mapping, e.g. "Domain-KB=domain,Body-KB=body"
+//   - MILVUS_COLLECTION_PREFIX prefix for derived names (Domain-KB -> <prefix>domain_kb)
+//   - MILVUS_ID_FIELD / MILVUS_VECTOR_FIELD primary key and vector fields (default: id / vector)
+//   - MILVUS_TEXT_FIELD / MILVUS_SOURCE_FIELD output fields (default: text / source)
+//   - MILVUS_METRIC_TYPE (default: COSINE) — COSINE, IP or L2; must match the index
+//   - MILVUS_SEARCH_PARAMS index search params as JSON, e.g. {"ef":64} or {"nprobe":16}
+func NewMilvusRAGClient(cfg RAGConfig, store *secrets.Store, embedder Embedder) (*MilvusRAGClient, error) {
+	mc := cfg.Milvus
+	c := &MilvusRAGClient{
+		baseURL:     strings.TrimRight(mc.URL, "/"),
+		store:       store,
+		embedder:    embedder,
+		httpClient:  &http.Client{Timeout: 10 * time.Second},
+		dbName:      mc.DBName,
+		prefix:      mc.CollectionPrefix,
+		idField:     mc.IDField,
+		vectorField: mc.VectorField,
+		textField:   mc.TextField,
+		sourceField: mc.SourceField,
+		fields:      ragMetadataFieldsFromConfig(cfg),
+	}
+	var err error
+	if c.collections, err = parseKBMapping("MILVUS_COLLECTIONS", mc.Collections, "collection"); err != nil {
+		return nil, err
+	}
+	metricName := strings.ToUpper(mc.MetricType)
+	metric, ok := milvusMetrics[metricName]
+	if !ok {
+		return nil, fmt.Errorf("unsupported MILVUS_METRIC_TYPE=%q (supported: COSINE, IP, L2)", metricName)
+	}
+	c.metric = metric
+	if v := mc.SearchParams; v != "" {
+		if err := json.Unmarshal([]byte(v), &c.searchParams); err != nil {
+			return nil, fmt.Errorf("MILVUS_SEARCH_PARAMS: %w", err)
+		}
+	}
+	return c, nil
+}
+
+// collectionFor maps a conceptual KB name to its Milvus collection. Milvus
+// names allow only letters, digits and underscores.
+func (c *MilvusRAGClient) collectionFor(kb string) string {
+	if coll, ok := c.collections[kb]; ok {
+		return coll
+	}
+	return c.prefix + strings.ToLower(strings.ReplaceAll(kb, "-", "_"))
+}
+
+type milvusSearchRequest struct {
+	DBName         string         `json:"dbName,omitempty"`
+	CollectionName string         `json:"collectionName"`
+	Data           [][]float32    `json:"data"`
+	AnnsField      string         `json:"annsField"`
+	Limit          int            `json:"limit"`
+	OutputFields   []string       `json:"outputFields"`
+	Filter         string         `json:"filter,omitempty"`
+	SearchParams   map[string]any `json:"searchParams"`
+}
+
+func (c *MilvusRAGClient) GetContext(ctx context.Context, req VectorQueryRequest) ([]VectorQueryMatch, error) {
+	if req.TopK <= 0 {
+		req.TopK = 2
+	}
+	kbs := req.KnowledgeBases
+	if len(kbs) == 0 {
+		kbs = []string{defaultRAGKnowledgeBase}
+	}
+
+	vec, err := c.embedder.Embed(ctx, req.QueryText)
+	if err != nil {
+		return nil, err
+	}
+
+	searchParams := map[string]any{"metricType": c.metric.name}
+	if c.searchParams != nil {
+		searchParams["params"] = c.searchParams
+	}
+	matches, err := searchKBs(ctx, "MilvusRAGClient", kbs, func(ctx context.Context, kb string) ([]VectorQueryMatch, error) {
+		return c.search(ctx, kb, milvusSearchRequest{
+			DBName:         c.dbName,
+			CollectionName: c.collectionFor(kb),
+			Data:           [][]float32{vec},
+			AnnsField:      c.vectorField,
+			Limit:          req.TopK,
+			OutputFields:   []string{c.textField, c.sourceField},
+			SearchParams:   searchParams,
+			Filter:         c.filter(req.Namespace, req.Filter),
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("milvus: %w", err)
+	}
+
+	log.Printf(
+		`{"timestamp":"%s","level":"info","service":"%s","component":"MilvusRAGClient","method":"GetContext","milvus_url":%q,"metric_type":%q,"query_text":%q,"top_k":%d,"match_count":%d}`,
+		time.Now().Format(time.RFC3339Nano), SERVICE_NAME, c.baseURL, c.metric.name, req.QueryText, req.TopK, len(matches),
+	)
+	return matches, nil
+}
+
+// filter translates the tenant namespace and a metadata filter into a Milvus
+// boolean expression. The tags field is expected to be an ARRAY<VARCHAR> field.
+func (c *MilvusRAGClient) filter(ns string, f *ragfilter.Filter) string {
+	// JSON string escapes are valid in Milvus expressions.
+	quote := func(v any) string {
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+	var terms []string
+	if ns != "" {
+		terms = append(terms, fmt.Sprintf("%s == %s", c.fields.namespace, quote(ns)))
+	}
+	if f == nil {
+		f = &ragfilter.Filter{}
+	}
+	if len(f.Sources) > 0 {
+		terms = append(terms, fmt.Sprintf("%s in %s", c.sourceField, quote(f.Sources)))
+	}
+	if len(f.Tags) > 0 {
+		terms = append(terms, fmt.Sprintf("array_contains_all(%s, %s)", c.fields.tags, quote(f.Tags)))
+	}
+	if len(f.DocumentIDs) > 0 {
+		terms = append(terms, fmt.Sprintf("%s in %s", c.fields.documentID, quote(f.DocumentIDs)))
+	}
+	if !f.CreatedAfter.IsZero() {
+		terms = append(terms, fmt.Sprintf("%s >= %d", c.fields.createdAt, f.CreatedAfter.Unix()))
+	}
+	if !f.CreatedBefore.IsZero() {
+		terms = append(terms, fmt.Sprintf("%s < %d", c.fields.createdAt, f.CreatedBefore.Unix()))
+	}
+	return strings.Join(terms, " and ")
+}
+
+func (c *MilvusRAGClient) search(ctx context.Context, kb string, body milvusSearchRequest) ([]VectorQueryMatch, error) {
+	coll := body.CollectionName
+	data, err := c.post(ctx, "/v2/vectordb/entities/search", body)
+	if err != nil {
+		return nil, fmt.Errorf("search %s: %w", coll, err)
+	}
+	var hits []map[string]json.RawMessage
+	if err := json.Unmarshal(data, &hits); err != nil {
+		return nil, fmt.Errorf("decode %s search response: %w", coll, err)
+	}
+
+	matches := make([]VectorQueryMatch, 0, len(hits))
+	for _, hit := range hits {
+		var text, source string
+		var distance float64
+		_ = json.Unmarshal(hit[c.textField], &text)
+		_ = json.Unmarshal(hit[c.sourceField], &source)
+		_ = json.Unmarshal(hit["distance"], &distance)
+		if source == "" {
+			source = "milvus"
+		}
+		matches = append(matches, VectorQueryMatch{
+			ID:            jsonID(hit[c.idField]),
+			Score:         c.metric.score(distance),
+			RawScore:      distance,
+			Text:          text,
+			Source:        source,
+			KnowledgeBase: kb,
+		})
+	}
+	return matches, nil
+}
+
+// post sends a request to Milvus's RESTful API and returns the response's
+// data field.
+func (c *MilvusRAGClient) post(ctx context.Context, path string, body any) (json.RawMessage, error) {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	token, err := c.store.Lookup(ctx, "MILVUS_TOKEN")
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, &httpStatusError{code: resp.StatusCode, body: strings.TrimSpace(string(msg))}
+	}
+
+	var out struct {
+		Code    int             `json:"code"`
+		Message string          `json:"message"`
+		Data    json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	// Milvus reports failures (missing collection, bad field) in the body with
+	// HTTP 200; success is code 0 (200 on older 2.3.x releases).
+	if out.Code != 0 && out.Code != http.StatusOK {
+		return nil, fmt.Errorf("code %d: %s", out.Code, out.Message)
+	}
+	return out.Data, nil
+}
+
+// ReplaceDocument deletes the document's entities by filter expression, then
+// upserts the new chunks. Chunk IDs are UUID strings, so the collection's
+// primary key must be a VARCHAR field.
+func (c *MilvusRAGClient) ReplaceDocument(ctx context.Context, kb, namespace, documentID string, chunks []ingestChunk) error {
+	coll := c.collectionFor(kb)
+	expr := c.filter(namespace, &ragfilter.Filter{DocumentIDs: []string{documentID}})
+	del := c.collectionRequest(coll)
+	del["filter"] = expr
+	if _, err := c.post(ctx, "/v2/vectordb/entities/delete", del); err != nil {
+		return fmt.Errorf("milvus: delete %s from %s: %w", documentID, coll, err)
+	}
+
+	rows := make([]map[string]any, len(chunks))
+	for i, ch := range chunks {
+		row := map[string]any{
+			c.idField:           ch.ID,
+			c.vectorField:       ch.Embedding,
+			c.textField:         ch.Text,
+			c.sourceField:       ch.Source,
+			c.fields.documentID: ch.DocumentID,
+			c.fields.tags:       append([]string{}, ch.Tags...),
+			c.fields.createdAt:  ch.CreatedAt.Unix(),
+		}
+		if ch.Namespace != "" {
+			row[c.fields.namespace] = ch.Namespace
+		}
+		rows[i] = row
+	}
+	upsert := c.collectionRequest(coll)
+	upsert["data"] = rows
+	if _, err := c.post(ctx, "/v2/vectordb/entities/upsert", upsert); err != nil {
+		return fmt.Errorf("milvus: upsert into %s: %w", coll, err)
+	}
+	return nil
+}
+
+// collectionRequest starts a request body addressing coll.
+func (c *MilvusRAGClient) collectionRequest(coll string) map[string]any {
+	body := map[string]any{"collectionName": coll}
+	if c.dbName != "" {
+		body["dbName"] = c.dbName
+	}
+	return body
+}
+
+// CreateKB creates kb's collection unless it exists: a VARCHAR primary key
+// (chunk IDs are UUIDs), the vector field with an AUTOINDEX for the configured
+// metric, and dynamic fields for text and metadata.
+func (c *MilvusRAGClient) CreateKB(ctx context.Context, kb string, dims int) error {
+	coll := c.collectionFor(kb)
+	data, err := c.post(ctx, "/v2/vectordb/collections/has", c.collectionRequest(coll))
+	if err != nil {
+		return fmt.Errorf("milvus: has %s: %w", coll, err)
+	}
+	var has struct {
+		Has bool `json:"has"`
+	}
+	if err := json.Unmarshal(data, &has); err != nil {
+		return fmt.Errorf("milvus: decode has %s: %w", coll, err)
+	}
+	if has.Has {
+		return nil
+	}
+	if dims <= 0 {
+		return fmt.Errorf("milvus: creating %s needs the embedding dimensions", coll)
+	}
+
+	body := c.collectionRequest(coll)
+	body["schema"] = map[string]any{
+		"autoId":             false,
+		"enableDynamicField": true,
+		"fields": []any{
+			map[string]any{"fieldName": c.idField, "dataType": "VarChar", "isPrimary": true, "elementTypeParams": map[string]any{"max_length": 64}},
+			map[string]any{"fieldName": c.vectorField, "dataType": "FloatVector", "elementTypeParams": map[string]any{"dim": dims}},
+		},
+	}
+	body["indexParams"] = []any{map[string]any{"fieldName": c.vectorField, "indexName": c.vectorField + "_idx", "metricType": c.metric.name, "indexType": "AUTOINDEX"}}
+	if _, err := c.post(ctx, "/v2/vectordb/collections/create", body); err != nil {
+		return fmt.Errorf("milvus: create %s: %w", coll, err)
+	}
+	return nil
+}
+
+// DropKB drops kb's collection. Milvus treats a missing collection as success.
+func (c *MilvusRAGClient) DropKB(ctx context.Context, kb string) error {
+	coll := c.collectionFor(kb)
+	if _, err := c.post(ctx, "/v2/vectordb/collections/drop", c.collectionRequest(coll)); err != nil {
+		return fmt.Errorf("milvus: drop %s: %w", coll, err)
+	}
+	return nil
+}
+
+// KBStats reports kb's row count. Milvus has no max aggregation, so
+// LastUpdated is left unknown.
+func (c *MilvusRAGClient) KBStats(ctx context.Context, kb string) (kbStats, error) {
+	coll := c.collectionFor(kb)
+	data, err := c.post(ctx, "/v2/vectordb/collections/get_stats", c.collectionRequest(coll))
+	if err != nil {
+		return kbStats{}, fmt.Errorf("milvus: stats %s: %w", coll, err)
+	}
+	var stats struct {
+		RowCount int64 `json:"rowCount"`
+	}
+	if err := json.Unmarshal(data, &stats); err != nil {
+		return kbStats{}, fmt.Errorf("milvus: decode stats %s: %w", coll, err)
+	}
+	return kbStats{Documents: stats.RowCount}, nil
+}
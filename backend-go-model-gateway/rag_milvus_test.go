@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"backend-go-model-gateway/pkg/ragfilter"
+)
+
+func TestMilvusRAGClient_SearchesCollectionPerKB(t *testing.T) {
+	var mu sync.Mutex
+	gotBodies := map[string]map[string]any{}
+	milvus := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/vectordb/entities/search" || r.Header.Get("Authorization") != "Bearer root:Milvus" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		coll, _ := body["collectionName"].(string)
+		mu.Lock()
+		gotBodies[coll] = body
+		mu.Unlock()
+
+		switch coll {
+		case "pagi_domain_kb":
+			_, _ = w.Write([]byte(`{"code":0,"data":[
+				{"id":7,"distance":0.91,"text":"Onboarding protocol","source":"handbook.md"},
+				{"id":8,"distance":0.55,"text":"Older note"}
+			]}`))
+		case "body":
+			_, _ = w.Write([]byte(`{"code":0,"data":[{"id":"doc-1","distance":0.8,"text":"Sleep 8h"}]}`))
+		default:
+			_, _ = w.Write([]byte(`{"code":100,"message":"collection not found[collection=pagi_soul_kb]"}`))
+		}
+	}))
+	t.Cleanup(milvus.Close)
+
+	t.Setenv("MILVUS_URL", milvus.URL)
+	t.Setenv("MILVUS_TOKEN", "root:Milvus")
+	t.Setenv("MILVUS_DB_NAME", "pagi")
+	t.Setenv("MILVUS_COLLECTION_PREFIX", "pagi_")
+	t.Setenv("MILVUS_COLLECTIONS", "Body-KB=body")
+	t.Setenv("MILVUS_METRIC_TYPE", "ip")
+	t.Setenv("MILVUS_SEARCH_PARAMS", `{"ef":64}`)
+
+	c, err := NewMilvusRAGClient(envConfig(t).RAG, nil, fakeEmbedder{})
+	if err != nil {
+		t.Fatalf("NewMilvusRAGClient: %v", err)
+	}
+
+	matches, err := c.GetContext(context.Background(), VectorQueryRequest{
+		QueryText:      "new user protocol",
+		TopK:           2,
+		KnowledgeBases: []string{"Domain-KB", "Body-KB", "Soul-KB"},
+	})
+	if err != nil {
+		t.Fatalf("GetContext: %v", err)
+	}
+
+	// Soul-KB's missing collection is skipped, not fatal.
+	want := []VectorQueryMatch{
+		{ID: "7", Score: 0.91, RawScore: 0.91, Text: "Onboarding protocol", Source: "handbook.md", KnowledgeBase: "Domain-KB"},
+		{ID: "8", Score: 0.55, RawScore: 0.55, Text: "Older note", Source: "milvus", KnowledgeBase: "Domain-KB"},
+		{ID: "doc-1", Score: 0.8, RawScore: 0.8, Text: "Sleep 8h", Source: "milvus", KnowledgeBase: "Body-KB"},
+	}
+	if len(matches) != len(want) {
+		t.Fatalf("got %d matches, want %d: %+v", len(matches), len(want), matches)
+	}
+	for i := range want {
+		if matches[i] != want[i] {
+			t.Errorf("match[%d] = %+v, want %+v", i, matches[i], want[i])
+		}
+	}
+
+	body := gotBodies["pagi_domain_kb"]
+	params, _ := body["searchParams"].(map[string]any)
+	if body["limit"] != float64(2) || body["dbName"] != "pagi" || body["annsField"] != "vector" ||
+		params["metricType"] != "IP" || params["params"].(map[string]any)["ef"] != float64(64) {
+		t.Errorf("unexpected search body: %v", body)
+	}
+}
+
+func TestMilvusRAGClient_L2Score(t *testing.T) {
+	t.Setenv("MILVUS_METRIC_TYPE", "L2")
+	c, err := NewMilvusRAGClient(envConfig(t).RAG, nil, fakeEmbedder{})
+	if err != nil {
+		t.Fatalf("NewMilvusRAGClient: %v", err)
+	}
+	if got := c.metric.score(1); got != 0.5 {
+		t.Errorf("L2 score(1) = %v, want 0.5", got)
+	}
+
+	cfg := envConfig(t).RAG
+	cfg.Milvus.MetricType = "HAMMING"
+	if _, err := NewMilvusRAGClient(cfg, nil, fakeEmbedder{}); err == nil {
+		t.Fatal("expected an error for an unsupported metric type")
+	}
+	t.Setenv("MILVUS_METRIC_TYPE", "HAMMING")
+	if _, err := loadConfig(); err == nil {
+		t.Fatal("expected loadConfig to reject an unsupported metric type")
+	}
+}
+
+func TestMilvusRAGClient_FilterExpression(t *testing.T) {
+	c, err := NewMilvusRAGClient(envConfig(t).RAG, nil, fakeEmbedder{})
+	if err != nil {
+		t.Fatalf("NewMilvusRAGClient: %v", err)
+	}
+	got := c.filter("", &ragfilter.Filter{
+		Sources:      []string{"journal"},
+		Tags:         []string{"health", `"quoted"`},
+		CreatedAfter: time.Unix(1700000000, 0),
+	})
+	want := `source in ["journal"] and array_contains_all(tags, ["health","\"quoted\""]) and created_at >= 1700000000`
+	if got != want {
+		t.Errorf("filter:\n got %s\nwant %s", got, want)
+	}
+	if got := c.filter("", nil); got != "" {
+		t.Errorf("nil filter = %q, want empty", got)
+	}
+}
@@ -0,0 +1,448 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend-go-model-gateway/pkg/ragfilter"
+	"backend-go-model-gateway/pkg/secrets"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PGVectorRAGClient implements RAGContextClient against Postgres with the
+// pgvector extension (RAG_BACKEND=pgvector), for deployments that already run
+// Postgres and do not want another vector store.
+//
+// Two layouts are supported:
+//
+//   - label (default): one table; a KB column selects the knowledge base
+//   - table: one table per KB (Domain-KB -> <prefix>domain_kb)
+type PGVectorRAGClient struct {
+	pool     *pgxpool.Pool
+	embedder Embedder
+
+	layout       string
+	table        string
+	tablePrefix  string
+	kbColumn     string
+	idColumn     string
+	textColumn   string
+	sourceColumn string
+	vectorColumn string
+	fields       ragMetadataFields
+	metric       pgvectorMetric
+	// textSearchConfig is the Postgres text search configuration used by
+	// KeywordSearch (RAG_RETRIEVAL_MODE=hybrid).
+	textSearchConfig string
+}
+
+// pgvectorMetric maps a distance metric to its pgvector operator and a
+// conversion from distance to a higher-is-better score.
+type pgvectorMetric struct {
+	name     string
+	operator string
+	// opclass is the operator class of an index serving operator.
+	opclass string
+	score   func(distance float64) float64
+}
+
+var pgvectorMetrics = map[string]pgvectorMetric{
+	"cosine": {name: "cosine", operator: "<=>", opclass: "vector_cosine_ops", score: func(d float64) float64 { return 1 - d }},
+	"l2":     {name: "l2", operator: "<->", opclass: "vector_l2_ops", score: func(d float64) float64 { return 1 / (1 + d) }},
+	// <#> returns the negative inner product.
+	"ip": {name: "ip", operator: "<#>", opclass: "vector_ip_ops", score: func(d float64) float64 { return -d }},
+}
+
+// NewPGVectorRAGClient connects the pool and configures the client from
+// cfg.PGVector.
+//
+//   - PGVECTOR_DSN (required; resolved through pkg/secrets since it embeds credentials)
+//   - PGVECTOR_MAX_CONNS (default: pgxpool's default)
+//   - PGVECTOR_LAYOUT (default: label) — label or table
+//   - PGVECTOR_TABLE (default: rag_documents) — label layout table
+//   - PGVECTOR_TABLE_PREFIX — table layout prefix
+//   - PGVECTOR_KB_COLUMN / _ID_COLUMN / _TEXT_COLUMN / _SOURCE_COLUMN / _EMBEDDING_COLUMN
+//     (default: kb / id / text / source / embedding)
+//   - PGVECTOR_DISTANCE (default: cosine) — cosine, l2 or ip
+//   - PGVECTOR_TEXT_SEARCH_CONFIG (default: simple) — for hybrid keyword search
+func NewPGVectorRAGClient(ctx context.Context, cfg RAGConfig, store *secrets.Store, embedder Embedder) (*PGVectorRAGClient, error) {
+	dsn, err := store.Get(ctx, "PGVECTOR_DSN")
+	if err != nil {
+		return nil, fmt.Errorf("PGVECTOR_DSN is required when RAG_BACKEND=pgvector: %w", err)
+	}
+	c, err := newPGVectorRAGClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	poolCfg, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("PGVECTOR_DSN: %w", err)
+	}
+	if v := cfg.PGVector.MaxConns; v > 0 {
+		poolCfg.MaxConns = int32(v)
+	}
+	pool, err := pgxpool.NewWithConfig(ctx, poolCfg)
+	if err != nil {
+		return nil, fmt.Errorf("pgvector pool: %w", err)
+	}
+	c.pool = pool
+	c.embedder = embedder
+	return c, nil
+}
+
+func newPGVectorRAGClient(cfg RAGConfig) (*PGVectorRAGClient, error) {
+	pc := cfg.PGVector
+	layout := strings.ToLower(pc.Layout)
+	if layout != "label" && layout != "table" {
+		return nil, fmt.Errorf("unsupported PGVECTOR_LAYOUT=%q (supported: label, table)", layout)
+	}
+	metricName := strings.ToLower(pc.Distance)
+	metric, ok := pgvectorMetrics[metricName]
+	if !ok {
+		return nil, fmt.Errorf("unsupported PGVECTOR_DISTANCE=%q (supported: cosine, l2, ip)", metricName)
+	}
+	tsConfig := pc.TextSearchConfig
+	if !pgTextSearchConfig.MatchString(tsConfig) {
+		return nil, fmt.Errorf("invalid PGVECTOR_TEXT_SEARCH_CONFIG=%q", tsConfig)
+	}
+	return &PGVectorRAGClient{
+		layout:       layout,
+		table:        pc.Table,
+		tablePrefix:  pc.TablePrefix,
+		kbColumn:     pc.KBColumn,
+		idColumn:     pc.IDColumn,
+		textColumn:   pc.TextColumn,
+		sourceColumn: pc.SourceColumn,
+		vectorColumn: pc.EmbeddingColumn,
+		fields:       ragMetadataFieldsFromConfig(cfg),
+		metric:       metric,
+
+		textSearchConfig: tsConfig,
+	}, nil
+}
+
+func (c *PGVectorRAGClient) Close() {
+	if c != nil && c.pool != nil {
+		c.pool.Close()
+	}
+}
+
+// pgTextSearchConfig matches Postgres text search configuration names, which
+// are interpolated into keyword queries as literals so expression indexes on
+// to_tsvector('<config>', text) can be used.
+var pgTextSearchConfig = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// pgArgs accumulates positional query arguments.
+type pgArgs []any
+
+// add appends v and returns its placeholder ($n).
+func (a *pgArgs) add(v any) string {
+	*a = append(*a, v)
+	return "$" + strconv.Itoa(len(*a))
+}
+
+func pgIdent(s string) string { return pgx.Identifier{s}.Sanitize() }
+
+// tableName returns the (unquoted) table holding kb's documents.
+func (c *PGVectorRAGClient) tableName(kb string) string {
+	if c.layout == "table" {
+		return c.tablePrefix + strings.ToLower(strings.ReplaceAll(kb, "-", "_"))
+	}
+	return c.table
+}
+
+// tableFor returns the quoted table holding kb's documents.
+func (c *PGVectorRAGClient) tableFor(kb string) string { return pgIdent(c.tableName(kb)) }
+
+// conditions returns the WHERE conditions selecting kb (label layout), the
+// tenant namespace and the metadata filter, appending their arguments to args.
+func (c *PGVectorRAGClient) conditions(kb, ns string, f *ragfilter.Filter, args *pgArgs) []string {
+	var conds []string
+	if c.layout == "label" {
+		conds = append(conds, pgIdent(c.kbColumn)+" = "+args.add(kb))
+	}
+	if ns != "" {
+		conds = append(conds, pgIdent(c.fields.namespace)+" = "+args.add(ns))
+	}
+	if f.IsZero() {
+		return conds
+	}
+	if len(f.Sources) > 0 {
+		conds = append(conds, pgIdent(c.sourceColumn)+"::text = ANY("+args.add(f.Sources)+"::text[])")
+	}
+	if len(f.Tags) > 0 {
+		conds = append(conds, pgIdent(c.fields.tags)+" @> "+args.add(f.Tags)+"::text[]")
+	}
+	if len(f.DocumentIDs) > 0 {
+		conds = append(conds, pgIdent(c.fields.documentID)+"::text = ANY("+args.add(f.DocumentIDs)+"::text[])")
+	}
+	if !f.CreatedAfter.IsZero() {
+		conds = append(conds, pgIdent(c.fields.createdAt)+" >= "+args.add(f.CreatedAfter))
+	}
+	if !f.CreatedBefore.IsZero() {
+		conds = append(conds, pgIdent(c.fields.createdAt)+" < "+args.add(f.CreatedBefore))
+	}
+	return conds
+}
+
+func whereClause(conds []string) string {
+	if len(conds) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(conds, " AND ")
+}
+
+// searchSQL returns the nearest-neighbour query for kb and its arguments;
+// vector is the query embedding in pgvector's text format.
+func (c *PGVectorRAGClient) searchSQL(kb, vector string, limit int, ns string, f *ragfilter.Filter) (string, []any) {
+	args := pgArgs{vector}
+	dist := pgIdent(c.vectorColumn) + " " + c.metric.operator + " $1::vector"
+	where := whereClause(c.conditions(kb, ns, f, &args))
+	return "SELECT " + pgIdent(c.idColumn) + "::text, " + pgIdent(c.textColumn) + ", COALESCE(" + pgIdent(c.sourceColumn) + "::text, ''), " + dist +
+		" AS distance FROM " + c.tableFor(kb) + where + " ORDER BY " + dist + " LIMIT " + args.add(limit), args
+}
+
+// keywordSQL returns the full-text query for kb, ranked with ts_rank_cd, and
+// its arguments.
+func (c *PGVectorRAGClient) keywordSQL(kb, text string, limit int, ns string, f *ragfilter.Filter) (string, []any) {
+	args := pgArgs{text}
+	cfg := "'" + c.textSearchConfig + "'"
+	doc := "to_tsvector(" + cfg + ", " + pgIdent(c.textColumn) + ")"
+	query := "websearch_to_tsquery(" + cfg + ", $1)"
+	conds := append(c.conditions(kb, ns, f, &args), doc+" @@ "+query)
+	return "SELECT " + pgIdent(c.idColumn) + "::text, " + pgIdent(c.textColumn) + ", COALESCE(" + pgIdent(c.sourceColumn) + "::text, ''), ts_rank_cd(" + doc + ", " + query + ")" +
+		" AS rank FROM " + c.tableFor(kb) + whereClause(conds) + " ORDER BY rank DESC LIMIT " + args.add(limit), args
+}
+
+// pgvectorLiteral renders v in pgvector's text input format: [1,2,3].
+func pgvectorLiteral(v []float32) string {
+	var b strings.Builder
+	b.Grow(len(v) * 8)
+	b.WriteByte('[')
+	for i, f := range v {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.FormatFloat(float64(f), 'g', -1, 32))
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+func (c *PGVectorRAGClient) GetContext(ctx context.Context, req VectorQueryRequest) ([]VectorQueryMatch, error) {
+	if req.TopK <= 0 {
+		req.TopK = 2
+	}
+	kbs := req.KnowledgeBases
+	if len(kbs) == 0 {
+		kbs = []string{defaultRAGKnowledgeBase}
+	}
+
+	vec, err := c.embedder.Embed(ctx, req.QueryText)
+	if err != nil {
+		return nil, err
+	}
+	literal := pgvectorLiteral(vec)
+	matches, err := c.query(ctx, kbs, func(kb string) (string, []any) {
+		return c.searchSQL(kb, literal, req.TopK, req.Namespace, req.Filter)
+	}, c.metric.score)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf(
+		`{"timestamp":"%s","level":"info","service":"%s","component":"PGVectorRAGClient","method":"GetContext","layout":%q,"distance":%q,"query_text":%q,"top_k":%d,"match_count":%d}`,
+		time.Now().Format(time.RFC3339Nano), SERVICE_NAME, c.layout, c.metric.name, req.QueryText, req.TopK, len(matches),
+	)
+	return matches, nil
+}
+
+// KeywordSearch ranks documents with Postgres full-text search.
+func (c *PGVectorRAGClient) KeywordSearch(ctx context.Context, req VectorQueryRequest) ([]VectorQueryMatch, error) {
+	if req.TopK <= 0 {
+		req.TopK = 2
+	}
+	kbs := req.KnowledgeBases
+	if len(kbs) == 0 {
+		kbs = []string{defaultRAGKnowledgeBase}
+	}
+	return c.query(ctx, kbs, func(kb string) (string, []any) {
+		return c.keywordSQL(kb, req.QueryText, req.TopK, req.Namespace, req.Filter)
+	}, func(rank float64) float64 { return rank })
+}
+
+// query runs buildSQL(kb) for each KB in turn and maps the fourth column
+// through score.
+func (c *PGVectorRAGClient) query(ctx context.Context, kbs []string, buildSQL func(kb string) (string, []any), score func(float64) float64) ([]VectorQueryMatch, error) {
+	matches := []VectorQueryMatch{}
+	for _, kb := range kbs {
+		query, args := buildSQL(kb)
+		rows, err := c.pool.Query(ctx, query, args...)
+		if err != nil {
+			return nil, fmt.Errorf("pgvector search %s: %w", kb, err)
+		}
+		for rows.Next() {
+			var m VectorQueryMatch
+			var raw float64
+			if err := rows.Scan(&m.ID, &m.Text, &m.Source, &raw); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("pgvector scan %s: %w", kb, err)
+			}
+			if m.Source == "" {
+				m.Source = "pgvector"
+			}
+			m.Score = score(raw)
+			m.RawScore = raw
+			m.KnowledgeBase = kb
+			matches = append(matches, m)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("pgvector search %s: %w", kb, err)
+		}
+	}
+	return matches, nil
+}
+
+// deleteDocumentSQL returns the statement removing a document's rows from kb.
+func (c *PGVectorRAGClient) deleteDocumentSQL(kb, namespace, documentID string) (string, []any) {
+	var args pgArgs
+	conds := c.conditions(kb, namespace, nil, &args)
+	conds = append(conds, pgIdent(c.fields.documentID)+" = "+args.add(documentID))
+	return "DELETE FROM " + c.tableFor(kb) + whereClause(conds), args
+}
+
+// insertChunkSQL returns the statement inserting one chunk into kb. The
+// namespace column is only written for namespaced documents, so tables
+// without one keep working while RAG_TENANCY is off.
+func (c *PGVectorRAGClient) insertChunkSQL(kb string, ch ingestChunk) (string, []any) {
+	var args pgArgs
+	var cols, vals []string
+	add := func(col string, v any, cast string) {
+		cols = append(cols, pgIdent(col))
+		vals = append(vals, args.add(v)+cast)
+	}
+	add(c.idColumn, ch.ID, "")
+	if c.layout == "label" {
+		add(c.kbColumn, kb, "")
+	}
+	add(c.textColumn, ch.Text, "")
+	add(c.sourceColumn, ch.Source, "")
+	add(c.vectorColumn, pgvectorLiteral(ch.Embedding), "::vector")
+	add(c.fields.documentID, ch.DocumentID, "")
+	add(c.fields.tags, append([]string{}, ch.Tags...), "::text[]")
+	add(c.fields.createdAt, ch.CreatedAt, "")
+	if ch.Namespace != "" {
+		add(c.fields.namespace, ch.Namespace, "")
+	}
+	return "INSERT INTO " + c.tableFor(kb) + " (" + strings.Join(cols, ", ") + ") VALUES (" + strings.Join(vals, ", ") + ")", args
+}
+
+// ReplaceDocument deletes and re-inserts the document's rows in one
+// transaction, so searches never see a half-ingested document.
+func (c *PGVectorRAGClient) ReplaceDocument(ctx context.Context, kb, namespace, documentID string, chunks []ingestChunk) error {
+	tx, err := c.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("pgvector ingest %s: %w", kb, err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	query, args := c.deleteDocumentSQL(kb, namespace, documentID)
+	if _, err := tx.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("pgvector delete %s from %s: %w", documentID, kb, err)
+	}
+	batch := &pgx.Batch{}
+	for _, ch := range chunks {
+		query, args := c.insertChunkSQL(kb, ch)
+		batch.Queue(query, args...)
+	}
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("pgvector insert into %s: %w", kb, err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("pgvector ingest %s: %w", kb, err)
+	}
+	return nil
+}
+
+// createKBSQL returns the statements creating kb's table (the shared table in
+// the label layout) with the columns ingestion writes, and an HNSW index for
+// the configured distance.
+func (c *PGVectorRAGClient) createKBSQL(kb string, dims int) []string {
+	cols := []string{pgIdent(c.idColumn) + " text PRIMARY KEY"}
+	if c.layout == "label" {
+		cols = append(cols, pgIdent(c.kbColumn)+" text NOT NULL")
+	}
+	cols = append(cols,
+		pgIdent(c.textColumn)+" text NOT NULL",
+		pgIdent(c.sourceColumn)+" text",
+		pgIdent(c.vectorColumn)+" vector("+strconv.Itoa(dims)+") NOT NULL",
+		pgIdent(c.fields.documentID)+" text",
+		pgIdent(c.fields.tags)+" text[]",
+		pgIdent(c.fields.createdAt)+" timestamptz",
+		pgIdent(c.fields.namespace)+" text",
+	)
+	table := c.tableName(kb)
+	stmts := []string{
+		"CREATE EXTENSION IF NOT EXISTS vector",
+		"CREATE TABLE IF NOT EXISTS " + pgIdent(table) + " (" + strings.Join(cols, ", ") + ")",
+		"CREATE INDEX IF NOT EXISTS " + pgIdent(table+"_"+c.vectorColumn+"_idx") + " ON " + pgIdent(table) + " USING hnsw (" + pgIdent(c.vectorColumn) + " " + c.metric.opclass + ")",
+	}
+	if c.layout == "label" {
+		stmts = append(stmts, "CREATE INDEX IF NOT EXISTS "+pgIdent(table+"_"+c.kbColumn+"_idx")+" ON "+pgIdent(table)+" ("+pgIdent(c.kbColumn)+")")
+	}
+	return stmts
+}
+
+// kbStatsSQL returns the query counting kb's rows and their newest created_at.
+func (c *PGVectorRAGClient) kbStatsSQL(kb string) (string, []any) {
+	var args pgArgs
+	return "SELECT count(*), max(" + pgIdent(c.fields.createdAt) + ") FROM " + c.tableFor(kb) + whereClause(c.conditions(kb, "", nil, &args)), args
+}
+
+func (c *PGVectorRAGClient) CreateKB(ctx context.Context, kb string, dims int) error {
+	if dims <= 0 {
+		return fmt.Errorf("pgvector: creating %s needs the embedding dimensions", kb)
+	}
+	for _, stmt := range c.createKBSQL(kb, dims) {
+		if _, err := c.pool.Exec(ctx, stmt); err != nil {
+			return fmt.Errorf("pgvector create %s: %w", kb, err)
+		}
+	}
+	return nil
+}
+
+// DropKB drops kb's table in the table layout, or deletes its rows from the
+// shared table in the label layout.
+func (c *PGVectorRAGClient) DropKB(ctx context.Context, kb string) error {
+	query := "DROP TABLE IF EXISTS " + c.tableFor(kb)
+	var args pgArgs
+	if c.layout == "label" {
+		query = "DELETE FROM " + c.tableFor(kb) + whereClause(c.conditions(kb, "", nil, &args))
+	}
+	if _, err := c.pool.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("pgvector drop %s: %w", kb, err)
+	}
+	return nil
+}
+
+func (c *PGVectorRAGClient) KBStats(ctx context.Context, kb string) (kbStats, error) {
+	var st kbStats
+	var last *time.Time
+	query, args := c.kbStatsSQL(kb)
+	if err := c.pool.QueryRow(ctx, query, args...).Scan(&st.Documents, &last); err != nil {
+		return kbStats{}, fmt.Errorf("pgvector stats %s: %w", kb, err)
+	}
+	if last != nil {
+		st.LastUpdated = *last
+	}
+	return st, nil
+}
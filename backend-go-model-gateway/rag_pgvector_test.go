@@ -0,0 +1,121 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"backend-go-model-gateway/pkg/ragfilter"
+)
+
+func TestPGVectorSearchSQL(t *testing.T) {
+	t.Setenv("PGVECTOR_DISTANCE", "cosine")
+
+	t.Run("label layout", func(t *testing.T) {
+		c, err := newPGVectorRAGClient(envConfig(t).RAG)
+		if err != nil {
+			t.Fatal(err)
+		}
+		q, args := c.searchSQL("Domain-KB", "[1,0]", 3, "", nil)
+		want := `SELECT "id"::text, "text", COALESCE("source"::text, ''), "embedding" <=> $1::vector AS distance FROM "rag_documents" WHERE "kb" = $2 ORDER BY "embedding" <=> $1::vector LIMIT $3`
+		if q != want || !reflect.DeepEqual(args, []any{"[1,0]", "Domain-KB", 3}) {
+			t.Fatalf("searchSQL =\n%s %v\nwant\n%s", q, args, want)
+		}
+	})
+
+	t.Run("table layout", func(t *testing.T) {
+		t.Setenv("PGVECTOR_LAYOUT", "table")
+		t.Setenv("PGVECTOR_TABLE_PREFIX", "pagi_")
+		t.Setenv("PGVECTOR_DISTANCE", "l2")
+		c, err := newPGVectorRAGClient(envConfig(t).RAG)
+		if err != nil {
+			t.Fatal(err)
+		}
+		q, args := c.searchSQL("Body-KB", "[1,0]", 3, "", nil)
+		want := `SELECT "id"::text, "text", COALESCE("source"::text, ''), "embedding" <-> $1::vector AS distance FROM "pagi_body_kb" ORDER BY "embedding" <-> $1::vector LIMIT $2`
+		if q != want || !reflect.DeepEqual(args, []any{"[1,0]", 3}) {
+			t.Fatalf("searchSQL =\n%s %v\nwant\n%s", q, args, want)
+		}
+	})
+
+	t.Run("metadata filter", func(t *testing.T) {
+		c, err := newPGVectorRAGClient(envConfig(t).RAG)
+		if err != nil {
+			t.Fatal(err)
+		}
+		after := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+		f := &ragfilter.Filter{Sources: []string{"journal"}, Tags: []string{"health"}, CreatedAfter: after}
+		q, args := c.searchSQL("Body-KB", "[1,0]", 2, "", f)
+		want := `SELECT "id"::text, "text", COALESCE("source"::text, ''), "embedding" <=> $1::vector AS distance FROM "rag_documents" WHERE "kb" = $2 AND "source"::text = ANY($3::text[]) AND "tags" @> $4::text[] AND "created_at" >= $5 ORDER BY "embedding" <=> $1::vector LIMIT $6`
+		wantArgs := []any{"[1,0]", "Body-KB", []string{"journal"}, []string{"health"}, after, 2}
+		if q != want || !reflect.DeepEqual(args, wantArgs) {
+			t.Fatalf("searchSQL =\n%s %v\nwant\n%s %v", q, args, want, wantArgs)
+		}
+	})
+}
+
+func TestPGVectorKeywordSQL(t *testing.T) {
+	c, err := newPGVectorRAGClient(envConfig(t).RAG)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, args := c.keywordSQL("Domain-KB", "E-1042", 3, "", nil)
+	want := `SELECT "id"::text, "text", COALESCE("source"::text, ''), ts_rank_cd(to_tsvector('simple', "text"), websearch_to_tsquery('simple', $1)) AS rank FROM "rag_documents" WHERE "kb" = $2 AND to_tsvector('simple', "text") @@ websearch_to_tsquery('simple', $1) ORDER BY rank DESC LIMIT $3`
+	if q != want || !reflect.DeepEqual(args, []any{"E-1042", "Domain-KB", 3}) {
+		t.Fatalf("keywordSQL =\n%s %v\nwant\n%s", q, args, want)
+	}
+
+	t.Setenv("PGVECTOR_TEXT_SEARCH_CONFIG", "english'); DROP TABLE x; --")
+	if _, err := newPGVectorRAGClient(envConfig(t).RAG); err == nil {
+		t.Fatal("expected an invalid text search config to be rejected")
+	}
+}
+
+func TestPGVectorLiteral(t *testing.T) {
+	if got := pgvectorLiteral([]float32{1, -0.5, 0.25}); got != "[1,-0.5,0.25]" {
+		t.Fatalf("pgvectorLiteral = %q", got)
+	}
+}
+
+func TestPGVectorIngestSQL(t *testing.T) {
+	c, err := newPGVectorRAGClient(envConfig(t).RAG)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, args := c.deleteDocumentSQL("Body-KB", "acme", "guide")
+	if want := `DELETE FROM "rag_documents" WHERE "kb" = $1 AND "namespace" = $2 AND "document_id" = $3`; q != want {
+		t.Errorf("deleteDocumentSQL = %s, want %s", q, want)
+	}
+	if len(args) != 3 || args[2] != "guide" {
+		t.Errorf("delete args = %v", args)
+	}
+
+	q, args = c.insertChunkSQL("Body-KB", ingestChunk{ID: "id-1", Text: "t", Source: "s", DocumentID: "guide", Embedding: []float32{1, 0}})
+	want := `INSERT INTO "rag_documents" ("id", "kb", "text", "source", "embedding", "document_id", "tags", "created_at") VALUES ($1, $2, $3, $4, $5::vector, $6, $7::text[], $8)`
+	if q != want {
+		t.Errorf("insertChunkSQL = %s, want %s", q, want)
+	}
+	if args[4] != "[1,0]" {
+		t.Errorf("embedding arg = %v, want [1,0]", args[4])
+	}
+}
+
+func TestPGVectorKBSQL(t *testing.T) {
+	t.Setenv("PGVECTOR_LAYOUT", "table")
+	t.Setenv("PGVECTOR_TABLE_PREFIX", "rag_")
+	c, err := newPGVectorRAGClient(envConfig(t).RAG)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stmts := c.createKBSQL("Legal-KB", 768)
+	want := `CREATE TABLE IF NOT EXISTS "rag_legal_kb" ("id" text PRIMARY KEY, "text" text NOT NULL, "source" text, "embedding" vector(768) NOT NULL, "document_id" text, "tags" text[], "created_at" timestamptz, "namespace" text)`
+	if len(stmts) != 3 || stmts[1] != want {
+		t.Errorf("createKBSQL = %q, want table statement %s", stmts, want)
+	}
+	if want := `CREATE INDEX IF NOT EXISTS "rag_legal_kb_embedding_idx" ON "rag_legal_kb" USING hnsw ("embedding" vector_cosine_ops)`; stmts[2] != want {
+		t.Errorf("index statement = %s, want %s", stmts[2], want)
+	}
+	if q, args := c.kbStatsSQL("Legal-KB"); q != `SELECT count(*), max("created_at") FROM "rag_legal_kb"` || len(args) != 0 {
+		t.Errorf("kbStatsSQL = %s %v", q, args)
+	}
+}
@@ -0,0 +1,382 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"backend-go-model-gateway/pkg/ragfilter"
+	"backend-go-model-gateway/pkg/secrets"
+)
+
+// QdrantRAGClient implements RAGContextClient by querying Qdrant directly over
+// its REST API, one collection per knowledge base. It lets small deployments
+// serve retrieval without the Python memory service (RAG_BACKEND=qdrant).
+type QdrantRAGClient struct {
+	baseURL        string
+	store          *secrets.Store
+	embedder       Embedder
+	httpClient     *http.Client
+	collections    map[string]string
+	prefix         string
+	vectorName     string
+	textField      string
+	sourceField    string
+	fields         ragMetadataFields
+	scoreThreshold *float64
+	// distance is used for collections created through the KB API.
+	distance string
+}
+
+// NewQdrantRAGClient configures a QdrantRAGClient from cfg.Qdrant.
+//
+//   - QDRANT_URL (default: http://localhost:6333)
+//   - QDRANT_API_KEY (optional; resolved through pkg/secrets)
+//   - QDRANT_COLLECTIONS explicit KB mapping, e.g. "Domain-KB=domain,Body-KB=body"
+//   - QDRANT_COLLECTION_PREFIX prefix for derived names (Domain-KB -> <prefix>domain_kb)
+//   - QDRANT_VECTOR_NAME named vector to search (default: the unnamed vector)
+//   - QDRANT_TEXT_FIELD / QDRANT_SOURCE_FIELD payload keys (default: text / source)
+//   - QDRANT_SCORE_THRESHOLD minimum similarity score (optional)
+//   - QDRANT_DISTANCE (default: Cosine) — Cosine, Dot, Euclid or Manhattan, for
+//     collections created through the KB API
+func NewQdrantRAGClient(cfg RAGConfig, store *secrets.Store, embedder Embedder) (*QdrantRAGClient, error) {
+	qc := cfg.Qdrant
+	c := &QdrantRAGClient{
+		baseURL:     strings.TrimRight(qc.URL, "/"),
+		store:       store,
+		embedder:    embedder,
+		httpClient:  &http.Client{Timeout: 10 * time.Second},
+		prefix:      qc.CollectionPrefix,
+		vectorName:  qc.VectorName,
+		textField:   qc.TextField,
+		sourceField: qc.SourceField,
+		fields:      ragMetadataFieldsFromConfig(cfg),
+	}
+	var err error
+	if c.collections, err = parseKBMapping("QDRANT_COLLECTIONS", qc.Collections, "collection"); err != nil {
+		return nil, err
+	}
+	if c.scoreThreshold, err = parseOptionalFloat("QDRANT_SCORE_THRESHOLD", qc.ScoreThreshold); err != nil {
+		return nil, err
+	}
+	switch c.distance = qc.Distance; c.distance {
+	case "Cosine", "Dot", "Euclid", "Manhattan":
+	default:
+		return nil, fmt.Errorf("unsupported QDRANT_DISTANCE=%q (supported: Cosine, Dot, Euclid, Manhattan)", c.distance)
+	}
+	return c, nil
+}
+
+// collectionFor maps a conceptual KB name to its Qdrant collection.
+func (c *QdrantRAGClient) collectionFor(kb string) string {
+	if coll, ok := c.collections[kb]; ok {
+		return coll
+	}
+	return c.prefix + strings.ToLower(strings.ReplaceAll(kb, "-", "_"))
+}
+
+type qdrantSearchRequest struct {
+	Vector         any      `json:"vector"`
+	Limit          int      `json:"limit"`
+	WithPayload    bool     `json:"with_payload"`
+	ScoreThreshold *float64 `json:"score_threshold,omitempty"`
+	Filter         any      `json:"filter,omitempty"`
+}
+
+type qdrantPoint struct {
+	ID      json.RawMessage `json:"id"`
+	Score   float64         `json:"score"`
+	Payload map[string]any  `json:"payload"`
+}
+
+func (c *QdrantRAGClient) GetContext(ctx context.Context, req VectorQueryRequest) ([]VectorQueryMatch, error) {
+	if req.TopK <= 0 {
+		req.TopK = 2
+	}
+	kbs := req.KnowledgeBases
+	if len(kbs) == 0 {
+		kbs = []string{defaultRAGKnowledgeBase}
+	}
+
+	vec, err := c.embedder.Embed(ctx, req.QueryText)
+	if err != nil {
+		return nil, err
+	}
+	var vector any = vec
+	if c.vectorName != "" {
+		vector = map[string]any{"name": c.vectorName, "vector": vec}
+	}
+
+	matches, err := searchKBs(ctx, "QdrantRAGClient", kbs, func(ctx context.Context, kb string) ([]VectorQueryMatch, error) {
+		return c.search(ctx, kb, qdrantSearchRequest{
+			Vector:         vector,
+			Limit:          req.TopK,
+			WithPayload:    true,
+			ScoreThreshold: c.scoreThreshold,
+			Filter:         c.filter(req.Namespace, req.Filter),
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("qdrant: %w", err)
+	}
+
+	log.Printf(
+		`{"timestamp":"%s","level":"info","service":"%s","component":"QdrantRAGClient","method":"GetContext","qdrant_url":%q,"query_text":%q,"top_k":%d,"match_count":%d}`,
+		time.Now().Format(time.RFC3339Nano), SERVICE_NAME, c.baseURL, req.QueryText, req.TopK, len(matches),
+	)
+	return matches, nil
+}
+
+// filter translates the tenant namespace and a metadata filter into a Qdrant
+// payload filter.
+func (c *QdrantRAGClient) filter(ns string, f *ragfilter.Filter) any {
+	var must []map[string]any
+	if ns != "" {
+		must = append(must, map[string]any{"key": c.fields.namespace, "match": map[string]any{"value": ns}})
+	}
+	if f == nil {
+		f = &ragfilter.Filter{}
+	}
+	if len(f.Sources) > 0 {
+		must = append(must, map[string]any{"key": c.sourceField, "match": map[string]any{"any": f.Sources}})
+	}
+	for _, tag := range f.Tags {
+		must = append(must, map[string]any{"key": c.fields.tags, "match": map[string]any{"value": tag}})
+	}
+	if len(f.DocumentIDs) > 0 {
+		must = append(must, map[string]any{"key": c.fields.documentID, "match": map[string]any{"any": f.DocumentIDs}})
+	}
+	if !f.CreatedAfter.IsZero() || !f.CreatedBefore.IsZero() {
+		r := map[string]int64{}
+		if !f.CreatedAfter.IsZero() {
+			r["gte"] = f.CreatedAfter.Unix()
+		}
+		if !f.CreatedBefore.IsZero() {
+			r["lt"] = f.CreatedBefore.Unix()
+		}
+		must = append(must, map[string]any{"key": c.fields.createdAt, "range": r})
+	}
+	if len(must) == 0 {
+		return nil
+	}
+	return map[string]any{"must": must}
+}
+
+func (c *QdrantRAGClient) search(ctx context.Context, kb string, body qdrantSearchRequest) ([]VectorQueryMatch, error) {
+	coll := c.collectionFor(kb)
+	resp, err := c.do(ctx, http.MethodPost, "/collections/"+url.PathEscape(coll)+"/points/search", body)
+	if err != nil {
+		return nil, fmt.Errorf("search %s: %w", coll, err)
+	}
+	defer resp.Body.Close()
+
+	var out struct {
+		Result []qdrantPoint `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("decode %s search response: %w", coll, err)
+	}
+
+	// Qdrant already returns best-first; keep that order stable.
+	sort.SliceStable(out.Result, func(i, j int) bool { return out.Result[i].Score > out.Result[j].Score })
+	matches := make([]VectorQueryMatch, 0, len(out.Result))
+	for _, p := range out.Result {
+		text, _ := p.Payload[c.textField].(string)
+		source, _ := p.Payload[c.sourceField].(string)
+		if source == "" {
+			source = "qdrant"
+		}
+		matches = append(matches, VectorQueryMatch{
+			ID:            jsonID(p.ID),
+			Score:         p.Score,
+			RawScore:      p.Score,
+			Text:          text,
+			Source:        source,
+			KnowledgeBase: kb,
+		})
+	}
+	return matches, nil
+}
+
+// do sends a JSON request to Qdrant (without a body when body is nil).
+// Non-2xx responses are returned as *httpStatusError.
+func (c *QdrantRAGClient) do(ctx context.Context, method, path string, body any) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(b)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	apiKey, err := c.store.Lookup(ctx, "QDRANT_API_KEY")
+	if err != nil {
+		return nil, err
+	}
+	if apiKey != "" {
+		httpReq.Header.Set("api-key", apiKey)
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, &httpStatusError{code: resp.StatusCode, body: strings.TrimSpace(string(msg))}
+	}
+	return resp, nil
+}
+
+// ReplaceDocument deletes the document's points by payload filter, then
+// upserts the new chunks. Both calls wait for the write to be applied.
+func (c *QdrantRAGClient) ReplaceDocument(ctx context.Context, kb, namespace, documentID string, chunks []ingestChunk) error {
+	coll := c.collectionFor(kb)
+	must := []map[string]any{{"key": c.fields.documentID, "match": map[string]any{"value": documentID}}}
+	if namespace != "" {
+		must = append(must, map[string]any{"key": c.fields.namespace, "match": map[string]any{"value": namespace}})
+	}
+	resp, err := c.do(ctx, http.MethodPost, "/collections/"+url.PathEscape(coll)+"/points/delete?wait=true",
+		map[string]any{"filter": map[string]any{"must": must}})
+	if err != nil {
+		return fmt.Errorf("qdrant: delete %s from %s: %w", documentID, coll, err)
+	}
+	resp.Body.Close()
+
+	points := make([]map[string]any, len(chunks))
+	for i, ch := range chunks {
+		var vector any = ch.Embedding
+		if c.vectorName != "" {
+			vector = map[string]any{c.vectorName: ch.Embedding}
+		}
+		payload := map[string]any{
+			c.textField:         ch.Text,
+			c.sourceField:       ch.Source,
+			c.fields.documentID: ch.DocumentID,
+			c.fields.tags:       ch.Tags,
+			c.fields.createdAt:  ch.CreatedAt.Unix(),
+		}
+		if ch.Namespace != "" {
+			payload[c.fields.namespace] = ch.Namespace
+		}
+		points[i] = map[string]any{"id": ch.ID, "vector": vector, "payload": payload}
+	}
+	resp, err = c.do(ctx, http.MethodPut, "/collections/"+url.PathEscape(coll)+"/points?wait=true", map[string]any{"points": points})
+	if err != nil {
+		return fmt.Errorf("qdrant: upsert into %s: %w", coll, err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// CreateKB creates kb's collection unless it exists, then indexes the
+// metadata fields filters and KB stats use.
+func (c *QdrantRAGClient) CreateKB(ctx context.Context, kb string, dims int) error {
+	coll := c.collectionFor(kb)
+	path := "/collections/" + url.PathEscape(coll)
+	resp, err := c.do(ctx, http.MethodGet, path, nil)
+	switch {
+	case err == nil:
+		resp.Body.Close()
+	case isHTTPStatus(err, http.StatusNotFound):
+		if dims <= 0 {
+			return fmt.Errorf("qdrant: creating %s needs the embedding dimensions", coll)
+		}
+		var vectors any = map[string]any{"size": dims, "distance": c.distance}
+		if c.vectorName != "" {
+			vectors = map[string]any{c.vectorName: vectors}
+		}
+		resp, err := c.do(ctx, http.MethodPut, path, map[string]any{"vectors": vectors})
+		if err != nil {
+			return fmt.Errorf("qdrant: create %s: %w", coll, err)
+		}
+		resp.Body.Close()
+	default:
+		return fmt.Errorf("qdrant: get %s: %w", coll, err)
+	}
+
+	for field, schema := range map[string]string{
+		c.sourceField:       "keyword",
+		c.fields.documentID: "keyword",
+		c.fields.tags:       "keyword",
+		c.fields.namespace:  "keyword",
+		c.fields.createdAt:  "integer",
+	} {
+		resp, err := c.do(ctx, http.MethodPut, path+"/index?wait=true", map[string]any{"field_name": field, "field_schema": schema})
+		if err != nil {
+			return fmt.Errorf("qdrant: index %s.%s: %w", coll, field, err)
+		}
+		resp.Body.Close()
+	}
+	return nil
+}
+
+// DropKB deletes kb's collection. A missing collection is not an error.
+func (c *QdrantRAGClient) DropKB(ctx context.Context, kb string) error {
+	coll := c.collectionFor(kb)
+	resp, err := c.do(ctx, http.MethodDelete, "/collections/"+url.PathEscape(coll), nil)
+	if isHTTPStatus(err, http.StatusNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("qdrant: delete %s: %w", coll, err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// KBStats counts kb's points exactly. The newest created_at needs a range
+// index on that field (CreateKB adds one); without it LastUpdated stays zero.
+func (c *QdrantRAGClient) KBStats(ctx context.Context, kb string) (kbStats, error) {
+	coll := c.collectionFor(kb)
+	path := "/collections/" + url.PathEscape(coll)
+	resp, err := c.do(ctx, http.MethodPost, path+"/points/count", map[string]any{"exact": true})
+	if err != nil {
+		return kbStats{}, fmt.Errorf("qdrant: count %s: %w", coll, err)
+	}
+	defer resp.Body.Close()
+	var count struct {
+		Result struct {
+			Count int64 `json:"count"`
+		} `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&count); err != nil {
+		return kbStats{}, fmt.Errorf("qdrant: decode %s count: %w", coll, err)
+	}
+	st := kbStats{Documents: count.Result.Count}
+
+	resp, err = c.do(ctx, http.MethodPost, path+"/points/scroll", map[string]any{
+		"limit":        1,
+		"with_payload": []string{c.fields.createdAt},
+		"order_by":     map[string]any{"key": c.fields.createdAt, "direction": "desc"},
+	})
+	if err != nil {
+		return st, nil
+	}
+	defer resp.Body.Close()
+	var scroll struct {
+		Result struct {
+			Points []qdrantPoint `json:"points"`
+		} `json:"result"`
+	}
+	if json.NewDecoder(resp.Body).Decode(&scroll) == nil && len(scroll.Result.Points) > 0 {
+		if ts, ok := scroll.Result.Points[0].Payload[c.fields.createdAt].(float64); ok {
+			st.LastUpdated = time.Unix(int64(ts), 0).UTC()
+		}
+	}
+	return st, nil
+}
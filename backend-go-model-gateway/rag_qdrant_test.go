@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type fakeEmbedder struct{}
+
+func (fakeEmbedder) Embed(_ context.Context, text string) ([]float32, error) {
+	return []float32{float32(len(text)), 1, 0}, nil
+}
+
+func TestQdrantRAGClient_SearchesCollectionPerKB(t *testing.T) {
+	var mu sync.Mutex
+	gotBodies := map[string]map[string]any{}
+	qdrant := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("api-key") != "qk" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		coll := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/collections/"), "/points/search")
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		gotBodies[coll] = body
+		mu.Unlock()
+
+		switch coll {
+		case "pagi_domain_kb":
+			_, _ = w.Write([]byte(`{"result":[
+				{"id":7,"score":0.91,"payload":{"text":"Onboarding protocol","source":"handbook.md"}},
+				{"id":"3f2c6a52-9f0e-4c3f-8d8e-2b1a2c3d4e5f","score":0.55,"payload":{"text":"Older note"}}
+			]}`))
+		case "body":
+			_, _ = w.Write([]byte(`{"result":[{"id":1,"score":0.8,"payload":{"text":"Sleep 8h"}}]}`))
+		default:
+			http.Error(w, `{"status":{"error":"Not found: Collection doesn't exist"}}`, http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(qdrant.Close)
+
+	t.Setenv("QDRANT_URL", qdrant.URL)
+	t.Setenv("QDRANT_API_KEY", "qk")
+	t.Setenv("QDRANT_COLLECTION_PREFIX", "pagi_")
+	t.Setenv("QDRANT_COLLECTIONS", "Body-KB=body")
+	t.Setenv("QDRANT_SCORE_THRESHOLD", "0.5")
+
+	c, err := NewQdrantRAGClient(envConfig(t).RAG, nil, fakeEmbedder{})
+	if err != nil {
+		t.Fatalf("NewQdrantRAGClient: %v", err)
+	}
+
+	matches, err := c.GetContext(context.Background(), VectorQueryRequest{
+		QueryText:      "new user protocol",
+		TopK:           2,
+		KnowledgeBases: []string{"Domain-KB", "Body-KB", "Soul-KB"},
+	})
+	if err != nil {
+		t.Fatalf("GetContext: %v", err)
+	}
+
+	// Soul-KB's missing collection is skipped, not fatal.
+	if len(matches) != 3 {
+		t.Fatalf("got %d matches, want 3: %+v", len(matches), matches)
+	}
+	want := []VectorQueryMatch{
+		{ID: "7", Score: 0.91, RawScore: 0.91, Text: "Onboarding protocol", Source: "handbook.md", KnowledgeBase: "Domain-KB"},
+		{ID: "3f2c6a52-9f0e-4c3f-8d8e-2b1a2c3d4e5f", Score: 0.55, RawScore: 0.55, Text: "Older note", Source: "qdrant", KnowledgeBase: "Domain-KB"},
+		{ID: "1", Score: 0.8, RawScore: 0.8, Text: "Sleep 8h", Source: "qdrant", KnowledgeBase: "Body-KB"},
+	}
+	for i := range want {
+		if matches[i] != want[i] {
+			t.Errorf("match[%d] = %+v, want %+v", i, matches[i], want[i])
+		}
+	}
+
+	body := gotBodies["pagi_domain_kb"]
+	if body["limit"] != float64(2) || body["score_threshold"] != 0.5 || body["with_payload"] != true {
+		t.Errorf("unexpected search body: %v", body)
+	}
+}
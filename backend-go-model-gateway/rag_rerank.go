@@ -0,0 +1,355 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"backend-go-model-gateway/pkg/discovery"
+	"backend-go-model-gateway/pkg/secrets"
+	pb "backend-go-model-gateway/proto/proto"
+
+	"github.com/sashabaranov/go-openai"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// reranker scores passages for relevance to a query, one score per passage in
+// order; higher is more relevant.
+type reranker interface {
+	Rerank(ctx context.Context, query string, passages []string) ([]float64, error)
+}
+
+// rerankingRAGClient over-fetches candidates and reorders them with a
+// reranker (typically a cross-encoder, which reads query and passage together
+// and is far more precise than embedding similarity, but too slow to run over
+// a whole collection). Results keep the GetContext layout: each KB's top_k,
+// best-first, grouped by KB in request order. Match scores become reranker
+// scores.
+//
+// A failing reranker degrades to the retrieval order rather than failing the
+// request.
+type rerankingRAGClient struct {
+	next     RAGContextClient
+	reranker reranker
+	// kind names the reranker in logs (http, grpc, llm).
+	kind string
+	// candidates multiplies top_k for the retrieval feeding the reranker.
+	candidates int
+	close      func()
+}
+
+// newRerankingRAGClient wraps next for RAG_RERANKER, or returns nil when
+// reranking is off.
+//
+//   - RAG_RERANKER (default: off) — off, http, grpc or llm
+//   - RAG_RERANK_CANDIDATES (default: 4) — candidates fetched per KB: top_k * this
+//   - RERANKER_URL — for http: the rerank endpoint, e.g. http://tei:8080/rerank
+//   - RERANKER_API (default: cohere) — for http: cohere (Cohere/Jina/Voyage
+//     {"query","documents"} → {"results":[{"index","relevance_score"}]}) or tei
+//     (Hugging Face text-embeddings-inference {"query","texts"} → [{"index","score"}])
+//   - RERANKER_MODEL (optional) — model name sent to the reranker
+//   - RERANKER_API_KEY (optional; resolved through pkg/secrets, sent as a bearer token)
+//   - RERANKER_GRPC_ADDR — for grpc: a Reranker service (proto/model.proto);
+//     a static host:port or a discovery target
+//   - for llm: the gateway's own LLM_PROVIDER (llmCfg) scores each passage 0-10
+func newRerankingRAGClient(ctx context.Context, store *secrets.Store, cfg RerankConfig, llmCfg LLMConfig, next RAGContextClient) (*rerankingRAGClient, error) {
+	c := &rerankingRAGClient{
+		next:       next,
+		kind:       strings.ToLower(cfg.Mode),
+		candidates: cfg.Candidates,
+	}
+	model := cfg.Model
+	switch c.kind {
+	case "off", "":
+		return nil, nil
+
+	case "http":
+		rr := &httpReranker{
+			url:        cfg.URL,
+			api:        strings.ToLower(cfg.API),
+			model:      model,
+			store:      store,
+			httpClient: &http.Client{Timeout: 10 * time.Second},
+		}
+		if rr.url == "" {
+			return nil, fmt.Errorf("RERANKER_URL is required when RAG_RERANKER=http")
+		}
+		if rr.api != "cohere" && rr.api != "tei" {
+			return nil, fmt.Errorf("unsupported RERANKER_API=%q (supported: cohere, tei)", rr.api)
+		}
+		c.reranker = rr
+
+	case "grpc":
+		addr := cfg.GRPCAddr
+		if addr == "" {
+			return nil, fmt.Errorf("RERANKER_GRPC_ADDR is required when RAG_RERANKER=grpc")
+		}
+		opts := append(discovery.DialOptions(),
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+			grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+		)
+		conn, err := grpc.NewClient(addr, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("reranker: %w", err)
+		}
+		c.reranker = grpcReranker{client: pb.NewRerankerClient(conn), model: model}
+		c.close = func() { _ = conn.Close() }
+
+	case "llm":
+		llm, err := newLLMClient(ctx, store, llmCfg)
+		if err != nil {
+			return nil, fmt.Errorf("RAG_RERANKER=llm: %w", err)
+		}
+		if llm.Client == nil {
+			return nil, fmt.Errorf("RAG_RERANKER=llm needs a real LLM_PROVIDER, not %q", llm.Provider)
+		}
+		if model == "" {
+			model = llm.Model
+		}
+		c.reranker = llmReranker{client: llm.Client, model: model}
+
+	default:
+		return nil, fmt.Errorf("unsupported RAG_RERANKER=%q (supported: off, http, grpc, llm)", c.kind)
+	}
+	return c, nil
+}
+
+func (c *rerankingRAGClient) Close() {
+	if c != nil && c.close != nil {
+		c.close()
+	}
+}
+
+func (c *rerankingRAGClient) GetContext(ctx context.Context, req VectorQueryRequest) ([]VectorQueryMatch, error) {
+	if req.TopK <= 0 {
+		req.TopK = 2
+	}
+	if len(req.KnowledgeBases) == 0 {
+		req.KnowledgeBases = []string{defaultRAGKnowledgeBase}
+	}
+	wide := req
+	wide.TopK = req.TopK * c.candidates
+	candidates, err := c.next.GetContext(ctx, wide)
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 {
+		return candidates, nil
+	}
+
+	reranked, err := c.rerank(ctx, req.QueryText, candidates)
+	if err != nil {
+		log.Printf(
+			`{"timestamp":"%s","level":"warn","service":"%s","component":"RerankingRAGClient","reranker":%q,"error":%q,"message":"reranking failed; using retrieval order"}`,
+			time.Now().Format(time.RFC3339Nano), SERVICE_NAME, c.kind, err.Error(),
+		)
+		return topKPerKB(candidates, req.KnowledgeBases, req.TopK), nil
+	}
+	return topKPerKB(reranked, req.KnowledgeBases, req.TopK), nil
+}
+
+// rerank scores every candidate with the reranker and returns them sorted by
+// the new scores, best first.
+func (c *rerankingRAGClient) rerank(ctx context.Context, query string, candidates []VectorQueryMatch) ([]VectorQueryMatch, error) {
+	start := time.Now()
+	passages := make([]string, len(candidates))
+	for i, m := range candidates {
+		passages[i] = m.Text
+	}
+	scores, err := c.reranker.Rerank(ctx, query, passages)
+	if err == nil && len(scores) != len(candidates) {
+		err = fmt.Errorf("got %d scores for %d passages", len(scores), len(candidates))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	type change struct {
+		ID     string  `json:"id"`
+		KB     string  `json:"kb"`
+		Before float64 `json:"before"`
+		After  float64 `json:"after"`
+	}
+	changes := make([]change, len(candidates))
+	reranked := make([]VectorQueryMatch, len(candidates))
+	for i, m := range candidates {
+		changes[i] = change{ID: m.ID, KB: m.KnowledgeBase, Before: m.Score, After: scores[i]}
+		m.Score = scores[i]
+		reranked[i] = m
+	}
+	sort.SliceStable(reranked, func(i, j int) bool { return reranked[i].Score > reranked[j].Score })
+
+	scoresJSON, _ := json.Marshal(changes)
+	log.Printf(
+		`{"timestamp":"%s","level":"info","service":"%s","component":"RerankingRAGClient","method":"Rerank","reranker":%q,"query_text":%q,"candidates":%d,"latency_ms":%d,"scores":%s}`,
+		time.Now().Format(time.RFC3339Nano), SERVICE_NAME, c.kind, query, len(candidates), time.Since(start).Milliseconds(), scoresJSON,
+	)
+	return reranked, nil
+}
+
+// topKPerKB keeps the first k matches of each KB, grouped in kbs order.
+func topKPerKB(matches []VectorQueryMatch, kbs []string, k int) []VectorQueryMatch {
+	byKB := map[string][]VectorQueryMatch{}
+	for _, m := range matches {
+		if len(byKB[m.KnowledgeBase]) < k {
+			byKB[m.KnowledgeBase] = append(byKB[m.KnowledgeBase], m)
+		}
+	}
+	out := make([]VectorQueryMatch, 0, len(kbs)*k)
+	for _, kb := range kbs {
+		out = append(out, byKB[kb]...)
+	}
+	return out
+}
+
+// httpReranker calls a hosted or self-hosted rerank endpoint.
+type httpReranker struct {
+	url        string
+	api        string
+	model      string
+	store      *secrets.Store
+	httpClient *http.Client
+}
+
+func (r *httpReranker) Rerank(ctx context.Context, query string, passages []string) ([]float64, error) {
+	body := map[string]any{"query": query}
+	if r.api == "tei" {
+		body["texts"] = passages
+	} else {
+		body["documents"] = passages
+		body["top_n"] = len(passages)
+	}
+	if r.model != "" {
+		body["model"] = r.model
+	}
+	b, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, r.url, bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	apiKey, err := r.store.Lookup(ctx, "RERANKER_API_KEY")
+	if err != nil {
+		return nil, err
+	}
+	if apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	resp, err := r.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("rerank: HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	type result struct {
+		Index          int      `json:"index"`
+		RelevanceScore *float64 `json:"relevance_score"`
+		Score          *float64 `json:"score"`
+	}
+	var results []result
+	if r.api == "tei" {
+		err = json.NewDecoder(resp.Body).Decode(&results)
+	} else {
+		var out struct {
+			Results []result `json:"results"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&out)
+		results = out.Results
+	}
+	if err != nil {
+		return nil, fmt.Errorf("decode rerank response: %w", err)
+	}
+
+	scores := make([]float64, len(passages))
+	seen := 0
+	for _, res := range results {
+		score := res.RelevanceScore
+		if score == nil {
+			score = res.Score
+		}
+		if res.Index < 0 || res.Index >= len(passages) || score == nil {
+			return nil, fmt.Errorf("rerank: invalid result %+v", res)
+		}
+		scores[res.Index] = *score
+		seen++
+	}
+	if seen != len(passages) {
+		return nil, fmt.Errorf("rerank: scored %d of %d passages", seen, len(passages))
+	}
+	return scores, nil
+}
+
+// grpcReranker calls a Reranker service.
+type grpcReranker struct {
+	client pb.RerankerClient
+	model  string
+}
+
+func (r grpcReranker) Rerank(ctx context.Context, query string, passages []string) ([]float64, error) {
+	resp, err := r.client.Rerank(ctx, &pb.RerankRequest{Query: query, Passages: passages, Model: r.model})
+	if err != nil {
+		return nil, err
+	}
+	return resp.GetScores(), nil
+}
+
+// llmReranker asks the chat model to grade every passage in one call. It
+// needs no extra service but is slower and coarser than a cross-encoder.
+type llmReranker struct {
+	client chatCompleter
+	model  string
+}
+
+// llmScores extracts the first JSON object from a model reply, which may wrap
+// it in prose or code fences.
+var llmScores = regexp.MustCompile(`(?s)\{.*\}`)
+
+func (r llmReranker) Rerank(ctx context.Context, query string, passages []string) ([]float64, error) {
+	var user strings.Builder
+	fmt.Fprintf(&user, "Query: %s\n\n", query)
+	for i, p := range passages {
+		fmt.Fprintf(&user, "Passage %d:\n%s\n\n", i, p)
+	}
+	fmt.Fprintf(&user, `Return {"scores": [...]} with exactly %d numbers, one per passage in order.`, len(passages))
+
+	resp, err := r.client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model: r.model,
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: "You grade how well passages answer a query, from 0 (irrelevant) to 10 (answers it fully). Return STRICT JSON only."},
+			{Role: openai.ChatMessageRoleUser, Content: user.String()},
+		},
+		Temperature: 0,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("rerank: empty LLM response")
+	}
+	var out struct {
+		Scores []float64 `json:"scores"`
+	}
+	raw := llmScores.FindString(replyAnswer(resp.Choices[0].Message.Content))
+	if err := json.Unmarshal([]byte(raw), &out); err != nil {
+		return nil, fmt.Errorf("rerank: parse LLM scores: %w", err)
+	}
+	return out.Scores, nil
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// stubReranker scores a passage by its length, or fails.
+type stubReranker struct{ err error }
+
+func (r stubReranker) Rerank(_ context.Context, _ string, passages []string) ([]float64, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	scores := make([]float64, len(passages))
+	for i, p := range passages {
+		scores[i] = float64(len(p))
+	}
+	return scores, nil
+}
+
+// candidatesRAG returns top_k matches per KB with descending retrieval scores and
+// ascending text lengths, so reranking by length inverts each KB's order.
+type candidatesRAG struct{ topK int }
+
+func (r *candidatesRAG) GetContext(_ context.Context, req VectorQueryRequest) ([]VectorQueryMatch, error) {
+	r.topK = req.TopK
+	var out []VectorQueryMatch
+	for _, kb := range req.KnowledgeBases {
+		for i := range req.TopK {
+			out = append(out, VectorQueryMatch{
+				ID:            kb + "-" + strings.Repeat("x", i+1),
+				KnowledgeBase: kb,
+				Text:          strings.Repeat("x", i+1),
+				Score:         1 - float64(i)/10,
+			})
+		}
+	}
+	return out, nil
+}
+
+func TestRerankingRAGClient_ReordersPerKB(t *testing.T) {
+	next := &candidatesRAG{}
+	c := &rerankingRAGClient{next: next, reranker: stubReranker{}, kind: "stub", candidates: 3}
+
+	got, err := c.GetContext(context.Background(), VectorQueryRequest{QueryText: "q", TopK: 2, KnowledgeBases: []string{"Domain-KB", "Body-KB"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.topK != 6 {
+		t.Fatalf("retrieval top_k = %d, want 6", next.topK)
+	}
+	var ids []string
+	for _, m := range got {
+		ids = append(ids, m.ID)
+	}
+	want := "Domain-KB-xxxxxx,Domain-KB-xxxxx,Body-KB-xxxxxx,Body-KB-xxxxx"
+	if strings.Join(ids, ",") != want {
+		t.Fatalf("got %v, want %s", ids, want)
+	}
+	if got[0].Score != 6 {
+		t.Fatalf("score = %v, want the reranker's 6", got[0].Score)
+	}
+}
+
+func TestRerankingRAGClient_FallsBackToRetrievalOrder(t *testing.T) {
+	c := &rerankingRAGClient{next: &candidatesRAG{}, reranker: stubReranker{err: errors.New("down")}, kind: "stub", candidates: 3}
+
+	got, err := c.GetContext(context.Background(), VectorQueryRequest{QueryText: "q", TopK: 2, KnowledgeBases: []string{"Body-KB"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].ID != "Body-KB-x" || got[1].ID != "Body-KB-xx" || got[0].Score != 1 {
+		t.Fatalf("got %+v, want the first two candidates unchanged", got)
+	}
+}
+
+func TestHTTPReranker(t *testing.T) {
+	for _, api := range []string{"cohere", "tei"} {
+		t.Run(api, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Authorization") != "Bearer k" {
+					t.Errorf("Authorization = %q", r.Header.Get("Authorization"))
+				}
+				var body map[string]any
+				_ = json.NewDecoder(r.Body).Decode(&body)
+				if body["query"] != "q" || body["model"] != "m" {
+					t.Errorf("request body = %v", body)
+				}
+				// Results come back best-first, not in passage order.
+				if api == "tei" {
+					if len(body["texts"].([]any)) != 2 {
+						t.Errorf("texts = %v", body["texts"])
+					}
+					_, _ = w.Write([]byte(`[{"index":1,"score":0.9},{"index":0,"score":0.1}]`))
+					return
+				}
+				if len(body["documents"].([]any)) != 2 {
+					t.Errorf("documents = %v", body["documents"])
+				}
+				_, _ = w.Write([]byte(`{"results":[{"index":1,"relevance_score":0.9},{"index":0,"relevance_score":0.1}]}`))
+			}))
+			defer srv.Close()
+
+			t.Setenv("RAG_RERANKER", "http")
+			t.Setenv("RERANKER_URL", srv.URL)
+			t.Setenv("RERANKER_API", api)
+			t.Setenv("RERANKER_MODEL", "m")
+			t.Setenv("RERANKER_API_KEY", "k")
+			cfg := envConfig(t)
+			c, err := newRerankingRAGClient(context.Background(), nil, cfg.RAG.Rerank, cfg.LLM, &candidatesRAG{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			scores, err := c.reranker.Rerank(context.Background(), "q", []string{"a", "b"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(scores) != 2 || scores[0] != 0.1 || scores[1] != 0.9 {
+				t.Fatalf("scores = %v, want [0.1 0.9]", scores)
+			}
+		})
+	}
+}
+
+func TestNewRerankingRAGClient(t *testing.T) {
+	rerank := func() (*rerankingRAGClient, error) {
+		cfg, err := loadConfig()
+		if err != nil {
+			return nil, err
+		}
+		return newRerankingRAGClient(context.Background(), nil, cfg.RAG.Rerank, cfg.LLM, &candidatesRAG{})
+	}
+	if c, err := rerank(); c != nil || err != nil {
+		t.Fatalf("default: got %v, %v; want reranking off", c, err)
+	}
+	t.Setenv("RAG_RERANKER", "http")
+	if _, err := rerank(); err == nil {
+		t.Fatal("http without RERANKER_URL: want an error")
+	}
+	t.Setenv("RAG_RERANKER", "llm")
+	t.Setenv("LLM_PROVIDER", "mock")
+	if _, err := rerank(); err == nil {
+		t.Fatal("llm under the mock provider: want an error")
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"backend-go-model-gateway/service"
+)
+
+const (
+	ragTenancyOff      = "off"
+	ragTenancyRequired = "required"
+)
+
+var (
+	// errTenantRequired is returned for RAG requests without an authenticated
+	// tenant when RAG_TENANCY=required.
+	errTenantRequired = errors.New("rag: request has no tenant (RAG_TENANCY=required)")
+	// errInvalidTenant is returned for tenant IDs that are not safe to use as
+	// a namespace value.
+	errInvalidTenant = errors.New("rag: invalid tenant id")
+)
+
+// tenantIDPattern keeps namespace values to a conservative alphabet so they
+// read the same in every backend's filter syntax.
+var tenantIDPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,63}$`)
+
+// tenantRAGClient enforces tenant isolation (RAG_TENANCY=required): every
+// request is scoped to the namespace of the tenant the caller authenticated
+// (x-tenant-id gRPC metadata), overriding whatever the request carried, and
+// requests without a tenant are rejected rather than searched unscoped.
+// Documents are visible only to the tenant whose namespace they were indexed
+// under (RAG_NAMESPACE_FIELD).
+type tenantRAGClient struct {
+	next RAGContextClient
+}
+
+func (c tenantRAGClient) GetContext(ctx context.Context, req VectorQueryRequest) ([]VectorQueryMatch, error) {
+	tenant := service.TenantIDFromIncomingGRPC(ctx)
+	if tenant == "" {
+		return nil, errTenantRequired
+	}
+	if !tenantIDPattern.MatchString(tenant) {
+		return nil, fmt.Errorf("%w %q", errInvalidTenant, tenant)
+	}
+	req.Namespace = tenant
+	return c.next.GetContext(ctx, req)
+}
+
+// ragTenancy parses RAG_TENANCY (default: off).
+func ragTenancy(cfg RAGConfig) (string, error) {
+	switch mode := strings.ToLower(strings.TrimSpace(cfg.Tenancy)); mode {
+	case ragTenancyOff, ragTenancyRequired:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unsupported RAG_TENANCY=%q (supported: off, required)", mode)
+	}
+}
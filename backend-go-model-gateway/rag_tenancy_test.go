@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	pb "backend-go-model-gateway/proto/proto"
+	"backend-go-model-gateway/service"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestGetRAGContext_EnforcesTenantNamespace(t *testing.T) {
+	path := writeCorpus(t,
+		`{"id":"acme-1","text":"Quarterly health plan","namespace":"acme"}`,
+		`{"id":"globex-1","text":"Quarterly health plan","namespace":"globex"}`,
+		`{"id":"unowned","text":"Quarterly health plan"}`,
+	)
+	ec, err := LoadEmbeddedRAGClient(context.Background(), path, hashEmbedder{dims: 64})
+	if err != nil {
+		t.Fatalf("LoadEmbeddedRAGClient: %v", err)
+	}
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gs := grpc.NewServer()
+	pb.RegisterModelGatewayServer(gs, &server{vectorDB: tenantRAGClient{next: ec}})
+	go func() { _ = gs.Serve(lis) }()
+	t.Cleanup(gs.Stop)
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	client := pb.NewModelGatewayClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req := &pb.RAGContextRequest{Query: "health plan", TopK: 5}
+
+	acme := metadata.AppendToOutgoingContext(ctx, service.TenantIDMetadataKey, "acme")
+	resp, err := client.GetRAGContext(acme, req)
+	if err != nil {
+		t.Fatalf("GetRAGContext: %v", err)
+	}
+	if len(resp.GetMatches()) != 1 || resp.GetMatches()[0].GetId() != "acme-1" {
+		t.Fatalf("tenant acme got %v, want only acme-1", resp.GetMatches())
+	}
+
+	if _, err := client.GetRAGContext(ctx, req); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("without a tenant: got %v, want Unauthenticated", err)
+	}
+	bad := metadata.AppendToOutgoingContext(ctx, service.TenantIDMetadataKey, `acme" || true`)
+	if _, err := client.GetRAGContext(bad, req); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("malformed tenant: got %v, want InvalidArgument", err)
+	}
+}
+
+func TestRAGBackends_NamespaceClauses(t *testing.T) {
+	qc, err := NewQdrantRAGClient(envConfig(t).RAG, nil, fakeEmbedder{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := json.Marshal(qc.filter("acme", nil))
+	if got, want := string(b), `{"must":[{"key":"namespace","match":{"value":"acme"}}]}`; got != want {
+		t.Errorf("qdrant filter = %s, want %s", got, want)
+	}
+	if qc.filter("", nil) != nil {
+		t.Error("qdrant: expected no filter without a namespace")
+	}
+
+	mc, err := NewMilvusRAGClient(envConfig(t).RAG, nil, fakeEmbedder{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := mc.filter("acme", nil), `namespace == "acme"`; got != want {
+		t.Errorf("milvus filter = %s, want %s", got, want)
+	}
+
+	wc, err := NewWeaviateRAGClient(envConfig(t).RAG, nil, fakeEmbedder{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := wc.where("acme", nil), `, where: {path: ["namespace"], operator: Equal, valueText: "acme"}`; got != want {
+		t.Errorf("weaviate where = %s, want %s", got, want)
+	}
+
+	pc, err := newPGVectorRAGClient(envConfig(t).RAG)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, args := pc.searchSQL("Body-KB", "[1,0]", 2, "acme", nil)
+	if !strings.Contains(q, `WHERE "kb" = $2 AND "namespace" = $3`) || args[2] != "acme" {
+		t.Errorf("pgvector searchSQL = %s %v, want a namespace condition", q, args)
+	}
+}
+
+func TestInitRAGBackend_TenancyRequiresDirectBackend(t *testing.T) {
+	t.Setenv("RAG_TENANCY", "required")
+	t.Setenv("RAG_BACKEND", "memory")
+	if _, err := initRAGBackend(context.Background(), nil, envConfig(t)); err == nil {
+		t.Fatal("expected RAG_TENANCY=required to be rejected for the memory backend")
+	}
+
+	t.Setenv("RAG_TENANCY", "sometimes")
+	if _, err := loadConfig(); err == nil {
+		t.Fatal("expected an error for an unsupported RAG_TENANCY")
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// ragMinScore parses RAG_MIN_SCORE, the score a match needs to be put in the
+// prompt. It returns nil when unset: every match is used. Scores are
+// whatever the last retrieval stage produced (similarities, RRF scores with
+// RAG_RETRIEVAL_MODE=hybrid, reranker scores with RAG_RERANKER), so the
+// threshold must be tuned for the configured pipeline.
+func ragMinScore(cfg RAGConfig) (*float64, error) {
+	v := cfg.MinScore
+	if v == "" {
+		return nil, nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return nil, fmt.Errorf("RAG_MIN_SCORE: want a number, got %q", v)
+	}
+	return &f, nil
+}
+
+// relevantMatches keeps the matches scoring at least minScore, in order. A nil
+// minScore keeps them all.
+func relevantMatches(matches []VectorQueryMatch, minScore *float64) []VectorQueryMatch {
+	if minScore == nil {
+		return matches
+	}
+	kept := make([]VectorQueryMatch, 0, len(matches))
+	for _, m := range matches {
+		if m.Score >= *minScore {
+			kept = append(kept, m)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	pb "backend-go-model-gateway/proto/proto"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestRagMinScore(t *testing.T) {
+	if v, err := ragMinScore(envConfig(t).RAG); v != nil || err != nil {
+		t.Fatalf("unset: got %v, %v; want no threshold", v, err)
+	}
+	t.Setenv("RAG_MIN_SCORE", "0.35")
+	if v, err := ragMinScore(envConfig(t).RAG); err != nil || v == nil || *v != 0.35 {
+		t.Fatalf("got %v, %v; want 0.35", v, err)
+	}
+	t.Setenv("RAG_MIN_SCORE", "high")
+	if _, err := loadConfig(); err == nil {
+		t.Fatal("want an error for a non-numeric threshold")
+	}
+}
+
+// planRAG returns fixed matches regardless of the query.
+type planRAG []VectorQueryMatch
+
+func (r planRAG) GetContext(context.Context, VectorQueryRequest) ([]VectorQueryMatch, error) {
+	return r, nil
+}
+
+func TestGetPlan_MinScore(t *testing.T) {
+	var userPrompt string
+	llm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req openai.ChatCompletionRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		userPrompt = req.Messages[len(req.Messages)-1].Content
+		_ = json.NewEncoder(w).Encode(openai.ChatCompletionResponse{
+			Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Role: "assistant", Content: `{"steps":["rest"]}`}}},
+		})
+	}))
+	defer llm.Close()
+	cfg := openai.DefaultConfig("")
+	cfg.BaseURL = llm.URL
+
+	rag := planRAG{
+		{ID: "sleep-1", KnowledgeBase: "Body-KB", Text: "Sleep eight hours.", Score: 0.82},
+		{ID: "tax-1", KnowledgeBase: "Domain-KB", Text: "File taxes by April.", Score: 0.21},
+	}
+	for _, tc := range []struct {
+		name           string
+		minScore       float64
+		wantUngrounded bool
+		want, notWant  []string
+	}{
+		{name: "drops irrelevant matches", minScore: 0.5, want: []string{"<context>", "sleep-1"}, notWant: []string{"tax-1"}},
+		{name: "no match is relevant", minScore: 0.9, wantUngrounded: true, notWant: []string{"<context>", "sleep-1", "tax-1"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := &server{
+				llm:            &llmRuntime{Provider: providerOllama, Model: "m", Client: openai.NewClientWithConfig(cfg)},
+				vectorDB:       rag,
+				minScore:       &tc.minScore,
+				requestTimeout: time.Duration(defaultRequestTimeoutSec) * time.Second,
+			}
+			resp, err := s.GetPlan(context.Background(), &pb.PlanRequest{Prompt: "how should I recover?"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.GetUngrounded() != tc.wantUngrounded {
+				t.Errorf("ungrounded = %t, want %t", resp.GetUngrounded(), tc.wantUngrounded)
+			}
+			for _, part := range tc.want {
+				if !strings.Contains(userPrompt, part) {
+					t.Errorf("prompt lacks %q:\n%s", part, userPrompt)
+				}
+			}
+			for _, part := range tc.notWant {
+				if strings.Contains(userPrompt, part) {
+					t.Errorf("prompt contains %q:\n%s", part, userPrompt)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,517 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"backend-go-model-gateway/pkg/ragfilter"
+	"backend-go-model-gateway/pkg/secrets"
+)
+
+// WeaviateRAGClient implements RAGContextClient against Weaviate's GraphQL API
+// (RAG_BACKEND=weaviate), one class per knowledge base. Queries are either pure
+// vector searches (nearVector/nearText) or hybrid BM25 + vector searches.
+type WeaviateRAGClient struct {
+	baseURL    string
+	store      *secrets.Store
+	embedder   Embedder
+	httpClient *http.Client
+
+	classes        map[string]string
+	classPrefix    string
+	textProperty   string
+	sourceProperty string
+	fields         ragMetadataFields
+	// hybridAlpha enables hybrid search when set: 0 is pure keyword, 1 pure vector.
+	hybridAlpha *float64
+	// serverVectorizer leaves embedding to the class's Weaviate vectorizer
+	// module instead of the gateway's embedder.
+	serverVectorizer bool
+}
+
+// graphQLName matches valid GraphQL names, which Weaviate class and property
+// names must be since they are interpolated into the query.
+var graphQLName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// NewWeaviateRAGClient configures a WeaviateRAGClient from cfg.Weaviate.
+// embedder may be nil when WEAVIATE_VECTORIZER=weaviate.
+//
+//   - WEAVIATE_URL (default: http://localhost:8080)
+//   - WEAVIATE_API_KEY (optional; resolved through pkg/secrets, sent as a bearer token)
+//   - WEAVIATE_CLASSES explicit KB mapping, e.g. "Domain-KB=DomainDoc,Body-KB=BodyDoc"
+//   - WEAVIATE_CLASS_PREFIX prefix for derived names (Domain-KB -> <Prefix>DomainKB)
+//   - WEAVIATE_TEXT_PROPERTY / WEAVIATE_SOURCE_PROPERTY (default: text / source)
+//   - WEAVIATE_HYBRID_ALPHA enables hybrid search with this alpha (0-1)
+//   - WEAVIATE_VECTORIZER (default: gateway) — gateway or weaviate
+func NewWeaviateRAGClient(cfg RAGConfig, store *secrets.Store, embedder Embedder) (*WeaviateRAGClient, error) {
+	wc := cfg.Weaviate
+	c := &WeaviateRAGClient{
+		baseURL:        strings.TrimRight(wc.URL, "/"),
+		store:          store,
+		embedder:       embedder,
+		httpClient:     &http.Client{Timeout: 10 * time.Second},
+		classPrefix:    wc.ClassPrefix,
+		textProperty:   wc.TextProperty,
+		sourceProperty: wc.SourceProperty,
+		fields:         ragMetadataFieldsFromConfig(cfg),
+	}
+	var err error
+	if c.classes, err = parseKBMapping("WEAVIATE_CLASSES", wc.Classes, "Class"); err != nil {
+		return nil, err
+	}
+	for kb, class := range c.classes {
+		if !graphQLName.MatchString(class) {
+			return nil, fmt.Errorf("WEAVIATE_CLASSES: invalid class name %q for %s", class, kb)
+		}
+	}
+	for name, v := range map[string]string{
+		"WEAVIATE_TEXT_PROPERTY":   c.textProperty,
+		"WEAVIATE_SOURCE_PROPERTY": c.sourceProperty,
+		"RAG_TAGS_FIELD":           c.fields.tags,
+		"RAG_DOCUMENT_ID_FIELD":    c.fields.documentID,
+		"RAG_CREATED_AT_FIELD":     c.fields.createdAt,
+		"RAG_NAMESPACE_FIELD":      c.fields.namespace,
+	} {
+		if !graphQLName.MatchString(v) {
+			return nil, fmt.Errorf("%s: invalid property name %q", name, v)
+		}
+	}
+	if c.hybridAlpha, err = parseOptionalFloat("WEAVIATE_HYBRID_ALPHA", wc.HybridAlpha); err != nil {
+		return nil, err
+	}
+	if c.hybridAlpha != nil && (*c.hybridAlpha < 0 || *c.hybridAlpha > 1) {
+		return nil, fmt.Errorf("WEAVIATE_HYBRID_ALPHA: want a number between 0 and 1, got %q", wc.HybridAlpha)
+	}
+	switch v := strings.ToLower(wc.Vectorizer); v {
+	case "gateway":
+		if embedder == nil {
+			return nil, fmt.Errorf("WEAVIATE_VECTORIZER=gateway requires an embedder")
+		}
+	case "weaviate":
+		c.serverVectorizer = true
+	default:
+		return nil, fmt.Errorf("unsupported WEAVIATE_VECTORIZER=%q (supported: gateway, weaviate)", v)
+	}
+	return c, nil
+}
+
+// classFor maps a conceptual KB name to its Weaviate class. Derived names drop
+// characters GraphQL does not allow and start with an upper-case letter, as
+// Weaviate requires.
+func (c *WeaviateRAGClient) classFor(kb string) string {
+	if class, ok := c.classes[kb]; ok {
+		return class
+	}
+	name := []rune(c.classPrefix + strings.Map(func(r rune) rune {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_') {
+			return r
+		}
+		return -1
+	}, kb))
+	if len(name) > 0 {
+		name[0] = unicode.ToUpper(name[0])
+	}
+	return string(name)
+}
+
+// searchQuery builds the GraphQL Get query for one class. vector is nil when
+// Weaviate vectorizes the query itself.
+func (c *WeaviateRAGClient) searchQuery(class, text string, vector []float32, limit int, ns string, f *ragfilter.Filter) string {
+	// JSON string escapes are valid GraphQL, and pgvector's [1,2,3] text format
+	// is also a GraphQL list literal.
+	quoted, _ := json.Marshal(text)
+	var arg string
+	switch {
+	case c.hybridAlpha != nil:
+		arg = fmt.Sprintf("hybrid: {query: %s, alpha: %s", quoted, strconv.FormatFloat(*c.hybridAlpha, 'g', -1, 64))
+		if vector != nil {
+			arg += ", vector: " + pgvectorLiteral(vector)
+		}
+		arg += "}"
+	case vector != nil:
+		arg = "nearVector: {vector: " + pgvectorLiteral(vector) + "}"
+	default:
+		arg = fmt.Sprintf("nearText: {concepts: [%s]}", quoted)
+	}
+	return fmt.Sprintf("{ Get { %s(%s%s, limit: %d) { %s %s _additional { id distance score } } } }",
+		class, arg, c.where(ns, f), limit, c.textProperty, c.sourceProperty)
+}
+
+// keywordQuery builds a BM25 Get query for one class, searching the text property.
+func (c *WeaviateRAGClient) keywordQuery(class, text string, limit int, ns string, f *ragfilter.Filter) string {
+	quoted, _ := json.Marshal(text)
+	return fmt.Sprintf("{ Get { %s(bm25: {query: %s, properties: [%q]}%s, limit: %d) { %s %s _additional { id distance score } } } }",
+		class, quoted, c.textProperty, c.where(ns, f), limit, c.textProperty, c.sourceProperty)
+}
+
+// where renders the tenant namespace and a metadata filter as a GraphQL where
+// argument (with a leading comma), or "" when there is nothing to filter.
+func (c *WeaviateRAGClient) where(ns string, f *ragfilter.Filter) string {
+	texts := func(v any) string {
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+	var operands []string
+	if ns != "" {
+		operands = append(operands, fmt.Sprintf("{path: [%q], operator: Equal, valueText: %s}", c.fields.namespace, texts(ns)))
+	}
+	if f == nil {
+		f = &ragfilter.Filter{}
+	}
+	if len(f.Sources) > 0 {
+		operands = append(operands, fmt.Sprintf("{path: [%q], operator: ContainsAny, valueText: %s}", c.sourceProperty, texts(f.Sources)))
+	}
+	if len(f.Tags) > 0 {
+		operands = append(operands, fmt.Sprintf("{path: [%q], operator: ContainsAll, valueText: %s}", c.fields.tags, texts(f.Tags)))
+	}
+	if len(f.DocumentIDs) > 0 {
+		operands = append(operands, fmt.Sprintf("{path: [%q], operator: ContainsAny, valueText: %s}", c.fields.documentID, texts(f.DocumentIDs)))
+	}
+	if !f.CreatedAfter.IsZero() {
+		operands = append(operands, fmt.Sprintf("{path: [%q], operator: GreaterThanEqual, valueInt: %d}", c.fields.createdAt, f.CreatedAfter.Unix()))
+	}
+	if !f.CreatedBefore.IsZero() {
+		operands = append(operands, fmt.Sprintf("{path: [%q], operator: LessThan, valueInt: %d}", c.fields.createdAt, f.CreatedBefore.Unix()))
+	}
+	switch len(operands) {
+	case 0:
+		return ""
+	case 1:
+		return ", where: " + operands[0]
+	}
+	return ", where: {operator: And, operands: [" + strings.Join(operands, ", ") + "]}"
+}
+
+func (c *WeaviateRAGClient) GetContext(ctx context.Context, req VectorQueryRequest) ([]VectorQueryMatch, error) {
+	if req.TopK <= 0 {
+		req.TopK = 2
+	}
+	kbs := req.KnowledgeBases
+	if len(kbs) == 0 {
+		kbs = []string{defaultRAGKnowledgeBase}
+	}
+
+	var vec []float32
+	if !c.serverVectorizer {
+		var err error
+		if vec, err = c.embedder.Embed(ctx, req.QueryText); err != nil {
+			return nil, err
+		}
+	}
+
+	matches, err := searchKBs(ctx, "WeaviateRAGClient", kbs, func(ctx context.Context, kb string) ([]VectorQueryMatch, error) {
+		return c.search(ctx, kb, c.searchQuery(c.classFor(kb), req.QueryText, vec, req.TopK, req.Namespace, req.Filter))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("weaviate: %w", err)
+	}
+
+	log.Printf(
+		`{"timestamp":"%s","level":"info","service":"%s","component":"WeaviateRAGClient","method":"GetContext","weaviate_url":%q,"hybrid":%t,"query_text":%q,"top_k":%d,"match_count":%d}`,
+		time.Now().Format(time.RFC3339Nano), SERVICE_NAME, c.baseURL, c.hybridAlpha != nil, req.QueryText, req.TopK, len(matches),
+	)
+	return matches, nil
+}
+
+// KeywordSearch ranks objects with Weaviate's BM25 operator. It backs
+// RAG_RETRIEVAL_MODE=hybrid's gateway-side fusion; WEAVIATE_HYBRID_ALPHA is
+// Weaviate's own alternative and needs no second query.
+func (c *WeaviateRAGClient) KeywordSearch(ctx context.Context, req VectorQueryRequest) ([]VectorQueryMatch, error) {
+	if req.TopK <= 0 {
+		req.TopK = 2
+	}
+	kbs := req.KnowledgeBases
+	if len(kbs) == 0 {
+		kbs = []string{defaultRAGKnowledgeBase}
+	}
+	matches, err := searchKBs(ctx, "WeaviateRAGClient", kbs, func(ctx context.Context, kb string) ([]VectorQueryMatch, error) {
+		return c.search(ctx, kb, c.keywordQuery(c.classFor(kb), req.QueryText, req.TopK, req.Namespace, req.Filter))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("weaviate: %w", err)
+	}
+	return matches, nil
+}
+
+type weaviateAdditional struct {
+	ID       string   `json:"id"`
+	Distance *float64 `json:"distance"`
+	// Score is only set for hybrid queries; Weaviate renders it as a string.
+	Score json.RawMessage `json:"score"`
+}
+
+func (c *WeaviateRAGClient) search(ctx context.Context, kb, query string) ([]VectorQueryMatch, error) {
+	class := c.classFor(kb)
+	var data struct {
+		Get map[string][]map[string]json.RawMessage `json:"Get"`
+	}
+	if err := c.graphql(ctx, query, &data); err != nil {
+		return nil, fmt.Errorf("query %s: %w", class, err)
+	}
+
+	objects := data.Get[class]
+	matches := make([]VectorQueryMatch, 0, len(objects))
+	for _, obj := range objects {
+		var text, source string
+		var add weaviateAdditional
+		_ = json.Unmarshal(obj[c.textProperty], &text)
+		_ = json.Unmarshal(obj[c.sourceProperty], &source)
+		_ = json.Unmarshal(obj["_additional"], &add)
+		if source == "" {
+			source = "weaviate"
+		}
+		matches = append(matches, VectorQueryMatch{
+			ID:            add.ID,
+			Score:         add.score(),
+			RawScore:      add.raw(),
+			Text:          text,
+			Source:        source,
+			KnowledgeBase: kb,
+		})
+	}
+	return matches, nil
+}
+
+// score returns a higher-is-better relevance: the fused score for hybrid
+// queries, otherwise 1 - cosine distance.
+func (a weaviateAdditional) score() float64 {
+	if len(a.Score) > 0 && string(a.Score) != "null" {
+		raw := strings.Trim(string(a.Score), `"`)
+		if f, err := strconv.ParseFloat(raw, 64); err == nil {
+			return f
+		}
+	}
+	if a.Distance != nil {
+		return 1 - *a.Distance
+	}
+	return 0
+}
+
+// raw returns the distance Weaviate reported, or the fused score for hybrid
+// queries.
+func (a weaviateAdditional) raw() float64 {
+	if a.Distance != nil {
+		return *a.Distance
+	}
+	return a.score()
+}
+
+// graphql runs query and decodes its data into out.
+func (c *WeaviateRAGClient) graphql(ctx context.Context, query string, out any) error {
+	resp, err := c.do(ctx, http.MethodPost, "/v1/graphql", map[string]string{"query": query})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	// GraphQL reports errors (unknown class, bad property) with HTTP 200.
+	if len(body.Errors) > 0 {
+		return errors.New(body.Errors[0].Message)
+	}
+	return json.Unmarshal(body.Data, out)
+}
+
+// do sends a JSON request to Weaviate's REST API (without a body when body is
+// nil). Non-2xx responses are returned as *httpStatusError.
+func (c *WeaviateRAGClient) do(ctx context.Context, method, path string, body any) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(b)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	apiKey, err := c.store.Lookup(ctx, "WEAVIATE_API_KEY")
+	if err != nil {
+		return nil, err
+	}
+	if apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, &httpStatusError{code: resp.StatusCode, body: strings.TrimSpace(string(msg))}
+	}
+	return resp, nil
+}
+
+// ReplaceDocument batch-deletes the document's objects, then batch-creates
+// the new chunks. Objects carry no vector when Weaviate vectorizes them.
+func (c *WeaviateRAGClient) ReplaceDocument(ctx context.Context, kb, namespace, documentID string, chunks []ingestChunk) error {
+	class := c.classFor(kb)
+	// The batch delete endpoint takes the REST (JSON) form of a where filter.
+	where := map[string]any{"path": []string{c.fields.documentID}, "operator": "Equal", "valueText": documentID}
+	if namespace != "" {
+		where = map[string]any{"operator": "And", "operands": []any{
+			where,
+			map[string]any{"path": []string{c.fields.namespace}, "operator": "Equal", "valueText": namespace},
+		}}
+	}
+	resp, err := c.do(ctx, http.MethodDelete, "/v1/batch/objects", map[string]any{
+		"match": map[string]any{"class": class, "where": where},
+	})
+	if err != nil {
+		return fmt.Errorf("weaviate: delete %s from %s: %w", documentID, class, err)
+	}
+	resp.Body.Close()
+
+	objects := make([]map[string]any, len(chunks))
+	for i, ch := range chunks {
+		props := map[string]any{
+			c.textProperty:      ch.Text,
+			c.sourceProperty:    ch.Source,
+			c.fields.documentID: ch.DocumentID,
+			c.fields.tags:       ch.Tags,
+			c.fields.createdAt:  ch.CreatedAt.Unix(),
+		}
+		if ch.Namespace != "" {
+			props[c.fields.namespace] = ch.Namespace
+		}
+		obj := map[string]any{"class": class, "id": ch.ID, "properties": props}
+		if ch.Embedding != nil {
+			obj["vector"] = ch.Embedding
+		}
+		objects[i] = obj
+	}
+	resp, err = c.do(ctx, http.MethodPost, "/v1/batch/objects", map[string]any{"objects": objects})
+	if err != nil {
+		return fmt.Errorf("weaviate: write to %s: %w", class, err)
+	}
+	defer resp.Body.Close()
+
+	// Batch writes report per-object failures with HTTP 200.
+	var results []struct {
+		ID     string `json:"id"`
+		Result struct {
+			Errors *struct {
+				Error []struct {
+					Message string `json:"message"`
+				} `json:"error"`
+			} `json:"errors"`
+		} `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		return fmt.Errorf("weaviate: decode %s batch response: %w", class, err)
+	}
+	for _, r := range results {
+		if r.Result.Errors != nil && len(r.Result.Errors.Error) > 0 {
+			return fmt.Errorf("weaviate: write %s to %s: %s", r.ID, class, r.Result.Errors.Error[0].Message)
+		}
+	}
+	return nil
+}
+
+// CreateKB creates kb's class unless it exists, with the properties ingestion
+// writes. Objects carry gateway-computed vectors unless Weaviate vectorizes
+// them (WEAVIATE_VECTORIZER=weaviate), in which case the class uses the
+// server's default vectorizer module.
+func (c *WeaviateRAGClient) CreateKB(ctx context.Context, kb string, _ int) error {
+	class := c.classFor(kb)
+	resp, err := c.do(ctx, http.MethodGet, "/v1/schema/"+url.PathEscape(class), nil)
+	if err == nil {
+		resp.Body.Close()
+		return nil
+	}
+	if !isHTTPStatus(err, http.StatusNotFound) {
+		return fmt.Errorf("weaviate: get %s: %w", class, err)
+	}
+
+	prop := func(name, dataType string) map[string]any {
+		return map[string]any{"name": name, "dataType": []string{dataType}}
+	}
+	schema := map[string]any{
+		"class": class,
+		"properties": []any{
+			prop(c.textProperty, "text"),
+			prop(c.sourceProperty, "text"),
+			prop(c.fields.documentID, "text"),
+			prop(c.fields.tags, "text[]"),
+			prop(c.fields.createdAt, "int"),
+			prop(c.fields.namespace, "text"),
+		},
+	}
+	if !c.serverVectorizer {
+		schema["vectorizer"] = "none"
+	}
+	resp, err = c.do(ctx, http.MethodPost, "/v1/schema", schema)
+	if err != nil {
+		return fmt.Errorf("weaviate: create %s: %w", class, err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// DropKB deletes kb's class and its objects. A missing class is not an error.
+func (c *WeaviateRAGClient) DropKB(ctx context.Context, kb string) error {
+	class := c.classFor(kb)
+	resp, err := c.do(ctx, http.MethodDelete, "/v1/schema/"+url.PathEscape(class), nil)
+	if isHTTPStatus(err, http.StatusNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("weaviate: delete %s: %w", class, err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// KBStats aggregates the object count and newest created_at of kb's class.
+func (c *WeaviateRAGClient) KBStats(ctx context.Context, kb string) (kbStats, error) {
+	class := c.classFor(kb)
+	var data struct {
+		Aggregate map[string][]map[string]json.RawMessage `json:"Aggregate"`
+	}
+	query := fmt.Sprintf("{ Aggregate { %s { meta { count } %s { maximum } } } }", class, c.fields.createdAt)
+	if err := c.graphql(ctx, query, &data); err != nil {
+		return kbStats{}, fmt.Errorf("weaviate: aggregate %s: %w", class, err)
+	}
+	var st kbStats
+	if groups := data.Aggregate[class]; len(groups) > 0 {
+		var meta struct {
+			Count int64 `json:"count"`
+		}
+		var created struct {
+			Maximum *float64 `json:"maximum"`
+		}
+		_ = json.Unmarshal(groups[0]["meta"], &meta)
+		_ = json.Unmarshal(groups[0][c.fields.createdAt], &created)
+		st.Documents = meta.Count
+		if created.Maximum != nil {
+			st.LastUpdated = time.Unix(int64(*created.Maximum), 0).UTC()
+		}
+	}
+	return st, nil
+}
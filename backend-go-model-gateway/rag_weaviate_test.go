@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"backend-go-model-gateway/pkg/ragfilter"
+)
+
+func TestWeaviateRAGClient_QueriesClassPerKB(t *testing.T) {
+	var mu sync.Mutex
+	var queries []string
+	weaviate := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/graphql" || r.Header.Get("Authorization") != "Bearer wk" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		var body struct {
+			Query string `json:"query"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		queries = append(queries, body.Query)
+		mu.Unlock()
+
+		switch {
+		case strings.Contains(body.Query, "PagiDomainKB("):
+			_, _ = w.Write([]byte(`{"data":{"Get":{"PagiDomainKB":[
+				{"text":"Onboarding protocol","source":"handbook.md","_additional":{"id":"a1","distance":0.1,"score":null}},
+				{"text":"Older note","source":null,"_additional":{"id":"a2","distance":0.4,"score":null}}
+			]}}}`))
+		case strings.Contains(body.Query, "BodyDoc("):
+			_, _ = w.Write([]byte(`{"data":{"Get":{"BodyDoc":[{"text":"Sleep 8h","source":"log","_additional":{"id":"b1","distance":0.25}}]}}}`))
+		default:
+			_, _ = w.Write([]byte(`{"data":{"Get":{"PagiSoulKB":null}},"errors":[{"message":"Cannot query field \"PagiSoulKB\" on type \"GetObjectsObj\"."}]}`))
+		}
+	}))
+	t.Cleanup(weaviate.Close)
+
+	t.Setenv("WEAVIATE_URL", weaviate.URL)
+	t.Setenv("WEAVIATE_API_KEY", "wk")
+	t.Setenv("WEAVIATE_CLASS_PREFIX", "pagi")
+	t.Setenv("WEAVIATE_CLASSES", "Body-KB=BodyDoc")
+
+	c, err := NewWeaviateRAGClient(envConfig(t).RAG, nil, fakeEmbedder{})
+	if err != nil {
+		t.Fatalf("NewWeaviateRAGClient: %v", err)
+	}
+
+	matches, err := c.GetContext(context.Background(), VectorQueryRequest{
+		QueryText:      "new user protocol",
+		TopK:           2,
+		KnowledgeBases: []string{"Domain-KB", "Body-KB", "Soul-KB"},
+	})
+	if err != nil {
+		t.Fatalf("GetContext: %v", err)
+	}
+
+	// Soul-KB's missing class is skipped, not fatal.
+	want := []VectorQueryMatch{
+		{ID: "a1", Score: 0.9, RawScore: 0.1, Text: "Onboarding protocol", Source: "handbook.md", KnowledgeBase: "Domain-KB"},
+		{ID: "a2", Score: 0.6, RawScore: 0.4, Text: "Older note", Source: "weaviate", KnowledgeBase: "Domain-KB"},
+		{ID: "b1", Score: 0.75, RawScore: 0.25, Text: "Sleep 8h", Source: "log", KnowledgeBase: "Body-KB"},
+	}
+	if len(matches) != len(want) {
+		t.Fatalf("got %d matches, want %d: %+v", len(matches), len(want), matches)
+	}
+	for i := range want {
+		if matches[i] != want[i] {
+			t.Errorf("match[%d] = %+v, want %+v", i, matches[i], want[i])
+		}
+	}
+
+	for _, q := range queries {
+		if !strings.Contains(q, "nearVector: {vector: [17,1,0]}") || !strings.Contains(q, "limit: 2") {
+			t.Errorf("unexpected query: %s", q)
+		}
+	}
+}
+
+func TestWeaviateRAGClient_SearchQuery(t *testing.T) {
+	t.Setenv("WEAVIATE_HYBRID_ALPHA", "0.25")
+	c, err := NewWeaviateRAGClient(envConfig(t).RAG, nil, fakeEmbedder{})
+	if err != nil {
+		t.Fatalf("NewWeaviateRAGClient: %v", err)
+	}
+	got := c.searchQuery("BodyKB", `say "hi"`, []float32{0.5, 1}, 3, "", nil)
+	want := `{ Get { BodyKB(hybrid: {query: "say \"hi\"", alpha: 0.25, vector: [0.5,1]}, limit: 3) { text source _additional { id distance score } } } }`
+	if got != want {
+		t.Errorf("hybrid query:\n got %s\nwant %s", got, want)
+	}
+
+	t.Setenv("WEAVIATE_HYBRID_ALPHA", "")
+	t.Setenv("WEAVIATE_VECTORIZER", "weaviate")
+	c, err = NewWeaviateRAGClient(envConfig(t).RAG, nil, nil)
+	if err != nil {
+		t.Fatalf("NewWeaviateRAGClient: %v", err)
+	}
+	got = c.searchQuery("BodyKB", "sleep", nil, 2, "", nil)
+	want = `{ Get { BodyKB(nearText: {concepts: ["sleep"]}, limit: 2) { text source _additional { id distance score } } } }`
+	if got != want {
+		t.Errorf("nearText query:\n got %s\nwant %s", got, want)
+	}
+
+	if score := (weaviateAdditional{Score: json.RawMessage(`"0.8125"`)}).score(); score != 0.8125 {
+		t.Errorf("hybrid score = %v, want 0.8125", score)
+	}
+}
+
+func TestWeaviateRAGClient_RejectsInvalidNames(t *testing.T) {
+	t.Setenv("WEAVIATE_CLASSES", "Domain-KB=Domain-Doc")
+	if _, err := NewWeaviateRAGClient(envConfig(t).RAG, nil, fakeEmbedder{}); err == nil {
+		t.Fatal("expected an error for a class name GraphQL cannot express")
+	}
+}
+
+func TestWeaviateRAGClient_WhereFilter(t *testing.T) {
+	c, err := NewWeaviateRAGClient(envConfig(t).RAG, nil, fakeEmbedder{})
+	if err != nil {
+		t.Fatalf("NewWeaviateRAGClient: %v", err)
+	}
+	if got, want := c.where("", &ragfilter.Filter{Tags: []string{"health"}}),
+		`, where: {path: ["tags"], operator: ContainsAll, valueText: ["health"]}`; got != want {
+		t.Errorf("single operand:\n got %s\nwant %s", got, want)
+	}
+
+	got := c.searchQuery("BodyKB", "sleep", []float32{1}, 2, "", &ragfilter.Filter{
+		DocumentIDs:   []string{"doc-1"},
+		CreatedBefore: time.Unix(1700000000, 0),
+	})
+	want := `{ Get { BodyKB(nearVector: {vector: [1]}, where: {operator: And, operands: [` +
+		`{path: ["document_id"], operator: ContainsAny, valueText: ["doc-1"]}, ` +
+		`{path: ["created_at"], operator: LessThan, valueInt: 1700000000}]}, limit: 2) { text source _additional { id distance score } } } }`
+	if got != want {
+		t.Errorf("filtered query:\n got %s\nwant %s", got, want)
+	}
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"backend-go-model-gateway/internal/logger"
+	"backend-go-model-gateway/service"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// retryAfterMetadataKey is the response header metadata telling a rate
+// limited caller how many whole seconds to wait.
+const retryAfterMetadataKey = "retry-after"
+
+// anonymousCaller is the bucket shared by callers with neither an mTLS
+// identity nor an x-caller-id.
+const anonymousCaller = "anonymous"
+
+// maxRateLimitBuckets bounds the callers tracked. Past it the least recently
+// seen caller is forgotten and starts over with a full bucket.
+const maxRateLimitBuckets = 10000
+
+// rateLimit is a token bucket's refill rate and size.
+type rateLimit struct {
+	perSecond float64
+	burst     int
+}
+
+// rateLimiter is a token bucket per caller. The caller is the mTLS peer
+// identity when there is one, else the x-caller-id metadata; the latter is
+// self-declared, so it separates well-behaved callers rather than fencing
+// off hostile ones. Each caller gets its own limit from RATE_LIMIT_CALLERS,
+// or the default.
+//
+// A nil *rateLimiter admits everything (RATE_LIMIT_* unset).
+type rateLimiter struct {
+	def     rateLimit
+	callers map[string]rateLimit
+	now     func() time.Time
+
+	mu         sync.Mutex
+	maxBuckets int
+	buckets    map[string]*list.Element
+	order      *list.List // of *tokenBucket; front = most recently used
+	limited    int
+}
+
+type tokenBucket struct {
+	caller string
+	limit  rateLimit
+	tokens float64
+	last   time.Time
+}
+
+// limits parses RATE_LIMIT_RPS and RATE_LIMIT_BURST, the default limit, and
+// RATE_LIMIT_CALLERS, caller=rps[:burst] entries (rps 0: unlimited). The
+// burst defaults to the rate rounded up.
+func (c RateLimitConfig) limits() (rateLimit, map[string]rateLimit, error) {
+	burst := ""
+	if c.Burst != 0 {
+		burst = strconv.Itoa(c.Burst)
+	}
+	def, err := parseRateLimit(strconv.FormatFloat(c.RPS, 'g', -1, 64), burst)
+	if err != nil {
+		return rateLimit{}, nil, fmt.Errorf("RATE_LIMIT_RPS/RATE_LIMIT_BURST: %w", err)
+	}
+	callers := map[string]rateLimit{}
+	for _, entry := range c.Callers {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		// SPIFFE IDs contain colons but no equals sign.
+		i := strings.LastIndex(entry, "=")
+		if i <= 0 {
+			return rateLimit{}, nil, fmt.Errorf("RATE_LIMIT_CALLERS: %q is not caller=rps[:burst]", entry)
+		}
+		rps, burst, _ := strings.Cut(entry[i+1:], ":")
+		limit, err := parseRateLimit(rps, burst)
+		if err != nil {
+			return rateLimit{}, nil, fmt.Errorf("RATE_LIMIT_CALLERS: %s: %w", entry[:i], err)
+		}
+		callers[strings.TrimSpace(entry[:i])] = limit
+	}
+	return def, callers, nil
+}
+
+// rateLimiterFromConfig returns the limiter cfg describes, or nil when no
+// limit is set.
+func rateLimiterFromConfig(cfg RateLimitConfig) (*rateLimiter, error) {
+	def, callers, err := cfg.limits()
+	if err != nil {
+		return nil, err
+	}
+	if def.perSecond == 0 && len(callers) == 0 {
+		return nil, nil
+	}
+	return newRateLimiter(def, callers), nil
+}
+
+func parseRateLimit(rps, burst string) (rateLimit, error) {
+	perSecond, err := strconv.ParseFloat(strings.TrimSpace(rps), 64)
+	if err != nil || perSecond < 0 || math.IsInf(perSecond, 0) || math.IsNaN(perSecond) {
+		return rateLimit{}, fmt.Errorf("rate %q is not a number of requests per second", rps)
+	}
+	limit := rateLimit{perSecond: perSecond, burst: max(1, int(math.Ceil(perSecond)))}
+	if burst = strings.TrimSpace(burst); burst != "" {
+		if limit.burst, err = strconv.Atoi(burst); err != nil || limit.burst < 1 {
+			return rateLimit{}, fmt.Errorf("burst %q is not a positive integer", burst)
+		}
+	}
+	return limit, nil
+}
+
+func newRateLimiter(def rateLimit, callers map[string]rateLimit) *rateLimiter {
+	return &rateLimiter{def: def, callers: callers, now: time.Now, maxBuckets: maxRateLimitBuckets, buckets: map[string]*list.Element{}, order: list.New()}
+}
+
+// caller names who made an RPC and returns the limit that applies. An mTLS
+// peer's limit may be configured under any of its names.
+func (l *rateLimiter) caller(ctx context.Context) (string, rateLimit) {
+	if id, ok := peerIdentityFromContext(ctx); ok {
+		for _, name := range append([]string{id.Name}, id.names()...) {
+			if limit, ok := l.callers[name]; ok {
+				return id.Name, limit
+			}
+		}
+		return id.Name, l.def
+	}
+	name := service.CallerIDFromIncomingGRPC(ctx)
+	if name == "" {
+		name = anonymousCaller
+	}
+	if limit, ok := l.callers[name]; ok {
+		return name, limit
+	}
+	return name, l.def
+}
+
+// take spends a token from caller's bucket. Without one it returns how long
+// until the next token.
+func (l *rateLimiter) take(caller string, limit rateLimit) (bool, time.Duration) {
+	if limit.perSecond == 0 {
+		return true, 0
+	}
+	now := l.now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var b *tokenBucket
+	if el, ok := l.buckets[caller]; ok {
+		l.order.MoveToFront(el)
+		b = el.Value.(*tokenBucket)
+	} else {
+		if l.order.Len() >= l.maxBuckets {
+			oldest := l.order.Back()
+			l.order.Remove(oldest)
+			delete(l.buckets, oldest.Value.(*tokenBucket).caller)
+		}
+		b = &tokenBucket{caller: caller, limit: limit, tokens: float64(limit.burst), last: now}
+		l.buckets[caller] = l.order.PushFront(b)
+	}
+	b.refill(now)
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	l.limited++
+	return false, time.Duration((1 - b.tokens) / limit.perSecond * float64(time.Second))
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(float64(b.limit.burst), b.tokens+elapsed*b.limit.perSecond)
+	}
+	b.last = now
+}
+
+// admit applies the caller's limit to an RPC. A rejection is
+// RESOURCE_EXHAUSTED with a RetryInfo detail and retry-after header
+// metadata. Health checks are exempt.
+func (l *rateLimiter) admit(ctx context.Context, fullMethod string) error {
+	if l == nil || strings.HasPrefix(fullMethod, "/grpc.health.v1.Health/") {
+		return nil
+	}
+	caller, limit := l.caller(ctx)
+	ok, wait := l.take(caller, limit)
+	if ok {
+		return nil
+	}
+	lg := logger.NewContextLogger(service.ContextWithTraceIDFromIncomingGRPC(ctx))
+	lg.Warn("grpc_rate_limited", "method", fullMethod, "caller", caller, "retry_after_ms", wait.Milliseconds())
+	seconds := int(math.Ceil(wait.Seconds()))
+	_ = grpc.SetHeader(ctx, metadata.Pairs(retryAfterMetadataKey, strconv.Itoa(max(1, seconds))))
+	msg := fmt.Sprintf("rate limit exceeded for caller %q (%g requests/s, burst %d); retry after %s", caller, limit.perSecond, limit.burst, wait.Round(time.Millisecond))
+	st, err := status.New(codes.ResourceExhausted, msg).WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(wait)})
+	if err != nil {
+		return status.Error(codes.ResourceExhausted, msg)
+	}
+	return st.Err()
+}
+
+// unaryInterceptor rate limits unary RPCs. It runs after peer authorization,
+// which attaches the mTLS identity. A nil limiter returns nil.
+func (l *rateLimiter) unaryInterceptor() grpc.UnaryServerInterceptor {
+	if l == nil {
+		return nil
+	}
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := l.admit(ctx, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// streamInterceptor is unaryInterceptor for streaming RPCs: opening a stream
+// takes one token.
+func (l *rateLimiter) streamInterceptor() grpc.StreamServerInterceptor {
+	if l == nil {
+		return nil
+	}
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := l.admit(ss.Context(), info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+// status reports the limits for GET /admin/status.
+func (l *rateLimiter) status() map[string]any {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	callers := map[string]string{}
+	for name, limit := range l.callers {
+		callers[name] = fmt.Sprintf("%g/s burst %d", limit.perSecond, limit.burst)
+	}
+	return map[string]any{
+		"default_rps":   l.def.perSecond,
+		"default_burst": l.def.burst,
+		"callers":       callers,
+		"tracked":       len(l.buckets),
+		"limited":       l.limited,
+	}
+}
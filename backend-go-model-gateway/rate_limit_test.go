@@ -0,0 +1,225 @@
+package main
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	pb "backend-go-model-gateway/proto/proto"
+	"backend-go-model-gateway/service"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	grpc_health_v1 "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestRateLimiterFromConfig(t *testing.T) {
+	t.Setenv("RATE_LIMIT_RPS", "")
+	t.Setenv("RATE_LIMIT_BURST", "")
+	t.Setenv("RATE_LIMIT_CALLERS", "")
+	if l, err := rateLimiterFromConfig(envConfig(t).RateLimit); l != nil || err != nil {
+		t.Fatalf("unset = %v, %v", l, err)
+	}
+
+	t.Setenv("RATE_LIMIT_RPS", "2.5")
+	t.Setenv("RATE_LIMIT_CALLERS", "eval-runner=0.5:2, spiffe://pagi.test/sa/bff=0")
+	l, err := rateLimiterFromConfig(envConfig(t).RateLimit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.def != (rateLimit{perSecond: 2.5, burst: 3}) {
+		t.Fatalf("default = %+v", l.def)
+	}
+	if l.callers["eval-runner"] != (rateLimit{perSecond: 0.5, burst: 2}) || l.callers["spiffe://pagi.test/sa/bff"].perSecond != 0 {
+		t.Fatalf("callers = %+v", l.callers)
+	}
+
+	for _, bad := range []string{"eval-runner", "eval-runner=fast", "eval-runner=1:0", "=1"} {
+		t.Setenv("RATE_LIMIT_CALLERS", bad)
+		if _, err := loadConfig(); err == nil || !strings.Contains(err.Error(), "RATE_LIMIT_CALLERS") {
+			t.Fatalf("%q: err = %v", bad, err)
+		}
+	}
+}
+
+func TestRateLimiter_TokenBucket(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := newRateLimiter(rateLimit{perSecond: 1, burst: 2}, map[string]rateLimit{"batch": {perSecond: 0.5, burst: 1}, "ops": {}})
+	l.now = func() time.Time { return now }
+
+	for i := range 2 {
+		if ok, _ := l.take("planner", l.def); !ok {
+			t.Fatalf("request %d within the burst was limited", i)
+		}
+	}
+	if ok, wait := l.take("planner", l.def); ok || wait != time.Second {
+		t.Fatalf("past the burst: ok %v, wait %v", ok, wait)
+	}
+	now = now.Add(500 * time.Millisecond)
+	if ok, wait := l.take("planner", l.def); ok || wait != 500*time.Millisecond {
+		t.Fatalf("half a token: ok %v, wait %v", ok, wait)
+	}
+	now = now.Add(500 * time.Millisecond)
+	if ok, _ := l.take("planner", l.def); !ok {
+		t.Fatal("refilled token was not granted")
+	}
+
+	// Callers have their own buckets and limits.
+	if ok, _ := l.take("batch", l.callers["batch"]); !ok {
+		t.Fatal("first batch request was limited")
+	}
+	if ok, wait := l.take("batch", l.callers["batch"]); ok || wait != 2*time.Second {
+		t.Fatalf("second batch request: ok %v, wait %v", ok, wait)
+	}
+	for range 10 {
+		if ok, _ := l.take("ops", l.callers["ops"]); !ok {
+			t.Fatal("unlimited caller was limited")
+		}
+	}
+	if got := l.status()["limited"]; got != 3 {
+		t.Fatalf("limited = %v", got)
+	}
+}
+
+func TestRateLimiter_BoundedBuckets(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := newRateLimiter(rateLimit{perSecond: 1, burst: 1}, nil)
+	l.now = func() time.Time { return now }
+
+	// Every bucket is spent, so none is idle; rotating caller IDs still
+	// cannot grow the map past its bound.
+	for i := range 2 * maxRateLimitBuckets {
+		if ok, _ := l.take(fmt.Sprintf("caller-%d", i), l.def); !ok {
+			t.Fatalf("new caller %d was limited", i)
+		}
+	}
+	if n := len(l.buckets); n != maxRateLimitBuckets || l.order.Len() != n {
+		t.Fatalf("tracking %d buckets (%d ordered), want %d", n, l.order.Len(), maxRateLimitBuckets)
+	}
+
+	// The least recently seen caller is the one forgotten.
+	l.maxBuckets = 2
+	l.buckets, l.order = map[string]*list.Element{}, list.New()
+	l.take("a", l.def)
+	l.take("b", l.def)
+	l.take("a", l.def)
+	l.take("c", l.def)
+	if _, ok := l.buckets["b"]; ok {
+		t.Fatal("b was kept over the more recent a")
+	}
+	if ok, _ := l.take("a", l.def); ok {
+		t.Fatal("a's spent bucket was forgotten")
+	}
+}
+
+func TestRateLimiter_CallerIdentity(t *testing.T) {
+	l := newRateLimiter(rateLimit{perSecond: 1, burst: 1}, map[string]rateLimit{"agent-planner": {perSecond: 100, burst: 100}, "eval-runner": {perSecond: 2, burst: 2}})
+	planner := context.WithValue(context.Background(), peerIdentityKey{}, peerIdentity{Name: "spiffe://pagi.test/sa/planner", SPIFFEID: "spiffe://pagi.test/sa/planner", DNSNames: []string{"agent-planner"}})
+	// The mTLS identity wins over a self-declared caller ID.
+	planner = metadata.NewIncomingContext(planner, metadata.Pairs(service.CallerIDMetadataKey, "eval-runner"))
+
+	for _, tc := range []struct {
+		name   string
+		ctx    context.Context
+		caller string
+		limit  rateLimit
+	}{
+		{"limit under a DNS SAN", planner, "spiffe://pagi.test/sa/planner", l.callers["agent-planner"]},
+		{"caller ID", metadata.NewIncomingContext(context.Background(), metadata.Pairs(service.CallerIDMetadataKey, "eval-runner")), "eval-runner", l.callers["eval-runner"]},
+		{"unknown caller ID", metadata.NewIncomingContext(context.Background(), metadata.Pairs(service.CallerIDMetadataKey, "dashboard")), "dashboard", l.def},
+		{"anonymous", context.Background(), anonymousCaller, l.def},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if caller, limit := l.caller(tc.ctx); caller != tc.caller || limit != tc.limit {
+				t.Fatalf("caller = %q %+v, want %q %+v", caller, limit, tc.caller, tc.limit)
+			}
+		})
+	}
+}
+
+func TestRateLimiter_GRPCAndREST(t *testing.T) {
+	l := newRateLimiter(rateLimit{perSecond: 0.001, burst: 1}, nil)
+	gw := &server{llm: &llmRuntime{Provider: providerMock, Model: "mock"}, rateLimit: l}
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gs := grpc.NewServer(grpc.ChainUnaryInterceptor(l.unaryInterceptor()), grpc.ChainStreamInterceptor(l.streamInterceptor()))
+	grpc_health_v1.RegisterHealthServer(gs, &healthServer{gateway: gw})
+	pb.RegisterModelGatewayServer(gs, gw)
+	go func() { _ = gs.Serve(lis) }()
+	t.Cleanup(gs.Stop)
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	client := pb.NewModelGatewayClient(conn)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	as := func(caller string) context.Context {
+		return metadata.AppendToOutgoingContext(ctx, service.CallerIDMetadataKey, caller)
+	}
+
+	if _, err := client.GetCapabilities(as("eval-runner"), &pb.CapabilitiesRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	var header metadata.MD
+	_, err = client.GetCapabilities(as("eval-runner"), &pb.CapabilitiesRequest{}, grpc.Header(&header))
+	st := status.Convert(err)
+	if st.Code() != codes.ResourceExhausted || !strings.Contains(st.Message(), `"eval-runner"`) {
+		t.Fatalf("second call = %v", err)
+	}
+	if got := header.Get(retryAfterMetadataKey); len(got) != 1 || got[0] == "0" {
+		t.Fatalf("retry-after = %v", got)
+	}
+	var retry *errdetails.RetryInfo
+	for _, d := range st.Details() {
+		if info, ok := d.(*errdetails.RetryInfo); ok {
+			retry = info
+		}
+	}
+	if retry == nil || retry.GetRetryDelay().AsDuration() <= 0 {
+		t.Fatalf("RetryInfo = %v", st.Details())
+	}
+
+	// Other callers and health checks are unaffected.
+	if _, err := client.GetCapabilities(as("dashboard"), &pb.CapabilitiesRequest{}); err != nil {
+		t.Fatalf("other caller: %v", err)
+	}
+	for range 3 {
+		if _, err := grpc_health_v1.NewHealthClient(conn).Check(as("eval-runner"), &grpc_health_v1.HealthCheckRequest{}); err != nil {
+			t.Fatalf("health check: %v", err)
+		}
+	}
+
+	// REST callers share the limiter; the caller ID is a header.
+	rest := newRESTGateway(gw, l.unaryInterceptor())
+	get := func(caller string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/v1/capabilities", nil)
+		req.Header.Set("X-Caller-Id", caller)
+		rec := httptest.NewRecorder()
+		rest.ServeHTTP(rec, req)
+		return rec
+	}
+	if rec := get("webhook"); rec.Code != http.StatusOK {
+		t.Fatalf("first REST call = %d %s", rec.Code, rec.Body)
+	}
+	rec := get("webhook")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Grpc-Metadata-Retry-After") == "" {
+		t.Fatalf("second REST call = %d %v %s", rec.Code, rec.Header(), rec.Body)
+	}
+	if got := gw.adminStatus(ctx)["rate_limit"].(map[string]any)["limited"]; got != 2 {
+		t.Fatalf("limited = %v", got)
+	}
+}
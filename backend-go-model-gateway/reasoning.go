@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Reasoning modes (LLM_REASONING).
+const (
+	// reasoningDrop separates a reasoning model's trace from its answer and
+	// discards it.
+	reasoningDrop = "drop"
+	// reasoningReturn also returns the trace in PlanResponse.reasoning, for
+	// the planner's audit log.
+	reasoningReturn = "return"
+)
+
+// reasoningBlock matches the tags reasoning models wrap their trace in:
+// <think> (DeepSeek-R1, QwQ and their distills on Ollama), <thinking> and
+// <reasoning>. An unclosed block runs to the end of the reply, as when the
+// model hit max_tokens while still thinking.
+var reasoningBlock = regexp.MustCompile(`(?is)<(think|thinking|reasoning)>(.*?)(?:</(?:think|thinking|reasoning)>|\z)`)
+
+// splitReasoning separates the reasoning trace from a model reply, so JSON
+// parsing sees only the answer. Some Ollama templates put the opening <think>
+// in the prompt; a reply that only closes the block is reasoning up to the
+// </think>. Replies without reasoning are returned unchanged.
+func splitReasoning(content string) (answer, reasoning string) {
+	var traces []string
+	if i := strings.Index(strings.ToLower(content), "</think>"); i >= 0 && !strings.Contains(strings.ToLower(content[:i]), "<think>") {
+		traces = append(traces, strings.TrimSpace(content[:i]))
+		content = content[i+len("</think>"):]
+	}
+	answer = reasoningBlock.ReplaceAllStringFunc(content, func(block string) string {
+		traces = append(traces, strings.TrimSpace(reasoningBlock.FindStringSubmatch(block)[2]))
+		return ""
+	})
+	if len(traces) == 0 {
+		return content, ""
+	}
+	return strings.TrimSpace(answer), strings.TrimSpace(strings.Join(traces, "\n\n"))
+}
+
+// replyAnswer is a chat reply's content without its reasoning trace, for the
+// judge and the LLM-backed RAG stages.
+func replyAnswer(content string) string {
+	answer, _ := splitReasoning(content)
+	return answer
+}
+
+// joinReasoning combines a reply's separate reasoning field (reasoning_content)
+// with the trace split from its content.
+func joinReasoning(field, inline string) string {
+	field = strings.TrimSpace(field)
+	switch {
+	case field == "":
+		return inline
+	case inline == "":
+		return field
+	}
+	return field + "\n\n" + inline
+}
+
+// reasoningFieldTransport copies OpenRouter's "reasoning" field to
+// "reasoning_content" in chat completions, streamed or not. go-openai only
+// decodes the latter, the field DeepSeek's own API uses, so without it R1's
+// trace via OpenRouter would be lost.
+type reasoningFieldTransport struct {
+	Base http.RoundTripper
+}
+
+func (t *reasoningFieldTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	resp, err := t.Base.RoundTrip(r)
+	if err != nil || resp.StatusCode != http.StatusOK || !strings.HasSuffix(r.URL.Path, "/chat/completions") {
+		return resp, err
+	}
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		resp.Body = &reasoningEventStream{src: bufio.NewReader(resp.Body), Closer: resp.Body}
+		return resp, nil
+	}
+	b, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	b = copyReasoningField(b)
+	resp.Body = io.NopCloser(bytes.NewReader(b))
+	resp.ContentLength = int64(len(b))
+	resp.Header.Del("Content-Length")
+	return resp, nil
+}
+
+// copyReasoningField returns a chat completion or stream chunk with each
+// choice's message or delta "reasoning" also set as "reasoning_content".
+// Bodies it cannot parse, or without the field, are returned unchanged.
+func copyReasoningField(b []byte) []byte {
+	var body map[string]json.RawMessage
+	var choices []map[string]json.RawMessage
+	if json.Unmarshal(b, &body) != nil || json.Unmarshal(body["choices"], &choices) != nil {
+		return b
+	}
+	changed := false
+	for _, choice := range choices {
+		for _, key := range []string{"message", "delta"} {
+			var m map[string]json.RawMessage
+			if json.Unmarshal(choice[key], &m) != nil {
+				continue
+			}
+			if r, ok := m["reasoning"]; ok && string(r) != "null" && m["reasoning_content"] == nil {
+				m["reasoning_content"] = r
+				choice[key], _ = json.Marshal(m)
+				changed = true
+			}
+		}
+	}
+	if !changed {
+		return b
+	}
+	body["choices"], _ = json.Marshal(choices)
+	out, err := json.Marshal(body)
+	if err != nil {
+		return b
+	}
+	return out
+}
+
+// reasoningEventStream applies copyReasoningField to each "data:" event of a
+// streamed chat completion.
+type reasoningEventStream struct {
+	src *bufio.Reader
+	io.Closer
+	buf []byte
+	err error
+}
+
+func (s *reasoningEventStream) Read(p []byte) (int, error) {
+	for len(s.buf) == 0 && s.err == nil {
+		var line []byte
+		line, s.err = s.src.ReadBytes('\n')
+		if data, ok := bytes.CutPrefix(line, []byte("data: ")); ok {
+			event := bytes.TrimRight(data, "\r\n")
+			line = append(append([]byte("data: "), copyReasoningField(event)...), data[len(event):]...)
+		}
+		s.buf = line
+	}
+	n := copy(p, s.buf)
+	s.buf = s.buf[n:]
+	if len(s.buf) == 0 && s.err != nil {
+		return n, s.err
+	}
+	return n, nil
+}
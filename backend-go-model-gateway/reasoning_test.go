@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	pb "backend-go-model-gateway/proto/proto"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestSplitReasoning(t *testing.T) {
+	for _, tc := range []struct{ in, answer, reasoning string }{
+		{`{"steps":["a"]}`, `{"steps":["a"]}`, ""},
+		{"<think>\nThe user wants a plan.\n</think>\n\n{\"steps\":[\"a\"]}", `{"steps":["a"]}`, "The user wants a plan."},
+		{"<Thinking>one</Thinking> {} <reasoning>two</reasoning>", "{}", "one\n\ntwo"},
+		// The template opened the block; the reply only closes it.
+		{"Let me check the tools.\n</think>\n{}", "{}", "Let me check the tools."},
+		// Cut off while still thinking.
+		{"<think>Step 1: consider", "", "Step 1: consider"},
+	} {
+		answer, reasoning := splitReasoning(tc.in)
+		if answer != tc.answer || reasoning != tc.reasoning {
+			t.Errorf("splitReasoning(%q) = %q, %q; want %q, %q", tc.in, answer, reasoning, tc.answer, tc.reasoning)
+		}
+	}
+}
+
+func TestGetPlan_Reasoning(t *testing.T) {
+	llm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(openai.ChatCompletionResponse{
+			Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{
+				Role:    "assistant",
+				Content: "<think>\nThe user wants {a plan}.\n</think>\n{\"steps\":[\"Pack an umbrella\"]}",
+			}}},
+		})
+	}))
+	defer llm.Close()
+	cfg := openai.DefaultConfig("")
+	cfg.BaseURL = llm.URL
+
+	for _, mode := range []string{reasoningDrop, reasoningReturn} {
+		s := &server{
+			llm:            &llmRuntime{Provider: providerOllama, Model: "deepseek-r1", Client: openai.NewClientWithConfig(cfg), ToolCalling: toolCallingJSON, Reasoning: mode},
+			requestTimeout: time.Duration(defaultRequestTimeoutSec) * time.Second,
+		}
+		resp, err := s.GetPlan(context.Background(), &pb.PlanRequest{Prompt: "rain tomorrow"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var plan struct {
+			Steps []string `json:"steps"`
+		}
+		if err := json.Unmarshal([]byte(resp.GetPlan()), &plan); err != nil || len(plan.Steps) != 1 || plan.Steps[0] != "Pack an umbrella" {
+			t.Fatalf("%s: plan = %s (%v)", mode, resp.GetPlan(), err)
+		}
+		want := ""
+		if mode == reasoningReturn {
+			want = "The user wants {a plan}."
+		}
+		if resp.GetReasoning() != want {
+			t.Fatalf("%s: reasoning = %q, want %q", mode, resp.GetReasoning(), want)
+		}
+	}
+}
+
+// TestGetPlan_ReasoningField covers reasoning returned beside the content:
+// DeepSeek's API as reasoning_content, OpenRouter as reasoning, streamed or not.
+func TestGetPlan_ReasoningField(t *testing.T) {
+	const trace = "The user wants a <plan>."
+	for _, field := range []string{"reasoning_content", "reasoning"} {
+		llm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var req openai.ChatCompletionRequest
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			if !req.Stream {
+				w.Header().Set("Content-Type", "application/json")
+				fmt.Fprintf(w, `{"id":"gen-1","created":1760000000,"choices":[{"index":0,"message":{"role":"assistant","content":"{\"steps\":[\"Pack an umbrella\"]}",%q:%q}}],"usage":{"prompt_tokens":7,"completion_tokens":3}}`, field, trace)
+				return
+			}
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprintf(w, ": OPENROUTER PROCESSING\n\n")
+			fmt.Fprintf(w, "data: {\"choices\":[{\"index\":0,\"delta\":{\"role\":\"assistant\",%q:%q}}]}\n\n", field, trace[:9])
+			fmt.Fprintf(w, "data: {\"choices\":[{\"index\":0,\"delta\":{%q:%q}}]}\n\n", field, trace[9:])
+			fmt.Fprintf(w, "data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"{\\\"steps\\\":[\\\"Pack an umbrella\\\"]}\"}}]}\n\ndata: [DONE]\n\n")
+		}))
+		cfg := openai.DefaultConfig("")
+		cfg.BaseURL = llm.URL
+		cfg.HTTPClient = &http.Client{Transport: &reasoningFieldTransport{Base: http.DefaultTransport}}
+		s := &server{
+			llm:            &llmRuntime{Provider: providerOpenRouter, Model: "deepseek/deepseek-r1", Client: openai.NewClientWithConfig(cfg), ToolCalling: toolCallingJSON, Reasoning: reasoningReturn},
+			requestTimeout: time.Duration(defaultRequestTimeoutSec) * time.Second,
+		}
+
+		resp, err := s.GetPlan(context.Background(), &pb.PlanRequest{Prompt: "rain tomorrow"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.GetReasoning() != trace || !strings.Contains(resp.GetPlan(), "Pack an umbrella") || resp.GetPromptTokens() != 7 {
+			t.Fatalf("%s: response = %v", field, resp)
+		}
+
+		rec := &planChunkRecorder{}
+		if err := s.StreamPlan(&pb.PlanRequest{Prompt: "rain tomorrow"}, rec); err != nil {
+			t.Fatal(err)
+		}
+		if len(rec.chunks) < 2 {
+			t.Fatalf("%s: chunks = %v, want the plan streamed", field, rec.chunks)
+		}
+		final := rec.chunks[len(rec.chunks)-1].GetFinal()
+		if final.GetReasoning() != trace || !strings.Contains(final.GetPlan(), "Pack an umbrella") {
+			t.Fatalf("%s: streamed final = %v", field, final)
+		}
+		llm.Close()
+	}
+}
+
+func TestJoinReasoning(t *testing.T) {
+	if got := joinReasoning(" field ", "inline"); got != "field\n\ninline" {
+		t.Errorf("both = %q", got)
+	}
+	if got := joinReasoning("", "inline"); got != "inline" {
+		t.Errorf("inline only = %q", got)
+	}
+	if got := joinReasoning("field", ""); got != "field" {
+		t.Errorf("field only = %q", got)
+	}
+}
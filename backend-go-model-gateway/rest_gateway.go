@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"backend-go-model-gateway/pkg/secrets"
+	pb "backend-go-model-gateway/proto/proto"
+	"backend-go-model-gateway/service"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// restForwardedHeaders are the only HTTP headers passed to the RPCs, as the
+// gRPC metadata a gRPC caller would send. The tenant and principal are not
+// among them: a gRPC caller asserts those after authenticating the end user,
+// and the REST key says nothing about who that is. Other headers, including
+// grpc-gateway's Grpc-Metadata-* passthrough, are dropped.
+var restForwardedHeaders = map[string]bool{
+	"x-trace-id":                 true,
+	service.SessionIDMetadataKey: true,
+	service.CallerIDMetadataKey:  true,
+}
+
+// newRESTGateway transcodes HTTP/JSON to ModelGateway RPCs (grpc-gateway's
+// runtime), for callers without gRPC such as curl, dashboards and webhooks:
+//
+//	POST /v1/plan          GetPlan
+//	POST /v1/chat          Chat
+//	GET  /v1/models        ListModels
+//	GET  /v1/capabilities  GetCapabilities
+//
+// Bodies and responses are the messages in proto JSON with their proto field
+// names; gRPC errors map to HTTP statuses as grpc-gateway does. The RPCs run
+// in process, so peer authorization does not apply: the routes are behind
+// GATEWAY_REST_API_KEY instead (see requireKey). interceptors wrap every
+// call in order, as the gRPC server's do (nil ones are skipped); header
+// metadata they set comes back as Grpc-Metadata-* headers.
+func newRESTGateway(gw pb.ModelGatewayServer, interceptors ...grpc.UnaryServerInterceptor) http.Handler {
+	intercept := chainUnaryInterceptors(interceptors)
+	mux := runtime.NewServeMux(
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.JSONPb{
+			MarshalOptions:   protojson.MarshalOptions{UseProtoNames: true},
+			UnmarshalOptions: protojson.UnmarshalOptions{DiscardUnknown: true},
+		}),
+		runtime.WithIncomingHeaderMatcher(func(key string) (string, bool) {
+			if restForwardedHeaders[strings.ToLower(key)] {
+				return strings.ToLower(key), true
+			}
+			return "", false
+		}),
+	)
+	restUnary(mux, http.MethodPost, "/v1/plan", "GetPlan", intercept, gw.GetPlan)
+	restUnary(mux, http.MethodPost, "/v1/chat", "Chat", intercept, gw.Chat)
+	restUnary(mux, http.MethodGet, "/v1/models", "ListModels", intercept, gw.ListModels)
+	restUnary(mux, http.MethodGet, "/v1/capabilities", "GetCapabilities", intercept, gw.GetCapabilities)
+	return mux
+}
+
+// checkRESTAuth refuses the REST routes without GATEWAY_REST_API_KEY when
+// gRPC callers are authenticated (mTLS or a peer allowlist): the routes skip
+// peer authorization, so they would be the open door around it.
+func checkRESTAuth(ctx context.Context, store *secrets.Store, peersAuthenticated bool) error {
+	if !peersAuthenticated {
+		return nil
+	}
+	key, err := store.Lookup(ctx, "GATEWAY_REST_API_KEY")
+	if err != nil {
+		return fmt.Errorf("GATEWAY_REST_API_KEY: %w", err)
+	}
+	if key == "" {
+		return errors.New("GATEWAY_REST is on without GATEWAY_REST_API_KEY while mTLS is enabled; set the key or turn REST off")
+	}
+	return nil
+}
+
+// restUnary routes method and path to the unary RPC call, decoding the body
+// (if any) into its request. It is what protoc-gen-grpc-gateway generates for
+// a local handler.
+func restUnary[Req any, PReq interface {
+	*Req
+	proto.Message
+}, Resp proto.Message](mux *runtime.ServeMux, method, path, rpc string, intercept grpc.UnaryServerInterceptor, call func(context.Context, PReq) (Resp, error)) {
+	fullMethod := "/" + pb.ModelGateway_ServiceDesc.ServiceName + "/" + rpc
+	_ = mux.HandlePath(method, path, func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+		var stream runtime.ServerTransportStream
+		ctx = grpc.NewContextWithServerTransportStream(ctx, &stream)
+		inbound, outbound := runtime.MarshalerForRequest(mux, r)
+		ctx, err := runtime.AnnotateIncomingContext(ctx, mux, r, fullMethod, runtime.WithHTTPPathPattern(path))
+		if err != nil {
+			runtime.HTTPError(ctx, mux, outbound, w, r, err)
+			return
+		}
+		in := PReq(new(Req))
+		if method != http.MethodGet {
+			if err := inbound.NewDecoder(r.Body).Decode(in); err != nil && !errors.Is(err, io.EOF) {
+				runtime.HTTPError(ctx, mux, outbound, w, r, status.Errorf(codes.InvalidArgument, "%v", err))
+				return
+			}
+		}
+		var resp any
+		if intercept != nil {
+			resp, err = intercept(ctx, in, &grpc.UnaryServerInfo{FullMethod: fullMethod}, func(ctx context.Context, req any) (any, error) {
+				return call(ctx, req.(PReq))
+			})
+		} else {
+			resp, err = call(ctx, in)
+		}
+		ctx = runtime.NewServerMetadataContext(ctx, runtime.ServerMetadata{HeaderMD: stream.Header(), TrailerMD: stream.Trailer()})
+		if err != nil {
+			runtime.HTTPError(ctx, mux, outbound, w, r, err)
+			return
+		}
+		runtime.ForwardResponseMessage(ctx, mux, outbound, w, r, resp.(proto.Message), mux.GetForwardResponseOptions()...)
+	})
+}
+
+// chainUnaryInterceptors composes interceptors, the first outermost, into
+// one; nil when there are none.
+func chainUnaryInterceptors(interceptors []grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	var chain []grpc.UnaryServerInterceptor
+	for _, i := range interceptors {
+		if i != nil {
+			chain = append(chain, i)
+		}
+	}
+	if len(chain) == 0 {
+		return nil
+	}
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		next := handler
+		for i := len(chain) - 1; i > 0; i-- {
+			interceptor, inner := chain[i], next
+			next = func(ctx context.Context, req any) (any, error) {
+				return interceptor(ctx, req, info, inner)
+			}
+		}
+		return chain[0](ctx, req, info, next)
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"backend-go-model-gateway/pkg/secrets"
+	pb "backend-go-model-gateway/proto/proto"
+	"backend-go-model-gateway/service"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestRESTGateway_Plan(t *testing.T) {
+	t.Setenv("GATEWAY_REST_API_KEY", "rest")
+	gw := &server{llm: &llmRuntime{Provider: providerMock, Model: "mock"}, requestTimeout: time.Duration(defaultRequestTimeoutSec) * time.Second}
+	h := requireKey(secrets.New(secrets.Options{}), "GATEWAY_REST_API_KEY", newRESTGateway(gw))
+
+	do := func(method, path, body, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		req.Header.Set("X-Session-Id", "rest-session")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := do(http.MethodPost, "/v1/plan", `{"prompt":"hi"}`, ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("without key = %d %s", rec.Code, rec.Body)
+	}
+
+	rec := do(http.MethodPost, "/v1/plan", `{"prompt":"plan my day","unknown_field":1}`, "rest")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("POST /v1/plan = %d %s", rec.Code, rec.Body)
+	}
+	var resp struct {
+		Plan      string `json:"plan"`
+		ModelName string `json:"model_name"`
+		Provider  string `json:"provider"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || !strings.Contains(resp.Plan, "plan my day") || resp.ModelName == "" {
+		t.Fatalf("plan response = %s (%v)", rec.Body, err)
+	}
+
+	// Malformed JSON is the caller's error.
+	if rec := do(http.MethodPost, "/v1/plan", `{"prompt":`, "rest"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("malformed body = %d %s", rec.Code, rec.Body)
+	}
+	if rec := do(http.MethodGet, "/v1/capabilities", "", "rest"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"mock":true`) {
+		t.Fatalf("GET /v1/capabilities = %d %s", rec.Code, rec.Body)
+	}
+	// grpc-gateway answers a known path with the wrong method as Unimplemented.
+	if rec := do(http.MethodGet, "/v1/plan", "", "rest"); rec.Code != http.StatusNotImplemented {
+		t.Fatalf("GET /v1/plan = %d", rec.Code)
+	}
+}
+
+func TestRESTGateway_InterceptorsAndHeaders(t *testing.T) {
+	gw := &server{llm: &llmRuntime{Provider: providerMock, Model: "mock"}}
+	var order []string
+	var md metadata.MD
+	record := func(name string) grpc.UnaryServerInterceptor {
+		return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			order = append(order, name+" "+info.FullMethod)
+			md, _ = metadata.FromIncomingContext(ctx)
+			return handler(ctx, req)
+		}
+	}
+	h := newRESTGateway(gw, record("ops"), nil, record("rate"))
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/capabilities", nil)
+	req.Header.Set("X-Session-Id", "rest-session")
+	req.Header.Set("X-Tenant-Id", "someone-else")
+	req.Header.Set("X-Principal", "api_key:admin")
+	req.Header.Set("Grpc-Metadata-X-Tenant-Id", "victim")
+	req.Header.Set("Grpc-Metadata-X-Principal", "api_key:admin")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /v1/capabilities = %d %s", rec.Code, rec.Body)
+	}
+	if want := []string{"ops " + pb.ModelGateway_GetCapabilities_FullMethodName, "rate " + pb.ModelGateway_GetCapabilities_FullMethodName}; !slices.Equal(order, want) {
+		t.Fatalf("interceptors ran as %v, want %v", order, want)
+	}
+	// The tenant and principal are asserted by authenticated gRPC callers
+	// only; over REST anyone could pick them, plainly or as Grpc-Metadata-*.
+	if got := md.Get(service.SessionIDMetadataKey); len(got) != 1 || got[0] != "rest-session" {
+		t.Fatalf("session metadata = %v", got)
+	}
+	if got := append(md.Get(service.TenantIDMetadataKey), md.Get(service.PrincipalMetadataKey)...); len(got) > 0 {
+		t.Fatalf("tenant/principal forwarded from REST headers: %v", got)
+	}
+}
+
+func TestCheckRESTAuth(t *testing.T) {
+	store := secrets.New(secrets.Options{})
+	t.Setenv("GATEWAY_REST_API_KEY", "")
+	if err := checkRESTAuth(context.Background(), store, false); err != nil {
+		t.Fatalf("without mTLS: %v", err)
+	}
+	if err := checkRESTAuth(context.Background(), store, true); err == nil {
+		t.Fatal("mTLS with an open REST API was accepted")
+	}
+	t.Setenv("GATEWAY_REST_API_KEY", "rest")
+	if err := checkRESTAuth(context.Background(), store, true); err != nil {
+		t.Fatalf("mTLS with a REST key: %v", err)
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/json"
+	"time"
+
+	pb "backend-go-model-gateway/proto/proto"
+)
+
+// retrievalOnlyModel is the model_name of a retrieval-only plan.
+const retrievalOnlyModel = "retrieval-only"
+
+// retrievalOnlyPlan is the degraded answer to a GetPlan no provider could
+// serve: the matches retrieved for the prompt, as a final plan the planner
+// ends its run with. The response is flagged degraded and carries the
+// matches, so callers need not parse the plan to show them.
+func retrievalOnlyPlan(in *pb.PlanRequest, matches []VectorQueryMatch, promptVersion string, requestStart time.Time) *pb.PlanResponse {
+	type passage struct {
+		KnowledgeBase string  `json:"knowledge_base"`
+		ID            string  `json:"id"`
+		Text          string  `json:"text"`
+		Source        string  `json:"source,omitempty"`
+		Score         float64 `json:"score"`
+	}
+	passages := make([]passage, 0, len(matches))
+	resp := &pb.PlanResponse{
+		ModelName:     retrievalOnlyModel,
+		PromptVersion: promptVersion,
+		Degraded:      true,
+	}
+	for _, m := range matches {
+		passages = append(passages, passage{KnowledgeBase: m.KnowledgeBase, ID: m.ID, Text: m.Text, Source: m.Source, Score: m.Score})
+		resp.Matches = append(resp.Matches, &pb.RAGMatch{Id: m.ID, Text: m.Text, Distance: 1 - m.Score, KnowledgeBase: m.KnowledgeBase, Source: m.Source})
+	}
+	plan, _ := json.Marshal(map[string]any{
+		"model_type": retrievalOnlyModel,
+		"prompt":     in.GetPrompt(),
+		"degraded":   true,
+		"answer":     "The language model is unavailable. These knowledge-base passages matched the request.",
+		"matches":    passages,
+	})
+	resp.Plan = string(plan)
+	resp.LatencyMs = time.Since(requestStart).Milliseconds()
+	return resp
+}
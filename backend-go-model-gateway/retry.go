@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+const (
+	defaultRetryMaxAttempts = 3
+	defaultRetryBaseDelayMS = 200
+	defaultRetryMaxDelayMS  = 2000
+	// defaultRetryBudgetRatio lets retries add at most 20% to provider calls.
+	defaultRetryBudgetRatio = 0.2
+	// retryBudgetBurst is how many retries the budget holds when idle.
+	retryBudgetBurst = 10
+)
+
+// retryPolicy retries provider calls that failed with a 429, a 5xx or a
+// connection error, with exponential backoff and full jitter. A shared budget
+// bounds retries to a share of all calls, so a provider outage is not met
+// with a multiple of its usual traffic.
+//
+// A nil *retryPolicy makes a single attempt.
+type retryPolicy struct {
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+
+	mu     sync.Mutex
+	ratio  float64
+	tokens float64
+	// jitter returns a random duration in [0, d); tests replace it.
+	jitter func(d time.Duration) time.Duration
+}
+
+// newRetryPolicy returns a policy making up to maxAttempts calls; fewer than
+// two attempts returns nil.
+func newRetryPolicy(maxAttempts int, baseDelay, maxDelay time.Duration, budgetRatio float64) *retryPolicy {
+	if maxAttempts < 2 {
+		return nil
+	}
+	return &retryPolicy{
+		maxAttempts: maxAttempts,
+		baseDelay:   baseDelay,
+		maxDelay:    max(maxDelay, baseDelay),
+		ratio:       budgetRatio,
+		tokens:      retryBudgetBurst,
+		jitter:      func(d time.Duration) time.Duration { return rand.N(d) },
+	}
+}
+
+// retryPolicyFromConfig returns the policy cfg describes.
+func retryPolicyFromConfig(cfg RetryConfig) *retryPolicy {
+	return newRetryPolicy(
+		cfg.MaxAttempts,
+		time.Duration(cfg.BaseDelayMS)*time.Millisecond,
+		time.Duration(cfg.MaxDelayMS)*time.Millisecond,
+		cfg.BudgetRatio,
+	)
+}
+
+// called credits the budget for one provider call.
+func (p *retryPolicy) called() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.tokens = min(p.tokens+p.ratio, retryBudgetBurst)
+}
+
+// allow reports whether the budget has a retry left, and spends it.
+func (p *retryPolicy) allow() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.tokens < 1 {
+		return false
+	}
+	p.tokens--
+	return true
+}
+
+// backoff is the wait before retry n (1-based): a random share of
+// baseDelay*2^(n-1), capped at maxDelay.
+func (p *retryPolicy) backoff(n int) time.Duration {
+	d := p.maxDelay
+	if n <= 16 {
+		d = min(p.baseDelay<<(n-1), p.maxDelay)
+	}
+	if d <= 0 {
+		return 0
+	}
+	return p.jitter(d)
+}
+
+// retryDecision is why a failed call is, or is not, retried.
+type retryDecision string
+
+const (
+	retryYes          retryDecision = "retry"
+	retryNotRetryable retryDecision = "not_retryable"
+	retryExhausted    retryDecision = "attempts_exhausted"
+	retryNoBudget     retryDecision = "budget_exhausted"
+	retryNoTime       retryDecision = "deadline"
+)
+
+// next decides whether to retry after attempt (1-based) failed with err, and
+// how long to wait first.
+func (p *retryPolicy) next(ctx context.Context, attempt int, err error) (time.Duration, retryDecision) {
+	switch {
+	case p == nil || ctx.Err() != nil || !failoverWorthy(err):
+		return 0, retryNotRetryable
+	case attempt >= p.maxAttempts:
+		return 0, retryExhausted
+	}
+	delay := p.backoff(attempt)
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= delay {
+		return 0, retryNoTime
+	}
+	if !p.allow() {
+		return 0, retryNoBudget
+	}
+	return delay, retryYes
+}
+
+// status is the retry policy's part of GET /admin/status.
+func (p *retryPolicy) status() map[string]any {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return map[string]any{
+		"max_attempts":  p.maxAttempts,
+		"budget_ratio":  p.ratio,
+		"budget_tokens": p.tokens,
+	}
+}
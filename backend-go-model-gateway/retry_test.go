@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	pb "backend-go-model-gateway/proto/proto"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// flakyProvider is a fake OpenAI-compatible provider answering statuses in
+// order, then 200 with a plan.
+func flakyProvider(t *testing.T, calls *int, statuses ...int) *llmRuntime {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*calls++
+		w.Header().Set("Content-Type", "application/json")
+		if *calls <= len(statuses) {
+			w.WriteHeader(statuses[*calls-1])
+			_, _ = w.Write([]byte(`{"error":{"message":"try again","type":"server_error"}}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(openai.ChatCompletionResponse{
+			Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Role: "assistant", Content: `{"steps":["ok"]}`}}},
+		})
+	}))
+	t.Cleanup(srv.Close)
+	cfg := openai.DefaultConfig("")
+	cfg.BaseURL = srv.URL
+	return &llmRuntime{Provider: providerOllama, Model: "flaky", Client: openai.NewClientWithConfig(cfg), ToolCalling: toolCallingJSON}
+}
+
+func TestGetPlan_RetriesTransientErrors(t *testing.T) {
+	var calls int
+	retry := newRetryPolicy(3, time.Millisecond, 5*time.Millisecond, defaultRetryBudgetRatio)
+	s := &server{llm: flakyProvider(t, &calls, http.StatusTooManyRequests, http.StatusBadGateway), requestTimeout: 5 * time.Second, retry: retry}
+	resp, err := s.GetPlan(context.Background(), &pb.PlanRequest{Prompt: "plan"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 || resp.GetPlan() == "" {
+		t.Fatalf("%d calls, plan %q", calls, resp.GetPlan())
+	}
+
+	// Client errors are not retried.
+	calls = 0
+	s.llm = flakyProvider(t, &calls, http.StatusBadRequest)
+	if _, err := s.GetPlan(context.Background(), &pb.PlanRequest{Prompt: "plan"}); err == nil || calls != 1 {
+		t.Fatalf("400: %d calls, err %v", calls, err)
+	}
+
+	// Nor past max attempts.
+	calls = 0
+	s.llm = flakyProvider(t, &calls, 500, 500, 500, 500)
+	if _, err := s.GetPlan(context.Background(), &pb.PlanRequest{Prompt: "plan"}); err == nil || calls != 3 {
+		t.Fatalf("500s: %d calls, err %v", calls, err)
+	}
+}
+
+func TestRetryPolicy_Budget(t *testing.T) {
+	p := newRetryPolicy(3, 10*time.Millisecond, 40*time.Millisecond, 0.5)
+	p.jitter = func(d time.Duration) time.Duration { return d }
+	if d := p.backoff(1); d != 10*time.Millisecond {
+		t.Fatalf("backoff(1) = %v", d)
+	}
+	if d := p.backoff(5); d != 40*time.Millisecond {
+		t.Fatalf("backoff(5) = %v, want the 40ms cap", d)
+	}
+
+	transient := &openai.APIError{HTTPStatusCode: http.StatusServiceUnavailable}
+	ctx := context.Background()
+	for i := 0; i < retryBudgetBurst; i++ {
+		if _, decision := p.next(ctx, 1, transient); decision != retryYes {
+			t.Fatalf("retry %d: %s", i, decision)
+		}
+	}
+	if _, decision := p.next(ctx, 1, transient); decision != retryNoBudget {
+		t.Fatalf("empty budget: %s", decision)
+	}
+	// Two calls earn one retry at ratio 0.5.
+	p.called()
+	p.called()
+	if _, decision := p.next(ctx, 1, transient); decision != retryYes {
+		t.Fatalf("refilled budget: %s", decision)
+	}
+
+	short, cancel := context.WithTimeout(ctx, time.Millisecond)
+	defer cancel()
+	p.called()
+	p.called()
+	if _, decision := p.next(short, 1, transient); decision != retryNoTime {
+		t.Fatalf("near deadline: %s", decision)
+	}
+
+	if newRetryPolicy(1, time.Millisecond, time.Millisecond, 1) != nil {
+		t.Fatal("one attempt should disable retries")
+	}
+}
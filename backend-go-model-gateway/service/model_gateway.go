@@ -24,3 +24,71 @@ func ContextWithTraceIDFromIncomingGRPC(ctx context.Context) context.Context {
 	}
 	return context.WithValue(ctx, logger.TraceIDKey, traceID)
 }
+
+// SessionIDMetadataKey is the gRPC metadata key callers use to tag a request
+// with the agent session it belongs to.
+const SessionIDMetadataKey = "x-session-id"
+
+// SessionIDFromIncomingGRPC returns the session ID attached by the caller, or "".
+func SessionIDFromIncomingGRPC(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if ids := md.Get(SessionIDMetadataKey); len(ids) > 0 {
+		return strings.TrimSpace(ids[0])
+	}
+	return ""
+}
+
+// TenantIDMetadataKey is the gRPC metadata key carrying the authenticated
+// tenant. Callers set it only after authenticating the end user (the planner
+// maps API keys to tenants); the gateway scopes RAG retrieval by it.
+const TenantIDMetadataKey = "x-tenant-id"
+
+// TenantIDFromIncomingGRPC returns the tenant attached by the caller, or "".
+func TenantIDFromIncomingGRPC(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if ids := md.Get(TenantIDMetadataKey); len(ids) > 0 {
+		return strings.TrimSpace(ids[0])
+	}
+	return ""
+}
+
+// PrincipalMetadataKey is the gRPC metadata key carrying the authenticated
+// caller a request is made for, as "<kind>:<id>" (e.g. "api_key:acme",
+// "agent:twin-b"). Like the tenant, callers set it only after authenticating
+// the end user; it attributes actions, it does not authorize them.
+const PrincipalMetadataKey = "x-principal"
+
+// PrincipalFromIncomingGRPC returns the principal attached by the caller, or "".
+func PrincipalFromIncomingGRPC(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if ids := md.Get(PrincipalMetadataKey); len(ids) > 0 {
+		return strings.TrimSpace(ids[0])
+	}
+	return ""
+}
+
+// CallerIDMetadataKey is the gRPC metadata key a caller without an mTLS
+// identity uses to name itself (e.g. "eval-runner"), for per-caller rate
+// limits. It is a label, not a credential.
+const CallerIDMetadataKey = "x-caller-id"
+
+// CallerIDFromIncomingGRPC returns the caller ID attached by the caller, or "".
+func CallerIDFromIncomingGRPC(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if ids := md.Get(CallerIDMetadataKey); len(ids) > 0 {
+		return strings.TrimSpace(ids[0])
+	}
+	return ""
+}
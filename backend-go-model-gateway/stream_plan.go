@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+
+	"backend-go-model-gateway/pkg/chaos"
+	pb "backend-go-model-gateway/proto/proto"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// chatStreamer is the streaming chat completion call. *openai.Client
+// implements it; anthropicClient does not, so its plans arrive whole.
+type chatStreamer interface {
+	CreateChatCompletionStream(ctx context.Context, req openai.ChatCompletionRequest) (*openai.ChatCompletionStream, error)
+}
+
+type planDeltasKey struct{}
+
+// contextWithPlanDeltas asks planWith to pass the plan's text to sink as the
+// provider generates it.
+func contextWithPlanDeltas(ctx context.Context, sink func(string)) context.Context {
+	return context.WithValue(ctx, planDeltasKey{}, sink)
+}
+
+func planDeltasFromContext(ctx context.Context) func(string) {
+	sink, _ := ctx.Value(planDeltasKey{}).(func(string))
+	return sink
+}
+
+// StreamPlan is GetPlan with the plan's text streamed as the provider
+// generates it, ending with the PlanResponse GetPlan would have returned.
+// Deltas are best-effort: they are only sent for the first call of a plan
+// that is neither scrubbed of PII nor a native tool call, by providers that
+// stream, and only when the reply starts as a JSON object. With moderation on
+// only the final response is sent, as deltas would bypass the plan screen.
+func (s *server) StreamPlan(in *pb.PlanRequest, stream pb.ModelGateway_StreamPlanServer) error {
+	var sendErr error
+	sink := func(delta string) {
+		if sendErr == nil {
+			sendErr = stream.Send(&pb.PlanChunk{Delta: delta})
+		}
+	}
+	ctx := stream.Context()
+	if s.moderation == nil {
+		ctx = contextWithPlanDeltas(ctx, sink)
+	}
+	resp, err := s.GetPlan(ctx, in)
+	if err != nil {
+		return err
+	}
+	if sendErr != nil {
+		return sendErr
+	}
+	return stream.Send(&pb.PlanChunk{Final: resp})
+}
+
+// streamChatCompletion makes the call streaming, passes the reply's content
+// to sink as it arrives and returns the reply assembled as
+// createChatCompletion would. If streaming fails before anything was sent the
+// call is made again with createChatCompletion, so retries and PAGI_CHAOS
+// faults apply as usual.
+func (s *server) streamChatCompletion(ctx context.Context, llm *llmRuntime, streamer chatStreamer, req openai.ChatCompletionRequest, sink func(string)) (openai.ChatCompletionResponse, error) {
+	req.StreamOptions = &openai.StreamOptions{IncludeUsage: true}
+	stream, err := streamer.CreateChatCompletionStream(ctx, req)
+	if err != nil {
+		req.StreamOptions = nil
+		return s.createChatCompletion(ctx, llm, req)
+	}
+	defer stream.Close()
+
+	var (
+		resp      openai.ChatCompletionResponse
+		content   strings.Builder
+		reasoning strings.Builder
+		deltas    planDeltaGate
+		finish    openai.FinishReason
+	)
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			if deltas.sent {
+				gatewayUsage.fail()
+				return openai.ChatCompletionResponse{}, err
+			}
+			req.StreamOptions = nil
+			return s.createChatCompletion(ctx, llm, req)
+		}
+		resp.ID, resp.Model, resp.Created = chunk.ID, chunk.Model, chunk.Created
+		if chunk.Usage != nil {
+			resp.Usage = *chunk.Usage
+		}
+		for _, choice := range chunk.Choices {
+			if choice.Index != 0 {
+				continue
+			}
+			content.WriteString(choice.Delta.Content)
+			reasoning.WriteString(choice.Delta.ReasoningContent)
+			deltas.write(choice.Delta.Content, sink)
+			if choice.FinishReason != "" {
+				finish = choice.FinishReason
+			}
+		}
+	}
+	gatewayUsage.record(resp.Usage)
+	reply, _ := s.chaos.Malform(chaos.Provider, content.String())
+	resp.Choices = []openai.ChatCompletionChoice{{
+		Message:      openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: reply, ReasoningContent: reasoning.String()},
+		FinishReason: finish,
+	}}
+	return resp, nil
+}
+
+// planDeltaGate holds a streamed reply back until its first non-space
+// character: a reply that starts as a JSON object is passed on, anything else
+// (a reasoning trace, prose around the JSON) only arrives in the final
+// response.
+type planDeltaGate struct {
+	held    strings.Builder
+	decided bool
+	pass    bool
+	sent    bool
+}
+
+func (g *planDeltaGate) write(delta string, sink func(string)) {
+	if delta == "" {
+		return
+	}
+	if !g.decided {
+		g.held.WriteString(delta)
+		trimmed := strings.TrimSpace(g.held.String())
+		if trimmed == "" {
+			return
+		}
+		g.decided, g.pass = true, trimmed[0] == '{'
+		delta = g.held.String()
+	}
+	if g.pass {
+		sink(delta)
+		g.sent = true
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	pb "backend-go-model-gateway/proto/proto"
+
+	"github.com/sashabaranov/go-openai"
+	"google.golang.org/grpc"
+)
+
+type planChunkRecorder struct {
+	grpc.ServerStream
+	chunks []*pb.PlanChunk
+}
+
+func (r *planChunkRecorder) Context() context.Context { return context.Background() }
+
+func (r *planChunkRecorder) Send(c *pb.PlanChunk) error {
+	r.chunks = append(r.chunks, c)
+	return nil
+}
+
+// sseServer streams reply in pieces of three bytes, as an OpenAI-compatible
+// provider would with "stream": true.
+func sseServer(t *testing.T, reply string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req openai.ChatCompletionRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if !req.Stream {
+			_ = json.NewEncoder(w).Encode(openai.ChatCompletionResponse{Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Role: "assistant", Content: reply}}}})
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := 0; i < len(reply); i += 3 {
+			chunk, _ := json.Marshal(openai.ChatCompletionStreamResponse{Model: req.Model, Choices: []openai.ChatCompletionStreamChoice{{
+				Delta: openai.ChatCompletionStreamChoiceDelta{Content: reply[i:min(i+3, len(reply))]},
+			}}})
+			fmt.Fprintf(w, "data: %s\n\n", chunk)
+		}
+		usage, _ := json.Marshal(openai.ChatCompletionStreamResponse{Usage: &openai.Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15}})
+		fmt.Fprintf(w, "data: %s\n\ndata: [DONE]\n\n", usage)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func streamingServer(baseURL string) *server {
+	cfg := openai.DefaultConfig("")
+	cfg.BaseURL = baseURL
+	return &server{
+		llm:            &llmRuntime{Provider: providerOllama, Model: "llama3", Client: openai.NewClientWithConfig(cfg)},
+		requestTimeout: time.Duration(defaultRequestTimeoutSec) * time.Second,
+	}
+}
+
+func TestStreamPlan_StreamsDeltasThenFinal(t *testing.T) {
+	reply := `{"tool":{"name":"web_search","args":{"query":"lisbon"}}}`
+	s := streamingServer(sseServer(t, reply).URL)
+
+	rec := &planChunkRecorder{}
+	if err := s.StreamPlan(&pb.PlanRequest{Prompt: "weather in lisbon"}, rec); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.chunks) < 3 {
+		t.Fatalf("%d chunks, want deltas and a final", len(rec.chunks))
+	}
+	var streamed strings.Builder
+	for _, c := range rec.chunks[:len(rec.chunks)-1] {
+		if c.GetFinal() != nil {
+			t.Fatalf("final before the last chunk: %v", c)
+		}
+		streamed.WriteString(c.GetDelta())
+	}
+	if streamed.String() != reply {
+		t.Fatalf("deltas = %q, want %q", streamed.String(), reply)
+	}
+	final := rec.chunks[len(rec.chunks)-1].GetFinal()
+	if final == nil || !strings.Contains(final.GetPlan(), `"web_search"`) || final.GetProvider() != "ollama" {
+		t.Fatalf("final = %v", final)
+	}
+}
+
+func TestStreamPlan_ReasoningOnlyInFinal(t *testing.T) {
+	s := streamingServer(sseServer(t, `<think>{"tool":"maybe"}</think>{"steps":["Pack an umbrella"]}`).URL)
+
+	rec := &planChunkRecorder{}
+	if err := s.StreamPlan(&pb.PlanRequest{Prompt: "weather in lisbon"}, rec); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.chunks) != 1 || rec.chunks[0].GetFinal() == nil {
+		t.Fatalf("chunks = %v, want only the final", rec.chunks)
+	}
+	if plan := rec.chunks[0].GetFinal().GetPlan(); !strings.Contains(plan, "Pack an umbrella") || strings.Contains(plan, "maybe") {
+		t.Fatalf("plan = %s", plan)
+	}
+}
+
+func TestStreamPlan_MockSendsFinalOnly(t *testing.T) {
+	s := &server{llm: &llmRuntime{Provider: providerMock, Model: "mock"}, requestTimeout: time.Second}
+
+	rec := &planChunkRecorder{}
+	if err := s.StreamPlan(&pb.PlanRequest{Prompt: "hello"}, rec); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.chunks) != 1 || rec.chunks[0].GetFinal().GetPlan() == "" {
+		t.Fatalf("chunks = %v", rec.chunks)
+	}
+}
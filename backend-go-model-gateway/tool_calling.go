@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"backend-go-model-gateway/pkg/tools"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// Tool calling modes (LLM_TOOL_CALLING).
+const (
+	// toolCallingNative offers tools through the API's tools/tool_calls and
+	// keeps the JSON convention for models that reject them.
+	toolCallingNative = "native"
+	// toolCallingJSON always describes tools in the system prompt and parses
+	// a {"tool": ...} object from the reply.
+	toolCallingJSON = "json"
+)
+
+// jsonToolModels remembers the models that rejected native tools, so later
+// requests go straight to the JSON convention. It lives until the runtime is
+// reloaded.
+type jsonToolModels struct {
+	mu     sync.Mutex
+	models map[string]bool
+}
+
+func (m *jsonToolModels) add(model string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.models == nil {
+		m.models = map[string]bool{}
+	}
+	m.models[model] = true
+}
+
+func (m *jsonToolModels) has(model string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.models[model]
+}
+
+// nativeTools reports whether a GetPlan for model offers tools natively.
+func (r *llmRuntime) nativeTools(model string) bool {
+	return r.ToolCalling == toolCallingNative && r.Provider != providerMock && !r.jsonTools.has(model)
+}
+
+// openAITools converts tool definitions to function tools. Every parameter is
+// required, as the JSON convention's tools have no optional arguments.
+func openAITools(defs []tools.Definition) []openai.Tool {
+	tools := make([]openai.Tool, 0, len(defs))
+	for _, d := range defs {
+		properties := make(map[string]any, len(d.Parameters))
+		required := make([]string, 0, len(d.Parameters))
+		for name, p := range d.Parameters {
+			properties[name] = map[string]any{"type": p.Type, "description": p.Description}
+			required = append(required, name)
+		}
+		sort.Strings(required)
+		tools = append(tools, openai.Tool{
+			Type: openai.ToolTypeFunction,
+			Function: &openai.FunctionDefinition{
+				Name:        d.Name,
+				Description: d.Description,
+				Parameters:  map[string]any{"type": "object", "properties": properties, "required": required},
+			},
+		})
+	}
+	return tools
+}
+
+// toolCallPlan returns the reply's first tool call in the JSON convention the
+// planner parses ({"tool": {"name", "args"}}). ok is false when the reply has
+// no tool call or its arguments are not a JSON object; the content is parsed
+// as before then.
+func toolCallPlan(msg openai.ChatCompletionMessage) (plan string, ok bool) {
+	for _, call := range msg.ToolCalls {
+		if call.Function.Name == "" {
+			continue
+		}
+		args := map[string]any{}
+		if raw := strings.TrimSpace(call.Function.Arguments); raw != "" {
+			if err := json.Unmarshal([]byte(raw), &args); err != nil {
+				return "", false
+			}
+		}
+		b, _ := json.Marshal(map[string]any{"tool": map[string]any{"name": call.Function.Name, "args": args}})
+		return string(b), true
+	}
+	return "", false
+}
+
+// toolsUnsupported reports whether err is a provider refusing the tools
+// parameter for the model: OpenRouter answers 404 "No endpoints found that
+// support tool use", Ollama 400 "... does not support tools".
+func toolsUnsupported(err error) bool {
+	var code int
+	var apiErr *openai.APIError
+	var reqErr *openai.RequestError
+	switch {
+	case errors.As(err, &apiErr):
+		code = apiErr.HTTPStatusCode
+	case errors.As(err, &reqErr):
+		code = reqErr.HTTPStatusCode
+	default:
+		return false
+	}
+	if code != http.StatusBadRequest && code != http.StatusNotFound {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "tool") || strings.Contains(msg, "function")
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"backend-go-model-gateway/pkg/tools"
+	pb "backend-go-model-gateway/proto/proto"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestGetPlan_NativeToolCall(t *testing.T) {
+	var sent openai.ChatCompletionRequest
+	llm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&sent)
+		_ = json.NewEncoder(w).Encode(openai.ChatCompletionResponse{
+			Choices: []openai.ChatCompletionChoice{{
+				Message: openai.ChatCompletionMessage{Role: "assistant", ToolCalls: []openai.ToolCall{{
+					ID: "call_1", Type: openai.ToolTypeFunction,
+					Function: openai.FunctionCall{Name: "web_search", Arguments: `{"query":"lisbon weather"}`},
+				}}},
+				FinishReason: openai.FinishReasonToolCalls,
+			}},
+		})
+	}))
+	defer llm.Close()
+	cfg := openai.DefaultConfig("")
+	cfg.BaseURL = llm.URL
+	s := &server{
+		llm:            &llmRuntime{Provider: providerOpenRouter, Model: "m", Client: openai.NewClientWithConfig(cfg), ToolCalling: toolCallingNative},
+		requestTimeout: time.Duration(defaultRequestTimeoutSec) * time.Second,
+	}
+
+	resp, err := s.GetPlan(context.Background(), &pb.PlanRequest{Prompt: "weather in lisbon"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sent.Tools) != 1 || sent.Tools[0].Function.Name != "web_search" {
+		t.Fatalf("tools sent = %+v", sent.Tools)
+	}
+	if system := sent.Messages[0].Content; strings.Contains(system, "<available_tools>") || strings.Contains(system, "'tool'") {
+		t.Fatalf("system message still describes the JSON convention: %s", system)
+	}
+	var plan struct {
+		Tool struct {
+			Name string         `json:"name"`
+			Args map[string]any `json:"args"`
+		} `json:"tool"`
+		ModelType string `json:"model_type"`
+	}
+	if err := json.Unmarshal([]byte(resp.GetPlan()), &plan); err != nil || plan.Tool.Name != "web_search" || plan.Tool.Args["query"] != "lisbon weather" || plan.ModelType != "openrouter" {
+		t.Fatalf("plan = %s (%v)", resp.GetPlan(), err)
+	}
+}
+
+func TestGetPlan_NativeToolsUnsupported(t *testing.T) {
+	var calls, withTools int
+	var sent openai.ChatCompletionRequest
+	llm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent = openai.ChatCompletionRequest{}
+		_ = json.NewDecoder(r.Body).Decode(&sent)
+		calls++
+		if len(sent.Tools) > 0 {
+			withTools++
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":{"message":"registry.ollama.ai/library/llama3:latest does not support tools","type":"api_error"}}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(openai.ChatCompletionResponse{
+			Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Role: "assistant", Content: `{"tool":{"name":"web_search","args":{"query":"q"}}}`}}},
+		})
+	}))
+	defer llm.Close()
+	cfg := openai.DefaultConfig("")
+	cfg.BaseURL = llm.URL
+	s := &server{
+		llm:            &llmRuntime{Provider: providerOllama, Model: "llama3", Client: openai.NewClientWithConfig(cfg), ToolCalling: toolCallingNative},
+		requestTimeout: time.Duration(defaultRequestTimeoutSec) * time.Second,
+	}
+
+	for i := 0; i < 2; i++ {
+		resp, err := s.GetPlan(context.Background(), &pb.PlanRequest{Prompt: "search for q"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(resp.GetPlan(), `"web_search"`) || !strings.Contains(sent.Messages[0].Content, "<available_tools>") {
+			t.Fatalf("plan = %s, system = %s", resp.GetPlan(), sent.Messages[0].Content)
+		}
+	}
+	// The model is remembered: only the first request tried native tools.
+	if calls != 3 || withTools != 1 {
+		t.Fatalf("calls = %d, with tools = %d; want 3 and 1", calls, withTools)
+	}
+}
+
+func TestToolCallPlan(t *testing.T) {
+	call := func(name, args string) openai.ChatCompletionMessage {
+		return openai.ChatCompletionMessage{ToolCalls: []openai.ToolCall{{Function: openai.FunctionCall{Name: name, Arguments: args}}}}
+	}
+	if plan, ok := toolCallPlan(call("web_search", "")); !ok || plan != `{"tool":{"args":{},"name":"web_search"}}` {
+		t.Fatalf("no args: %s %v", plan, ok)
+	}
+	if _, ok := toolCallPlan(call("web_search", `{"query":`)); ok {
+		t.Fatal("truncated arguments accepted")
+	}
+	if _, ok := toolCallPlan(openai.ChatCompletionMessage{Content: "{}"}); ok {
+		t.Fatal("content without tool calls accepted")
+	}
+
+	offered := openAITools(tools.Builtin)
+	params, _ := json.Marshal(offered[0].Function.Parameters)
+	if string(params) != `{"properties":{"query":{"description":"The search query.","type":"string"}},"required":["query"],"type":"object"}` {
+		t.Fatalf("web_search parameters = %s", params)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"backend-go-model-gateway/pkg/discovery"
+	"backend-go-model-gateway/pkg/tools"
+	pb "backend-go-model-gateway/proto/proto"
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+const defaultToolDiscoveryIntervalSec = 60
+
+// newToolCatalog dials the sandbox at cfg.SandboxGRPCAddr
+// (RUST_SANDBOX_GRPC_ADDR) for its ListTools. Without the address the gateway
+// offers tools.Builtin (nil catalog). The catalog is refreshed every
+// cfg.DiscoveryIntervalSeconds (0 refreshes only at startup).
+func newToolCatalog(cfg ToolsConfig) (catalog *tools.Catalog, interval time.Duration, closeFn func(), err error) {
+	addr := cfg.SandboxGRPCAddr
+	if addr == "" {
+		return nil, 0, func() {}, nil
+	}
+	interval = time.Duration(cfg.DiscoveryIntervalSeconds) * time.Second
+	opts := append(discovery.DialOptions(),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+	)
+	conn, err := grpc.NewClient(addr, opts...)
+	if err != nil {
+		return nil, 0, nil, fmt.Errorf("tool discovery: %w", err)
+	}
+	return tools.NewCatalog(pb.NewToolServiceClient(conn)), interval, func() { _ = conn.Close() }, nil
+}
+
+// logToolRefresh reports a tool catalog refresh. Failures are warnings: the
+// last good list (or tools.Builtin) stays in effect.
+func logToolRefresh(catalog *tools.Catalog) func(changed bool, err error) {
+	return func(changed bool, err error) {
+		if err != nil {
+			log.Printf(
+				`{"timestamp":"%s","level":"warn","service":"%s","component":"tool_discovery","error":%q,"message":"tool discovery failed; keeping the current tools."}`,
+				time.Now().Format(time.RFC3339Nano), SERVICE_NAME, err.Error(),
+			)
+			return
+		}
+		if changed {
+			names := make([]string, 0)
+			for _, d := range catalog.Definitions() {
+				names = append(names, d.Name)
+			}
+			log.Printf(
+				`{"timestamp":"%s","level":"info","service":"%s","component":"tool_discovery","tools":%q,"message":"tools discovered from the sandbox."}`,
+				time.Now().Format(time.RFC3339Nano), SERVICE_NAME, strings.Join(names, ","),
+			)
+		}
+	}
+}
@@ -3,7 +3,8 @@ package main
 import (
 	"context"
 	"net/http"
-	"os"
+
+	"backend-go-model-gateway/pkg/tracing"
 
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"go.opentelemetry.io/otel"
@@ -12,25 +13,20 @@ import (
 	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 )
 
 // InitTracer configures OpenTelemetry tracing with an OTLP/gRPC exporter.
 //
-// Default exporter target: localhost:4317
-// Override with OTEL_EXPORTER_OTLP_ENDPOINT.
+// The collector endpoint, export timeout, TLS and sampler come from the
+// standard OTEL_* variables (see pkg/tracing); the default is a plaintext
+// localhost:4317 collector and every trace sampled.
 func InitTracer(ctx context.Context) (*sdktrace.TracerProvider, error) {
-	endpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
-	if endpoint == "" {
-		endpoint = "localhost:4317"
+	cfg, err := tracing.FromEnv()
+	if err != nil {
+		return nil, err
 	}
 
-	exporter, err := otlptracegrpc.New(
-		ctx,
-		otlptracegrpc.WithEndpoint(endpoint),
-		otlptracegrpc.WithDialOption(grpc.WithTransportCredentials(insecure.NewCredentials())),
-	)
+	exporter, err := otlptracegrpc.New(ctx, cfg.ExporterOptions()...)
 	if err != nil {
 		return nil, err
 	}
@@ -48,6 +44,7 @@ func InitTracer(ctx context.Context) (*sdktrace.TracerProvider, error) {
 	tp := sdktrace.NewTracerProvider(
 		sdktrace.WithBatcher(exporter),
 		sdktrace.WithResource(res),
+		sdktrace.WithSampler(cfg.Sampler),
 	)
 
 	otel.SetTracerProvider(tp)
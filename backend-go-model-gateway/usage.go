@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// tokenUsage accumulates provider token usage across all GetPlan calls since
+// process start. It is exposed on GET /api/v1/usage so load tests can compute
+// per-run deltas without scraping provider dashboards, and so the BFF can
+// show today's LLM spend.
+type tokenUsage struct {
+	requests         atomic.Int64
+	errors           atomic.Int64
+	promptTokens     atomic.Int64
+	completionTokens atomic.Int64
+
+	// mu guards the current UTC day's totals.
+	mu    sync.Mutex
+	day   string
+	today TokenUsageDay
+
+	// prices are USD per million prompt and completion tokens
+	// (LLM_PRICE_INPUT_PER_MTOK, LLM_PRICE_OUTPUT_PER_MTOK); nil when unset.
+	prices *[2]float64
+}
+
+// TokenUsageSnapshot is the JSON shape returned by GET /api/v1/usage.
+type TokenUsageSnapshot struct {
+	Requests int64 `json:"requests"`
+	// Errors counts failed provider calls, retries included.
+	Errors           int64         `json:"errors"`
+	PromptTokens     int64         `json:"prompt_tokens"`
+	CompletionTokens int64         `json:"completion_tokens"`
+	TotalTokens      int64         `json:"total_tokens"`
+	Today            TokenUsageDay `json:"today"`
+}
+
+// TokenUsageDay is the usage of one UTC day.
+type TokenUsageDay struct {
+	Date             string `json:"date"`
+	Requests         int64  `json:"requests"`
+	PromptTokens     int64  `json:"prompt_tokens"`
+	CompletionTokens int64  `json:"completion_tokens"`
+	// CostUSD estimates the day's spend at the configured prices; it is
+	// omitted when no price is set.
+	CostUSD *float64 `json:"cost_usd,omitempty"`
+}
+
+var gatewayUsage tokenUsage
+
+// usagePrices parses LLM_PRICE_INPUT_PER_MTOK and LLM_PRICE_OUTPUT_PER_MTOK
+// (nil when neither is set). Both or neither must be set.
+func usagePrices(cfg LLMConfig) (*[2]float64, error) {
+	in, out := cfg.PriceInputPerMTok, cfg.PriceOutputPerMTok
+	if in == "" && out == "" {
+		return nil, nil
+	}
+	var prices [2]float64
+	for i, kv := range [][2]string{{"LLM_PRICE_INPUT_PER_MTOK", in}, {"LLM_PRICE_OUTPUT_PER_MTOK", out}} {
+		f, err := strconv.ParseFloat(kv[1], 64)
+		if err != nil || f < 0 {
+			return nil, fmt.Errorf("%s: want a non-negative number (USD per million tokens), got %q", kv[0], kv[1])
+		}
+		prices[i] = f
+	}
+	return &prices, nil
+}
+
+func (u *tokenUsage) record(usage openai.Usage) {
+	u.requests.Add(1)
+	u.promptTokens.Add(int64(usage.PromptTokens))
+	u.completionTokens.Add(int64(usage.CompletionTokens))
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.rollover(time.Now())
+	u.today.Requests++
+	u.today.PromptTokens += int64(usage.PromptTokens)
+	u.today.CompletionTokens += int64(usage.CompletionTokens)
+}
+
+func (u *tokenUsage) fail() {
+	u.errors.Add(1)
+}
+
+// rollover starts a new day's totals once the UTC date changes. u.mu must
+// be held.
+func (u *tokenUsage) rollover(now time.Time) {
+	if date := now.UTC().Format(time.DateOnly); date != u.day {
+		u.day = date
+		u.today = TokenUsageDay{}
+	}
+}
+
+func (u *tokenUsage) snapshot() TokenUsageSnapshot {
+	prompt := u.promptTokens.Load()
+	completion := u.completionTokens.Load()
+	u.mu.Lock()
+	u.rollover(time.Now())
+	today := u.today
+	today.Date = u.day
+	prices := u.prices
+	u.mu.Unlock()
+	if prices != nil {
+		cost := (float64(today.PromptTokens)*prices[0] + float64(today.CompletionTokens)*prices[1]) / 1e6
+		today.CostUSD = &cost
+	}
+	return TokenUsageSnapshot{
+		Requests:         u.requests.Load(),
+		Errors:           u.errors.Load(),
+		PromptTokens:     prompt,
+		CompletionTokens: completion,
+		TotalTokens:      prompt + completion,
+		Today:            today,
+	}
+}
+
+func (u *tokenUsage) setPrices(prices *[2]float64) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.prices = prices
+}
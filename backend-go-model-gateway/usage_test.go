@@ -0,0 +1,46 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+func TestTokenUsage_Today(t *testing.T) {
+	var u tokenUsage
+	u.setPrices(&[2]float64{3, 15})
+	u.record(openai.Usage{PromptTokens: 1000, CompletionTokens: 200})
+	u.record(openai.Usage{PromptTokens: 1000, CompletionTokens: 200})
+	u.fail()
+
+	s := u.snapshot()
+	if s.Requests != 2 || s.Errors != 1 || s.TotalTokens != 2400 {
+		t.Fatalf("snapshot = %+v", s)
+	}
+	if s.Today.Date != time.Now().UTC().Format(time.DateOnly) || s.Today.Requests != 2 || s.Today.CostUSD == nil || *s.Today.CostUSD != 0.012 {
+		t.Fatalf("today = %+v", s.Today)
+	}
+
+	// A new UTC day starts from zero; the totals since start are kept.
+	u.mu.Lock()
+	u.day = "2000-01-01"
+	u.mu.Unlock()
+	if s := u.snapshot(); s.Today.Requests != 0 || *s.Today.CostUSD != 0 || s.Requests != 2 {
+		t.Fatalf("after rollover = %+v", s)
+	}
+}
+
+func TestUsagePrices(t *testing.T) {
+	if p, err := usagePrices(envConfig(t).LLM); p != nil || err != nil {
+		t.Fatalf("unset = %v, %v", p, err)
+	}
+	t.Setenv("LLM_PRICE_INPUT_PER_MTOK", "0.5")
+	if _, err := loadConfig(); err == nil {
+		t.Fatal("input price without output price accepted")
+	}
+	t.Setenv("LLM_PRICE_OUTPUT_PER_MTOK", "1.5")
+	if p, err := usagePrices(envConfig(t).LLM); err != nil || *p != [2]float64{0.5, 1.5} {
+		t.Fatalf("prices = %v, %v", p, err)
+	}
+}
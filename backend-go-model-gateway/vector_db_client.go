@@ -6,6 +6,8 @@ import (
 	"math"
 	"time"
 
+	"backend-go-model-gateway/pkg/discovery"
+	"backend-go-model-gateway/pkg/ragfilter"
 	pb "backend-go-model-gateway/proto/proto"
 
 	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
@@ -14,10 +16,6 @@ import (
 )
 
 // VectorQueryRequest defines the input for a vector search.
-//
-// This is intentionally small for the initial integration phase.
-// The actual Vector DB implementation (Pinecone/Weaviate/etc.) will likely
-// expand this with filters, namespaces, and/or an embedding vector.
 type VectorQueryRequest struct {
 	QueryText string `json:"query_text"`
 	TopK      int    `json:"top_k"`
@@ -27,14 +25,24 @@ type VectorQueryRequest struct {
 	// gateway to simulate multi-KB retrieval while the external request schema is
 	// still fixed.
 	KnowledgeBases []string `json:"knowledge_bases,omitempty"`
+	// Filter scopes retrieval by document metadata (nil: no filtering).
+	Filter *ragfilter.Filter `json:"filter,omitempty"`
+	// Namespace confines retrieval to one tenant's documents ("": no
+	// scoping). Only tenantRAGClient sets it, from the authenticated tenant;
+	// it is never taken from caller input.
+	Namespace string `json:"-"`
 	// Placeholder for embedding vector if needed later.
 	// Embedding []float32 `json:"embedding,omitempty"`
 }
 
 // VectorQueryMatch defines a single search result.
 type VectorQueryMatch struct {
-	ID            string  `json:"id"`
-	Score         float64 `json:"score"`
+	ID    string  `json:"id"`
+	Score float64 `json:"score"`
+	// RawScore is the value the backend returned before it was mapped to
+	// Score: a distance (memory service, pgvector, Milvus L2), a similarity or
+	// a keyword rank. Wrapping stages keep it while replacing Score.
+	RawScore      float64 `json:"raw_score,omitempty"`
 	Text          string  `json:"text"`
 	Source        string  `json:"source"`
 	KnowledgeBase string  `json:"knowledge_base"`
@@ -47,24 +55,25 @@ type RAGContextClient interface {
 
 // RAGGRPCClient implements RAG retrieval by calling the Python Memory Service over gRPC.
 type RAGGRPCClient struct {
+	// addr is the Memory Service target, for logs.
+	addr   string
 	conn   *grpc.ClientConn
 	client pb.ModelGatewayClient
 }
 
-func NewRAGGRPCClient(ctx context.Context) (*RAGGRPCClient, error) {
-	addr := getEnv("RAG_GRPC_ADDR", "localhost:50052")
-
-	conn, err := grpc.DialContext(
-		ctx,
-		addr,
+// NewRAGGRPCClient dials the Memory Service at addr (RAG_GRPC_ADDR).
+func NewRAGGRPCClient(ctx context.Context, addr string) (*RAGGRPCClient, error) {
+	// addr may be a static host:port or a discovery target (consul:///, dnssrv:///).
+	opts := append(discovery.DialOptions(),
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
 		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
 	)
+	conn, err := grpc.DialContext(ctx, addr, opts...)
 	if err != nil {
 		return nil, err
 	}
 
-	return &RAGGRPCClient{conn: conn, client: pb.NewModelGatewayClient(conn)}, nil
+	return &RAGGRPCClient{addr: addr, conn: conn, client: pb.NewModelGatewayClient(conn)}, nil
 }
 
 func (c *RAGGRPCClient) Close() error {
@@ -83,6 +92,7 @@ func (c *RAGGRPCClient) GetContext(ctx context.Context, req VectorQueryRequest)
 		Query:          req.QueryText,
 		TopK:           int32(req.TopK),
 		KnowledgeBases: req.KnowledgeBases,
+		Filter:         req.Filter.Proto(time.Now()),
 	})
 	if err != nil {
 		return nil, err
@@ -98,6 +108,7 @@ func (c *RAGGRPCClient) GetContext(ctx context.Context, req VectorQueryRequest)
 		matches = append(matches, VectorQueryMatch{
 			ID:            m.GetId(),
 			Score:         score,
+			RawScore:      d,
 			Text:          m.GetText(),
 			Source:        m.GetSource(),
 			KnowledgeBase: m.GetKnowledgeBase(),
@@ -106,7 +117,7 @@ func (c *RAGGRPCClient) GetContext(ctx context.Context, req VectorQueryRequest)
 
 	log.Printf(
 		`{"timestamp":"%s","level":"info","service":"%s","component":"RAGGRPCClient","method":"GetContext","rag_addr":%q,"query_text":%q,"top_k":%d,"match_count":%d}`,
-		time.Now().Format(time.RFC3339Nano), SERVICE_NAME, getEnv("RAG_GRPC_ADDR", "localhost:50052"), req.QueryText, req.TopK, len(matches),
+		time.Now().Format(time.RFC3339Nano), SERVICE_NAME, c.addr, req.QueryText, req.TopK, len(matches),
 	)
 
 	return matches, nil
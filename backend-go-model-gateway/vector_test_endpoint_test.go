@@ -29,7 +29,7 @@ func (fakeRAGClient) GetContext(_ context.Context, req VectorQueryRequest) ([]Ve
 
 func TestVectorTestEndpoint_DefaultsToBodyKBAndEchoesQueryAndTopK(t *testing.T) {
 	vectorClient := fakeRAGClient{}
-	srv := httptest.NewServer(NewHTTPMux(vectorClient))
+	srv := httptest.NewServer(NewHTTPMux(vectorClient, adminRoutes{}))
 	t.Cleanup(srv.Close)
 
 	queryText := "What is the protocol for new users?"
@@ -85,7 +85,7 @@ func TestVectorTestEndpoint_DefaultsToBodyKBAndEchoesQueryAndTopK(t *testing.T)
 
 func TestVectorTestEndpoint_MissingQueryParam_Returns400(t *testing.T) {
 	vectorClient := fakeRAGClient{}
-	srv := httptest.NewServer(NewHTTPMux(vectorClient))
+	srv := httptest.NewServer(NewHTTPMux(vectorClient, adminRoutes{}))
 	t.Cleanup(srv.Close)
 
 	resp, err := http.Get(srv.URL + "/api/v1/vector-test?k=3")
@@ -98,3 +98,22 @@ func TestVectorTestEndpoint_MissingQueryParam_Returns400(t *testing.T) {
 		t.Fatalf("expected status 400, got %d", resp.StatusCode)
 	}
 }
+
+func TestVectorTestEndpoint_KBParam(t *testing.T) {
+	srv := httptest.NewServer(NewHTTPMux(fakeRAGClient{}, adminRoutes{}))
+	t.Cleanup(srv.Close)
+
+	resp, err := http.Get(srv.URL + "/api/v1/vector-test?query=hello&kb=Domain-KB")
+	if err != nil {
+		t.Fatalf("http get: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var matches []VectorQueryMatch
+	if err := json.NewDecoder(resp.Body).Decode(&matches); err != nil {
+		t.Fatalf("decode json: %v", err)
+	}
+	if len(matches) != 1 || matches[0].KnowledgeBase != "Domain-KB" {
+		t.Fatalf("expected a Domain-KB match, got %#v", matches)
+	}
+}
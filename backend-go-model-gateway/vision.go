@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"backend-go-model-gateway/internal/logger"
+	"backend-go-model-gateway/pkg/egress"
+	pb "backend-go-model-gateway/proto/proto"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+const (
+	defaultVisionMaxImageBytes = 5 << 20
+	defaultVisionMaxImages     = 4
+	visionFetchTimeout         = 10 * time.Second
+)
+
+// visionImageTypes are the image formats every vision provider accepts.
+var visionImageTypes = []string{"image/png", "image/jpeg", "image/gif", "image/webp"}
+
+// visionFetcher loads GetPlan's image resources, so vision-capable models
+// see them. Images are sent inline as data URLs: providers do not fetch URLs
+// themselves, and the gateway decides what may be fetched.
+type visionFetcher struct {
+	// policy lists the hosts images may be fetched from (nil: none; data
+	// URLs need no fetch).
+	policy   *egress.Policy
+	client   *http.Client
+	maxBytes int64
+	// maxImages is how many images one plan carries; later ones are dropped.
+	maxImages int
+}
+
+// newVisionFetcher configures image resources from cfg: the hosts and
+// address ranges images may be fetched from, as for PAGI_EGRESS_ALLOW (none:
+// only data: URIs are used), and the size and count limits per plan.
+func newVisionFetcher(cfg VisionConfig) (*visionFetcher, error) {
+	v := &visionFetcher{
+		maxBytes:  int64(cfg.MaxImageBytes),
+		maxImages: cfg.MaxImages,
+	}
+	policy, err := cfg.policy()
+	if err != nil {
+		return nil, err
+	}
+	if policy == nil {
+		return v, nil
+	}
+	v.policy = policy
+	// On top of http.DefaultTransport, so PAGI_EGRESS_ALLOW applies as well.
+	v.client = &http.Client{
+		Transport: policy.Transport(http.DefaultTransport.(*http.Transport)),
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 3 {
+				return errors.New("too many redirects")
+			}
+			return policy.CheckURL(req.Context(), req.URL.String())
+		},
+	}
+	return v, nil
+}
+
+// policy is the egress policy for fetched images (nil: none allowed).
+func (c VisionConfig) policy() (*egress.Policy, error) {
+	if len(c.AllowedHosts) == 0 {
+		return nil, nil
+	}
+	policy, err := egress.New(c.AllowedHosts, []string{"https"})
+	if err != nil {
+		return nil, fmt.Errorf("VISION_ALLOWED_HOSTS: %w", err)
+	}
+	return policy, nil
+}
+
+// images returns the data URLs of the request's image resources. Resources
+// that cannot be used are logged and skipped: a plan without the image beats
+// no plan.
+func (v *visionFetcher) images(ctx context.Context, resources []*pb.Resource) []string {
+	if v == nil {
+		return nil
+	}
+	lg := logger.NewContextLogger(ctx)
+	var out []string
+	for _, r := range resources {
+		if !strings.EqualFold(r.GetType(), "image") {
+			continue
+		}
+		if len(out) == v.maxImages {
+			lg.Warn("vision_resource_skipped", "uri", resourceLogURI(r.GetUri()), "reason", fmt.Sprintf("more than %d images", v.maxImages))
+			continue
+		}
+		url, err := v.fetch(ctx, r.GetUri())
+		if err != nil {
+			lg.Warn("vision_resource_skipped", "uri", resourceLogURI(r.GetUri()), "reason", err.Error())
+			continue
+		}
+		out = append(out, url)
+	}
+	return out
+}
+
+// fetch returns an image URI as a data URL: data: URIs are checked, https
+// URIs on an allowed host are downloaded.
+func (v *visionFetcher) fetch(ctx context.Context, uri string) (string, error) {
+	if rest, ok := strings.CutPrefix(uri, "data:"); ok {
+		meta, data, ok := strings.Cut(rest, ",")
+		mediaType, isBase64 := strings.CutSuffix(meta, ";base64")
+		if !ok || !isBase64 || !isVisionImageType(mediaType) {
+			return "", errors.New("data URI must be a base64 PNG, JPEG, GIF or WebP image")
+		}
+		if int64(base64.StdEncoding.DecodedLen(len(data))) > v.maxBytes {
+			return "", fmt.Errorf("image exceeds %d bytes", v.maxBytes)
+		}
+		return uri, nil
+	}
+	if v.policy == nil {
+		return "", errors.New("fetching images is off (set VISION_ALLOWED_HOSTS)")
+	}
+	if err := v.policy.CheckURL(ctx, uri); err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(ctx, visionFetchTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", strings.Join(visionImageTypes, ", "))
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("fetch image: HTTP %d", resp.StatusCode)
+	}
+	if resp.ContentLength > v.maxBytes {
+		return "", fmt.Errorf("image exceeds %d bytes", v.maxBytes)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, v.maxBytes+1))
+	if err != nil {
+		return "", err
+	}
+	if int64(len(body)) > v.maxBytes {
+		return "", fmt.Errorf("image exceeds %d bytes", v.maxBytes)
+	}
+	// The content decides, not the Content-Type header: a mislabelled HTML
+	// page is not an image.
+	mediaType := http.DetectContentType(body)
+	if !isVisionImageType(mediaType) {
+		return "", fmt.Errorf("not a PNG, JPEG, GIF or WebP image (%s)", mediaType)
+	}
+	return "data:" + mediaType + ";base64," + base64.StdEncoding.EncodeToString(body), nil
+}
+
+func isVisionImageType(mediaType string) bool {
+	for _, t := range visionImageTypes {
+		if strings.EqualFold(mediaType, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// resourceLogURI shortens data URIs for logs.
+func resourceLogURI(uri string) string {
+	if meta, _, ok := strings.Cut(uri, ","); ok && strings.HasPrefix(uri, "data:") {
+		return meta + ",..."
+	}
+	return uri
+}
+
+// withImages turns a user message into text and image parts.
+func withImages(msg openai.ChatCompletionMessage, images []string) openai.ChatCompletionMessage {
+	if len(images) == 0 {
+		return msg
+	}
+	parts := []openai.ChatMessagePart{{Type: openai.ChatMessagePartTypeText, Text: msg.Content}}
+	for _, url := range images {
+		parts = append(parts, openai.ChatMessagePart{Type: openai.ChatMessagePartTypeImageURL, ImageURL: &openai.ChatMessageImageURL{URL: url}})
+	}
+	msg.Content, msg.MultiContent = "", parts
+	return msg
+}
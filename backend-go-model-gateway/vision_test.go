@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"backend-go-model-gateway/pkg/egress"
+	pb "backend-go-model-gateway/proto/proto"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func pngBytes(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 2, 2))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestVisionFetcher_Fetch(t *testing.T) {
+	img := pngBytes(t)
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/chart.png":
+			_, _ = w.Write(img)
+		case "/page":
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write([]byte("<html><body>not an image</body></html>"))
+		case "/huge.png":
+			_, _ = w.Write(append(img, make([]byte, 4096)...))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	allowLocal, err := egress.New([]string{"127.0.0.1"}, []string{"https"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	allowOther, _ := egress.New([]string{"images.example.com"}, []string{"https"})
+	v := &visionFetcher{policy: allowLocal, client: srv.Client(), maxBytes: 1024, maxImages: 4}
+	dataURL := "data:image/png;base64," + base64.StdEncoding.EncodeToString(img)
+
+	got, err := v.fetch(context.Background(), srv.URL+"/chart.png")
+	if err != nil || got != dataURL {
+		t.Fatalf("fetch = %.40q, %v; want the PNG as a data URL", got, err)
+	}
+	for name, tc := range map[string]struct {
+		v   *visionFetcher
+		uri string
+	}{
+		"not an image":     {v, srv.URL + "/page"},
+		"too large":        {v, srv.URL + "/huge.png"},
+		"not found":        {v, srv.URL + "/missing.png"},
+		"http":             {v, strings.Replace(srv.URL, "https:", "http:", 1) + "/chart.png"},
+		"host not allowed": {&visionFetcher{policy: allowOther, client: srv.Client(), maxBytes: 1024}, srv.URL + "/chart.png"},
+		"fetching off":     {&visionFetcher{maxBytes: 1024}, srv.URL + "/chart.png"},
+		"data URI type":    {v, "data:text/html;base64,PGh0bWw+"},
+	} {
+		if _, err := tc.v.fetch(context.Background(), tc.uri); err == nil {
+			t.Errorf("%s: fetched %s", name, tc.uri)
+		}
+	}
+	if got, err := (&visionFetcher{maxBytes: 1024}).fetch(context.Background(), dataURL); err != nil || got != dataURL {
+		t.Fatalf("data URI without fetching: %v", err)
+	}
+}
+
+func TestGetPlan_ImageResources(t *testing.T) {
+	img := "data:image/png;base64," + base64.StdEncoding.EncodeToString(pngBytes(t))
+	for _, tc := range []struct {
+		model  string
+		images int
+	}{
+		{"gpt-4o", 1},
+		{"llama3", 0},
+	} {
+		t.Run(tc.model, func(t *testing.T) {
+			var sent struct {
+				Messages []struct {
+					Content json.RawMessage `json:"content"`
+				} `json:"messages"`
+			}
+			llm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_ = json.NewDecoder(r.Body).Decode(&sent)
+				_ = json.NewEncoder(w).Encode(openai.ChatCompletionResponse{Choices: []openai.ChatCompletionChoice{{
+					Message: openai.ChatCompletionMessage{Role: "assistant", Content: `{"steps":["Read the chart"]}`},
+				}}})
+			}))
+			defer llm.Close()
+			cfg := openai.DefaultConfig("")
+			cfg.BaseURL = llm.URL
+			s := &server{
+				llm:            &llmRuntime{Provider: providerOpenRouter, Model: tc.model, Client: openai.NewClientWithConfig(cfg)},
+				requestTimeout: time.Duration(defaultRequestTimeoutSec) * time.Second,
+				vision:         &visionFetcher{maxBytes: 1 << 20, maxImages: 4},
+			}
+
+			_, err := s.GetPlan(context.Background(), &pb.PlanRequest{Prompt: "what does the chart show?", Resources: []*pb.Resource{
+				{Type: "image", Uri: img},
+				{Type: "audio", Uri: "https://example.com/a.mp3"},
+			}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var parts []openai.ChatMessagePart
+			_ = json.Unmarshal(sent.Messages[1].Content, &parts)
+			images := 0
+			for _, p := range parts {
+				if p.Type == openai.ChatMessagePartTypeImageURL && p.ImageURL.URL == img {
+					images++
+				}
+			}
+			if images != tc.images {
+				t.Fatalf("user message = %s; want %d images", sent.Messages[1].Content, tc.images)
+			}
+			if tc.images > 0 && (parts[0].Type != openai.ChatMessagePartTypeText || !strings.Contains(parts[0].Text, "what does the chart show?")) {
+				t.Fatalf("first part = %+v, want the prompt", parts[0])
+			}
+		})
+	}
+}
@@ -2,14 +2,23 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log"
+	"net/http"
 	"os"
-	"os/signal"
-	"syscall"
+	"sync"
+	"time"
+
+	"backend-go-model-gateway/pkg/admin"
+	"backend-go-model-gateway/pkg/buildinfo"
+	"backend-go-model-gateway/pkg/lifecycle"
 
 	"github.com/go-redis/redis/v8"
 )
 
+// serviceName identifies the service in GET /version and GET /admin/status.
+const serviceName = "backend-go-notification-service"
+
 func getenv(key, fallback string) string {
 	if v := os.Getenv(key); v != "" {
 		return v
@@ -17,9 +26,62 @@ func getenv(key, fallback string) string {
 	return fallback
 }
 
+// subscription tracks which channel the service listens on. Draining
+// unsubscribes so another replica picks up new notifications; a config reload
+// can move it to a different channel.
+type subscription struct {
+	sub *redis.PubSub
+
+	mu       sync.Mutex
+	channel  string
+	draining bool
+}
+
+func (s *subscription) setDraining(ctx context.Context, draining bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.draining = draining
+	if draining {
+		return s.sub.Unsubscribe(ctx, s.channel)
+	}
+	return s.sub.Subscribe(ctx, s.channel)
+}
+
+func (s *subscription) setChannel(ctx context.Context, channel string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if channel == s.channel {
+		return nil
+	}
+	if !s.draining {
+		if err := s.sub.Subscribe(ctx, channel); err != nil {
+			return err
+		}
+		if err := s.sub.Unsubscribe(ctx, s.channel); err != nil {
+			return err
+		}
+	}
+	s.channel = channel
+	return nil
+}
+
+func (s *subscription) status(context.Context) map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return map[string]any{"channel": s.channel, "subscribed": !s.draining}
+}
+
 func main() {
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	// The subscriber and the admin API run in this group; SIGINT or SIGTERM,
+	// or either of them exiting, shuts the other down.
+	group, ctx := lifecycle.New(context.Background(), lifecycle.Options{})
+
+	adminOpts := admin.OptionsFromEnv()
+	if adminOpts.ConfigFile != "" {
+		if _, err := admin.LoadEnvFile(adminOpts.ConfigFile); err != nil {
+			log.Fatalf("failed to load PAGI_CONFIG_FILE: %v", err)
+		}
+	}
 
 	redisAddr := getenv("REDIS_ADDR", "redis:6379")
 	channel := getenv("PAGI_NOTIFICATIONS_CHANNEL", "pagi_notifications")
@@ -31,27 +93,62 @@ func main() {
 		log.Fatalf("failed to connect to redis at %s: %v", redisAddr, err)
 	}
 
-	sub := rdb.Subscribe(ctx, channel)
-	defer func() { _ = sub.Close() }()
+	sub := &subscription{sub: rdb.Subscribe(ctx, channel), channel: channel}
+	defer func() { _ = sub.sub.Close() }()
 
 	log.Printf("notification-service subscribed to redis channel=%s addr=%s", channel, redisAddr)
 
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
-
-	msgCh := sub.Channel()
-	for {
-		select {
-		case <-quit:
-			log.Println("notification-service shutting down")
+	adminOpts.Service, adminOpts.Version = serviceName, buildinfo.Version
+	adminOpts.KeyName = "NOTIFICATION_ADMIN_API_KEY"
+	adminOpts.Status = sub.status
+	adminOpts.Reload = func(ctx context.Context) (map[string]any, error) {
+		if err := sub.setChannel(ctx, getenv("PAGI_NOTIFICATIONS_CHANNEL", "pagi_notifications")); err != nil {
+			return nil, err
+		}
+		cfg := sub.status(ctx)
+		log.Printf("notification-service config reloaded channel=%s", cfg["channel"])
+		return cfg, nil
+	}
+	adminOpts.OnDrain = func(draining bool) {
+		if err := sub.setDraining(ctx, draining); err != nil {
+			log.Printf("notification-service drain=%t failed: %v", draining, err)
 			return
-		case msg, ok := <-msgCh:
-			if !ok {
-				log.Println("redis subscription channel closed")
-				return
+		}
+		log.Printf("notification-service draining=%t", draining)
+	}
+	ops := admin.New(adminOpts)
+
+	// The admin API (with GET /version) is the only HTTP surface, so it is
+	// opt-in.
+	if port := os.Getenv("NOTIFICATION_ADMIN_PORT"); port != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/admin/", ops.Handler())
+		mux.Handle("/version", buildinfo.Handler(serviceName, nil))
+		group.HTTPServer("admin_http", &http.Server{Addr: ":" + port, Handler: mux, ReadHeaderTimeout: 10 * time.Second})
+		log.Printf("notification-service admin API listening on :%s", port)
+	}
+
+	group.Go("subscriber", func(ctx context.Context) error {
+		msgCh := sub.sub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case msg, ok := <-msgCh:
+				if !ok {
+					return errors.New("redis subscription channel closed")
+				}
+				end := ops.Begin()
+				// Payload is JSON published by the Agent Planner.
+				log.Printf("notification: %s", msg.Payload)
+				end()
 			}
-			// Payload is JSON published by the Agent Planner.
-			log.Printf("notification: %s", msg.Payload)
 		}
+	})
+
+	if err := group.Wait(); err != nil {
+		log.Printf("notification-service stopped: %v", err)
+		os.Exit(1)
 	}
+	log.Printf("notification-service shutting down (%v)", group.Cause())
 }
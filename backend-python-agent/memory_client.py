@@ -1,13 +1,46 @@
 from __future__ import annotations
 
+import hashlib
+import hmac
 import json
 import os
+import time
 from datetime import datetime
 from typing import Any
 
 import httpx
 
 
+def _hmac_key() -> str:
+    path = os.environ.get("PAGI_HMAC_KEY_FILE", "").strip()
+    if path:
+        with open(path, encoding="utf-8") as f:
+            return f.read().strip()
+    return os.environ.get("PAGI_HMAC_KEY", "")
+
+
+async def _sign_request(request: httpx.Request) -> None:
+    """Sign the request when PAGI_HMAC_KEY is set.
+
+    Same scheme as backend-go-model-gateway/pkg/httpsign, which the Memory
+    service verifies.
+    """
+
+    key = _hmac_key()
+    if not key:
+        return
+    ts = str(int(time.time()))
+    msg = "\n".join([
+        "v1",
+        ts,
+        request.method.upper(),
+        request.url.raw_path.decode("ascii"),
+        hashlib.sha256(request.content).hexdigest(),
+    ])
+    request.headers["X-Pagi-Timestamp"] = ts
+    request.headers["X-Pagi-Signature"] = "v1=" + hmac.new(key.encode(), msg.encode(), hashlib.sha256).hexdigest()
+
+
 async def get_session_history(session_id: str) -> list[dict[str, Any]]:
     """Fetch session history from the Memory service.
 
@@ -19,7 +52,7 @@ async def get_session_history(session_id: str) -> list[dict[str, Any]]:
     timeout_seconds = float(os.environ.get("REQUEST_TIMEOUT_SECONDS", 2))
 
     try:
-        async with httpx.AsyncClient(timeout=timeout_seconds) as client:
+        async with httpx.AsyncClient(timeout=timeout_seconds, event_hooks={"request": [_sign_request]}) as client:
             resp = await client.get(
                 f"{memory_url.rstrip('/')}/memory/latest",
                 params={"session_id": session_id},
@@ -57,7 +90,7 @@ async def store_session_history(
     }
 
     try:
-        async with httpx.AsyncClient(timeout=timeout_seconds) as client:
+        async with httpx.AsyncClient(timeout=timeout_seconds, event_hooks={"request": [_sign_request]}) as client:
             resp = await client.post(
                 f"{memory_url.rstrip('/')}/memory/store",
                 json=payload,
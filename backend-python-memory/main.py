@@ -2,11 +2,14 @@ from __future__ import annotations
 
 import json
 import os
+import threading
+from collections import OrderedDict
 from datetime import datetime
 from typing import Any
 
 import uvicorn
-from fastapi import FastAPI, HTTPException
+from fastapi import FastAPI, HTTPException, Request
+from fastapi.responses import JSONResponse
 from pydantic import BaseModel
 
 from opentelemetry import trace
@@ -18,10 +21,13 @@ from opentelemetry.sdk.trace.export import BatchSpanProcessor
 
 from memory_service import (
     check_health,
+    delete_session,
     get_mock_session_history,
+    record_retrieval_feedback,
     start_grpc_server_background,
     store_mind_playbook,
 )
+from request_signing import SignatureError, keys_from_env, max_skew_from_env, verify
 
 
 app = FastAPI(title="Python Memory Service")
@@ -49,6 +55,93 @@ async def _startup():
 
 PORT = int(os.environ.get("MEMORY_PORT", 8003))
 
+# Delta IDs of recent writes, so a write the planner retried after a timeout
+# is not applied twice. Per process and bounded to the most recent
+# MEMORY_DEDUPE_SIZE writes.
+DEDUPE_SIZE = int(os.environ.get("MEMORY_DEDUPE_SIZE", "10000"))
+_recent_deltas: OrderedDict[str, None] = OrderedDict()
+_recent_deltas_lock = threading.Lock()
+
+
+def seen_delta(delta_id: str | None) -> bool:
+    """Record a write's delta ID; True when it was already applied.
+
+    The ID is recorded before the write so a concurrent retry is dropped;
+    a write that fails must call forget_delta so its retry goes through.
+    """
+
+    if not delta_id or DEDUPE_SIZE <= 0:
+        return False
+    with _recent_deltas_lock:
+        if delta_id in _recent_deltas:
+            _recent_deltas.move_to_end(delta_id)
+            return True
+        _recent_deltas[delta_id] = None
+        while len(_recent_deltas) > DEDUPE_SIZE:
+            _recent_deltas.popitem(last=False)
+    return False
+
+
+def forget_delta(delta_id: str | None) -> None:
+    """Drop the delta ID of a write that failed."""
+
+    if not delta_id:
+        return
+    with _recent_deltas_lock:
+        _recent_deltas.pop(delta_id, None)
+
+
+def duplicate_response(method: str, session_id: str, delta_id: str) -> JSONResponse:
+    print(json.dumps({
+        "timestamp": datetime.utcnow().isoformat() + "Z",
+        "level": "info",
+        "service": SERVICE_NAME,
+        "method": method,
+        "session_id": session_id,
+        "delta_id": delta_id,
+        "message": "dropped duplicate write",
+    }))
+    return JSONResponse(status_code=409, content={"status": "duplicate", "session_id": session_id, "delta_id": delta_id})
+
+
+@app.middleware("http")
+async def verify_request_signature(request: Request, call_next):
+    """Reject unsigned /memory/* writes when PAGI_HMAC_KEY is set.
+
+    Stops anyone who can reach the port on a flat network from spoofing
+    memory writes (see request_signing). Reads stay open: the BFF dashboard
+    and the frontend call GET /memory/latest without a key.
+    """
+
+    keys = keys_from_env()
+    if not keys or not request.url.path.startswith("/memory/") or request.method in ("GET", "HEAD"):
+        return await call_next(request)
+
+    request_uri = request.scope.get("raw_path", request.url.path.encode()).decode("latin-1")
+    if request.url.query:
+        request_uri += "?" + request.url.query
+    try:
+        verify(
+            request.method,
+            request_uri,
+            {k.lower(): v for k, v in request.headers.items()},
+            await request.body(),
+            keys,
+            max_skew_from_env(),
+        )
+    except SignatureError as exc:
+        print(json.dumps({
+            "timestamp": datetime.utcnow().isoformat() + "Z",
+            "level": "warn",
+            "service": SERVICE_NAME,
+            "method": f"{request.method} {request.url.path}",
+            "client": request.client.host if request.client else "",
+            "error": str(exc),
+            "message": "rejected request with an invalid HMAC signature",
+        }))
+        return JSONResponse(status_code=401, content={"error": str(exc)})
+    return await call_next(request)
+
 
 class StoreHistoryPayload(BaseModel):
     session_id: str
@@ -56,12 +149,28 @@ class StoreHistoryPayload(BaseModel):
     prompt: str
     llm_response: dict[str, Any]
     stored_at: str | None = None
+    delta_id: str | None = None
 
 
 class StorePlaybookPayload(BaseModel):
     session_id: str
     prompt: str
     history_sequence: list[dict[str, str]]
+    delta_id: str | None = None
+
+
+class FeedbackMatch(BaseModel):
+    id: str
+    knowledge_base: str
+    used: bool
+    cited: bool = False
+    overlap: float = 0.0
+
+
+class RetrievalFeedbackPayload(BaseModel):
+    session_id: str
+    prompt: str = ""
+    matches: list[FeedbackMatch]
 
 
 @app.get("/health")
@@ -83,19 +192,27 @@ def store_memory(payload: StoreHistoryPayload):
     """Accept and "store" session history.
 
     Persistence is simulated for now by printing a structured summary.
+    A delta_id already stored is answered 409.
     """
 
-    turns = len(payload.history) if isinstance(payload.history, list) else 0
-    log_entry = {
-        "timestamp": datetime.utcnow().isoformat() + "Z",
-        "level": "info",
-        "service": SERVICE_NAME,
-        "method": "POST /memory/store",
-        "session_id": payload.session_id,
-        "turns": turns,
-        "message": "received session history for persistence (simulated)",
-    }
-    print(json.dumps(log_entry))
+    if seen_delta(payload.delta_id):
+        return duplicate_response("POST /memory/store", payload.session_id, payload.delta_id)
+
+    try:
+        turns = len(payload.history) if isinstance(payload.history, list) else 0
+        log_entry = {
+            "timestamp": datetime.utcnow().isoformat() + "Z",
+            "level": "info",
+            "service": SERVICE_NAME,
+            "method": "POST /memory/store",
+            "session_id": payload.session_id,
+            "turns": turns,
+            "message": "received session history for persistence (simulated)",
+        }
+        print(json.dumps(log_entry))
+    except Exception:
+        forget_delta(payload.delta_id)
+        raise
 
 
     return {"status": "ok", "session_id": payload.session_id, "turns": turns}
@@ -106,17 +223,67 @@ def store_playbook(payload: StorePlaybookPayload):
     """Persist a successful multi-step tool sequence into Mind-KB.
 
     This is called by the Go Agent Planner when it detects successful completion
-    after one or more tool calls.
+    after one or more tool calls. A delta_id already stored is answered 409.
     """
 
-    playbook_id = store_mind_playbook(
-        session_id=payload.session_id,
-        prompt=payload.prompt,
-        history_sequence=payload.history_sequence,
-    )
+    if seen_delta(payload.delta_id):
+        return duplicate_response("POST /memory/playbook", payload.session_id, payload.delta_id)
+
+    try:
+        playbook_id = store_mind_playbook(
+            session_id=payload.session_id,
+            prompt=payload.prompt,
+            history_sequence=payload.history_sequence,
+        )
+    except Exception:
+        forget_delta(payload.delta_id)
+        raise
     return {"status": "ok", "playbook_id": playbook_id}
 
 
+@app.post("/memory/feedback")
+def store_retrieval_feedback(payload: RetrievalFeedbackPayload):
+    """Record which retrieved matches a successful agent run used.
+
+    Used documents gain relevance and unused ones lose it; rag_retrieve ranks
+    documents with it (MEMORY_FEEDBACK_WEIGHT).
+    """
+
+    updated = record_retrieval_feedback([m.model_dump() for m in payload.matches])
+    used = sum(1 for m in payload.matches if m.used)
+    print(json.dumps({
+        "timestamp": datetime.utcnow().isoformat() + "Z",
+        "level": "info",
+        "service": SERVICE_NAME,
+        "method": "POST /memory/feedback",
+        "session_id": payload.session_id,
+        "matches": len(payload.matches),
+        "used": used,
+    }))
+    return {"status": "ok", "updated": updated}
+
+
+@app.delete("/memory/session")
+def forget_session(session_id: str):
+    """Delete a session's history and the playbooks learned from it.
+
+    Called by the planner's DELETE /sessions/{id}/data, which signs a receipt
+    with the counts returned here.
+    """
+
+    messages, playbooks = delete_session(session_id)
+    print(json.dumps({
+        "timestamp": datetime.utcnow().isoformat() + "Z",
+        "level": "info",
+        "service": SERVICE_NAME,
+        "method": "DELETE /memory/session",
+        "session_id": session_id,
+        "messages": messages,
+        "playbooks": playbooks,
+    }))
+    return {"status": "ok", "session_id": session_id, "messages": messages, "playbooks": playbooks}
+
+
 if __name__ == "__main__":
     uvicorn.run("main:app", host="0.0.0.0", port=PORT, reload=False)
 
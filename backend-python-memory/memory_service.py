@@ -6,6 +6,7 @@ import json
 import os
 import sqlite3
 import sys
+from datetime import datetime, timezone
 from pathlib import Path
 from typing import Any
 
@@ -208,14 +209,18 @@ def rag_retrieve(query: str, knowledge_bases: list[str] | None = None, top_k: in
 	matches: list[dict[str, Any]] = []
 	for kb in kb_list:
 		collection = get_collection(kb)
-		res = collection.query(query_texts=[query], n_results=top_k)
+		# Over-fetch so retrieval feedback can promote a document from just
+		# below the cut.
+		n_results = top_k * 2 if _FEEDBACK_WEIGHT > 0 else top_k
+		res = collection.query(query_texts=[query], n_results=n_results)
 
 		ids = (res.get("ids") or [[]])[0]
 		docs = (res.get("documents") or [[]])[0]
 		dists = (res.get("distances") or [[]])[0]
 
+		kb_matches: list[dict[str, Any]] = []
 		for i in range(min(len(ids), len(docs), len(dists))):
-			matches.append(
+			kb_matches.append(
 				{
 					"id": ids[i],
 					"text": docs[i],
@@ -224,6 +229,7 @@ def rag_retrieve(query: str, knowledge_bases: list[str] | None = None, top_k: in
 					"source": "chroma",
 				}
 			)
+		matches.extend(_apply_feedback(kb, kb_matches)[:top_k])
 
 	return matches
 
@@ -509,3 +515,121 @@ def get_session_history(session_id: str) -> list[dict[str, Any]]:
 		)
 		conn.commit()
 		return []
+
+
+def delete_session(session_id: str) -> tuple[int, int]:
+	"""Delete a session's chat history and the Mind-KB playbooks it produced.
+
+	Returns (messages deleted, playbooks deleted). Used by the planner's
+	data-subject deletion (DELETE /sessions/{id}/data).
+	"""
+	with _open_session_db() as conn:
+		row = conn.execute(
+			"SELECT history_json FROM sessions WHERE session_id = ?",
+			(session_id,),
+		).fetchone()
+		messages = 0
+		if row is not None:
+			try:
+				parsed = json.loads(row["history_json"])
+				messages = len(parsed) if isinstance(parsed, list) else 0
+			except Exception:
+				pass
+		conn.execute("DELETE FROM sessions WHERE session_id = ?", (session_id,))
+		conn.commit()
+
+	mind_kb_collection = get_collection(MIND_KB_NAME)
+	found = mind_kb_collection.get(where={"source_session": session_id})
+	playbook_ids = found.get("ids") or []
+	if playbook_ids:
+		mind_kb_collection.delete(ids=playbook_ids)
+	return messages, len(playbook_ids)
+
+
+# --- Retrieval feedback: per-document relevance learned from agent runs ---
+
+# How far feedback moves a document's distance: a document every run used is
+# ranked as if FEEDBACK_WEIGHT closer, one no run used FEEDBACK_WEIGHT further.
+# 0 records feedback without using it.
+_FEEDBACK_WEIGHT = float(os.environ.get("MEMORY_FEEDBACK_WEIGHT", "0.1"))
+
+# Each report moves relevance this far towards 1 (used) or 0 (retrieved but
+# unused), so old feedback decays as new runs come in.
+_FEEDBACK_RATE = 0.2
+
+
+def _init_feedback_schema(conn: sqlite3.Connection) -> None:
+	conn.execute(
+		"""
+		CREATE TABLE IF NOT EXISTS retrieval_feedback (
+			knowledge_base TEXT NOT NULL,
+			doc_id TEXT NOT NULL,
+			relevance REAL NOT NULL,
+			used_count INTEGER NOT NULL,
+			unused_count INTEGER NOT NULL,
+			updated_at TEXT NOT NULL,
+			PRIMARY KEY (knowledge_base, doc_id)
+		);
+		"""
+	)
+	conn.commit()
+
+
+def _open_feedback_db() -> sqlite3.Connection:
+	conn = _open_session_db()
+	_init_feedback_schema(conn)
+	return conn
+
+
+def record_retrieval_feedback(matches: list[dict[str, Any]]) -> int:
+	"""Update document relevance from one run's feedback.
+
+	Each match is {"id", "knowledge_base", "used"}. Relevance starts neutral
+	(0.5) and moves towards 1 for used matches and towards 0 for unused ones.
+	Returns the number of documents updated.
+	"""
+	now = datetime.now(timezone.utc).isoformat()
+	updated = 0
+	with _open_feedback_db() as conn:
+		for m in matches:
+			doc_id, kb = m.get("id"), m.get("knowledge_base")
+			if not doc_id or not kb:
+				continue
+			used = bool(m.get("used"))
+			row = conn.execute(
+				"SELECT relevance FROM retrieval_feedback WHERE knowledge_base = ? AND doc_id = ?",
+				(kb, doc_id),
+			).fetchone()
+			relevance = row["relevance"] if row is not None else 0.5
+			relevance += _FEEDBACK_RATE * ((1.0 if used else 0.0) - relevance)
+			conn.execute(
+				"""
+				INSERT INTO retrieval_feedback (knowledge_base, doc_id, relevance, used_count, unused_count, updated_at)
+				VALUES (?, ?, ?, ?, ?, ?)
+				ON CONFLICT (knowledge_base, doc_id) DO UPDATE SET
+					relevance = excluded.relevance,
+					used_count = used_count + excluded.used_count,
+					unused_count = unused_count + excluded.unused_count,
+					updated_at = excluded.updated_at
+				""",
+				(kb, doc_id, relevance, int(used), int(not used), now),
+			)
+			updated += 1
+		conn.commit()
+	return updated
+
+
+def _apply_feedback(kb: str, matches: list[dict[str, Any]]) -> list[dict[str, Any]]:
+	"""Shift distances by learned relevance and re-sort, nearest first."""
+	if _FEEDBACK_WEIGHT <= 0 or not matches:
+		return matches
+	ids = [m["id"] for m in matches]
+	with _open_feedback_db() as conn:
+		rows = conn.execute(
+			f"SELECT doc_id, relevance FROM retrieval_feedback WHERE knowledge_base = ? AND doc_id IN ({','.join('?' * len(ids))})",
+			(kb, *ids),
+		).fetchall()
+	relevance = {r["doc_id"]: r["relevance"] for r in rows}
+	for m in matches:
+		m["distance"] -= _FEEDBACK_WEIGHT * (2 * relevance.get(m["id"], 0.5) - 1)
+	return sorted(matches, key=lambda m: m["distance"])
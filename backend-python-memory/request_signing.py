@@ -0,0 +1,81 @@
+"""HMAC request verification for the Memory Service HTTP API.
+
+Mirrors backend-go-model-gateway/pkg/httpsign: callers sign each request with
+the shared PAGI_HMAC_KEY, sending
+
+    X-Pagi-Timestamp: <unix seconds>
+    X-Pagi-Signature: v1=<hex HMAC-SHA256>
+
+over "v1\\n<timestamp>\\n<METHOD>\\n<request-uri>\\n<hex sha256(body)>".
+Requests more than PAGI_HMAC_MAX_SKEW_SECONDS (default 300) away from our
+clock are rejected. PAGI_HMAC_KEY_PREVIOUS is also accepted during a key
+rotation. main.py only verifies writes; reads stay open.
+"""
+
+from __future__ import annotations
+
+import hashlib
+import hmac
+import os
+import time
+
+TIMESTAMP_HEADER = "x-pagi-timestamp"
+SIGNATURE_HEADER = "x-pagi-signature"
+VERSION = "v1"
+
+
+class SignatureError(Exception):
+    pass
+
+
+def _secret(name: str) -> str:
+    path = os.environ.get(name + "_FILE", "").strip()
+    if path:
+        with open(path, encoding="utf-8") as f:
+            return f.read().strip()
+    return os.environ.get(name, "")
+
+
+def keys_from_env() -> list[bytes]:
+    """Return the accepted keys; empty when signing is not configured."""
+    return [k.encode() for k in (_secret("PAGI_HMAC_KEY"), _secret("PAGI_HMAC_KEY_PREVIOUS")) if k]
+
+
+def max_skew_from_env() -> int:
+    try:
+        return int(os.environ.get("PAGI_HMAC_MAX_SKEW_SECONDS", "300"))
+    except ValueError:
+        return 300
+
+
+def _mac(key: bytes, ts: str, method: str, request_uri: str, body: bytes) -> str:
+    msg = "\n".join([VERSION, ts, method.upper(), request_uri, hashlib.sha256(body).hexdigest()])
+    return hmac.new(key, msg.encode(), hashlib.sha256).hexdigest()
+
+
+def verify(
+    method: str,
+    request_uri: str,
+    headers: dict[str, str],
+    body: bytes,
+    keys: list[bytes],
+    max_skew: int,
+    now: float | None = None,
+) -> None:
+    """Raise SignatureError unless the request is signed with one of keys."""
+    ts = headers.get(TIMESTAMP_HEADER, "")
+    sig = headers.get(SIGNATURE_HEADER, "")
+    if not ts or not sig:
+        raise SignatureError("request is not signed")
+    try:
+        sent = int(ts)
+    except ValueError as exc:
+        raise SignatureError(f"bad timestamp {ts!r}") from exc
+    if abs((time.time() if now is None else now) - sent) > max_skew:
+        raise SignatureError("request timestamp outside the allowed window")
+    prefix = VERSION + "="
+    if not sig.startswith(prefix):
+        raise SignatureError("unsupported signature version")
+    got = sig[len(prefix):]
+    if not any(hmac.compare_digest(got, _mac(k, ts, method, request_uri, body)) for k in keys):
+        raise SignatureError("signature mismatch")
@@ -9,7 +9,9 @@ pub mod proto {
 }
 
 use proto::tool_service_server::{ToolService, ToolServiceServer};
-use proto::{ToolRequest, ToolResponse};
+use proto::{
+	ListToolsRequest, ListToolsResponse, ToolDefinition, ToolParameter, ToolRequest, ToolResponse,
+};
 
 #[derive(Debug, Default)]
 pub struct SandboxToolService;
@@ -49,6 +51,49 @@ impl ToolService for SandboxToolService {
 			stderr: result.stderr,
 		}))
 	}
+
+	async fn list_tools(
+		&self,
+		_request: Request<ListToolsRequest>,
+	) -> Result<Response<ListToolsResponse>, Status> {
+		Ok(Response::new(ListToolsResponse {
+			tools: tool_definitions(),
+		}))
+	}
+}
+
+/// The tools this sandbox executes, as advertised by ListTools. The gateway
+/// and planner build the model's `<available_tools>` from this list, so a
+/// tool added to `tool_executor::execute_tool` should be described here too.
+fn tool_definitions() -> Vec<ToolDefinition> {
+	let param = |kind: &str, description: &str| ToolParameter {
+		r#type: kind.to_string(),
+		description: description.to_string(),
+	};
+	vec![
+		ToolDefinition {
+			name: "web_search".to_string(),
+			description: "Use this tool to find up-to-date information or external knowledge."
+				.to_string(),
+			parameters: [("query".to_string(), param("string", "The search query."))]
+				.into_iter()
+				.collect(),
+		},
+		ToolDefinition {
+			name: "execute_code".to_string(),
+			description: "Run a short program in an isolated sandbox and return its output."
+				.to_string(),
+			parameters: [
+				(
+					"language".to_string(),
+					param("string", "One of python, go, rust or java."),
+				),
+				("code".to_string(), param("string", "The complete source code to run.")),
+			]
+			.into_iter()
+			.collect(),
+		},
+	]
 }
 
 pub fn tool_service_server() -> ToolServiceServer<SandboxToolService> {
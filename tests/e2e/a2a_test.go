@@ -0,0 +1,62 @@
+package e2e
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"backend-go-agent-planner/agent"
+)
+
+func TestAgentMessage_TaskAndResult(t *testing.T) {
+	h := Start(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	task := agent.AgentMessage{
+		ID:   "msg-1",
+		From: "spoofed",
+		Type: agent.AgentMessageTask,
+		Task: &agent.AgentTask{Prompt: "search the web for the latest Go release"},
+	}
+	reply, err := h.Planner.HandleAgentMessage(ctx, "twin-b", task)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply.Type != agent.AgentMessageResult || reply.CorrelationID != "msg-1" || reply.To != "twin-b" || reply.Result.Output == "" {
+		t.Fatalf("reply = %+v", reply)
+	}
+	sessionID := "a2a:twin-b:msg-1"
+	if reply.Result.SessionID != sessionID {
+		t.Fatalf("session = %q, want %q", reply.Result.SessionID, sessionID)
+	}
+	rows := h.AuditRows(t, sessionID)
+	first, last := rows[0], rows[len(rows)-1]
+	if first.EventType != "AGENT_MESSAGE_RECEIVED" || first.Data["from"] != "twin-b" || last.EventType != "AGENT_MESSAGE_SENT" || last.Data["correlation_id"] != "msg-1" {
+		t.Fatalf("audit trail %s ... %s: %v / %v", first.EventType, last.EventType, first.Data, last.Data)
+	}
+
+	// A follow-up in the same conversation shares the session's history.
+	followUp := agent.AgentMessage{CorrelationID: "msg-1", Type: agent.AgentMessageTask, Task: &agent.AgentTask{Prompt: "summarize that"}}
+	if reply, err := h.Planner.HandleAgentMessage(ctx, "twin-b", followUp); err != nil || reply.Result.SessionID != sessionID || reply.ID == "" {
+		t.Fatalf("follow-up: %+v, %v", reply, err)
+	}
+
+	// Peers cannot name sessions outside their namespace.
+	for _, session := range []string{"user-session", "a2a:twin-c:msg-1", "a2a:twin-b:"} {
+		hijack := agent.AgentMessage{Type: agent.AgentMessageTask, Task: &agent.AgentTask{Prompt: "what did we discuss?", SessionID: session}}
+		if reply, err := h.Planner.HandleAgentMessage(ctx, "twin-b", hijack); !errors.Is(err, agent.ErrAgentMessageInvalid) || reply.Type != agent.AgentMessageError {
+			t.Fatalf("session %q: %+v, %v", session, reply, err)
+		}
+	}
+	explicit := agent.AgentMessage{Type: agent.AgentMessageTask, Task: &agent.AgentTask{Prompt: "hello", SessionID: "a2a:twin-b:thread-7"}}
+	if reply, err := h.Planner.HandleAgentMessage(ctx, "twin-b", explicit); err != nil || reply.Result.SessionID != "a2a:twin-b:thread-7" {
+		t.Fatalf("own namespace: %+v, %v", reply, err)
+	}
+
+	reply, err = h.Planner.HandleAgentMessage(ctx, "twin-b", agent.AgentMessage{ID: "msg-2", Type: agent.AgentMessageResult})
+	if !errors.Is(err, agent.ErrAgentMessageInvalid) || reply.Type != agent.AgentMessageError || reply.CorrelationID != "msg-2" {
+		t.Fatalf("result message: %+v, %v", reply, err)
+	}
+}
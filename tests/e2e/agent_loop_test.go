@@ -0,0 +1,190 @@
+package e2e
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"backend-go-model-gateway/pkg/fakememory"
+	"backend-go-model-gateway/pkg/httpsign"
+	"backend-go-model-gateway/pkg/ragfilter"
+	"backend-go-model-gateway/pkg/secrets"
+
+	"github.com/go-redis/redis/v8"
+)
+
+func TestAgentLoop_MultiTurnToolLoop(t *testing.T) {
+	h := Start(t)
+	h.Memory.Seed("Domain-KB", fakememory.Document{ID: "domain-1", Text: "Go releases ship every six months"})
+	h.Memory.SeedHistory("e2e-session", fakememory.Message{Role: "user", Content: "hello from a previous run"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	rdb := redis.NewClient(&redis.Options{Addr: h.Redis.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+	sub := rdb.Subscribe(ctx, "pagi_notifications")
+	t.Cleanup(func() { _ = sub.Close() })
+	if _, err := sub.Receive(ctx); err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+
+	result, err := h.Planner.AgentLoop(ctx, "search the web for the latest Go release", "e2e-session", nil, nil)
+	if err != nil {
+		t.Fatalf("AgentLoop: %v", err)
+	}
+	if !strings.Contains(result, "tool result") {
+		t.Fatalf("expected final mock answer after tool result, got %q", result)
+	}
+
+	// Gateway: two turns (tool call, then final plan), with RAG + history in the prompt.
+	planReqs := h.Gateway.Requests()
+	if len(planReqs) != 2 {
+		t.Fatalf("expected 2 GetPlan calls, got %d", len(planReqs))
+	}
+	if !strings.Contains(planReqs[0].GetPrompt(), "domain-1") || !strings.Contains(planReqs[0].GetPrompt(), "hello from a previous run") {
+		t.Fatalf("first planner prompt is missing RAG/history context:\n%s", planReqs[0].GetPrompt())
+	}
+	// The tool turn follows the prompt as messages: the plan, then its result.
+	turns := planReqs[1].GetMessages()
+	if len(turns) != 2 || turns[0].GetRole() != "assistant" || turns[0].GetContent() != h.Gateway.Plans()[0] ||
+		turns[1].GetRole() != "user" || !strings.Contains(turns[1].GetContent(), "<tool_result>") {
+		t.Fatalf("second planner request is missing the tool turn: %v", turns)
+	}
+	if strings.Contains(planReqs[1].GetPrompt(), "<tool_result>") {
+		t.Fatalf("tool result was flattened into the prompt:\n%s", planReqs[1].GetPrompt())
+	}
+
+	// Sandbox: exactly one tool execution.
+	calls := h.Sandbox.Calls()
+	if len(calls) != 1 || calls[0].GetToolName() != "web_search" {
+		t.Fatalf("unexpected tool calls: %v", calls)
+	}
+
+	// Audit trail.
+	var events []string
+	for _, row := range h.AuditRows(t, "e2e-session") {
+		events = append(events, row.EventType)
+	}
+	want := []string{"PLAN_START", "PLAN_MODEL_RESPONSE", "TOOL_CALL", "TOOL_RESULT", "PLAN_MODEL_RESPONSE", "PLAN_END", "PLAYBOOK_STORED", "RAG_FEEDBACK"}
+	if strings.Join(events, ",") != strings.Join(want, ",") {
+		t.Fatalf("audit events\n got: %v\nwant: %v", events, want)
+	}
+
+	// Memory writes: tool plan + tool output + final answer, and one playbook.
+	if stores := h.Memory.Stores(); len(stores) != 3 {
+		t.Fatalf("expected 3 /memory/store calls, got %d", len(stores))
+	}
+	playbooks := h.Memory.Playbooks()
+	if len(playbooks) != 1 || len(playbooks[0].HistorySequence) != 4 {
+		t.Fatalf("expected one 4-step playbook, got %#v", playbooks)
+	}
+	// Retrieval feedback covers the seeded match.
+	feedback := h.Memory.Feedback()
+	if len(feedback) != 1 || feedback[0].SessionID != "e2e-session" || len(feedback[0].Matches) == 0 || feedback[0].Matches[0].ID != "domain-1" {
+		t.Fatalf("expected retrieval feedback for domain-1, got %#v", feedback)
+	}
+
+	// Notifications: STARTED, result, COMPLETED.
+	var statuses []string
+	sawResult := false
+	for i := 0; i < 3; i++ {
+		msg, err := sub.ReceiveMessage(ctx)
+		if err != nil {
+			t.Fatalf("receive notification %d: %v", i, err)
+		}
+		var payload map[string]any
+		if err := json.Unmarshal([]byte(msg.Payload), &payload); err != nil {
+			t.Fatalf("decode notification: %v", err)
+		}
+		if payload["session_id"] != "e2e-session" {
+			t.Fatalf("unexpected notification session: %v", payload)
+		}
+		if s, ok := payload["status"].(string); ok {
+			statuses = append(statuses, s)
+		}
+		if _, ok := payload["result"]; ok {
+			sawResult = true
+		}
+	}
+	if strings.Join(statuses, ",") != "STARTED,COMPLETED" || !sawResult {
+		t.Fatalf("unexpected notifications: statuses=%v result=%v", statuses, sawResult)
+	}
+}
+
+func TestAgentLoop_RAGFilterScopesRetrieval(t *testing.T) {
+	h := Start(t)
+	now := time.Now()
+	h.Memory.Seed("Body-KB",
+		fakememory.Document{ID: "body-recent", Text: "Sleep log: eight hours", Tags: []string{"health"}, CreatedAt: now.AddDate(0, 0, -3)},
+		fakememory.Document{ID: "body-old", Text: "Sleep log: six hours", Tags: []string{"health"}, CreatedAt: now.AddDate(0, 0, -90)},
+		fakememory.Document{ID: "body-untagged", Text: "Sleep log: seven hours", CreatedAt: now.AddDate(0, 0, -1)},
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	filter := &ragfilter.Filter{Tags: []string{"health"}, WithinDays: 30}
+	if _, err := h.Planner.AgentLoop(ctx, "summarize my sleep log", "e2e-filter", nil, filter); err != nil {
+		t.Fatalf("AgentLoop: %v", err)
+	}
+
+	ragReqs := h.Memory.RAGRequests()
+	if len(ragReqs) == 0 {
+		t.Fatal("expected a GetRAGContext call")
+	}
+	got := ragReqs[0].GetFilter()
+	if strings.Join(got.GetTags(), ",") != "health" || got.GetCreatedAfterUnix() == 0 {
+		t.Fatalf("memory service got filter %v, want tags=[health] and a resolved created_after", got)
+	}
+	if gw := h.Gateway.Requests()[0].GetRagFilter(); gw.GetCreatedAfterUnix() != got.GetCreatedAfterUnix() {
+		t.Fatalf("gateway got filter %v, want %v", gw, got)
+	}
+
+	prompt := h.Gateway.Requests()[0].GetPrompt()
+	if !strings.Contains(prompt, "body-recent") || strings.Contains(prompt, "body-old") || strings.Contains(prompt, "body-untagged") {
+		t.Fatalf("planner prompt should only carry the filtered document:\n%s", prompt)
+	}
+}
+
+func TestAgentLoop_SignsMemoryWrites(t *testing.T) {
+	h := Start(t)
+	t.Setenv("PAGI_HMAC_KEY", "e2e-key")
+	h.Memory.Verifier = &httpsign.Verifier{}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if _, err := h.Planner.AgentLoop(ctx, "hello", "e2e-signed", nil, nil); err != nil {
+		t.Fatalf("AgentLoop: %v", err)
+	}
+	if len(h.Memory.Stores()) != 1 {
+		t.Fatalf("stores = %d, want the signed session delta accepted", len(h.Memory.Stores()))
+	}
+
+	// Reads stay open for unsigned callers such as the BFF dashboard.
+	resp, err := http.Get(h.Memory.HTTPURL + "/memory/latest?session_id=e2e-signed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unsigned GET /memory/latest = %d, want 200", resp.StatusCode)
+	}
+
+	// A verifier with another key rejects the planner's writes.
+	h.Memory.Verifier = &httpsign.Verifier{Store: secrets.New(secrets.Options{Env: func(k string) string {
+		if k == httpsign.KeyName {
+			return "other-key"
+		}
+		return ""
+	}})}
+	if _, err := h.Planner.AgentLoop(ctx, "hello again", "e2e-signed", nil, nil); err != nil {
+		t.Fatalf("AgentLoop: %v", err)
+	}
+	if len(h.Memory.Stores()) != 1 {
+		t.Fatalf("stores = %d, want the mis-signed write rejected", len(h.Memory.Stores()))
+	}
+}
@@ -0,0 +1,66 @@
+package e2e
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+func TestAgentLoop_RepeatedToolCallIsAnomalous(t *testing.T) {
+	h := Start(t)
+	t.Setenv("AGENT_ANOMALY_DETECTION", "on")
+	t.Setenv("AGENT_ANOMALY_REPEAT_LIMIT", "2")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if _, err := h.Planner.ReloadConfig(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	rdb := redis.NewClient(&redis.Options{Addr: h.Redis.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+	sub := rdb.Subscribe(ctx, "pagi_notifications")
+	t.Cleanup(func() { _ = sub.Close() })
+	if _, err := sub.Receive(ctx); err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+
+	// The model asks for the same search twice.
+	h.Gateway.Cassette = []string{
+		`{"tool":{"name":"web_search","args":{"query":"lisbon weather"}}}`,
+		`{"tool":{"name":"web_search","args":{"query":"lisbon weather"}}}`,
+		`{"steps":["Pack an umbrella"]}`,
+	}
+	if _, err := h.Planner.AgentLoop(ctx, "weather in lisbon", "anomaly-1", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var flagged []any
+	for _, r := range h.AuditRows(t, "anomaly-1") {
+		if r.EventType == "ANOMALY" {
+			flagged, _ = r.Data["anomalies"].([]any)
+		}
+	}
+	if len(flagged) != 1 || flagged[0].(map[string]any)["kind"] != "repeated_tool_call" {
+		t.Fatalf("ANOMALY step = %v", flagged)
+	}
+
+	for {
+		msg, err := sub.ReceiveMessage(ctx)
+		if err != nil {
+			t.Fatalf("no ANOMALY notification: %v", err)
+		}
+		var payload map[string]any
+		if err := json.Unmarshal([]byte(msg.Payload), &payload); err != nil {
+			t.Fatalf("decode notification: %v", err)
+		}
+		if payload["status"] == "ANOMALY" {
+			if payload["level"] != "WARN" || payload["session_id"] != "anomaly-1" {
+				t.Fatalf("notification = %v", payload)
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package e2e
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"backend-go-model-gateway/pkg/fakememory"
+)
+
+func TestAgentLoop_EvaluatesFinalAnswers(t *testing.T) {
+	for _, mode := range []string{"heuristic", "llm"} {
+		t.Run(mode, func(t *testing.T) {
+			h := Start(t)
+			h.Memory.Seed("Body-KB", fakememory.Document{ID: "cal-1", Text: "Dentist appointment Tuesday morning"})
+			t.Setenv("AGENT_EVALUATION", mode)
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			if _, err := h.Planner.ReloadConfig(ctx); err != nil {
+				t.Fatal(err)
+			}
+			h.Gateway.Cassette = []string{`{"steps":["Your dentist appointment is Tuesday morning."]}`}
+			if _, err := h.Planner.AgentLoop(ctx, "When is my dentist appointment?", "eval-1", nil, nil); err != nil {
+				t.Fatal(err)
+			}
+
+			// Evaluation runs after AgentLoop returns.
+			var eval *AuditRow
+			for deadline := time.Now().Add(5 * time.Second); eval == nil && time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+				for _, row := range h.AuditRows(t, "eval-1") {
+					if row.EventType == "EVALUATION" {
+						eval = &row
+					}
+				}
+			}
+			if eval == nil {
+				t.Fatal("no EVALUATION audit step")
+			}
+			if eval.Data["mode"] != mode || eval.Data["score"] != 1.0 || (mode == "llm") != (eval.Data["model"] == "mock") {
+				t.Fatalf("EVALUATION = %v", eval.Data)
+			}
+			status := h.Planner.AdminStatus(ctx)["evaluation"].(map[string]any)
+			if status["evaluated"] != 1 || status["recent_mean_score"] != 1.0 {
+				t.Fatalf("admin status = %v", status)
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package e2e
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Golden transcripts pin the observable behaviour of whole agent runs: every
+// prompt the planner sends, every plan it gets back, the tool calls, the audit
+// trail and the final answer. A scenario is recorded once against the mock
+// provider (go test -run Golden -update) and then replayed with the recorded
+// plans served as a cassette, so a change to prompt templates, RAG formatting
+// or plan normalization shows up as a diff against testdata/golden.
+
+// Scenario is one recorded agent run.
+type Scenario struct {
+	Name      string
+	Prompt    string
+	SessionID string
+	// Seed prepares the fakes (RAG documents, history) before the run.
+	Seed func(h *Harness)
+}
+
+// Transcript is the golden-file form of a run. Prompts are split into lines so
+// diffs point at the line that changed.
+type Transcript struct {
+	Prompt      string     `json:"prompt"`
+	SessionID   string     `json:"session_id"`
+	Turns       []Turn     `json:"turns"`
+	ToolCalls   []ToolCall `json:"tool_calls"`
+	AuditEvents []string   `json:"audit_events"`
+	Result      string     `json:"result"`
+	Error       string     `json:"error,omitempty"`
+}
+
+// Turn is one GetPlan round trip.
+type Turn struct {
+	Prompt   []string      `json:"prompt"`
+	Messages []TurnMessage `json:"messages,omitempty"`
+	Plan     string        `json:"plan"`
+}
+
+// TurnMessage is one of the earlier turns a GetPlan sent after its prompt.
+type TurnMessage struct {
+	Role    string   `json:"role"`
+	Content []string `json:"content"`
+}
+
+// ToolCall is one sandbox execution.
+type ToolCall struct {
+	Name string          `json:"name"`
+	Args json.RawMessage `json:"args"`
+}
+
+// GoldenPath is where a scenario's transcript lives.
+func GoldenPath(name string) string {
+	return filepath.Join("testdata", "golden", name+".json")
+}
+
+// RunGolden runs sc and compares the transcript with its golden file. With
+// update it runs against the mock provider and rewrites the file instead.
+func RunGolden(t *testing.T, sc Scenario, update bool) {
+	t.Helper()
+	path := GoldenPath(sc.Name)
+
+	var golden Transcript
+	if !update {
+		b, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			t.Fatalf("%s is missing; record it with: go test -run Golden -update", path)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(b, &golden); err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+	}
+
+	h := Start(t)
+	if sc.Seed != nil {
+		sc.Seed(h)
+	}
+	if !update {
+		h.Gateway.Cassette = make([]string, 0, len(golden.Turns))
+		for _, turn := range golden.Turns {
+			h.Gateway.Cassette = append(h.Gateway.Cassette, turn.Plan)
+		}
+	}
+
+	got := h.record(t, sc)
+	gotJSON := marshalTranscript(t, got)
+	if update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, gotJSON, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	if diff := firstDiff(marshalTranscript(t, golden), gotJSON); diff != "" {
+		t.Fatalf("%s drifted from its golden transcript (re-record with -update if intended):\n%s", sc.Name, diff)
+	}
+}
+
+func (h *Harness) record(t *testing.T, sc Scenario) Transcript {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	result, err := h.Planner.AgentLoop(ctx, sc.Prompt, sc.SessionID, nil, nil)
+
+	tr := Transcript{Prompt: sc.Prompt, SessionID: sc.SessionID, Result: result, ToolCalls: []ToolCall{}, AuditEvents: []string{}}
+	if err != nil {
+		tr.Error = err.Error()
+	}
+	plans := h.Gateway.Plans()
+	for i, req := range h.Gateway.Requests() {
+		turn := Turn{Prompt: strings.Split(req.GetPrompt(), "\n")}
+		for _, m := range req.GetMessages() {
+			turn.Messages = append(turn.Messages, TurnMessage{Role: m.GetRole(), Content: strings.Split(m.GetContent(), "\n")})
+		}
+		if i < len(plans) {
+			turn.Plan = plans[i]
+		}
+		tr.Turns = append(tr.Turns, turn)
+	}
+	for _, call := range h.Sandbox.Calls() {
+		tr.ToolCalls = append(tr.ToolCalls, ToolCall{Name: call.GetToolName(), Args: json.RawMessage(call.GetArgsJson())})
+	}
+	for _, row := range h.AuditRows(t, sc.SessionID) {
+		tr.AuditEvents = append(tr.AuditEvents, row.EventType)
+	}
+	return tr
+}
+
+func marshalTranscript(t *testing.T, tr Transcript) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false) // keep <tool_result> and friends readable
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(tr); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+// firstDiff reports the first differing line of two transcripts with a few
+// lines of context, or "" when they match.
+func firstDiff(want, got []byte) string {
+	if string(want) == string(got) {
+		return ""
+	}
+	w, g := strings.Split(string(want), "\n"), strings.Split(string(got), "\n")
+	i := 0
+	for i < len(w) && i < len(g) && w[i] == g[i] {
+		i++
+	}
+	var b strings.Builder
+	for j := max(0, i-3); j < i; j++ {
+		fmt.Fprintf(&b, "  %s\n", w[j])
+	}
+	if i < len(w) {
+		fmt.Fprintf(&b, "- %s\n", w[i])
+	}
+	if i < len(g) {
+		fmt.Fprintf(&b, "+ %s\n", g[i])
+	}
+	fmt.Fprintf(&b, "(line %d; golden has %d lines, run has %d)", i+1, len(w), len(g))
+	return b.String()
+}
@@ -0,0 +1,52 @@
+package e2e
+
+import (
+	"flag"
+	"strings"
+	"testing"
+
+	"backend-go-model-gateway/pkg/fakememory"
+)
+
+var update = flag.Bool("update", false, "re-record golden transcripts in testdata/golden")
+
+var goldenScenarios = []Scenario{
+	{
+		Name:      "tool_call",
+		Prompt:    "search the web for the latest Go release",
+		SessionID: "golden-tool",
+		Seed: func(h *Harness) {
+			h.Memory.Seed("Domain-KB", fakememory.Document{ID: "domain-1", Text: "Go releases ship every six months"})
+			h.Memory.SeedHistory("golden-tool", fakememory.Message{Role: "user", Content: "hello from a previous run"})
+		},
+	},
+	{
+		Name:      "direct_plan",
+		Prompt:    "plan my week around two deep-work blocks",
+		SessionID: "golden-plan",
+		Seed: func(h *Harness) {
+			h.Memory.Seed("Soul-KB", fakememory.Document{ID: "soul-1", Text: "Values focus over busyness"})
+		},
+	},
+}
+
+func TestGolden(t *testing.T) {
+	for _, sc := range goldenScenarios {
+		t.Run(sc.Name, func(t *testing.T) { RunGolden(t, sc, *update) })
+	}
+}
+
+func TestGolden_FlagsDrift(t *testing.T) {
+	if *update {
+		t.Skip("recording")
+	}
+	golden := marshalTranscript(t, Transcript{Prompt: "p", Turns: []Turn{{Prompt: []string{"System:", "Answer in JSON."}}}})
+	drifted := marshalTranscript(t, Transcript{Prompt: "p", Turns: []Turn{{Prompt: []string{"System:", "Answer in strict JSON."}}}})
+	diff := firstDiff(golden, drifted)
+	if !strings.Contains(diff, "-         \"Answer in JSON.\"") || !strings.Contains(diff, "+         \"Answer in strict JSON.\"") {
+		t.Fatalf("diff = %q", diff)
+	}
+	if firstDiff(golden, golden) != "" {
+		t.Fatal("identical transcripts should not differ")
+	}
+}
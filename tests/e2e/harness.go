@@ -0,0 +1,362 @@
+// Package e2e boots the Go agent stack in-process with hermetic fakes:
+//
+//   - Model Gateway gRPC server backed by the mock provider
+//   - Agent Planner (real agent.Planner)
+//   - fake Memory Service (gRPC RAG + HTTP history/playbooks)
+//   - fake Rust sandbox ToolService
+//   - miniredis for notifications
+//
+// No Docker, Python, Rust or API keys are required.
+package e2e
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"backend-go-agent-planner/agent"
+	"backend-go-model-gateway/pkg/fakememory"
+	"backend-go-model-gateway/pkg/mockprovider"
+	pb "backend-go-model-gateway/proto/proto"
+
+	"github.com/alicebob/miniredis/v2"
+	_ "github.com/mattn/go-sqlite3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Harness holds the running stack for a single test.
+type Harness struct {
+	Memory      *fakememory.Server
+	Sandbox     *FakeSandbox
+	Gateway     *MockGateway
+	Redis       *miniredis.Miniredis
+	Planner     *agent.Planner
+	AuditDBPath string
+}
+
+// Start boots every component on ephemeral ports and registers cleanup.
+func Start(t *testing.T) *Harness {
+	t.Helper()
+
+	// Make sure ambient mTLS / chaos settings never leak into the hermetic run.
+	for _, key := range []string{"TLS_CLIENT_CERT_PATH", "TLS_CLIENT_KEY_PATH", "TLS_CA_CERT_PATH", "PAGI_CHAOS", "PAGI_FLAGS_FILE", "PAGI_HMAC_KEY", "PAGI_EGRESS_ALLOW", "TLS_SOURCE"} {
+		t.Setenv(key, "")
+	}
+
+	h := &Harness{
+		Memory:      fakememory.StartT(t),
+		Sandbox:     &FakeSandbox{},
+		Gateway:     &MockGateway{},
+		Redis:       miniredis.RunT(t),
+		AuditDBPath: filepath.Join(t.TempDir(), "audit.db"),
+	}
+
+	gatewayAddr := serveGRPC(t, func(s *grpc.Server) { pb.RegisterModelGatewayServer(s, h.Gateway) })
+	sandboxAddr := serveGRPC(t, func(s *grpc.Server) { pb.RegisterToolServiceServer(s, h.Sandbox) })
+
+	cfg := agent.Config{
+		ModelGatewayAddr:    gatewayAddr,
+		MemoryServiceAddr:   h.Memory.GRPCAddr,
+		MemoryServiceHTTP:   h.Memory.HTTPURL,
+		RustSandboxGRPCAddr: sandboxAddr,
+		AuditDBPath:         h.AuditDBPath,
+		RedisAddr:           h.Redis.Addr(),
+		MaxTurns:            3,
+		TopK:                2,
+		KBs:                 []string{"Mind-KB", "Domain-KB", "Body-KB", "Soul-KB"},
+		RAGFeedback:         true,
+		// The defaults ConfigFromEnv would apply.
+		ScratchpadTTL:        time.Hour,
+		ScratchpadMaxEntries: 20,
+		ScratchpadReuseTools: []string{"web_search"},
+
+		ToolBudgetTools:         []string{"web_search", "http_fetch"},
+		ToolBudgetPerSession:    20,
+		ToolBudgetSessionWindow: 24 * time.Hour,
+		ToolBudgetPerHour:       500,
+		ToolOutputMaxBytes:      8192,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	planner, err := agent.NewPlanner(ctx, cfg)
+	if err != nil {
+		t.Fatalf("NewPlanner: %v", err)
+	}
+	t.Cleanup(planner.Close)
+	h.Planner = planner
+
+	return h
+}
+
+func serveGRPC(t *testing.T, register func(*grpc.Server)) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	s := grpc.NewServer()
+	register(s)
+	go func() { _ = s.Serve(lis) }()
+	t.Cleanup(s.Stop)
+	return lis.Addr().String()
+}
+
+// AuditRow is a single audit_log row.
+type AuditRow struct {
+	TraceID   string
+	SessionID string
+	Principal string
+	EventType string
+	Data      map[string]any
+}
+
+// AuditRows reads every audit_log row for a session in insertion order.
+func (h *Harness) AuditRows(t *testing.T, sessionID string) []AuditRow {
+	t.Helper()
+	db, err := sql.Open("sqlite3", h.AuditDBPath)
+	if err != nil {
+		t.Fatalf("open audit db: %v", err)
+	}
+	defer db.Close()
+
+	rows, err := db.Query(`SELECT trace_id, session_id, principal, event_type, data FROM audit_log WHERE session_id = ? ORDER BY id`, sessionID)
+	if err != nil {
+		t.Fatalf("query audit_log: %v", err)
+	}
+	defer rows.Close()
+
+	var out []AuditRow
+	for rows.Next() {
+		var r AuditRow
+		var traceID, principal, data sql.NullString
+		if err := rows.Scan(&traceID, &r.SessionID, &principal, &r.EventType, &data); err != nil {
+			t.Fatalf("scan audit_log: %v", err)
+		}
+		r.TraceID, r.Principal = traceID.String, principal.String
+		if data.String != "" {
+			_ = json.Unmarshal([]byte(data.String), &r.Data)
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// MockGateway is an in-process ModelGateway serving the gateway's mock
+// provider, or recorded plans when Cassette is set.
+type MockGateway struct {
+	pb.UnimplementedModelGatewayServer
+
+	// Cassette, when non-nil, is served in order instead of the mock
+	// provider's plans (see golden.go); a call past its end fails.
+	Cassette []string
+	// Capabilities, when non-nil, answers GetCapabilities; nil answers
+	// Unimplemented, like a gateway predating the RPC.
+	Capabilities *pb.CapabilitiesResponse
+	// StreamDelay is the pause between StreamPlan's deltas.
+	StreamDelay time.Duration
+	// Fixtures, when set, answer before the mock provider's heuristics, as
+	// MOCK_FIXTURES_DIR does in the gateway. A scripted error fails the
+	// call with Unavailable.
+	Fixtures *mockprovider.Fixtures
+
+	mu         sync.Mutex
+	requests   []*pb.PlanRequest
+	principals []string
+	plans      []string
+}
+
+func (g *MockGateway) GetPlan(ctx context.Context, in *pb.PlanRequest) (*pb.PlanResponse, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.requests = append(g.requests, in)
+	g.principals = append(g.principals, principal(ctx))
+	resp := mockprovider.BuildPlanResponse(in, time.Now())
+	if g.Fixtures != nil {
+		scripted, fixture, _, err := g.Fixtures.Plan(in, metadataValue(ctx, "x-session-id"), time.Now())
+		if err != nil {
+			return nil, status.Error(codes.Unavailable, err.Error())
+		}
+		if fixture != "" {
+			resp = scripted
+		}
+	}
+	if g.Cassette != nil {
+		n := len(g.plans)
+		if n >= len(g.Cassette) {
+			return nil, status.Errorf(codes.FailedPrecondition, "cassette exhausted after %d plans", n)
+		}
+		resp = &pb.PlanResponse{Plan: g.Cassette[n], ModelName: "cassette"}
+	}
+	// Like a gateway that has every system prompt version the planner asks for.
+	resp.PromptVersion = in.GetPromptVersion()
+	g.plans = append(g.plans, resp.GetPlan())
+	return resp, nil
+}
+
+// StreamPlan serves GetPlan's plan in deltas of streamDeltaBytes, then the
+// final response.
+func (g *MockGateway) StreamPlan(in *pb.PlanRequest, stream pb.ModelGateway_StreamPlanServer) error {
+	resp, err := g.GetPlan(stream.Context(), in)
+	if err != nil {
+		return err
+	}
+	plan := resp.GetPlan()
+	for i := 0; i < len(plan); i += streamDeltaBytes {
+		if i > 0 {
+			time.Sleep(g.StreamDelay)
+		}
+		if err := stream.Send(&pb.PlanChunk{Delta: plan[i:min(i+streamDeltaBytes, len(plan))]}); err != nil {
+			return err
+		}
+	}
+	return stream.Send(&pb.PlanChunk{Final: resp})
+}
+
+const streamDeltaBytes = 8
+
+// EvaluateAnswer answers like the gateway's mock provider.
+func (g *MockGateway) EvaluateAnswer(_ context.Context, in *pb.EvaluateRequest) (*pb.EvaluateResponse, error) {
+	return mockprovider.Evaluate(in), nil
+}
+
+func (g *MockGateway) GetCapabilities(ctx context.Context, in *pb.CapabilitiesRequest) (*pb.CapabilitiesResponse, error) {
+	if g.Capabilities == nil {
+		return g.UnimplementedModelGatewayServer.GetCapabilities(ctx, in)
+	}
+	return g.Capabilities, nil
+}
+
+// Plans returns every plan served so far.
+func (g *MockGateway) Plans() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]string(nil), g.plans...)
+}
+
+// Requests returns every PlanRequest received so far.
+func (g *MockGateway) Requests() []*pb.PlanRequest {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]*pb.PlanRequest(nil), g.requests...)
+}
+
+// LastTurn returns the last message of a PlanRequest: what answered the
+// plan before it, a tool result or error ("" on a first turn).
+func LastTurn(req *pb.PlanRequest) string {
+	turns := req.GetMessages()
+	if len(turns) == 0 {
+		return ""
+	}
+	return turns[len(turns)-1].GetContent()
+}
+
+// Principals returns the x-principal metadata of every PlanRequest received
+// so far ("" when unset).
+func (g *MockGateway) Principals() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]string(nil), g.principals...)
+}
+
+// principal returns an incoming call's x-principal metadata, or "".
+func principal(ctx context.Context) string {
+	return metadataValue(ctx, "x-principal")
+}
+
+func metadataValue(ctx context.Context, key string) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if v := md.Get(key); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// FakeSandbox is an in-process ToolService returning canned tool output.
+type FakeSandbox struct {
+	pb.UnimplementedToolServiceServer
+
+	mu         sync.Mutex
+	calls      []*pb.ToolRequest
+	principals []string
+	err        error
+	stdout     string
+	tools      []*pb.ToolDefinition
+}
+
+// SetTools makes ListTools return tools (nil: Unimplemented, like an older
+// sandbox).
+func (s *FakeSandbox) SetTools(tools []*pb.ToolDefinition) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tools = tools
+}
+
+func (s *FakeSandbox) ListTools(ctx context.Context, in *pb.ListToolsRequest) (*pb.ListToolsResponse, error) {
+	s.mu.Lock()
+	tools := s.tools
+	s.mu.Unlock()
+	if tools == nil {
+		return s.UnimplementedToolServiceServer.ListTools(ctx, in)
+	}
+	return &pb.ListToolsResponse{Tools: tools}, nil
+}
+
+// SetError makes every following ExecuteTool fail with err (nil restores it).
+func (s *FakeSandbox) SetError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+}
+
+// SetStdout makes every following ExecuteTool return stdout instead of the
+// canned search result ("" restores it).
+func (s *FakeSandbox) SetStdout(stdout string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stdout = stdout
+}
+
+func (s *FakeSandbox) ExecuteTool(ctx context.Context, in *pb.ToolRequest) (*pb.ToolResponse, error) {
+	s.mu.Lock()
+	s.calls = append(s.calls, in)
+	s.principals = append(s.principals, principal(ctx))
+	err, stdout := s.err, s.stdout
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if stdout != "" {
+		return &pb.ToolResponse{Status: "success", Stdout: stdout}, nil
+	}
+
+	out, _ := json.Marshal(map[string]any{
+		"tool":    in.GetToolName(),
+		"args":    json.RawMessage(in.GetArgsJson()),
+		"results": []map[string]string{{"title": "Fake result", "url": "https://example.invalid/result"}},
+	})
+	return &pb.ToolResponse{Status: "success", Stdout: string(out)}, nil
+}
+
+// Calls returns every ToolRequest received so far.
+func (s *FakeSandbox) Calls() []*pb.ToolRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*pb.ToolRequest(nil), s.calls...)
+}
+
+// Principals returns the x-principal metadata of every ToolRequest received
+// so far ("" when unset).
+func (s *FakeSandbox) Principals() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.principals...)
+}
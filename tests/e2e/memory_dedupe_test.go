@@ -0,0 +1,45 @@
+package e2e
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestAgentLoop_RetriedMemoryWritesAreDeduplicated(t *testing.T) {
+	h := Start(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// Every write lands, but the first two replies are lost: the planner
+	// retries them and the memory service drops the copies by delta ID.
+	h.Memory.LoseResponses(2)
+	h.Gateway.Cassette = []string{
+		`{"tool":{"name":"web_search","args":{"query":"lisbon weather"}}}`,
+		`{"steps":["Pack an umbrella"]}`,
+	}
+	if _, err := h.Planner.AgentLoop(ctx, "weather in lisbon", "dedupe-1", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := h.Memory.Duplicates(); n != 2 {
+		t.Fatalf("%d duplicate writes dropped, want 2", n)
+	}
+	stores := h.Memory.Stores()
+	if len(stores) != 3 {
+		t.Fatalf("%d /memory/store writes applied, want 3", len(stores))
+	}
+	if len(h.Memory.History("dedupe-1")) != 6 {
+		t.Fatalf("history has %d messages, want 6", len(h.Memory.History("dedupe-1")))
+	}
+	ids := map[string]bool{}
+	for _, s := range stores {
+		if s.DeltaID == "" || ids[s.DeltaID] {
+			t.Fatalf("delta IDs not unique per write: %q", s.DeltaID)
+		}
+		ids[s.DeltaID] = true
+	}
+	if p := h.Memory.Playbooks(); len(p) != 1 || p[0].DeltaID == "" {
+		t.Fatalf("playbooks = %#v", p)
+	}
+}
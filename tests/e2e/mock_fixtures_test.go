@@ -0,0 +1,57 @@
+package e2e
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"backend-go-model-gateway/pkg/mockprovider"
+)
+
+func startWithFixtures(t *testing.T) *Harness {
+	t.Helper()
+	fixtures, err := mockprovider.LoadFixtures("testdata/fixtures")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := Start(t)
+	h.Gateway.Fixtures = fixtures
+	return h
+}
+
+func TestMockFixtures_ScriptedToolFlow(t *testing.T) {
+	h := startWithFixtures(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	result, err := h.Planner.AgentLoop(ctx, "plan a trip to Lisbon", "fixture-session", nil, nil)
+	if err != nil {
+		t.Fatalf("AgentLoop: %v", err)
+	}
+	if !strings.Contains(result, "tram 28") {
+		t.Fatalf("want the scripted final plan, got %q", result)
+	}
+	// Two scripted tool calls, then the plan.
+	calls := h.Sandbox.Calls()
+	if len(calls) != 2 || !strings.Contains(calls[0].GetArgsJson(), "Lisbon weather") || !strings.Contains(calls[1].GetArgsJson(), "tram 28") {
+		t.Fatalf("tool calls = %v", calls)
+	}
+	if n := len(h.Gateway.Requests()); n != 3 {
+		t.Fatalf("GetPlan calls = %d, want 3", n)
+	}
+}
+
+func TestMockFixtures_ScriptedOutage(t *testing.T) {
+	h := startWithFixtures(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := h.Planner.AgentLoop(ctx, "survive a provider outage", "fixture-outage", nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "upstream unavailable") {
+		t.Fatalf("err = %v, want the scripted 503", err)
+	}
+	if len(h.Sandbox.Calls()) != 0 {
+		t.Fatalf("tool calls = %v", h.Sandbox.Calls())
+	}
+}
@@ -0,0 +1,39 @@
+package e2e
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	pb "backend-go-model-gateway/proto/proto"
+)
+
+func TestAgentLoop_MockToolsInMockMode(t *testing.T) {
+	h := Start(t)
+	h.Gateway.Capabilities = &pb.CapabilitiesResponse{Provider: "mock", Providers: []string{"mock"}, Mock: true}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if _, err := h.Planner.AgentLoop(ctx, "search the web for the latest Go release", "mock-tools-1", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if calls := h.Sandbox.Calls(); len(calls) != 0 {
+		t.Fatalf("sandbox called in mock mode: %v", calls)
+	}
+
+	reqs := h.Gateway.Requests()
+	if len(reqs) != 2 || !strings.Contains(reqs[1].GetPrompt(), "Canned result from the mock provider") {
+		t.Fatalf("tool result not fed back: %d requests, last prompt:\n%s", len(reqs), reqs[len(reqs)-1].GetPrompt())
+	}
+	var events []string
+	for _, row := range h.AuditRows(t, "mock-tools-1") {
+		events = append(events, row.EventType)
+	}
+	if joined := strings.Join(events, ","); !strings.Contains(joined, "TOOL_RESULT") || !strings.Contains(joined, "PLAYBOOK_STORED") {
+		t.Fatalf("audit events = %v", events)
+	}
+	if status := h.Planner.AdminStatus(ctx)["mock_tools"].(map[string]any); status["active"] != true {
+		t.Fatalf("mock_tools status = %v", status)
+	}
+}
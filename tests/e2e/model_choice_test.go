@@ -0,0 +1,42 @@
+package e2e
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestAgentLoop_RoutingAndSynthesisModels(t *testing.T) {
+	h := Start(t)
+	t.Setenv("AGENT_ROUTING_PROVIDER", "ollama")
+	t.Setenv("AGENT_ROUTING_MODEL", "qwen2.5:0.5b")
+	t.Setenv("AGENT_SYNTHESIS_MODEL", "openai/gpt-4o")
+	t.Setenv("AGENT_ROUTING_TEMPERATURE", "0")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if _, err := h.Planner.ReloadConfig(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	h.Gateway.Cassette = []string{
+		`{"tool":{"name":"web_search","args":{"query":"lisbon weather"}}}`,
+		`{"steps":["Pack an umbrella"]}`,
+	}
+	if _, err := h.Planner.AgentLoop(ctx, "weather in lisbon", "models-1", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	reqs := h.Gateway.Requests()
+	if len(reqs) != 2 {
+		t.Fatalf("%d GetPlan requests, want 2", len(reqs))
+	}
+	if reqs[0].GetProvider() != "ollama" || reqs[0].GetModel() != "qwen2.5:0.5b" {
+		t.Fatalf("routing turn asked for %q/%q", reqs[0].GetProvider(), reqs[0].GetModel())
+	}
+	if reqs[0].Temperature == nil || reqs[0].GetTemperature() != 0 || reqs[1].Temperature != nil {
+		t.Fatalf("temperatures %v, %v; want 0 to route and the gateway default to answer", reqs[0].Temperature, reqs[1].Temperature)
+	}
+	if reqs[1].GetProvider() != "" || reqs[1].GetModel() != "openai/gpt-4o" {
+		t.Fatalf("synthesis turn asked for %q/%q", reqs[1].GetProvider(), reqs[1].GetModel())
+	}
+}
@@ -0,0 +1,59 @@
+package e2e
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"backend-go-agent-planner/agent"
+)
+
+func TestAgentLoop_Persona(t *testing.T) {
+	h := Start(t)
+	path := filepath.Join(t.TempDir(), "personas.json")
+	personas := `{"work-assistant": {"system_prompt": "You are Sam's work assistant.", "knowledge_bases": ["Domain-KB"], "allowed_tools": [], "model": "work-model"}}`
+	if err := os.WriteFile(path, []byte(personas), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AGENT_PERSONAS_PATH", path)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if _, err := h.Planner.ReloadConfig(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// The model names a tool the persona does not allow, then answers.
+	h.Gateway.Cassette = []string{
+		`{"tool":{"name":"web_search","args":{"query":"q3 roadmap"}}}`,
+		`{"steps":["Answer from the roadmap notes"]}`,
+	}
+	if _, err := h.Planner.AgentLoop(agent.ContextWithPersona(ctx, "work-assistant"), "search for the q3 roadmap", "work-1", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	req := h.Gateway.Requests()[0]
+	if req.GetPersona() != "work-assistant" || req.GetModel() != "work-model" || req.GetSystemPrompt() != "You are Sam's work assistant." || strings.Join(req.GetAllowedTools(), ",") != "none" {
+		t.Fatalf("GetPlan request = %+v", req)
+	}
+	if calls := h.Sandbox.Calls(); len(calls) != 0 {
+		t.Fatalf("sandbox ran %d tools, want none", len(calls))
+	}
+	rows := h.AuditRows(t, "work-1")
+	if rows[0].Data["persona"] != "work-assistant" {
+		t.Fatalf("PLAN_START = %v", rows[0].Data)
+	}
+	if kbs, _ := rows[0].Data["kbs"].([]any); len(kbs) != 1 || kbs[0] != "Domain-KB" {
+		t.Fatalf("kbs = %v, want the persona's", rows[0].Data["kbs"])
+	}
+	if rows[3].EventType != "TOOL_ERROR" {
+		t.Fatalf("event after the refused call = %s, want TOOL_ERROR", rows[3].EventType)
+	}
+
+	if _, err := h.Planner.AgentLoop(agent.ContextWithPersona(ctx, "poet"), "hi", "work-2", nil, nil); !errors.Is(err, agent.ErrUnknownPersona) {
+		t.Fatalf("unknown persona: %v, want ErrUnknownPersona", err)
+	}
+}
@@ -0,0 +1,56 @@
+package e2e
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"backend-go-agent-planner/agent"
+)
+
+func TestAgentLoop_PrincipalPropagation(t *testing.T) {
+	h := Start(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	h.Gateway.Cassette = []string{
+		`{"tool":{"name":"web_search","args":{"query":"lisbon weather"}}}`,
+		`{"steps":["Pack an umbrella"]}`,
+	}
+	ctx = agent.ContextWithPrincipal(ctx, agent.PrincipalAPIKey, "acme")
+	if _, err := h.Planner.AgentLoop(ctx, "weather in lisbon", "principal-1", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, got := range append(h.Gateway.Principals(), h.Sandbox.Principals()...) {
+		if got != "api_key:acme" {
+			t.Fatalf("downstream x-principal = %q, want api_key:acme", got)
+		}
+	}
+	if len(h.Sandbox.Principals()) != 1 {
+		t.Fatalf("sandbox ran %d tools, want 1", len(h.Sandbox.Principals()))
+	}
+	rows := h.AuditRows(t, "principal-1")
+	if len(rows) == 0 {
+		t.Fatal("no audit rows")
+	}
+	for _, r := range rows {
+		if r.Principal != "api_key:acme" {
+			t.Fatalf("%s row principal = %q, want api_key:acme", r.EventType, r.Principal)
+		}
+	}
+
+	// Without authentication nothing is attributed.
+	h.Gateway.Cassette = nil
+	if _, err := h.Planner.AgentLoop(context.Background(), "hello", "principal-2", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := h.Gateway.Principals(); got[len(got)-1] != "" {
+		t.Fatalf("unauthenticated x-principal = %q", got[len(got)-1])
+	}
+	for _, r := range h.AuditRows(t, "principal-2") {
+		if r.Principal != "" {
+			t.Fatalf("unauthenticated %s row principal = %q", r.EventType, r.Principal)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package e2e
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"backend-go-agent-planner/agent"
+)
+
+func TestAgentLoop_Priority(t *testing.T) {
+	h := Start(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if _, err := h.Planner.AgentLoop(ctx, "hello", "chat-1", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	batchCtx, err := agent.ContextWithPriority(ctx, agent.PriorityBatch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.Planner.AgentLoop(batchCtx, "hello", "eval-1", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	reqs := h.Gateway.Requests()
+	if len(reqs) != 2 || reqs[0].GetPriority() != "interactive" || reqs[1].GetPriority() != "batch" {
+		t.Fatalf("priorities = %v", reqs)
+	}
+}
@@ -0,0 +1,93 @@
+package e2e
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"backend-go-agent-planner/agent"
+
+	"github.com/go-redis/redis/v8"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestProbe_FlagsSilentToolFailure(t *testing.T) {
+	h := Start(t)
+	cfg := agent.ProbeConfig{Timeout: 10 * time.Second, Prompt: "search the web for the latest Go release", SessionID: "canary", RequireTool: true}
+
+	// Each run is a fresh session: an earlier run's stored tool result must
+	// not let the next one answer without calling the tool.
+	for i := range 3 {
+		if res := h.Planner.Probe(context.Background(), cfg); !res.OK {
+			t.Fatalf("healthy probe %d = %+v", i, res)
+		}
+	}
+
+	// AgentLoop feeds tool errors back to the model and still answers; the
+	// probe must not.
+	h.Sandbox.SetError(status.Error(codes.Unavailable, "sandbox down"))
+	res := h.Planner.Probe(context.Background(), cfg)
+	if res.OK || res.Stage != agent.StageTool {
+		t.Fatalf("probe with a failing sandbox = %+v, want a tool failure", res)
+	}
+
+	// A direct answer does not exercise the sandbox. On a fresh stack: the
+	// playbooks of the runs above would bring "search" into the prompt, and
+	// the mock provider would call the tool.
+	h = Start(t)
+	cfg.Prompt = "plan my week"
+	if res := h.Planner.Probe(context.Background(), cfg); res.OK || res.Stage != agent.StageTool {
+		t.Fatalf("probe without a tool call = %+v", res)
+	}
+}
+
+func TestRunProbes_PublishesCanaryFailedOnce(t *testing.T) {
+	h := Start(t)
+	h.Sandbox.SetError(status.Error(codes.Unavailable, "sandbox down"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	rdb := redis.NewClient(&redis.Options{Addr: h.Redis.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+	sub := rdb.Subscribe(ctx, "pagi_notifications")
+	t.Cleanup(func() { _ = sub.Close() })
+	if _, err := sub.Receive(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	probeCtx, stop := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		h.Planner.RunProbes(probeCtx, agent.ProbeConfig{Interval: 20 * time.Millisecond, Timeout: 5 * time.Second, Prompt: "search the web for news", SessionID: "canary", RequireTool: true})
+	}()
+
+	var canary []string
+	for len(canary) < 2 {
+		msg, err := sub.ReceiveMessage(ctx)
+		if err != nil {
+			t.Fatalf("notifications so far %v: %v", canary, err)
+		}
+		var payload map[string]any
+		_ = json.Unmarshal([]byte(msg.Payload), &payload)
+		switch payload["status"] {
+		case "CANARY_FAILED":
+			if payload["stage"] != agent.StageTool || len(canary) > 0 {
+				t.Fatalf("unexpected CANARY_FAILED %v after %v", payload, canary)
+			}
+			canary = append(canary, "CANARY_FAILED")
+			// Repeated failures stay quiet; recovery is announced.
+			time.Sleep(100 * time.Millisecond)
+			h.Sandbox.SetError(nil)
+		case "CANARY_RECOVERED":
+			canary = append(canary, "CANARY_RECOVERED")
+		}
+	}
+	stop()
+	<-done
+	if h.Planner.ProbeStatus() == nil {
+		t.Fatal("ProbeStatus is empty after probing")
+	}
+}
@@ -0,0 +1,67 @@
+package e2e
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAgentLoop_PromptExperiment(t *testing.T) {
+	h := Start(t)
+	path := filepath.Join(t.TempDir(), "prompts.json")
+	if err := os.WriteFile(path, []byte(`{"v2": {"planner": "<task>\n{{.Prompt}}\n</task>\n"}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AGENT_PROMPTS_PATH", path)
+	t.Setenv("AGENT_PROMPT_CANDIDATE", "v2")
+	t.Setenv("AGENT_PROMPT_CANDIDATE_PERCENT", "100")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if _, err := h.Planner.ReloadConfig(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := h.Planner.AgentLoop(ctx, "plan my week", "exp-1", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	req := h.Gateway.Requests()[0]
+	if req.GetPromptVersion() != "v2" || !strings.HasPrefix(req.GetPrompt(), "<task>\nplan my week") {
+		t.Fatalf("GetPlan request: version %q, prompt %q", req.GetPromptVersion(), req.GetPrompt())
+	}
+	rows := h.AuditRows(t, "exp-1")
+	if rows[0].Data["experiment"] != "candidate" {
+		t.Fatalf("PLAN_START = %v", rows[0].Data)
+	}
+	for _, r := range rows {
+		if r.Data["prompt_version"] != "v2" {
+			t.Fatalf("%s not tagged v2: %v", r.EventType, r.Data)
+		}
+	}
+	if got := rows[1].Data["gateway_prompt_version"]; rows[1].EventType != "PLAN_MODEL_RESPONSE" || got != "v2" {
+		t.Fatalf("%s gateway_prompt_version = %v", rows[1].EventType, got)
+	}
+
+	// With the experiment off every session is back on the default version.
+	t.Setenv("AGENT_PROMPT_CANDIDATE_PERCENT", "0")
+	if _, err := h.Planner.ReloadConfig(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.Planner.AgentLoop(ctx, "plan my week", "exp-1", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if req := h.Gateway.Requests()[1]; req.GetPromptVersion() != "v1" || !strings.HasPrefix(req.GetPrompt(), "<session_history>") {
+		t.Fatalf("GetPlan request: version %q, prompt %q", req.GetPromptVersion(), req.GetPrompt())
+	}
+	var start map[string]any
+	for _, r := range h.AuditRows(t, "exp-1") {
+		if r.EventType == "PLAN_START" {
+			start = r.Data
+		}
+	}
+	if start["experiment"] != "control" || start["prompt_version"] != "v1" {
+		t.Fatalf("second PLAN_START = %v", start)
+	}
+}
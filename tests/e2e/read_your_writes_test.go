@@ -0,0 +1,50 @@
+package e2e
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAgentLoop_ReadYourWrites(t *testing.T) {
+	for _, mode := range []string{"off", "on"} {
+		t.Run(mode, func(t *testing.T) {
+			h := Start(t)
+			t.Setenv("AGENT_READ_YOUR_WRITES", mode)
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			if _, err := h.Planner.ReloadConfig(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			// The memory service accepts the tool turn's writes but does not
+			// serve them back yet.
+			h.Memory.LagWrites(true)
+			h.Gateway.Cassette = []string{
+				`{"tool":{"name":"web_search","args":{"query":"lisbon weather"}}}`,
+				`{"steps":["Pack an umbrella"]}`,
+				`{"steps":["Still rainy"]}`,
+			}
+			if _, err := h.Planner.AgentLoop(ctx, "weather in lisbon", "ryw-1", nil, nil); err != nil {
+				t.Fatal(err)
+			}
+			reqs := h.Gateway.Requests()
+			if len(reqs) != 2 {
+				t.Fatalf("%d GetPlan requests, want 2", len(reqs))
+			}
+			if got, want := strings.Contains(reqs[1].GetPrompt(), "[tool-plan]"), mode == "on"; got != want {
+				t.Fatalf("second turn's history has the tool plan = %v, want %v:\n%s", got, want, reqs[1].GetPrompt())
+			}
+
+			// The next run reads them from the memory service once visible.
+			h.Memory.LagWrites(false)
+			if _, err := h.Planner.AgentLoop(ctx, "and tomorrow?", "ryw-1", nil, nil); err != nil {
+				t.Fatal(err)
+			}
+			if n := strings.Count(h.Gateway.Requests()[2].GetPrompt(), "[tool-plan]"); n != 1 {
+				t.Fatalf("tool plan appears %d times in the next run's history", n)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package e2e
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAgentLoop_ScratchpadReusesToolResults(t *testing.T) {
+	h := Start(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	search := `{"tool":{"name":"web_search","args":{"query":"latest Go release"}}}`
+	h.Gateway.Cassette = []string{
+		search,
+		`{"steps":["Go 1.99 is out"],"scratchpad":["The twin follows Go releases"]}`,
+		search,
+		`{"steps":["Still Go 1.99"]}`,
+	}
+	for range 2 {
+		if _, err := h.Planner.AgentLoop(ctx, "what is the latest Go release?", "pad-1", nil, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if calls := h.Sandbox.Calls(); len(calls) != 1 {
+		t.Fatalf("sandbox ran %d tools, want 1 (the repeat comes from the scratchpad)", len(calls))
+	}
+	second := h.Gateway.Requests()[2].GetPrompt()
+	if !strings.Contains(second, "<scratchpad>") || !strings.Contains(second, `web_search {"query":"latest Go release"}`) || !strings.Contains(second, "The twin follows Go releases") {
+		t.Fatalf("second run's prompt is missing the scratchpad:\n%s", second)
+	}
+	if first := h.Gateway.Requests()[0].GetPrompt(); strings.Contains(first, "<scratchpad>") {
+		t.Fatalf("first run has a scratchpad:\n%s", first)
+	}
+
+	var reused int
+	for _, row := range h.AuditRows(t, "pad-1") {
+		if row.EventType == "TOOL_RESULT" && row.Data["scratchpad"] == true {
+			reused++
+		}
+	}
+	if reused != 1 {
+		t.Fatalf("%d TOOL_RESULT steps came from the scratchpad, want 1", reused)
+	}
+	if ttl := h.Redis.TTL("pagi:scratchpad:pad-1"); ttl <= 0 {
+		t.Fatalf("scratchpad TTL = %v", ttl)
+	}
+}
@@ -0,0 +1,70 @@
+package e2e
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"backend-go-agent-planner/agent"
+)
+
+func TestSessionArchive_MovesSessionBetweenEnvironments(t *testing.T) {
+	dev, prod := Start(t), Start(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if _, err := dev.Planner.AgentLoop(ctx, "search the web for the latest Go release", "twin-1", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	archive, err := dev.Planner.ExportSession(ctx, "twin-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(archive.History) == 0 || len(archive.Playbooks) != 1 || len(archive.Audit) == 0 {
+		t.Fatalf("archive: %d history, %d playbooks, %d audit rows", len(archive.History), len(archive.Playbooks), len(archive.Audit))
+	}
+
+	// The archive is moved as JSON.
+	raw, err := json.Marshal(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var moved agent.SessionArchive
+	if err := json.Unmarshal(raw, &moved); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := prod.Planner.ImportSession(ctx, &moved, "twin-1-debug")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.SessionID != "twin-1-debug" || res.From != "twin-1" || res.AuditRows != len(archive.Audit) {
+		t.Fatalf("import = %+v", res)
+	}
+
+	if got, want := len(prod.Memory.History("twin-1-debug")), len(dev.Memory.History("twin-1")); got != want {
+		t.Fatalf("imported history has %d messages, want %d", got, want)
+	}
+	playbooks := prod.Memory.Playbooks()
+	if len(playbooks) != 1 || playbooks[0].SessionID != "twin-1-debug" || playbooks[0].Prompt != archive.Playbooks[0].Prompt {
+		t.Fatalf("imported playbooks = %+v", playbooks)
+	}
+	rows := prod.AuditRows(t, "twin-1-debug")
+	if len(rows) != len(archive.Audit)+1 || rows[0].EventType != "PLAN_START" || rows[len(rows)-1].EventType != "SESSION_IMPORTED" {
+		t.Fatalf("imported audit trail has %d rows", len(rows))
+	}
+	if rows[0].TraceID != archive.Audit[0].TraceID {
+		t.Fatalf("trace ID %q not kept (want %q)", rows[0].TraceID, archive.Audit[0].TraceID)
+	}
+
+	// Imports never merge into an existing session.
+	if _, err := prod.Planner.ImportSession(ctx, &moved, "twin-1-debug"); !errors.Is(err, agent.ErrSessionExists) {
+		t.Fatalf("second import: %v, want ErrSessionExists", err)
+	}
+	moved.Version = 99
+	if _, err := prod.Planner.ImportSession(ctx, &moved, "other"); !errors.Is(err, agent.ErrArchiveInvalid) {
+		t.Fatalf("unknown version: %v, want ErrArchiveInvalid", err)
+	}
+}
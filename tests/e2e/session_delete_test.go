@@ -0,0 +1,81 @@
+package e2e
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"backend-go-agent-planner/agent"
+	"backend-go-agent-planner/audit"
+)
+
+func TestForgetSession(t *testing.T) {
+	seed := []byte(strings.Repeat("s", ed25519.SeedSize))
+	t.Setenv("PAGI_AUDIT_SIGNING_KEY", base64.StdEncoding.EncodeToString(seed))
+	t.Setenv("PAGI_SESSION_MASTER_KEY", base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32))))
+	h := Start(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	h.Gateway.Cassette = []string{
+		`{"tool":{"name":"web_search","args":{"query":"lisbon weather"}}}`,
+		`{"steps":["Pack an umbrella"]}`,
+		`{"steps":["Noted"]}`,
+	}
+	if _, err := h.Planner.AgentLoop(ctx, "weather in lisbon", "forget-1", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.Planner.AgentLoop(ctx, "hello", "keep-1", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.Planner.TagSession(ctx, "forget-1", []string{"health"}); err != nil {
+		t.Fatal(err)
+	}
+
+	receipt, err := h.Planner.ForgetSession(ctx, "forget-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := receipt.Deleted
+	if receipt.SessionID != "forget-1" || !receipt.KeyErased || d["memory_messages"] != 6 || d["memory_playbooks"] != 1 || d["audit_rows"] == 0 || d["tags"] != 1 {
+		t.Fatalf("receipt = %+v", receipt)
+	}
+	pub := ed25519.NewKeyFromSeed(seed).Public().(ed25519.PublicKey)
+	if err := audit.VerifyReceipt(*receipt, pub); err != nil {
+		t.Fatalf("receipt signature: %v", err)
+	}
+
+	if got := h.Memory.Forgotten(); len(got) != 1 || got[0] != "forget-1" {
+		t.Fatalf("memory deletions = %v", got)
+	}
+	if len(h.Memory.History("forget-1")) != 0 || len(h.Memory.Playbooks()) != 0 {
+		t.Fatal("memory still holds the session")
+	}
+	if rows := h.AuditRows(t, "forget-1"); len(rows) != 0 {
+		t.Fatalf("%d audit rows left", len(rows))
+	}
+	if rows := h.AuditRows(t, "keep-1"); len(rows) == 0 {
+		t.Fatal("other session's audit rows deleted")
+	}
+	deleted, _ := h.Planner.QueryAudit(ctx, audit.QueryFilter{EventType: "SESSION_DELETED"})
+	if len(deleted) != 1 || !strings.Contains(string(deleted[0].Data), receipt.Signature) {
+		t.Fatalf("SESSION_DELETED rows = %+v", deleted)
+	}
+}
+
+func TestForgetSession_NeedsSigningKey(t *testing.T) {
+	h := Start(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, err := h.Planner.ForgetSession(ctx, "s1"); !errors.Is(err, agent.ErrBundleKeyUnset) {
+		t.Fatalf("ForgetSession without a key: %v", err)
+	}
+	if got := h.Memory.Forgotten(); len(got) != 0 {
+		t.Fatalf("memory deletions = %v, want none", got)
+	}
+}
@@ -0,0 +1,73 @@
+package e2e
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"backend-go-agent-planner/audit"
+)
+
+func TestAgentLoop_SessionKeys(t *testing.T) {
+	t.Setenv("PAGI_SESSION_MASTER_KEY", base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32))))
+	h := Start(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	h.Gateway.Cassette = []string{`{"steps":["Book the blue flight"]}`, `{"steps":["Done"]}`}
+	if _, err := h.Planner.AgentLoop(ctx, "my passport number is X123", "keys-1", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// The Memory Service and the audit DB only see sealed text.
+	for _, s := range h.Memory.Stores() {
+		if strings.Contains(s.Prompt, "X123") || strings.Contains(s.History[0].Content, "X123") || !audit.IsSealed(s.History[1].Content) {
+			t.Fatalf("memory delta not sealed: %+v", s)
+		}
+	}
+	for _, row := range h.AuditRows(t, "keys-1") {
+		if row.Data != nil {
+			t.Fatalf("audit row %s stored in the clear: %v", row.EventType, row.Data)
+		}
+	}
+
+	// The planner reads its own writes back in the clear.
+	if _, err := h.Planner.AgentLoop(ctx, "and the hotel?", "keys-1", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if prompt := h.Gateway.Requests()[1].GetPrompt(); !strings.Contains(prompt, "X123") {
+		t.Fatalf("second run's history was not opened:\n%s", prompt)
+	}
+	rows, err := h.Planner.QueryAudit(ctx, audit.QueryFilter{SessionID: "keys-1"})
+	if err != nil || len(rows) == 0 || rows[0].Erased || !strings.Contains(string(rows[0].Data), "X123") {
+		t.Fatalf("QueryAudit = %+v, %v", rows, err)
+	}
+
+	// Erasing the key makes all of it unreadable.
+	if erased, err := h.Planner.EraseSessionKey(ctx, "keys-1"); err != nil || !erased {
+		t.Fatalf("EraseSessionKey = %v, %v", erased, err)
+	}
+	rows, _ = h.Planner.QueryAudit(ctx, audit.QueryFilter{SessionID: "keys-1"})
+	for _, row := range rows {
+		if !row.Erased {
+			t.Fatalf("row %s still readable: %s", row.EventType, row.Data)
+		}
+	}
+	archive, err := h.Planner.ExportSession(ctx, "keys-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(archive.History) != 4 {
+		t.Fatalf("exported %d history messages, want 4", len(archive.History))
+	}
+	for _, msg := range archive.History {
+		if msg["content"] != "" || msg["erased"] != true {
+			t.Fatalf("history message still readable: %v", msg)
+		}
+	}
+	if erased, _ := h.Planner.QueryAudit(ctx, audit.QueryFilter{EventType: "SESSION_KEY_ERASED"}); len(erased) != 1 {
+		t.Fatalf("SESSION_KEY_ERASED rows = %+v", erased)
+	}
+}
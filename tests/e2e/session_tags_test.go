@@ -0,0 +1,64 @@
+package e2e
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"backend-go-agent-planner/agent"
+	"backend-go-agent-planner/audit"
+)
+
+func TestSessionTags(t *testing.T) {
+	h := Start(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// Tagged before the first run, as /plan does with "tags".
+	tags, err := h.Planner.TagSession(ctx, "tagged-1", []string{"Health", " project:offsite ", "health"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(tags, []string{"health", "project:offsite"}) {
+		t.Fatalf("tags = %v", tags)
+	}
+	for _, session := range []string{"tagged-1", "untagged-1"} {
+		if _, err := h.Planner.AgentLoop(ctx, "hello", session, nil, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sessions, err := h.Planner.SearchSessions(ctx, audit.SessionFilter{Tags: []string{"HEALTH"}, Since: time.Now().Add(-time.Minute)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 1 || sessions[0].SessionID != "tagged-1" || sessions[0].Events < 3 {
+		t.Fatalf("sessions = %+v", sessions)
+	}
+
+	if _, err := h.Planner.TagSession(ctx, "tagged-1", []string{"has space"}); !errors.Is(err, agent.ErrInvalidTags) {
+		t.Fatalf("invalid tag: err = %v", err)
+	}
+
+	// Tags travel with the session archive.
+	archive, err := h.Planner.ExportSession(ctx, "tagged-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(archive.Tags, []string{"health", "project:offsite"}) {
+		t.Fatalf("archive tags = %v", archive.Tags)
+	}
+	prod := Start(t)
+	if _, err := prod.Planner.ImportSession(ctx, archive, ""); err != nil {
+		t.Fatal(err)
+	}
+	if tags, err := prod.Planner.SessionTags(ctx, "tagged-1"); err != nil || !reflect.DeepEqual(tags, archive.Tags) {
+		t.Fatalf("imported tags = %v, %v", tags, err)
+	}
+
+	if tags, err := h.Planner.UntagSession(ctx, "tagged-1", "health"); err != nil || !reflect.DeepEqual(tags, []string{"project:offsite"}) {
+		t.Fatalf("untag = %v, %v", tags, err)
+	}
+}
@@ -0,0 +1,43 @@
+package e2e
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestAgentLoop_StreamPlansDispatchToolEarly(t *testing.T) {
+	h := Start(t)
+	t.Setenv("AGENT_STREAM_PLANS", "on")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if _, err := h.Planner.ReloadConfig(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// The tool call is complete well before the rest of the plan arrives.
+	h.Gateway.StreamDelay = 5 * time.Millisecond
+	h.Gateway.Cassette = []string{
+		`{"tool":{"name":"web_search","args":{"query":"lisbon weather"}},"facts":["The user is travelling to Lisbon next week and wants to know what to pack."]}`,
+		`{"steps":["Pack an umbrella"]}`,
+	}
+	if _, err := h.Planner.AgentLoop(ctx, "weather in lisbon", "stream-1", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if calls := h.Sandbox.Calls(); len(calls) != 1 || calls[0].GetToolName() != "web_search" {
+		t.Fatalf("sandbox calls = %v, want one web_search", calls)
+	}
+	var result map[string]any
+	for _, row := range h.AuditRows(t, "stream-1") {
+		if row.EventType == "TOOL_RESULT" {
+			result = row.Data
+		}
+	}
+	if result == nil || result["early"] != true {
+		t.Fatalf("TOOL_RESULT = %v, want an early dispatch", result)
+	}
+	if overlap, _ := result["overlap_ms"].(float64); overlap <= 0 {
+		t.Fatalf("overlap_ms = %v, want the tool to run while the plan streamed", result["overlap_ms"])
+	}
+}
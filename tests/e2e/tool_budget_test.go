@@ -0,0 +1,50 @@
+package e2e
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAgentLoop_ToolBudget(t *testing.T) {
+	h := Start(t)
+	t.Setenv("AGENT_TOOL_BUDGET_PER_SESSION", "1")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if _, err := h.Planner.ReloadConfig(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	h.Gateway.Cassette = []string{
+		`{"tool":{"name":"web_search","args":{"query":"lisbon weather"}}}`,
+		`{"steps":["Pack an umbrella"]}`,
+		`{"tool":{"name":"web_search","args":{"query":"porto weather"}}}`,
+		`{"steps":["Answer without searching again"]}`,
+	}
+	for _, prompt := range []string{"weather in lisbon", "and in porto?"} {
+		if _, err := h.Planner.AgentLoop(ctx, prompt, "budget-1", nil, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The second search is over the session's cap and never reaches the sandbox.
+	if calls := h.Sandbox.Calls(); len(calls) != 1 {
+		t.Fatalf("sandbox ran %d tools, want 1", len(calls))
+	}
+	var refused map[string]any
+	for _, r := range h.AuditRows(t, "budget-1") {
+		if r.EventType == "TOOL_ERROR" {
+			refused = r.Data
+		}
+	}
+	if refused["budget"] != true || !strings.Contains(refused["error"].(string), "tool budget exceeded") {
+		t.Fatalf("TOOL_ERROR = %v", refused)
+	}
+	if last := LastTurn(h.Gateway.Requests()[3]); !strings.Contains(last, "Tool error: tool budget exceeded") {
+		t.Fatalf("refusal not fed back to the model: %s", last)
+	}
+	if n, _ := h.Redis.Get("pagi:toolbudget:budget-1"); n != "2" || h.Redis.TTL("pagi:toolbudget:budget-1") != 24*time.Hour {
+		t.Fatalf("session counter = %q, ttl %v", n, h.Redis.TTL("pagi:toolbudget:budget-1"))
+	}
+}
@@ -0,0 +1,42 @@
+package e2e
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"backend-go-model-gateway/pkg/tools"
+	pb "backend-go-model-gateway/proto/proto"
+)
+
+func TestAgentLoop_DiscoveredTool(t *testing.T) {
+	h := Start(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// An older sandbox without ListTools leaves the built-in tools.
+	h.Planner.RunToolDiscovery(ctx, 0)
+	if source, _ := h.Planner.ToolSource(); source != tools.SourceBuiltin {
+		t.Fatalf("source = %s without ListTools", source)
+	}
+
+	h.Sandbox.SetTools([]*pb.ToolDefinition{
+		tools.ToProto(tools.Builtin[0]),
+		{Name: "calendar_lookup", Description: "Find events on a day.", Parameters: map[string]*pb.ToolParameter{"day": {Type: "string"}}},
+	})
+	h.Planner.RunToolDiscovery(ctx, 0)
+	if source, _ := h.Planner.ToolSource(); source != tools.SourceSandbox || len(h.Planner.Tools()) != 2 {
+		t.Fatalf("source = %s, tools = %+v", source, h.Planner.Tools())
+	}
+
+	h.Gateway.Cassette = []string{
+		`{"tool":{"name":"calendar_lookup","args":{"day":"monday"}}}`,
+		`{"steps":["You are free on Monday"]}`,
+	}
+	if _, err := h.Planner.AgentLoop(ctx, "am I free on monday?", "discover-1", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if calls := h.Sandbox.Calls(); len(calls) != 1 || calls[0].GetToolName() != "calendar_lookup" {
+		t.Fatalf("sandbox calls = %v", calls)
+	}
+}
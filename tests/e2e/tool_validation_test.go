@@ -0,0 +1,42 @@
+package e2e
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAgentLoop_InvalidToolCall(t *testing.T) {
+	h := Start(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// The model renames the argument, then fixes the call after the error.
+	h.Gateway.Cassette = []string{
+		`{"tool":{"name":"web_search","args":{"q":"lisbon weather"}}}`,
+		`{"tool":{"name":"web_search","args":{"query":"lisbon weather"}}}`,
+		`{"steps":["Pack an umbrella"]}`,
+	}
+	if _, err := h.Planner.AgentLoop(ctx, "weather in lisbon", "validate-1", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the corrected call reached the sandbox.
+	if calls := h.Sandbox.Calls(); len(calls) != 1 || !strings.Contains(calls[0].GetArgsJson(), `"query"`) {
+		t.Fatalf("sandbox calls = %v", calls)
+	}
+	var refused map[string]any
+	for _, r := range h.AuditRows(t, "validate-1") {
+		if r.EventType == "TOOL_ERROR" {
+			refused = r.Data
+		}
+	}
+	problems, _ := refused["validation"].([]any)
+	if len(problems) != 2 || problems[0] != `missing required argument "query" (string)` {
+		t.Fatalf("TOOL_ERROR = %v", refused)
+	}
+	if second := LastTurn(h.Gateway.Requests()[1]); !strings.Contains(second, `Tool error: invalid call to tool "web_search": missing required argument "query" (string); unknown argument "q"; expected query`) {
+		t.Fatalf("validation error not fed back to the model: %s", second)
+	}
+}
@@ -0,0 +1,55 @@
+package e2e
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"backend-go-agent-planner/agent"
+)
+
+func TestAgentLoop_TurnOverBudget(t *testing.T) {
+	h := Start(t)
+	// Every turn is over a 1ns budget; only the first one degrades the run.
+	t.Setenv("AGENT_TURN_LATENCY_BUDGET", "1ns")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if _, err := h.Planner.ReloadConfig(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	h.Gateway.Cassette = []string{
+		`{"tool":{"name":"web_search","args":{"query":"lisbon weather"}}}`,
+		`{"steps":["Pack an umbrella"]}`,
+	}
+	runCtx, report := agent.WithRunReport(ctx)
+	if _, err := h.Planner.AgentLoop(runCtx, "weather in lisbon", "budget-turn-1", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if report.Degraded == nil || report.Degraded.Turn != 1 || !slices.Equal(report.Degraded.SkippedKBs, []string{"Mind-KB"}) {
+		t.Fatalf("report = %+v", report.Degraded)
+	}
+
+	// No routing rule matches, so each turn is one GetRAGContext call: the
+	// first over every KB, the second without the playbooks.
+	reqs := h.Memory.RAGRequests()
+	if len(reqs) != 2 || !slices.Contains(reqs[0].GetKnowledgeBases(), "Mind-KB") || !slices.Equal(reqs[1].GetKnowledgeBases(), []string{"Domain-KB", "Body-KB", "Soul-KB"}) {
+		t.Fatalf("RAG requests = %v", reqs)
+	}
+
+	var degradedSteps int
+	for _, row := range h.AuditRows(t, "budget-turn-1") {
+		switch row.EventType {
+		case "TURN_DEGRADED":
+			degradedSteps++
+		case "PLAN_END":
+			if row.Data["degraded"] == nil {
+				t.Fatalf("PLAN_END = %v, want degraded", row.Data)
+			}
+		}
+	}
+	if degradedSteps != 1 {
+		t.Fatalf("%d TURN_DEGRADED steps, want 1", degradedSteps)
+	}
+}